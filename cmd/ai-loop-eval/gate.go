@@ -78,15 +78,14 @@ type gateReport struct {
 	Passed        bool             `json:"passed"`
 	Status        string           `json:"status"`
 	Reasons       []string         `json:"reasons,omitempty"`
+	// WithinNoise is set when a gated pass rate or accuracy is less than one trial standard deviation
+	// from its threshold or reference, so the decision could flip on a re-run. NoiseNotes lists those comparisons.
+	WithinNoise bool     `json:"within_noise,omitempty"`
+	NoiseNotes  []string `json:"noise_notes,omitempty"`
 }
 
-var fallbackFinalPhrases = []string{
-	"i have reached the current automatic loop limit",
-	"reply with one concrete next step",
-	"assistant finished without a visible response",
-	"tool workflow failed",
-	"no response",
-}
+// fallbackFinalPhrases is the runtime's localized fallback phrase catalog.
+var fallbackFinalPhrases = ai.DefaultFallbackPhraseCatalog()
 
 func assessTaskOutcome(task evalTask, result taskResult) taskOutcome {
 	out := taskOutcome{
@@ -99,14 +98,11 @@ func assessTaskOutcome(task evalTask, result taskResult) taskOutcome {
 
 	finalTextLower := strings.ToLower(strings.TrimSpace(result.FinalText))
 	if task.Assertions.Output.MustNotEndWithFallback || len(task.Assertions.Events.HardFail) > 0 {
-		for _, phrase := range fallbackFinalPhrases {
-			if strings.Contains(finalTextLower, phrase) {
-				out.FallbackFinal = true
-				out.Passed = false
-				out.LoopSafe = false
-				out.HardFailReasons = append(out.HardFailReasons, "fallback_final_message")
-				break
-			}
+		if _, _, ok := fallbackFinalPhrases.Match(finalTextLower); ok {
+			out.FallbackFinal = true
+			out.Passed = false
+			out.LoopSafe = false
+			out.HardFailReasons = append(out.HardFailReasons, "fallback_final_message")
 		}
 	}
 
@@ -146,6 +142,10 @@ func assessTaskOutcome(task evalTask, result taskResult) taskOutcome {
 			out.HardFailReasons = append(out.HardFailReasons, "run_error")
 		}
 	}
+	if result.TaskTimeout {
+		out.Passed = false
+		out.HardFailReasons = append(out.HardFailReasons, taskTimeoutReason)
+	}
 
 	output := task.Assertions.Output
 	if !containsAllRequirements(finalTextLower, output.MustContain) {
@@ -156,6 +156,12 @@ func assessTaskOutcome(task evalTask, result taskResult) taskOutcome {
 		out.Passed = false
 		out.HardFailReasons = append(out.HardFailReasons, "contains_forbidden")
 	}
+	for _, turn := range result.TurnScores {
+		if !turn.Passed {
+			out.Passed = false
+			out.HardFailReasons = append(out.HardFailReasons, fmt.Sprintf("turn_%d_assertions_failed", turn.Turn))
+		}
+	}
 	if output.RequireEvidence && !containsEvidencePath(result.FinalText, result.WorkspacePath) {
 		out.Passed = false
 		out.HardFailReasons = append(out.HardFailReasons, "missing_evidence_path")
@@ -538,7 +544,11 @@ func referenceBestMetrics(baselines benchmarkBaselines) benchmarkMetrics {
 	return best
 }
 
-func evaluateGate(metrics suiteMetrics, baselines benchmarkBaselines, thresholds gateThresholds) gateReport {
+// evaluateGate compares metrics against the thresholds and the best baseline.
+//
+// noise, when set by a -trials run, marks pass rate and accuracy comparisons that are within one
+// trial standard deviation; their reasons end with "(within noise ...)".
+func evaluateGate(metrics suiteMetrics, baselines benchmarkBaselines, thresholds gateThresholds, noise *suiteNoise) gateReport {
 	reference := referenceBestMetrics(baselines)
 	delta := benchmarkDeltas{
 		PassRate:            metrics.PassRate - reference.PassRate,
@@ -548,21 +558,36 @@ func evaluateGate(metrics suiteMetrics, baselines benchmarkBaselines, thresholds
 		AverageAccuracy:     metrics.AverageAccuracy - reference.AverageAccuracy,
 	}
 	reasons := make([]string, 0, 10)
-	if metrics.PassRate < thresholds.MinPassRate {
-		reasons = append(reasons, fmt.Sprintf("pass_rate %.3f < threshold %.3f", metrics.PassRate, thresholds.MinPassRate))
+	var noiseNotes []string
+	// gated checks a pass rate or accuracy bound. A comparison within one trial stddev is also a noise note.
+	gated := func(metricName string, precision int, metric float64, boundName string, bound float64, spread float64) {
+		op := ">="
+		if metric < bound {
+			op = "<"
+		}
+		text := fmt.Sprintf("%s %.*f %s %s %.*f", metricName, precision, metric, op, boundName, precision, bound)
+		if noise != nil && withinNoise(metric, bound, spread) {
+			text += fmt.Sprintf(" (within noise: stddev %.*f over %d trials)", precision, spread, noise.Trials)
+			noiseNotes = append(noiseNotes, text)
+		}
+		if metric < bound {
+			reasons = append(reasons, text)
+		}
+	}
+	var passRateSpread, accuracySpread float64
+	if noise != nil {
+		passRateSpread, accuracySpread = noise.PassRateStddev, noise.AccuracyStddev
 	}
+
+	gated("pass_rate", 3, metrics.PassRate, "threshold", thresholds.MinPassRate, passRateSpread)
 	if metrics.LoopSafetyRate < thresholds.MinLoopSafetyRate {
 		reasons = append(reasons, fmt.Sprintf("loop_safety_rate %.3f < threshold %.3f", metrics.LoopSafetyRate, thresholds.MinLoopSafetyRate))
 	}
 	if metrics.FallbackFreeRate < thresholds.MinFallbackFreeRate {
 		reasons = append(reasons, fmt.Sprintf("fallback_free_rate %.3f < threshold %.3f", metrics.FallbackFreeRate, thresholds.MinFallbackFreeRate))
 	}
-	if metrics.AverageAccuracy < thresholds.MinAverageAccuracy {
-		reasons = append(reasons, fmt.Sprintf("average_accuracy %.2f < threshold %.2f", metrics.AverageAccuracy, thresholds.MinAverageAccuracy))
-	}
-	if metrics.PassRate < reference.PassRate {
-		reasons = append(reasons, fmt.Sprintf("pass_rate %.3f < best_ref %.3f", metrics.PassRate, reference.PassRate))
-	}
+	gated("average_accuracy", 2, metrics.AverageAccuracy, "threshold", thresholds.MinAverageAccuracy, accuracySpread)
+	gated("pass_rate", 3, metrics.PassRate, "best_ref", reference.PassRate, passRateSpread)
 	if metrics.LoopSafetyRate < reference.LoopSafetyRate {
 		reasons = append(reasons, fmt.Sprintf("loop_safety_rate %.3f < best_ref %.3f", metrics.LoopSafetyRate, reference.LoopSafetyRate))
 	}
@@ -572,9 +597,7 @@ func evaluateGate(metrics suiteMetrics, baselines benchmarkBaselines, thresholds
 	if metrics.FallbackFreeRate < reference.FallbackFreeRate {
 		reasons = append(reasons, fmt.Sprintf("fallback_free_rate %.3f < best_ref %.3f", metrics.FallbackFreeRate, reference.FallbackFreeRate))
 	}
-	if metrics.AverageAccuracy < reference.AverageAccuracy {
-		reasons = append(reasons, fmt.Sprintf("average_accuracy %.2f < best_ref %.2f", metrics.AverageAccuracy, reference.AverageAccuracy))
-	}
+	gated("average_accuracy", 2, metrics.AverageAccuracy, "best_ref", reference.AverageAccuracy, accuracySpread)
 
 	return gateReport{
 		Enabled:       true,
@@ -589,7 +612,9 @@ func evaluateGate(metrics suiteMetrics, baselines benchmarkBaselines, thresholds
 			}
 			return "reject"
 		}(),
-		Reasons: reasons,
+		Reasons:     reasons,
+		WithinNoise: len(noiseNotes) > 0,
+		NoiseNotes:  noiseNotes,
 	}
 }
 
@@ -47,7 +47,7 @@ func TestEvaluateGate_RejectBelowBaseline(t *testing.T) {
 		MinFallbackFreeRate: 0.9,
 		MinAverageAccuracy:  75,
 	}
-	report := evaluateGate(metrics, baselines, thresholds)
+	report := evaluateGate(metrics, baselines, thresholds, nil)
 	if report.Status != "reject" {
 		t.Fatalf("status=%s, want reject", report.Status)
 	}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"net/http"
 	"os"
@@ -39,28 +40,46 @@ type turnMetrics struct {
 	FinalizationReason   string        `json:"finalization_reason,omitempty"`
 	EndState             string        `json:"end_state,omitempty"`
 	MonitorAbort         string        `json:"monitor_abort,omitempty"`
+	Timeout              string        `json:"timeout,omitempty"`
 	RunError             string        `json:"run_error,omitempty"`
 	CompletionReasonFlow []string      `json:"completion_reason_flow,omitempty"`
 }
 
+// turnScore is the result of one turn's own output assertions.
+type turnScore struct {
+	Turn          int      `json:"turn"`
+	Passed        bool     `json:"passed"`
+	Accuracy      float64  `json:"accuracy"`
+	MissingMust   []string `json:"missing_must_contain,omitempty"`
+	ForbiddenHits []string `json:"forbidden_hits,omitempty"`
+	TextPreview   string   `json:"text_preview,omitempty"`
+}
+
 type taskResult struct {
 	Task                evalTask             `json:"task"`
 	Inputs              []string             `json:"inputs,omitempty"`
 	Turns               []turnMetrics        `json:"turns"`
+	TurnScores          []turnScore          `json:"turn_scores,omitempty"`
 	FinalText           string               `json:"final_text"`
 	DurationTotalMS     int64                `json:"duration_total_ms"`
+	TaskTimeout         bool                 `json:"task_timeout,omitempty"`
+	SkippedTurns        int                  `json:"skipped_turns,omitempty"`
 	Score               scoreBreakdown       `json:"score"`
+	ScoreStats          *scoreStats          `json:"score_stats,omitempty"`
+	Trials              []trialScore         `json:"trials,omitempty"`
 	Outcome             taskOutcome          `json:"outcome"`
 	SourceWorkspacePath string               `json:"source_workspace_path"`
 	WorkspacePath       string               `json:"workspace_path"`
 	WorkspaceMode       string               `json:"workspace_mode,omitempty"`
 	WorkspaceSeed       string               `json:"workspace_seed,omitempty"`
+	ProviderSeed        *int64               `json:"provider_seed,omitempty"`
 	ThreadState         threadStateSummary   `json:"thread_state"`
 	ToolCalls           []toolCallSummary    `json:"tool_calls,omitempty"`
 	TodoSnapshot        *todoSnapshotSummary `json:"todo_snapshot,omitempty"`
 	EventCounts         map[string]int       `json:"event_counts,omitempty"`
 	FinalizationReasons []string             `json:"finalization_reasons,omitempty"`
 	EvidencePaths       []string             `json:"evidence_paths,omitempty"`
+	MissingEvidence     []string             `json:"missing_evidence,omitempty"`
 
 	rawThread    *ai.ThreadView               `json:"-"`
 	rawTodos     *ai.ThreadTodosView          `json:"-"`
@@ -111,6 +130,9 @@ type evalReport struct {
 	SourceWorkspacePath      string                  `json:"source_workspace_path"`
 	MaterializedWorkspaceDir string                  `json:"materialized_workspace_dir,omitempty"`
 	TaskCount                int                     `json:"task_count"`
+	Seed                     int64                   `json:"seed,omitempty"`
+	Trials                   int                     `json:"trials,omitempty"`
+	Noise                    *suiteNoise             `json:"noise,omitempty"`
 	Results                  []taskResult            `json:"results"`
 	Metrics                  suiteMetrics            `json:"metrics"`
 	StageMetrics             map[string]suiteMetrics `json:"stage_metrics,omitempty"`
@@ -141,30 +163,23 @@ func (w *monitoredResponseWriter) Write(p []byte) (int, error) {
 }
 
 type streamMonitor struct {
-	svc    *ai.Service
-	meta   *session.Meta
-	runID  string
-	ctx    context.Context
-	cancel context.CancelFunc
-
-	mu             sync.Mutex
-	partial        string
-	lastDelta      string
-	repeatDelta    int
-	toolSigCounter map[string]int
-	approvalSeen   map[string]struct{}
-	abortReason    string
+	svc   *ai.Service
+	meta  *session.Meta
+	runID string
+	ctx   context.Context
+
+	mu           sync.Mutex
+	partial      string
+	approvalSeen map[string]struct{}
 }
 
-func newStreamMonitor(svc *ai.Service, meta *session.Meta, runID string, ctx context.Context, cancel context.CancelFunc) *streamMonitor {
+func newStreamMonitor(svc *ai.Service, meta *session.Meta, runID string, ctx context.Context) *streamMonitor {
 	return &streamMonitor{
-		svc:            svc,
-		meta:           meta,
-		runID:          runID,
-		ctx:            ctx,
-		cancel:         cancel,
-		toolSigCounter: make(map[string]int),
-		approvalSeen:   make(map[string]struct{}),
+		svc:          svc,
+		meta:         meta,
+		runID:        runID,
+		ctx:          ctx,
+		approvalSeen: make(map[string]struct{}),
 	}
 }
 
@@ -192,35 +207,12 @@ func (m *streamMonitor) consume(line string) {
 	if err := json.Unmarshal([]byte(line), &payload); err != nil {
 		return
 	}
-	typ := strings.TrimSpace(strings.ToLower(anyToString(payload["type"])))
-	switch typ {
-	case "block-delta":
-		m.consumeDelta(anyToString(payload["delta"]))
-	case "block-set":
+	if strings.TrimSpace(strings.ToLower(anyToString(payload["type"]))) == "block-set" {
 		blk, _ := payload["block"].(map[string]any)
 		m.consumeBlock(blk)
 	}
 }
 
-func (m *streamMonitor) consumeDelta(delta string) {
-	normalized := normalizeText(delta)
-	if normalized == "" {
-		return
-	}
-	m.mu.Lock()
-	if normalized == m.lastDelta {
-		m.repeatDelta++
-	} else {
-		m.lastDelta = normalized
-		m.repeatDelta = 1
-	}
-	repeat := m.repeatDelta
-	m.mu.Unlock()
-	if repeat >= 10 {
-		m.abort("repeated_delta")
-	}
-}
-
 func (m *streamMonitor) consumeBlock(block map[string]any) {
 	if len(block) == 0 {
 		return
@@ -228,14 +220,9 @@ func (m *streamMonitor) consumeBlock(block map[string]any) {
 	if strings.TrimSpace(strings.ToLower(anyToString(block["type"]))) != "tool-call" {
 		return
 	}
-	toolName := strings.TrimSpace(strings.ToLower(anyToString(block["toolName"])))
 	toolID := strings.TrimSpace(anyToString(block["toolId"]))
-	args, _ := block["args"].(map[string]any)
-	signature := toolName + "|" + compactJSON(args)
 
 	m.mu.Lock()
-	m.toolSigCounter[signature] = m.toolSigCounter[signature] + 1
-	count := m.toolSigCounter[signature]
 	requiresApproval := anyToBool(block["requiresApproval"])
 	approvalState := strings.TrimSpace(strings.ToLower(anyToString(block["approvalState"])))
 	_, approvalHandled := m.approvalSeen[toolID]
@@ -244,10 +231,6 @@ func (m *streamMonitor) consumeBlock(block map[string]any) {
 		go m.rejectTool(toolID)
 	}
 	m.mu.Unlock()
-
-	if count > 16 {
-		m.abort("tool_signature_loop")
-	}
 }
 
 func (m *streamMonitor) rejectTool(toolID string) {
@@ -268,45 +251,43 @@ func (m *streamMonitor) rejectTool(toolID string) {
 	}
 }
 
-func (m *streamMonitor) abort(reason string) {
-	if m == nil {
-		return
-	}
-	reason = strings.TrimSpace(reason)
-	if reason == "" {
-		reason = "monitor_abort"
-	}
-	m.mu.Lock()
-	if m.abortReason != "" {
-		m.mu.Unlock()
-		return
-	}
-	m.abortReason = reason
-	m.mu.Unlock()
-	m.cancel()
-}
-
-func (m *streamMonitor) abortState() string {
-	if m == nil {
+// runtimeGuardAbort maps the runtime's stream-health guard finalization reasons to the eval abort label.
+//
+// The runtime aborts repeated-delta and tool-signature loops itself; the eval only reports them.
+func runtimeGuardAbort(finalizationReason string) string {
+	switch strings.TrimSpace(finalizationReason) {
+	case "repeated_delta_guard":
+		return "repeated_delta"
+	case "tool_signature_guard":
+		return "tool_signature_loop"
+	default:
 		return ""
 	}
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	return strings.TrimSpace(m.abortReason)
 }
 
 func main() {
 	workspace := flag.String("workspace", "/Users/tangjianyin/Downloads/code/openclaw", "workspace absolute path for evaluation tasks")
 	reportDir := flag.String("report-dir", "", "output directory for reports (default: ~/.redeven/ai/evals/<timestamp>)")
-	taskSpecPath := flag.String("task-spec", filepath.Clean("eval/tasks/default.yaml"), "task specification yaml path")
+	reportHTML := flag.String("report-html", "report.html", "HTML report path, relative to the report dir unless absolute (empty disables)")
+	taskSpecPath := flag.String("task-spec", filepath.Clean("eval/tasks/default.yaml"), "task specification yaml files or directories, comma-separated")
 	baselinePath := flag.String("baseline", filepath.Clean("eval/baselines/open_source_best.json"), "behavioral benchmark baseline json path")
 	enforceGate := flag.Bool("enforce-gate", false, "enforce hard gate against configured baselines")
 	minPassRate := flag.Float64("min-pass-rate", 0.8, "hard gate minimum pass rate")
 	minLoopSafetyRate := flag.Float64("min-loop-safety-rate", 0.95, "hard gate minimum loop safety rate")
 	minFallbackFreeRate := flag.Float64("min-fallback-free-rate", 0.98, "hard gate minimum fallback-free rate")
 	minAverageAccuracy := flag.Float64("min-accuracy", 80, "hard gate minimum average accuracy")
+	resumeDir := flag.String("resume", "", "report dir of an interrupted run; finished tasks are reloaded from its state dir")
+	concurrency := flag.Int("concurrency", 1, "number of tasks evaluated in parallel (keep low to respect provider rate limits)")
+	promptProfile := flag.String("prompt-profile", "", "system prompt profile applied to every task, overriding runtime.prompt_profile (empty keeps the task spec value)")
+	suiteSeed := flag.Int64("seed", defaultEvalSeed, "provider sampling seed; each task runs with a fixed seed derived from it (0 disables)")
+	recordPath := flag.String("record", "", "JSONL path capturing every provider turn for offline replay with a \"replay\" provider (empty disables)")
+	trials := flag.Int("trials", 1, "number of times each task runs; reports the mean and standard deviation of its scores")
 	flag.Parse()
 
+	if *trials < 1 {
+		fatalf("-trials must be at least 1")
+	}
+
 	workspacePath := strings.TrimSpace(*workspace)
 	if workspacePath == "" || !filepath.IsAbs(workspacePath) {
 		fatalf("workspace must be an absolute path")
@@ -340,6 +321,17 @@ func main() {
 
 	timestamp := time.Now().Format("20060102-150405")
 	outDir := strings.TrimSpace(*reportDir)
+	resuming := strings.TrimSpace(*resumeDir) != ""
+	if resuming {
+		resumePath := filepath.Clean(strings.TrimSpace(*resumeDir))
+		if outDir != "" && filepath.Clean(outDir) != resumePath {
+			fatalf("-resume and -report-dir point to different directories")
+		}
+		if st, err := os.Stat(resumePath); err != nil || !st.IsDir() {
+			fatalf("resume dir does not exist or is not a directory: %s", resumePath)
+		}
+		outDir = resumePath
+	}
 	if outDir == "" {
 		outDir = filepath.Join(cfgLayout.StateDir, "ai", "evals", timestamp)
 	}
@@ -360,20 +352,72 @@ func main() {
 	if loadErr != nil {
 		fatalf("failed to load task specs: %v", loadErr)
 	}
+	if err := overrideTaskPromptProfile(tasks, *promptProfile); err != nil {
+		fatalf("invalid -prompt-profile: %v", err)
+	}
+
+	completed := map[string]taskResult{}
+	if resuming {
+		completed, err = loadCompletedTaskResults(stateDir, tasks)
+		if err != nil {
+			fatalf("failed to load finished tasks from %s: %v", stateDir, err)
+		}
+		fmt.Printf("[ai-loop-eval] resume: %d/%d tasks already finished\n", len(completed), len(tasks))
+	}
+
+	var recorder *ai.ProviderRecorder
+	if path := strings.TrimSpace(*recordPath); path != "" {
+		recorder, err = ai.NewProviderRecorder(path)
+		if err != nil {
+			fatalf("failed to open -record file: %v", err)
+		}
+		fmt.Printf("[ai-loop-eval] recording provider turns to %s\n", path)
+	}
 
 	stageMetrics := make(map[string]suiteMetrics)
 	fmt.Printf("[ai-loop-eval] model=%s tasks=%d workspace=%s\n", modelID, len(tasks), workspacePath)
 
 	ctx := context.Background()
-	results := make([]taskResult, 0, len(tasks))
-	for i, task := range tasks {
+	var printMu sync.Mutex
+	results := runTasksConcurrently(ctx, tasks, *concurrency, func(taskCtx context.Context, i int, task evalTask) taskResult {
+		if res, ok := completed[task.ID]; ok && resultTrialCount(res) == *trials {
+			printMu.Lock()
+			fmt.Printf("[task] (%d/%d) %s (resumed)\n", i+1, len(tasks), task.ID)
+			printMu.Unlock()
+			return res
+		}
+		printMu.Lock()
 		fmt.Printf("[task] (%d/%d) %s\n", i+1, len(tasks), task.ID)
-		res := runTask(ctx, cfg.AI, resolver, modelID, workspacePath, materializedWorkspaceRoot, stateDir, task)
-		results = append(results, res)
-		fmt.Printf("  - score=%.2f acc=%.2f nat=%.2f eff=%.2f pass=%t\n", res.Score.Overall, res.Score.Accuracy, res.Score.Natural, res.Score.Efficiency, res.Outcome.Passed)
+		printMu.Unlock()
+		trialResults := make([]taskResult, 0, *trials)
+		for trial := 1; trial <= *trials; trial++ {
+			res := runTask(taskCtx, cfg.AI, resolver, recorder, modelID, workspacePath, materializedWorkspaceRoot, stateDir, task, trial, evalTrialSeed(*suiteSeed, task.ID, trial))
+			trialResults = append(trialResults, res)
+			if *trials > 1 {
+				printMu.Lock()
+				fmt.Printf("  - %s trial %d/%d score=%.2f pass=%t\n", task.ID, trial, *trials, res.Score.Overall, res.Outcome.Passed)
+				printMu.Unlock()
+			}
+		}
+		res := mergeTrialResults(trialResults)
+		saveErr := saveTaskResult(stateDir, res)
+		printMu.Lock()
+		if saveErr != nil {
+			fmt.Printf("  - %s checkpoint failed: %v\n", task.ID, saveErr)
+		}
+		fmt.Printf("  - %s score=%.2f acc=%.2f nat=%.2f eff=%.2f pass=%t\n", task.ID, res.Score.Overall, res.Score.Accuracy, res.Score.Natural, res.Score.Efficiency, res.Outcome.Passed)
+		if stats := res.ScoreStats; stats != nil {
+			fmt.Printf("  - %s trials=%d mean=%.2f stddev=%.2f pass_rate=%.2f\n", task.ID, stats.Trials, stats.Mean.Overall, stats.Stddev.Overall, stats.PassRate)
+		}
+		printMu.Unlock()
+		return res
+	})
+	if err := recorder.Close(); err != nil {
+		fatalf("failed to close -record file: %v", err)
 	}
 
 	metrics := aggregateSuiteMetrics(results)
+	noise := computeSuiteNoise(results)
 	for _, stage := range []string{"screen", "deep"} {
 		stageResults := filterTaskResultsByStage(results, stage)
 		if len(stageResults) == 0 {
@@ -403,7 +447,7 @@ func main() {
 			}
 			fmt.Printf("[ai-loop-eval] baseline skipped: %v\n", loadErr)
 		} else {
-			gate = evaluateGate(metrics, baselines, thresholds)
+			gate = evaluateGate(metrics, baselines, thresholds, noise)
 			gate.BaselinePath = filepath.Clean(baseline)
 		}
 	}
@@ -415,6 +459,9 @@ func main() {
 		SourceWorkspacePath:      workspacePath,
 		MaterializedWorkspaceDir: materializedWorkspaceRoot,
 		TaskCount:                len(results),
+		Seed:                     *suiteSeed,
+		Trials:                   *trials,
+		Noise:                    noise,
 		Results:                  results,
 		Metrics:                  metrics,
 		StageMetrics:             stageMetrics,
@@ -429,6 +476,14 @@ func main() {
 	if err := writeMarkdown(mdPath, report); err != nil {
 		fatalf("failed to write report.md: %v", err)
 	}
+	if htmlPath := strings.TrimSpace(*reportHTML); htmlPath != "" {
+		if !filepath.IsAbs(htmlPath) {
+			htmlPath = filepath.Join(outDir, htmlPath)
+		}
+		if err := writeHTML(htmlPath, report); err != nil {
+			fatalf("failed to write %s: %v", filepath.Base(htmlPath), err)
+		}
+	}
 
 	fmt.Printf("[ai-loop-eval] suite pass_rate=%.2f loop_safe=%.2f accuracy=%.2f\n", metrics.PassRate, metrics.LoopSafetyRate, metrics.AverageAccuracy)
 	fmt.Printf("[ai-loop-eval] report dir: %s\n", outDir)
@@ -437,6 +492,9 @@ func main() {
 		if len(gate.Reasons) > 0 {
 			fmt.Printf("[ai-loop-eval] gate reasons: %s\n", strings.Join(gate.Reasons, "; "))
 		}
+		if gate.WithinNoise {
+			fmt.Printf("[ai-loop-eval] gate decision is within noise: %s\n", strings.Join(gate.NoiseNotes, "; "))
+		}
 	}
 
 	if *enforceGate {
@@ -449,17 +507,71 @@ func main() {
 	}
 }
 
+// defaultEvalSeed keeps eval runs reproducible unless -seed overrides it.
+const defaultEvalSeed = 20240601
+
+// evalTaskSeed derives the provider seed of one task from the suite seed.
+//
+// Seeds depend only on the suite seed and the task ID, so re-running a task, alone or in a different suite,
+// samples with the same seed. The result fits in 31 bits, which every seeded provider accepts.
+func evalTaskSeed(suiteSeed int64, taskID string) *int64 {
+	if suiteSeed == 0 {
+		return nil
+	}
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%d:%s", suiteSeed, strings.TrimSpace(taskID))
+	seed := int64(h.Sum64() & math.MaxInt32)
+	return &seed
+}
+
+// runTasksConcurrently evaluates tasks in a worker pool of at most concurrency workers.
+//
+// Each task gets its own context, and results keep the task order regardless of completion order,
+// so aggregated metrics do not depend on the concurrency setting.
+func runTasksConcurrently(ctx context.Context, tasks []evalTask, concurrency int, runOne func(ctx context.Context, index int, task evalTask) taskResult) []taskResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(tasks) {
+		concurrency = len(tasks)
+	}
+	results := make([]taskResult, len(tasks))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				taskCtx, cancel := context.WithCancel(ctx)
+				results[i] = runOne(taskCtx, i, tasks[i])
+				cancel()
+			}
+		}()
+	}
+	for i := range tasks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
 func runTask(
 	ctx context.Context,
 	aiCfg *config.AIConfig,
 	resolveProviderAPIKey evalProviderKeyResolver,
+	recorder *ai.ProviderRecorder,
 	modelID string,
 	sourceWorkspace string,
 	taskWorkspaceRoot string,
 	taskStateRoot string,
 	task evalTask,
+	trial int,
+	seed *int64,
 ) taskResult {
-	sandbox, err := prepareTaskSandbox(taskWorkspaceRoot, taskStateRoot, task.ID, sourceWorkspace, task.Runtime.Workspace)
+	trialKey := evalTrialKey(task.ID, trial)
+	sandbox, err := prepareTaskSandbox(taskWorkspaceRoot, taskStateRoot, trialKey, sourceWorkspace, task.Runtime.Workspace)
 	inputs := renderTaskTurns(task.Turns, sandbox.WorkspacePath)
 	if err != nil {
 		return failedTaskResult(task, sourceWorkspace, sandbox, inputs, "prepare_task_workspace_failed", err)
@@ -468,9 +580,14 @@ func runTask(
 	runOptions := ai.RunOptions{
 		MaxSteps:                         task.Runtime.MaxSteps,
 		MaxNoToolRounds:                  task.Runtime.MaxNoToolRounds,
+		LoopProfile:                      task.Runtime.LoopProfile,
+		PromptProfile:                    task.Runtime.PromptProfile,
 		ReasoningOnly:                    task.Runtime.ReasoningOnly,
 		RequireUserConfirmOnTaskComplete: task.Runtime.RequireUserConfirmOnTaskComplete,
 		NoUserInteraction:                task.Runtime.NoUserInteraction,
+		AllowParallelToolCalls:           task.Runtime.AllowParallelToolCalls,
+		ForceIntent:                      task.Runtime.ForceIntent,
+		Seed:                             seed,
 	}
 	if sandbox.WorkspaceMode == taskWorkspaceModeSourceReadonly {
 		runOptions.ToolAllowlist = evalReadonlyToolAllowlist()
@@ -487,13 +604,15 @@ func runTask(
 		ToolApprovalTimeout:   20 * time.Second,
 		PersistOpTimeout:      10 * time.Second,
 		ResolveProviderAPIKey: resolveProviderAPIKey,
+		ProviderRecorder:      recorder,
+		ProviderSession:       trialKey,
 	})
 	if err != nil {
 		return failedTaskResult(task, sourceWorkspace, sandbox, inputs, "init_task_service_failed", err)
 	}
 	defer func() { _ = svc.Close() }()
 
-	channelID := sanitizeID("ch_eval_" + task.ID)
+	channelID := sanitizeID("ch_eval_" + trialKey)
 	meta := &session.Meta{
 		EndpointID:        "env_ai_loop_eval",
 		NamespacePublicID: "ns_ai_loop_eval",
@@ -505,28 +624,48 @@ func runTask(
 		CanExecute:        true,
 		CanAdmin:          false,
 	}
-	thread, err := svc.CreateThread(ctx, meta, "eval-"+task.ID, modelID, task.Runtime.ExecutionMode, sandbox.WorkspacePath)
+	thread, err := svc.CreateThread(ctx, meta, "eval-"+trialKey, modelID, task.Runtime.ExecutionMode, sandbox.WorkspacePath)
 	if err != nil {
 		return failedTaskResult(task, sourceWorkspace, sandbox, inputs, "create_thread_failed", err)
 	}
 
 	turns := make([]turnMetrics, 0, len(inputs))
+	var turnScores []turnScore
 	eventCounts := make(map[string]int)
 	finalizationReasons := make([]string, 0, len(inputs))
 	started := time.Now()
-
-	for _, turnText := range inputs {
+	taskCtx, cancelTask := context.WithCancel(ctx)
+	if task.Runtime.TimeoutTotal > 0 {
+		taskCtx, cancelTask = context.WithTimeout(ctx, task.Runtime.TimeoutTotal)
+	}
+	defer cancelTask()
+	taskTimedOut := false
+	skippedTurns := 0
+
+	for turnIndex, turnText := range inputs {
+		if taskCtx.Err() != nil {
+			// The task budget is spent: the remaining turns are not run and fail their assertions.
+			taskTimedOut = true
+			skippedTurns++
+			if task.TurnAssertions != nil {
+				turnScores = append(turnScores, scoreTurnOutput(turnIndex+1, task.TurnAssertions[turnIndex], ""))
+			}
+			continue
+		}
 		runID, ridErr := ai.NewRunID()
 		if ridErr != nil {
 			turns = append(turns, turnMetrics{RunError: ridErr.Error()})
+			if task.TurnAssertions != nil {
+				turnScores = append(turnScores, scoreTurnOutput(turnIndex+1, task.TurnAssertions[turnIndex], ""))
+			}
 			continue
 		}
 		timeout := task.Runtime.TimeoutPerTurn
 		if timeout <= 0 {
 			timeout = 90 * time.Second
 		}
-		runCtx, cancel := context.WithTimeout(ctx, timeout)
-		monitor := newStreamMonitor(svc, meta, runID, runCtx, cancel)
+		runCtx, cancel := context.WithTimeout(taskCtx, timeout)
+		monitor := newStreamMonitor(svc, meta, runID, runCtx)
 		writer := &monitoredResponseWriter{monitor: monitor}
 
 		oneStart := time.Now()
@@ -537,42 +676,30 @@ func runTask(
 			Options:  runOptions,
 		}, writer)
 		dur := time.Since(oneStart)
+		timeoutKind := turnTimeoutKind(runCtx, taskCtx)
 		cancel()
+		if timeoutKind == taskTimeoutReason {
+			taskTimedOut = true
+		}
 
-		metrics := turnMetrics{RunID: runID, Duration: dur, DurationMS: dur.Milliseconds(), MonitorAbort: monitor.abortState()}
+		metrics := turnMetrics{RunID: runID, Duration: dur, DurationMS: dur.Milliseconds(), Timeout: timeoutKind}
 		if runErr != nil {
 			metrics.RunError = runErr.Error()
 		}
 		reasonFlow := make([]string, 0, 12)
+		snapshot, snapErr := svc.RunMetricsSnapshot(context.Background(), meta, runID)
+		if snapErr == nil {
+			applyRunMetricsSnapshot(&metrics, snapshot)
+			metrics.MonitorAbort = runtimeGuardAbort(snapshot.FinalizationReason)
+			reasonFlow = append(reasonFlow, snapshot.ContinueReasons...)
+		}
 		events, evErr := svc.ListRunEvents(context.Background(), meta, runID, 2000)
 		if evErr == nil {
 			for _, ev := range events.Events {
 				eventType := normalizeName(ev.EventType)
 				eventCounts[eventType] = eventCounts[eventType] + 1
-				switch eventType {
-				case "turn.attempt.started":
-					metrics.AttemptCount++
-				case "tool.call":
-					metrics.ToolCallCount++
-				case "tool.error":
-					metrics.ToolErrorCount++
-				case "turn.recovery.triggered":
-					metrics.RecoveryCount++
-				case "turn.completion.continue":
-					metrics.CompletionRetrys++
-					if reason := extractReasonFromPayload(ev.Payload); reason != "" {
-						reasonFlow = append(reasonFlow, "completion:"+reason)
-					}
-				case "task.loop.continue":
-					metrics.TaskLoopContinue++
-					if reason := extractReasonFromPayload(ev.Payload); reason != "" {
-						reasonFlow = append(reasonFlow, "task:"+reason)
-					}
-				case "turn.loop.exhausted":
-					metrics.LoopExhausted = true
-				case "run.end":
-					metrics.FinalizationReason = payloadFieldString(ev.Payload, "finalization_reason")
-					metrics.EndState = payloadFieldString(ev.Payload, "state")
+				if snapErr != nil {
+					reasonFlow = applyLegacyRunEvent(&metrics, reasonFlow, eventType, ev.Payload)
 				}
 			}
 		}
@@ -588,6 +715,10 @@ func runTask(
 			finalizationReasons = append(finalizationReasons, strings.TrimSpace(metrics.FinalizationReason))
 		}
 		turns = append(turns, metrics)
+		if task.TurnAssertions != nil {
+			turnText := extractLatestAssistantText(ctx, svc, meta, thread.ThreadID)
+			turnScores = append(turnScores, scoreTurnOutput(turnIndex+1, task.TurnAssertions[turnIndex], turnText))
+		}
 	}
 
 	threadView, _ := svc.GetThread(context.Background(), meta, thread.ThreadID)
@@ -610,18 +741,23 @@ func runTask(
 		Task:                task,
 		Inputs:              inputs,
 		Turns:               turns,
+		TurnScores:          turnScores,
 		FinalText:           finalText,
 		DurationTotalMS:     totalDur.Milliseconds(),
+		TaskTimeout:         taskTimedOut,
+		SkippedTurns:        skippedTurns,
 		SourceWorkspacePath: sourceWorkspace,
 		WorkspacePath:       sandbox.WorkspacePath,
 		WorkspaceMode:       sandbox.WorkspaceMode,
 		WorkspaceSeed:       sandbox.WorkspaceSeed,
+		ProviderSeed:        seed,
 		ThreadState:         summarizeThreadState(threadView),
 		ToolCalls:           summarizeToolCalls(toolCalls),
 		TodoSnapshot:        buildTodoSnapshotSummary(todoView),
 		EventCounts:         eventCounts,
 		FinalizationReasons: uniqueStrings(finalizationReasons),
 		EvidencePaths:       extractEvidencePaths(finalText, sandbox.WorkspacePath),
+		MissingEvidence:     missingEvidenceRequirements(task.Assertions.Output, finalText),
 		rawThread:           threadView,
 		rawTodos:            todoView,
 		rawToolCalls:        toolCalls,
@@ -631,6 +767,69 @@ func runTask(
 	return result
 }
 
+const (
+	turnTimeoutReason = "turn_timeout"
+	taskTimeoutReason = "task_timeout"
+)
+
+// turnTimeoutKind reports which deadline ended a turn: the turn's own timeout or the task's total timeout.
+func turnTimeoutKind(runCtx context.Context, taskCtx context.Context) string {
+	if !errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return ""
+	}
+	if errors.Is(taskCtx.Err(), context.DeadlineExceeded) {
+		return taskTimeoutReason
+	}
+	return turnTimeoutReason
+}
+
+// applyRunMetricsSnapshot copies the runtime's typed run metrics into the eval turn metrics.
+func applyRunMetricsSnapshot(metrics *turnMetrics, snapshot *ai.RunMetricsSnapshot) {
+	if metrics == nil || snapshot == nil {
+		return
+	}
+	metrics.AttemptCount = snapshot.AttemptCount
+	metrics.ToolCallCount = int(snapshot.ToolCallCount)
+	metrics.ToolErrorCount = snapshot.ToolErrorCount
+	metrics.RecoveryCount = snapshot.RecoveryCount
+	metrics.CompletionRetrys = snapshot.CompletionContinues
+	metrics.TaskLoopContinue = snapshot.TaskLoopContinues
+	metrics.LoopExhausted = snapshot.LoopExhausted
+	metrics.FinalizationReason = snapshot.FinalizationReason
+	metrics.EndState = snapshot.EndState
+}
+
+// applyLegacyRunEvent derives turn metrics from event names for runs recorded before run.metrics existed.
+func applyLegacyRunEvent(metrics *turnMetrics, reasonFlow []string, eventType string, payload any) []string {
+	switch eventType {
+	case "turn.attempt.started":
+		metrics.AttemptCount++
+	case "tool.call":
+		metrics.ToolCallCount++
+	case "tool.error":
+		metrics.ToolErrorCount++
+	case "turn.recovery.triggered":
+		metrics.RecoveryCount++
+	case "turn.completion.continue":
+		metrics.CompletionRetrys++
+		if reason := extractReasonFromPayload(payload); reason != "" {
+			reasonFlow = append(reasonFlow, "completion:"+reason)
+		}
+	case "task.loop.continue":
+		metrics.TaskLoopContinue++
+		if reason := extractReasonFromPayload(payload); reason != "" {
+			reasonFlow = append(reasonFlow, "task:"+reason)
+		}
+	case "turn.loop.exhausted":
+		metrics.LoopExhausted = true
+	case "run.end":
+		metrics.FinalizationReason = payloadFieldString(payload, "finalization_reason")
+		metrics.MonitorAbort = runtimeGuardAbort(metrics.FinalizationReason)
+		metrics.EndState = payloadFieldString(payload, "state")
+	}
+	return reasonFlow
+}
+
 func evalReadonlyToolAllowlist() []string {
 	return []string{
 		"ask_user",
@@ -663,6 +862,32 @@ func failedTaskResult(task evalTask, sourceWorkspace string, sandbox evalTaskSan
 	}
 }
 
+// scoreTurnOutput checks one turn's assistant reply against that turn's must_contain and forbidden lists.
+// Accuracy uses the same deductions as the task-level output assertions.
+func scoreTurnOutput(turn int, checks evalTurnAssertions, text string) turnScore {
+	lower := strings.ToLower(text)
+	score := turnScore{Turn: turn}
+	for _, must := range checks.MustContain {
+		if !matchesRequirement(lower, must) {
+			score.MissingMust = append(score.MissingMust, must)
+		}
+	}
+	for _, ban := range checks.Forbidden {
+		if strings.Contains(lower, strings.ToLower(ban)) {
+			score.ForbiddenHits = append(score.ForbiddenHits, ban)
+		}
+	}
+	score.Passed = len(score.MissingMust) == 0 && len(score.ForbiddenHits) == 0
+	score.Accuracy = clampScore(100 - float64(len(score.MissingMust))*12 - float64(len(score.ForbiddenHits))*35)
+	if txt := strings.TrimSpace(text); txt != "" {
+		if utf8.RuneCountInString(txt) > 160 {
+			txt = string([]rune(txt)[:160]) + "..."
+		}
+		score.TextPreview = strings.ReplaceAll(txt, "\n", " ")
+	}
+	return score
+}
+
 func evaluateScore(task evalTask, result taskResult, outcome taskOutcome) scoreBreakdown {
 	accuracy := 100.0
 	natural := 100.0
@@ -681,12 +906,20 @@ func evaluateScore(task evalTask, result taskResult, outcome taskOutcome) scoreB
 			natural -= 20
 		}
 	}
+	for _, turn := range result.TurnScores {
+		accuracy -= float64(len(turn.MissingMust)) * 12
+		accuracy -= float64(len(turn.ForbiddenHits)) * 35
+		natural -= float64(len(turn.ForbiddenHits)) * 20
+	}
 	if output.RequireEvidence && len(result.EvidencePaths) == 0 {
 		accuracy -= 28
 	}
 	if output.MinEvidencePaths > 0 && len(result.EvidencePaths) < output.MinEvidencePaths {
 		accuracy -= 18
 	}
+	if len(result.MissingEvidence) > 0 {
+		accuracy -= math.Min(45, float64(len(result.MissingEvidence))*15)
+	}
 	if output.MinLength > 0 && utf8.RuneCountInString(strings.TrimSpace(result.FinalText)) < output.MinLength {
 		accuracy -= 18
 		natural -= 12
@@ -695,16 +928,13 @@ func evaluateScore(task evalTask, result taskResult, outcome taskOutcome) scoreB
 		accuracy -= 12
 		natural -= 15
 	}
-	if looksPreambleOnly(result.FinalText) {
+	if ai.LooksPreambleOnly(result.FinalText) {
 		natural -= 35
 	}
-	for _, phrase := range fallbackFinalPhrases {
-		if strings.Contains(lower, phrase) {
-			accuracy -= 40
-			natural -= 25
-			efficiency -= 20
-			break
-		}
+	if _, _, ok := fallbackFinalPhrases.Match(lower); ok {
+		accuracy -= 40
+		natural -= 25
+		efficiency -= 20
 	}
 	natural -= float64(repetitionPenalty(result.FinalText))
 
@@ -736,6 +966,9 @@ func evaluateScore(task evalTask, result taskResult, outcome taskOutcome) scoreB
 			efficiency -= 18
 		}
 	}
+	if result.TaskTimeout {
+		efficiency -= 40
+	}
 
 	if len(outcome.HardFailReasons) > 0 {
 		accuracy -= math.Min(56, float64(len(outcome.HardFailReasons))*8)
@@ -1000,6 +1233,24 @@ func matchesRequirement(text string, requirement string) bool {
 	return false
 }
 
+// missingEvidenceRequirements reports each task-specific evidence requirement the final text does not satisfy,
+// as "evidence_path:<path>" or "evidence_regex:<pattern>".
+func missingEvidenceRequirements(output taskOutputAssertions, text string) []string {
+	var missing []string
+	for _, path := range output.EvidencePaths {
+		if !strings.Contains(text, path) {
+			missing = append(missing, "evidence_path:"+path)
+		}
+	}
+	for _, pattern := range output.EvidenceRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil || !re.MatchString(text) {
+			missing = append(missing, "evidence_regex:"+pattern)
+		}
+	}
+	return missing
+}
+
 func containsEvidencePath(text string, workspacePath string) bool {
 	if len(extractEvidencePaths(text, workspacePath)) > 0 {
 		return true
@@ -1018,34 +1269,6 @@ func containsEvidencePath(text string, workspacePath string) bool {
 	return false
 }
 
-func looksPreambleOnly(text string) bool {
-	trimmed := strings.TrimSpace(strings.ToLower(text))
-	if trimmed == "" {
-		return true
-	}
-	if utf8.RuneCountInString(trimmed) > 180 {
-		return false
-	}
-	preambleHints := []string{"let me", "i will", "first i", "i'll first", "quick scan", "first pass"}
-	hasPreamble := false
-	for _, hint := range preambleHints {
-		if strings.Contains(trimmed, hint) {
-			hasPreamble = true
-			break
-		}
-	}
-	if !hasPreamble {
-		return false
-	}
-	finalHints := []string{"final", "result", "directory", "conclusion", "recommendation", "risk"}
-	for _, hint := range finalHints {
-		if strings.Contains(trimmed, hint) {
-			return false
-		}
-	}
-	return true
-}
-
 func repetitionPenalty(text string) int {
 	clean := normalizeText(text)
 	if clean == "" {
@@ -1117,17 +1340,6 @@ func anyToBool(v any) bool {
 	}
 }
 
-func compactJSON(v any) string {
-	if v == nil {
-		return "{}"
-	}
-	b, err := json.Marshal(v)
-	if err != nil {
-		return "{}"
-	}
-	return string(b)
-}
-
 func normalizeText(text string) string {
 	trimmed := strings.TrimSpace(text)
 	if trimmed == "" {
@@ -1243,6 +1455,12 @@ func writeMarkdown(path string, report evalReport) error {
 	b.WriteString(fmt.Sprintf("- Source workspace: `%s`\n", report.SourceWorkspacePath))
 	b.WriteString(fmt.Sprintf("- Materialized task workspaces: `%s`\n", report.MaterializedWorkspaceDir))
 	b.WriteString(fmt.Sprintf("- Tasks: %d\n", report.TaskCount))
+	if report.Seed != 0 {
+		b.WriteString(fmt.Sprintf("- Suite seed: %d\n", report.Seed))
+	}
+	if report.Trials > 1 {
+		b.WriteString(fmt.Sprintf("- Trials per task: %d\n", report.Trials))
+	}
 
 	b.WriteString("\n## Suite Metrics\n\n")
 	b.WriteString(fmt.Sprintf("- Pass rate: %.2f\n", report.Metrics.PassRate))
@@ -1251,6 +1469,14 @@ func writeMarkdown(path string, report evalReport) error {
 	b.WriteString(fmt.Sprintf("- Fallback-free rate: %.2f\n", report.Metrics.FallbackFreeRate))
 	b.WriteString(fmt.Sprintf("- Average accuracy: %.2f\n", report.Metrics.AverageAccuracy))
 	b.WriteString(fmt.Sprintf("- Average overall: %.2f\n", report.Metrics.AverageOverall))
+	if noise := report.Noise; noise != nil {
+		b.WriteString(fmt.Sprintf("- Trial spread (%d trials): pass rate %.2f ± %.2f, accuracy %.2f ± %.2f, overall %.2f ± %.2f\n",
+			noise.Trials,
+			noise.PassRateMean, noise.PassRateStddev,
+			noise.AccuracyMean, noise.AccuracyStddev,
+			noise.OverallMean, noise.OverallStddev,
+		))
+	}
 
 	if len(report.StageMetrics) > 0 {
 		b.WriteString("\n## Stage Metrics\n\n")
@@ -1285,12 +1511,22 @@ func writeMarkdown(path string, report evalReport) error {
 		if len(report.Gate.Reasons) > 0 {
 			b.WriteString("- Reasons: " + strings.Join(report.Gate.Reasons, "; ") + "\n")
 		}
+		if report.Gate.WithinNoise {
+			b.WriteString("- Within noise: the decision may flip on a re-run. " + strings.Join(report.Gate.NoiseNotes, "; ") + "\n")
+		}
 	}
 
 	b.WriteString("\n## Task Results\n\n")
 	for _, result := range report.Results {
 		b.WriteString(fmt.Sprintf("### %s\n\n", result.Task.ID))
 		b.WriteString(fmt.Sprintf("- Score: %.2f (acc %.2f / nat %.2f / eff %.2f)\n", result.Score.Overall, result.Score.Accuracy, result.Score.Natural, result.Score.Efficiency))
+		if stats := result.ScoreStats; stats != nil {
+			overall := make([]string, 0, len(result.Trials))
+			for _, trial := range result.Trials {
+				overall = append(overall, fmt.Sprintf("%.2f", trial.Score.Overall))
+			}
+			b.WriteString(fmt.Sprintf("- Trials: %d, overall mean %.2f ± %.2f, pass rate %.2f (scores %s)\n", stats.Trials, stats.Mean.Overall, stats.Stddev.Overall, stats.PassRate, strings.Join(overall, ", ")))
+		}
 		b.WriteString(fmt.Sprintf("- Outcome: passed=%t loop_safe=%t fallback=%t recovery_candidate=%t recovery_succeeded=%t\n",
 			result.Outcome.Passed,
 			result.Outcome.LoopSafe,
@@ -1307,7 +1543,13 @@ func writeMarkdown(path string, report evalReport) error {
 		if seed := strings.TrimSpace(result.WorkspaceSeed); seed != "" {
 			b.WriteString(fmt.Sprintf("- Workspace seed: `%s`\n", seed))
 		}
+		if result.ProviderSeed != nil {
+			b.WriteString(fmt.Sprintf("- Provider seed: %d\n", *result.ProviderSeed))
+		}
 		b.WriteString(fmt.Sprintf("- Tool calls: %d\n", len(result.ToolCalls)))
+		if result.TaskTimeout {
+			b.WriteString(fmt.Sprintf("- Task timeout: exceeded %ds, skipped turns=%d\n", result.Task.Runtime.TimeoutTotalSeconds, result.SkippedTurns))
+		}
 		if result.TodoSnapshot != nil {
 			b.WriteString(fmt.Sprintf("- Todos: total=%d pending=%d in_progress=%d completed=%d cancelled=%d\n",
 				result.TodoSnapshot.Total,
@@ -1317,9 +1559,26 @@ func writeMarkdown(path string, report evalReport) error {
 				result.TodoSnapshot.Cancelled,
 			))
 		}
+		for _, turn := range result.TurnScores {
+			status := "pass"
+			if !turn.Passed {
+				status = "fail"
+			}
+			line := fmt.Sprintf("- Turn %d: %s (acc %.2f)", turn.Turn, status, turn.Accuracy)
+			if len(turn.MissingMust) > 0 {
+				line += " missing=" + strings.Join(turn.MissingMust, ", ")
+			}
+			if len(turn.ForbiddenHits) > 0 {
+				line += " forbidden=" + strings.Join(turn.ForbiddenHits, ", ")
+			}
+			b.WriteString(line + "\n")
+		}
 		if len(result.EvidencePaths) > 0 {
 			b.WriteString("- Evidence paths: " + strings.Join(result.EvidencePaths, ", ") + "\n")
 		}
+		if len(result.MissingEvidence) > 0 {
+			b.WriteString("- Missing evidence: " + strings.Join(result.MissingEvidence, ", ") + "\n")
+		}
 		if len(result.Outcome.HardFailReasons) > 0 {
 			b.WriteString("- Hard fail reasons: " + strings.Join(result.Outcome.HardFailReasons, ", ") + "\n")
 		}
@@ -1,6 +1,13 @@
 package main
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestMatchesRequirement_WithAlternatives(t *testing.T) {
 	t.Parallel()
@@ -30,6 +37,44 @@ func TestExtractEvidencePaths_FiltersToWorkspace(t *testing.T) {
 	}
 }
 
+func TestMissingEvidenceRequirements_PenalizesAccuracy(t *testing.T) {
+	t.Parallel()
+
+	output := taskOutputAssertions{
+		EvidencePaths: []string{"internal/ai/run.go", "cmd/app/main.go"},
+		EvidenceRegex: []string{`run\.go:\d+`, `(?i)retry policy`},
+	}
+	text := "The loop lives in internal/ai/run.go:120 and backs off with the Retry Policy described there, which is long enough."
+	missing := missingEvidenceRequirements(output, text)
+	if len(missing) != 1 || missing[0] != "evidence_path:cmd/app/main.go" {
+		t.Fatalf("missing=%v", missing)
+	}
+	if got := missingEvidenceRequirements(taskOutputAssertions{EvidenceRegex: []string{`\bTODO\b`}}, text); len(got) != 1 || got[0] != `evidence_regex:\bTODO\b` {
+		t.Fatalf("missing regex=%v", got)
+	}
+
+	task := evalTask{Assertions: taskAssertionsSpec{Output: output}}
+	complete := evaluateScore(task, taskResult{FinalText: text}, taskOutcome{})
+	incomplete := evaluateScore(task, taskResult{FinalText: text, MissingEvidence: missing}, taskOutcome{})
+	if incomplete.Accuracy >= complete.Accuracy || incomplete.Natural != complete.Natural {
+		t.Fatalf("complete=%+v incomplete=%+v, want an accuracy-only penalty", complete, incomplete)
+	}
+}
+
+func TestRuntimeGuardAbort_MapsFinalizationReasons(t *testing.T) {
+	t.Parallel()
+
+	if got := runtimeGuardAbort("repeated_delta_guard"); got != "repeated_delta" {
+		t.Fatalf("repeated_delta_guard -> %q", got)
+	}
+	if got := runtimeGuardAbort("tool_signature_guard"); got != "tool_signature_loop" {
+		t.Fatalf("tool_signature_guard -> %q", got)
+	}
+	if got := runtimeGuardAbort("task_complete"); got != "" {
+		t.Fatalf("task_complete -> %q, want no abort", got)
+	}
+}
+
 func TestRenderTaskTurns_ReplacesWorkspacePlaceholder(t *testing.T) {
 	t.Parallel()
 
@@ -41,3 +86,139 @@ func TestRenderTaskTurns_ReplacesWorkspacePlaceholder(t *testing.T) {
 		t.Fatalf("turns[1]=%q", turns[1])
 	}
 }
+
+func TestRunTasksConcurrently_BoundedAndOrdered(t *testing.T) {
+	t.Parallel()
+
+	tasks := make([]evalTask, 7)
+	for i := range tasks {
+		tasks[i] = evalTask{ID: fmt.Sprintf("task_%d", i)}
+	}
+	for _, concurrency := range []int{0, 1, 3, 20} {
+		var mu sync.Mutex
+		inflight, peak := 0, 0
+		results := runTasksConcurrently(context.Background(), tasks, concurrency, func(ctx context.Context, i int, task evalTask) taskResult {
+			mu.Lock()
+			inflight++
+			peak = max(peak, inflight)
+			mu.Unlock()
+			// Later tasks finish first so out-of-order completion is exercised.
+			time.Sleep(time.Duration(len(tasks)-i) * time.Millisecond)
+			mu.Lock()
+			inflight--
+			mu.Unlock()
+			return taskResult{Task: task, Score: scoreBreakdown{Overall: float64(i)}}
+		})
+		want := max(1, min(concurrency, len(tasks)))
+		if peak > want {
+			t.Fatalf("concurrency=%d: peak=%d, want <= %d", concurrency, peak, want)
+		}
+		for i, res := range results {
+			if res.Task.ID != tasks[i].ID || res.Score.Overall != float64(i) {
+				t.Fatalf("concurrency=%d: results[%d]=%s, want %s", concurrency, i, res.Task.ID, tasks[i].ID)
+			}
+		}
+	}
+}
+
+func TestEvalTaskSeed_IsStablePerTask(t *testing.T) {
+	t.Parallel()
+
+	if seed := evalTaskSeed(0, "repo_overview"); seed != nil {
+		t.Fatalf("seed=%d, want nil when seeding is disabled", *seed)
+	}
+	a := evalTaskSeed(defaultEvalSeed, "repo_overview")
+	b := evalTaskSeed(defaultEvalSeed, " repo_overview ")
+	if a == nil || b == nil || *a != *b {
+		t.Fatalf("seeds for the same task differ: %v %v", a, b)
+	}
+	if *a < 0 || *a > 1<<31-1 {
+		t.Fatalf("seed=%d out of 31-bit range", *a)
+	}
+	if other := evalTaskSeed(defaultEvalSeed, "bug_hunt"); other == nil || *other == *a {
+		t.Fatalf("different tasks should get different seeds: %v", other)
+	}
+	if reseeded := evalTaskSeed(defaultEvalSeed+1, "repo_overview"); reseeded == nil || *reseeded == *a {
+		t.Fatalf("a different suite seed should change the task seed: %v", reseeded)
+	}
+}
+
+func TestScoreTurnOutput_FoldsIntoTaskScore(t *testing.T) {
+	t.Parallel()
+
+	checks := evalTurnAssertions{MustContain: []string{"config", "port"}, Forbidden: []string{"I cannot"}}
+	passed := scoreTurnOutput(1, checks, "The config sets the port to 8080.")
+	if !passed.Passed || passed.Accuracy != 100 {
+		t.Fatalf("passed=%+v", passed)
+	}
+	failed := scoreTurnOutput(2, checks, "I cannot read the config.")
+	if failed.Passed || len(failed.MissingMust) != 1 || failed.MissingMust[0] != "port" || len(failed.ForbiddenHits) != 1 {
+		t.Fatalf("failed=%+v", failed)
+	}
+	if failed.Accuracy != 53 {
+		t.Fatalf("failed accuracy=%v, want 53", failed.Accuracy)
+	}
+
+	text := "The final answer explains the config and the port in enough detail."
+	task := evalTask{Turns: []string{"a", "b"}, TurnAssertions: []evalTurnAssertions{checks, checks}}
+	clean := evaluateScore(task, taskResult{FinalText: text, TurnScores: []turnScore{passed, passed}}, taskOutcome{})
+	dirty := evaluateScore(task, taskResult{FinalText: text, TurnScores: []turnScore{failed, passed}}, taskOutcome{})
+	if dirty.Accuracy >= clean.Accuracy {
+		t.Fatalf("clean=%+v dirty=%+v, want a failing earlier turn to lower accuracy", clean, dirty)
+	}
+
+	outcome := assessTaskOutcome(task, taskResult{FinalText: text, TurnScores: []turnScore{failed, passed}})
+	if outcome.Passed || !strings.Contains(strings.Join(outcome.HardFailReasons, ","), "turn_2_assertions_failed") {
+		t.Fatalf("outcome=%+v", outcome)
+	}
+}
+
+func TestTurnTimeoutKind_DistinguishesTurnAndTaskDeadlines(t *testing.T) {
+	t.Parallel()
+
+	taskCtx, cancelTask := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelTask()
+	turnCtx, cancelTurn := context.WithTimeout(taskCtx, time.Nanosecond)
+	defer cancelTurn()
+	<-turnCtx.Done()
+	if got := turnTimeoutKind(turnCtx, taskCtx); got != turnTimeoutReason {
+		t.Fatalf("turn deadline -> %q, want %q", got, turnTimeoutReason)
+	}
+
+	expiredTask, cancelExpired := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancelExpired()
+	childCtx, cancelChild := context.WithTimeout(expiredTask, time.Hour)
+	defer cancelChild()
+	<-childCtx.Done()
+	if got := turnTimeoutKind(childCtx, expiredTask); got != taskTimeoutReason {
+		t.Fatalf("task deadline -> %q, want %q", got, taskTimeoutReason)
+	}
+
+	if got := turnTimeoutKind(context.Background(), context.Background()); got != "" {
+		t.Fatalf("no deadline -> %q, want empty", got)
+	}
+}
+
+func TestTaskTimeout_FailsOutcomeAndPenalizesEfficiency(t *testing.T) {
+	t.Parallel()
+
+	text := "The final answer explains the config and the port in enough detail."
+	task := evalTask{Turns: []string{"a", "b"}}
+	base := taskResult{FinalText: text, Turns: []turnMetrics{{AttemptCount: 1}}}
+	timedOut := base
+	timedOut.TaskTimeout = true
+	timedOut.SkippedTurns = 1
+
+	if outcome := assessTaskOutcome(task, base); !outcome.Passed {
+		t.Fatalf("base outcome=%+v, want pass", outcome)
+	}
+	outcome := assessTaskOutcome(task, timedOut)
+	if outcome.Passed || !strings.Contains(strings.Join(outcome.HardFailReasons, ","), taskTimeoutReason) {
+		t.Fatalf("timed out outcome=%+v", outcome)
+	}
+	clean := evaluateScore(task, base, taskOutcome{})
+	slow := evaluateScore(task, timedOut, taskOutcome{})
+	if clean.Efficiency-slow.Efficiency != 40 || clean.Accuracy != slow.Accuracy {
+		t.Fatalf("clean=%+v slow=%+v, want an efficiency-only penalty of 40", clean, slow)
+	}
+}
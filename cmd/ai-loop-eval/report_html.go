@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const htmlReportPreviewRunes = 1200
+
+type htmlReportView struct {
+	Title       string
+	GeneratedAt string
+	ModelID     string
+	TaskSpec    string
+	TaskCount   int
+	Metrics     suiteMetrics
+	Gate        gateReport
+	GateRows    []htmlGateRow
+	Tasks       []htmlTaskView
+}
+
+type htmlGateRow struct {
+	Metric    string
+	Value     string
+	Threshold htmlGateCell
+	BestRef   htmlGateCell
+}
+
+type htmlGateCell struct {
+	Target string
+	Class  string
+}
+
+type htmlTaskView struct {
+	Rank            int
+	ID              string
+	Title           string
+	Stage           string
+	Passed          bool
+	LoopSafe        bool
+	Score           scoreBreakdown
+	HardFails       []string
+	MissingEvidence []string
+	Preview         string
+	Turns           []htmlTurnView
+	ToolCalls       int
+	DurationMS      int64
+}
+
+type htmlTurnView struct {
+	Index              int
+	RunID              string
+	AttemptCount       int
+	ToolCallCount      int
+	RecoveryCount      int
+	FinalizationReason string
+	ReasonFlow         []string
+}
+
+// writeHTML writes a self-contained HTML report with a sortable task ranking, the gate decision matrix,
+// and collapsible per-task details. All report text is escaped by html/template.
+func writeHTML(path string, report evalReport) error {
+	if report.TaskCount == 0 {
+		return errors.New("empty report")
+	}
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, buildHTMLReportView(report)); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+func buildHTMLReportView(report evalReport) htmlReportView {
+	view := htmlReportView{
+		Title:       "Flower Behavioral Eval Report",
+		GeneratedAt: report.GeneratedAt.Format(time.RFC3339),
+		ModelID:     report.ModelID,
+		TaskSpec:    report.TaskSpecPath,
+		TaskCount:   report.TaskCount,
+		Metrics:     report.Metrics,
+		Gate:        report.Gate,
+		GateRows:    buildHTMLGateRows(report.Gate),
+		Tasks:       make([]htmlTaskView, 0, len(report.Results)),
+	}
+	for _, result := range report.Results {
+		task := htmlTaskView{
+			ID:              result.Task.ID,
+			Title:           result.Task.Title,
+			Stage:           result.Task.Stage,
+			Passed:          result.Outcome.Passed,
+			LoopSafe:        result.Outcome.LoopSafe,
+			Score:           result.Score,
+			HardFails:       result.Outcome.HardFailReasons,
+			MissingEvidence: result.MissingEvidence,
+			Preview:         htmlReportPreview(result.FinalText),
+			ToolCalls:       len(result.ToolCalls),
+			DurationMS:      result.DurationTotalMS,
+		}
+		for i, turn := range result.Turns {
+			task.Turns = append(task.Turns, htmlTurnView{
+				Index:              i + 1,
+				RunID:              turn.RunID,
+				AttemptCount:       turn.AttemptCount,
+				ToolCallCount:      turn.ToolCallCount,
+				RecoveryCount:      turn.RecoveryCount,
+				FinalizationReason: turn.FinalizationReason,
+				ReasonFlow:         turn.CompletionReasonFlow,
+			})
+		}
+		view.Tasks = append(view.Tasks, task)
+	}
+	sort.SliceStable(view.Tasks, func(i, j int) bool {
+		return view.Tasks[i].Score.Overall > view.Tasks[j].Score.Overall
+	})
+	for i := range view.Tasks {
+		view.Tasks[i].Rank = i + 1
+	}
+	return view
+}
+
+// buildHTMLGateRows lays out each gated metric against its threshold and the best baseline reference.
+func buildHTMLGateRows(gate gateReport) []htmlGateRow {
+	rate := func(v float64) string { return fmt.Sprintf("%.3f", v) }
+	score := func(v float64) string { return fmt.Sprintf("%.2f", v) }
+	cell := func(value float64, target float64, enabled bool, format func(float64) string) htmlGateCell {
+		if !enabled {
+			return htmlGateCell{Target: "-", Class: "skip"}
+		}
+		if value >= target {
+			return htmlGateCell{Target: format(target), Class: "pass"}
+		}
+		return htmlGateCell{Target: format(target), Class: "fail"}
+	}
+	m := gate.Metrics
+	ref := gate.ReferenceBest
+	th := gate.Thresholds
+	on := gate.Enabled
+	return []htmlGateRow{
+		{Metric: "pass_rate", Value: rate(m.PassRate), Threshold: cell(m.PassRate, th.MinPassRate, on, rate), BestRef: cell(m.PassRate, ref.PassRate, on, rate)},
+		{Metric: "loop_safety_rate", Value: rate(m.LoopSafetyRate), Threshold: cell(m.LoopSafetyRate, th.MinLoopSafetyRate, on, rate), BestRef: cell(m.LoopSafetyRate, ref.LoopSafetyRate, on, rate)},
+		{Metric: "recovery_success_rate", Value: rate(m.RecoverySuccessRate), Threshold: htmlGateCell{Target: "-", Class: "skip"}, BestRef: cell(m.RecoverySuccessRate, ref.RecoverySuccessRate, on, rate)},
+		{Metric: "fallback_free_rate", Value: rate(m.FallbackFreeRate), Threshold: cell(m.FallbackFreeRate, th.MinFallbackFreeRate, on, rate), BestRef: cell(m.FallbackFreeRate, ref.FallbackFreeRate, on, rate)},
+		{Metric: "average_accuracy", Value: score(m.AverageAccuracy), Threshold: cell(m.AverageAccuracy, th.MinAverageAccuracy, on, score), BestRef: cell(m.AverageAccuracy, ref.AverageAccuracy, on, score)},
+	}
+}
+
+func htmlReportPreview(text string) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > htmlReportPreviewRunes {
+		return string([]rune(text)[:htmlReportPreviewRunes]) + "..."
+	}
+	return text
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"score": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"rate":  func(v float64) string { return fmt.Sprintf("%.3f", v) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; margin: 24px; color: #1f2328; }
+h1 { font-size: 22px; } h2 { font-size: 18px; margin-top: 28px; }
+table { border-collapse: collapse; margin: 8px 0; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: left; font-size: 13px; }
+th.sortable { cursor: pointer; user-select: none; background: #f6f8fa; }
+th.sortable:hover { background: #eaeef2; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.pass { background: #dafbe1; } .fail { background: #ffebe9; } .skip { background: #f6f8fa; color: #656d76; }
+.meta { color: #656d76; font-size: 13px; }
+details { border: 1px solid #d0d7de; border-radius: 6px; padding: 6px 10px; margin: 8px 0; }
+summary { cursor: pointer; font-weight: 600; }
+pre { white-space: pre-wrap; word-break: break-word; background: #f6f8fa; padding: 8px; font-size: 12px; }
+ol.flow { font-family: ui-monospace, monospace; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Generated {{.GeneratedAt}} · model <code>{{.ModelID}}</code> · spec <code>{{.TaskSpec}}</code> · {{.TaskCount}} tasks</p>
+
+<h2>Gate decision</h2>
+<p>Status: <span class="{{if not .Gate.Enabled}}skip{{else if .Gate.Passed}}pass{{else}}fail{{end}}">{{.Gate.Status}}</span>{{with .Gate.BaselinePath}} · baseline <code>{{.}}</code>{{end}}</p>
+<table>
+<thead><tr><th>Metric</th><th>Value</th><th>Threshold</th><th>Best reference</th></tr></thead>
+<tbody>
+{{range .GateRows}}<tr><td>{{.Metric}}</td><td class="num">{{.Value}}</td><td class="num {{.Threshold.Class}}">{{.Threshold.Target}}</td><td class="num {{.BestRef.Class}}">{{.BestRef.Target}}</td></tr>
+{{end}}</tbody>
+</table>
+{{with .Gate.Reasons}}<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
+
+<h2>Ranking</h2>
+<table id="ranking">
+<thead><tr>
+<th class="sortable" data-type="num">#</th>
+<th class="sortable" data-type="text">Task</th>
+<th class="sortable" data-type="text">Stage</th>
+<th class="sortable" data-type="text">Passed</th>
+<th class="sortable" data-type="num">Accuracy</th>
+<th class="sortable" data-type="num">Natural</th>
+<th class="sortable" data-type="num">Efficiency</th>
+<th class="sortable" data-type="num">Overall</th>
+</tr></thead>
+<tbody>
+{{range .Tasks}}<tr class="{{if .Passed}}pass{{else}}fail{{end}}">
+<td class="num">{{.Rank}}</td><td><a href="#task-{{.Rank}}">{{.ID}}</a></td><td>{{.Stage}}</td><td>{{.Passed}}</td>
+<td class="num">{{score .Score.Accuracy}}</td><td class="num">{{score .Score.Natural}}</td><td class="num">{{score .Score.Efficiency}}</td><td class="num">{{score .Score.Overall}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+<p class="meta">Suite: pass {{rate .Metrics.PassRate}} · loop safe {{rate .Metrics.LoopSafetyRate}} · fallback free {{rate .Metrics.FallbackFreeRate}} · accuracy {{score .Metrics.AverageAccuracy}} · overall {{score .Metrics.AverageOverall}}</p>
+
+<h2>Tasks</h2>
+{{range .Tasks}}<details id="task-{{.Rank}}">
+<summary>#{{.Rank}} {{.ID}}{{with .Title}} — {{.}}{{end}} ({{score .Score.Overall}})</summary>
+<p class="meta">passed={{.Passed}} loop_safe={{.LoopSafe}} tool_calls={{.ToolCalls}} duration_ms={{.DurationMS}}</p>
+{{with .HardFails}}<p>Hard fail reasons:</p><ul>{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{with .MissingEvidence}}<p>Missing evidence:</p><ul>{{range .}}<li><code>{{.}}</code></li>{{end}}</ul>{{end}}
+{{range .Turns}}<p>Turn {{.Index}} <code>{{.RunID}}</code>: attempts={{.AttemptCount}} tools={{.ToolCallCount}} recoveries={{.RecoveryCount}}{{with .FinalizationReason}} finalization=<code>{{.}}</code>{{end}}</p>
+{{with .ReasonFlow}}<ol class="flow">{{range .}}<li>{{.}}</li>{{end}}</ol>{{end}}
+{{end}}{{with .Preview}}<p>Final text preview:</p><pre>{{.}}</pre>{{end}}
+</details>
+{{end}}
+<script>
+(function () {
+  var table = document.getElementById("ranking");
+  var headers = table.tHead.rows[0].cells;
+  for (var i = 0; i < headers.length; i++) {
+    (function (col, th) {
+      var desc = th.getAttribute("data-type") === "num";
+      th.addEventListener("click", function () {
+        var body = table.tBodies[0];
+        var rows = Array.prototype.slice.call(body.rows);
+        var numeric = th.getAttribute("data-type") === "num";
+        rows.sort(function (a, b) {
+          var x = a.cells[col].textContent, y = b.cells[col].textContent;
+          var cmp = numeric ? parseFloat(x) - parseFloat(y) : x.localeCompare(y);
+          return desc ? -cmp : cmp;
+        });
+        desc = !desc;
+        rows.forEach(function (row) { body.appendChild(row); });
+      });
+    })(i, headers[i]);
+  }
+})();
+</script>
+</body>
+</html>
+`))
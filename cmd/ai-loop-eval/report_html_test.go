@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTML_EscapesModelOutputAndRanksByOverall(t *testing.T) {
+	t.Parallel()
+
+	report := evalReport{
+		GeneratedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		ModelID:     "openai/gpt-5",
+		TaskCount:   2,
+		Results: []taskResult{
+			{
+				Task:      evalTask{ID: "low_task", Stage: "screen"},
+				FinalText: `<script>alert("x")</script> done`,
+				Score:     scoreBreakdown{Overall: 40},
+				Turns:     []turnMetrics{{RunID: "run_1", CompletionReasonFlow: []string{"completion:<b>empty_result</b>"}}},
+			},
+			{
+				Task:            evalTask{ID: "high_task", Stage: "deep"},
+				Score:           scoreBreakdown{Overall: 90},
+				MissingEvidence: []string{"evidence_path:cmd/<app>/main.go"},
+			},
+		},
+		Gate: gateReport{Enabled: true, Status: "reject", Reasons: []string{"pass_rate 0.500 < threshold 0.800"}},
+	}
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := writeHTML(path, report); err != nil {
+		t.Fatalf("writeHTML: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	html := string(raw)
+	if strings.Contains(html, `<script>alert`) || strings.Contains(html, "<b>empty_result</b>") {
+		t.Fatalf("model output was not escaped")
+	}
+	if !strings.Contains(html, "&lt;script&gt;alert") || !strings.Contains(html, "&lt;b&gt;empty_result&lt;/b&gt;") {
+		t.Fatalf("escaped model output missing from report")
+	}
+	if strings.Index(html, `href="#task-1">high_task`) < 0 || strings.Index(html, `href="#task-2">low_task`) < 0 {
+		t.Fatalf("tasks not ranked by overall score")
+	}
+	if !strings.Contains(html, "evidence_path:cmd/&lt;app&gt;/main.go") {
+		t.Fatalf("missing evidence not reported")
+	}
+	if strings.Contains(html, "<link") || strings.Contains(html, "src=") {
+		t.Fatalf("report must not reference external assets")
+	}
+
+	if err := writeHTML(path, evalReport{}); err == nil {
+		t.Fatalf("expected error for empty report")
+	}
+}
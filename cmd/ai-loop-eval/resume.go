@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+const taskResultFileSuffix = ".result.json"
+
+// taskResultPath returns where a finished task result is checkpointed inside the state dir.
+//
+// Sanitized ids never contain '.', so the file cannot collide with a task's own state directory.
+func taskResultPath(stateDir string, taskID string) string {
+	return filepath.Join(filepath.Clean(stateDir), sanitizeID(taskID)+taskResultFileSuffix)
+}
+
+// saveTaskResult checkpoints one finished task so an interrupted run loses at most the tasks in flight.
+func saveTaskResult(stateDir string, result taskResult) error {
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := taskResultPath(stateDir, result.Task.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadCompletedTaskResults reads the checkpoints for tasks that still match the current task spec.
+//
+// A checkpoint is ignored when the task definition changed since it was written, so edited tasks are re-run.
+func loadCompletedTaskResults(stateDir string, tasks []evalTask) (map[string]taskResult, error) {
+	out := make(map[string]taskResult, len(tasks))
+	for _, task := range tasks {
+		b, err := os.ReadFile(taskResultPath(stateDir, task.ID))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var result taskResult
+		if err := json.Unmarshal(b, &result); err != nil {
+			return nil, fmt.Errorf("task %s checkpoint: %w", task.ID, err)
+		}
+		if !sameEvalTask(result.Task, task) {
+			continue
+		}
+		out[task.ID] = result
+	}
+	return out, nil
+}
+
+// sameEvalTask compares tasks through their JSON form, which is what checkpoints preserve.
+func sameEvalTask(a evalTask, b evalTask) bool {
+	var normA, normB evalTask
+	ab, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	if json.Unmarshal(ab, &normA) != nil || json.Unmarshal(bb, &normB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(normA, normB)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTaskResultCheckpoint_RoundTripAndStaleTask(t *testing.T) {
+	t.Parallel()
+
+	stateDir := t.TempDir()
+	done := evalTask{ID: "repo/overview", Stage: "screen", Turns: []string{"Inspect ${workspace}"}, Runtime: evalTaskRuntime{MaxSteps: 4}}
+	edited := evalTask{ID: "edited_task", Stage: "deep", Turns: []string{"old prompt"}}
+	pending := evalTask{ID: "pending_task", Stage: "screen", Turns: []string{"todo"}}
+
+	if err := saveTaskResult(stateDir, taskResult{Task: done, FinalText: "summary", Score: scoreBreakdown{Overall: 88}, Outcome: taskOutcome{Passed: true}}); err != nil {
+		t.Fatalf("saveTaskResult: %v", err)
+	}
+	if err := saveTaskResult(stateDir, taskResult{Task: edited}); err != nil {
+		t.Fatalf("saveTaskResult: %v", err)
+	}
+	if _, err := os.Stat(taskResultPath(stateDir, done.ID) + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary checkpoint file left behind: %v", err)
+	}
+
+	editedNow := edited
+	editedNow.Turns = []string{"new prompt"}
+	completed, err := loadCompletedTaskResults(stateDir, []evalTask{done, editedNow, pending})
+	if err != nil {
+		t.Fatalf("loadCompletedTaskResults: %v", err)
+	}
+	if len(completed) != 1 {
+		t.Fatalf("completed=%d, want only the unchanged task", len(completed))
+	}
+	got, ok := completed[done.ID]
+	if !ok || got.FinalText != "summary" || got.Score.Overall != 88 || !got.Outcome.Passed {
+		t.Fatalf("unexpected resumed result: %+v", got)
+	}
+}
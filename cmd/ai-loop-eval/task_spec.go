@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/floegence/redeven/internal/ai"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,11 +22,27 @@ type taskSpecItem struct {
 	Title      string             `yaml:"title"`
 	Stage      string             `yaml:"stage"`
 	Category   string             `yaml:"category"`
-	Turns      []string           `yaml:"turns"`
+	Turns      []taskTurnSpec     `yaml:"turns"`
 	Runtime    taskRuntimeSpec    `yaml:"runtime"`
 	Assertions taskAssertionsSpec `yaml:"assertions"`
 }
 
+// taskTurnSpec is one user turn. It is either a plain string or a mapping with the turn input
+// and output assertions checked against the assistant reply to that turn alone.
+type taskTurnSpec struct {
+	Input       string   `yaml:"input"`
+	MustContain []string `yaml:"must_contain"`
+	Forbidden   []string `yaml:"forbidden"`
+}
+
+func (t *taskTurnSpec) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&t.Input)
+	}
+	type plain taskTurnSpec
+	return node.Decode((*plain)(t))
+}
+
 type taskWorkspaceSpec struct {
 	Mode    string `yaml:"mode"`
 	Fixture string `yaml:"fixture"`
@@ -34,10 +52,15 @@ type taskRuntimeSpec struct {
 	ExecutionMode                    string            `yaml:"execution_mode"`
 	MaxSteps                         int               `yaml:"max_steps"`
 	MaxNoToolRounds                  int               `yaml:"max_no_tool_rounds"`
+	LoopProfile                      string            `yaml:"loop_profile"`
+	PromptProfile                    string            `yaml:"prompt_profile"`
 	TimeoutSeconds                   int               `yaml:"timeout_seconds"`
+	TimeoutTotalSeconds              int               `yaml:"timeout_total_seconds"`
 	ReasoningOnly                    bool              `yaml:"reasoning_only"`
 	RequireUserConfirmOnTaskComplete bool              `yaml:"require_user_confirm_on_task_complete"`
 	NoUserInteraction                bool              `yaml:"no_user_interaction"`
+	AllowParallelToolCalls           bool              `yaml:"allow_parallel_tool_calls"`
+	ForceIntent                      string            `yaml:"force_intent"`
 	Workspace                        taskWorkspaceSpec `yaml:"workspace"`
 }
 
@@ -56,6 +79,10 @@ type taskOutputAssertions struct {
 	MustContain            []string `yaml:"must_contain"`
 	Forbidden              []string `yaml:"forbidden"`
 	MustNotEndWithFallback bool     `yaml:"must_not_end_with_fallback"`
+	// EvidencePaths are task-specific paths that must appear verbatim in the final text.
+	EvidencePaths []string `yaml:"evidence_paths"`
+	// EvidenceRegex are patterns the final text must match, for evidence that cannot be a fixed string.
+	EvidenceRegex []string `yaml:"evidence_regex"`
 }
 
 type taskThreadAssertions struct {
@@ -86,13 +113,24 @@ type taskTodoAssertions struct {
 }
 
 type evalTask struct {
-	ID         string             `json:"id"`
-	Title      string             `json:"title"`
-	Stage      string             `json:"stage"`
-	Category   string             `json:"category,omitempty"`
-	Turns      []string           `json:"turns"`
-	Runtime    evalTaskRuntime    `json:"runtime"`
-	Assertions taskAssertionsSpec `json:"assertions"`
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Stage    string   `json:"stage"`
+	Category string   `json:"category,omitempty"`
+	Turns    []string `json:"turns"`
+	// TurnAssertions is parallel to Turns; it is nil when no turn has its own assertions.
+	TurnAssertions []evalTurnAssertions `json:"turn_assertions,omitempty"`
+	Runtime        evalTaskRuntime      `json:"runtime"`
+	Assertions     taskAssertionsSpec   `json:"assertions"`
+}
+
+type evalTurnAssertions struct {
+	MustContain []string `json:"must_contain,omitempty"`
+	Forbidden   []string `json:"forbidden,omitempty"`
+}
+
+func (a evalTurnAssertions) empty() bool {
+	return len(a.MustContain) == 0 && len(a.Forbidden) == 0
 }
 
 type evalTaskWorkspace struct {
@@ -104,27 +142,123 @@ type evalTaskRuntime struct {
 	ExecutionMode                    string            `json:"execution_mode"`
 	MaxSteps                         int               `json:"max_steps"`
 	MaxNoToolRounds                  int               `json:"max_no_tool_rounds,omitempty"`
+	LoopProfile                      string            `json:"loop_profile"`
+	PromptProfile                    string            `json:"prompt_profile"`
 	TimeoutPerTurn                   time.Duration     `json:"-"`
 	TimeoutSeconds                   int               `json:"timeout_seconds"`
+	TimeoutTotal                     time.Duration     `json:"-"`
+	TimeoutTotalSeconds              int               `json:"timeout_total_seconds"`
 	ReasoningOnly                    bool              `json:"reasoning_only,omitempty"`
 	RequireUserConfirmOnTaskComplete bool              `json:"require_user_confirm_on_task_complete,omitempty"`
 	NoUserInteraction                bool              `json:"no_user_interaction,omitempty"`
+	AllowParallelToolCalls           bool              `json:"allow_parallel_tool_calls,omitempty"`
+	ForceIntent                      string            `json:"force_intent,omitempty"`
 	Workspace                        evalTaskWorkspace `json:"workspace"`
 }
 
+// defaultTaskTimeoutGraceSeconds is added to timeout_seconds x turns when a task sets no timeout_total_seconds.
+const defaultTaskTimeoutGraceSeconds = 30
+
 const (
 	taskWorkspaceModeNone           = "none"
 	taskWorkspaceModeSourceReadonly = "source_readonly"
 	taskWorkspaceModeFixtureCopy    = "fixture_copy"
 )
 
-func loadTaskSpecs(specPath string) ([]evalTask, error) {
-	cleanPath := strings.TrimSpace(specPath)
-	if cleanPath == "" {
+// loadTaskSpecs loads and merges the tasks of every spec in specPaths.
+//
+// specPaths is a comma-separated list of yaml files and directories; a directory contributes all of its
+// .yaml files in name order. Task ids must be unique across the merged specs, and the merged suite
+// needs at least one screen and one deep task.
+func loadTaskSpecs(specPaths string) ([]evalTask, error) {
+	files, err := resolveTaskSpecFiles(specPaths)
+	if err != nil {
+		return nil, err
+	}
+	var out []evalTask
+	taskFiles := make(map[string]string)
+	sandboxIDs := make(map[string]string)
+	for _, file := range files {
+		tasks, err := loadTaskSpecFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, task := range tasks {
+			if prev, ok := taskFiles[task.ID]; ok {
+				return nil, fmt.Errorf("task %s is defined in both %s and %s", task.ID, prev, file)
+			}
+			taskFiles[task.ID] = file
+			// Task sandboxes and channels are keyed by the sanitized id, so ids must stay distinct after sanitizing.
+			sandboxID := sanitizeID(task.ID)
+			if prev, ok := sandboxIDs[sandboxID]; ok {
+				return nil, fmt.Errorf("task %s collides with task %s", task.ID, prev)
+			}
+			sandboxIDs[sandboxID] = task.ID
+			out = append(out, task)
+		}
+	}
+	stageCounts := make(map[string]int, 2)
+	for _, task := range out {
+		stageCounts[task.Stage]++
+	}
+	for _, stage := range []string{"screen", "deep"} {
+		if stageCounts[stage] == 0 {
+			return nil, fmt.Errorf("task specs have no %s task; at least one screen and one deep task are required", stage)
+		}
+	}
+	return out, nil
+}
+
+// resolveTaskSpecFiles expands a comma-separated list of spec files and directories into yaml files.
+// A file reached twice, for example through its directory and by name, is loaded once.
+func resolveTaskSpecFiles(specPaths string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		files = append(files, path)
+	}
+	for _, raw := range strings.Split(specPaths, ",") {
+		specPath := strings.TrimSpace(raw)
+		if specPath == "" {
+			continue
+		}
+		specPath = filepath.Clean(specPath)
+		info, err := os.Stat(specPath)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			add(specPath)
+			continue
+		}
+		entries, err := os.ReadDir(specPath)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+				continue
+			}
+			add(filepath.Join(specPath, entry.Name()))
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("task spec dir %s has no .yaml files", specPath)
+		}
+	}
+	if len(files) == 0 {
 		return nil, fmt.Errorf("missing task spec path")
 	}
-	cleanPath = filepath.Clean(cleanPath)
-	data, err := os.ReadFile(cleanPath)
+	return files, nil
+}
+
+func loadTaskSpecFile(specPath string) ([]evalTask, error) {
+	data, err := os.ReadFile(specPath)
 	if err != nil {
 		return nil, err
 	}
@@ -135,7 +269,7 @@ func loadTaskSpecs(specPath string) ([]evalTask, error) {
 	if len(spec.Tasks) == 0 {
 		return nil, fmt.Errorf("task spec has no tasks")
 	}
-	specDir := filepath.Dir(cleanPath)
+	specDir := filepath.Dir(specPath)
 	out := make([]evalTask, 0, len(spec.Tasks))
 	for _, item := range spec.Tasks {
 		task, err := normalizeTaskSpecItem(item, specDir)
@@ -158,16 +292,30 @@ func normalizeTaskSpecItem(item taskSpecItem, specDir string) (evalTask, error)
 	}
 
 	turns := make([]string, 0, len(item.Turns))
-	for _, turn := range item.Turns {
-		turn = strings.TrimSpace(turn)
-		if turn == "" {
+	turnAssertions := make([]evalTurnAssertions, 0, len(item.Turns))
+	hasTurnAssertions := false
+	for i, turn := range item.Turns {
+		input := strings.TrimSpace(turn.Input)
+		checks := evalTurnAssertions{
+			MustContain: normalizeStringSlice(turn.MustContain),
+			Forbidden:   normalizeStringSlice(turn.Forbidden),
+		}
+		if input == "" {
+			if !checks.empty() {
+				return evalTask{}, fmt.Errorf("task %s turn %d has assertions but no input", id, i+1)
+			}
 			continue
 		}
-		turns = append(turns, turn)
+		turns = append(turns, input)
+		turnAssertions = append(turnAssertions, checks)
+		hasTurnAssertions = hasTurnAssertions || !checks.empty()
 	}
 	if len(turns) == 0 {
 		return evalTask{}, fmt.Errorf("task %s has no turns", id)
 	}
+	if !hasTurnAssertions {
+		turnAssertions = nil
+	}
 
 	executionMode := normalizeExecutionMode(item.Runtime.ExecutionMode)
 	if executionMode == "" {
@@ -178,6 +326,13 @@ func normalizeTaskSpecItem(item taskSpecItem, specDir string) (evalTask, error)
 	if timeoutSeconds <= 0 {
 		timeoutSeconds = 45
 	}
+	if item.Runtime.TimeoutTotalSeconds < 0 {
+		return evalTask{}, fmt.Errorf("task %s has invalid timeout_total_seconds", id)
+	}
+	timeoutTotalSeconds := item.Runtime.TimeoutTotalSeconds
+	if timeoutTotalSeconds == 0 {
+		timeoutTotalSeconds = timeoutSeconds*len(turns) + defaultTaskTimeoutGraceSeconds
+	}
 
 	maxSteps := item.Runtime.MaxSteps
 	if maxSteps <= 0 {
@@ -187,6 +342,18 @@ func normalizeTaskSpecItem(item taskSpecItem, specDir string) (evalTask, error)
 	if item.Runtime.MaxNoToolRounds < 0 {
 		return evalTask{}, fmt.Errorf("task %s has invalid max_no_tool_rounds", id)
 	}
+	loopProfile, ok := ai.LookupLoopProfile(item.Runtime.LoopProfile)
+	if !ok {
+		return evalTask{}, fmt.Errorf("task %s has unknown loop_profile: %s", id, item.Runtime.LoopProfile)
+	}
+	promptProfile, ok := ai.LookupPromptProfile(item.Runtime.PromptProfile)
+	if !ok {
+		return evalTask{}, fmt.Errorf("task %s has unknown prompt_profile: %s", id, item.Runtime.PromptProfile)
+	}
+	forceIntent := strings.TrimSpace(strings.ToLower(item.Runtime.ForceIntent))
+	if err := ai.ValidateRunOptions(ai.RunOptions{ForceIntent: forceIntent}); err != nil {
+		return evalTask{}, fmt.Errorf("task %s has %w", id, err)
+	}
 	workspace, err := normalizeTaskWorkspaceSpec(item.Runtime.Workspace, specDir)
 	if err != nil {
 		return evalTask{}, fmt.Errorf("task %s has invalid workspace config: %w", id, err)
@@ -195,6 +362,13 @@ func normalizeTaskSpecItem(item taskSpecItem, specDir string) (evalTask, error)
 	assertions := item.Assertions
 	assertions.Output.MustContain = normalizeStringSlice(assertions.Output.MustContain)
 	assertions.Output.Forbidden = normalizeStringSlice(assertions.Output.Forbidden)
+	assertions.Output.EvidencePaths = normalizeStringSlice(assertions.Output.EvidencePaths)
+	assertions.Output.EvidenceRegex = normalizeStringSlice(assertions.Output.EvidenceRegex)
+	for _, pattern := range assertions.Output.EvidenceRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return evalTask{}, fmt.Errorf("task %s has invalid evidence_regex %q: %w", id, pattern, err)
+		}
+	}
 	assertions.Tools.MustCall = normalizeStringSlice(assertions.Tools.MustCall)
 	assertions.Tools.MustNotCall = normalizeStringSlice(assertions.Tools.MustNotCall)
 	assertions.Tools.MustSucceed = normalizeStringSlice(assertions.Tools.MustSucceed)
@@ -231,20 +405,27 @@ func normalizeTaskSpecItem(item taskSpecItem, specDir string) (evalTask, error)
 	}
 
 	return evalTask{
-		ID:       id,
-		Title:    strings.TrimSpace(item.Title),
-		Stage:    stage,
-		Category: strings.TrimSpace(strings.ToLower(item.Category)),
-		Turns:    turns,
+		ID:             id,
+		Title:          strings.TrimSpace(item.Title),
+		Stage:          stage,
+		Category:       strings.TrimSpace(strings.ToLower(item.Category)),
+		Turns:          turns,
+		TurnAssertions: turnAssertions,
 		Runtime: evalTaskRuntime{
 			ExecutionMode:                    executionMode,
 			MaxSteps:                         maxSteps,
 			MaxNoToolRounds:                  item.Runtime.MaxNoToolRounds,
+			LoopProfile:                      loopProfile.ID,
+			PromptProfile:                    promptProfile.ID,
 			TimeoutPerTurn:                   time.Duration(timeoutSeconds) * time.Second,
 			TimeoutSeconds:                   timeoutSeconds,
+			TimeoutTotal:                     time.Duration(timeoutTotalSeconds) * time.Second,
+			TimeoutTotalSeconds:              timeoutTotalSeconds,
 			ReasoningOnly:                    item.Runtime.ReasoningOnly,
 			RequireUserConfirmOnTaskComplete: item.Runtime.RequireUserConfirmOnTaskComplete,
 			NoUserInteraction:                item.Runtime.NoUserInteraction,
+			AllowParallelToolCalls:           item.Runtime.AllowParallelToolCalls,
+			ForceIntent:                      forceIntent,
 			Workspace:                        workspace,
 		},
 		Assertions: assertions,
@@ -338,3 +519,19 @@ func normalizeStringSlice(in []string) []string {
 	}
 	return out
 }
+
+// overrideTaskPromptProfile sets one prompt profile on every task so two suite runs can A/B a profile.
+// An empty id keeps each task's own prompt_profile.
+func overrideTaskPromptProfile(tasks []evalTask, id string) error {
+	if strings.TrimSpace(id) == "" {
+		return nil
+	}
+	profile, ok := ai.LookupPromptProfile(id)
+	if !ok {
+		return fmt.Errorf("unknown prompt_profile: %s", id)
+	}
+	for i := range tasks {
+		tasks[i].Runtime.PromptProfile = profile.ID
+	}
+	return nil
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadTaskSpecs(t *testing.T) {
@@ -27,8 +29,13 @@ tasks:
       execution_mode: plan
       max_steps: 3
       max_no_tool_rounds: 1
+      loop_profile: fast_exit_v1
+      prompt_profile: natural_evidence_v2
       timeout_seconds: 20
+      timeout_total_seconds: 45
       no_user_interaction: true
+      allow_parallel_tool_calls: true
+      force_intent: Social
     assertions:
       output:
         require_evidence: true
@@ -75,9 +82,33 @@ tasks:
 	if tasks[0].Runtime.ExecutionMode != "plan" {
 		t.Fatalf("execution_mode=%q", tasks[0].Runtime.ExecutionMode)
 	}
+	if tasks[0].Runtime.LoopProfile != "fast_exit_v1" || tasks[1].Runtime.LoopProfile != "default" {
+		t.Fatalf("loop_profile=%q/%q", tasks[0].Runtime.LoopProfile, tasks[1].Runtime.LoopProfile)
+	}
+	if tasks[0].Runtime.PromptProfile != "natural_evidence_v2" || tasks[1].Runtime.PromptProfile != "default" {
+		t.Fatalf("prompt_profile=%q/%q", tasks[0].Runtime.PromptProfile, tasks[1].Runtime.PromptProfile)
+	}
+	if err := overrideTaskPromptProfile(tasks, "concise_finish_v1"); err != nil {
+		t.Fatalf("overrideTaskPromptProfile: %v", err)
+	}
+	if tasks[0].Runtime.PromptProfile != "concise_finish_v1" || tasks[1].Runtime.PromptProfile != "concise_finish_v1" {
+		t.Fatalf("overridden prompt_profile=%q/%q", tasks[0].Runtime.PromptProfile, tasks[1].Runtime.PromptProfile)
+	}
+	if err := overrideTaskPromptProfile(tasks, "turbo_v9"); err == nil {
+		t.Fatalf("expected unknown prompt_profile error")
+	}
+	if tasks[0].Runtime.TimeoutTotal != 45*time.Second || tasks[1].Runtime.TimeoutTotal != 45*time.Second {
+		t.Fatalf("timeout_total=%v/%v, want explicit 45s and default 15s*1+30s", tasks[0].Runtime.TimeoutTotal, tasks[1].Runtime.TimeoutTotal)
+	}
 	if !tasks[0].Runtime.NoUserInteraction {
 		t.Fatalf("expected no_user_interaction=true")
 	}
+	if !tasks[0].Runtime.AllowParallelToolCalls || tasks[1].Runtime.AllowParallelToolCalls {
+		t.Fatalf("allow_parallel_tool_calls=%v/%v", tasks[0].Runtime.AllowParallelToolCalls, tasks[1].Runtime.AllowParallelToolCalls)
+	}
+	if tasks[0].Runtime.ForceIntent != "social" || tasks[1].Runtime.ForceIntent != "" {
+		t.Fatalf("force_intent=%q/%q", tasks[0].Runtime.ForceIntent, tasks[1].Runtime.ForceIntent)
+	}
 	if tasks[0].Assertions.Thread.WaitingPrompt != "required" {
 		t.Fatalf("waiting_prompt=%q", tasks[0].Assertions.Thread.WaitingPrompt)
 	}
@@ -119,3 +150,272 @@ tasks:
 		t.Fatalf("expected invalid workspace mode error")
 	}
 }
+
+func TestLoadTaskSpecs_NegativeTotalTimeout(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.yaml")
+	content := `version: v2
+
+tasks:
+  - id: bad_timeout
+    title: Bad Timeout
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+    runtime:
+      timeout_total_seconds: -1
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write task spec: %v", err)
+	}
+
+	if _, err := loadTaskSpecs(path); err == nil {
+		t.Fatalf("expected invalid timeout_total_seconds error")
+	}
+}
+
+func TestLoadTaskSpecs_UnknownLoopProfile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.yaml")
+	content := `version: v2
+
+tasks:
+  - id: bad_profile
+    title: Bad Profile
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+    runtime:
+      loop_profile: turbo_v9
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write task spec: %v", err)
+	}
+
+	if _, err := loadTaskSpecs(path); err == nil {
+		t.Fatalf("expected unknown loop_profile error")
+	}
+}
+
+func TestLoadTaskSpecs_UnknownPromptProfile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.yaml")
+	content := `version: v2
+
+tasks:
+  - id: bad_prompt_profile
+    title: Bad Prompt Profile
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+    runtime:
+      prompt_profile: turbo_v9
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write task spec: %v", err)
+	}
+
+	if _, err := loadTaskSpecs(path); err == nil {
+		t.Fatalf("expected unknown prompt_profile error")
+	}
+}
+
+func TestLoadTaskSpecs_DuplicateSandboxID(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.yaml")
+	content := `version: v2
+
+tasks:
+  - id: repo/overview
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+  - id: repo_overview
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write task spec: %v", err)
+	}
+
+	if _, err := loadTaskSpecs(path); err == nil {
+		t.Fatalf("expected colliding task ids to be rejected")
+	}
+}
+
+func writeTaskSpecTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir task spec dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write task spec: %v", err)
+	}
+}
+
+func TestLoadTaskSpecs_MergesDirectoriesAndLists(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeTaskSpecTestFile(t, filepath.Join(dir, "core", "b_deep.yaml"), `tasks:
+  - id: core_deep
+    stage: deep
+    turns:
+      - "Fix ${workspace}"
+`)
+	writeTaskSpecTestFile(t, filepath.Join(dir, "core", "a_screen.yaml"), `tasks:
+  - id: core_screen
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+`)
+	writeTaskSpecTestFile(t, filepath.Join(dir, "core", "notes.md"), "not a task spec")
+	extra := filepath.Join(dir, "extra.yaml")
+	writeTaskSpecTestFile(t, extra, `tasks:
+  - id: extra_screen
+    stage: screen
+    turns:
+      - "Summarize ${workspace}"
+`)
+
+	tasks, err := loadTaskSpecs(filepath.Join(dir, "core") + ", " + extra + "," + filepath.Join(dir, "core", "a_screen.yaml"))
+	if err != nil {
+		t.Fatalf("loadTaskSpecs: %v", err)
+	}
+	var ids []string
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	if len(ids) != 3 || ids[0] != "core_screen" || ids[1] != "core_deep" || ids[2] != "extra_screen" {
+		t.Fatalf("task ids=%v", ids)
+	}
+}
+
+func TestLoadTaskSpecs_RejectsDuplicateIDsAcrossFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeTaskSpecTestFile(t, filepath.Join(dir, "a.yaml"), `tasks:
+  - id: overview
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+  - id: fix
+    stage: deep
+    turns:
+      - "Fix ${workspace}"
+`)
+	writeTaskSpecTestFile(t, filepath.Join(dir, "b.yaml"), `tasks:
+  - id: overview
+    stage: screen
+    turns:
+      - "Inspect again ${workspace}"
+`)
+
+	_, err := loadTaskSpecs(dir)
+	if err == nil || !strings.Contains(err.Error(), "task overview is defined in both") {
+		t.Fatalf("err=%v, want duplicate task id error", err)
+	}
+}
+
+func TestLoadTaskSpecs_RequiresScreenAndDeepStages(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "screen_only.yaml")
+	writeTaskSpecTestFile(t, path, `tasks:
+  - id: overview
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+`)
+
+	if _, err := loadTaskSpecs(path); err == nil || !strings.Contains(err.Error(), "no deep task") {
+		t.Fatalf("err=%v, want missing deep stage error", err)
+	}
+	if _, err := loadTaskSpecs(filepath.Join(dir, "empty") + ","); err == nil {
+		t.Fatalf("expected missing path error")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "empty"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err := loadTaskSpecs(filepath.Join(dir, "empty")); err == nil || !strings.Contains(err.Error(), "has no .yaml files") {
+		t.Fatalf("err=%v, want empty dir error", err)
+	}
+}
+
+func TestLoadTaskSpecs_InvalidEvidenceRegex(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+	writeTaskSpecTestFile(t, path, `tasks:
+  - id: overview
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+    assertions:
+      output:
+        evidence_paths:
+          - " README.md "
+        evidence_regex:
+          - "main\\.go("
+  - id: fix
+    stage: deep
+    turns:
+      - "Fix ${workspace}"
+`)
+
+	if _, err := loadTaskSpecs(path); err == nil || !strings.Contains(err.Error(), "invalid evidence_regex") {
+		t.Fatalf("err=%v, want invalid evidence_regex error", err)
+	}
+}
+
+func TestLoadTaskSpecs_TurnAssertions(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+	writeTaskSpecTestFile(t, path, `tasks:
+  - id: overview
+    stage: screen
+    turns:
+      - "Inspect ${workspace}"
+      - input: " Now summarize the config "
+        must_contain:
+          - " config "
+        forbidden:
+          - "I cannot"
+  - id: fix
+    stage: deep
+    turns:
+      - "Fix ${workspace}"
+`)
+
+	tasks, err := loadTaskSpecs(path)
+	if err != nil {
+		t.Fatalf("loadTaskSpecs: %v", err)
+	}
+	overview := tasks[0]
+	if len(overview.Turns) != 2 || overview.Turns[1] != "Now summarize the config" {
+		t.Fatalf("turns=%q", overview.Turns)
+	}
+	if len(overview.TurnAssertions) != 2 || !overview.TurnAssertions[0].empty() {
+		t.Fatalf("turn_assertions=%+v", overview.TurnAssertions)
+	}
+	second := overview.TurnAssertions[1]
+	if len(second.MustContain) != 1 || second.MustContain[0] != "config" || len(second.Forbidden) != 1 || second.Forbidden[0] != "I cannot" {
+		t.Fatalf("second turn assertions=%+v", second)
+	}
+	if tasks[1].TurnAssertions != nil {
+		t.Fatalf("plain turns got turn_assertions=%+v", tasks[1].TurnAssertions)
+	}
+
+	writeTaskSpecTestFile(t, path, `tasks:
+  - id: overview
+    stage: screen
+    turns:
+      - must_contain: ["config"]
+`)
+	if _, err := loadTaskSpecs(path); err == nil || !strings.Contains(err.Error(), "has assertions but no input") {
+		t.Fatalf("err=%v, want missing turn input error", err)
+	}
+}
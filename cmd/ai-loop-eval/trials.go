@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// trialScore is the score of one trial of a task run with -trials > 1.
+type trialScore struct {
+	Trial        int            `json:"trial"`
+	ProviderSeed *int64         `json:"provider_seed,omitempty"`
+	Passed       bool           `json:"passed"`
+	Score        scoreBreakdown `json:"score"`
+}
+
+// scoreStats is the mean and sample standard deviation of a task's scores across its trials.
+type scoreStats struct {
+	Trials   int            `json:"trials"`
+	PassRate float64        `json:"pass_rate"`
+	Mean     scoreBreakdown `json:"mean"`
+	Stddev   scoreBreakdown `json:"stddev"`
+}
+
+// suiteNoise measures how much the suite metrics move from one trial to the next.
+//
+// Trial k of the suite is trial k of every task; its pass rate and average scores are computed like
+// suiteMetrics, and the mean and sample standard deviation are taken across suite trials.
+type suiteNoise struct {
+	Trials         int     `json:"trials"`
+	PassRateMean   float64 `json:"pass_rate_mean"`
+	PassRateStddev float64 `json:"pass_rate_stddev"`
+	AccuracyMean   float64 `json:"average_accuracy_mean"`
+	AccuracyStddev float64 `json:"average_accuracy_stddev"`
+	OverallMean    float64 `json:"average_overall_mean"`
+	OverallStddev  float64 `json:"average_overall_stddev"`
+}
+
+// evalTrialKey names the sandbox, channel, and provider session of one trial.
+//
+// The first trial keeps the task id, so -trials 1 runs and existing recordings are unchanged.
+func evalTrialKey(taskID string, trial int) string {
+	if trial <= 1 {
+		return taskID
+	}
+	return fmt.Sprintf("%s__trial%d", taskID, trial)
+}
+
+// evalTrialSeed derives the provider seed of one trial; the first trial uses the task seed.
+func evalTrialSeed(suiteSeed int64, taskID string, trial int) *int64 {
+	if trial <= 1 {
+		return evalTaskSeed(suiteSeed, taskID)
+	}
+	return evalTaskSeed(suiteSeed, fmt.Sprintf("%s#%d", strings.TrimSpace(taskID), trial))
+}
+
+// resultTrialCount returns how many trials produced result.
+func resultTrialCount(result taskResult) int {
+	if len(result.Trials) == 0 {
+		return 1
+	}
+	return len(result.Trials)
+}
+
+// mergeTrialResults folds the trials of one task into a single result.
+//
+// The merged result keeps the first trial's details (outcome, thread state, tool calls) and replaces its
+// score with the mean across trials. Per-trial scores and their spread are kept in Trials and ScoreStats.
+func mergeTrialResults(trials []taskResult) taskResult {
+	if len(trials) == 0 {
+		return taskResult{}
+	}
+	merged := trials[0]
+	if len(trials) == 1 {
+		return merged
+	}
+	merged.Trials = make([]trialScore, 0, len(trials))
+	scores := make([]scoreBreakdown, 0, len(trials))
+	passed := 0
+	for i, trial := range trials {
+		merged.Trials = append(merged.Trials, trialScore{
+			Trial:        i + 1,
+			ProviderSeed: trial.ProviderSeed,
+			Passed:       trial.Outcome.Passed,
+			Score:        trial.Score,
+		})
+		scores = append(scores, trial.Score)
+		if trial.Outcome.Passed {
+			passed++
+		}
+	}
+	stats := &scoreStats{
+		Trials:   len(trials),
+		PassRate: float64(passed) / float64(len(trials)),
+		Mean:     meanScore(scores),
+		Stddev:   stddevScore(scores),
+	}
+	merged.ScoreStats = stats
+	merged.Score = stats.Mean
+	return merged
+}
+
+// computeSuiteNoise returns the trial-to-trial spread of the suite metrics, or nil for single-trial runs.
+//
+// Tasks with fewer trials than the suite (for example a failed setup) only count toward the trials they ran.
+func computeSuiteNoise(results []taskResult) *suiteNoise {
+	trials := 0
+	for _, result := range results {
+		trials = max(trials, len(result.Trials))
+	}
+	if trials < 2 {
+		return nil
+	}
+	passRates := make([]float64, 0, trials)
+	accuracies := make([]float64, 0, trials)
+	overalls := make([]float64, 0, trials)
+	for k := 0; k < trials; k++ {
+		count, passed := 0, 0
+		accuracy, overall := 0.0, 0.0
+		for _, result := range results {
+			if k >= len(result.Trials) {
+				continue
+			}
+			trial := result.Trials[k]
+			count++
+			if trial.Passed {
+				passed++
+			}
+			accuracy += trial.Score.Accuracy
+			overall += trial.Score.Overall
+		}
+		if count == 0 {
+			continue
+		}
+		den := float64(count)
+		passRates = append(passRates, float64(passed)/den)
+		accuracies = append(accuracies, accuracy/den)
+		overalls = append(overalls, overall/den)
+	}
+	return &suiteNoise{
+		Trials:         trials,
+		PassRateMean:   mean(passRates),
+		PassRateStddev: stddev(passRates),
+		AccuracyMean:   mean(accuracies),
+		AccuracyStddev: stddev(accuracies),
+		OverallMean:    mean(overalls),
+		OverallStddev:  stddev(overalls),
+	}
+}
+
+// withinNoise reports whether metric and bound differ by less than one standard deviation.
+func withinNoise(metric float64, bound float64, spread float64) bool {
+	return spread > 0 && math.Abs(metric-bound) < spread
+}
+
+// scoreColumns splits scores into one slice per score component.
+func scoreColumns(scores []scoreBreakdown) (accuracy, natural, efficiency, overall []float64) {
+	for _, s := range scores {
+		accuracy = append(accuracy, s.Accuracy)
+		natural = append(natural, s.Natural)
+		efficiency = append(efficiency, s.Efficiency)
+		overall = append(overall, s.Overall)
+	}
+	return accuracy, natural, efficiency, overall
+}
+
+func meanScore(scores []scoreBreakdown) scoreBreakdown {
+	accuracy, natural, efficiency, overall := scoreColumns(scores)
+	return scoreBreakdown{Accuracy: mean(accuracy), Natural: mean(natural), Efficiency: mean(efficiency), Overall: mean(overall)}
+}
+
+func stddevScore(scores []scoreBreakdown) scoreBreakdown {
+	accuracy, natural, efficiency, overall := scoreColumns(scores)
+	return scoreBreakdown{Accuracy: stddev(accuracy), Natural: stddev(natural), Efficiency: stddev(efficiency), Overall: stddev(overall)}
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// stddev returns the sample standard deviation of values (0 for fewer than two values).
+func stddev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
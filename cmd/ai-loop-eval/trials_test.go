@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeTrialResults_MeanStddevAndPerTrialScores(t *testing.T) {
+	t.Parallel()
+
+	task := evalTask{ID: "repo_overview", Stage: "screen"}
+	merged := mergeTrialResults([]taskResult{
+		{Task: task, FinalText: "first", Score: scoreBreakdown{Accuracy: 80, Overall: 70}, Outcome: taskOutcome{Passed: true}},
+		{Task: task, FinalText: "second", Score: scoreBreakdown{Accuracy: 90, Overall: 80}, Outcome: taskOutcome{Passed: false}},
+		{Task: task, FinalText: "third", Score: scoreBreakdown{Accuracy: 100, Overall: 90}, Outcome: taskOutcome{Passed: true}},
+	})
+	if merged.FinalText != "first" {
+		t.Fatalf("FinalText=%q, want the first trial's details", merged.FinalText)
+	}
+	if len(merged.Trials) != 3 || merged.Trials[1].Trial != 2 || merged.Trials[1].Score.Overall != 80 || merged.Trials[1].Passed {
+		t.Fatalf("Trials=%+v", merged.Trials)
+	}
+	stats := merged.ScoreStats
+	if stats == nil || stats.Trials != 3 || stats.Mean.Overall != 80 || stats.Mean.Accuracy != 90 {
+		t.Fatalf("ScoreStats=%+v", stats)
+	}
+	if math.Abs(stats.Stddev.Overall-10) > 1e-9 || math.Abs(stats.PassRate-2.0/3.0) > 1e-9 {
+		t.Fatalf("ScoreStats stddev=%v pass_rate=%v, want 10 and 2/3", stats.Stddev.Overall, stats.PassRate)
+	}
+	if merged.Score != stats.Mean {
+		t.Fatalf("Score=%+v, want the trial mean", merged.Score)
+	}
+	if resultTrialCount(merged) != 3 {
+		t.Fatalf("resultTrialCount=%d", resultTrialCount(merged))
+	}
+
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var decoded struct {
+		Trials []struct {
+			Trial int `json:"trial"`
+			Score struct {
+				Overall float64 `json:"overall"`
+			} `json:"score"`
+		} `json:"trials"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(decoded.Trials) != 3 || decoded.Trials[2].Trial != 3 || decoded.Trials[2].Score.Overall != 90 {
+		t.Fatalf("report JSON trials=%+v", decoded.Trials)
+	}
+
+	single := mergeTrialResults([]taskResult{{Task: task, Score: scoreBreakdown{Overall: 55}}})
+	if single.ScoreStats != nil || single.Trials != nil || resultTrialCount(single) != 1 {
+		t.Fatalf("single trial result=%+v, want no trial stats", single)
+	}
+}
+
+func TestEvalTrialSeedAndKey_FirstTrialUnchanged(t *testing.T) {
+	t.Parallel()
+
+	if *evalTrialSeed(7, "task_a", 1) != *evalTaskSeed(7, "task_a") || evalTrialKey("task_a", 1) != "task_a" {
+		t.Fatalf("first trial must keep the task seed and key")
+	}
+	if *evalTrialSeed(7, "task_a", 2) == *evalTaskSeed(7, "task_a") {
+		t.Fatalf("later trials must sample with a different seed")
+	}
+	if key := evalTrialKey("task_a", 2); key == "task_a" || strings.Contains(sanitizeID(key), ".") {
+		t.Fatalf("trial key=%q", key)
+	}
+}
+
+func TestEvaluateGate_FlagsComparisonsWithinNoise(t *testing.T) {
+	t.Parallel()
+
+	results := []taskResult{
+		{Trials: []trialScore{{Passed: true, Score: scoreBreakdown{Accuracy: 76}}, {Passed: true, Score: scoreBreakdown{Accuracy: 84}}}},
+		{Trials: []trialScore{{Passed: true, Score: scoreBreakdown{Accuracy: 76}}, {Passed: true, Score: scoreBreakdown{Accuracy: 84}}}},
+	}
+	noise := computeSuiteNoise(results)
+	if noise == nil || noise.Trials != 2 || noise.AccuracyMean != 80 || math.Abs(noise.AccuracyStddev-math.Sqrt(32)) > 1e-9 {
+		t.Fatalf("noise=%+v", noise)
+	}
+	if computeSuiteNoise([]taskResult{{Score: scoreBreakdown{Accuracy: 80}}}) != nil {
+		t.Fatalf("single-trial runs must not report noise")
+	}
+
+	metrics := suiteMetrics{PassRate: 1, LoopSafetyRate: 1, RecoverySuccessRate: 1, FallbackFreeRate: 1, AverageAccuracy: 79}
+	baselines := benchmarkBaselines{Sources: map[string]benchmarkMetrics{"ref": {PassRate: 1, LoopSafetyRate: 1, RecoverySuccessRate: 1, FallbackFreeRate: 1, AverageAccuracy: 70}}}
+	thresholds := gateThresholds{MinPassRate: 0.8, MinLoopSafetyRate: 0.9, MinFallbackFreeRate: 0.9, MinAverageAccuracy: 80}
+
+	gate := evaluateGate(metrics, baselines, thresholds, noise)
+	if gate.Status != "reject" || len(gate.Reasons) != 1 || !strings.Contains(gate.Reasons[0], "average_accuracy 79.00 < threshold 80.00 (within noise") {
+		t.Fatalf("gate=%+v, want one accuracy reason marked within noise", gate)
+	}
+	if !gate.WithinNoise || len(gate.NoiseNotes) != 1 {
+		t.Fatalf("WithinNoise=%v notes=%v", gate.WithinNoise, gate.NoiseNotes)
+	}
+
+	metrics.AverageAccuracy = 81
+	passing := evaluateGate(metrics, baselines, thresholds, noise)
+	if passing.Status != "pass" || !passing.WithinNoise || !strings.Contains(passing.NoiseNotes[0], "average_accuracy 81.00 >= threshold 80.00 (within noise") {
+		t.Fatalf("gate=%+v, want a pass flagged within noise", passing)
+	}
+	if quiet := evaluateGate(metrics, baselines, thresholds, nil); quiet.WithinNoise || len(quiet.NoiseNotes) != 0 {
+		t.Fatalf("gate without trials=%+v, want no noise notes", quiet)
+	}
+}
+
+func TestWriteMarkdown_ReportsTrialsAndWithinNoise(t *testing.T) {
+	t.Parallel()
+
+	result := mergeTrialResults([]taskResult{
+		{Task: evalTask{ID: "task_a"}, Score: scoreBreakdown{Overall: 70}},
+		{Task: evalTask{ID: "task_a"}, Score: scoreBreakdown{Overall: 90}},
+	})
+	report := evalReport{
+		GeneratedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		TaskCount:   1,
+		Trials:      2,
+		Noise:       computeSuiteNoise([]taskResult{result}),
+		Results:     []taskResult{result},
+		Gate:        gateReport{Enabled: true, Status: "pass", WithinNoise: true, NoiseNotes: []string{"average_accuracy 81.00 >= threshold 80.00 (within noise: stddev 5.66 over 2 trials)"}},
+	}
+	path := filepath.Join(t.TempDir(), "report.md")
+	if err := writeMarkdown(path, report); err != nil {
+		t.Fatalf("writeMarkdown: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	out := string(raw)
+	for _, want := range []string{
+		"- Trials per task: 2",
+		"- Trial spread (2 trials):",
+		"- Within noise: the decision may flip on a re-run.",
+		"- Trials: 2, overall mean 80.00 ± 14.14, pass rate 0.00 (scores 70.00, 90.00)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("report.md missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const batchMessageLogName = "message.log"
+
+// batchEntry is the replay outcome for one message.log found under the batch root.
+type batchEntry struct {
+	// RelPath is the log path relative to the batch root, with forward slashes.
+	RelPath  string
+	Report   replayReport
+	Err      error
+	Duration time.Duration
+}
+
+func (e batchEntry) failed() bool {
+	return e.Err != nil || e.Report.Status != "pass"
+}
+
+// findMessageLogs returns every message.log under root, sorted so batch output is stable.
+func findMessageLogs(root string) ([]string, error) {
+	var out []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == batchMessageLogName {
+			out = append(out, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// runBatch replays every message.log under root. A log that cannot be read or parsed is a failed entry, not an error.
+func runBatch(root string, rules replayRules) ([]batchEntry, error) {
+	paths, err := findMessageLogs(root)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]batchEntry, 0, len(paths))
+	for _, path := range paths {
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			rel = path
+		}
+		started := time.Now()
+		report, replayErr := runReplay(path, rules)
+		entries = append(entries, batchEntry{
+			RelPath:  filepath.ToSlash(rel),
+			Report:   report,
+			Err:      replayErr,
+			Duration: time.Since(started),
+		})
+	}
+	return entries, nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnit writes one testcase per log; replay failures carry the report reasons.
+func writeJUnit(path string, entries []batchEntry) error {
+	suite := junitSuite{Name: "ai-loop-replay", Tests: len(entries)}
+	var total time.Duration
+	for _, entry := range entries {
+		total += entry.Duration
+		tc := junitTestCase{
+			Name:      entry.RelPath,
+			ClassName: "ai-loop-replay",
+			Time:      junitSeconds(entry.Duration),
+		}
+		switch {
+		case entry.Err != nil:
+			suite.Errors++
+			tc.Error = &junitFailure{Message: entry.Err.Error(), Type: "replay_error", Body: entry.Err.Error()}
+		case entry.Report.Status != "pass":
+			suite.Failures++
+			reasons := strings.Join(entry.Report.Reasons, ", ")
+			tc.Failure = &junitFailure{Message: reasons, Type: "replay_fail", Body: strings.Join(entry.Report.Reasons, "\n")}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Time = junitSeconds(total)
+	doc := junitTestSuites{Tests: suite.Tests, Failures: suite.Failures, Errors: suite.Errors, Suites: []junitSuite{suite}}
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	out := append([]byte(xml.Header), b...)
+	out = append(out, '\n')
+	return os.WriteFile(path, out, 0o600)
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// writeJSONReports mirrors the batch tree under dir, writing <log dir>/replay.json for every replayed log.
+func writeJSONReports(dir string, entries []batchEntry) error {
+	for _, entry := range entries {
+		if entry.Err != nil {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(filepath.Dir(entry.RelPath)), "replay.json")
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		b, err := json.MarshalIndent(entry.Report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, append(b, '\n'), 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const batchPassLog = `{"ok":true,"data":{"messages":[{"role":"assistant","blocks":[{"type":"markdown","content":"Summary: the build passes and the config loader is covered."}]}]}}`
+
+const batchFailLog = `{"ok":true,"data":{"messages":[{"role":"assistant","blocks":[{"type":"markdown","content":"I have reached the current automatic loop limit."}]}]}}`
+
+func writeBatchLog(t *testing.T, root string, rel string, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", rel, err)
+	}
+}
+
+func TestRunBatch_JUnitAndJSONReports(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeBatchLog(t, root, "b/run_2/message.log", batchFailLog)
+	writeBatchLog(t, root, "a/run_1/message.log", batchPassLog)
+	writeBatchLog(t, root, "c/message.log", "not json")
+	writeBatchLog(t, root, "a/run_1/other.log", batchFailLog)
+
+	entries, err := runBatch(root, defaultReplayRules())
+	if err != nil {
+		t.Fatalf("runBatch: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entries=%d, want 3", len(entries))
+	}
+	if entries[0].RelPath != "a/run_1/message.log" || entries[0].failed() {
+		t.Fatalf("entries[0]=%+v, want passing a/run_1", entries[0])
+	}
+	if entries[1].RelPath != "b/run_2/message.log" || entries[1].Report.Status != "fail" {
+		t.Fatalf("entries[1]=%+v, want failing b/run_2", entries[1])
+	}
+	if entries[2].Err == nil || !entries[2].failed() {
+		t.Fatalf("entries[2]=%+v, want parse error", entries[2])
+	}
+
+	junitPath := filepath.Join(t.TempDir(), "junit.xml")
+	if err := writeJUnit(junitPath, entries); err != nil {
+		t.Fatalf("writeJUnit: %v", err)
+	}
+	raw, err := os.ReadFile(junitPath)
+	if err != nil {
+		t.Fatalf("read junit: %v", err)
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("parse junit: %v", err)
+	}
+	if doc.Tests != 3 || doc.Failures != 1 || doc.Errors != 1 || len(doc.Suites) != 1 {
+		t.Fatalf("unexpected junit totals: %+v", doc)
+	}
+	failure := doc.Suites[0].TestCases[1].Failure
+	if failure == nil || !strings.Contains(failure.Message, "fallback_phrase:") {
+		t.Fatalf("failure should carry replay reasons: %+v", failure)
+	}
+
+	jsonDir := t.TempDir()
+	if err := writeJSONReports(jsonDir, entries); err != nil {
+		t.Fatalf("writeJSONReports: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(jsonDir, "b", "run_2", "replay.json"))
+	if err != nil {
+		t.Fatalf("read per-file report: %v", err)
+	}
+	var report replayReport
+	if err := json.Unmarshal(b, &report); err != nil || report.Status != "fail" {
+		t.Fatalf("per-file report=%+v err=%v", report, err)
+	}
+	if _, err := os.Stat(filepath.Join(jsonDir, "c", "replay.json")); !os.IsNotExist(err) {
+		t.Fatalf("unreadable log should not produce a report: %v", err)
+	}
+}
@@ -31,13 +31,33 @@ type replayReport struct {
 func main() {
 	messageLogPath := flag.String("message-log", "", "message.log path")
 	expect := flag.String("expect", "", "optional expectation: pass|fail")
+	batchDir := flag.String("dir", "", "replay every message.log under this directory (batch mode)")
+	junitPath := flag.String("junit", "", "batch mode: write a JUnit XML summary to this path")
+	jsonDir := flag.String("json-dir", "", "batch mode: write each log's replay report under this directory")
+	rulesPath := flag.String("rules", "", "optional rules yaml with fallback phrases (including localized_fallback_phrases), conclusion hints, and thresholds")
+	rulesReplace := flag.Bool("rules-replace", false, "use --rules as-is instead of merging it with the default rules")
 	flag.Parse()
 
+	rules := defaultReplayRules()
+	if path := strings.TrimSpace(*rulesPath); path != "" {
+		loaded, err := loadReplayRules(path, *rulesReplace)
+		if err != nil {
+			fatalf("invalid replay rules: %v", err)
+		}
+		rules = loaded
+	} else if *rulesReplace {
+		fatalf("--rules-replace requires --rules")
+	}
+
 	if strings.TrimSpace(*messageLogPath) == "" {
-		fatalf("--message-log is required")
+		if strings.TrimSpace(*batchDir) != "" {
+			runBatchMode(strings.TrimSpace(*batchDir), strings.TrimSpace(*junitPath), strings.TrimSpace(*jsonDir), rules)
+			return
+		}
+		fatalf("--message-log or --dir is required")
 	}
 
-	report, err := runReplay(strings.TrimSpace(*messageLogPath))
+	report, err := runReplay(strings.TrimSpace(*messageLogPath), rules)
 	if err != nil {
 		fatalf("replay failed: %v", err)
 	}
@@ -60,7 +80,45 @@ func main() {
 	}
 }
 
-func runReplay(path string) (replayReport, error) {
+func runBatchMode(root string, junitPath string, jsonDir string, rules replayRules) {
+	entries, err := runBatch(root, rules)
+	if err != nil {
+		fatalf("batch replay failed: %v", err)
+	}
+	if len(entries) == 0 {
+		fatalf("no %s found under %s", batchMessageLogName, root)
+	}
+	if junitPath != "" {
+		if err := writeJUnit(junitPath, entries); err != nil {
+			fatalf("failed to write junit report: %v", err)
+		}
+	}
+	if jsonDir != "" {
+		if err := writeJSONReports(jsonDir, entries); err != nil {
+			fatalf("failed to write json reports: %v", err)
+		}
+	}
+
+	failed := 0
+	for _, entry := range entries {
+		switch {
+		case entry.Err != nil:
+			failed++
+			fmt.Printf("error %s: %v\n", entry.RelPath, entry.Err)
+		case entry.failed():
+			failed++
+			fmt.Printf("fail  %s: %s\n", entry.RelPath, strings.Join(entry.Report.Reasons, ", "))
+		default:
+			fmt.Printf("pass  %s\n", entry.RelPath)
+		}
+	}
+	fmt.Printf("[ai-loop-replay] %d logs, %d failed\n", len(entries), failed)
+	if failed > 0 {
+		os.Exit(2)
+	}
+}
+
+func runReplay(path string, rules replayRules) (replayReport, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return replayReport{}, err
@@ -110,7 +168,7 @@ func runReplay(path string) (replayReport, error) {
 		}
 	}
 
-	reasons := evaluateReplay(assistantText, toolCalls)
+	reasons := evaluateReplay(assistantText, toolCalls, rules)
 	report := replayReport{
 		Status:         "pass",
 		Reasons:        nil,
@@ -124,29 +182,19 @@ func runReplay(path string) (replayReport, error) {
 	return report, nil
 }
 
-func evaluateReplay(assistantText string, toolCalls int) []string {
+func evaluateReplay(assistantText string, toolCalls int, rules replayRules) []string {
 	text := strings.TrimSpace(strings.ToLower(assistantText))
 	reasons := make([]string, 0, 4)
 	if text == "" {
 		reasons = append(reasons, "empty_assistant_text")
 	}
-	fallbackPhrases := []string{
-		"i have reached the current automatic loop limit",
-		"reply with one concrete next step",
-		"assistant finished without a visible response",
-		"tool workflow failed",
-		"no response",
-	}
-	for _, phrase := range fallbackPhrases {
-		if strings.Contains(text, phrase) {
-			reasons = append(reasons, "fallback_phrase:"+phrase)
-			break
-		}
+	if phrase, _, ok := rules.fallbackCatalog().Match(text); ok {
+		reasons = append(reasons, "fallback_phrase:"+phrase)
 	}
-	if toolCalls > 0 && utf8.RuneCountInString(text) < 40 {
+	if toolCalls > 0 && utf8.RuneCountInString(text) < rules.MinCharsAfterToolCalls {
 		reasons = append(reasons, "too_short_after_tool_calls")
 	}
-	if toolCalls >= 6 && !containsAny(text, []string{"conclusion", "result", "findings", "summary"}) {
+	if toolCalls >= rules.ManyToolCallsThreshold && !containsAny(text, rules.ConclusionHints) {
 		reasons = append(reasons, "many_tool_calls_without_conclusion")
 	}
 	return reasons
@@ -34,7 +34,7 @@ func TestRunReplay_UsesTaskCompleteFallback(t *testing.T) {
 		t.Fatalf("write fixture: %v", err)
 	}
 
-	report, err := runReplay(path)
+	report, err := runReplay(path, defaultReplayRules())
 	if err != nil {
 		t.Fatalf("runReplay: %v", err)
 	}
@@ -82,7 +82,7 @@ func TestRunReplay_UsesAskUserFallback(t *testing.T) {
 		t.Fatalf("write fixture: %v", err)
 	}
 
-	report, err := runReplay(path)
+	report, err := runReplay(path, defaultReplayRules())
 	if err != nil {
 		t.Fatalf("runReplay: %v", err)
 	}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/floegence/redeven/internal/ai"
+	"gopkg.in/yaml.v3"
+)
+
+// replayRules are the phrase lists and thresholds evaluateReplay gates on.
+type replayRules struct {
+	// FallbackPhrases fail a replay when the final assistant text contains any of them.
+	FallbackPhrases []string `yaml:"fallback_phrases"`
+	// LocalizedFallbackPhrases are fallback phrases keyed by language code, for non-English runs.
+	LocalizedFallbackPhrases map[string][]string `yaml:"localized_fallback_phrases"`
+	// ConclusionHints must appear in the final text of tool-heavy runs.
+	ConclusionHints []string `yaml:"conclusion_hints"`
+	// MinCharsAfterToolCalls is the shortest acceptable final text once any tool ran.
+	MinCharsAfterToolCalls int `yaml:"min_chars_after_tool_calls"`
+	// ManyToolCallsThreshold is the tool call count from which a conclusion hint is required.
+	ManyToolCallsThreshold int `yaml:"many_tool_calls_threshold"`
+}
+
+func defaultReplayRules() replayRules {
+	catalog := ai.DefaultFallbackPhraseCatalog()
+	localized := make(map[string][]string, len(catalog))
+	for language, phrases := range catalog {
+		if language != ai.FallbackPhraseLanguageEnglish {
+			localized[language] = phrases
+		}
+	}
+	return replayRules{
+		FallbackPhrases:          catalog[ai.FallbackPhraseLanguageEnglish],
+		LocalizedFallbackPhrases: localized,
+		ConclusionHints:          []string{"conclusion", "result", "findings", "summary"},
+		MinCharsAfterToolCalls:   40,
+		ManyToolCallsThreshold:   6,
+	}
+}
+
+// fallbackCatalog returns the rules' fallback phrases as a catalog; FallbackPhrases count as English.
+func (r replayRules) fallbackCatalog() ai.FallbackPhraseCatalog {
+	return ai.FallbackPhraseCatalog{ai.FallbackPhraseLanguageEnglish: r.FallbackPhrases}.Merge(r.LocalizedFallbackPhrases)
+}
+
+// loadReplayRules reads a rules YAML file and merges it into the defaults.
+//
+// Phrase lists, including each language's localized fallback phrases, are appended to the defaults and thresholds
+// override them when set. With replace, the file is used as-is and must define every threshold and at least one
+// conclusion hint.
+func loadReplayRules(path string, replace bool) (replayRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return replayRules{}, err
+	}
+	var file replayRules
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return replayRules{}, fmt.Errorf("rules %s: %w", path, err)
+	}
+	if err := validateReplayRules(file, replace); err != nil {
+		return replayRules{}, fmt.Errorf("rules %s: %w", path, err)
+	}
+	if replace {
+		file.FallbackPhrases = normalizePhrases(file.FallbackPhrases)
+		file.LocalizedFallbackPhrases = ai.FallbackPhraseCatalog{}.Merge(file.LocalizedFallbackPhrases)
+		file.ConclusionHints = normalizePhrases(file.ConclusionHints)
+		return file, nil
+	}
+	rules := defaultReplayRules()
+	rules.FallbackPhrases = normalizePhrases(append(rules.FallbackPhrases, file.FallbackPhrases...))
+	rules.LocalizedFallbackPhrases = ai.FallbackPhraseCatalog(rules.LocalizedFallbackPhrases).Merge(file.LocalizedFallbackPhrases)
+	rules.ConclusionHints = normalizePhrases(append(rules.ConclusionHints, file.ConclusionHints...))
+	if file.MinCharsAfterToolCalls > 0 {
+		rules.MinCharsAfterToolCalls = file.MinCharsAfterToolCalls
+	}
+	if file.ManyToolCallsThreshold > 0 {
+		rules.ManyToolCallsThreshold = file.ManyToolCallsThreshold
+	}
+	return rules, nil
+}
+
+func validateReplayRules(rules replayRules, replace bool) error {
+	for i, phrase := range rules.FallbackPhrases {
+		if strings.TrimSpace(phrase) == "" {
+			return fmt.Errorf("fallback_phrases[%d] is empty", i)
+		}
+	}
+	for language, phrases := range rules.LocalizedFallbackPhrases {
+		if strings.TrimSpace(language) == "" {
+			return fmt.Errorf("localized_fallback_phrases has an empty language code")
+		}
+		for i, phrase := range phrases {
+			if strings.TrimSpace(phrase) == "" {
+				return fmt.Errorf("localized_fallback_phrases[%s][%d] is empty", language, i)
+			}
+		}
+	}
+	for i, hint := range rules.ConclusionHints {
+		if strings.TrimSpace(hint) == "" {
+			return fmt.Errorf("conclusion_hints[%d] is empty", i)
+		}
+	}
+	if rules.MinCharsAfterToolCalls < 0 || (replace && rules.MinCharsAfterToolCalls == 0) {
+		return fmt.Errorf("min_chars_after_tool_calls must be positive")
+	}
+	if rules.ManyToolCallsThreshold < 0 || (replace && rules.ManyToolCallsThreshold == 0) {
+		return fmt.Errorf("many_tool_calls_threshold must be positive")
+	}
+	if replace && len(rules.ConclusionHints) == 0 {
+		return fmt.Errorf("conclusion_hints must not be empty when replacing the defaults")
+	}
+	return nil
+}
+
+// normalizePhrases lowercases and trims phrases, dropping duplicates while keeping order.
+func normalizePhrases(in []string) []string {
+	out := make([]string, 0, len(in))
+	seen := make(map[string]struct{}, len(in))
+	for _, phrase := range in {
+		phrase = strings.ToLower(strings.TrimSpace(phrase))
+		if _, ok := seen[phrase]; ok {
+			continue
+		}
+		seen[phrase] = struct{}{}
+		out = append(out, phrase)
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeRulesFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	return path
+}
+
+func TestLoadReplayRules_MergesWithDefaults(t *testing.T) {
+	t.Parallel()
+
+	path := writeRulesFile(t, `fallback_phrases:
+  - "  Ich habe das automatische Limit erreicht "
+  - "no response"
+conclusion_hints: ["fazit"]
+many_tool_calls_threshold: 3
+`)
+	rules, err := loadReplayRules(path, false)
+	if err != nil {
+		t.Fatalf("loadReplayRules: %v", err)
+	}
+	defaults := defaultReplayRules()
+	if len(rules.FallbackPhrases) != len(defaults.FallbackPhrases)+1 || !slices.Contains(rules.FallbackPhrases, "ich habe das automatische limit erreicht") {
+		t.Fatalf("fallback phrases=%v", rules.FallbackPhrases)
+	}
+	if !slices.Contains(rules.ConclusionHints, "fazit") || !slices.Contains(rules.ConclusionHints, "summary") {
+		t.Fatalf("conclusion hints=%v", rules.ConclusionHints)
+	}
+	if rules.ManyToolCallsThreshold != 3 || rules.MinCharsAfterToolCalls != defaults.MinCharsAfterToolCalls {
+		t.Fatalf("thresholds=%d/%d", rules.ManyToolCallsThreshold, rules.MinCharsAfterToolCalls)
+	}
+
+	text := "Ich habe das automatische Limit erreicht, bitte antworten."
+	reasons := evaluateReplay(text, 3, rules)
+	if !slices.Contains(reasons, "fallback_phrase:ich habe das automatische limit erreicht") || !slices.Contains(reasons, "many_tool_calls_without_conclusion") {
+		t.Fatalf("reasons=%v", reasons)
+	}
+}
+
+func TestLoadReplayRules_ReplaceAndValidation(t *testing.T) {
+	t.Parallel()
+
+	path := writeRulesFile(t, `fallback_phrases: ["limite atteinte"]
+conclusion_hints: ["conclusion"]
+min_chars_after_tool_calls: 10
+many_tool_calls_threshold: 2
+`)
+	rules, err := loadReplayRules(path, true)
+	if err != nil {
+		t.Fatalf("loadReplayRules replace: %v", err)
+	}
+	if len(rules.FallbackPhrases) != 1 || rules.MinCharsAfterToolCalls != 10 {
+		t.Fatalf("replace rules=%+v", rules)
+	}
+	if reasons := evaluateReplay("no response here, done.", 1, rules); len(reasons) != 0 {
+		t.Fatalf("replaced rules should drop default phrases, got %v", reasons)
+	}
+
+	cases := map[string]struct {
+		content string
+		replace bool
+		want    string
+	}{
+		"empty_phrase":       {content: "fallback_phrases: [\"ok\", \"  \"]\n", want: "fallback_phrases[1] is empty"},
+		"negative_threshold": {content: "min_chars_after_tool_calls: -1\n", want: "min_chars_after_tool_calls must be positive"},
+		"replace_missing":    {content: "conclusion_hints: [\"done\"]\n", replace: true, want: "min_chars_after_tool_calls must be positive"},
+		"unknown_field":      {content: "fallback_phrase: [\"typo\"]\n", want: "field fallback_phrase not found"},
+	}
+	for name, tc := range cases {
+		_, err := loadReplayRules(writeRulesFile(t, tc.content), tc.replace)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: err=%v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestEvaluateReplay_DetectsLocalizedFallbackPhrases(t *testing.T) {
+	t.Parallel()
+
+	defaults := defaultReplayRules()
+	reasons := evaluateReplay("我已经检查了配置文件，但已达到当前自动循环上限，请回复一个具体的下一步。", 2, defaults)
+	if !slices.Contains(reasons, "fallback_phrase:已达到当前自动循环上限") {
+		t.Fatalf("reasons=%v", reasons)
+	}
+	if reasons := evaluateReplay("结论：配置加载器在启动时没有初始化 map，已修复并通过全部测试，问题已经解决。", 2, defaults); len(reasons) != 0 {
+		t.Fatalf("clean Chinese conclusion reasons=%v", reasons)
+	}
+
+	path := writeRulesFile(t, `localized_fallback_phrases:
+  zh: ["  任务暂停 "]
+  ja: ["応答がありません"]
+`)
+	rules, err := loadReplayRules(path, false)
+	if err != nil {
+		t.Fatalf("loadReplayRules: %v", err)
+	}
+	if !slices.Contains(rules.LocalizedFallbackPhrases["zh"], "任务暂停") || !slices.Contains(rules.LocalizedFallbackPhrases["zh"], "无响应") {
+		t.Fatalf("zh phrases=%v", rules.LocalizedFallbackPhrases["zh"])
+	}
+	if reasons := evaluateReplay("エラーのため応答がありません。", 0, rules); !slices.Contains(reasons, "fallback_phrase:応答がありません") {
+		t.Fatalf("ja reasons=%v", reasons)
+	}
+
+	if _, err := loadReplayRules(writeRulesFile(t, "localized_fallback_phrases:\n  zh: [\" \"]\n"), false); err == nil || !strings.Contains(err.Error(), "localized_fallback_phrases[zh][0] is empty") {
+		t.Fatalf("empty localized phrase err=%v", err)
+	}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/floegence/redeven/internal/knowledge"
 )
@@ -15,8 +18,23 @@ func main() {
 	distRoot := flag.String("dist-root", cleanAbs(filepath.Join("internal", "knowledge", "dist")), "Dist output root")
 	verifyOnly := flag.Bool("verify-only", false, "Verify dist files without rewriting")
 	validateSourceOnly := flag.Bool("validate-source-only", false, "Validate source files only without reading dist")
+	watch := flag.Bool("watch", false, "Watch source-root and rewrite changed dist files on every change")
 	flag.Parse()
 
+	if *watch {
+		if *verifyOnly || *validateSourceOnly {
+			fmt.Fprintln(os.Stderr, "-watch cannot be combined with -verify-only or -validate-source-only")
+			os.Exit(2)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := watchAndRebuild(ctx, cleanAbs(*sourceRoot), cleanAbs(*distRoot)); err != nil {
+			fmt.Fprintf(os.Stderr, "knowledge bundle watch failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	result, err := knowledge.BuildFromSource(cleanAbs(*sourceRoot))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "knowledge bundle build failed: %v\n", err)
@@ -36,10 +54,15 @@ func main() {
 		return
 	}
 
-	if err := knowledge.WriteDistFiles(cleanAbs(*distRoot), result); err != nil {
+	written, err := knowledge.WriteDistFiles(cleanAbs(*distRoot), result)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "knowledge bundle write failed: %v\n", err)
 		os.Exit(1)
 	}
+	if len(written) == 0 {
+		fmt.Printf("knowledge bundle unchanged: %s\n", cleanAbs(*distRoot))
+		return
+	}
 	fmt.Printf("knowledge bundle updated: %s\n", cleanAbs(*distRoot))
 }
 
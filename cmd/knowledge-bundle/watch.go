@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/floegence/redeven/internal/knowledge"
+)
+
+// watchDebounce coalesces editor save bursts (temp file, rename, chmod) into one rebuild.
+const watchDebounce = 300 * time.Millisecond
+
+// watchAndRebuild rebuilds the bundle whenever source-root changes until ctx is done.
+//
+// Build errors are reported and the watch keeps running, so a half-edited card does not end the session.
+func watchAndRebuild(ctx context.Context, sourceRoot string, distRoot string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = watcher.Close() }()
+
+	if err := addWatchTree(watcher, sourceRoot); err != nil {
+		return err
+	}
+	fmt.Printf("knowledge bundle watching: %s\n", sourceRoot)
+	rebuildAndWrite(sourceRoot, distRoot)
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) {
+				if st, err := os.Stat(event.Name); err == nil && st.IsDir() {
+					if err := addWatchTree(watcher, event.Name); err != nil {
+						fmt.Fprintf(os.Stderr, "knowledge bundle watch failed to add %s: %v\n", event.Name, err)
+					}
+				}
+			}
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			pending = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "knowledge bundle watch error: %v\n", err)
+		case <-pending:
+			pending = nil
+			rebuildAndWrite(sourceRoot, distRoot)
+		}
+	}
+}
+
+// addWatchTree registers root and every directory below it; fsnotify does not watch recursively.
+func addWatchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return watcher.Add(path)
+	})
+}
+
+func rebuildAndWrite(sourceRoot string, distRoot string) {
+	result, err := knowledge.BuildFromSource(sourceRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "knowledge bundle build failed: %v\n", err)
+		return
+	}
+	written, err := knowledge.WriteDistFiles(distRoot, result)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "knowledge bundle write failed: %v\n", err)
+		return
+	}
+	if len(written) == 0 {
+		fmt.Printf("knowledge bundle unchanged: %s\n", distRoot)
+		return
+	}
+	fmt.Printf("knowledge bundle updated: %s (%s)\n", distRoot, strings.Join(written, ", "))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/settings"
+)
+
+func (c *cli) aiCmd(args []string) int {
+	if len(args) == 0 {
+		writeText(c.stderr, aiHelpText())
+		return 2
+	}
+	if isHelpToken(args[0]) {
+		writeText(c.stdout, aiHelpText())
+		return 0
+	}
+
+	switch strings.TrimSpace(strings.ToLower(args[0])) {
+	case "test-provider":
+		return c.aiTestProviderCmd(args[1:])
+	default:
+		writeErrorWithHelp(
+			c.stderr,
+			fmt.Sprintf("unknown command for `redeven ai`: %s", strings.TrimSpace(args[0])),
+			[]string{"Run `redeven help ai` for usage information."},
+			aiHelpText(),
+		)
+		return 2
+	}
+}
+
+func (c *cli) aiTestProviderCmd(args []string) int {
+	fs := newCLIFlagSet("ai test-provider")
+	format := fs.String("format", "text", "Output format: json|text (default: text)")
+	scopeRaw := fs.String("scope", "", "Scope selector: local, local/<name>, named/<name>, or controlplane/<provider_key>/<env_id>")
+	stateRoot := fs.String("state-root", "", "State root override (default: $REDEVEN_STATE_ROOT or ~/.redeven)")
+	configPath := fs.String("config-path", "", "Config path override")
+	secretsPath := fs.String("secrets-path", "", "Secrets path (default: the configured secrets_backend, else <config dir>/secrets.json)")
+	timeout := fs.Duration("timeout", 20*time.Second, "Provider request timeout")
+
+	if err := parseCommandFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			writeText(c.stdout, aiTestProviderHelpText())
+			return 0
+		}
+		message, details := translateFlagParseError("ai test-provider", err)
+		writeErrorWithHelp(c.stderr, message, details, aiTestProviderHelpText())
+		return 2
+	}
+
+	providerID := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if providerID == "" {
+		writeErrorWithHelp(
+			c.stderr,
+			"missing provider id for `redeven ai test-provider`",
+			[]string{"Example: redeven ai test-provider openai"},
+			aiTestProviderHelpText(),
+		)
+		return 2
+	}
+	outputFormat := strings.TrimSpace(strings.ToLower(*format))
+	if outputFormat != "" && outputFormat != "json" && outputFormat != "text" {
+		writeErrorWithHelp(
+			c.stderr,
+			fmt.Sprintf("invalid value for `--format`: %s", strings.TrimSpace(*format)),
+			[]string{"Allowed values: json, text."},
+			aiTestProviderHelpText(),
+		)
+		return 2
+	}
+
+	scopeRef, err := parseOptionalScopeRef(*scopeRaw)
+	if err != nil {
+		writeErrorWithHelp(c.stderr, fmt.Sprintf("invalid value for `--scope`: %v", err), nil, aiTestProviderHelpText())
+		return 2
+	}
+	if err := validateStateLayoutSelection(*configPath, scopeRef, *stateRoot); err != nil {
+		writeErrorWithHelp(c.stderr, err.Error(), nil, aiTestProviderHelpText())
+		return 2
+	}
+	stateLayout, err := resolveLocalCommandStateLayout(*configPath, *stateRoot, scopeRef)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to resolve config path: %v\n", err)
+		return 1
+	}
+	cfg, err := config.Load(stateLayout.ConfigPath)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to load config: %v\n", err)
+		return 1
+	}
+	if cfg.AI == nil {
+		fmt.Fprintf(c.stderr, "ai is not configured in %s\n", stateLayout.ConfigPath)
+		return 1
+	}
+	// An explicit -secrets-path file wins over the configured secrets backend.
+	var store settings.SecretsBackend
+	if secrets := strings.TrimSpace(*secretsPath); secrets != "" {
+		store = settings.NewSecretsStore(secrets)
+	} else {
+		store, err = settings.NewSecretsBackend(cfg.SecretsBackend, filepath.Join(stateLayout.StateDir, "secrets.json"))
+		if err != nil {
+			fmt.Fprintf(c.stderr, "failed to open secrets backend: %v\n", err)
+			return 1
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	result, err := ai.TestProviderConnectivity(ctx, cfg.AI, providerID, store.GetAIProviderAPIKey)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "provider test failed: %v\n", err)
+		return 1
+	}
+
+	if outputFormat == "json" {
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintf(c.stderr, "failed to encode result: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(c.stdout, "%s\n", string(b))
+	} else {
+		status := "ok"
+		if !result.OK {
+			status = "failed"
+		}
+		fmt.Fprintf(c.stdout, "provider:           %s (%s)\n", result.ProviderID, result.ProviderType)
+		fmt.Fprintf(c.stdout, "model:              %s\n", result.Model)
+		fmt.Fprintf(c.stdout, "base url:           %s\n", result.BaseURL)
+		fmt.Fprintf(c.stdout, "strict tool schema: %t\n", result.StrictToolSchema)
+		fmt.Fprintf(c.stdout, "latency:            %dms\n", result.LatencyMS)
+		fmt.Fprintf(c.stdout, "status:             %s\n", status)
+		if !result.OK {
+			fmt.Fprintf(c.stdout, "error:              %s: %s\n", result.ErrorCode, result.Error)
+		}
+	}
+	if !result.OK {
+		return 1
+	}
+	return 0
+}
@@ -32,6 +32,7 @@ Commands:
   run         Start the runtime in remote, hybrid, local, or desktop mode.
   search      Run web search using configured provider credentials.
   knowledge   Build or verify embedded knowledge bundle assets.
+  ai          Check Flower AI provider configuration.
   version     Print build information.
   help        Show detailed help and startup examples.
 
@@ -265,6 +266,50 @@ Examples:
 `, "\n")
 }
 
+func aiHelpText() string {
+	return strings.TrimLeft(`
+redeven ai
+
+Check Flower AI provider configuration.
+
+Usage:
+  redeven ai <command> [flags]
+  redeven help ai test-provider
+
+Commands:
+  test-provider   Send a minimal request to a configured provider and report the result.
+
+Examples:
+  redeven ai test-provider openai
+`, "\n")
+}
+
+func aiTestProviderHelpText() string {
+	return strings.TrimLeft(`
+redeven ai test-provider
+
+Send a minimal request to a configured provider and report latency, resolved base URL,
+strict tool schema choice, and a normalized error. API keys are never printed.
+
+Usage:
+  redeven ai test-provider [flags] <provider_id>
+
+Flags:
+  --format <json|text>              Output format (default: text).
+  --scope <selector>                Scope selector: local, local/<name>, named/<name>, or controlplane/<provider_key>/<env_id>.
+  --state-root <path>               State root override (default: $REDEVEN_STATE_ROOT or ~/.redeven).
+  --config-path <path>              Config path override.
+  --secrets-path <path>             Secrets path override.
+  --timeout <duration>              Provider request timeout (default: 20s).
+
+Exit status is 0 when the provider answered and 1 otherwise.
+
+Examples:
+  redeven ai test-provider openai
+  redeven ai test-provider --format json --scope named/dev-a anthropic
+`, "\n")
+}
+
 func versionHelpText() string {
 	return strings.TrimLeft(`
 redeven version
@@ -313,6 +358,10 @@ func lookupHelpText(args []string) (string, bool) {
 		return knowledgeHelpText(), true
 	case "knowledge bundle":
 		return knowledgeBundleHelpText(), true
+	case "ai":
+		return aiHelpText(), true
+	case "ai test-provider":
+		return aiTestProviderHelpText(), true
 	case "version":
 		return versionHelpText(), true
 	default:
@@ -70,10 +70,15 @@ func (c *cli) knowledgeBundleCmd(args []string) int {
 		return 0
 	}
 
-	if err := knowledge.WriteDistFiles(cleanAbs(*distRoot), result); err != nil {
+	written, err := knowledge.WriteDistFiles(cleanAbs(*distRoot), result)
+	if err != nil {
 		fmt.Fprintf(c.stderr, "knowledge bundle write failed: %v\n", err)
 		return 1
 	}
+	if len(written) == 0 {
+		fmt.Fprintf(c.stdout, "knowledge bundle unchanged: %s\n", cleanAbs(*distRoot))
+		return 0
+	}
 	fmt.Fprintf(c.stdout, "knowledge bundle updated: %s\n", cleanAbs(*distRoot))
 	return 0
 }
@@ -86,6 +86,21 @@ func TestRunCLIHelp(t *testing.T) {
 			"--validate-source-only",
 		)
 	})
+
+	t.Run("ai test-provider help is available through help command", func(t *testing.T) {
+		code, stdout, stderr := runCLITest(t, "help", "ai", "test-provider")
+		if code != 0 {
+			t.Fatalf("exit code = %d, want 0", code)
+		}
+		if stderr != "" {
+			t.Fatalf("stderr = %q, want empty", stderr)
+		}
+		assertContainsAll(t, stdout,
+			"redeven ai test-provider [flags] <provider_id>",
+			"--format <json|text>",
+			"API keys are never printed.",
+		)
+	})
 }
 
 func TestRunCLIStartupGuidanceErrors(t *testing.T) {
@@ -119,6 +134,20 @@ func TestRunCLIStartupGuidanceErrors(t *testing.T) {
 		)
 	})
 
+	t.Run("ai test-provider requires a provider id", func(t *testing.T) {
+		code, stdout, stderr := runCLITest(t, "ai", "test-provider")
+		if code != 2 {
+			t.Fatalf("exit code = %d, want 2", code)
+		}
+		if stdout != "" {
+			t.Fatalf("stdout = %q, want empty", stdout)
+		}
+		assertContainsAll(t, stderr,
+			"missing provider id for `redeven ai test-provider`",
+			"Example: redeven ai test-provider openai",
+		)
+	})
+
 	t.Run("bootstrap missing flags are listed explicitly", func(t *testing.T) {
 		code, _, stderr := runCLITest(t, "bootstrap")
 		if code != 2 {
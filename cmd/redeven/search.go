@@ -59,7 +59,7 @@ func (c *cli) searchCmd(args []string) int {
 		return 2
 	}
 
-	stateLayout, err := resolveSearchStateLayout(*configPath, *stateRoot, scopeRef)
+	stateLayout, err := resolveLocalCommandStateLayout(*configPath, *stateRoot, scopeRef)
 	if err != nil {
 		if errors.Is(err, config.ErrHomeDirUnavailable) {
 			writeErrorWithHelp(
@@ -153,7 +153,7 @@ func (c *cli) searchCmd(args []string) int {
 	}
 }
 
-func resolveSearchStateLayout(configPath string, stateRoot string, scopeRef *config.ScopeRef) (config.StateLayout, error) {
+func resolveLocalCommandStateLayout(configPath string, stateRoot string, scopeRef *config.ScopeRef) (config.StateLayout, error) {
 	cleanPath := strings.TrimSpace(configPath)
 	if cleanPath == "" {
 		if scopeRef != nil {
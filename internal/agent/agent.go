@@ -262,7 +262,9 @@ func New(opts Options) (*Agent, error) {
 		CodeServerPortMax:   opts.Config.CodeServerPortMax,
 		AgentHomeDir:        agentHomeAbs,
 		Shell:               shell,
+		AgentVersion:        opts.Version,
 		AIConfig:            opts.Config.AI,
+		SecretsBackend:      opts.Config.SecretsBackend,
 		Audit:               auditStore,
 		Diagnostics:         a.diag,
 		Terminal:            a.term,
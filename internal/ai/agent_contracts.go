@@ -19,6 +19,7 @@ const (
 	StreamEventThinkingDelta StreamEventType = "thinking_delta"
 	StreamEventUsage         StreamEventType = "usage"
 	StreamEventFinishReason  StreamEventType = "finish_reason"
+	StreamEventImage         StreamEventType = "image"
 )
 
 type PartialToolCall struct {
@@ -40,6 +41,17 @@ type StreamEvent struct {
 	ToolCall   *PartialToolCall `json:"tool_call,omitempty"`
 	Usage      *PartialUsage    `json:"usage,omitempty"`
 	FinishHint string           `json:"finish_hint,omitempty"`
+	Image      *GeneratedImage  `json:"image,omitempty"`
+}
+
+// GeneratedImage is an image produced by the model, e.g. by the OpenAI image_generation tool.
+type GeneratedImage struct {
+	ID       string `json:"id,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	// DataURL carries the image bytes inline (data:<mime>;base64,...).
+	DataURL string `json:"data_url,omitempty"`
+	// URL references an image that is already stored, e.g. an upload URL.
+	URL string `json:"url,omitempty"`
 }
 
 type ContentPart struct {
@@ -60,12 +72,16 @@ type Message struct {
 }
 
 type ProviderControls struct {
-	ThinkingBudgetTokens int      `json:"thinking_budget_tokens,omitempty"`
-	CacheControl         string   `json:"cache_control,omitempty"`
-	ResponseFormat       string   `json:"response_format,omitempty"`
-	PreviousResponseID   string   `json:"previous_response_id,omitempty"`
-	Temperature          *float64 `json:"temperature,omitempty"`
-	TopP                 *float64 `json:"top_p,omitempty"`
+	ThinkingBudgetTokens int    `json:"thinking_budget_tokens,omitempty"`
+	CacheControl         string `json:"cache_control,omitempty"`
+	ResponseFormat       string `json:"response_format,omitempty"`
+	// ResponseJSONSchema is attached when ResponseFormat is json_schema (see RunOptions.ResponseJSONSchema).
+	ResponseJSONSchema json.RawMessage `json:"response_json_schema,omitempty"`
+	PreviousResponseID string          `json:"previous_response_id,omitempty"`
+	Temperature        *float64        `json:"temperature,omitempty"`
+	TopP               *float64        `json:"top_p,omitempty"`
+	Seed               *int64          `json:"seed,omitempty"`
+	ParallelToolCalls  bool            `json:"parallel_tool_calls,omitempty"`
 }
 
 type TurnBudgets struct {
@@ -88,6 +104,8 @@ type TurnRequest struct {
 	ModeFlags        ModeFlags        `json:"mode_flags"`
 	ProviderControls ProviderControls `json:"provider_controls,omitempty"`
 	WebSearchEnabled bool             `json:"web_search_enabled,omitempty"`
+	// ImageOutputEnabled lets the model return generated images (ModelCapability.SupportsImageOutput).
+	ImageOutputEnabled bool `json:"image_output_enabled,omitempty"`
 }
 
 type ToolCall struct {
@@ -130,6 +148,7 @@ type TurnResult struct {
 	Reasoning       string             `json:"reasoning,omitempty"`
 	ToolCalls       []ToolCall         `json:"tool_calls,omitempty"`
 	Sources         []SourceRef        `json:"sources,omitempty"`
+	Images          []GeneratedImage   `json:"images,omitempty"`
 	Usage           TurnUsage          `json:"usage,omitempty"`
 	ProviderState   *TurnProviderState `json:"provider_state,omitempty"`
 	RawProviderDiag map[string]any     `json:"raw_provider_diag,omitempty"`
@@ -259,7 +278,13 @@ type runtimeState struct {
 	TodoInProgressCount   int                 `json:"todo_in_progress_count,omitempty"`
 	TodoSnapshotVersion   int64               `json:"todo_snapshot_version,omitempty"`
 	TodoLastUpdatedRound  int                 `json:"todo_last_updated_round,omitempty"`
+	ScratchpadKeyCount    int                 `json:"scratchpad_key_count,omitempty"`
+	ScratchpadTotalBytes  int64               `json:"scratchpad_total_bytes,omitempty"`
 	InteractionContract   interactionContract `json:"interaction_contract,omitempty"`
+	// ToolCallCounts counts the dispatched calls per tool name, for RunOptions.ToolCallLimits.
+	ToolCallCounts map[string]int `json:"tool_call_counts,omitempty"`
+	// ResultFingerprints maps a read-only tool result fingerprint to the call signatures that returned it.
+	ResultFingerprints map[string][]string `json:"result_fingerprints,omitempty"`
 }
 
 func newRuntimeState(objective string) runtimeState {
@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestClassifyFinalizationReason_AskUserBounceGuard(t *testing.T) {
+	t.Parallel()
+
+	if got := classifyFinalizationReason(finalizationReasonAskUserBounceGuard); got != finalizationClassSuccess {
+		t.Fatalf("classifyFinalizationReason(%q)=%q, want %q", finalizationReasonAskUserBounceGuard, got, finalizationClassSuccess)
+	}
+	if pass, reason := evaluateGuardAskUserGate("ask_user_bounce_guard", runtimeState{TodoTrackingEnabled: true, TodoOpenCount: 2}, TaskComplexityStandard); !pass {
+		t.Fatalf("ask_user_bounce_guard gate rejected: %s", reason)
+	}
+}
+
+func TestIntegration_NativeSDK_OpenAI_GuardAskUserBounceStopsRun(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	stateDir := t.TempDir()
+
+	mock := &openAINoToolTextOnlyMock{replyToken: "STILL_THINKING"}
+	srv := httptest.NewServer(http.HandlerFunc(mock.handle))
+	t.Cleanup(srv.Close)
+
+	cfg := &config.AIConfig{
+		Providers: []config.AIProvider{
+			{
+				ID:      "openai",
+				Name:    "OpenAI",
+				Type:    "openai",
+				BaseURL: strings.TrimSuffix(srv.URL, "/") + "/v1",
+				Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+	}
+	meta := session.Meta{
+		EndpointID:        "env_test",
+		NamespacePublicID: "ns_test",
+		ChannelID:         "ch_test_ask_user_bounce",
+		UserPublicID:      "u_test",
+		UserEmail:         "u_test@example.com",
+		CanRead:           true,
+		CanWrite:          true,
+		CanExecute:        true,
+		CanAdmin:          true,
+	}
+	svc, err := NewService(Options{
+		Logger:              logger,
+		StateDir:            stateDir,
+		AgentHomeDir:        t.TempDir(),
+		Shell:               "bash",
+		Config:              cfg,
+		RunMaxWallTime:      30 * time.Second,
+		RunIdleTimeout:      10 * time.Second,
+		ToolApprovalTimeout: 5 * time.Second,
+		ResolveProviderAPIKey: func(string) (string, bool, error) {
+			return "sk-test", true, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	th, err := svc.CreateThread(ctx, &meta, "hello", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	if err := svc.contextRepo.SetOpenGoal(ctx, meta.EndpointID, th.ThreadID, "continue repository analysis"); err != nil {
+		t.Fatalf("SetOpenGoal: %v", err)
+	}
+	// Open todos without blockers make the gate reject every guard-originated ask_user.
+	if _, err := svc.threadsDB.ReplaceThreadTodosSnapshot(ctx, threadstore.ThreadTodosSnapshot{
+		EndpointID:      meta.EndpointID,
+		ThreadID:        th.ThreadID,
+		TodosJSON:       `[{"id":"todo_1","content":"Inspect workspace","status":"pending"},{"id":"todo_2","content":"Run tests","status":"pending"}]`,
+		UpdatedByRunID:  "run_seed",
+		UpdatedByToolID: "tool_seed",
+	}, nil); err != nil {
+		t.Fatalf("ReplaceThreadTodosSnapshot: %v", err)
+	}
+
+	runID := "run_test_native_openai_ask_user_bounce_1"
+	rr := httptest.NewRecorder()
+	if err := svc.StartRun(ctx, &meta, runID, RunStartRequest{
+		ThreadID: th.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "continue"},
+		Options:  RunOptions{MaxSteps: 4, MaxNoToolRounds: 1},
+	}, rr); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+
+	events, err := svc.ListRunEvents(ctx, &meta, runID, 2000)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	bounceEvents := 0
+	waitingSource := ""
+	for _, ev := range events.Events {
+		payload, _ := ev.Payload.(map[string]any)
+		switch strings.TrimSpace(ev.EventType) {
+		case "guard.ask_user_bounce":
+			bounceEvents++
+			if got := fmt.Sprint(payload["count"]); got != fmt.Sprint(nativeAskUserBounceLimit+1) {
+				t.Fatalf("guard.ask_user_bounce count=%s, want %d", got, nativeAskUserBounceLimit+1)
+			}
+		case "ask_user.waiting":
+			waitingSource = strings.TrimSpace(fmt.Sprint(payload["source"]))
+		}
+	}
+	if bounceEvents != 1 {
+		t.Fatalf("guard.ask_user_bounce events=%d, want 1", bounceEvents)
+	}
+	if waitingSource != "ask_user_bounce_guard" {
+		t.Fatalf("ask_user.waiting source=%q, want %q", waitingSource, "ask_user_bounce_guard")
+	}
+}
@@ -0,0 +1,208 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	requestUserInputSpecTypeString = "string"
+	requestUserInputSpecTypeNumber = "number"
+	requestUserInputSpecTypeDate   = "date"
+	requestUserInputSpecTypePath   = "path"
+	requestUserInputSpecTypeEnum   = "enum"
+
+	requestUserInputSpecDateLayout      = "2006-01-02"
+	requestUserInputSpecMaxPatternLen   = 200
+	requestUserInputSpecMaxEnumValues   = 20
+	requestUserInputSpecMaxEnumValueLen = 200
+	requestUserInputAnswerMaxTextLen    = 2000
+)
+
+// ErrInvalidPromptAnswer is returned when a written answer does not satisfy the question's input spec.
+// The waiting prompt stays open so the user can answer again.
+var ErrInvalidPromptAnswer = errors.New("invalid prompt answer")
+
+func parseRequestUserInputSpecAny(value any) *RequestUserInputSpec {
+	if value == nil {
+		return nil
+	}
+	var spec RequestUserInputSpec
+	switch v := value.(type) {
+	case *RequestUserInputSpec:
+		if v == nil {
+			return nil
+		}
+		spec = *v
+	case RequestUserInputSpec:
+		spec = v
+	default:
+		raw, err := json.Marshal(value)
+		if err != nil || json.Unmarshal(raw, &spec) != nil {
+			return nil
+		}
+	}
+	return normalizeRequestUserInputSpec(&spec)
+}
+
+func cloneFloat64Ptr(value *float64) *float64 {
+	if value == nil || math.IsNaN(*value) || math.IsInf(*value, 0) {
+		return nil
+	}
+	out := *value
+	return &out
+}
+
+func normalizeRequestUserInputSpecDate(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if _, err := time.Parse(requestUserInputSpecDateLayout, raw); err != nil {
+		return ""
+	}
+	return raw
+}
+
+// normalizeRequestUserInputSpec drops unknown types, constraints that do not apply to the type, and
+// constraints that cannot be satisfied, so a bad spec degrades to plain text instead of blocking the prompt.
+func normalizeRequestUserInputSpec(spec *RequestUserInputSpec) *RequestUserInputSpec {
+	if spec == nil {
+		return nil
+	}
+	out := &RequestUserInputSpec{Type: strings.ToLower(strings.TrimSpace(spec.Type))}
+	switch out.Type {
+	case requestUserInputSpecTypeString:
+		out.MinLength = max(0, min(spec.MinLength, requestUserInputAnswerMaxTextLen))
+		out.MaxLength = max(0, min(spec.MaxLength, requestUserInputAnswerMaxTextLen))
+		if out.MaxLength > 0 && out.MinLength > out.MaxLength {
+			out.MinLength, out.MaxLength = 0, 0
+		}
+		pattern := strings.TrimSpace(spec.Pattern)
+		if pattern != "" && utf8.RuneCountInString(pattern) <= requestUserInputSpecMaxPatternLen {
+			if _, err := regexp.Compile(pattern); err == nil {
+				out.Pattern = pattern
+			}
+		}
+	case requestUserInputSpecTypeNumber:
+		out.Min = cloneFloat64Ptr(spec.Min)
+		out.Max = cloneFloat64Ptr(spec.Max)
+		if out.Min != nil && out.Max != nil && *out.Min > *out.Max {
+			out.Min, out.Max = nil, nil
+		}
+		out.Integer = spec.Integer
+	case requestUserInputSpecTypeDate:
+		out.MinDate = normalizeRequestUserInputSpecDate(spec.MinDate)
+		out.MaxDate = normalizeRequestUserInputSpecDate(spec.MaxDate)
+		if out.MinDate != "" && out.MaxDate != "" && out.MinDate > out.MaxDate {
+			out.MinDate, out.MaxDate = "", ""
+		}
+	case requestUserInputSpecTypePath:
+		out.Absolute = spec.Absolute
+	case requestUserInputSpecTypeEnum:
+		seen := make(map[string]struct{}, len(spec.Values))
+		for _, value := range spec.Values {
+			value = truncateRunes(strings.TrimSpace(value), requestUserInputSpecMaxEnumValueLen)
+			key := strings.ToLower(value)
+			if value == "" {
+				continue
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			out.Values = append(out.Values, value)
+			if len(out.Values) >= requestUserInputSpecMaxEnumValues {
+				break
+			}
+		}
+		if len(out.Values) == 0 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return out
+}
+
+func formatRequestUserInputSpecNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// validateRequestUserInputSpecAnswer checks a written answer against spec and returns its canonical form.
+// The error message is shown to the user, so it says what a valid answer looks like.
+func validateRequestUserInputSpecAnswer(spec *RequestUserInputSpec, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if spec == nil {
+		return text, nil
+	}
+	switch spec.Type {
+	case requestUserInputSpecTypeString:
+		length := utf8.RuneCountInString(text)
+		if spec.MinLength > 0 && length < spec.MinLength {
+			return "", fmt.Errorf("must be at least %d characters", spec.MinLength)
+		}
+		if spec.MaxLength > 0 && length > spec.MaxLength {
+			return "", fmt.Errorf("must be at most %d characters", spec.MaxLength)
+		}
+		if spec.Pattern != "" {
+			re, err := regexp.Compile(`^(?:` + spec.Pattern + `)$`)
+			if err == nil && !re.MatchString(text) {
+				return "", fmt.Errorf("must match the pattern %s", spec.Pattern)
+			}
+		}
+		return text, nil
+
+	case requestUserInputSpecTypeNumber:
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return "", errors.New("must be a number")
+		}
+		if spec.Integer && value != math.Trunc(value) {
+			return "", errors.New("must be a whole number")
+		}
+		if spec.Min != nil && value < *spec.Min {
+			return "", fmt.Errorf("must be at least %s", formatRequestUserInputSpecNumber(*spec.Min))
+		}
+		if spec.Max != nil && value > *spec.Max {
+			return "", fmt.Errorf("must be at most %s", formatRequestUserInputSpecNumber(*spec.Max))
+		}
+		return formatRequestUserInputSpecNumber(value), nil
+
+	case requestUserInputSpecTypeDate:
+		if _, err := time.Parse(requestUserInputSpecDateLayout, text); err != nil {
+			return "", errors.New("must be a date in YYYY-MM-DD format")
+		}
+		// The layout is fixed-width, so dates compare correctly as strings.
+		if spec.MinDate != "" && text < spec.MinDate {
+			return "", fmt.Errorf("must be on or after %s", spec.MinDate)
+		}
+		if spec.MaxDate != "" && text > spec.MaxDate {
+			return "", fmt.Errorf("must be on or before %s", spec.MaxDate)
+		}
+		return text, nil
+
+	case requestUserInputSpecTypePath:
+		if text == "" || strings.ContainsRune(text, 0) {
+			return "", errors.New("must be a file path")
+		}
+		if spec.Absolute && !filepath.IsAbs(text) {
+			return "", errors.New("must be an absolute path")
+		}
+		return filepath.Clean(text), nil
+
+	case requestUserInputSpecTypeEnum:
+		for _, value := range spec.Values {
+			if strings.EqualFold(value, text) {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("must be one of: %s", strings.Join(spec.Values, ", "))
+	}
+	return text, nil
+}
@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+)
+
+func testFloat64Ptr(value float64) *float64 {
+	return &value
+}
+
+func TestValidateRequestUserInputSpecAnswer(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		spec    RequestUserInputSpec
+		answer  string
+		want    string
+		wantErr bool
+	}{
+		{name: "string ok", spec: RequestUserInputSpec{Type: "string", MinLength: 2, MaxLength: 5}, answer: " abc ", want: "abc"},
+		{name: "string too short", spec: RequestUserInputSpec{Type: "string", MinLength: 2}, answer: "a", wantErr: true},
+		{name: "string too long", spec: RequestUserInputSpec{Type: "string", MaxLength: 3}, answer: "abcd", wantErr: true},
+		{name: "string pattern matches whole answer", spec: RequestUserInputSpec{Type: "string", Pattern: `[a-z]+-\d+`}, answer: "ticket-42", want: "ticket-42"},
+		{name: "string pattern partial match", spec: RequestUserInputSpec{Type: "string", Pattern: `[a-z]+`}, answer: "abc123", wantErr: true},
+
+		{name: "number ok", spec: RequestUserInputSpec{Type: "number", Min: testFloat64Ptr(1), Max: testFloat64Ptr(10)}, answer: "2.50", want: "2.5"},
+		{name: "number not numeric", spec: RequestUserInputSpec{Type: "number"}, answer: "two", wantErr: true},
+		{name: "number not finite", spec: RequestUserInputSpec{Type: "number"}, answer: "Inf", wantErr: true},
+		{name: "number below min", spec: RequestUserInputSpec{Type: "number", Min: testFloat64Ptr(1)}, answer: "0", wantErr: true},
+		{name: "number above max", spec: RequestUserInputSpec{Type: "number", Max: testFloat64Ptr(10)}, answer: "11", wantErr: true},
+		{name: "number integer", spec: RequestUserInputSpec{Type: "number", Integer: true}, answer: "8080", want: "8080"},
+		{name: "number fraction for integer", spec: RequestUserInputSpec{Type: "number", Integer: true}, answer: "1.5", wantErr: true},
+
+		{name: "date ok", spec: RequestUserInputSpec{Type: "date", MinDate: "2026-01-01", MaxDate: "2026-12-31"}, answer: "2026-10-16", want: "2026-10-16"},
+		{name: "date bad format", spec: RequestUserInputSpec{Type: "date"}, answer: "10/16/2026", wantErr: true},
+		{name: "date invalid day", spec: RequestUserInputSpec{Type: "date"}, answer: "2026-02-30", wantErr: true},
+		{name: "date before min", spec: RequestUserInputSpec{Type: "date", MinDate: "2026-01-01"}, answer: "2025-12-31", wantErr: true},
+		{name: "date after max", spec: RequestUserInputSpec{Type: "date", MaxDate: "2026-12-31"}, answer: "2027-01-01", wantErr: true},
+
+		{name: "path cleaned", spec: RequestUserInputSpec{Type: "path"}, answer: "src/./pkg/", want: "src/pkg"},
+		{name: "path absolute", spec: RequestUserInputSpec{Type: "path", Absolute: true}, answer: "/var/log/app.log", want: "/var/log/app.log"},
+		{name: "path relative for absolute", spec: RequestUserInputSpec{Type: "path", Absolute: true}, answer: "var/log", wantErr: true},
+		{name: "path with nul", spec: RequestUserInputSpec{Type: "path"}, answer: "a\x00b", wantErr: true},
+
+		{name: "enum canonical case", spec: RequestUserInputSpec{Type: "enum", Values: []string{"Staging", "Production"}}, answer: "production", want: "Production"},
+		{name: "enum unknown", spec: RequestUserInputSpec{Type: "enum", Values: []string{"Staging", "Production"}}, answer: "dev", wantErr: true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			spec := normalizeRequestUserInputSpec(&tc.spec)
+			if spec == nil {
+				t.Fatalf("spec %+v normalized to nil", tc.spec)
+			}
+			got, err := validateRequestUserInputSpecAnswer(spec, tc.answer)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("answer %q accepted as %q, want an error", tc.answer, got)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("answer %q = %q, %v; want %q", tc.answer, got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRequestUserInputSpec_DropsInapplicableConstraints(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []RequestUserInputSpec{
+		{Type: "color"},
+		{Type: "enum"},
+		{Type: "enum", Values: []string{" ", ""}},
+	} {
+		if got := normalizeRequestUserInputSpec(&spec); got != nil {
+			t.Fatalf("normalize(%+v)=%+v, want nil", spec, got)
+		}
+	}
+
+	got := normalizeRequestUserInputSpec(&RequestUserInputSpec{
+		Type:      " Number ",
+		Min:       testFloat64Ptr(5),
+		Max:       testFloat64Ptr(1),
+		MinLength: 3,
+		Pattern:   "x",
+		Values:    []string{"a"},
+	})
+	if got == nil || got.Type != "number" || got.Min != nil || got.Max != nil || got.MinLength != 0 || got.Pattern != "" || got.Values != nil {
+		t.Fatalf("normalized number spec=%+v", got)
+	}
+
+	got = normalizeRequestUserInputSpec(&RequestUserInputSpec{Type: "string", Pattern: "(", MinLength: 9, MaxLength: 3})
+	if got == nil || got.Pattern != "" || got.MinLength != 0 || got.MaxLength != 0 {
+		t.Fatalf("normalized string spec=%+v", got)
+	}
+
+	got = normalizeRequestUserInputSpec(&RequestUserInputSpec{Type: "enum", Values: []string{"a", "A", " b "}})
+	if got == nil || len(got.Values) != 2 || got.Values[0] != "a" || got.Values[1] != "b" {
+		t.Fatalf("normalized enum spec=%+v", got)
+	}
+}
+
+func TestValidateRequestUserInputResponse_EnforcesInputSpec(t *testing.T) {
+	t.Parallel()
+
+	prompt := testRequestUserInputPrompt(
+		"msg_input_spec",
+		"tool_input_spec",
+		AskUserReasonMissingExternalInput,
+		[]RequestUserInputQuestion{
+			{
+				ID:           "port",
+				Header:       "Port",
+				Question:     "Which port should the server listen on?",
+				ResponseMode: requestUserInputResponseModeWrite,
+				InputSpec:    &RequestUserInputSpec{Type: "number", Integer: true, Min: testFloat64Ptr(1), Max: testFloat64Ptr(65535)},
+			},
+			{
+				ID:                "env",
+				Header:            "Environment",
+				Question:          "Which environment?",
+				ResponseMode:      requestUserInputResponseModeSelectText,
+				ChoicesExhaustive: testBoolPtr(false),
+				InputSpec:         &RequestUserInputSpec{Type: "enum", Values: []string{"staging", "canary"}},
+				Choices: []RequestUserInputChoice{
+					{ChoiceID: "prod", Label: "Production", Kind: requestUserInputChoiceKindSelect},
+				},
+			},
+		},
+	)
+	if prompt == nil || prompt.Questions[0].InputSpec == nil || prompt.Questions[1].InputSpec == nil {
+		t.Fatalf("prompt should keep input specs: %+v", prompt)
+	}
+
+	_, err := validateRequestUserInputResponse(prompt, &RequestUserInputResponse{
+		PromptID: prompt.PromptID,
+		Answers: map[string]RequestUserInputAnswer{
+			"port": {Text: "http"},
+			"env":  {ChoiceID: "prod"},
+		},
+	})
+	if !errors.Is(err, ErrInvalidPromptAnswer) {
+		t.Fatalf("invalid number err=%v, want %v", err, ErrInvalidPromptAnswer)
+	}
+
+	// A fixed choice bypasses the spec; it only applies to written answers.
+	normalized, err := validateRequestUserInputResponse(prompt, &RequestUserInputResponse{
+		PromptID: prompt.PromptID,
+		Answers: map[string]RequestUserInputAnswer{
+			"port": {Text: "8080"},
+			"env":  {ChoiceID: "prod"},
+		},
+	})
+	if err != nil || normalized.Answers["port"].Text != "8080" {
+		t.Fatalf("valid answers normalized=%+v err=%v", normalized, err)
+	}
+
+	normalized, err = validateRequestUserInputResponse(prompt, &RequestUserInputResponse{
+		PromptID: prompt.PromptID,
+		Answers: map[string]RequestUserInputAnswer{
+			"port": {Text: "443"},
+			"env":  {Text: "Canary"},
+		},
+	})
+	if err != nil || normalized.Answers["env"].Text != "canary" {
+		t.Fatalf("written enum answer normalized=%+v err=%v", normalized, err)
+	}
+}
+
+func TestParseRequestUserInputPromptJSON_PreservesInputSpec(t *testing.T) {
+	t.Parallel()
+
+	prompt := parseRequestUserInputPromptJSON(`{
+		"prompt_id":"rui_msg_spec_tool_spec",
+		"message_id":"msg_spec",
+		"tool_id":"tool_spec",
+		"questions":[
+			{"id":"due","header":"Due date","question":"When is it due?","is_secret":false,"response_mode":"write",
+			 "input_spec":{"type":"date","min_date":"2026-01-01"}},
+			{"id":"pick","header":"Pick","question":"Pick one.","is_secret":false,"response_mode":"select","choices_exhaustive":true,
+			 "choices":[{"choice_id":"a","label":"A","kind":"select"}],
+			 "input_spec":{"type":"number"}}
+		]
+	}`)
+	if prompt == nil || len(prompt.Questions) != 2 {
+		t.Fatalf("prompt=%+v", prompt)
+	}
+	if spec := prompt.Questions[0].InputSpec; spec == nil || spec.Type != "date" || spec.MinDate != "2026-01-01" {
+		t.Fatalf("write question input_spec=%+v", spec)
+	}
+	if spec := prompt.Questions[1].InputSpec; spec != nil {
+		t.Fatalf("select-only question should drop input_spec, got %+v", spec)
+	}
+}
@@ -86,7 +86,7 @@ func defaultGuardAskUserSignal(question string, options []string, source string,
 		}}),
 	}
 	switch strings.TrimSpace(source) {
-	case "tool_mistake_loop", "guard_doom_loop":
+	case "tool_mistake_loop", "guard_doom_loop", "guard_repeated_result":
 		signal.ReasonCode = AskUserReasonConflictingWork
 		signal.RequiredFromUser = []string{"Provide missing context or choose the next direction so execution can avoid repeating failed tool paths."}
 	case "completion_empty_result_repeated", "missing_explicit_completion":
@@ -95,6 +95,9 @@ func defaultGuardAskUserSignal(question string, options []string, source string,
 	case "complex_task_missing_todos":
 		signal.ReasonCode = AskUserReasonUserDecisionRequired
 		signal.RequiredFromUser = []string{"Confirm the key goals to continue with a valid todo plan."}
+	case "objective_clarify":
+		signal.ReasonCode = AskUserReasonMissingExternalInput
+		signal.RequiredFromUser = []string{"Describe the goal of this task and what a finished result looks like."}
 	default:
 		signal.ReasonCode = AskUserReasonMissingExternalInput
 		signal.RequiredFromUser = []string{"Provide clarification so execution can continue safely."}
@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	contextmodel "github.com/floegence/redeven/internal/ai/context/model"
+	"github.com/floegence/redeven/internal/config"
+)
+
+const (
+	imageRejectUnsupportedFormat = "unsupported_format"
+	imageRejectTooLarge          = "too_large"
+	imageRejectInvalidDataURL    = "invalid_data_url"
+)
+
+// supportedImageMimeTypes are the image formats every vision-capable provider accepts.
+var supportedImageMimeTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type imageAttachmentRejection struct {
+	Attachment RunAttachmentIn
+	MimeType   string
+	Reason     string
+	SizeBytes  int64
+}
+
+func (rj imageAttachmentRejection) describe(maxBytes int64) string {
+	name := attachmentDisplayName(rj.Attachment)
+	switch rj.Reason {
+	case imageRejectTooLarge:
+		return fmt.Sprintf("%s is %s, over the %s image limit", name, formatAttachmentBytes(rj.SizeBytes), formatAttachmentBytes(maxBytes))
+	case imageRejectInvalidDataURL:
+		return fmt.Sprintf("%s is not valid base64 image data", name)
+	default:
+		return fmt.Sprintf("%s has unsupported image format %s (use PNG, JPEG, GIF, or WebP)", name, rj.MimeType)
+	}
+}
+
+// preflightImageAttachments validates image attachments before any model call.
+//
+// Images that fail validation return a user-facing error under the reject attachment policy and are
+// dropped with an attachment.rejected event otherwise. Images sent to a model without image input
+// are replaced by text references. The returned note tells the model what was left out.
+func (s *Service) preflightImageAttachments(ctx context.Context, r *run, cfg *config.AIConfig, modelID string, capability contextmodel.ModelCapability, input *RunInput) (string, error) {
+	if input == nil || len(input.Attachments) == 0 {
+		return "", nil
+	}
+	maxBytes := cfg.EffectiveMaxImageBytes()
+	policy := cfg.EffectiveAttachmentExcessPolicy()
+
+	kept := make([]RunAttachmentIn, 0, len(input.Attachments))
+	var (
+		rejected   []imageAttachmentRejection
+		references []string
+	)
+	for _, att := range input.Attachments {
+		mimeType, isImage := imageAttachmentMimeType(att)
+		if !isImage {
+			kept = append(kept, att)
+			continue
+		}
+		if !capability.SupportsImageInput {
+			ref := attachmentDisplayName(att)
+			if u := strings.TrimSpace(att.URL); u != ref && !strings.HasPrefix(u, "data:") {
+				ref += " (" + u + ")"
+			}
+			references = append(references, ref)
+			continue
+		}
+		if rj, ok := s.validateImageAttachment(ctx, r, att, mimeType, maxBytes); !ok {
+			rejected = append(rejected, rj)
+			continue
+		}
+		kept = append(kept, att)
+	}
+
+	for _, rj := range rejected {
+		r.persistRunEvent("attachment.rejected", RealtimeStreamKindLifecycle, map[string]any{
+			"name":       attachmentDisplayName(rj.Attachment),
+			"mime_type":  rj.MimeType,
+			"reason":     rj.Reason,
+			"size_bytes": rj.SizeBytes,
+			"max_bytes":  maxBytes,
+			"policy":     policy,
+		})
+	}
+	if len(rejected) > 0 && policy == config.AIAttachmentExcessReject {
+		reasons := make([]string, 0, len(rejected))
+		for _, rj := range rejected {
+			reasons = append(reasons, rj.describe(maxBytes))
+		}
+		return "", errors.New("Some image attachments cannot be sent: " + strings.Join(reasons, "; ") + ". Remove or replace them and send the message again.")
+	}
+	input.Attachments = kept
+
+	notes := make([]string, 0, 2)
+	if len(rejected) > 0 {
+		reasons := make([]string, 0, len(rejected))
+		for _, rj := range rejected {
+			reasons = append(reasons, rj.describe(maxBytes))
+		}
+		notes = append(notes, "Image attachments not included: "+strings.Join(reasons, "; ")+". Tell the user these images were not read.")
+	}
+	if len(references) > 0 {
+		notes = append(notes, fmt.Sprintf("The model %s cannot read images, so these image attachments are listed by reference only: %s. Do not describe their contents.",
+			strings.TrimSpace(modelID), strings.Join(references, ", ")))
+	}
+	return strings.Join(notes, "\n\n"), nil
+}
+
+// validateImageAttachment checks the format, size, and (for data URLs) decodability of one image.
+func (s *Service) validateImageAttachment(ctx context.Context, r *run, att RunAttachmentIn, mimeType string, maxBytes int64) (imageAttachmentRejection, bool) {
+	rj := imageAttachmentRejection{Attachment: att, MimeType: mimeType}
+	if !supportedImageMimeTypes[mimeType] {
+		rj.Reason = imageRejectUnsupportedFormat
+		return rj, false
+	}
+	url := strings.TrimSpace(att.URL)
+	if strings.HasPrefix(url, "data:") {
+		b64, ok := extractDataURLBase64(url)
+		if !ok {
+			rj.Reason = imageRejectInvalidDataURL
+			return rj, false
+		}
+		decoded, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil || len(decoded) == 0 {
+			rj.Reason = imageRejectInvalidDataURL
+			return rj, false
+		}
+		rj.SizeBytes = int64(len(decoded))
+	} else if uploadID := parseUploadIDFromURL(url); uploadID != "" {
+		if rec, err := s.ensureUploadRecord(ctx, r.endpointID, uploadID); err == nil && rec != nil {
+			rj.SizeBytes = rec.SizeBytes
+		}
+	}
+	if rj.SizeBytes > maxBytes {
+		rj.Reason = imageRejectTooLarge
+		return rj, false
+	}
+	return rj, true
+}
+
+// imageAttachmentMimeType returns the normalized MIME type of an attachment and whether it is an image.
+//
+// Data URLs carry their own media type, which is used when the attachment has none.
+func imageAttachmentMimeType(att RunAttachmentIn) (string, bool) {
+	mimeType := strings.TrimSpace(att.MimeType)
+	if url := strings.TrimSpace(att.URL); mimeType == "" && strings.HasPrefix(url, "data:") {
+		header, _, _ := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		mimeType, _, _ = strings.Cut(header, ";")
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if base, _, ok := strings.Cut(mimeType, ";"); ok {
+		mimeType = strings.TrimSpace(base)
+	}
+	return mimeType, strings.HasPrefix(mimeType, "image/")
+}
+
+func attachmentDisplayName(att RunAttachmentIn) string {
+	if name := strings.TrimSpace(att.Name); name != "" {
+		return name
+	}
+	if url := strings.TrimSpace(att.URL); url != "" && !strings.HasPrefix(url, "data:") {
+		return url
+	}
+	return "image"
+}
+
+func formatAttachmentBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	contextmodel "github.com/floegence/redeven/internal/ai/context/model"
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+)
+
+func testImageDataURL(mimeType string, size int) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(make([]byte, size))
+}
+
+func attachmentRejectedReasons(t *testing.T, db *threadstore.Store) []string {
+	t.Helper()
+	events, err := db.ListRunEvents(context.Background(), "env_attach", "run_attach", 100)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	var out []string
+	for _, ev := range events {
+		if ev.EventType == "attachment.rejected" {
+			for _, reason := range []string{imageRejectTooLarge, imageRejectUnsupportedFormat, imageRejectInvalidDataURL} {
+				if strings.Contains(ev.PayloadJSON, `"reason":"`+reason+`"`) {
+					out = append(out, reason)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func TestPreflightImageAttachments_DropsOversizedAndWrongMIME(t *testing.T) {
+	t.Parallel()
+
+	maxBytes := int64(2 << 10)
+	cfg := &config.AIConfig{AttachmentLimit: &config.AIAttachmentLimitPolicy{MaxImageBytes: &maxBytes}}
+	r, db := newAttachmentLimitTestRun(t, cfg)
+	input := RunInput{Text: "look", Attachments: []RunAttachmentIn{
+		{Name: "ok.png", URL: testImageDataURL("image/png", 512)},
+		{Name: "huge.png", URL: testImageDataURL("image/png", 4<<10)},
+		{Name: "diagram.svg", MimeType: "image/svg+xml", URL: "https://example.com/diagram.svg"},
+		{Name: "broken.jpg", URL: "data:image/jpeg;base64,!!not-base64!!"},
+		{Name: "notes.txt", MimeType: "text/plain", URL: "/_redeven_proxy/api/ai/uploads/upl_notes"},
+	}}
+
+	note, err := (&Service{}).preflightImageAttachments(context.Background(), r, cfg, "openai/gpt-5-mini", contextmodel.ModelCapability{SupportsImageInput: true}, &input)
+	if err != nil {
+		t.Fatalf("preflightImageAttachments: %v", err)
+	}
+	if len(input.Attachments) != 2 || input.Attachments[0].Name != "ok.png" || input.Attachments[1].Name != "notes.txt" {
+		t.Fatalf("kept attachments=%+v", input.Attachments)
+	}
+	for _, want := range []string{"huge.png is 4.0 KiB, over the 2.0 KiB image limit", "diagram.svg has unsupported image format image/svg+xml", "broken.jpg is not valid base64"} {
+		if !strings.Contains(note, want) {
+			t.Fatalf("note missing %q: %q", want, note)
+		}
+	}
+	got := attachmentRejectedReasons(t, db)
+	if len(got) != 3 || got[0] != imageRejectTooLarge || got[1] != imageRejectUnsupportedFormat || got[2] != imageRejectInvalidDataURL {
+		t.Fatalf("attachment.rejected reasons=%v", got)
+	}
+}
+
+func TestPreflightImageAttachments_RejectPolicyFailsRunStart(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.AIConfig{AttachmentLimit: &config.AIAttachmentLimitPolicy{OnExcess: config.AIAttachmentExcessReject}}
+	r, db := newAttachmentLimitTestRun(t, cfg)
+	input := RunInput{Attachments: []RunAttachmentIn{
+		{Name: "scan.tiff", MimeType: "image/tiff", URL: "https://example.com/scan.tiff"},
+	}}
+
+	_, err := (&Service{}).preflightImageAttachments(context.Background(), r, cfg, "openai/gpt-5-mini", contextmodel.ModelCapability{SupportsImageInput: true}, &input)
+	if err == nil || !strings.Contains(err.Error(), "scan.tiff has unsupported image format image/tiff") {
+		t.Fatalf("err=%v, want unsupported format rejection", err)
+	}
+	if len(input.Attachments) != 1 {
+		t.Fatalf("reject policy must leave the input untouched: %+v", input.Attachments)
+	}
+	if got := attachmentRejectedReasons(t, db); len(got) != 1 {
+		t.Fatalf("attachment.rejected reasons=%v", got)
+	}
+}
+
+func TestPreflightImageAttachments_NonVisionModelGetsReferences(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.AIConfig{}
+	r, db := newAttachmentLimitTestRun(t, cfg)
+	input := RunInput{Attachments: []RunAttachmentIn{
+		{Name: "photo.jpg", MimeType: "image/jpeg", URL: "/_redeven_proxy/api/ai/uploads/upl_photo"},
+		{Name: "notes.txt", MimeType: "text/plain", URL: "/_redeven_proxy/api/ai/uploads/upl_notes"},
+	}}
+
+	note, err := (&Service{}).preflightImageAttachments(context.Background(), r, cfg, "ollama/llama3", contextmodel.ModelCapability{}, &input)
+	if err != nil {
+		t.Fatalf("preflightImageAttachments: %v", err)
+	}
+	if len(input.Attachments) != 1 || input.Attachments[0].Name != "notes.txt" {
+		t.Fatalf("kept attachments=%+v", input.Attachments)
+	}
+	if !strings.Contains(note, "ollama/llama3 cannot read images") || !strings.Contains(note, "photo.jpg (/_redeven_proxy/api/ai/uploads/upl_photo)") {
+		t.Fatalf("unexpected note: %q", note)
+	}
+	if got := attachmentRejectedReasons(t, db); len(got) != 0 {
+		t.Fatalf("text references are not rejections: %v", got)
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const attachmentLimitMaxListedNames = 10
+
+// attachmentLimitOutcome is the result of applying the per-message attachment cap.
+type attachmentLimitOutcome struct {
+	Policy   string
+	Limit    int
+	Received int
+	Kept     []RunAttachmentIn
+	Dropped  []RunAttachmentIn
+}
+
+func applyAttachmentLimit(items []RunAttachmentIn, limit int, policy string) attachmentLimitOutcome {
+	out := attachmentLimitOutcome{Policy: policy, Limit: limit, Received: len(items), Kept: items}
+	if limit <= 0 || len(items) <= limit {
+		return out
+	}
+	out.Kept = append([]RunAttachmentIn(nil), items[:limit]...)
+	out.Dropped = append([]RunAttachmentIn(nil), items[limit:]...)
+	return out
+}
+
+func (o attachmentLimitOutcome) exceeded() bool {
+	return len(o.Dropped) > 0
+}
+
+func (o attachmentLimitOutcome) droppedNames() []string {
+	names := make([]string, 0, len(o.Dropped))
+	for _, it := range o.Dropped {
+		name := strings.TrimSpace(it.Name)
+		if name == "" {
+			name = strings.TrimSpace(it.URL)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (o attachmentLimitOutcome) listedDroppedNames() string {
+	names := o.droppedNames()
+	if len(names) > attachmentLimitMaxListedNames {
+		rest := len(names) - attachmentLimitMaxListedNames
+		names = append(names[:attachmentLimitMaxListedNames:attachmentLimitMaxListedNames], fmt.Sprintf("and %d more", rest))
+	}
+	return strings.Join(names, ", ")
+}
+
+// rejectMessage is shown to the user when the reject policy refuses the message.
+func (o attachmentLimitOutcome) rejectMessage() string {
+	return fmt.Sprintf("This message has %d attachments, but the limit is %d per message. Remove some attachments and send it again.", o.Received, o.Limit)
+}
+
+// noticeMessage tells the model (and, through it, the user) which attachments were left out.
+func (o attachmentLimitOutcome) noticeMessage() string {
+	return fmt.Sprintf("Attachment limit: only the first %d of %d attachments were included. Not included: %s. Tell the user these attachments were not read and can be sent in a follow-up message.",
+		len(o.Kept), o.Received, o.listedDroppedNames())
+}
+
+func (o attachmentLimitOutcome) eventPayload() map[string]any {
+	return map[string]any{
+		"policy":        o.Policy,
+		"limit":         o.Limit,
+		"received":      o.Received,
+		"kept":          len(o.Kept),
+		"dropped":       len(o.Dropped),
+		"dropped_names": o.droppedNames(),
+	}
+}
+
+// applyRunAttachmentLimit enforces the configured cap on the run input.
+//
+// It returns a user-facing error for the reject policy; otherwise it trims input in place and
+// returns the note that tells the model which attachments were left out.
+func (r *run) applyRunAttachmentLimit(cfg *config.AIConfig, input *RunInput) (string, error) {
+	if input == nil {
+		return "", nil
+	}
+	outcome := applyAttachmentLimit(input.Attachments, cfg.EffectiveMaxAttachmentsPerMessage(), cfg.EffectiveAttachmentExcessPolicy())
+	if !outcome.exceeded() {
+		return "", nil
+	}
+	r.persistRunEvent("attachments.limit_applied", RealtimeStreamKindLifecycle, outcome.eventPayload())
+	if outcome.Policy == config.AIAttachmentExcessReject {
+		return "", errors.New(outcome.rejectMessage())
+	}
+	input.Attachments = outcome.Kept
+	return outcome.noticeMessage(), nil
+}
+
+// isTextLikeAttachment reports whether an attachment is plain text that can share a message with others.
+func isTextLikeAttachment(mimeType string, name string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if base, _, ok := strings.Cut(mimeType, ";"); ok {
+		mimeType = strings.TrimSpace(base)
+	}
+	switch {
+	case strings.HasPrefix(mimeType, "text/"):
+		return true
+	case mimeType == "application/json", mimeType == "application/xml", mimeType == "application/yaml",
+		mimeType == "application/x-yaml", mimeType == "application/toml", mimeType == "application/x-sh":
+		return true
+	case mimeType != "" && mimeType != "application/octet-stream":
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(strings.TrimSpace(name))) {
+	case ".txt", ".md", ".json", ".yaml", ".yml", ".toml", ".xml", ".csv", ".log", ".go", ".py", ".js", ".ts", ".sh":
+		return true
+	default:
+		return false
+	}
+}
+
+// buildAttachmentMessages emits one user message per attachment part, except that text
+// references and text-like files are combined into a single message.
+func buildAttachmentMessages(parts []ContentPart) []Message {
+	out := make([]Message, 0, len(parts))
+	combinedIdx := -1
+	for _, part := range parts {
+		combinable := part.Type == "text" || (part.Type == "file" && isTextLikeAttachment(part.MimeType, part.Text))
+		if combinable && combinedIdx >= 0 {
+			out[combinedIdx].Content = append(out[combinedIdx].Content, part)
+			continue
+		}
+		if combinable {
+			combinedIdx = len(out)
+		}
+		out = append(out, Message{Role: "user", Content: []ContentPart{part}})
+	}
+	return out
+}
+
+func appendAttachmentLimitNote(messages []Message, note string) []Message {
+	if note = strings.TrimSpace(note); note == "" {
+		return messages
+	}
+	return append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: note}}})
+}
@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+)
+
+func testAttachments(n int) []RunAttachmentIn {
+	out := make([]RunAttachmentIn, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, RunAttachmentIn{
+			Name:     fmt.Sprintf("file_%d.txt", i),
+			MimeType: "text/plain",
+			URL:      fmt.Sprintf("/_redeven_proxy/api/ai/uploads/upl_%d", i),
+		})
+	}
+	return out
+}
+
+func newAttachmentLimitTestRun(t *testing.T, cfg *config.AIConfig) (*run, *threadstore.Store) {
+	t.Helper()
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	r := newRun(runOptions{
+		AIConfig:         cfg,
+		RunID:            "run_attach",
+		EndpointID:       "env_attach",
+		ThreadID:         "th_attach",
+		MessageID:        "msg_attach",
+		ThreadsDB:        db,
+		PersistOpTimeout: 2 * time.Second,
+	})
+	return r, db
+}
+
+func hasAttachmentLimitEvent(t *testing.T, db *threadstore.Store) bool {
+	t.Helper()
+	events, err := db.ListRunEvents(context.Background(), "env_attach", "run_attach", 100)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	for _, ev := range events {
+		if ev.EventType == "attachments.limit_applied" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestApplyAttachmentLimit(t *testing.T) {
+	t.Parallel()
+
+	within := applyAttachmentLimit(testAttachments(3), 3, config.AIAttachmentExcessTruncate)
+	if within.exceeded() || len(within.Kept) != 3 {
+		t.Fatalf("within limit: %+v", within)
+	}
+
+	over := applyAttachmentLimit(testAttachments(14), 2, config.AIAttachmentExcessTruncate)
+	if !over.exceeded() || len(over.Kept) != 2 || len(over.Dropped) != 12 {
+		t.Fatalf("over limit: kept=%d dropped=%d", len(over.Kept), len(over.Dropped))
+	}
+	notice := over.noticeMessage()
+	if !strings.Contains(notice, "first 2 of 14") || !strings.Contains(notice, "file_3.txt") || !strings.Contains(notice, "and 2 more") {
+		t.Fatalf("unexpected notice: %q", notice)
+	}
+	if strings.Contains(notice, "file_13.txt") {
+		t.Fatalf("notice must cap the listed names: %q", notice)
+	}
+}
+
+func TestRunApplyRunAttachmentLimit_Truncate(t *testing.T) {
+	t.Parallel()
+
+	limit := 2
+	r, db := newAttachmentLimitTestRun(t, &config.AIConfig{AttachmentLimit: &config.AIAttachmentLimitPolicy{MaxPerMessage: &limit}})
+	input := RunInput{Text: "review these", Attachments: testAttachments(4)}
+	note, err := r.applyRunAttachmentLimit(r.cfg, &input)
+	if err != nil {
+		t.Fatalf("applyRunAttachmentLimit: %v", err)
+	}
+	if len(input.Attachments) != 2 || input.Attachments[1].Name != "file_2.txt" {
+		t.Fatalf("attachments=%+v, want the first 2", input.Attachments)
+	}
+	if !strings.Contains(note, "file_3.txt, file_4.txt") {
+		t.Fatalf("note=%q", note)
+	}
+	if !hasAttachmentLimitEvent(t, db) {
+		t.Fatalf("missing attachments.limit_applied event")
+	}
+}
+
+func TestRunApplyRunAttachmentLimit_Reject(t *testing.T) {
+	t.Parallel()
+
+	limit := 2
+	r, db := newAttachmentLimitTestRun(t, &config.AIConfig{AttachmentLimit: &config.AIAttachmentLimitPolicy{
+		MaxPerMessage: &limit,
+		OnExcess:      config.AIAttachmentExcessReject,
+	}})
+	input := RunInput{Text: "review these", Attachments: testAttachments(3)}
+	note, err := r.applyRunAttachmentLimit(r.cfg, &input)
+	if err == nil || !strings.Contains(err.Error(), "limit is 2") {
+		t.Fatalf("err=%v, want a reject message", err)
+	}
+	if note != "" || len(input.Attachments) != 3 {
+		t.Fatalf("reject must leave input untouched: note=%q attachments=%d", note, len(input.Attachments))
+	}
+	if !hasAttachmentLimitEvent(t, db) {
+		t.Fatalf("missing attachments.limit_applied event")
+	}
+}
+
+func TestIsTextLikeAttachment(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		mime string
+		name string
+		want bool
+	}{
+		{mime: "text/plain; charset=utf-8", name: "a.txt", want: true},
+		{mime: "application/json", name: "a.json", want: true},
+		{mime: "image/png", name: "a.png", want: false},
+		{mime: "application/pdf", name: "a.md", want: false},
+		{mime: "", name: "notes.md", want: true},
+		{mime: "application/octet-stream", name: "main.go", want: true},
+		{mime: "", name: "archive.zip", want: false},
+	}
+	for _, tc := range cases {
+		if got := isTextLikeAttachment(tc.mime, tc.name); got != tc.want {
+			t.Fatalf("isTextLikeAttachment(%q, %q)=%v, want %v", tc.mime, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBuildMessagesForRun_CombinesTextAttachments(t *testing.T) {
+	t.Parallel()
+
+	req := RunRequest{
+		Input: RunInput{
+			Text: "compare",
+			Attachments: []RunAttachmentIn{
+				{Name: "a.txt", MimeType: "text/plain", URL: "/u/a"},
+				{Name: "shot.png", MimeType: "image/png", URL: "/u/shot"},
+				{Name: "b.json", MimeType: "application/json", URL: "/u/b"},
+			},
+		},
+		AttachmentLimitNote: "Attachment limit: only the first 3 of 5 attachments were included.",
+	}
+	messages := buildMessagesForRun(req)
+	// user text, combined text files, image, limit note
+	if len(messages) != 4 {
+		t.Fatalf("messages=%d, want 4: %+v", len(messages), messages)
+	}
+	combined := messages[1].Content
+	if len(combined) != 2 || combined[0].FileURI != "/u/a" || combined[1].FileURI != "/u/b" {
+		t.Fatalf("text attachments not combined: %+v", combined)
+	}
+	if got := messages[2].Content; len(got) != 1 || got[0].FileURI != "/u/shot" {
+		t.Fatalf("image attachment must stay separate: %+v", got)
+	}
+	if got := messages[3].Content[0].Text; !strings.HasPrefix(got, "Attachment limit:") {
+		t.Fatalf("limit note missing: %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/floegence/redeven/internal/session"
+)
+
+const (
+	// backgroundRunShutdownGrace bounds how long Close waits for canceled background runs to persist their end state.
+	backgroundRunShutdownGrace  = 10 * time.Second
+	cancelReasonServiceShutdown = "service_shutdown"
+)
+
+var errBackgroundRunsClosed = errors.New("ai service is shutting down")
+
+// backgroundRuns tracks runs started with RunOptions.Background.
+//
+// They have no client stream and no request context, so the service owns their lifetime and cancels them on Close.
+type backgroundRuns struct {
+	mu     sync.Mutex
+	closed bool
+	runs   map[string]*run
+	wg     sync.WaitGroup
+}
+
+func newBackgroundRuns() *backgroundRuns {
+	return &backgroundRuns{runs: make(map[string]*run)}
+}
+
+func (b *backgroundRuns) start(r *run, fn func()) error {
+	if b == nil || r == nil || fn == nil {
+		return errors.New("invalid background run")
+	}
+	runID := strings.TrimSpace(r.id)
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return errBackgroundRunsClosed
+	}
+	b.runs[runID] = r
+	b.wg.Add(1)
+	b.mu.Unlock()
+
+	go func() {
+		defer b.wg.Done()
+		defer func() {
+			b.mu.Lock()
+			delete(b.runs, runID)
+			b.mu.Unlock()
+		}()
+		fn()
+	}()
+	return nil
+}
+
+// shutdown cancels every background run and waits up to grace for them to finish.
+// It reports whether all of them finished in time.
+func (b *backgroundRuns) shutdown(grace time.Duration) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	b.closed = true
+	runs := make([]*run, 0, len(b.runs))
+	for _, r := range b.runs {
+		runs = append(runs, r)
+	}
+	b.mu.Unlock()
+
+	for _, r := range runs {
+		r.requestCancel(cancelReasonServiceShutdown)
+	}
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// startBackgroundRun prepares a run without a client stream and executes it on the service's lifetime.
+// Events and messages are persisted as usual, so clients follow it through the run event stream.
+// ctx only bounds the wait for a queued start; the run itself does not end with it.
+func (s *Service) startBackgroundRun(ctx context.Context, meta *session.Meta, runID string, req RunStartRequest) error {
+	prepared, err := s.prepareRun(ctx, meta, runID, req, nil, nil)
+	if err != nil {
+		return err
+	}
+	execute := func() {
+		if err := s.executePreparedRun(context.Background(), prepared); err != nil && s.log != nil {
+			s.log.Warn("ai background run failed", "run_id", runID, "thread_id", strings.TrimSpace(req.ThreadID), "error", err)
+		}
+	}
+	if err := s.backgroundRuns.start(prepared.r, execute); err != nil {
+		// The service closed while the run was being prepared. Finish it as canceled so the thread slot is released.
+		prepared.r.requestCancel(cancelReasonServiceShutdown)
+		execute()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestBackgroundRuns_ShutdownCancelsAndWaits(t *testing.T) {
+	t.Parallel()
+
+	b := newBackgroundRuns()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &run{id: "run_bg", doneCh: make(chan struct{}), cancelFn: cancel}
+	finished := make(chan string, 1)
+	if err := b.start(r, func() {
+		<-ctx.Done()
+		r.markDone()
+		finished <- r.getCancelReason()
+	}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	if !b.shutdown(5 * time.Second) {
+		t.Fatalf("shutdown did not wait for the background run")
+	}
+	select {
+	case reason := <-finished:
+		if reason != cancelReasonServiceShutdown {
+			t.Fatalf("cancel reason=%q, want %q", reason, cancelReasonServiceShutdown)
+		}
+	default:
+		t.Fatalf("background run still running after shutdown")
+	}
+
+	if err := b.start(&run{id: "run_late"}, func() {}); !errors.Is(err, errBackgroundRunsClosed) {
+		t.Fatalf("start after shutdown err=%v, want %v", err, errBackgroundRunsClosed)
+	}
+}
+
+func TestIntegration_StartRun_BackgroundDetachesFromStream(t *testing.T) {
+	t.Parallel()
+
+	token := "MOCK_OK_BACKGROUND"
+	mock := &openAIMock{token: token}
+	srv := httptest.NewServer(http.HandlerFunc(mock.handle))
+	t.Cleanup(srv.Close)
+
+	svc, err := NewService(Options{
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		StateDir:     t.TempDir(),
+		AgentHomeDir: t.TempDir(),
+		Shell:        "bash",
+		Config: &config.AIConfig{Providers: []config.AIProvider{{
+			ID:      "openai",
+			Name:    "OpenAI",
+			Type:    "openai",
+			BaseURL: strings.TrimSuffix(srv.URL, "/") + "/v1",
+			Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+		}}},
+		RunMaxWallTime:      30 * time.Second,
+		RunIdleTimeout:      10 * time.Second,
+		ToolApprovalTimeout: 5 * time.Second,
+		ResolveProviderAPIKey: func(string) (string, bool, error) {
+			return "sk-test", true, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+
+	meta := session.Meta{
+		EndpointID: "env_test",
+		ChannelID:  "ch_test_background",
+		CanRead:    true,
+		CanWrite:   true,
+		CanExecute: true,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	th, err := svc.CreateThread(ctx, &meta, "hello", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	// The caller's context ends right away, as it does when the browser closes; the run must not notice.
+	reqCtx, cancelReq := context.WithCancel(ctx)
+	rr := httptest.NewRecorder()
+	if err := svc.StartRun(reqCtx, &meta, "run_test_background", RunStartRequest{
+		ThreadID: th.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "hello"},
+		Options:  RunOptions{MaxSteps: 1, Background: true},
+	}, rr); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	cancelReq()
+	svc.backgroundRuns.wg.Wait()
+
+	if rr.Body.Len() != 0 {
+		t.Fatalf("background run wrote to the client stream: %q", rr.Body.String())
+	}
+	view, err := svc.GetThread(ctx, &meta, th.ThreadID)
+	if err != nil || view == nil {
+		t.Fatalf("GetThread view=%v err=%v", view, err)
+	}
+	if !strings.Contains(view.LastMessagePreview, token) {
+		t.Fatalf("last_message_preview=%q, want it to include %q", view.LastMessagePreview, token)
+	}
+}
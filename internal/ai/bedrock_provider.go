@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/bedrock"
+	aoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+// newBedrockProvider runs the Anthropic adapter against the AWS Bedrock runtime.
+//
+// Credentials and, when region is empty, the region come from the AWS environment: the AWS_* variables,
+// the shared config profile, or the instance role.
+func newBedrockProvider(ctx context.Context, region string, baseURL string, retry providerRetryPolicy, httpClient *http.Client) (*anthropicProvider, error) {
+	var loadOpts []func(*awsconfig.LoadOptions) error
+	if region = strings.TrimSpace(region); region != "" {
+		loadOpts = append(loadOpts, awsconfig.WithRegion(region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config failed: %w", err)
+	}
+	return newBedrockProviderWithAWSConfig(awsCfg, baseURL, retry, httpClient)
+}
+
+// newBedrockProviderWithAWSConfig builds the Bedrock adapter on a resolved AWS config.
+//
+// The SDK's bedrock middleware moves the model id into the /model/{modelId}/invoke-with-response-stream
+// path, signs each request with SigV4, and decodes the AWS event stream back into Anthropic stream
+// events. Messages, tools, and stop reasons therefore go through the same helpers as the anthropic
+// provider type.
+func newBedrockProviderWithAWSConfig(awsCfg aws.Config, baseURL string, retry providerRetryPolicy, httpClient *http.Client) (*anthropicProvider, error) {
+	if strings.TrimSpace(awsCfg.Region) == "" {
+		return nil, errors.New("missing bedrock region (set region or AWS_REGION)")
+	}
+	if awsCfg.Credentials == nil && awsCfg.BearerAuthTokenProvider == nil && os.Getenv("AWS_BEARER_TOKEN_BEDROCK") == "" {
+		return nil, errors.New("missing aws credentials for bedrock")
+	}
+	opts := []aoption.RequestOption{
+		bedrock.WithConfig(awsCfg),
+		// Never forward an ANTHROPIC_API_KEY picked up from the environment to AWS.
+		aoption.WithHeaderDel("x-api-key"),
+		aoption.WithMaxRetries(0),
+	}
+	if strings.TrimSpace(baseURL) != "" {
+		opts = append(opts, aoption.WithBaseURL(strings.TrimSpace(baseURL)))
+	}
+	if httpClient != nil {
+		opts = append(opts, aoption.WithHTTPClient(httpClient))
+	}
+	return &anthropicProvider{client: anthropic.NewClient(opts...), retry: retry}, nil
+}
+
+// bedrockRuntimeEndpoint is the default Bedrock runtime endpoint of an AWS region.
+func bedrockRuntimeEndpoint(region string) string {
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", strings.TrimSpace(region))
+}
@@ -32,10 +32,16 @@ func toolSuccessSummary(toolName string) string {
 		return "web.search"
 	case "knowledge.search":
 		return "knowledge.search"
+	case "read_tool_output":
+		return "tool_output.read"
+	case "scratchpad":
+		return "scratchpad"
 	case "use_skill":
 		return "skill.activated"
 	case "subagents":
 		return "delegation.managed"
+	case "spawn_subtask":
+		return "subtask.completed"
 	default:
 		return "tool.success"
 	}
@@ -67,15 +73,16 @@ func (h *builtInToolHandler) Execute(ctx context.Context, call ToolCall) (ToolRe
 		return ToolResult{ToolID: call.ID, ToolName: toolName, Status: toolResultStatusError, Summary: "tool.error", Details: "empty tool outcome"}, nil
 	}
 	if outcome.Success {
-		data, truncated := normalizeTruncatedToolPayload(toolName, outcome.Result)
+		data, truncated, contentRef := h.r.inlineToolResultPayload(strings.TrimSpace(call.ID), toolName, outcome.Result)
 		return ToolResult{
-			ToolID:    strings.TrimSpace(call.ID),
-			ToolName:  toolName,
-			Status:    toolResultStatusSuccess,
-			Summary:   toolSuccessSummary(toolName),
-			Details:   "tool execution completed",
-			Data:      data,
-			Truncated: truncated,
+			ToolID:     strings.TrimSpace(call.ID),
+			ToolName:   toolName,
+			Status:     toolResultStatusSuccess,
+			Summary:    toolSuccessSummary(toolName),
+			Details:    "tool execution completed",
+			Data:       data,
+			Truncated:  truncated,
+			ContentRef: contentRef,
 		}, nil
 	}
 	if outcome.ToolError != nil {
@@ -95,21 +102,31 @@ func (h *builtInToolHandler) Execute(ctx context.Context, call ToolCall) (ToolRe
 			summary = "tool.aborted"
 		case aitools.ErrorCodePermissionDenied:
 			summary = "permission_denied"
+		case aitools.ErrorCodeSandboxViolation:
+			status = toolResultStatusAborted
+			summary = sandboxViolationSummary
+		case aitools.ErrorCodeCommandDenied:
+			status = toolResultStatusAborted
+			summary = commandDeniedSummary
+		case aitools.ErrorCodePatchRejected:
+			status = toolResultStatusAborted
+			summary = patchRejectedSummary
 		}
 	}
 	if details == "" {
 		details = "tool execution failed"
 	}
-	data, truncated := normalizeTruncatedToolPayload(toolName, outcome.Result)
+	data, truncated, contentRef := h.r.inlineToolResultPayload(strings.TrimSpace(call.ID), toolName, outcome.Result)
 	return ToolResult{
-		ToolID:    strings.TrimSpace(call.ID),
-		ToolName:  toolName,
-		Status:    status,
-		Summary:   summary,
-		Details:   details,
-		Data:      data,
-		Truncated: truncated,
-		Error:     outcome.ToolError,
+		ToolID:     strings.TrimSpace(call.ID),
+		ToolName:   toolName,
+		Status:     status,
+		Summary:    summary,
+		Details:    details,
+		Data:       data,
+		Truncated:  truncated,
+		ContentRef: contentRef,
+		Error:      outcome.ToolError,
 	}, nil
 }
 
@@ -183,6 +200,9 @@ func normalizeTruncatedToolPayload(toolName string, payload any) (any, bool) {
 			m["truncated"] = true
 		}
 		return m, truncated
+	case "read_tool_output":
+		// Pages are already bounded by readToolOutputMaxBytes.
+		return payload, false
 	default:
 		if payload == nil {
 			return nil, false
@@ -351,6 +371,30 @@ func askUserToolInputSchema() map[string]any {
 			"choices_exhaustive": map[string]any{"type": "boolean", "description": "For choice-based questions, true means the fixed choices are genuinely exhaustive; false means the user may need a typed fallback beyond the listed choices."},
 			"write_label":        map[string]any{"type": "string", "maxLength": 200, "description": "For select_or_write, this is the standardized typed fallback label such as None of the above. For write, it can label the direct input."},
 			"write_placeholder":  map[string]any{"type": "string", "maxLength": 160},
+			"input_spec": map[string]any{
+				"type":        "object",
+				"description": "Optional type for the written answer of write and select_or_write questions. The UI renders a matching control and the answer is validated before the run resumes.",
+				"properties": map[string]any{
+					"type":       map[string]any{"type": "string", "enum": []string{"string", "number", "date", "path", "enum"}},
+					"min_length": map[string]any{"type": "integer", "minimum": 0, "maximum": 2000, "description": "string only."},
+					"max_length": map[string]any{"type": "integer", "minimum": 1, "maximum": 2000, "description": "string only."},
+					"pattern":    map[string]any{"type": "string", "maxLength": 200, "description": "string only. Go regular expression that must match the whole answer."},
+					"min":        map[string]any{"type": "number", "description": "number only."},
+					"max":        map[string]any{"type": "number", "description": "number only."},
+					"integer":    map[string]any{"type": "boolean", "description": "number only. Require a whole number."},
+					"min_date":   map[string]any{"type": "string", "description": "date only. YYYY-MM-DD."},
+					"max_date":   map[string]any{"type": "string", "description": "date only. YYYY-MM-DD."},
+					"absolute":   map[string]any{"type": "boolean", "description": "path only. Require an absolute path."},
+					"values": map[string]any{
+						"type":        "array",
+						"maxItems":    20,
+						"description": "enum only. The accepted answers.",
+						"items":       map[string]any{"type": "string", "maxLength": 200},
+					},
+				},
+				"required":             []string{"type"},
+				"additionalProperties": false,
+			},
 			"choices": map[string]any{
 				"type":     "array",
 				"maxItems": 4,
@@ -501,7 +545,7 @@ func builtInToolDefinitions() []ToolDef {
 			Name:             "terminal.exec",
 			Description:      "Execute a shell command on the local machine. Defaults to the run working directory. When timeout_ms is omitted, the runtime applies a 2-minute default timeout; any requested timeout is capped at 10 minutes.",
 			InputSchema:      toSchema(map[string]any{"type": "object", "properties": map[string]any{"command": map[string]any{"type": "string"}, "stdin": map[string]any{"type": "string", "maxLength": 200000}, "cwd": map[string]any{"type": "string"}, "workdir": map[string]any{"type": "string"}, "timeout_ms": map[string]any{"type": "integer", "minimum": 1, "maximum": 600000}, "description": map[string]any{"type": "string", "maxLength": 200}}, "required": []string{"command"}, "additionalProperties": false}),
-			ParallelSafe:     false,
+			ParallelSafe:     true,
 			Mutating:         false,
 			RequiresApproval: false,
 			Source:           "builtin",
@@ -530,11 +574,33 @@ func builtInToolDefinitions() []ToolDef {
 			Namespace:        "builtin.knowledge",
 			Priority:         100,
 		},
+		{
+			Name:             "read_tool_output",
+			Description:      "Read more of a large tool result that was stored out of band. Tool results with a content_ref carry only a truncated preview; page through the full serialized result by byte offset.",
+			InputSchema:      toSchema(map[string]any{"type": "object", "properties": map[string]any{"content_ref": map[string]any{"type": "string", "description": "The content_ref of a truncated tool result."}, "offset": map[string]any{"type": "integer", "minimum": 0, "description": "Byte offset to start from. Use next_offset from the previous page to continue."}, "limit": map[string]any{"type": "integer", "minimum": 1, "maximum": readToolOutputMaxBytes, "description": "Maximum bytes to return. Defaults to 8192."}}, "required": []string{"content_ref"}, "additionalProperties": false}),
+			ParallelSafe:     true,
+			Mutating:         false,
+			RequiresApproval: false,
+			Source:           "builtin",
+			Namespace:        "builtin.state",
+			Priority:         100,
+		},
 		{
 			Name:             "write_todos",
 			Description:      "Replace the current thread todo list snapshot for actionable work. Keep at most one in_progress item, avoid empty lists unless explicitly clearing prior todos, and use at least 3 todos when the user asks for explicit planning/task breakdown.",
 			InputSchema:      toSchema(map[string]any{"type": "object", "properties": map[string]any{"todos": map[string]any{"type": "array", "items": map[string]any{"type": "object", "properties": map[string]any{"id": map[string]any{"type": "string"}, "content": map[string]any{"type": "string"}, "status": map[string]any{"type": "string", "enum": []string{"pending", "in_progress", "completed", "cancelled"}}, "note": map[string]any{"type": "string"}}, "required": []string{"content", "status"}, "additionalProperties": false}}, "expected_version": map[string]any{"type": "integer", "minimum": 0}, "explanation": map[string]any{"type": "string", "maxLength": 500}}, "required": []string{"todos"}, "additionalProperties": false}),
-			ParallelSafe:     true,
+			ParallelSafe:     false,
+			Mutating:         false,
+			RequiresApproval: false,
+			Source:           "builtin",
+			Namespace:        "builtin.state",
+			Priority:         100,
+		},
+		{
+			Name:             "scratchpad",
+			Description:      "Keep keyed notes for this thread so later turns can reuse findings instead of re-deriving them. op=set replaces a key, append adds to it, get reads one key, and list returns every key with a short preview. All notes in a thread share a 64 KiB limit.",
+			InputSchema:      toSchema(map[string]any{"type": "object", "properties": map[string]any{"op": map[string]any{"type": "string", "enum": []string{"set", "get", "append", "list"}}, "key": map[string]any{"type": "string", "maxLength": scratchpadMaxKeyRunes, "description": "Note key. Required except for list."}, "value": map[string]any{"type": "string", "description": "Text to store (set) or add (append)."}}, "required": []string{"op"}, "additionalProperties": false}),
+			ParallelSafe:     false,
 			Mutating:         false,
 			RequiresApproval: false,
 			Source:           "builtin",
@@ -591,6 +657,16 @@ func builtInToolDefinitions() []ToolDef {
 			Namespace:    "builtin.subagent",
 			Priority:     100,
 		},
+		{
+			Name:         "spawn_subtask",
+			Description:  "Run a focused sub-task in an isolated child agent and wait for its result. The child sees only the objective and context you pass, inherits the current mode and tool limits, gets a smaller step budget, and cannot ask the user. Nesting is limited to two levels.",
+			InputSchema:  toSchema(map[string]any{"type": "object", "properties": map[string]any{"objective": map[string]any{"type": "string", "maxLength": 2000, "description": "Self-contained objective for the child agent."}, "context": map[string]any{"type": "string", "maxLength": 8000, "description": "Optional trusted facts the child needs, such as file paths or findings so far."}, "max_steps": map[string]any{"type": "integer", "minimum": 1, "description": "Optional step budget. It is capped at half of the current run's budget."}}, "required": []string{"objective"}, "additionalProperties": false}),
+			ParallelSafe: false,
+			Mutating:     false,
+			Source:       "builtin",
+			Namespace:    "builtin.subagent",
+			Priority:     100,
+		},
 	}
 	return defs
 }
@@ -612,6 +688,12 @@ func registerBuiltInTools(reg *InMemoryToolRegistry, r *run) error {
 				continue
 			}
 		}
+		if def.Name == "spawn_subtask" {
+			if !r.canSpawnSubtask() {
+				continue
+			}
+			def.Mutating = r.subtaskMayMutate()
+		}
 		handler := ToolHandler(&builtInToolHandler{r: r, toolName: def.Name})
 		if def.Name == "task_complete" || def.Name == "ask_user" || def.Name == "exit_plan_mode" {
 			handler = signalToolHandler{}
@@ -61,7 +61,7 @@ func TestPrepareRun_DoesNotCreateThreadCheckpoint(t *testing.T) {
 	}
 
 	runID := "run_prepare_without_checkpoint"
-	prepared, err := svc.prepareRun(meta, runID, RunStartRequest{
+	prepared, err := svc.prepareRun(context.Background(), meta, runID, RunStartRequest{
 		ThreadID: thread.ThreadID,
 		Model:    "openai/gpt-5-mini",
 		Input:    RunInput{Text: "hello"},
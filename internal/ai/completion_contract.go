@@ -12,6 +12,10 @@ const (
 	finalizationClassFailure     = "failure"
 
 	finalizationReasonBlockedNoUserInteraction = "blocked_no_user_interaction"
+	// finalizationReasonAskUserBounceGuard ends a run whose guard-originated ask_user kept being rejected.
+	finalizationReasonAskUserBounceGuard = "ask_user_bounce_guard"
+	// finalizationReasonDegradedSummary ends a run with a summary built from recorded facts after the summary turn failed.
+	finalizationReasonDegradedSummary = "degraded_summary"
 )
 
 func completionContractForExecutionContract(executionContract string) string {
@@ -27,7 +31,7 @@ func completionContractForExecutionContract(executionContract string) string {
 
 func classifyFinalizationReason(finalizationReason string) string {
 	switch strings.TrimSpace(finalizationReason) {
-	case "task_complete", "task_complete_forced", "social_reply", "creative_reply", "hybrid_first_turn_reply", finalizationReasonProtocolCloseout:
+	case "task_complete", "task_complete_forced", "social_reply", "creative_reply", "hybrid_first_turn_reply", finalizationReasonProtocolCloseout, finalizationReasonCostBudgetExceeded, finalizationReasonWallTimeExceeded, finalizationReasonAskUserBounceGuard, finalizationReasonDegradedSummary:
 		return finalizationClassSuccess
 	case "ask_user_waiting", "ask_user_waiting_model", "ask_user_waiting_guard", finalizationReasonExitPlanModeWaiting:
 		return finalizationClassWaitingUser
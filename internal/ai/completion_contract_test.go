@@ -29,6 +29,7 @@ func TestClassifyFinalizationReason(t *testing.T) {
 	}{
 		{reason: "task_complete", want: finalizationClassSuccess},
 		{reason: "task_complete_forced", want: finalizationClassSuccess},
+		{reason: finalizationReasonCostBudgetExceeded, want: finalizationClassSuccess},
 		{reason: finalizationReasonProtocolCloseout, want: finalizationClassSuccess},
 		{reason: "social_reply", want: finalizationClassSuccess},
 		{reason: "creative_reply", want: finalizationClassSuccess},
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCompletionQualityRejects caps quality-guard rejections per run; later task_complete calls are accepted as-is.
+const maxCompletionQualityRejects = 2
+
+// Completion quality rejection reasons persisted on completion.quality_guard events.
+const (
+	completionRejectTooShort     = "completion_too_short"
+	completionRejectPreambleOnly = "completion_preamble_only"
+)
+
+// preambleOnlyMaxRunes is the length above which a result is never treated as preamble-only.
+const preambleOnlyMaxRunes = 180
+
+// LooksPreambleOnly reports whether text only announces upcoming work ("let me check ...")
+// without a result, conclusion, or recommendation. Empty text counts as preamble-only.
+func LooksPreambleOnly(text string) bool {
+	trimmed := strings.TrimSpace(strings.ToLower(text))
+	if trimmed == "" {
+		return true
+	}
+	if utf8.RuneCountInString(trimmed) > preambleOnlyMaxRunes {
+		return false
+	}
+	preambleHints := []string{"let me", "i will", "first i", "i'll first", "quick scan", "first pass"}
+	hasPreamble := false
+	for _, hint := range preambleHints {
+		if strings.Contains(trimmed, hint) {
+			hasPreamble = true
+			break
+		}
+	}
+	if !hasPreamble {
+		return false
+	}
+	finalHints := []string{"final", "result", "directory", "conclusion", "recommendation", "risk"}
+	for _, hint := range finalHints {
+		if strings.Contains(trimmed, hint) {
+			return false
+		}
+	}
+	return true
+}
+
+// completionQualityRejectReason returns why resultText fails the opt-in quality guard, or "" when it passes
+// or the guard is disabled for this run.
+func completionQualityRejectReason(resultText string, opts RunOptions) string {
+	resultText = strings.TrimSpace(resultText)
+	if opts.MinCompletionChars > 0 && utf8.RuneCountInString(resultText) < opts.MinCompletionChars {
+		return completionRejectTooShort
+	}
+	if opts.RejectPreambleOnlyCompletion && LooksPreambleOnly(resultText) {
+		return completionRejectPreambleOnly
+	}
+	return ""
+}
+
+// completionQualityRejectPrompts returns the user message and recovery overlay sent back after a rejection.
+func completionQualityRejectPrompts(reason string, resultText string, minChars int) (string, string) {
+	if reason == completionRejectTooShort {
+		got := utf8.RuneCountInString(strings.TrimSpace(resultText))
+		return fmt.Sprintf("task_complete was rejected: the result has %d characters, below the required minimum of %d. Write the complete answer in the result, then call task_complete again.", got, minChars),
+			"[RECOVERY] Completion result too short. Put the full, substantive answer in task_complete.result."
+	}
+	return "task_complete was rejected: the result only announces what you will do. Put the actual findings or answer in the result, then call task_complete again.",
+		"[RECOVERY] Completion result is preamble only. Deliver the concrete result, not a plan to produce it."
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLooksPreambleOnly(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"":                                  true,
+		"Let me scan the repository first.": true,
+		"I will check the config next.":     true,
+		"Let me summarize the final result: the build passes.": false,
+		"The service listens on port 8080.":                    false,
+		"Let me " + strings.Repeat("explain ", 30):             false,
+	}
+	for text, want := range cases {
+		if got := LooksPreambleOnly(text); got != want {
+			t.Fatalf("LooksPreambleOnly(%q)=%v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestCompletionQualityRejectReason(t *testing.T) {
+	t.Parallel()
+
+	preamble := "Let me take a quick scan of the repo."
+	if got := completionQualityRejectReason(preamble, RunOptions{}); got != "" {
+		t.Fatalf("guard must be opt-in, got %q", got)
+	}
+	if got := completionQualityRejectReason("  Done.  ", RunOptions{MinCompletionChars: 20}); got != completionRejectTooShort {
+		t.Fatalf("short result: got %q", got)
+	}
+	if got := completionQualityRejectReason("完成了所有的修改", RunOptions{MinCompletionChars: 8}); got != "" {
+		t.Fatalf("length must count characters, not bytes: got %q", got)
+	}
+	if got := completionQualityRejectReason(preamble, RunOptions{RejectPreambleOnlyCompletion: true}); got != completionRejectPreambleOnly {
+		t.Fatalf("preamble result: got %q", got)
+	}
+	if got := completionQualityRejectReason("Let me recap the conclusion: all checks pass.", RunOptions{MinCompletionChars: 10, RejectPreambleOnlyCompletion: true}); got != "" {
+		t.Fatalf("substantive result: got %q", got)
+	}
+
+	msg, overlay := completionQualityRejectPrompts(completionRejectTooShort, "Done.", 20)
+	if !strings.Contains(msg, "5 characters") || !strings.Contains(msg, "minimum of 20") || !strings.HasPrefix(overlay, "[RECOVERY]") {
+		t.Fatalf("msg=%q overlay=%q", msg, overlay)
+	}
+}
+
+func TestValidateRunOptions_MinCompletionChars(t *testing.T) {
+	t.Parallel()
+
+	if err := ValidateRunOptions(RunOptions{MinCompletionChars: -1}); err == nil {
+		t.Fatalf("negative min_completion_chars must be rejected")
+	}
+	if err := ValidateRunOptions(RunOptions{MinCompletionChars: 200}); err != nil {
+		t.Fatalf("ValidateRunOptions: %v", err)
+	}
+}
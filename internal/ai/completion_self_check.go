@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const (
+	// maxCompletionSelfChecks caps self-check turns per run; later task_complete calls are accepted unchecked.
+	maxCompletionSelfChecks            = 2
+	completionSelfCheckMaxOutputTokens = 400
+	completionSelfCheckTimeout         = 45 * time.Second
+	completionSelfCheckHistoryMaxRunes = 24000
+)
+
+// Self-check verdicts persisted on completion.self_check events.
+const (
+	completionSelfCheckVerdictPass    = "pass"
+	completionSelfCheckVerdictFail    = "fail"
+	completionSelfCheckVerdictError   = "error"
+	completionSelfCheckVerdictSkipped = "skipped"
+)
+
+// completionSelfCheckResult is the model's judgement of a proposed task_complete.
+type completionSelfCheckResult struct {
+	Verdict  string   `json:"verdict"`
+	Reason   string   `json:"reason"`
+	Evidence []string `json:"evidence,omitempty"`
+}
+
+// runCompletionSelfCheck asks the run's model, in one short tool-free turn, whether resultText meets the objective.
+//
+// A provider error or unparsable answer returns an error; callers treat that as "not verified" and accept the completion.
+func runCompletionSelfCheck(ctx context.Context, adapter Provider, model string, objective string, resultText string, history []Message) (completionSelfCheckResult, TurnUsage, error) {
+	if adapter == nil {
+		return completionSelfCheckResult{}, TurnUsage{}, errors.New("missing self-check provider")
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, completionSelfCheckTimeout)
+	defer cancel()
+
+	result, err := adapter.StreamTurn(checkCtx, TurnRequest{
+		Model:     strings.TrimSpace(model),
+		Messages:  buildCompletionSelfCheckMessages(objective, resultText, history),
+		Budgets:   TurnBudgets{MaxSteps: 1, MaxOutputToken: completionSelfCheckMaxOutputTokens},
+		ModeFlags: ModeFlags{Mode: config.AIModePlan},
+	}, nil)
+	if err != nil {
+		return completionSelfCheckResult{}, result.Usage, err
+	}
+	verdict, err := parseCompletionSelfCheckResult(result.Text)
+	return verdict, result.Usage, err
+}
+
+func buildCompletionSelfCheckMessages(objective string, resultText string, history []Message) []Message {
+	system := strings.Join([]string{
+		"You review whether an agent really finished its task before the result is shown to the user.",
+		"Compare the objective with the work transcript and the proposed final result.",
+		"Pass only when the transcript shows concrete evidence (tool results, file paths, command output) that the objective is met.",
+		"Fail when required work is missing, unverified, or contradicted by the transcript.",
+		"Do not call tools. Reply with only a JSON object:",
+		`{"verdict":"pass"|"fail","reason":"one sentence","evidence":["short citations from the transcript"]}`,
+	}, "\n")
+	transcript := renderCompactionTranscript(history)
+	if runes := []rune(transcript); len(runes) > completionSelfCheckHistoryMaxRunes {
+		// The end of the transcript holds the work that should justify completion.
+		transcript = "[earlier transcript omitted] ... " + string(runes[len(runes)-completionSelfCheckHistoryMaxRunes:])
+	}
+	user := strings.Join([]string{
+		"Objective:\n" + strings.TrimSpace(objective),
+		"Work transcript:\n" + transcript,
+		"Proposed final result:\n" + strings.TrimSpace(resultText),
+	}, "\n\n")
+	return []Message{
+		{Role: "system", Content: []ContentPart{{Type: "text", Text: system}}},
+		{Role: "user", Content: []ContentPart{{Type: "text", Text: user}}},
+	}
+}
+
+func parseCompletionSelfCheckResult(text string) (completionSelfCheckResult, error) {
+	raw := extractFirstJSONObject(text)
+	if raw == "" {
+		return completionSelfCheckResult{}, errors.New("self-check returned no JSON verdict")
+	}
+	var out completionSelfCheckResult
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return completionSelfCheckResult{}, err
+	}
+	out.Verdict = strings.ToLower(strings.TrimSpace(out.Verdict))
+	out.Reason = sanitizeLogText(out.Reason, 300)
+	switch out.Verdict {
+	case completionSelfCheckVerdictPass, completionSelfCheckVerdictFail:
+	default:
+		return completionSelfCheckResult{}, errors.New("self-check returned an unknown verdict")
+	}
+	evidence := make([]string, 0, len(out.Evidence))
+	for _, item := range out.Evidence {
+		if item = sanitizeLogText(item, 200); item != "" {
+			evidence = append(evidence, item)
+		}
+		if len(evidence) == 5 {
+			break
+		}
+	}
+	out.Evidence = evidence
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type completionSelfCheckProviderStub struct {
+	text string
+	err  error
+	req  TurnRequest
+}
+
+func (p *completionSelfCheckProviderStub) StreamTurn(_ context.Context, req TurnRequest, _ func(StreamEvent)) (TurnResult, error) {
+	p.req = req
+	return TurnResult{Text: p.text, Usage: TurnUsage{InputTokens: 120, OutputTokens: 30}}, p.err
+}
+
+func TestParseCompletionSelfCheckResult(t *testing.T) {
+	t.Parallel()
+
+	got, err := parseCompletionSelfCheckResult("Sure.\n```json\n{\"verdict\":\" PASS \",\"reason\":\"tests ran\",\"evidence\":[\"go test ok\",\"  \",\"a\",\"b\",\"c\",\"d\",\"e\"]}\n```")
+	if err != nil {
+		t.Fatalf("parse pass: %v", err)
+	}
+	if got.Verdict != completionSelfCheckVerdictPass || got.Reason != "tests ran" || len(got.Evidence) != 5 || got.Evidence[0] != "go test ok" {
+		t.Fatalf("got=%+v", got)
+	}
+
+	got, err = parseCompletionSelfCheckResult(`{"verdict":"fail","reason":"the file was never written"}`)
+	if err != nil || got.Verdict != completionSelfCheckVerdictFail || got.Reason != "the file was never written" {
+		t.Fatalf("parse fail: got=%+v err=%v", got, err)
+	}
+
+	for _, bad := range []string{"", "looks good to me", `{"verdict":"maybe"}`} {
+		if _, err := parseCompletionSelfCheckResult(bad); err == nil {
+			t.Fatalf("parse %q: want error", bad)
+		}
+	}
+}
+
+func TestRunCompletionSelfCheck(t *testing.T) {
+	t.Parallel()
+
+	history := []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "write notes.md"}}}}
+	for i := 0; i < 40; i++ {
+		history = append(history, Message{Role: "assistant", Content: []ContentPart{{Type: "text", Text: strings.Repeat("x", 1000)}}})
+	}
+	history = append(history, Message{Role: "assistant", Content: []ContentPart{{Type: "text", Text: "wrote notes.md"}}})
+	stub := &completionSelfCheckProviderStub{text: `{"verdict":"fail","reason":"notes.md was not verified"}`}
+	got, usage, err := runCompletionSelfCheck(context.Background(), stub, "gpt-5-mini", "write notes.md", "Done.", history)
+	if err != nil {
+		t.Fatalf("runCompletionSelfCheck: %v", err)
+	}
+	if got.Verdict != completionSelfCheckVerdictFail || usage.InputTokens != 120 {
+		t.Fatalf("got=%+v usage=%+v", got, usage)
+	}
+	if len(stub.req.Tools) != 0 || stub.req.Budgets.MaxOutputToken != completionSelfCheckMaxOutputTokens || len(stub.req.Messages) != 2 {
+		t.Fatalf("unexpected self-check request: %+v", stub.req)
+	}
+	prompt := stub.req.Messages[1].Content[0].Text
+	if !strings.Contains(prompt, "[earlier transcript omitted]") || !strings.Contains(prompt, "wrote notes.md") || !strings.Contains(prompt, "Proposed final result:\nDone.") {
+		t.Fatalf("prompt should keep the transcript tail and the result: %q", prompt[len(prompt)-200:])
+	}
+
+	stub = &completionSelfCheckProviderStub{err: errors.New("provider down")}
+	if _, _, err := runCompletionSelfCheck(context.Background(), stub, "gpt-5-mini", "x", "y", nil); err == nil {
+		t.Fatalf("provider error must surface")
+	}
+	if _, _, err := runCompletionSelfCheck(context.Background(), nil, "gpt-5-mini", "x", "y", nil); err == nil {
+		t.Fatalf("nil provider must error")
+	}
+}
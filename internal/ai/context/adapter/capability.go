@@ -67,7 +67,18 @@ func capabilitiesEquivalent(a model.ModelCapability, b model.ModelCapability) bo
 		a.SupportsAskUserQuestionBatches == b.SupportsAskUserQuestionBatches &&
 		a.MaxContextTokens == b.MaxContextTokens &&
 		a.MaxOutputTokens == b.MaxOutputTokens &&
-		a.PreferredToolSchemaMode == b.PreferredToolSchemaMode
+		a.PreferredToolSchemaMode == b.PreferredToolSchemaMode &&
+		a.RejectsSamplingParams == b.RejectsSamplingParams &&
+		a.SupportsImageOutput == b.SupportsImageOutput &&
+		floatPtrEqual(a.DefaultTemperature, b.DefaultTemperature) &&
+		floatPtrEqual(a.DefaultTopP, b.DefaultTopP)
+}
+
+func floatPtrEqual(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
 }
 
 func modelNameFromID(modelID string) string {
@@ -97,8 +108,14 @@ func defaultCapability(provider config.AIProvider, modelName string) model.Model
 		PreferredToolSchemaMode:        "json_schema",
 	}
 
+	if providerType == "bedrock" {
+		// Bedrock model ids wrap the Claude model name (us.anthropic.claude-…-v1:0).
+		if name, ok := config.BedrockClaudeModelName(modelName); ok {
+			modelLower = name
+		}
+	}
 	switch providerType {
-	case "anthropic":
+	case "anthropic", "bedrock":
 		cap.SupportsParallelTools = false
 		cap.SupportsStrictJSONSchema = false
 		cap.SupportsAskUserQuestionBatches = false
@@ -135,10 +152,32 @@ func defaultCapability(provider config.AIProvider, modelName string) model.Model
 		cap.PreferredToolSchemaMode = "relaxed_json"
 		cap.MaxContextTokens = 64000
 		cap.MaxOutputTokens = 4096
+	case "ollama":
+		// Local models: conservative defaults; configure context_window per model to go higher.
+		cap.SupportsParallelTools = false
+		cap.SupportsStrictJSONSchema = false
+		cap.SupportsImageInput = false
+		cap.SupportsFileInput = false
+		cap.SupportsReasoningTokens = false
+		cap.SupportsAskUserQuestionBatches = false
+		cap.PreferredToolSchemaMode = "relaxed_json"
+		cap.MaxContextTokens = 32768
+		cap.MaxOutputTokens = 4096
+	case "mistral":
+		cap.SupportsStrictJSONSchema = false
+		cap.SupportsReasoningTokens = false
+		cap.SupportsAskUserQuestionBatches = false
+		cap.PreferredToolSchemaMode = "relaxed_json"
+		cap.MaxContextTokens = 128000
+		cap.MaxOutputTokens = 8192
 	case "openai":
 		cap.SupportsParallelTools = false
 		cap.SupportsStrictJSONSchema = true
 		cap.PreferredToolSchemaMode = "json_schema"
+	case "azure_openai":
+		cap.SupportsParallelTools = false
+		cap.SupportsStrictJSONSchema = false
+		cap.PreferredToolSchemaMode = "relaxed_json"
 	}
 
 	if strings.Contains(modelLower, "mini") {
@@ -171,6 +210,7 @@ func defaultCapability(provider config.AIProvider, modelName string) model.Model
 		cap.MaxContextTokens = max(cap.MaxContextTokens, 200000)
 		cap.MaxOutputTokens = max(cap.MaxOutputTokens, 128000)
 	}
+	cap.RejectsSamplingParams = rejectsSamplingParams(providerType, modelLower)
 
 	if providerModel, ok := providerModelByName(provider, modelName); ok {
 		if effectiveInputWindow := providerModel.EffectiveInputWindowTokens(); effectiveInputWindow > 0 {
@@ -179,10 +219,32 @@ func defaultCapability(provider config.AIProvider, modelName string) model.Model
 		if providerModel.MaxOutputTokens > 0 {
 			cap.MaxOutputTokens = providerModel.MaxOutputTokens
 		}
+		if providerModel.OmitSamplingParams != nil {
+			cap.RejectsSamplingParams = *providerModel.OmitSamplingParams
+		}
+		if providerType == "openai" || providerType == "azure_openai" {
+			cap.SupportsImageOutput = providerModel.ImageOutput
+		}
+		cap.DefaultTemperature = providerModel.DefaultTemperature
+		cap.DefaultTopP = providerModel.DefaultTopP
 	}
 	return cap
 }
 
+// rejectsSamplingParams reports built-in reasoning models that reject temperature/top_p.
+func rejectsSamplingParams(providerType string, modelLower string) bool {
+	switch providerType {
+	case "openai", "azure_openai":
+		if strings.HasPrefix(modelLower, "gpt-5") && !strings.Contains(modelLower, "chat") {
+			return true
+		}
+		return strings.HasPrefix(modelLower, "o1") || strings.HasPrefix(modelLower, "o3") || strings.HasPrefix(modelLower, "o4")
+	case "deepseek":
+		return strings.Contains(modelLower, "deepseek-reasoner")
+	}
+	return false
+}
+
 func providerModelByName(provider config.AIProvider, modelName string) (config.AIProviderModel, bool) {
 	target := strings.TrimSpace(modelName)
 	if target == "" {
@@ -169,3 +169,52 @@ func TestAdaptAttachments_DegradeUnsupportedModes(t *testing.T) {
 		t.Fatalf("out[1].Mode=%q, want text_reference", out[1].Mode)
 	}
 }
+
+func TestDefaultCapability_SamplingParams(t *testing.T) {
+	t.Parallel()
+
+	if cap := defaultCapability(config.AIProvider{Type: "openai"}, "gpt-5-mini"); !cap.RejectsSamplingParams {
+		t.Fatalf("gpt-5-mini should reject sampling params")
+	}
+	if cap := defaultCapability(config.AIProvider{Type: "openai"}, "gpt-4.1"); cap.RejectsSamplingParams {
+		t.Fatalf("gpt-4.1 should accept sampling params")
+	}
+
+	temp := 0.3
+	omit := false
+	provider := config.AIProvider{
+		Type: "openai",
+		Models: []config.AIProviderModel{
+			{ModelName: "gpt-5", DefaultTemperature: &temp, OmitSamplingParams: &omit},
+		},
+	}
+	cap := defaultCapability(provider, "gpt-5")
+	if cap.RejectsSamplingParams {
+		t.Fatalf("omit_sampling_params=false must override built-in detection")
+	}
+	if cap.DefaultTemperature == nil || *cap.DefaultTemperature != 0.3 {
+		t.Fatalf("DefaultTemperature=%v, want 0.3", cap.DefaultTemperature)
+	}
+}
+
+func TestDefaultCapability_ImageOutputIsPerModelOptIn(t *testing.T) {
+	t.Parallel()
+
+	provider := config.AIProvider{
+		Type: "openai",
+		Models: []config.AIProviderModel{
+			{ModelName: "gpt-5", ImageOutput: true},
+			{ModelName: "gpt-5-mini"},
+		},
+	}
+	if cap := defaultCapability(provider, "gpt-5"); !cap.SupportsImageOutput {
+		t.Fatalf("image_output=true must enable SupportsImageOutput")
+	}
+	if cap := defaultCapability(provider, "gpt-5-mini"); cap.SupportsImageOutput {
+		t.Fatalf("text-only model must not support image output")
+	}
+	provider.Type = "anthropic"
+	if cap := defaultCapability(provider, "gpt-5"); cap.SupportsImageOutput {
+		t.Fatalf("non-OpenAI provider must not support image output")
+	}
+}
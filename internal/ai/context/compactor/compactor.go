@@ -23,7 +23,25 @@ func New(repo *contextstore.Repository) *SnapshotCompactor {
 	return &SnapshotCompactor{repo: repo, now: time.Now}
 }
 
+// Summarizer condenses archived dialogue turns and execution evidence into a dense snapshot, typically by asking a model.
+type Summarizer func(ctx context.Context, archived []model.DialogueTurn, evidence []model.ExecutionEvidence) (string, error)
+
 func (c *SnapshotCompactor) CompactPromptPack(ctx context.Context, endpointID string, targetInputTokens int, in model.PromptPack) (model.PromptPack, bool, verifier.VerifyResult, error) {
+	return c.compactPromptPack(ctx, endpointID, targetInputTokens, in, nil)
+}
+
+// CompactPromptPackWithSummarizer is CompactPromptPack with archived turns folded by summarize instead of truncated bullets.
+//
+// summarize sees the evidence before L1 trimming. A summarizer error aborts compaction and is returned to the caller,
+// which can fall back to CompactPromptPack.
+func (c *SnapshotCompactor) CompactPromptPackWithSummarizer(ctx context.Context, endpointID string, targetInputTokens int, in model.PromptPack, summarize Summarizer) (model.PromptPack, bool, verifier.VerifyResult, error) {
+	if summarize == nil {
+		return c.CompactPromptPack(ctx, endpointID, targetInputTokens, in)
+	}
+	return c.compactPromptPack(ctx, endpointID, targetInputTokens, in, summarize)
+}
+
+func (c *SnapshotCompactor) compactPromptPack(ctx context.Context, endpointID string, targetInputTokens int, in model.PromptPack, summarize Summarizer) (model.PromptPack, bool, verifier.VerifyResult, error) {
 	before := clonePromptPack(in)
 	if targetInputTokens <= 0 {
 		targetInputTokens = 12000
@@ -52,7 +70,18 @@ func (c *SnapshotCompactor) CompactPromptPack(ctx context.Context, endpointID st
 		cutoff := len(working.RecentDialogue) - 4
 		archived := working.RecentDialogue[:cutoff]
 		kept := working.RecentDialogue[cutoff:]
-		summary := summarizeTurns(archived)
+		summary := ""
+		if summarize != nil {
+			modelSummary, err := summarize(ctx, append([]model.DialogueTurn(nil), archived...), append([]model.ExecutionEvidence(nil), before.ExecutionEvidence...))
+			if err != nil {
+				return before, false, verifier.VerifyResult{}, err
+			}
+			if modelSummary = strings.TrimSpace(modelSummary); modelSummary != "" {
+				summary = "Episode snapshot:\n" + modelSummary
+			}
+		} else {
+			summary = summarizeTurns(archived)
+		}
 		if summary != "" {
 			if strings.TrimSpace(working.ThreadSnapshot) != "" {
 				working.ThreadSnapshot = strings.TrimSpace(working.ThreadSnapshot + "\n" + summary)
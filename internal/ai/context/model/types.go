@@ -18,6 +18,13 @@ type ModelCapability struct {
 	MaxContextTokens               int    `json:"max_context_tokens"`
 	MaxOutputTokens                int    `json:"max_output_tokens"`
 	PreferredToolSchemaMode        string `json:"preferred_tool_schema_mode"`
+	// RejectsSamplingParams marks models (typically reasoning models) that reject temperature/top_p.
+	RejectsSamplingParams bool `json:"rejects_sampling_params,omitempty"`
+	// DefaultTemperature and DefaultTopP apply when a run does not set sampling explicitly.
+	DefaultTemperature *float64 `json:"default_temperature,omitempty"`
+	DefaultTopP        *float64 `json:"default_top_p,omitempty"`
+	// SupportsImageOutput marks models allowed to return generated images (opt-in per model).
+	SupportsImageOutput bool `json:"supports_image_output,omitempty"`
 }
 
 type MemoryScope string
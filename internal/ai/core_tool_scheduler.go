@@ -2,15 +2,15 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	aitools "github.com/floegence/redeven/internal/ai/tools"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -167,11 +167,73 @@ func (f DefaultModeToolFilter) FilterToolsForMode(mode string, all []ToolDef) []
 	return out
 }
 
+const defaultParallelToolCallConcurrency = 4
+
 type CoreToolScheduler struct {
 	registry     toolResolver
 	interceptors []ToolInterceptor
 	modeFilter   ModeToolFilter
 	parallelism  int
+	rateLimit    *toolRateLimitBinding
+	// approval evaluates ai.tool_approval for each call; nil leaves approval to execution_policy.
+	approval *toolApprovalBinding
+	// allowParallel extends concurrent dispatch to parallel-safe tools whose effect depends on the
+	// invocation, such as read-only terminal.exec commands.
+	allowParallel bool
+	// onParallelDispatch is called for each batch that runs more than one call concurrently.
+	onParallelDispatch func(toolParallelDispatch)
+	// callTimeout returns the per-call timeout enforced around each handler; nil or <= 0 disables it.
+	callTimeout func(ToolCall) time.Duration
+	// onToolTimeout is called for each call canceled by its per-call timeout.
+	onToolTimeout func(toolCallTimeout)
+	// tracer records one span per executed call; nil disables tool spans.
+	tracer trace.Tracer
+}
+
+// toolParallelDispatch describes one batch of concurrently executed tool calls.
+type toolParallelDispatch struct {
+	ToolIDs     []string
+	ToolNames   []string
+	Concurrency int
+	Limit       int
+}
+
+// enableParallelToolCalls lets Dispatch run read-only calls of parallel-safe tools concurrently, bounded by maxConcurrency.
+func (s *CoreToolScheduler) enableParallelToolCalls(maxConcurrency int, onDispatch func(toolParallelDispatch)) {
+	if s == nil {
+		return
+	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultParallelToolCallConcurrency
+	}
+	s.allowParallel = true
+	s.parallelism = maxConcurrency
+	s.onParallelDispatch = onDispatch
+}
+
+// enableToolCallTimeouts cancels each handler that outlives timeoutFor(call) and reports it as tool_timeout.
+func (s *CoreToolScheduler) enableToolCallTimeouts(timeoutFor func(ToolCall) time.Duration, onTimeout func(toolCallTimeout)) {
+	if s == nil {
+		return
+	}
+	s.callTimeout = timeoutFor
+	s.onToolTimeout = onTimeout
+}
+
+// canRunConcurrently reports whether call may join a concurrent batch.
+//
+// Tools opt in with ToolDef.ParallelSafe; being non-mutating is not enough, because tools such as
+// spawn_subtask, write_todos, and scratchpad share per-run state. A parallel-safe tool whose effect
+// depends on the invocation, such as terminal.exec, joins only when parallel tool calls are enabled
+// and the call is read-only.
+func (s *CoreToolScheduler) canRunConcurrently(def ToolDef, call ToolCall) bool {
+	if def.Mutating || !def.ParallelSafe || isMutatingInvocation(call.Name, call.Args) {
+		return false
+	}
+	if aitools.InvocationCommandProfile(call.Name, call.Args).Risk != "" {
+		return s.allowParallel
+	}
+	return true
 }
 
 func NewCoreToolScheduler(reg ToolRegistry, modeFilter ModeToolFilter, interceptors ...ToolInterceptor) (*CoreToolScheduler, error) {
@@ -210,6 +272,10 @@ func (s *CoreToolScheduler) HandlePartial(ctx context.Context, partial PartialTo
 	return handler.HandlePartial(ctx, partial)
 }
 
+// Dispatch executes calls and returns one result per call, in call order.
+//
+// Consecutive calls that may run concurrently form a batch that runs on a bounded worker pool;
+// any other call runs alone, after the batch before it and before the calls after it.
 func (s *CoreToolScheduler) Dispatch(ctx context.Context, mode string, calls []ToolCall) []ToolResult {
 	if s == nil || s.registry == nil {
 		return []ToolResult{{Status: toolResultStatusError, Summary: "tool.scheduler_error", Details: "tool scheduler unavailable"}}
@@ -230,8 +296,7 @@ func (s *CoreToolScheduler) Dispatch(ctx context.Context, mode string, calls []T
 		handler ToolHandler
 	}
 	results := make([]ToolResult, len(calls))
-	parallelItems := make([]dispatchItem, 0, len(calls))
-	serialItems := make([]dispatchItem, 0, len(calls))
+	items := make([]dispatchItem, 0, len(calls))
 
 	for idx, call := range calls {
 		call.Name = strings.TrimSpace(call.Name)
@@ -239,6 +304,11 @@ func (s *CoreToolScheduler) Dispatch(ctx context.Context, mode string, calls []T
 			results[idx] = ToolResult{ToolID: call.ID, Status: toolResultStatusError, Summary: "tool.argument_error", Details: "missing tool name"}
 			continue
 		}
+		registeredDef, _, _ := s.registry.resolve(call.Name)
+		if planModeBlocksInvocation(mode, registeredDef, call) {
+			results[idx] = ToolResult{ToolID: call.ID, ToolName: call.Name, Status: toolResultStatusAborted, Summary: planModeBlockedSummary, Details: "Mutating tool call blocked in plan mode. Replan with read-only tools, or call exit_plan_mode to request act mode."}
+			continue
+		}
 		def, ok := activeSet[call.Name]
 		if !ok {
 			results[idx] = ToolResult{ToolID: call.ID, ToolName: call.Name, Status: toolResultStatusError, Summary: "tool.argument_error", Details: fmt.Sprintf("unknown or disabled tool: %s", call.Name)}
@@ -250,33 +320,46 @@ func (s *CoreToolScheduler) Dispatch(ctx context.Context, mode string, calls []T
 			continue
 		}
 		if err := validateToolArgs(def, call.Args); err != nil {
-			results[idx] = ToolResult{ToolID: call.ID, ToolName: call.Name, Status: toolResultStatusError, Summary: "tool.argument_error", Details: err.Error()}
+			results[idx] = ToolResult{ToolID: call.ID, ToolName: call.Name, Status: toolResultStatusAborted, Summary: "tool.argument_error", Details: err.Error()}
 			continue
 		}
 		if err := handler.Validate(ctx, call); err != nil {
 			results[idx] = ToolResult{ToolID: call.ID, ToolName: call.Name, Status: toolResultStatusError, Summary: "tool.argument_error", Details: err.Error()}
 			continue
 		}
-		item := dispatchItem{index: idx, call: call, def: def, handler: handler}
-		if def.ParallelSafe && !def.Mutating {
-			parallelItems = append(parallelItems, item)
-		} else {
-			serialItems = append(serialItems, item)
-		}
+		items = append(items, dispatchItem{index: idx, call: call, def: def, handler: handler})
 	}
 
 	runItem := func(item dispatchItem) {
-		results[item.index] = s.executeOne(ctx, item.call, item.def, item.handler)
+		if s.tracer == nil {
+			results[item.index] = s.executeOne(ctx, item.call, item.def, item.handler)
+			return
+		}
+		spanCtx, span := startToolSpan(ctx, s.tracer, item.call)
+		results[item.index] = s.executeOne(spanCtx, item.call, item.def, item.handler)
+		endToolSpan(span, results[item.index])
 	}
 
-	if len(parallelItems) > 0 {
+	runBatch := func(batch []dispatchItem) {
+		if len(batch) == 1 {
+			runItem(batch[0])
+			return
+		}
 		limit := s.parallelism
 		if limit <= 0 {
 			limit = 2
 		}
+		if s.onParallelDispatch != nil {
+			info := toolParallelDispatch{Concurrency: min(limit, len(batch)), Limit: limit}
+			for _, item := range batch {
+				info.ToolIDs = append(info.ToolIDs, item.call.ID)
+				info.ToolNames = append(info.ToolNames, item.call.Name)
+			}
+			s.onParallelDispatch(info)
+		}
 		sem := make(chan struct{}, limit)
 		var wg sync.WaitGroup
-		for _, item := range parallelItems {
+		for _, item := range batch {
 			item := item
 			wg.Add(1)
 			go func() {
@@ -293,9 +376,21 @@ func (s *CoreToolScheduler) Dispatch(ctx context.Context, mode string, calls []T
 		wg.Wait()
 	}
 
-	for _, item := range serialItems {
+	batch := make([]dispatchItem, 0, len(items))
+	for _, item := range items {
+		if s.canRunConcurrently(item.def, item.call) {
+			batch = append(batch, item)
+			continue
+		}
+		if len(batch) > 0 {
+			runBatch(batch)
+			batch = batch[:0]
+		}
 		runItem(item)
 	}
+	if len(batch) > 0 {
+		runBatch(batch)
+	}
 
 	for i, result := range results {
 		if strings.TrimSpace(result.Status) == "" {
@@ -309,6 +404,9 @@ func (s *CoreToolScheduler) executeOne(ctx context.Context, call ToolCall, def T
 	if err := ctx.Err(); err != nil {
 		return ToolResult{ToolID: call.ID, ToolName: call.Name, Status: toolResultStatusAborted, Summary: "tool.aborted", Details: err.Error()}
 	}
+	if throttled, blocked := s.rateLimit.throttle(ctx, call); blocked {
+		return throttled
+	}
 	patched := call
 	for _, interceptor := range s.interceptors {
 		if interceptor == nil {
@@ -320,8 +418,27 @@ func (s *CoreToolScheduler) executeOne(ctx context.Context, call ToolCall, def T
 		}
 		patched = nextCall
 	}
+	if decision := s.approval.decide(patched); decision.Matched {
+		ctx = withToolApprovalDecision(ctx, decision)
+	}
 
-	result, err := handler.Execute(ctx, patched)
+	result, timedOut, err := s.executeHandler(ctx, patched, handler)
+	if timedOut != nil {
+		if s.onToolTimeout != nil {
+			s.onToolTimeout(*timedOut)
+		}
+		return ToolResult{
+			ToolID:   call.ID,
+			ToolName: call.Name,
+			Status:   toolResultStatusAborted,
+			Summary:  toolCallTimeoutSummary,
+			Details:  fmt.Sprintf("%s did not finish within %s and was canceled.", call.Name, timedOut.Timeout),
+			Data: map[string]any{
+				"timeout_ms": timedOut.Timeout.Milliseconds(),
+				"elapsed_ms": timedOut.Elapsed.Milliseconds(),
+			},
+		}
+	}
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			return ToolResult{ToolID: call.ID, ToolName: call.Name, Status: toolResultStatusAborted, Summary: "tool.aborted", Details: "tool execution canceled"}
@@ -354,77 +471,48 @@ func (s *CoreToolScheduler) executeOne(ctx context.Context, call ToolCall, def T
 	return result
 }
 
-func validateToolArgs(def ToolDef, args map[string]any) error {
-	if len(def.InputSchema) == 0 {
-		return nil
-	}
-	if args == nil {
-		args = map[string]any{}
-	}
-	var schema map[string]any
-	if err := json.Unmarshal(def.InputSchema, &schema); err != nil {
-		return nil
-	}
-	if req, ok := schema["required"].([]any); ok {
-		for _, item := range req {
-			name, _ := item.(string)
-			name = strings.TrimSpace(name)
-			if name == "" {
-				continue
-			}
-			if _, exists := args[name]; !exists {
-				return fmt.Errorf("missing required field: %s", name)
-			}
-		}
-	}
-	properties, _ := schema["properties"].(map[string]any)
-	for key, val := range args {
-		propRaw, ok := properties[key]
-		if !ok {
-			continue
-		}
-		prop, _ := propRaw.(map[string]any)
-		typeName, _ := prop["type"].(string)
-		typeName = strings.TrimSpace(typeName)
-		if typeName == "" {
-			continue
-		}
-		if !matchesSchemaType(typeName, val) {
-			return fmt.Errorf("invalid type for %s: expected %s", key, typeName)
+// executeHandler runs handler under the call's per-call timeout.
+//
+// When the timeout elapses the call context is canceled, the handler gets a short window to unwind
+// (terminal.exec kills its process group on cancel), and the timeout is returned instead of its result.
+func (s *CoreToolScheduler) executeHandler(ctx context.Context, call ToolCall, handler ToolHandler) (ToolResult, *toolCallTimeout, error) {
+	timeout := time.Duration(0)
+	if s.callTimeout != nil {
+		timeout = s.callTimeout(call)
+	}
+	if timeout <= 0 {
+		result, err := handler.Execute(ctx, call)
+		return result, nil, err
+	}
+
+	started := time.Now()
+	callCtx, deadline := withToolCallDeadline(ctx, timeout)
+	defer deadline.stop()
+	type outcome struct {
+		result ToolResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := handler.Execute(callCtx, call)
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		if !errors.Is(context.Cause(callCtx), errToolCallTimeout) || (out.err == nil && out.result.Status != toolResultStatusAborted && out.result.Status != toolResultStatusError) {
+			return out.result, nil, out.err
 		}
-	}
-	return nil
-}
-
-func matchesSchemaType(typeName string, v any) bool {
-	typeName = strings.ToLower(strings.TrimSpace(typeName))
-	switch typeName {
-	case "string":
-		_, ok := v.(string)
-		return ok
-	case "boolean":
-		_, ok := v.(bool)
-		return ok
-	case "integer":
-		switch v.(type) {
-		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float64, float32:
-			return true
-		default:
-			return false
+	case <-callCtx.Done():
+		if !errors.Is(context.Cause(callCtx), errToolCallTimeout) {
+			// The run itself was canceled; the handler reports that on its own.
+			out := <-done
+			return out.result, nil, out.err
 		}
-	case "number":
-		switch v.(type) {
-		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float64, float32:
-			return true
-		default:
-			return false
+		select {
+		case <-done:
+		case <-time.After(toolCallTimeoutWaitAfterCancel):
 		}
-	case "object":
-		return reflect.TypeOf(v) != nil && reflect.TypeOf(v).Kind() == reflect.Map
-	case "array":
-		kind := reflect.TypeOf(v)
-		return kind != nil && (kind.Kind() == reflect.Slice || kind.Kind() == reflect.Array)
-	default:
-		return true
 	}
+	return ToolResult{}, &toolCallTimeout{ToolID: call.ID, ToolName: call.Name, Timeout: timeout, Elapsed: time.Since(started)}, nil
 }
@@ -0,0 +1,353 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+// probeToolHandler records execution order and peak concurrency.
+//
+// Calls listed in peers wait (bounded) until all of them are running, so a serialized
+// dispatch shows up as a peak concurrency of 1 instead of a deadlock.
+type probeToolHandler struct {
+	mu          sync.Mutex
+	inflight    int
+	maxInflight int
+	order       []string
+	peers       map[string]bool
+	peersReady  sync.WaitGroup
+}
+
+func newProbeToolHandler(peerIDs ...string) *probeToolHandler {
+	h := &probeToolHandler{peers: make(map[string]bool, len(peerIDs))}
+	for _, id := range peerIDs {
+		h.peers[id] = true
+	}
+	h.peersReady.Add(len(peerIDs))
+	return h
+}
+
+func (h *probeToolHandler) Validate(context.Context, ToolCall) error { return nil }
+
+func (h *probeToolHandler) HandlePartial(context.Context, PartialToolCall) error { return nil }
+
+func (h *probeToolHandler) Execute(_ context.Context, call ToolCall) (ToolResult, error) {
+	h.mu.Lock()
+	h.inflight++
+	h.maxInflight = max(h.maxInflight, h.inflight)
+	h.order = append(h.order, call.ID)
+	h.mu.Unlock()
+
+	if h.peers[call.ID] {
+		h.peersReady.Done()
+		done := make(chan struct{})
+		go func() {
+			h.peersReady.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	h.mu.Lock()
+	h.inflight--
+	h.mu.Unlock()
+	return ToolResult{Summary: call.ID}, nil
+}
+
+func newProbeScheduler(t *testing.T, handler *probeToolHandler) *CoreToolScheduler {
+	t.Helper()
+	reg := NewInMemoryToolRegistry()
+	for _, def := range []ToolDef{
+		{Name: "terminal.exec", ParallelSafe: true},
+		{Name: "apply_patch", Mutating: true},
+	} {
+		if err := reg.Register(def, handler); err != nil {
+			t.Fatalf("Register %s: %v", def.Name, err)
+		}
+	}
+	scheduler, err := NewCoreToolScheduler(reg, nil)
+	if err != nil {
+		t.Fatalf("NewCoreToolScheduler: %v", err)
+	}
+	return scheduler
+}
+
+func probeCalls() []ToolCall {
+	return []ToolCall{
+		{ID: "call_read_1", Name: "terminal.exec", Args: map[string]any{"command": "ls"}},
+		{ID: "call_read_2", Name: "terminal.exec", Args: map[string]any{"command": "cat README.md"}},
+		{ID: "call_patch", Name: "apply_patch", Args: map[string]any{"patch": "*** Begin Patch\n*** End Patch"}},
+		{ID: "call_read_3", Name: "terminal.exec", Args: map[string]any{"command": "pwd"}},
+		{ID: "call_write", Name: "terminal.exec", Args: map[string]any{"command": "rm -rf build"}},
+	}
+}
+
+func TestCoreToolScheduler_ParallelDispatchKeepsMutatingCallsSerialized(t *testing.T) {
+	t.Parallel()
+
+	handler := newProbeToolHandler("call_read_1", "call_read_2")
+	scheduler := newProbeScheduler(t, handler)
+	var batches []toolParallelDispatch
+	scheduler.enableParallelToolCalls(4, func(batch toolParallelDispatch) { batches = append(batches, batch) })
+
+	calls := probeCalls()
+	results := scheduler.Dispatch(context.Background(), "act", calls)
+	if len(results) != len(calls) {
+		t.Fatalf("results=%d, want %d", len(results), len(calls))
+	}
+	for i, res := range results {
+		if res.ToolID != calls[i].ID || res.Status != toolResultStatusSuccess {
+			t.Fatalf("result[%d]=%+v, want success for %s", i, res, calls[i].ID)
+		}
+	}
+	if handler.maxInflight != 2 {
+		t.Fatalf("max concurrency=%d, want 2", handler.maxInflight)
+	}
+	if got := handler.order[2:]; got[0] != "call_patch" || got[1] != "call_read_3" || got[2] != "call_write" {
+		t.Fatalf("calls after the read batch ran out of order: %v", handler.order)
+	}
+	if len(batches) != 1 || batches[0].Concurrency != 2 || batches[0].Limit != 4 {
+		t.Fatalf("unexpected parallel batches: %+v", batches)
+	}
+	if ids := batches[0].ToolIDs; len(ids) != 2 || ids[0] != "call_read_1" || ids[1] != "call_read_2" {
+		t.Fatalf("unexpected batch tool ids: %v", ids)
+	}
+}
+
+func TestCoreToolScheduler_TerminalExecSerialWithoutParallelFlag(t *testing.T) {
+	t.Parallel()
+
+	handler := newProbeToolHandler()
+	scheduler := newProbeScheduler(t, handler)
+	calls := probeCalls()
+	scheduler.Dispatch(context.Background(), "act", calls)
+	if handler.maxInflight != 1 {
+		t.Fatalf("max concurrency=%d, want 1", handler.maxInflight)
+	}
+	for i, id := range handler.order {
+		if id != calls[i].ID {
+			t.Fatalf("execution order=%v, want call order", handler.order)
+		}
+	}
+}
+
+func TestCoreToolScheduler_PlanModeAbortsMutatingCalls(t *testing.T) {
+	t.Parallel()
+
+	handler := newProbeToolHandler()
+	scheduler := newProbeScheduler(t, handler)
+	calls := []ToolCall{
+		{ID: "call_patch", Name: "apply_patch", Args: map[string]any{"patch": "*** Begin Patch\n*** End Patch"}},
+		{ID: "call_rm", Name: "terminal.exec", Args: map[string]any{"command": "rm -rf build"}},
+		{ID: "call_redirect", Name: "terminal.exec", Args: map[string]any{"command": "echo hi > notes.txt"}},
+		{ID: "call_read", Name: "terminal.exec", Args: map[string]any{"command": "ls"}},
+	}
+
+	results := scheduler.Dispatch(context.Background(), "plan", calls)
+	if len(results) != len(calls) {
+		t.Fatalf("results=%d, want %d", len(results), len(calls))
+	}
+	for i, res := range results[:3] {
+		if res.ToolID != calls[i].ID || res.Status != toolResultStatusAborted || res.Summary != planModeBlockedSummary {
+			t.Fatalf("result[%d]=%+v, want aborted %s", i, res, planModeBlockedSummary)
+		}
+	}
+	if res := results[3]; res.Status != toolResultStatusSuccess {
+		t.Fatalf("read-only result=%+v, want success", res)
+	}
+	if len(handler.order) != 1 || handler.order[0] != "call_read" {
+		t.Fatalf("executed calls=%v, want only call_read", handler.order)
+	}
+
+	handler = newProbeToolHandler()
+	scheduler = newProbeScheduler(t, handler)
+	for _, res := range scheduler.Dispatch(context.Background(), "act", calls) {
+		if res.Status != toolResultStatusSuccess {
+			t.Fatalf("act result=%+v, want success", res)
+		}
+	}
+}
+
+func TestCoreToolScheduler_ValidatesArgsAgainstInputSchema(t *testing.T) {
+	t.Parallel()
+
+	handler := newProbeToolHandler()
+	reg := NewInMemoryToolRegistry()
+	def := ToolDef{
+		Name: "file.read",
+		InputSchema: json.RawMessage(`{
+			"type":"object",
+			"properties":{
+				"file_path":{"type":"string"},
+				"limit":{"type":["integer","null"],"minimum":1}
+			},
+			"required":["file_path"],
+			"additionalProperties":false
+		}`),
+	}
+	if err := reg.Register(def, handler); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	scheduler, err := NewCoreToolScheduler(reg, nil)
+	if err != nil {
+		t.Fatalf("NewCoreToolScheduler: %v", err)
+	}
+
+	calls := []ToolCall{
+		{ID: "call_missing", Name: "file.read", Args: map[string]any{"limit": 10}},
+		{ID: "call_wrong_type", Name: "file.read", Args: map[string]any{"file_path": "README.md", "limit": "ten"}},
+		{ID: "call_fraction", Name: "file.read", Args: map[string]any{"file_path": "README.md", "limit": 2.5}},
+		{ID: "call_extra", Name: "file.read", Args: map[string]any{"file_path": "README.md", "path": "README.md"}},
+		{ID: "call_ok", Name: "file.read", Args: map[string]any{"file_path": "README.md", "limit": nil}},
+	}
+	results := scheduler.Dispatch(context.Background(), "act", calls)
+	if len(results) != len(calls) {
+		t.Fatalf("results=%d, want %d", len(results), len(calls))
+	}
+	wantDetails := map[string]string{
+		"call_missing":    "missing property 'file_path'",
+		"call_wrong_type": "/limit: got string, want null or integer",
+		"call_fraction":   "/limit: got number, want null or integer",
+		"call_extra":      "additional properties 'path' not allowed",
+	}
+	for _, res := range results[:4] {
+		want := wantDetails[res.ToolID]
+		if res.Status != toolResultStatusAborted || res.Summary != "tool.argument_error" || !strings.Contains(res.Details, want) {
+			t.Fatalf("result=%+v, want aborted tool.argument_error mentioning %q", res, want)
+		}
+	}
+	if res := results[4]; res.Status != toolResultStatusSuccess {
+		t.Fatalf("valid call result=%+v, want success", res)
+	}
+	if len(handler.order) != 1 || handler.order[0] != "call_ok" {
+		t.Fatalf("executed calls=%v, want only call_ok", handler.order)
+	}
+}
+
+func TestValidateToolArgs_SkipsUnusableSchemas(t *testing.T) {
+	t.Parallel()
+
+	args := map[string]any{"anything": true}
+	for _, schema := range []string{``, `not json`, `{"type":"object","properties":{"x":{"$ref":"https://example.com/x.json"}}}`} {
+		if err := validateToolArgs(ToolDef{Name: "t", InputSchema: json.RawMessage(schema)}, args); err != nil {
+			t.Fatalf("schema %q: err=%v, want dispatch without validation", schema, err)
+		}
+	}
+}
+
+func TestBuiltInToolDefinitions_InputSchemasCompile(t *testing.T) {
+	t.Parallel()
+
+	for _, def := range builtInToolDefinitions() {
+		if _, err := compileToolArgsSchema(def.InputSchema); err != nil {
+			t.Fatalf("%s input schema does not compile: %v", def.Name, err)
+		}
+	}
+}
+
+func TestCoreToolScheduler_NonParallelSafeToolsStaySerial(t *testing.T) {
+	t.Parallel()
+
+	defs := map[string]ToolDef{}
+	for _, def := range builtInToolDefinitions() {
+		defs[def.Name] = def
+	}
+	handler := newProbeToolHandler()
+	reg := NewInMemoryToolRegistry()
+	for _, def := range []ToolDef{defs["write_todos"], defs["scratchpad"], {Name: "custom.lookup"}} {
+		if def.ParallelSafe || def.Mutating {
+			t.Fatalf("%s: want a non-mutating tool without ParallelSafe, got %+v", def.Name, def)
+		}
+		if err := reg.Register(def, handler); err != nil {
+			t.Fatalf("Register %s: %v", def.Name, err)
+		}
+	}
+	scheduler, err := NewCoreToolScheduler(reg, nil)
+	if err != nil {
+		t.Fatalf("NewCoreToolScheduler: %v", err)
+	}
+	var batches []toolParallelDispatch
+	scheduler.enableParallelToolCalls(4, func(batch toolParallelDispatch) { batches = append(batches, batch) })
+	calls := []ToolCall{
+		{ID: "call_todos", Name: "write_todos", Args: map[string]any{"todos": []any{}}},
+		{ID: "call_note_1", Name: "scratchpad", Args: map[string]any{"op": "list"}},
+		{ID: "call_note_2", Name: "scratchpad", Args: map[string]any{"op": "list"}},
+		{ID: "call_lookup_1", Name: "custom.lookup"},
+		{ID: "call_lookup_2", Name: "custom.lookup"},
+	}
+	for i, res := range scheduler.Dispatch(context.Background(), "act", calls) {
+		if res.Status != toolResultStatusSuccess {
+			t.Fatalf("result[%d]=%+v, want success", i, res)
+		}
+	}
+	if len(batches) != 0 || handler.maxInflight != 1 {
+		t.Fatalf("non-parallel-safe tools ran concurrently: batches=%+v max concurrency=%d", batches, handler.maxInflight)
+	}
+	for i, id := range handler.order {
+		if id != calls[i].ID {
+			t.Fatalf("execution order=%v, want call order", handler.order)
+		}
+	}
+}
+
+func TestCoreToolScheduler_SpawnSubtaskCallsNeverBatched(t *testing.T) {
+	t.Parallel()
+
+	builtins := NewInMemoryToolRegistry()
+	if err := registerBuiltInTools(builtins, &run{runMode: config.AIModeAct}); err != nil {
+		t.Fatalf("registerBuiltInTools: %v", err)
+	}
+	def, _, ok := builtins.resolve("spawn_subtask")
+	if !ok || !def.Mutating {
+		t.Fatalf("act-mode spawn_subtask def=%+v registered=%v, want mutating", def, ok)
+	}
+
+	handler := newProbeToolHandler()
+	reg := NewInMemoryToolRegistry()
+	if err := reg.Register(def, handler); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	scheduler, err := NewCoreToolScheduler(reg, nil)
+	if err != nil {
+		t.Fatalf("NewCoreToolScheduler: %v", err)
+	}
+	var batches []toolParallelDispatch
+	scheduler.enableParallelToolCalls(4, func(batch toolParallelDispatch) { batches = append(batches, batch) })
+	calls := []ToolCall{
+		{ID: "call_subtask_1", Name: "spawn_subtask", Args: map[string]any{"objective": "update docs"}},
+		{ID: "call_subtask_2", Name: "spawn_subtask", Args: map[string]any{"objective": "update tests"}},
+	}
+	for i, res := range scheduler.Dispatch(context.Background(), "act", calls) {
+		if res.Status != toolResultStatusSuccess {
+			t.Fatalf("result[%d]=%+v, want success", i, res)
+		}
+	}
+	if len(batches) != 0 || handler.maxInflight != 1 {
+		t.Fatalf("spawn_subtask calls ran concurrently: batches=%+v max concurrency=%d", batches, handler.maxInflight)
+	}
+
+	readonly := []struct {
+		name string
+		r    *run
+	}{
+		{name: "plan mode", r: &run{runMode: config.AIModePlan}},
+		{name: "read-only allowlist", r: &run{runMode: config.AIModeAct, toolAllowlist: map[string]struct{}{"file.read": {}, "terminal.exec": {}}, forceReadonlyExec: true}},
+	}
+	for _, tc := range readonly {
+		if tc.r.subtaskMayMutate() {
+			t.Fatalf("%s: subtask child should be read-only", tc.name)
+		}
+	}
+	if !(&run{runMode: config.AIModeAct, toolAllowlist: map[string]struct{}{"terminal.exec": {}}}).subtaskMayMutate() {
+		t.Fatalf("terminal.exec without forced read-only exec lets the child mutate")
+	}
+}
@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+// openAISummaryOutageMock answers the first task turn with a slow tool call, so the run passes its wall time,
+// and fails every later turn, including the final summary turn.
+type openAISummaryOutageMock struct {
+	mu    sync.Mutex
+	step  int
+	delay time.Duration
+}
+
+func (m *openAISummaryOutageMock) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	var req map[string]any
+	_ = json.Unmarshal(body, &req)
+
+	m.mu.Lock()
+	m.step++
+	step := m.step
+	m.mu.Unlock()
+	if step > 1 {
+		http.Error(w, `{"error":{"message":"provider unavailable"}}`, http.StatusServiceUnavailable)
+		return
+	}
+	time.Sleep(m.delay)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	f, _ := w.(http.Flusher)
+	writeOpenAISSEJSON(w, f, map[string]any{
+		"type":     "response.created",
+		"response": map[string]any{"id": "resp_outage_1", "created_at": time.Now().Unix(), "model": "gpt-5-mini"},
+	})
+	writeOpenAISSEJSON(w, f, map[string]any{
+		"type": "response.completed",
+		"response": map[string]any{
+			"id":     "resp_outage_1",
+			"model":  "gpt-5-mini",
+			"status": "completed",
+			"output": []any{
+				map[string]any{
+					"type":      "function_call",
+					"id":        "fc_outage_1",
+					"call_id":   "call_outage_1",
+					"name":      "file_read",
+					"arguments": `{"path":"README.md"}`,
+				},
+			},
+			"usage": map[string]any{"input_tokens": 1, "output_tokens": 1},
+		},
+	})
+	_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	f.Flush()
+}
+
+func runSummaryOutage(t *testing.T, fallback bool) (*Service, session.Meta, string, string) {
+	t.Helper()
+
+	mock := &openAISummaryOutageMock{delay: 1200 * time.Millisecond}
+	srv := httptest.NewServer(http.HandlerFunc(mock.handle))
+	t.Cleanup(srv.Close)
+
+	noRetries := 0
+	cfg := &config.AIConfig{
+		Providers: []config.AIProvider{
+			{
+				ID:      "openai",
+				Name:    "OpenAI",
+				Type:    "openai",
+				BaseURL: strings.TrimSuffix(srv.URL, "/") + "/v1",
+				Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+		ProviderRetry:                 &config.AIProviderRetryPolicy{MaxRetries: &noRetries},
+		EnableDegradedSummaryFallback: fallback,
+	}
+	meta := session.Meta{
+		EndpointID:        "env_test",
+		NamespacePublicID: "ns_test",
+		ChannelID:         "ch_test_degraded_summary",
+		UserPublicID:      "u_test",
+		UserEmail:         "u_test@example.com",
+		CanRead:           true,
+		CanWrite:          true,
+		CanExecute:        true,
+		CanAdmin:          true,
+	}
+	svc, err := NewService(Options{
+		Logger:              slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo})),
+		StateDir:            t.TempDir(),
+		AgentHomeDir:        t.TempDir(),
+		Shell:               "bash",
+		Config:              cfg,
+		RunMaxWallTime:      30 * time.Second,
+		RunIdleTimeout:      10 * time.Second,
+		ToolApprovalTimeout: 5 * time.Second,
+		ResolveProviderAPIKey: func(string) (string, bool, error) {
+			return "sk-test", true, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	th, err := svc.CreateThread(ctx, &meta, "outage", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	runID := fmt.Sprintf("run_test_degraded_summary_%v", fallback)
+	rr := httptest.NewRecorder()
+	if err := svc.StartRun(ctx, &meta, runID, RunStartRequest{
+		ThreadID: th.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "inspect the README and report what it covers"},
+		Options:  RunOptions{MaxSteps: 4, MaxWallTimeMs: 1000, ForceIntent: RunIntentTask},
+	}, rr); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	return svc, meta, runID, rr.Body.String()
+}
+
+func TestIntegration_DegradedSummaryFallback_OnSummaryTurnFailure(t *testing.T) {
+	t.Parallel()
+
+	svc, meta, runID, body := runSummaryOutage(t, true)
+	if !strings.Contains(body, "could not produce a summary") || !strings.Contains(body, "Not Done") || !strings.Contains(body, "Next Actions") {
+		t.Fatalf("stream missing degraded summary, body=%q", body)
+	}
+
+	events, err := svc.ListRunEvents(context.Background(), &meta, runID, 2000)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	degraded := findRunEventPayload(t, events.Events, "completion.degraded_summary")
+	if got := fmt.Sprint(degraded["source"]); got != "wall_time_summary_failed" {
+		t.Fatalf("completion.degraded_summary source=%q", got)
+	}
+	snapshot, err := svc.RunMetricsSnapshot(context.Background(), &meta, runID)
+	if err != nil {
+		t.Fatalf("RunMetricsSnapshot: %v", err)
+	}
+	if snapshot.FinalizationReason != finalizationReasonDegradedSummary {
+		t.Fatalf("finalization_reason=%q, want %q", snapshot.FinalizationReason, finalizationReasonDegradedSummary)
+	}
+	for _, ev := range events.Events {
+		if ev.EventType == "ask_user.waiting" {
+			t.Fatalf("fallback run still asked the user: %+v", ev.Payload)
+		}
+	}
+}
+
+func TestIntegration_DegradedSummaryFallback_DisabledAsksUser(t *testing.T) {
+	t.Parallel()
+
+	svc, meta, runID, _ := runSummaryOutage(t, false)
+	snapshot, err := svc.RunMetricsSnapshot(context.Background(), &meta, runID)
+	if err != nil {
+		t.Fatalf("RunMetricsSnapshot: %v", err)
+	}
+	if snapshot.FinalizationReason != "ask_user_waiting_guard" {
+		t.Fatalf("finalization_reason=%q, want ask_user_waiting_guard without enable_degraded_summary_fallback", snapshot.FinalizationReason)
+	}
+}
@@ -0,0 +1,289 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// externalToolTimeoutSummary is the tool result summary when no result arrives before ExternalToolDef.TimeoutMS.
+	externalToolTimeoutSummary = "external_tool_timeout"
+
+	defaultExternalToolTimeout = 30 * time.Minute
+	maxExternalToolTimeout     = 24 * time.Hour
+	maxExternalTools           = 16
+)
+
+var externalToolNameRE = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ExternalToolDef declares a tool that the run's caller executes outside the agent, such as a long CI job.
+//
+// A call parks the run until the caller posts the result with Service.ProvideToolResult.
+type ExternalToolDef struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+	// Mutating hides the tool in plan mode.
+	Mutating bool `json:"mutating,omitempty"`
+	// TimeoutMS bounds the wait for the result. Zero waits 30 minutes. The wait is further capped
+	// by the run's wall time, so it ends with a timeout result before the run deadline cancels it.
+	TimeoutMS int64 `json:"timeout_ms,omitempty"`
+}
+
+// ExternalToolResult is the outcome of one external tool call.
+type ExternalToolResult struct {
+	// Status is "success" (default) or "error".
+	Status string `json:"status,omitempty"`
+	// Data is the structured result the model sees.
+	Data any `json:"data,omitempty"`
+	// Error describes a failed call.
+	Error string `json:"error,omitempty"`
+}
+
+type ToolResultRequest struct {
+	ToolID string `json:"tool_id"`
+	ExternalToolResult
+}
+
+func validateExternalTools(defs []ExternalToolDef) error {
+	if len(defs) > maxExternalTools {
+		return fmt.Errorf("too many external_tools: %d (max %d)", len(defs), maxExternalTools)
+	}
+	reserved := make(map[string]struct{})
+	for _, def := range builtInToolDefinitions() {
+		reserved[def.Name] = struct{}{}
+		reserved[sanitizeProviderToolName(def.Name)] = struct{}{}
+	}
+	seen := make(map[string]struct{}, len(defs))
+	for i, def := range defs {
+		name := strings.TrimSpace(def.Name)
+		if !externalToolNameRE.MatchString(name) {
+			return fmt.Errorf("invalid external_tools[%d].name %q", i, def.Name)
+		}
+		if _, ok := reserved[name]; ok {
+			return fmt.Errorf("external_tools[%d].name %q conflicts with a built-in tool", i, name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate external_tools name %q", name)
+		}
+		seen[name] = struct{}{}
+		if strings.TrimSpace(def.Description) == "" {
+			return fmt.Errorf("external_tools[%d].description is required", i)
+		}
+		if len(def.InputSchema) > 0 {
+			var schema map[string]any
+			if err := json.Unmarshal(def.InputSchema, &schema); err != nil {
+				return fmt.Errorf("invalid external_tools[%d].input_schema: %w", i, err)
+			}
+		}
+		if def.TimeoutMS < 0 || time.Duration(def.TimeoutMS)*time.Millisecond > maxExternalToolTimeout {
+			return fmt.Errorf("invalid external_tools[%d].timeout_ms %d (must be in [0,%d])", i, def.TimeoutMS, maxExternalToolTimeout.Milliseconds())
+		}
+	}
+	return nil
+}
+
+func registerExternalTools(reg *InMemoryToolRegistry, r *run, defs []ExternalToolDef) error {
+	for _, def := range defs {
+		schema := def.InputSchema
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object","properties":{},"additionalProperties":true}`)
+		}
+		timeout := time.Duration(def.TimeoutMS) * time.Millisecond
+		if timeout <= 0 {
+			timeout = defaultExternalToolTimeout
+		}
+		if r != nil && r.maxWallTime > 0 && timeout > r.maxWallTime {
+			timeout = r.maxWallTime
+		}
+		toolDef := ToolDef{
+			Name:        strings.TrimSpace(def.Name),
+			Description: strings.TrimSpace(def.Description),
+			InputSchema: schema,
+			Mutating:    def.Mutating,
+			Source:      "external",
+			Namespace:   "external",
+		}
+		if err := reg.Register(toolDef, &externalToolHandler{r: r, timeout: timeout}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// externalToolHandler parks the calling run until the caller posts the tool result.
+type externalToolHandler struct {
+	r       *run
+	timeout time.Duration
+}
+
+func (h *externalToolHandler) Validate(_ context.Context, call ToolCall) error {
+	if h == nil || h.r == nil {
+		return errors.New("tool handler unavailable")
+	}
+	if strings.TrimSpace(call.ID) == "" {
+		return errors.New("missing tool id")
+	}
+	return nil
+}
+
+func (h *externalToolHandler) HandlePartial(_ context.Context, _ PartialToolCall) error {
+	return nil
+}
+
+func (h *externalToolHandler) Execute(ctx context.Context, call ToolCall) (ToolResult, error) {
+	r := h.r
+	toolID := strings.TrimSpace(call.ID)
+	toolName := strings.TrimSpace(call.Name)
+	r.recordRuntimeToolCall(toolName)
+
+	r.mu.Lock()
+	idx := r.nextBlockIndex
+	r.nextBlockIndex++
+	r.needNewTextBlock = true
+	r.mu.Unlock()
+	block := ToolCallBlock{
+		Type:     "tool-call",
+		ToolName: toolName,
+		ToolID:   toolID,
+		Args:     cloneAnyMap(call.Args),
+		Status:   ToolCallStatusPending,
+	}
+	r.emitPersistedToolBlockSet(idx, block)
+
+	timeout := h.waitTimeout()
+	ch := make(chan ExternalToolResult, 1)
+	r.mu.Lock()
+	r.externalToolWaits[toolID] = ch
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.externalToolWaits, toolID)
+		r.mu.Unlock()
+	}()
+	r.persistRunEvent("tool.external.waiting", RealtimeStreamKindLifecycle, map[string]any{
+		"tool_id":    toolID,
+		"tool_name":  toolName,
+		"timeout_ms": timeout.Milliseconds(),
+	})
+	r.debug("ai.run.tool.external.waiting", "tool_id", toolID, "tool_name", toolName)
+
+	// The wait is bounded by the external timeout, not by the tool call timeout or the idle watchdog.
+	resumeTimeout := pauseToolCallTimeout(ctx)
+	endBusy := r.beginBusy()
+	started := time.Now()
+	timer := time.NewTimer(timeout)
+	var (
+		result   ExternalToolResult
+		outcome  string
+		provided bool
+	)
+	select {
+	case res, open := <-ch:
+		if open {
+			result, outcome, provided = res, "provided", true
+		} else {
+			outcome = "canceled"
+		}
+	case <-ctx.Done():
+		outcome = "canceled"
+	case <-timer.C:
+		outcome = "timeout"
+	}
+	timer.Stop()
+	endBusy()
+	resumeTimeout()
+	r.touchActivity()
+
+	out := ToolResult{ToolID: toolID, ToolName: toolName}
+	switch {
+	case outcome == "timeout":
+		out.Status = toolResultStatusAborted
+		out.Summary = externalToolTimeoutSummary
+		out.Details = fmt.Sprintf("No result for %s arrived within %s.", toolName, timeout)
+		block.Status = ToolCallStatusError
+		block.Error = out.Details
+	case !provided:
+		out.Status = toolResultStatusAborted
+		out.Summary = "tool.aborted"
+		out.Details = "tool execution canceled"
+		block.Status = ToolCallStatusError
+		block.Error = "Canceled"
+	case result.Status == toolResultStatusError:
+		data, truncated, contentRef := r.inlineToolResultPayload(toolID, toolName, result.Data)
+		out.Status = toolResultStatusError
+		out.Summary = "tool.error"
+		out.Details = strings.TrimSpace(result.Error)
+		if out.Details == "" {
+			out.Details = "tool execution failed"
+		}
+		out.Data, out.Truncated, out.ContentRef = data, truncated, contentRef
+		block.Status = ToolCallStatusError
+		block.Error = out.Details
+		block.Result = result.Data
+	default:
+		data, truncated, contentRef := r.inlineToolResultPayload(toolID, toolName, result.Data)
+		out.Status = toolResultStatusSuccess
+		out.Summary = toolSuccessSummary(toolName)
+		out.Details = "tool execution completed"
+		out.Data, out.Truncated, out.ContentRef = data, truncated, contentRef
+		block.Status = ToolCallStatusSuccess
+		block.Result = result.Data
+	}
+	r.emitPersistedToolBlockSet(idx, block)
+	r.persistRunEvent("tool.external.resolved", RealtimeStreamKindLifecycle, map[string]any{
+		"tool_id":   toolID,
+		"tool_name": toolName,
+		"outcome":   outcome,
+		"status":    out.Status,
+		"waited_ms": time.Since(started).Milliseconds(),
+	})
+	return out, nil
+}
+
+// waitTimeout is how long one call waits for its result: the tool timeout, cut short by the run's
+// per-run wall time so the loop still gets the timeout result and can write its final summary.
+func (h *externalToolHandler) waitTimeout() time.Duration {
+	timeout := h.timeout
+	if deadline := h.r.wallDeadline; !deadline.IsZero() {
+		if remaining := time.Until(deadline); remaining < timeout {
+			timeout = max(remaining, 0)
+		}
+	}
+	return timeout
+}
+
+// provideToolResult hands the result of an external tool call to the waiting handler.
+func (r *run) provideToolResult(toolID string, result ExternalToolResult) error {
+	if r == nil {
+		return errors.New("nil run")
+	}
+	toolID = strings.TrimSpace(toolID)
+	if toolID == "" {
+		return errors.New("missing tool_id")
+	}
+	switch strings.TrimSpace(result.Status) {
+	case "":
+		result.Status = toolResultStatusSuccess
+	case toolResultStatusSuccess, toolResultStatusError:
+		result.Status = strings.TrimSpace(result.Status)
+	default:
+		return fmt.Errorf("invalid status %q", result.Status)
+	}
+
+	// The send happens under r.mu so it cannot race releasePendingApprovals closing the channel.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch := r.externalToolWaits[toolID]
+	if ch == nil {
+		return errors.New("tool not waiting for an external result")
+	}
+	delete(r.externalToolWaits, toolID)
+	ch <- result
+	return nil
+}
@@ -0,0 +1,338 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestValidateExternalTools(t *testing.T) {
+	t.Parallel()
+
+	valid := []ExternalToolDef{{Name: "ci_job", Description: "Run the CI pipeline", InputSchema: json.RawMessage(`{"type":"object"}`), TimeoutMS: 60_000}}
+	if err := validateExternalTools(valid); err != nil {
+		t.Fatalf("validateExternalTools: %v", err)
+	}
+	for name, defs := range map[string][]ExternalToolDef{
+		"invalid name":       {{Name: "CI job", Description: "x"}},
+		"dotted name":        {{Name: "ci.job", Description: "x"}},
+		"builtin name":       {{Name: "task_complete", Description: "x"}},
+		"builtin alias":      {{Name: "terminal_exec", Description: "x"}},
+		"duplicate":          {{Name: "ci_job", Description: "x"}, {Name: "ci_job", Description: "y"}},
+		"missing desc":       {{Name: "ci_job"}},
+		"invalid schema":     {{Name: "ci_job", Description: "x", InputSchema: json.RawMessage(`[1]`)}},
+		"timeout above max":  {{Name: "ci_job", Description: "x", TimeoutMS: maxExternalToolTimeout.Milliseconds() + 1}},
+		"negative timeout":   {{Name: "ci_job", Description: "x", TimeoutMS: -1}},
+		"too many externals": make([]ExternalToolDef, maxExternalTools+1),
+	} {
+		if err := validateExternalTools(defs); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+type openAIExternalToolMock struct {
+	mu sync.Mutex
+
+	step       int
+	finalToken string
+	toolInputs []string
+}
+
+func (m *openAIExternalToolMock) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	var req map[string]any
+	_ = json.Unmarshal(body, &req)
+	if isIntentClassifierRequest(req) {
+		writeOpenAIResponsesSSE(w, r, "gpt-5-mini", "resp_classifier", classifyIntentResponseToken(req))
+		return
+	}
+	m.mu.Lock()
+	m.step++
+	step := m.step
+	m.toolInputs = append(m.toolInputs, string(body))
+	m.mu.Unlock()
+	if step > 1 {
+		writeOpenAIResponsesSSE(w, r, "gpt-5-mini", fmt.Sprintf("resp_ext_%d", step), m.finalToken)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	f := w.(http.Flusher)
+	writeOpenAISSEJSON(w, f, map[string]any{
+		"type": "response.completed",
+		"response": map[string]any{
+			"id":     "resp_ext_1",
+			"model":  "gpt-5-mini",
+			"status": "completed",
+			"output": []any{
+				map[string]any{
+					"type":      "function_call",
+					"id":        "fc_ext_1",
+					"call_id":   "call_ext_1",
+					"name":      "ci_job",
+					"arguments": `{"branch":"main"}`,
+				},
+			},
+			"usage": map[string]any{"input_tokens": 1, "output_tokens": 1},
+		},
+	})
+	_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	f.Flush()
+}
+
+func (m *openAIExternalToolMock) lastInput() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.toolInputs) == 0 {
+		return ""
+	}
+	return m.toolInputs[len(m.toolInputs)-1]
+}
+
+func newExternalToolTestService(t *testing.T, mock *openAIExternalToolMock) *Service {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(mock.handle))
+	t.Cleanup(srv.Close)
+	svc, err := NewService(Options{
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo})),
+		StateDir:     t.TempDir(),
+		AgentHomeDir: t.TempDir(),
+		Shell:        "bash",
+		Config: &config.AIConfig{
+			Providers: []config.AIProvider{{
+				ID:      "openai",
+				Name:    "OpenAI",
+				Type:    "openai",
+				BaseURL: strings.TrimSuffix(srv.URL, "/") + "/v1",
+				Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+			}},
+		},
+		RunMaxWallTime:      30 * time.Second,
+		RunIdleTimeout:      10 * time.Second,
+		ToolApprovalTimeout: 5 * time.Second,
+		ResolveProviderAPIKey: func(string) (string, bool, error) {
+			return "sk-test", true, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+	return svc
+}
+
+func externalToolTestMeta(channelID string) session.Meta {
+	return session.Meta{
+		EndpointID:        "env_test",
+		NamespacePublicID: "ns_test",
+		ChannelID:         channelID,
+		UserPublicID:      "u_test",
+		UserEmail:         "u_test@example.com",
+		CanRead:           true,
+		CanWrite:          true,
+		CanExecute:        true,
+		CanAdmin:          true,
+	}
+}
+
+func runEventPayload(t *testing.T, svc *Service, endpointID string, runID string, eventType string) map[string]any {
+	t.Helper()
+	events, err := svc.threadsDB.ListRunEvents(context.Background(), endpointID, runID, 2000)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	for _, ev := range events {
+		if ev.EventType != eventType {
+			continue
+		}
+		var payload map[string]any
+		if err := json.Unmarshal([]byte(ev.PayloadJSON), &payload); err != nil {
+			t.Fatalf("decode %s payload: %v", eventType, err)
+		}
+		return payload
+	}
+	return nil
+}
+
+func TestIntegration_ExternalTool_ProvideToolResultResumesRun(t *testing.T) {
+	t.Parallel()
+
+	mock := &openAIExternalToolMock{finalToken: "EXTERNAL_TOOL_OK"}
+	svc := newExternalToolTestService(t, mock)
+	meta := externalToolTestMeta("ch_test_external_tool")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	th, err := svc.CreateThread(ctx, &meta, "hello", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	runID := "run_test_external_tool_1"
+	provided := make(chan error, 1)
+	go func() {
+		for ctx.Err() == nil {
+			if payload := runEventPayload(t, svc, meta.EndpointID, runID, "tool.external.waiting"); payload != nil {
+				provided <- svc.ProvideToolResult(&meta, runID, fmt.Sprint(payload["tool_id"]), ExternalToolResult{Data: map[string]any{"pipeline": "passed", "job_id": 42}})
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		provided <- ctx.Err()
+	}()
+
+	rr := httptest.NewRecorder()
+	if err := svc.StartRun(ctx, &meta, runID, RunStartRequest{
+		ThreadID: th.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "Run CI on main"},
+		Options: RunOptions{
+			MaxSteps:        4,
+			MaxNoToolRounds: 1,
+			ExternalTools:   []ExternalToolDef{{Name: "ci_job", Description: "Run the CI pipeline for a branch."}},
+		},
+	}, rr); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	if err := <-provided; err != nil {
+		t.Fatalf("ProvideToolResult: %v", err)
+	}
+	if !strings.Contains(rr.Body.String(), mock.finalToken) {
+		t.Fatalf("NDJSON stream missing %q", mock.finalToken)
+	}
+	if input := mock.lastInput(); !strings.Contains(input, "passed") || !strings.Contains(input, "call_ext_1") {
+		t.Fatalf("provider did not receive the external result: %s", input)
+	}
+	resolved := runEventPayload(t, svc, meta.EndpointID, runID, "tool.external.resolved")
+	if resolved == nil || resolved["outcome"] != "provided" || resolved["status"] != toolResultStatusSuccess {
+		t.Fatalf("tool.external.resolved=%v", resolved)
+	}
+	if err := svc.ProvideToolResult(&meta, runID, "call_ext_1", ExternalToolResult{}); err == nil {
+		t.Fatalf("ProvideToolResult after the run ended should fail")
+	}
+}
+
+func TestIntegration_ExternalTool_TimeoutAborts(t *testing.T) {
+	t.Parallel()
+
+	mock := &openAIExternalToolMock{finalToken: "EXTERNAL_TOOL_TIMEOUT_OK"}
+	svc := newExternalToolTestService(t, mock)
+	meta := externalToolTestMeta("ch_test_external_tool_timeout")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	th, err := svc.CreateThread(ctx, &meta, "hello", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	runID := "run_test_external_tool_timeout_1"
+	if err := svc.StartRun(ctx, &meta, runID, RunStartRequest{
+		ThreadID: th.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "Run CI on main"},
+		Options: RunOptions{
+			MaxSteps:        4,
+			MaxNoToolRounds: 1,
+			ExternalTools:   []ExternalToolDef{{Name: "ci_job", Description: "Run the CI pipeline for a branch.", TimeoutMS: 50}},
+		},
+	}, httptest.NewRecorder()); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	resolved := runEventPayload(t, svc, meta.EndpointID, runID, "tool.external.resolved")
+	if resolved == nil || resolved["outcome"] != "timeout" || resolved["status"] != toolResultStatusAborted {
+		t.Fatalf("tool.external.resolved=%v", resolved)
+	}
+	if input := mock.lastInput(); !strings.Contains(input, externalToolTimeoutSummary) {
+		t.Fatalf("provider did not receive %s: %s", externalToolTimeoutSummary, input)
+	}
+}
+
+func TestExternalToolWaitTimeout_CappedByRunWallTime(t *testing.T) {
+	t.Parallel()
+
+	r := &run{maxWallTime: 15 * time.Minute}
+	reg := NewInMemoryToolRegistry()
+	if err := registerExternalTools(reg, r, []ExternalToolDef{
+		{Name: "default_wait", Description: "Uses the default timeout."},
+		{Name: "long_wait", Description: "Asks for more than the run wall time.", TimeoutMS: (2 * time.Hour).Milliseconds()},
+		{Name: "short_wait", Description: "Fits inside the run wall time.", TimeoutMS: (5 * time.Minute).Milliseconds()},
+	}); err != nil {
+		t.Fatalf("registerExternalTools: %v", err)
+	}
+	for name, want := range map[string]time.Duration{
+		"default_wait": 15 * time.Minute,
+		"long_wait":    15 * time.Minute,
+		"short_wait":   5 * time.Minute,
+	} {
+		_, handler, ok := reg.resolve(name)
+		if !ok {
+			t.Fatalf("%s not registered", name)
+		}
+		if got := handler.(*externalToolHandler).waitTimeout(); got != want {
+			t.Fatalf("%s wait=%s, want %s", name, got, want)
+		}
+	}
+
+	// A per-run wall time cuts the wait short so the loop can still summarize.
+	r.wallDeadline = time.Now().Add(time.Minute)
+	_, handler, _ := reg.resolve("short_wait")
+	if got := handler.(*externalToolHandler).waitTimeout(); got <= 0 || got > time.Minute {
+		t.Fatalf("wait with per-run wall time=%s, want within (0, 1m]", got)
+	}
+	r.wallDeadline = time.Now().Add(-time.Second)
+	if got := handler.(*externalToolHandler).waitTimeout(); got != 0 {
+		t.Fatalf("wait past the per-run wall time=%s, want 0", got)
+	}
+}
+
+func TestIntegration_ExternalTool_PerRunWallTimeEndsWait(t *testing.T) {
+	t.Parallel()
+
+	mock := &openAIExternalToolMock{finalToken: "EXTERNAL_TOOL_WALL_OK"}
+	svc := newExternalToolTestService(t, mock)
+	meta := externalToolTestMeta("ch_test_external_tool_wall")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	th, err := svc.CreateThread(ctx, &meta, "hello", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	runID := "run_test_external_tool_wall_1"
+	if err := svc.StartRun(ctx, &meta, runID, RunStartRequest{
+		ThreadID: th.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "Run CI on main"},
+		Options: RunOptions{
+			MaxSteps:        4,
+			MaxNoToolRounds: 1,
+			MaxWallTimeMs:   300,
+			ExternalTools:   []ExternalToolDef{{Name: "ci_job", Description: "Run the CI pipeline for a branch.", TimeoutMS: (2 * time.Hour).Milliseconds()}},
+		},
+	}, httptest.NewRecorder()); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	waiting := runEventPayload(t, svc, meta.EndpointID, runID, "tool.external.waiting")
+	if waiting == nil {
+		t.Fatalf("missing tool.external.waiting")
+	}
+	if timeoutMS, _ := waiting["timeout_ms"].(float64); timeoutMS > 300 {
+		t.Fatalf("waiting timeout_ms=%v, want <= 300", waiting["timeout_ms"])
+	}
+	resolved := runEventPayload(t, svc, meta.EndpointID, runID, "tool.external.resolved")
+	if resolved == nil || resolved["outcome"] != "timeout" || resolved["status"] != toolResultStatusAborted {
+		t.Fatalf("tool.external.resolved=%v", resolved)
+	}
+}
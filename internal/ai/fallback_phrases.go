@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Languages of the built-in fallback phrase catalog.
+const (
+	FallbackPhraseLanguageEnglish = "en"
+	FallbackPhraseLanguageChinese = "zh"
+)
+
+// FallbackPhraseCatalog maps a language code to phrases that mark a final assistant message as a fallback
+// (loop-limit notices, empty-response placeholders) rather than a real answer.
+type FallbackPhraseCatalog map[string][]string
+
+var defaultFallbackPhrases = FallbackPhraseCatalog{
+	FallbackPhraseLanguageEnglish: {
+		"i have reached the current automatic loop limit",
+		"reply with one concrete next step",
+		"assistant finished without a visible response",
+		"tool workflow failed",
+		"no response",
+	},
+	FallbackPhraseLanguageChinese: {
+		"已达到当前自动循环上限",
+		"自动循环次数上限",
+		"请回复一个具体的下一步",
+		"请告诉我一个具体的下一步",
+		"助手未给出可见回复",
+		"没有可见的回复",
+		"工具流程执行失败",
+		"工具调用流程失败",
+		"没有回复",
+		"无响应",
+	},
+}
+
+// DefaultFallbackPhraseCatalog returns a copy of the built-in English and Chinese fallback phrases.
+func DefaultFallbackPhraseCatalog() FallbackPhraseCatalog {
+	return FallbackPhraseCatalog{}.Merge(defaultFallbackPhrases)
+}
+
+// Merge returns a new catalog with extra appended per language. Phrases are trimmed and lowercased; empty
+// and duplicate phrases are dropped.
+func (c FallbackPhraseCatalog) Merge(extra map[string][]string) FallbackPhraseCatalog {
+	out := make(FallbackPhraseCatalog, len(c)+len(extra))
+	add := func(language string, phrases []string) {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if language == "" {
+			return
+		}
+		for _, phrase := range phrases {
+			phrase = strings.ToLower(strings.TrimSpace(phrase))
+			if phrase == "" || slices.Contains(out[language], phrase) {
+				continue
+			}
+			out[language] = append(out[language], phrase)
+		}
+	}
+	for language, phrases := range c {
+		add(language, phrases)
+	}
+	for language, phrases := range extra {
+		add(language, phrases)
+	}
+	return out
+}
+
+// Match reports the first fallback phrase found in text. The catalog of the text's dominant language is
+// checked first, then every other language in code order, so mixed-language text is still caught.
+func (c FallbackPhraseCatalog) Match(text string) (phrase string, language string, ok bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" || len(c) == 0 {
+		return "", "", false
+	}
+	dominant := DetectTextLanguage(text)
+	languages := make([]string, 0, len(c))
+	for lang := range c {
+		if lang != dominant {
+			languages = append(languages, lang)
+		}
+	}
+	sort.Strings(languages)
+	if _, has := c[dominant]; has {
+		languages = append([]string{dominant}, languages...)
+	}
+	for _, lang := range languages {
+		for _, candidate := range c[lang] {
+			if candidate != "" && strings.Contains(text, candidate) {
+				return candidate, lang, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// DetectTextLanguage returns the dominant language of text: zh when Han characters make up at least a
+// third of its letters, en otherwise.
+func DetectTextLanguage(text string) string {
+	letters := 0
+	han := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Han, r) {
+			han++
+		}
+	}
+	if han > 0 && han*3 >= letters {
+		return FallbackPhraseLanguageChinese
+	}
+	return FallbackPhraseLanguageEnglish
+}
+
+// fallbackPhraseCatalog returns the built-in catalog extended with ai.fallback_phrases.
+func (r *run) fallbackPhraseCatalog() FallbackPhraseCatalog {
+	if r == nil || r.cfg == nil {
+		return DefaultFallbackPhraseCatalog()
+	}
+	return DefaultFallbackPhraseCatalog().Merge(r.cfg.FallbackPhrases)
+}
@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestFallbackPhraseCatalog_MatchesChineseAndEnglish(t *testing.T) {
+	t.Parallel()
+
+	catalog := DefaultFallbackPhraseCatalog()
+	cases := []struct {
+		text     string
+		phrase   string
+		language string
+	}{
+		{text: "I have reached the current automatic loop limit. Reply with one concrete next step.", phrase: "i have reached the current automatic loop limit", language: FallbackPhraseLanguageEnglish},
+		{text: "我已经读取了日志，但已达到当前自动循环上限。", phrase: "已达到当前自动循环上限", language: FallbackPhraseLanguageChinese},
+		{text: "工具调用流程失败，请稍后重试。", phrase: "工具调用流程失败", language: FallbackPhraseLanguageChinese},
+		// English fallback text inside a mostly Chinese reply is still caught.
+		{text: "运行结束：Tool workflow failed", phrase: "tool workflow failed", language: FallbackPhraseLanguageEnglish},
+	}
+	for _, tc := range cases {
+		phrase, language, ok := catalog.Match(tc.text)
+		if !ok || phrase != tc.phrase || language != tc.language {
+			t.Fatalf("Match(%q)=%q,%q,%v want %q,%q", tc.text, phrase, language, ok, tc.phrase, tc.language)
+		}
+	}
+	if phrase, _, ok := catalog.Match("结论：修复了配置加载器中的空 map 问题，测试全部通过。"); ok {
+		t.Fatalf("clean Chinese answer matched %q", phrase)
+	}
+
+	if got := DetectTextLanguage("修复了 config loader 的问题"); got != FallbackPhraseLanguageChinese {
+		t.Fatalf("DetectTextLanguage(mixed)=%q, want zh", got)
+	}
+	if got := DetectTextLanguage("Fixed the loader (见 issue 12)"); got != FallbackPhraseLanguageEnglish {
+		t.Fatalf("DetectTextLanguage(english)=%q, want en", got)
+	}
+}
+
+func TestRunFallbackPhraseCatalog_IncludesConfiguredPhrases(t *testing.T) {
+	t.Parallel()
+
+	r := &run{cfg: &config.AIConfig{FallbackPhrases: map[string][]string{
+		FallbackPhraseLanguageChinese: {" 任务已暂停 "},
+	}}}
+	phrase, language, ok := r.fallbackPhraseCatalog().Match("任务已暂停，等待进一步指示。")
+	if !ok || phrase != "任务已暂停" || language != FallbackPhraseLanguageChinese {
+		t.Fatalf("Match=%q,%q,%v", phrase, language, ok)
+	}
+	if _, _, ok := (&run{}).fallbackPhraseCatalog().Match("任务已暂停"); ok {
+		t.Fatalf("configured phrase leaked into the default catalog")
+	}
+}
@@ -17,7 +17,7 @@ func noUserInteractionFallbackReasonCode(source string) string {
 	switch source {
 	case "missing_explicit_completion", "provider_empty_output", "provider_empty_output_repeated":
 		return subagentFailureReasonMissingRequiredContext
-	case "tool_mistake_loop", "guard_doom_loop":
+	case "tool_mistake_loop", "guard_doom_loop", "guard_repeated_result":
 		return subagentFailureReasonRepeatedToolFailures
 	case "hard_max_summary_failed", "hard_max_steps":
 		return subagentFailureReasonHardMaxSteps
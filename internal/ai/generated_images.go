@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+)
+
+// maxGeneratedImageBytes caps one decoded model-generated image stored as an upload.
+const maxGeneratedImageBytes = 20 << 20
+
+func decodeImageDataURL(dataURL string) ([]byte, string, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(dataURL), "data:")
+	if !ok {
+		return nil, "", errors.New("invalid image data url")
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return nil, "", errors.New("invalid image data url")
+	}
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(raw) == 0 {
+		return nil, "", errors.New("empty image")
+	}
+	return raw, strings.TrimSpace(strings.TrimSuffix(meta, ";base64")), nil
+}
+
+func generatedImageFileName(mimeType string) string {
+	switch strings.ToLower(strings.TrimSpace(mimeType)) {
+	case "image/jpeg":
+		return "generated-image.jpg"
+	case "image/webp":
+		return "generated-image.webp"
+	case "image/gif":
+		return "generated-image.gif"
+	default:
+		return "generated-image.png"
+	}
+}
+
+// storeGeneratedImage saves a model-generated image as an upload claimed by the assistant message and
+// returns its upload URL. Images that already carry a URL are referenced as-is.
+func (r *run) storeGeneratedImage(ctx context.Context, img GeneratedImage) (string, error) {
+	if url := strings.TrimSpace(img.URL); url != "" {
+		return url, nil
+	}
+	raw, mimeType, err := decodeImageDataURL(img.DataURL)
+	if err != nil {
+		return "", err
+	}
+	if mt := strings.TrimSpace(img.MimeType); mt != "" {
+		mimeType = mt
+	}
+	if r.threadsDB == nil || strings.TrimSpace(r.threadID) == "" || strings.TrimSpace(r.messageID) == "" {
+		return "", errors.New("uploads not ready")
+	}
+	up, err := storeUpload(ctx, strings.TrimSpace(r.uploadsDir), r.threadsDB, r.persistTimeout(), r.endpointID, bytes.NewReader(raw), generatedImageFileName(mimeType), mimeType, maxGeneratedImageBytes)
+	if err != nil {
+		return "", err
+	}
+	// Claim the upload right away so staged-upload cleanup never removes an image the message references.
+	pctx, cancel := context.WithTimeout(ctxOrBackground(ctx), r.persistTimeout())
+	defer cancel()
+	if err := r.threadsDB.BindUploadsToRef(pctx, r.endpointID, r.threadID, threadstore.UploadRefKindMessage, r.messageID, []string{parseUploadIDFromURL(up.URL)}, 0); err != nil {
+		return "", err
+	}
+	return up.URL, nil
+}
+
+// appendGeneratedImage stores img and appends it to the assistant message as an image block.
+func (r *run) appendGeneratedImage(ctx context.Context, img GeneratedImage) {
+	if r == nil {
+		return
+	}
+	url, err := r.storeGeneratedImage(ctx, img)
+	if err != nil {
+		r.persistRunEvent("assistant.image.failed", RealtimeStreamKindLifecycle, map[string]any{
+			"image_id": strings.TrimSpace(img.ID),
+			"error":    sanitizeLogText(err.Error(), 240),
+		})
+		return
+	}
+	r.ensureAssistantMessageStarted()
+
+	r.mu.Lock()
+	idx := r.nextBlockIndex
+	r.nextBlockIndex++
+	r.needNewTextBlock = true
+	r.needNewThinkingBlock = true
+	r.generatedImageURLs = append(r.generatedImageURLs, url)
+	r.mu.Unlock()
+
+	block := persistedImageBlock{Type: "image", Src: url, Alt: "Generated image"}
+	r.muAssistant.Lock()
+	r.persistEnsureIndex(idx)
+	r.assistantBlocks[idx] = block
+	r.muAssistant.Unlock()
+	r.sendStreamEvent(streamEventBlockSet{Type: "block-set", MessageID: r.messageID, BlockIndex: idx, Block: block})
+	r.persistRunEvent("assistant.image", RealtimeStreamKindLifecycle, map[string]any{
+		"image_id":  strings.TrimSpace(img.ID),
+		"url":       url,
+		"mime_type": strings.TrimSpace(img.MimeType),
+	})
+}
+
+// generatedImageRefs returns the upload URLs of the images generated by this run, in order.
+func (r *run) generatedImageRefs() []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.generatedImageURLs...)
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	oresponses "github.com/openai/openai-go/responses"
+)
+
+// testPNG is the 8-byte PNG signature followed by a minimal IHDR chunk header; enough for content sniffing.
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+func TestExtractOpenAIResponseImages(t *testing.T) {
+	t.Parallel()
+
+	encoded := base64.StdEncoding.EncodeToString(testPNG)
+	raw := `{"id":"resp_1","output":[
+		{"type":"message","id":"msg_1","role":"assistant","content":[{"type":"output_text","text":"Here is the logo."}]},
+		{"type":"image_generation_call","id":"ig_1","status":"completed","result":"` + encoded + `"},
+		{"type":"image_generation_call","id":"ig_2","status":"failed","result":""},
+		{"type":"image_generation_call","id":"ig_3","status":"completed","result":"not base64!"}
+	]}`
+	var resp oresponses.Response
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+
+	images := extractOpenAIResponseImages(resp)
+	if len(images) != 1 {
+		t.Fatalf("images=%+v, want 1", images)
+	}
+	if images[0].ID != "ig_1" || images[0].MimeType != "image/png" || images[0].DataURL != "data:image/png;base64,"+encoded {
+		t.Fatalf("image=%+v", images[0])
+	}
+	if got := extractOpenAIResponseText(resp); got != "Here is the logo." {
+		t.Fatalf("text=%q", got)
+	}
+}
+
+func TestRunAppendGeneratedImage_StoresClaimedUpload(t *testing.T) {
+	t.Parallel()
+
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	r := newRun(runOptions{
+		RunID:            "run_image",
+		EndpointID:       "env_image",
+		ThreadID:         "th_image",
+		MessageID:        "msg_image",
+		UploadsDir:       t.TempDir(),
+		ThreadsDB:        db,
+		PersistOpTimeout: 2 * time.Second,
+	})
+
+	r.appendGeneratedImage(context.Background(), GeneratedImage{
+		ID:       "ig_1",
+		MimeType: "image/png",
+		DataURL:  "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG),
+	})
+
+	refs := r.generatedImageRefs()
+	if len(refs) != 1 || !strings.HasPrefix(refs[0], uploadURLPrefix) {
+		t.Fatalf("generated image refs=%v", refs)
+	}
+	rec, err := db.GetUpload(context.Background(), "env_image", parseUploadIDFromURL(refs[0]))
+	if err != nil {
+		t.Fatalf("GetUpload: %v", err)
+	}
+	if rec.State != threadstore.UploadStateLive || rec.MimeType != "image/png" || rec.SizeBytes != int64(len(testPNG)) {
+		t.Fatalf("upload record=%+v", rec)
+	}
+
+	r.muAssistant.Lock()
+	blocks := append([]any(nil), r.assistantBlocks...)
+	r.muAssistant.Unlock()
+	var found bool
+	for _, blk := range blocks {
+		if img, ok := blk.(persistedImageBlock); ok && img.Src == refs[0] {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("assistant blocks=%+v, want image block for %s", blocks, refs[0])
+	}
+
+	// Images without a decodable payload are reported instead of breaking the run.
+	r.appendGeneratedImage(context.Background(), GeneratedImage{ID: "ig_bad", DataURL: "data:image/png;base64,%%%"})
+	if got := r.generatedImageRefs(); len(got) != 1 {
+		t.Fatalf("invalid image was recorded: %v", got)
+	}
+}
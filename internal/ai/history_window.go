@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	contextmodel "github.com/floegence/redeven/internal/ai/context/model"
+)
+
+// historySummaryMaxRequests caps how many dropped user requests the trimmed-history summary lists.
+const historySummaryMaxRequests = 5
+
+// runHistoryTrim reports what applyRunHistoryWindow removed from a run's initial history.
+type runHistoryTrim struct {
+	Source          string // "history" or "prompt_pack"
+	MaxMessages     int
+	MaxTokens       int
+	KeptMessages    int
+	DroppedMessages int
+	DroppedTokens   int
+	Summarized      bool
+}
+
+// applyRunHistoryWindow trims the history a run starts with to RunOptions.MaxHistoryMessages and
+// MaxHistoryTokens, keeping the most recent messages.
+//
+// Runs with a context pack trim pack.RecentDialogue by whole turns; the others trim req.History.
+// With SummarizeTrimmedHistory, a short deterministic note about the dropped part takes its place.
+func applyRunHistoryWindow(req RunRequest) (RunRequest, runHistoryTrim) {
+	maxMessages := req.Options.MaxHistoryMessages
+	maxTokens := req.Options.MaxHistoryTokens
+	if maxMessages <= 0 && maxTokens <= 0 {
+		return req, runHistoryTrim{}
+	}
+	var trim runHistoryTrim
+	if strings.TrimSpace(req.ContextPack.ThreadID) != "" {
+		req, trim = trimPromptPackDialogue(req, maxMessages, maxTokens)
+	} else {
+		req, trim = trimRunHistory(req, maxMessages, maxTokens)
+	}
+	trim.MaxMessages = max(maxMessages, 0)
+	trim.MaxTokens = max(maxTokens, 0)
+	return req, trim
+}
+
+func trimRunHistory(req RunRequest, maxMessages int, maxTokens int) (RunRequest, runHistoryTrim) {
+	// Only messages buildInitialMessages would send count against the window.
+	history := make([]RunHistoryMsg, 0, len(req.History))
+	for _, msg := range req.History {
+		role := strings.ToLower(strings.TrimSpace(msg.Role))
+		if (role == "assistant" || role == "user") && strings.TrimSpace(msg.Text) != "" {
+			history = append(history, msg)
+		}
+	}
+	start := len(history)
+	tokens := 0
+	for start > 0 {
+		t := estimateTextTokens(history[start-1].Text)
+		if (maxMessages > 0 && len(history)-start >= maxMessages) || (maxTokens > 0 && tokens+t > maxTokens) {
+			break
+		}
+		tokens += t
+		start--
+	}
+	// Never open the window on an assistant reply whose request was dropped.
+	for start > 0 && start < len(history) && strings.EqualFold(strings.TrimSpace(history[start].Role), "assistant") {
+		start++
+	}
+	if start == 0 {
+		return req, runHistoryTrim{Source: "history", KeptMessages: len(history)}
+	}
+
+	trim := runHistoryTrim{Source: "history", KeptMessages: len(history) - start, DroppedMessages: start}
+	var droppedRequests []string
+	for _, msg := range history[:start] {
+		trim.DroppedTokens += estimateTextTokens(msg.Text)
+		if strings.EqualFold(strings.TrimSpace(msg.Role), "user") {
+			droppedRequests = append(droppedRequests, msg.Text)
+		}
+	}
+	kept := make([]RunHistoryMsg, 0, len(history)-start+1)
+	if req.Options.SummarizeTrimmedHistory {
+		kept = append(kept, RunHistoryMsg{Role: "user", Text: trimmedHistorySummary(trim.DroppedMessages, droppedRequests)})
+		trim.Summarized = true
+	}
+	req.History = append(kept, history[start:]...)
+	return req, trim
+}
+
+func trimPromptPackDialogue(req RunRequest, maxMessages int, maxTokens int) (RunRequest, runHistoryTrim) {
+	turns := req.ContextPack.RecentDialogue
+	start := len(turns)
+	messages, tokens := 0, 0
+	for start > 0 {
+		n, t := dialogueTurnSize(turns[start-1])
+		if (maxMessages > 0 && messages+n > maxMessages) || (maxTokens > 0 && tokens+t > maxTokens) {
+			break
+		}
+		messages += n
+		tokens += t
+		start--
+	}
+	if start == 0 {
+		return req, runHistoryTrim{Source: "prompt_pack", KeptMessages: messages}
+	}
+
+	trim := runHistoryTrim{Source: "prompt_pack", KeptMessages: messages}
+	var droppedRequests []string
+	for _, turn := range turns[:start] {
+		n, t := dialogueTurnSize(turn)
+		trim.DroppedMessages += n
+		trim.DroppedTokens += t
+		if txt := strings.TrimSpace(turn.UserText); txt != "" {
+			droppedRequests = append(droppedRequests, txt)
+		}
+	}
+	pack := req.ContextPack
+	pack.RecentDialogue = append([]contextmodel.DialogueTurn(nil), turns[start:]...)
+	if req.Options.SummarizeTrimmedHistory && trim.DroppedMessages > 0 {
+		summary := trimmedHistorySummary(trim.DroppedMessages, droppedRequests)
+		if snapshot := strings.TrimSpace(pack.ThreadSnapshot); snapshot != "" {
+			summary = snapshot + "\n" + summary
+		}
+		pack.ThreadSnapshot = summary
+		trim.Summarized = true
+	}
+	req.ContextPack = pack
+	return req, trim
+}
+
+func dialogueTurnSize(turn contextmodel.DialogueTurn) (messages int, tokens int) {
+	for _, txt := range []string{turn.UserText, turn.AssistantText} {
+		if strings.TrimSpace(txt) == "" {
+			continue
+		}
+		messages++
+		tokens += estimateTextTokens(txt)
+	}
+	return messages, tokens
+}
+
+// trimmedHistorySummary stands in for dropped history: how much was dropped and the earliest user requests.
+func trimmedHistorySummary(dropped int, requests []string) string {
+	lines := []string{fmt.Sprintf("Earlier conversation trimmed from this run: %d messages omitted.", dropped)}
+	if len(requests) > 0 {
+		lines = append(lines, "Earlier user requests:")
+		for i, req := range requests {
+			if i == historySummaryMaxRequests {
+				lines = append(lines, fmt.Sprintf("- ... and %d more", len(requests)-i))
+				break
+			}
+			lines = append(lines, "- "+truncateRunes(strings.Join(strings.Fields(req), " "), 160))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r *run) persistHistoryTrim(trim runHistoryTrim) {
+	if r == nil || trim.DroppedMessages <= 0 {
+		return
+	}
+	r.persistRunEvent("history.trimmed", RealtimeStreamKindLifecycle, map[string]any{
+		"source":               trim.Source,
+		"kept_messages":        trim.KeptMessages,
+		"dropped_messages":     trim.DroppedMessages,
+		"dropped_tokens":       trim.DroppedTokens,
+		"summarized":           trim.Summarized,
+		"max_history_messages": trim.MaxMessages,
+		"max_history_tokens":   trim.MaxTokens,
+	})
+}
@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	contextmodel "github.com/floegence/redeven/internal/ai/context/model"
+)
+
+func TestApplyRunHistoryWindow_History(t *testing.T) {
+	t.Parallel()
+
+	history := []RunHistoryMsg{
+		{Role: "user", Text: "first request"},
+		{Role: "assistant", Text: "first answer"},
+		{Role: "system", Text: "ignored"},
+		{Role: "user", Text: "second request"},
+		{Role: "assistant", Text: "second answer"},
+		{Role: "user", Text: "third request"},
+		{Role: "assistant", Text: "third answer"},
+	}
+
+	unchanged, trim := applyRunHistoryWindow(RunRequest{History: history})
+	if trim.DroppedMessages != 0 || len(unchanged.History) != len(history) {
+		t.Fatalf("no window: trim=%+v history=%d", trim, len(unchanged.History))
+	}
+
+	// A window of 3 would open on "second answer", so that orphaned reply is dropped too.
+	got, trim := applyRunHistoryWindow(RunRequest{History: history, Options: RunOptions{MaxHistoryMessages: 3}})
+	if trim.DroppedMessages != 4 || trim.KeptMessages != 2 || trim.Summarized || trim.MaxMessages != 3 {
+		t.Fatalf("trim=%+v", trim)
+	}
+	if len(got.History) != 2 || got.History[0].Text != "third request" {
+		t.Fatalf("history=%+v", got.History)
+	}
+
+	got, trim = applyRunHistoryWindow(RunRequest{
+		History: history,
+		Input:   RunInput{Text: "fourth request"},
+		Options: RunOptions{MaxHistoryTokens: estimateTextTokens("third request") + estimateTextTokens("third answer"), SummarizeTrimmedHistory: true},
+	})
+	if trim.DroppedMessages != 4 || !trim.Summarized || trim.Source != "history" {
+		t.Fatalf("trim=%+v", trim)
+	}
+	messages := buildMessagesForRun(got)
+	if len(messages) != 4 {
+		t.Fatalf("messages=%+v", messages)
+	}
+	summary := messages[0].Content[0].Text
+	if messages[0].Role != "user" || !strings.Contains(summary, "4 messages omitted") || !strings.Contains(summary, "- first request\n- second request") {
+		t.Fatalf("summary=%q", summary)
+	}
+	if messages[1].Content[0].Text != "third request" || messages[3].Content[0].Text != "fourth request" {
+		t.Fatalf("messages=%+v", messages)
+	}
+}
+
+func TestApplyRunHistoryWindow_PromptPackDialogue(t *testing.T) {
+	t.Parallel()
+
+	pack := contextmodel.PromptPack{
+		ThreadID:       "th_1",
+		ThreadSnapshot: "snapshot",
+		RecentDialogue: []contextmodel.DialogueTurn{
+			{UserText: "old request", AssistantText: "old answer"},
+			{UserText: "mid request", AssistantText: "mid answer"},
+			{UserText: "new request", AssistantText: "new answer"},
+		},
+	}
+	got, trim := applyRunHistoryWindow(RunRequest{ContextPack: pack, Options: RunOptions{MaxHistoryMessages: 3, SummarizeTrimmedHistory: true}})
+	if trim.Source != "prompt_pack" || trim.DroppedMessages != 4 || trim.KeptMessages != 2 || !trim.Summarized {
+		t.Fatalf("trim=%+v", trim)
+	}
+	if len(got.ContextPack.RecentDialogue) != 1 || got.ContextPack.RecentDialogue[0].UserText != "new request" {
+		t.Fatalf("dialogue=%+v", got.ContextPack.RecentDialogue)
+	}
+	if len(pack.RecentDialogue) != 3 {
+		t.Fatalf("input pack was modified")
+	}
+	if !strings.HasPrefix(got.ContextPack.ThreadSnapshot, "snapshot\nEarlier conversation trimmed from this run: 4 messages omitted.") || !strings.Contains(got.ContextPack.ThreadSnapshot, "- old request\n- mid request") {
+		t.Fatalf("snapshot=%q", got.ContextPack.ThreadSnapshot)
+	}
+
+	got, trim = applyRunHistoryWindow(RunRequest{ContextPack: pack, Options: RunOptions{MaxHistoryMessages: 6}})
+	if trim.DroppedMessages != 0 || len(got.ContextPack.RecentDialogue) != 3 || got.ContextPack.ThreadSnapshot != "snapshot" {
+		t.Fatalf("fits: trim=%+v pack=%+v", trim, got.ContextPack)
+	}
+}
@@ -16,6 +16,7 @@ const (
 
 	RunIntentSourceModel         = "model"
 	RunIntentSourceDeterministic = "deterministic_fallback"
+	RunIntentSourceForced        = "forced"
 	runPolicyClassifierMarker    = "RUN_POLICY_CLASSIFIER_V1"
 
 	RunObjectiveModeReplace  = "replace"
@@ -60,14 +61,45 @@ func normalizeRunMode(raw string, fallback string) string {
 	return config.AIModeAct
 }
 
+// parseForceIntent validates RunOptions.ForceIntent; an empty value leaves the intent to the classifier.
+func parseForceIntent(raw string) (string, error) {
+	v := strings.ToLower(strings.TrimSpace(raw))
+	switch v {
+	case "":
+		return "", nil
+	case RunIntentSocial, RunIntentCreative, RunIntentTask:
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid force_intent: %q (want social, creative, or task)", raw)
+	}
+}
+
 type modelRunPolicyClassifier func() (runPolicyDecision, error)
 
-func classifyRunPolicy(userInput string, attachments []RunAttachmentIn, openGoal string, structuredResponse bool, classifyByModel modelRunPolicyClassifier) runPolicyDecision {
-	structuredResponse = structuredResponse && strings.TrimSpace(openGoal) != ""
+// runPolicyInput is everything the run policy classification looks at besides the model classifier.
+type runPolicyInput struct {
+	Attachments []RunAttachmentIn
+	OpenGoal    string
+	// StructuredResponse reports that the input answers a waiting prompt; it only counts with an open goal.
+	StructuredResponse bool
+	// ForceIntent is RunOptions.ForceIntent; a valid value bypasses the attachment rule and the model classifier.
+	ForceIntent string
+}
+
+// classifyRunPolicy resolves the run policy of one user turn.
+//
+// It is pure apart from classifyByModel, which is only called when no deterministic rule applies. In order:
+// a structured response to an open goal continues it, a forced intent is taken as is, attachments make a
+// task, and otherwise the model decides, falling back to a task when it fails.
+func classifyRunPolicy(in runPolicyInput, classifyByModel modelRunPolicyClassifier) runPolicyDecision {
+	structuredResponse := in.StructuredResponse && strings.TrimSpace(in.OpenGoal) != ""
 	if structuredResponse {
 		return structuredResponseContinuationRunPolicyDecision()
 	}
-	if len(attachments) > 0 {
+	if intent, err := parseForceIntent(in.ForceIntent); err == nil && intent != "" {
+		return forcedRunPolicyDecision(intent)
+	}
+	if len(in.Attachments) > 0 {
 		return enforceStructuredResponseContinuation(runPolicyDecision{
 			Intent:            RunIntentTask,
 			ExecutionContract: RunExecutionContractHybridFirstTurn,
@@ -107,6 +139,30 @@ func classifyRunPolicy(userInput string, attachments []RunAttachmentIn, openGoal
 	}, structuredResponse)
 }
 
+// forcedRunPolicyDecision is the policy of a run whose caller pinned the intent.
+func forcedRunPolicyDecision(intent string) runPolicyDecision {
+	decision := runPolicyDecision{
+		Intent:            intent,
+		ExecutionContract: RunExecutionContractHybridFirstTurn,
+		Reason:            "forced_intent",
+		Source:            RunIntentSourceForced,
+		ObjectiveMode:     RunObjectiveModeReplace,
+		Complexity:        TaskComplexityStandard,
+		TodoPolicy:        TodoPolicyRecommended,
+		MinimumTodoItems:  0,
+		Confidence:        1,
+		InteractionContract: interactionContract{
+			Source: interactionContractSourceDeterministic,
+		},
+	}
+	if intent == RunIntentSocial || intent == RunIntentCreative {
+		decision.ExecutionContract = RunExecutionContractDirectReply
+		decision.Complexity = TaskComplexitySimple
+		decision.TodoPolicy = TodoPolicyNone
+	}
+	return decision
+}
+
 func structuredResponseContinuationRunPolicyDecision() runPolicyDecision {
 	return runPolicyDecision{
 		Intent:            RunIntentTask,
@@ -11,7 +11,7 @@ import (
 func TestClassifyRunPolicy_UsesModelDecision(t *testing.T) {
 	t.Parallel()
 
-	got := classifyRunPolicy("hello", nil, "", false, func() (runPolicyDecision, error) {
+	got := classifyRunPolicy(runPolicyInput{}, func() (runPolicyDecision, error) {
 		return runPolicyDecision{
 			Intent:           RunIntentSocial,
 			Reason:           "small_talk_detected_by_model",
@@ -43,7 +43,7 @@ func TestClassifyRunPolicy_UsesModelDecision(t *testing.T) {
 func TestClassifyRunPolicy_ModelFailureFallsBackToTask(t *testing.T) {
 	t.Parallel()
 
-	got := classifyRunPolicy("please analyze this repository architecture", nil, "", false, func() (runPolicyDecision, error) {
+	got := classifyRunPolicy(runPolicyInput{}, func() (runPolicyDecision, error) {
 		return runPolicyDecision{}, assertErr{}
 	})
 	if got.Intent != RunIntentTask {
@@ -66,7 +66,7 @@ func TestClassifyRunPolicy_ModelFailureFallsBackToTask(t *testing.T) {
 func TestClassifyRunPolicy_ModelControlsContinuationObjectiveMode(t *testing.T) {
 	t.Parallel()
 
-	got := classifyRunPolicy("continue", nil, "fix startup failure", false, func() (runPolicyDecision, error) {
+	got := classifyRunPolicy(runPolicyInput{OpenGoal: "fix startup failure"}, func() (runPolicyDecision, error) {
 		return runPolicyDecision{
 			Intent:           RunIntentTask,
 			Reason:           "follow_up_to_open_goal",
@@ -94,7 +94,7 @@ func TestClassifyRunPolicy_ModelControlsContinuationObjectiveMode(t *testing.T)
 func TestClassifyRunPolicy_TaskByAttachment(t *testing.T) {
 	t.Parallel()
 
-	got := classifyRunPolicy("take a look at this", []RunAttachmentIn{{URL: "file:///tmp/a.txt"}}, "", false, nil)
+	got := classifyRunPolicy(runPolicyInput{Attachments: []RunAttachmentIn{{URL: "file:///tmp/a.txt"}}}, nil)
 	if got.Intent != RunIntentTask {
 		t.Fatalf("intent=%q, want %q", got.Intent, RunIntentTask)
 	}
@@ -149,7 +149,7 @@ func TestBuildRunPolicyClassifierMessages_GuidedStructuredInteractionsAreTask(t
 func TestClassifyRunPolicy_StructuredResponseForcesContinuation(t *testing.T) {
 	t.Parallel()
 
-	got := classifyRunPolicy("Streaming apps", nil, "Run a guided music-preference questionnaire", true, func() (runPolicyDecision, error) {
+	got := classifyRunPolicy(runPolicyInput{OpenGoal: "Run a guided music-preference questionnaire", StructuredResponse: true}, func() (runPolicyDecision, error) {
 		return runPolicyDecision{
 			Intent:           RunIntentSocial,
 			Reason:           "small_talk_detected_by_model",
@@ -182,7 +182,7 @@ func TestClassifyRunPolicy_StructuredResponseContinuationSkipsModelClassifier(t
 	t.Parallel()
 
 	called := false
-	got := classifyRunPolicy("Streaming apps", nil, "Run a guided music-preference questionnaire", true, func() (runPolicyDecision, error) {
+	got := classifyRunPolicy(runPolicyInput{OpenGoal: "Run a guided music-preference questionnaire", StructuredResponse: true}, func() (runPolicyDecision, error) {
 		called = true
 		return runPolicyDecision{}, nil
 	})
@@ -318,3 +318,79 @@ func TestNormalizeRunMode(t *testing.T) {
 type assertErr struct{}
 
 func (assertErr) Error() string { return "assert error" }
+
+func TestClassifyRunPolicy_Table(t *testing.T) {
+	t.Parallel()
+
+	modelSays := func(intent string) modelRunPolicyClassifier {
+		return func() (runPolicyDecision, error) {
+			return runPolicyDecision{Intent: intent, Reason: "model_pick", Confidence: 0.9}, nil
+		}
+	}
+	attachments := []RunAttachmentIn{{URL: "file:///tmp/a.txt"}}
+	cases := []struct {
+		name         string
+		in           runPolicyInput
+		model        modelRunPolicyClassifier
+		wantIntent   string
+		wantSource   string
+		wantContract string
+		wantObjMode  string
+		wantModel    bool
+	}{
+		{name: "model social", model: modelSays(RunIntentSocial), wantIntent: RunIntentSocial, wantSource: RunIntentSourceModel, wantContract: RunExecutionContractDirectReply, wantObjMode: RunObjectiveModeReplace, wantModel: true},
+		{name: "model creative", model: modelSays(RunIntentCreative), wantIntent: RunIntentCreative, wantSource: RunIntentSourceModel, wantContract: RunExecutionContractDirectReply, wantObjMode: RunObjectiveModeReplace, wantModel: true},
+		{name: "model unknown intent is task", model: modelSays("banter"), wantIntent: RunIntentTask, wantSource: RunIntentSourceModel, wantContract: RunExecutionContractHybridFirstTurn, wantObjMode: RunObjectiveModeReplace, wantModel: true},
+		{name: "no classifier", wantIntent: RunIntentTask, wantSource: RunIntentSourceDeterministic, wantContract: RunExecutionContractHybridFirstTurn, wantObjMode: RunObjectiveModeReplace},
+		{name: "attachments skip model", in: runPolicyInput{Attachments: attachments}, model: modelSays(RunIntentSocial), wantIntent: RunIntentTask, wantSource: RunIntentSourceDeterministic, wantContract: RunExecutionContractHybridFirstTurn, wantObjMode: RunObjectiveModeReplace},
+		{name: "forced social", in: runPolicyInput{ForceIntent: "social"}, model: modelSays(RunIntentTask), wantIntent: RunIntentSocial, wantSource: RunIntentSourceForced, wantContract: RunExecutionContractDirectReply, wantObjMode: RunObjectiveModeReplace},
+		{name: "forced creative", in: runPolicyInput{ForceIntent: " Creative "}, model: modelSays(RunIntentTask), wantIntent: RunIntentCreative, wantSource: RunIntentSourceForced, wantContract: RunExecutionContractDirectReply, wantObjMode: RunObjectiveModeReplace},
+		{name: "forced task", in: runPolicyInput{ForceIntent: "task", OpenGoal: "old goal"}, model: modelSays(RunIntentSocial), wantIntent: RunIntentTask, wantSource: RunIntentSourceForced, wantContract: RunExecutionContractHybridFirstTurn, wantObjMode: RunObjectiveModeReplace},
+		{name: "forced beats attachments", in: runPolicyInput{ForceIntent: "creative", Attachments: attachments}, wantIntent: RunIntentCreative, wantSource: RunIntentSourceForced, wantContract: RunExecutionContractDirectReply, wantObjMode: RunObjectiveModeReplace},
+		{name: "invalid force falls through", in: runPolicyInput{ForceIntent: "banter"}, model: modelSays(RunIntentCreative), wantIntent: RunIntentCreative, wantSource: RunIntentSourceModel, wantContract: RunExecutionContractDirectReply, wantObjMode: RunObjectiveModeReplace, wantModel: true},
+		{name: "structured continuation beats force", in: runPolicyInput{ForceIntent: "social", OpenGoal: "questionnaire", StructuredResponse: true}, model: modelSays(RunIntentSocial), wantIntent: RunIntentTask, wantSource: RunIntentSourceDeterministic, wantContract: RunExecutionContractAgenticLoop, wantObjMode: RunObjectiveModeContinue},
+		{name: "structured response without open goal", in: runPolicyInput{ForceIntent: "social", StructuredResponse: true}, wantIntent: RunIntentSocial, wantSource: RunIntentSourceForced, wantContract: RunExecutionContractDirectReply, wantObjMode: RunObjectiveModeReplace},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			modelCalled := false
+			var classify modelRunPolicyClassifier
+			if tc.model != nil {
+				classify = func() (runPolicyDecision, error) {
+					modelCalled = true
+					return tc.model()
+				}
+			}
+			got := classifyRunPolicy(tc.in, classify)
+			if got.Intent != tc.wantIntent || got.Source != tc.wantSource || got.ExecutionContract != tc.wantContract || got.ObjectiveMode != tc.wantObjMode {
+				t.Fatalf("got intent=%q source=%q contract=%q objective_mode=%q", got.Intent, got.Source, got.ExecutionContract, got.ObjectiveMode)
+			}
+			if modelCalled != tc.wantModel {
+				t.Fatalf("model classifier called=%v, want %v", modelCalled, tc.wantModel)
+			}
+			if got.Intent != RunIntentTask && (got.TodoPolicy != TodoPolicyNone || got.Complexity != TaskComplexitySimple) {
+				t.Fatalf("non-task intent got todo_policy=%q complexity=%q", got.TodoPolicy, got.Complexity)
+			}
+		})
+	}
+}
+
+func TestParseForceIntent(t *testing.T) {
+	t.Parallel()
+
+	for raw, want := range map[string]string{"": "", " ": "", "social": RunIntentSocial, "CREATIVE": RunIntentCreative, " task ": RunIntentTask} {
+		got, err := parseForceIntent(raw)
+		if err != nil || got != want {
+			t.Fatalf("parseForceIntent(%q)=%q,%v want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parseForceIntent("chat"); err == nil || !strings.Contains(err.Error(), "force_intent") {
+		t.Fatalf("parseForceIntent(chat) err=%v", err)
+	}
+	if err := ValidateRunOptions(RunOptions{ForceIntent: "chat"}); err == nil {
+		t.Fatalf("ValidateRunOptions accepted an invalid force_intent")
+	}
+}
@@ -380,3 +380,122 @@ func TestIntentRouting_GuidedStructuredInteractionUsesTaskPath(t *testing.T) {
 		t.Fatalf("intent path=%q, want %q", got, RunExecutionContractAgenticLoop)
 	}
 }
+
+func TestIntentRouting_ForceIntentBypassesClassifier(t *testing.T) {
+	t.Parallel()
+
+	mock := &openAIMock{
+		token:           "FORCED_CREATIVE_OK",
+		classifierToken: `{"intent":"task","execution_contract":"agentic_loop","reason":"misclassified","objective_mode":"replace","complexity":"standard","todo_policy":"recommended","minimum_todo_items":0,"confidence":0.9}`,
+	}
+	svc, meta := newIntentRoutingService(t, mock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	thread, err := svc.CreateThread(ctx, &meta, "forced intent test", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	runID := "run_intent_forced_1"
+	rr := httptest.NewRecorder()
+	err = svc.StartRun(ctx, &meta, runID, RunStartRequest{
+		ThreadID: thread.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "write the release notes for v2"},
+		Options:  RunOptions{MaxSteps: 1, Mode: "plan", ForceIntent: "creative"},
+	}, rr)
+	if err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+
+	if !strings.Contains(rr.Body.String(), "FORCED_CREATIVE_OK") {
+		t.Fatalf("stream output missing creative reply token, body=%q", rr.Body.String())
+	}
+	if names := mock.toolNamesSnapshot(); len(names) != 0 {
+		t.Fatalf("forced creative path must not send tools, got=%v", names)
+	}
+
+	runEvents, err := svc.ListRunEvents(ctx, &meta, runID, 2000)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	classified := findRunEventPayload(t, runEvents.Events, "intent.classified")
+	if got := strings.TrimSpace(fmt.Sprint(classified["intent"])); got != RunIntentCreative {
+		t.Fatalf("intent=%q, want %q", got, RunIntentCreative)
+	}
+	if got := strings.TrimSpace(fmt.Sprint(classified["intent_source"])); got != RunIntentSourceForced {
+		t.Fatalf("intent_source=%q, want %q", got, RunIntentSourceForced)
+	}
+	if forced, _ := classified["intent_forced"].(bool); !forced {
+		t.Fatalf("intent_forced=%v, want true", classified["intent_forced"])
+	}
+
+	if err := svc.StartRun(ctx, &meta, "run_intent_forced_2", RunStartRequest{
+		ThreadID: thread.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "hello"},
+		Options:  RunOptions{ForceIntent: "chitchat"},
+	}, httptest.NewRecorder()); err == nil || !strings.Contains(err.Error(), "force_intent") {
+		t.Fatalf("StartRun with invalid force_intent err=%v", err)
+	}
+}
+
+func TestIntentRouting_RoutingRuleForcesIntentAndMode(t *testing.T) {
+	t.Parallel()
+
+	mock := &openAIMock{
+		token:           "ROUTED_CREATIVE_OK",
+		classifierToken: `{"intent":"task","execution_contract":"agentic_loop","reason":"misclassified","objective_mode":"replace","complexity":"standard","todo_policy":"recommended","minimum_todo_items":0,"confidence":0.9}`,
+	}
+	svc, meta := newIntentRoutingService(t, mock)
+	svc.cfg.RoutingRules = []config.AIRoutingRule{{Pattern: `^/draft\b`, Intent: "creative", Mode: "plan"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	thread, err := svc.CreateThread(ctx, &meta, "routing rule test", "", "act", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	runID := "run_intent_routing_rule_1"
+	rr := httptest.NewRecorder()
+	if err := svc.StartRun(ctx, &meta, runID, RunStartRequest{
+		ThreadID: thread.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "/draft the release notes for v2"},
+		Options:  RunOptions{MaxSteps: 1},
+	}, rr); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	if !strings.Contains(rr.Body.String(), "ROUTED_CREATIVE_OK") {
+		t.Fatalf("stream output missing creative reply token, body=%q", rr.Body.String())
+	}
+
+	runEvents, err := svc.ListRunEvents(ctx, &meta, runID, 2000)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	classified := findRunEventPayload(t, runEvents.Events, "intent.classified")
+	if got := strings.TrimSpace(fmt.Sprint(classified["intent"])); got != RunIntentCreative {
+		t.Fatalf("intent=%q, want %q", got, RunIntentCreative)
+	}
+	if got := strings.TrimSpace(fmt.Sprint(classified["mode"])); got != "plan" {
+		t.Fatalf("mode=%q, want plan", got)
+	}
+	start := findRunEventPayload(t, runEvents.Events, "native.runtime.start")
+	rule, _ := start["routing_rule"].(map[string]any)
+	if rule == nil || fmt.Sprint(rule["rule"]) != "routing_rules[0]" || fmt.Sprint(rule["trigger"]) != "/draft" || fmt.Sprint(rule["mode"]) != "plan" {
+		t.Fatalf("native.runtime.start routing_rule=%#v", start["routing_rule"])
+	}
+
+	view, err := svc.GetThread(ctx, &meta, thread.ThreadID)
+	if err != nil || view == nil {
+		t.Fatalf("GetThread: %v", err)
+	}
+	if view.ExecutionMode != "act" {
+		t.Fatalf("thread execution_mode=%q, want act to stay unchanged", view.ExecutionMode)
+	}
+}
@@ -0,0 +1,69 @@
+package ai
+
+import "strings"
+
+const (
+	LoopProfileDefault        = "default"
+	LoopProfileFastExitV1     = "fast_exit_v1"
+	LoopProfileDeepAnalysisV1 = "deep_analysis_v1"
+)
+
+// LoopProfile holds the native loop guard thresholds for one run.
+type LoopProfile struct {
+	ID string `json:"id"`
+	// MaxNoToolRounds is the number of no-tool backpressure rounds before forcing ask_user.
+	MaxNoToolRounds int `json:"max_no_tool_rounds"`
+	// DoomLoopThreshold is the number of identical tool calls that stops the loop.
+	DoomLoopThreshold int `json:"doom_loop_threshold"`
+	// MistakeWindowLimit is the number of recent mistakes that escalates to ask_user.
+	MistakeWindowLimit int `json:"mistake_window_limit"`
+	// RecoveryRetryLimit is the number of recovery retries before giving up on a failing step.
+	RecoveryRetryLimit int `json:"recovery_retry_limit"`
+	// CompactThreshold is the context usage fraction that triggers compaction.
+	CompactThreshold float64 `json:"compact_threshold"`
+}
+
+var loopProfileRegistry = map[string]LoopProfile{
+	LoopProfileDefault: {
+		ID:                 LoopProfileDefault,
+		MaxNoToolRounds:    nativeDefaultNoToolRounds,
+		DoomLoopThreshold:  3,
+		MistakeWindowLimit: 3,
+		RecoveryRetryLimit: 5,
+		CompactThreshold:   nativeDefaultCompactThreshold,
+	},
+	LoopProfileFastExitV1: {
+		ID:                 LoopProfileFastExitV1,
+		MaxNoToolRounds:    1,
+		DoomLoopThreshold:  2,
+		MistakeWindowLimit: 2,
+		RecoveryRetryLimit: 2,
+		CompactThreshold:   0.70,
+	},
+	LoopProfileDeepAnalysisV1: {
+		ID:                 LoopProfileDeepAnalysisV1,
+		MaxNoToolRounds:    5,
+		DoomLoopThreshold:  4,
+		MistakeWindowLimit: 5,
+		RecoveryRetryLimit: 8,
+		CompactThreshold:   0.85,
+	},
+}
+
+// LookupLoopProfile returns the registered profile for id. An empty id resolves to the default profile.
+func LookupLoopProfile(id string) (LoopProfile, bool) {
+	id = strings.TrimSpace(strings.ToLower(id))
+	if id == "" {
+		id = LoopProfileDefault
+	}
+	profile, ok := loopProfileRegistry[id]
+	return profile, ok
+}
+
+// resolveLoopProfile falls back to the default profile for unknown ids.
+func resolveLoopProfile(id string) (LoopProfile, bool) {
+	if profile, ok := LookupLoopProfile(id); ok {
+		return profile, true
+	}
+	return loopProfileRegistry[LoopProfileDefault], false
+}
@@ -0,0 +1,35 @@
+package ai
+
+import "testing"
+
+func TestLookupLoopProfile(t *testing.T) {
+	t.Parallel()
+
+	def, ok := LookupLoopProfile("")
+	if !ok || def.ID != LoopProfileDefault {
+		t.Fatalf("empty id resolved to %+v ok=%v", def, ok)
+	}
+	if def.MaxNoToolRounds != nativeDefaultNoToolRounds || def.CompactThreshold != nativeDefaultCompactThreshold {
+		t.Fatalf("default profile must match runtime defaults: %+v", def)
+	}
+	fast, ok := LookupLoopProfile(" Fast_Exit_V1 ")
+	if !ok || fast.ID != LoopProfileFastExitV1 {
+		t.Fatalf("fast_exit_v1 lookup=%+v ok=%v", fast, ok)
+	}
+	deep, _ := LookupLoopProfile(LoopProfileDeepAnalysisV1)
+	if fast.MaxNoToolRounds >= deep.MaxNoToolRounds || fast.RecoveryRetryLimit >= deep.RecoveryRetryLimit || fast.DoomLoopThreshold >= deep.DoomLoopThreshold {
+		t.Fatalf("profiles must differ: fast=%+v deep=%+v", fast, deep)
+	}
+	for id, profile := range loopProfileRegistry {
+		if profile.ID != id || profile.MaxNoToolRounds <= 0 || profile.DoomLoopThreshold < 2 || profile.MistakeWindowLimit <= 0 || profile.RecoveryRetryLimit <= 0 {
+			t.Fatalf("invalid registered profile %q: %+v", id, profile)
+		}
+		if profile.CompactThreshold < nativeMinCompactThreshold || profile.CompactThreshold > nativeMaxCompactThreshold {
+			t.Fatalf("profile %q compact threshold out of range: %v", id, profile.CompactThreshold)
+		}
+	}
+
+	if got, known := resolveLoopProfile("turbo_v9"); known || got.ID != LoopProfileDefault {
+		t.Fatalf("unknown id must fall back to default, got %+v known=%v", got, known)
+	}
+}
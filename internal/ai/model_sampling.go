@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"strings"
+
+	contextmodel "github.com/floegence/redeven/internal/ai/context/model"
+)
+
+// turnSampling is the sampling configuration actually sent to the provider for a run.
+type turnSampling struct {
+	Temperature *float64
+	TopP        *float64
+	// Source is one of: explicit, model_default, unset, omitted.
+	Source string
+}
+
+func (s turnSampling) eventPayload() map[string]any {
+	out := map[string]any{"source": s.Source}
+	if s.Temperature != nil {
+		out["temperature"] = *s.Temperature
+	}
+	if s.TopP != nil {
+		out["top_p"] = *s.TopP
+	}
+	return out
+}
+
+// resolveTurnSampling applies per-model sampling defaults.
+//
+// Explicit run values win. Models that reject sampling params never receive them, even when explicit.
+func resolveTurnSampling(capability contextmodel.ModelCapability, temperature *float64, topP *float64) turnSampling {
+	if capability.RejectsSamplingParams {
+		return turnSampling{Source: "omitted"}
+	}
+	out := turnSampling{Temperature: temperature, TopP: topP, Source: "unset"}
+	if temperature != nil || topP != nil {
+		out.Source = "explicit"
+	}
+	if out.Temperature == nil && capability.DefaultTemperature != nil {
+		v := *capability.DefaultTemperature
+		out.Temperature = &v
+		if out.Source == "unset" {
+			out.Source = "model_default"
+		}
+	}
+	if out.TopP == nil && capability.DefaultTopP != nil {
+		v := *capability.DefaultTopP
+		out.TopP = &v
+		if out.Source == "unset" {
+			out.Source = "model_default"
+		}
+	}
+	return out
+}
+
+// providerSupportsSeed reports whether the provider's API honors a sampling seed.
+//
+// The OpenAI Responses API, which the openai, openai_compatible, azure_openai, chatglm, and qwen adapters use,
+// has no seed parameter, and Anthropic has none either.
+func providerSupportsSeed(providerType string) bool {
+	switch strings.ToLower(strings.TrimSpace(providerType)) {
+	case "moonshot", "deepseek", "ollama", "mistral":
+		return true
+	default:
+		return false
+	}
+}
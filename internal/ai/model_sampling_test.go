@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	contextmodel "github.com/floegence/redeven/internal/ai/context/model"
+	"github.com/openai/openai-go"
+)
+
+func TestResolveTurnSampling(t *testing.T) {
+	t.Parallel()
+
+	f := func(v float64) *float64 { return &v }
+	capWithDefaults := contextmodel.ModelCapability{DefaultTemperature: f(0.2), DefaultTopP: f(0.9)}
+
+	got := resolveTurnSampling(capWithDefaults, nil, nil)
+	if got.Source != "model_default" || got.Temperature == nil || *got.Temperature != 0.2 || got.TopP == nil || *got.TopP != 0.9 {
+		t.Fatalf("defaults not applied: %+v", got)
+	}
+
+	got = resolveTurnSampling(capWithDefaults, f(0.7), nil)
+	if got.Source != "explicit" || *got.Temperature != 0.7 || got.TopP == nil || *got.TopP != 0.9 {
+		t.Fatalf("explicit temperature must win and top_p default fill in: %+v", got)
+	}
+
+	got = resolveTurnSampling(contextmodel.ModelCapability{}, nil, nil)
+	if got.Source != "unset" || got.Temperature != nil || got.TopP != nil {
+		t.Fatalf("expected unset sampling: %+v", got)
+	}
+
+	got = resolveTurnSampling(contextmodel.ModelCapability{RejectsSamplingParams: true, DefaultTemperature: f(1)}, f(0.3), f(0.5))
+	if got.Source != "omitted" || got.Temperature != nil || got.TopP != nil {
+		t.Fatalf("reasoning models must never receive sampling params: %+v", got)
+	}
+	if payload := got.eventPayload(); len(payload) != 1 || payload["source"] != "omitted" {
+		t.Fatalf("unexpected event payload: %+v", payload)
+	}
+}
+
+func TestProviderSupportsSeed(t *testing.T) {
+	t.Parallel()
+
+	for _, providerType := range []string{"moonshot", "deepseek", "ollama", " Mistral "} {
+		if !providerSupportsSeed(providerType) {
+			t.Fatalf("providerSupportsSeed(%q)=false, want true", providerType)
+		}
+	}
+	for _, providerType := range []string{"openai", "openai_compatible", "azure_openai", "chatglm", "qwen", "anthropic", ""} {
+		if providerSupportsSeed(providerType) {
+			t.Fatalf("providerSupportsSeed(%q)=true, want false", providerType)
+		}
+	}
+}
+
+func TestMoonshotProviderApplySeed(t *testing.T) {
+	t.Parallel()
+
+	seed := int64(42)
+	var params openai.ChatCompletionNewParams
+	if opts := (&moonshotProvider{}).applySeed(&params, ProviderControls{}); opts != nil || params.Seed.Valid() {
+		t.Fatalf("unseeded request set seed=%v opts=%d", params.Seed, len(opts))
+	}
+	if opts := (&moonshotProvider{}).applySeed(&params, ProviderControls{Seed: &seed}); opts != nil || params.Seed.Value != 42 {
+		t.Fatalf("seed param=%v opts=%d, want 42 on params", params.Seed, len(opts))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if got, _ := req["random_seed"].(float64); got != 42 {
+			t.Errorf("random_seed=%v, want 42", req["random_seed"])
+		}
+		if _, ok := req["seed"]; ok {
+			t.Errorf("mistral request must not send seed")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"cmpl_1","object":"chat.completion","created":1,"model":"mistral-small-latest","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	provider, err := newProviderAdapter("mistral", srv.URL+"/v1", "sk-mistral", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapter: %v", err)
+	}
+	result, err := provider.(*mistralProvider).turnOnce(context.Background(), TurnRequest{
+		Model:            "mistral-small-latest",
+		Messages:         []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "hi"}}}},
+		ProviderControls: ProviderControls{Seed: &seed},
+	})
+	if err != nil || result.Text != "ok" {
+		t.Fatalf("turnOnce result=%+v err=%v", result, err)
+	}
+}
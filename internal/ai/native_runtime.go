@@ -8,11 +8,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	anthropic "github.com/anthropics/anthropic-sdk-go"
 	aoption "github.com/anthropics/anthropic-sdk-go/option"
@@ -43,11 +46,15 @@ const (
 	// ask_user), NOT by a step budget. This constant only prevents
 	// runaway loops caused by bugs.
 	nativeHardMaxSteps = 200
+	// nativeAskUserBounceLimit caps consecutive guard-originated ask_user attempts that the gate rejects.
+	// Past it, the run finalizes with a summary instead of bouncing between ask_user and no-tool rounds.
+	nativeAskUserBounceLimit = 3
 )
 
 type openAIProvider struct {
 	client           openai.Client
 	strictToolSchema bool
+	retry            providerRetryPolicy
 }
 
 func runProviderTurn(ctx context.Context, provider Provider, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
@@ -63,6 +70,12 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 	if p == nil {
 		return TurnResult{}, errors.New("nil provider")
 	}
+	return withProviderRetry(ctx, p.retry, req.Model, onEvent, func(onEvent func(StreamEvent)) (TurnResult, error) {
+		return p.streamTurnOnce(ctx, req, onEvent)
+	})
+}
+
+func (p *openAIProvider) streamTurnOnce(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
 	if strings.TrimSpace(req.Model) == "" {
 		return TurnResult{}, errors.New("missing model")
 	}
@@ -70,7 +83,7 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 	params := oresponses.ResponseNewParams{
 		Model:             oshared.ResponsesModel(strings.TrimSpace(req.Model)),
 		MaxOutputTokens:   openai.Int(nativeDefaultMaxOutputTokens),
-		ParallelToolCalls: openai.Bool(false),
+		ParallelToolCalls: openai.Bool(req.ProviderControls.ParallelToolCalls && len(req.Tools) > 0),
 	}
 	if req.Budgets.MaxOutputToken > 0 {
 		params.MaxOutputTokens = openai.Int(int64(req.Budgets.MaxOutputToken))
@@ -98,7 +111,10 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 			Format: oresponses.ResponseFormatTextConfigUnionParam{OfJSONObject: &obj},
 		}
 	default:
-		// json_schema requires an explicit schema. Avoid implicit downgrade here and let upper layers drive structured output.
+		// json_schema requires an explicit schema. Without one, avoid implicit downgrade here and let upper layers drive structured output.
+		if spec, ok := requestedResponseJSONSchema(req.ProviderControls); ok {
+			params.Text = oresponses.ResponseTextConfigParam{Format: spec.openAIResponsesFormat()}
+		}
 	}
 
 	inputItems, instructions := buildOpenAIInput(req.Messages)
@@ -113,6 +129,9 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 	if req.WebSearchEnabled && p.strictToolSchema {
 		tools = append(tools, oresponses.ToolParamOfWebSearchPreview(oresponses.WebSearchToolTypeWebSearchPreview))
 	}
+	if req.ImageOutputEnabled {
+		tools = append(tools, oresponses.ToolUnionParam{OfImageGeneration: &oresponses.ToolImageGenerationParam{}})
+	}
 	if len(tools) > 0 {
 		params.Tools = tools
 	}
@@ -134,6 +153,7 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 		Args    map[string]any
 	}
 	partials := map[string]*partialCall{} // item_id -> partial
+	var argsRepair toolArgsRepairStats
 
 	emitStart := func(pc *partialCall) {
 		if pc == nil || pc.Started {
@@ -162,11 +182,7 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 			return
 		}
 		pc.Ended = true
-		rawArgs = strings.TrimSpace(rawArgs)
-		args := map[string]any{}
-		if rawArgs != "" {
-			_ = json.Unmarshal([]byte(rawArgs), &args)
-		}
+		args := argsRepair.parse(rawArgs)
 		pc.Args = args
 		emitStart(pc)
 		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallEnd, ToolCall: &PartialToolCall{ID: strings.TrimSpace(pc.CallID), Name: strings.TrimSpace(pc.Name), Arguments: cloneAnyMap(args)}})
@@ -382,10 +398,7 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 				toolName = realName
 			}
 			rawArgs := strings.TrimSpace(item.Arguments)
-			args := map[string]any{}
-			if rawArgs != "" {
-				_ = json.Unmarshal([]byte(rawArgs), &args)
-			}
+			args := argsRepair.parse(rawArgs)
 			call := ToolCall{ID: callID, Name: toolName, Args: args}
 			result.ToolCalls = append(result.ToolCalls, call)
 			emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallStart, ToolCall: &PartialToolCall{ID: call.ID, Name: call.Name}})
@@ -393,6 +406,7 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 			emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallEnd, ToolCall: &PartialToolCall{ID: call.ID, Name: call.Name, Arguments: cloneAnyMap(call.Args)}})
 		}
 	}
+	argsRepair.applyDiag(result.RawProviderDiag)
 	if len(result.ToolCalls) > 0 {
 		result.FinishReason = "tool_calls"
 	}
@@ -401,7 +415,14 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 			result.Text = strings.TrimSpace(extractOpenAIResponseText(completed))
 		}
 	}
-	if result.FinishReason == "unknown" && result.Text != "" {
+	if req.ImageOutputEnabled && gotCompleted {
+		result.Images = extractOpenAIResponseImages(completed)
+		for i := range result.Images {
+			img := result.Images[i]
+			emitProviderEvent(onEvent, StreamEvent{Type: StreamEventImage, Image: &img})
+		}
+	}
+	if result.FinishReason == "unknown" && (result.Text != "" || len(result.Images) > 0) {
 		result.FinishReason = "stop"
 	}
 	emitProviderEvent(onEvent, StreamEvent{Type: StreamEventUsage, Usage: &PartialUsage{InputTokens: result.Usage.InputTokens, OutputTokens: result.Usage.OutputTokens, ReasoningTokens: result.Usage.ReasoningTokens}})
@@ -412,25 +433,150 @@ func (p *openAIProvider) StreamTurn(ctx context.Context, req TurnRequest, onEven
 type moonshotProvider struct {
 	client           openai.Client
 	strictToolSchema bool
+	// callIDPrefix names synthesized tool call IDs when the stream omits them.
+	callIDPrefix string
+	// rewriteMessages adapts the chat history to provider quirks before every request.
+	rewriteMessages func([]openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion
+	// seedField names the request field carrying the sampling seed when the gateway does not use "seed".
+	seedField string
+	retry     providerRetryPolicy
+	// streamRejected is set once the gateway rejects stream=true; later turns go straight to the blocking call.
+	streamRejected atomic.Bool
+}
+
+// errChatStreamNoChunks reports a stream=true response that carried no chunks at all,
+// which is what gateways that ignore streaming and answer with plain JSON look like.
+var errChatStreamNoChunks = errors.New("missing streamed response: no stream chunks")
+
+// ollamaProvider talks to Ollama's OpenAI-compatible chat-completions endpoint.
+//
+// Local models stream through the same chat-completions path as Moonshot.
+type ollamaProvider struct {
+	moonshotProvider
+}
+
+// deepseekProvider streams DeepSeek's OpenAI-compatible chat-completions endpoint.
+//
+// DeepSeek reasoning models stream their chain of thought as delta.reasoning_content, which the
+// shared chat path surfaces as thinking deltas and replays on tool-call history turns.
+type deepseekProvider struct {
+	moonshotProvider
+}
+
+// mistralProvider streams Mistral la Plateforme's chat-completions endpoint.
+//
+// History is rewritten by normalizeMistralChatMessages before every request.
+type mistralProvider struct {
+	moonshotProvider
+}
+
+// chatMessages builds the request messages for req, including the provider's history rewrite.
+func (p *moonshotProvider) chatMessages(req TurnRequest) []openai.ChatCompletionMessageParamUnion {
+	messages := buildOpenAIChatMessages(req.Messages)
+	if len(messages) == 0 {
+		messages = append(messages, openai.UserMessage("Continue."))
+	}
+	if p.rewriteMessages != nil {
+		messages = p.rewriteMessages(messages)
+	}
+	return messages
 }
 
 func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
 	if p == nil {
 		return TurnResult{}, errors.New("nil provider")
 	}
-	if strings.TrimSpace(req.Model) == "" {
-		return TurnResult{}, errors.New("missing model")
+	return withProviderRetry(ctx, p.retry, req.Model, onEvent, func(onEvent func(StreamEvent)) (TurnResult, error) {
+		if p.streamRejected.Load() {
+			return p.replayBlockingTurn(ctx, req, onEvent)
+		}
+		emitted := false
+		result, err := p.streamTurnOnce(ctx, req, func(event StreamEvent) {
+			emitted = true
+			emitProviderEvent(onEvent, event)
+		})
+		if err != nil && !emitted && isChatStreamRejected(err) {
+			p.streamRejected.Store(true)
+			return p.replayBlockingTurn(ctx, req, onEvent)
+		}
+		return result, err
+	})
+}
+
+// isChatStreamRejected reports whether a streaming attempt failed because the gateway does not support stream=true.
+func isChatStreamRejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, errChatStreamNoChunks) {
+		return true
+	}
+	switch status, _ := providerErrorHTTPStatus(err); status {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusNotImplemented:
+		return strings.Contains(strings.ToLower(err.Error()), "stream")
+	default:
+		return false
 	}
+}
 
-	messages := buildOpenAIChatMessages(req.Messages)
-	if len(messages) == 0 {
-		messages = append(messages, openai.UserMessage("Continue."))
+// replayBlockingTurn runs the non-streaming call and replays its result as stream events.
+func (p *moonshotProvider) replayBlockingTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	result, err := p.turnOnce(ctx, req)
+	if err != nil {
+		return TurnResult{}, err
+	}
+	if result.RawProviderDiag == nil {
+		result.RawProviderDiag = map[string]any{}
+	}
+	result.RawProviderDiag["stream_fallback"] = true
+	if result.Reasoning != "" {
+		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventThinkingDelta, Text: result.Reasoning})
+	}
+	if result.Text != "" {
+		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventTextDelta, Text: result.Text})
+	}
+	prefix := strings.TrimSpace(p.callIDPrefix)
+	if prefix == "" {
+		prefix = "moonshot_call"
+	}
+	for i := range result.ToolCalls {
+		call := &result.ToolCalls[i]
+		if strings.TrimSpace(call.ID) == "" {
+			call.ID = fmt.Sprintf("%s_%d", prefix, i+1)
+		}
+		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallStart, ToolCall: &PartialToolCall{ID: call.ID, Name: call.Name}})
+		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallEnd, ToolCall: &PartialToolCall{ID: call.ID, Name: call.Name, Arguments: cloneAnyMap(call.Args)}})
+	}
+	emitProviderEvent(onEvent, StreamEvent{Type: StreamEventUsage, Usage: &PartialUsage{
+		InputTokens:     result.Usage.InputTokens,
+		OutputTokens:    result.Usage.OutputTokens,
+		ReasoningTokens: result.Usage.ReasoningTokens,
+	}})
+	emitProviderEvent(onEvent, StreamEvent{Type: StreamEventFinishReason, FinishHint: result.FinishReason})
+	return result, nil
+}
+
+// applySeed pins the sampling seed on params, or returns the request option that sends it under seedField.
+func (p *moonshotProvider) applySeed(params *openai.ChatCompletionNewParams, controls ProviderControls) []ooption.RequestOption {
+	if controls.Seed == nil {
+		return nil
+	}
+	if field := strings.TrimSpace(p.seedField); field != "" {
+		return []ooption.RequestOption{ooption.WithJSONSet(field, *controls.Seed)}
+	}
+	params.Seed = openai.Int(*controls.Seed)
+	return nil
+}
+
+func (p *moonshotProvider) streamTurnOnce(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	if strings.TrimSpace(req.Model) == "" {
+		return TurnResult{}, errors.New("missing model")
 	}
 
 	params := openai.ChatCompletionNewParams{
 		Model:             oshared.ChatModel(strings.TrimSpace(req.Model)),
-		Messages:          messages,
-		ParallelToolCalls: openai.Bool(false),
+		Messages:          p.chatMessages(req),
+		ParallelToolCalls: openai.Bool(req.ProviderControls.ParallelToolCalls && len(req.Tools) > 0),
 		StreamOptions:     openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)},
 	}
 	if req.Budgets.MaxOutputToken > 0 {
@@ -442,6 +588,7 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 	if req.ProviderControls.TopP != nil {
 		params.TopP = openai.Float(*req.ProviderControls.TopP)
 	}
+	seedOpts := p.applySeed(&params, req.ProviderControls)
 	switch strings.ToLower(strings.TrimSpace(req.ProviderControls.ResponseFormat)) {
 	case "":
 		// default behavior
@@ -452,7 +599,10 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 		obj := oshared.NewResponseFormatJSONObjectParam()
 		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{OfJSONObject: &obj}
 	default:
-		// json_schema requires an explicit schema; leave unset and let upper layers decide.
+		// json_schema requires an explicit schema; without one, leave unset and let upper layers decide.
+		if spec, ok := requestedResponseJSONSchema(req.ProviderControls); ok {
+			params.ResponseFormat = spec.openAIChatFormat()
+		}
 	}
 
 	tools, aliasToReal := buildOpenAIChatTools(req.Tools, p.strictToolSchema)
@@ -460,7 +610,7 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 		params.Tools = tools
 	}
 
-	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params, seedOpts...)
 	var textBuf strings.Builder
 	var reasoningBuf strings.Builder
 	result := TurnResult{
@@ -480,6 +630,10 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 
 	partials := map[int64]*partialCall{}
 	order := make([]int64, 0, 2)
+	// currentKey maps a stream tool-call index to the partial that receives its deltas.
+	currentKey := map[int64]int64{}
+	splitCalls := int64(0)
+	var argsRepair toolArgsRepairStats
 	getPartial := func(index int64) *partialCall {
 		if pc := partials[index]; pc != nil {
 			return pc
@@ -494,7 +648,11 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 			return ""
 		}
 		if strings.TrimSpace(pc.CallID) == "" {
-			pc.CallID = fmt.Sprintf("moonshot_call_%d", pc.Index+1)
+			prefix := strings.TrimSpace(p.callIDPrefix)
+			if prefix == "" {
+				prefix = "moonshot_call"
+			}
+			pc.CallID = fmt.Sprintf("%s_%d", prefix, pc.Index+1)
 		}
 		return strings.TrimSpace(pc.CallID)
 	}
@@ -550,11 +708,7 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 		if callID == "" || name == "" {
 			return
 		}
-		raw := strings.TrimSpace(pc.ArgsRaw.String())
-		args := map[string]any{}
-		if raw != "" {
-			_ = json.Unmarshal([]byte(raw), &args)
-		}
+		args := argsRepair.parse(pc.ArgsRaw.String())
 		pc.Args = args
 		pc.Ended = true
 		emitStart(pc)
@@ -568,8 +722,10 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 		})
 	}
 
+	chunks := 0
 	for stream.Next() {
 		chunk := stream.Current()
+		chunks++
 		if rid := strings.TrimSpace(chunk.ID); rid != "" {
 			result.RawProviderDiag["response_id"] = rid
 		}
@@ -594,11 +750,23 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 				emitProviderEvent(onEvent, StreamEvent{Type: StreamEventThinkingDelta, Text: reasoning})
 			}
 			for _, tc := range delta.ToolCalls {
-				pc := getPartial(tc.Index)
+				key, ok := currentKey[tc.Index]
+				if !ok {
+					key = tc.Index
+				}
+				id := strings.TrimSpace(tc.ID)
+				if prev := partials[key]; prev != nil && id != "" && prev.CallID != "" && prev.CallID != id {
+					// Mistral streams each tool call whole and may repeat index 0 for every call;
+					// a new call id at a used index starts a new call, ordered after the indexed ones.
+					splitCalls++
+					key = 1<<32 + splitCalls
+				}
+				currentKey[tc.Index] = key
+				pc := getPartial(key)
 				if pc == nil {
 					continue
 				}
-				if id := strings.TrimSpace(tc.ID); id != "" {
+				if id != "" {
 					pc.CallID = id
 				}
 				name := strings.TrimSpace(tc.Function.Name)
@@ -620,6 +788,9 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 	if err := stream.Err(); err != nil {
 		return TurnResult{}, err
 	}
+	if chunks == 0 {
+		return TurnResult{}, errChatStreamNoChunks
+	}
 
 	sort.SliceStable(order, func(i, j int) bool { return order[i] < order[j] })
 	for _, idx := range order {
@@ -638,6 +809,8 @@ func (p *moonshotProvider) StreamTurn(ctx context.Context, req TurnRequest, onEv
 		})
 	}
 
+	argsRepair.applyDiag(result.RawProviderDiag)
+
 	result.Text = strings.TrimSpace(textBuf.String())
 	result.Reasoning = strings.TrimSpace(reasoningBuf.String())
 	if len(result.ToolCalls) > 0 {
@@ -662,19 +835,20 @@ func (p *moonshotProvider) Turn(ctx context.Context, req TurnRequest) (TurnResul
 	if p == nil {
 		return TurnResult{}, errors.New("nil provider")
 	}
+	return withProviderRetry(ctx, p.retry, req.Model, nil, func(func(StreamEvent)) (TurnResult, error) {
+		return p.turnOnce(ctx, req)
+	})
+}
+
+func (p *moonshotProvider) turnOnce(ctx context.Context, req TurnRequest) (TurnResult, error) {
 	if strings.TrimSpace(req.Model) == "" {
 		return TurnResult{}, errors.New("missing model")
 	}
 
-	messages := buildOpenAIChatMessages(req.Messages)
-	if len(messages) == 0 {
-		messages = append(messages, openai.UserMessage("Continue."))
-	}
-
 	params := openai.ChatCompletionNewParams{
 		Model:             oshared.ChatModel(strings.TrimSpace(req.Model)),
-		Messages:          messages,
-		ParallelToolCalls: openai.Bool(false),
+		Messages:          p.chatMessages(req),
+		ParallelToolCalls: openai.Bool(req.ProviderControls.ParallelToolCalls && len(req.Tools) > 0),
 	}
 	if req.Budgets.MaxOutputToken > 0 {
 		params.MaxTokens = openai.Int(int64(req.Budgets.MaxOutputToken))
@@ -685,6 +859,7 @@ func (p *moonshotProvider) Turn(ctx context.Context, req TurnRequest) (TurnResul
 	if req.ProviderControls.TopP != nil {
 		params.TopP = openai.Float(*req.ProviderControls.TopP)
 	}
+	seedOpts := p.applySeed(&params, req.ProviderControls)
 	switch strings.ToLower(strings.TrimSpace(req.ProviderControls.ResponseFormat)) {
 	case "":
 		// default behavior
@@ -694,13 +869,17 @@ func (p *moonshotProvider) Turn(ctx context.Context, req TurnRequest) (TurnResul
 	case "json_object":
 		obj := oshared.NewResponseFormatJSONObjectParam()
 		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{OfJSONObject: &obj}
+	default:
+		if spec, ok := requestedResponseJSONSchema(req.ProviderControls); ok {
+			params.ResponseFormat = spec.openAIChatFormat()
+		}
 	}
 	tools, aliasToReal := buildOpenAIChatTools(req.Tools, p.strictToolSchema)
 	if len(tools) > 0 {
 		params.Tools = tools
 	}
 
-	completion, err := p.client.Chat.Completions.New(ctx, params)
+	completion, err := p.client.Chat.Completions.New(ctx, params, seedOpts...)
 	if err != nil {
 		return TurnResult{}, err
 	}
@@ -720,22 +899,20 @@ func (p *moonshotProvider) Turn(ctx context.Context, req TurnRequest) (TurnResul
 	result.FinishReason = mapOpenAIChatFinishReason(string(choice.FinishReason))
 	result.Text = strings.TrimSpace(choice.Message.Content)
 	result.Reasoning = strings.TrimSpace(extractMoonshotReasoningJSON(choice.Message.RawJSON()))
+	var argsRepair toolArgsRepairStats
 	for _, tc := range choice.Message.ToolCalls {
 		name := strings.TrimSpace(tc.Function.Name)
 		if realName, ok := aliasToReal[name]; ok {
 			name = realName
 		}
-		args := map[string]any{}
-		rawArgs := strings.TrimSpace(tc.Function.Arguments)
-		if rawArgs != "" {
-			_ = json.Unmarshal([]byte(rawArgs), &args)
-		}
+		args := argsRepair.parse(tc.Function.Arguments)
 		result.ToolCalls = append(result.ToolCalls, ToolCall{
 			ID:   strings.TrimSpace(tc.ID),
 			Name: name,
 			Args: cloneAnyMap(args),
 		})
 	}
+	argsRepair.applyDiag(result.RawProviderDiag)
 	if len(result.ToolCalls) > 0 {
 		result.FinishReason = "tool_calls"
 	}
@@ -921,6 +1098,42 @@ func buildOpenAIChatMessages(messages []Message) []openai.ChatCompletionMessageP
 	return out
 }
 
+// normalizeMistralChatMessages adapts chat history to Mistral's request validation.
+//
+// Mistral rejects the reasoning_content extra field and tool call ids that are not exactly nine
+// alphanumeric characters. Other ids (synthesized ones, or ids from another provider earlier in the
+// thread) are mapped to stable nine-character ids, applied to assistant tool calls and tool results
+// alike so every tool message still references its assistant tool call.
+func normalizeMistralChatMessages(messages []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	for i := range messages {
+		if assistant := messages[i].OfAssistant; assistant != nil {
+			assistant.SetExtraFields(nil)
+			for j := range assistant.ToolCalls {
+				assistant.ToolCalls[j].ID = mistralToolCallID(assistant.ToolCalls[j].ID)
+			}
+		}
+		if tool := messages[i].OfTool; tool != nil {
+			tool.ToolCallID = mistralToolCallID(tool.ToolCallID)
+		}
+	}
+	return messages
+}
+
+const mistralToolCallIDAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func mistralToolCallID(id string) string {
+	id = strings.TrimSpace(id)
+	if len(id) == 9 && strings.Trim(id, mistralToolCallIDAlphabet) == "" {
+		return id
+	}
+	sum := sha256.Sum256([]byte(id))
+	out := make([]byte, 9)
+	for i := range out {
+		out[i] = mistralToolCallIDAlphabet[int(sum[i])%len(mistralToolCallIDAlphabet)]
+	}
+	return string(out)
+}
+
 func extractMoonshotChatReasoningDelta(delta openai.ChatCompletionChunkChoiceDelta) string {
 	return extractMoonshotReasoningJSON(delta.RawJSON())
 }
@@ -972,6 +1185,9 @@ func mapOpenAIChatFinishReason(reason string) string {
 	switch reason {
 	case "stop", "length", "tool_calls", "content_filter", "function_call":
 		return reason
+	case "model_length":
+		// Mistral reports hitting the model context limit as model_length.
+		return "length"
 	default:
 		return "unknown"
 	}
@@ -1179,12 +1395,19 @@ func extractDataURLBase64(raw string) (string, bool) {
 
 type anthropicProvider struct {
 	client anthropic.Client
+	retry  providerRetryPolicy
 }
 
 func (p *anthropicProvider) StreamTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
 	if p == nil {
 		return TurnResult{}, errors.New("nil provider")
 	}
+	return withProviderRetry(ctx, p.retry, req.Model, onEvent, func(onEvent func(StreamEvent)) (TurnResult, error) {
+		return p.streamTurnOnce(ctx, req, onEvent)
+	})
+}
+
+func (p *anthropicProvider) streamTurnOnce(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
 	if strings.TrimSpace(req.Model) == "" {
 		return TurnResult{}, errors.New("missing model")
 	}
@@ -1210,6 +1433,9 @@ func (p *anthropicProvider) StreamTurn(ctx context.Context, req TurnRequest, onE
 	if system := collectSystemPrompt(req.Messages); strings.TrimSpace(system) != "" {
 		params.System = []anthropic.TextBlockParam{{Text: strings.TrimSpace(system)}}
 	}
+	if spec, ok := requestedResponseJSONSchema(req.ProviderControls); ok {
+		params.OutputConfig = spec.anthropicOutputConfig()
+	}
 
 	stream := p.client.Messages.NewStreaming(ctx, params)
 	msg := anthropic.Message{}
@@ -1226,6 +1452,7 @@ func (p *anthropicProvider) StreamTurn(ctx context.Context, req TurnRequest, onE
 		Args    map[string]any
 	}
 	partials := map[int64]*partialCall{} // content_block index -> partial
+	var argsRepair toolArgsRepairStats
 
 	emitStart := func(pc *partialCall) {
 		if pc == nil || pc.Started {
@@ -1254,22 +1481,21 @@ func (p *anthropicProvider) StreamTurn(ctx context.Context, req TurnRequest, onE
 			return
 		}
 		pc.Ended = true
-		rawArgs = strings.TrimSpace(rawArgs)
-		args := map[string]any{}
-		if rawArgs != "" {
-			_ = json.Unmarshal([]byte(rawArgs), &args)
-		}
+		args := argsRepair.parse(rawArgs)
 		pc.Args = args
 		emitStart(pc)
 		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallEnd, ToolCall: &PartialToolCall{ID: strings.TrimSpace(pc.ID), Name: strings.TrimSpace(pc.Name), Arguments: cloneAnyMap(args)}})
 	}
 
+	sawMessageStop := false
 	for stream.Next() {
 		event := stream.Current()
 		if err := msg.Accumulate(event); err != nil {
 			return TurnResult{}, err
 		}
 		switch variant := event.AsAny().(type) {
+		case anthropic.MessageStopEvent:
+			sawMessageStop = true
 		case anthropic.ContentBlockStartEvent:
 			if strings.TrimSpace(variant.ContentBlock.Type) != "tool_use" {
 				continue
@@ -1335,7 +1561,8 @@ func (p *anthropicProvider) StreamTurn(ctx context.Context, req TurnRequest, onE
 			emitEnd(pc, raw)
 		}
 	}
-	if err := stream.Err(); err != nil {
+	// A Bedrock event stream reports its end as io.EOF rather than closing cleanly.
+	if err := stream.Err(); err != nil && !(sawMessageStop && errors.Is(err, io.EOF)) {
 		return TurnResult{}, err
 	}
 
@@ -1378,10 +1605,7 @@ func (p *anthropicProvider) StreamTurn(ctx context.Context, req TurnRequest, onE
 				result.Text = strings.TrimSpace(variant.Text)
 			}
 		case anthropic.ToolUseBlock:
-			args := map[string]any{}
-			if len(variant.Input) > 0 {
-				_ = json.Unmarshal(variant.Input, &args)
-			}
+			args := argsRepair.parse(string(variant.Input))
 			callID := strings.TrimSpace(variant.ID)
 			if callID == "" {
 				callID = fmt.Sprintf("anthropic_call_%d", len(result.ToolCalls)+1)
@@ -1404,6 +1628,7 @@ func (p *anthropicProvider) StreamTurn(ctx context.Context, req TurnRequest, onE
 			emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallEnd, ToolCall: &PartialToolCall{ID: call.ID, Name: call.Name, Arguments: cloneAnyMap(call.Args)}})
 		}
 	}
+	argsRepair.applyDiag(result.RawProviderDiag)
 	if len(result.ToolCalls) > 0 {
 		result.FinishReason = "tool_calls"
 	}
@@ -1574,62 +1799,215 @@ func (r *run) shouldUseNativeRuntime(provider *config.AIProvider) bool {
 		return false
 	}
 	switch strings.ToLower(strings.TrimSpace(provider.Type)) {
-	case "openai", "anthropic", "moonshot", "chatglm", "deepseek", "qwen", "openai_compatible":
+	case "openai", "anthropic", "moonshot", "chatglm", "deepseek", "qwen", "openai_compatible", "ollama", "azure_openai", "mistral", "bedrock", "replay":
 		return true
 	default:
 		return false
 	}
 }
 
+// newProviderAdapterForConfig builds the adapter for a configured provider, including the
+// settings that only some provider types read (the Azure deployment and api-version, the Bedrock
+// region, the replay recording).
+func newProviderAdapterForConfig(provider config.AIProvider, apiKey string, httpClient *http.Client) (Provider, error) {
+	providerType := strings.ToLower(strings.TrimSpace(provider.Type))
+	if providerType == "replay" {
+		replayer, err := NewProviderReplayer(provider.RecordingPath)
+		if err != nil {
+			return nil, err
+		}
+		return replayer, nil
+	}
+	if providerType == "bedrock" {
+		return newBedrockProvider(context.Background(), provider.Region, provider.BaseURL, defaultProviderRetryPolicy(), httpClient)
+	}
+	if providerType != "azure_openai" {
+		return newProviderAdapterWithHTTPClient(providerType, strings.TrimSpace(provider.BaseURL), apiKey, provider.StrictToolSchema, httpClient)
+	}
+	if strings.TrimSpace(apiKey) == "" {
+		return nil, errors.New("missing provider api key")
+	}
+	return newAzureOpenAIProvider(
+		provider.BaseURL,
+		apiKey,
+		provider.Deployment,
+		provider.APIVersion,
+		resolveStrictToolSchema(providerType, provider.BaseURL, provider.StrictToolSchema),
+		defaultProviderRetryPolicy(),
+		httpClient,
+	)
+}
+
+// newAzureOpenAIProvider reuses the OpenAI Responses adapter against an Azure OpenAI resource.
+//
+// Azure authenticates with an api-key header and versions every request with an api-version query
+// parameter. The request model names the deployment: a configured deployment replaces every model,
+// otherwise the model name is sent as the deployment name.
+func newAzureOpenAIProvider(endpoint string, apiKey string, deployment string, apiVersion string, strictToolSchema bool, retry providerRetryPolicy, httpClient *http.Client) (*openAIProvider, error) {
+	baseURL, err := azureOpenAIBaseURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	apiVersion = strings.TrimSpace(apiVersion)
+	if apiVersion == "" {
+		apiVersion = config.DefaultAzureOpenAIAPIVersion
+	}
+	opts := []ooption.RequestOption{
+		ooption.WithBaseURL(baseURL),
+		ooption.WithQuery("api-version", apiVersion),
+		// Drop any bearer token picked up from OPENAI_API_KEY; Azure rejects mixed auth.
+		ooption.WithHeaderDel("authorization"),
+		ooption.WithHeader("api-key", strings.TrimSpace(apiKey)),
+		ooption.WithMaxRetries(0),
+	}
+	if deployment = strings.TrimSpace(deployment); deployment != "" {
+		opts = append(opts, ooption.WithJSONSet("model", deployment))
+	}
+	if httpClient != nil {
+		opts = append(opts, ooption.WithHTTPClient(httpClient))
+	}
+	return &openAIProvider{
+		client:           openai.NewClient(opts...),
+		strictToolSchema: strictToolSchema,
+		retry:            retry,
+	}, nil
+}
+
+// azureOpenAIBaseURL turns an Azure resource endpoint into the SDK base URL ending in "/openai/".
+func azureOpenAIBaseURL(endpoint string) (string, error) {
+	endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+	if endpoint == "" {
+		return "", errors.New("missing azure openai endpoint (base_url)")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u == nil || strings.TrimSpace(u.Host) == "" {
+		return "", fmt.Errorf("invalid azure openai endpoint %q", endpoint)
+	}
+	if !strings.HasSuffix(strings.ToLower(endpoint), "/openai") {
+		endpoint += "/openai"
+	}
+	return endpoint + "/", nil
+}
+
 func newProviderAdapter(providerType string, baseURL string, apiKey string, strictToolSchemaOverride *bool) (Provider, error) {
+	return newProviderAdapterWithHTTPClient(providerType, baseURL, apiKey, strictToolSchemaOverride, nil)
+}
+
+// newProviderAdapterWithHTTPClient builds the adapter on httpClient, or on the SDK default client when it is nil.
+func newProviderAdapterWithHTTPClient(providerType string, baseURL string, apiKey string, strictToolSchemaOverride *bool, httpClient *http.Client) (Provider, error) {
 	providerType = strings.ToLower(strings.TrimSpace(providerType))
-	if strings.TrimSpace(apiKey) == "" {
+	if strings.TrimSpace(apiKey) == "" && config.AIProviderTypeRequiresAPIKey(providerType) {
 		return nil, errors.New("missing provider api key")
 	}
 	strictToolSchema := resolveStrictToolSchema(providerType, baseURL, strictToolSchemaOverride)
+	// SDK-level retries are disabled: transient failures are retried by withProviderRetry, which can report them.
+	retry := defaultProviderRetryPolicy()
+	withHTTPClient := func(opts ...ooption.RequestOption) []ooption.RequestOption {
+		if httpClient != nil {
+			opts = append(opts, ooption.WithHTTPClient(httpClient))
+		}
+		return opts
+	}
 	switch providerType {
 	case "openai":
-		opts := []ooption.RequestOption{ooption.WithAPIKey(strings.TrimSpace(apiKey))}
+		opts := []ooption.RequestOption{ooption.WithAPIKey(strings.TrimSpace(apiKey)), ooption.WithMaxRetries(0)}
 		if strings.TrimSpace(baseURL) != "" {
 			opts = append(opts, ooption.WithBaseURL(strings.TrimSpace(baseURL)))
 		}
 		return &openAIProvider{
-			client:           openai.NewClient(opts...),
+			client:           openai.NewClient(withHTTPClient(opts...)...),
 			strictToolSchema: strictToolSchema,
+			retry:            retry,
 		}, nil
 	case "openai_compatible":
-		opts := []ooption.RequestOption{ooption.WithAPIKey(strings.TrimSpace(apiKey))}
+		opts := []ooption.RequestOption{ooption.WithAPIKey(strings.TrimSpace(apiKey)), ooption.WithMaxRetries(0)}
 		if strings.TrimSpace(baseURL) != "" {
 			opts = append(opts, ooption.WithBaseURL(strings.TrimSpace(baseURL)))
 		}
 		return &openAIProvider{
-			client:           openai.NewClient(opts...),
+			client:           openai.NewClient(withHTTPClient(opts...)...),
 			strictToolSchema: strictToolSchema,
+			retry:            retry,
 		}, nil
-	case "chatglm", "deepseek", "qwen":
-		opts := []ooption.RequestOption{ooption.WithAPIKey(strings.TrimSpace(apiKey))}
+	case "chatglm", "qwen":
+		opts := []ooption.RequestOption{ooption.WithAPIKey(strings.TrimSpace(apiKey)), ooption.WithMaxRetries(0)}
 		if strings.TrimSpace(baseURL) != "" {
 			opts = append(opts, ooption.WithBaseURL(strings.TrimSpace(baseURL)))
 		}
 		return &openAIProvider{
-			client:           openai.NewClient(opts...),
+			client:           openai.NewClient(withHTTPClient(opts...)...),
 			strictToolSchema: strictToolSchema,
+			retry:            retry,
 		}, nil
+	case "deepseek":
+		opts := []ooption.RequestOption{ooption.WithAPIKey(strings.TrimSpace(apiKey)), ooption.WithMaxRetries(0)}
+		if strings.TrimSpace(baseURL) != "" {
+			opts = append(opts, ooption.WithBaseURL(strings.TrimSpace(baseURL)))
+		}
+		return &deepseekProvider{moonshotProvider{
+			client:           openai.NewClient(withHTTPClient(opts...)...),
+			strictToolSchema: strictToolSchema,
+			callIDPrefix:     "deepseek_call",
+			retry:            retry,
+		}}, nil
 	case "moonshot":
-		opts := []ooption.RequestOption{ooption.WithAPIKey(strings.TrimSpace(apiKey))}
+		opts := []ooption.RequestOption{ooption.WithAPIKey(strings.TrimSpace(apiKey)), ooption.WithMaxRetries(0)}
 		if strings.TrimSpace(baseURL) != "" {
 			opts = append(opts, ooption.WithBaseURL(strings.TrimSpace(baseURL)))
 		}
 		return &moonshotProvider{
-			client:           openai.NewClient(opts...),
+			client:           openai.NewClient(withHTTPClient(opts...)...),
 			strictToolSchema: strictToolSchema,
+			retry:            retry,
 		}, nil
+	case "ollama":
+		// Ollama ignores the key, but the client always sends one; use a placeholder when unset.
+		key := strings.TrimSpace(apiKey)
+		if key == "" {
+			key = "ollama"
+		}
+		endpoint := strings.TrimSpace(baseURL)
+		if endpoint == "" {
+			endpoint = config.DefaultOllamaBaseURL
+		}
+		return &ollamaProvider{moonshotProvider{
+			client:           openai.NewClient(withHTTPClient(ooption.WithAPIKey(key), ooption.WithBaseURL(endpoint), ooption.WithMaxRetries(0))...),
+			strictToolSchema: strictToolSchema,
+			callIDPrefix:     "ollama_call",
+			retry:            retry,
+		}}, nil
+	case "mistral":
+		endpoint := strings.TrimSpace(baseURL)
+		if endpoint == "" {
+			endpoint = config.DefaultMistralBaseURL
+		}
+		return &mistralProvider{moonshotProvider{
+			client: openai.NewClient(withHTTPClient(
+				ooption.WithAPIKey(strings.TrimSpace(apiKey)),
+				ooption.WithBaseURL(endpoint),
+				ooption.WithMaxRetries(0),
+				// la Plateforme does not accept stream_options; it reports usage on the final chunk anyway.
+				ooption.WithJSONDel("stream_options"),
+			)...),
+			strictToolSchema: strictToolSchema,
+			callIDPrefix:     "mistral_call",
+			rewriteMessages:  normalizeMistralChatMessages,
+			seedField:        "random_seed",
+			retry:            retry,
+		}}, nil
+	case "azure_openai":
+		return newAzureOpenAIProvider(baseURL, apiKey, "", "", strictToolSchema, retry, httpClient)
+	case "bedrock":
+		return newBedrockProvider(context.Background(), "", baseURL, retry, httpClient)
 	case "anthropic":
-		opts := []aoption.RequestOption{aoption.WithAPIKey(strings.TrimSpace(apiKey))}
+		opts := []aoption.RequestOption{aoption.WithAPIKey(strings.TrimSpace(apiKey)), aoption.WithMaxRetries(0)}
 		if strings.TrimSpace(baseURL) != "" {
 			opts = append(opts, aoption.WithBaseURL(strings.TrimSpace(baseURL)))
 		}
-		return &anthropicProvider{client: anthropic.NewClient(opts...)}, nil
+		if httpClient != nil {
+			opts = append(opts, aoption.WithHTTPClient(httpClient))
+		}
+		return &anthropicProvider{client: anthropic.NewClient(opts...), retry: retry}, nil
 	default:
 		return nil, fmt.Errorf("unsupported provider type %q", providerType)
 	}
@@ -1648,8 +2026,12 @@ func shouldUseStrictOpenAIToolSchema(providerType string, baseURL string) bool {
 		// Compatible gateways vary widely in strict function schema support; disable strict mode by default.
 		return false
 	}
-	if providerType == "moonshot" {
-		// Moonshot uses a chat-completions-compatible endpoint; strict schema is not guaranteed.
+	if providerType == "moonshot" || providerType == "ollama" || providerType == "mistral" {
+		// Moonshot, Ollama, and Mistral use chat-completions-compatible endpoints; strict schema is not guaranteed.
+		return false
+	}
+	if providerType == "azure_openai" {
+		// Strict schema support depends on the deployed model version and api-version.
 		return false
 	}
 	if providerType != "openai" {
@@ -1694,8 +2076,26 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 	if !capability.SupportsReasoningTokens {
 		req.Options.ThinkingBudgetTokens = 0
 	}
+	sampling := resolveTurnSampling(capability, req.Options.Temperature, req.Options.TopP)
+	req.Options.Temperature = sampling.Temperature
+	req.Options.TopP = sampling.TopP
+	if req.Options.Seed != nil && !providerSupportsSeed(providerType) {
+		r.debug("ai.run.seed_unsupported", "provider_type", providerType, "model", modelName, "seed", *req.Options.Seed)
+		r.persistRunEvent("seed_unsupported", RealtimeStreamKindLifecycle, map[string]any{
+			"provider_type": providerType,
+			"model":         modelName,
+			"seed":          *req.Options.Seed,
+		})
+		req.Options.Seed = nil
+	}
 	if !capability.SupportsStrictJSONSchema && strings.EqualFold(strings.TrimSpace(req.Options.ResponseFormat), "json_schema") {
+		r.debug("ai.run.json_schema_downgraded",
+			"provider_type", providerType,
+			"model", modelName,
+			"has_schema", len(req.Options.ResponseJSONSchema) > 0,
+		)
 		req.Options.ResponseFormat = "json_object"
+		req.Options.ResponseJSONSchema = nil
 	}
 
 	maxSteps := req.Options.MaxSteps
@@ -1705,10 +2105,25 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 	if maxSteps > nativeHardMaxSteps {
 		maxSteps = nativeHardMaxSteps
 	}
+	loopProfile, knownLoopProfile := resolveLoopProfile(req.Options.LoopProfile)
+	if !knownLoopProfile {
+		r.debug("ai.run.loop_profile_unknown", "loop_profile", sanitizeLogText(req.Options.LoopProfile, 80))
+	}
+	req.Options.LoopProfile = loopProfile.ID
+	promptProfile, knownPromptProfile := resolvePromptProfile(req.Options.PromptProfile)
+	if !knownPromptProfile {
+		r.debug("ai.run.prompt_profile_unknown", "prompt_profile", sanitizeLogText(req.Options.PromptProfile, 80))
+	}
+	req.Options.PromptProfile = promptProfile.ID
+	r.promptProfile = promptProfile.ID
 	maxNoToolRounds := req.Options.MaxNoToolRounds
 	if maxNoToolRounds <= 0 {
-		maxNoToolRounds = nativeDefaultNoToolRounds
+		maxNoToolRounds = loopProfile.MaxNoToolRounds
 	}
+	if req.Options.CompactionThreshold <= 0 {
+		req.Options.CompactionThreshold = loopProfile.CompactThreshold
+	}
+	req.Options.CompactionStrategy = normalizeCompactionStrategy(req.Options.CompactionStrategy)
 
 	mode := normalizeRunMode(req.Options.Mode, r.cfg.EffectiveMode())
 	req.Options.Mode = mode
@@ -1730,10 +2145,83 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 
 	execCtx := ctx
 
-	adapter, err := newProviderAdapter(providerType, strings.TrimSpace(providerCfg.BaseURL), strings.TrimSpace(apiKey), providerCfg.StrictToolSchema)
+	circuitPolicy := providerCircuitPolicyFromConfig(r.cfg)
+	circuitTrial, circuitTransition, err := r.providerCircuits.admit(providerCfg.ID, circuitPolicy)
+	if err != nil {
+		return r.failRun("", err)
+	}
+	if circuitTransition != nil {
+		r.persistProviderCircuitTransition(providerType, *circuitTransition)
+	}
+	if circuitTrial {
+		defer r.providerCircuits.release(providerCfg.ID)
+	}
+
+	adapter, err := newProviderAdapterForConfig(providerCfg, strings.TrimSpace(apiKey), r.providerHTTP.get(providerCfg, strings.TrimSpace(apiKey)))
 	if err != nil {
 		return r.failRun("Failed to initialize provider adapter", err)
 	}
+	if r.tokenCounter == nil {
+		r.tokenCounter = newProviderTokenCounter(providerType, strings.TrimSpace(providerCfg.BaseURL), strings.TrimSpace(apiKey))
+	}
+	configureProviderRetry(adapter, providerRetryPolicy{
+		MaxRetries:     r.cfg.EffectiveProviderRetryMaxRetries(),
+		InitialBackoff: time.Duration(r.cfg.EffectiveProviderRetryInitialBackoffMS()) * time.Millisecond,
+		OnRetry: func(attempt providerRetryAttempt) {
+			errText := sanitizeLogText(errorString(attempt.Err), 240)
+			r.debug("ai.provider.retry",
+				"provider_type", providerType,
+				"model", attempt.Model,
+				"attempt", attempt.Attempt,
+				"status", attempt.StatusCode,
+				"backoff_ms", attempt.Backoff.Milliseconds(),
+			)
+			r.persistRunEvent("provider.retry", RealtimeStreamKindLifecycle, map[string]any{
+				"provider_id":   strings.TrimSpace(providerCfg.ID),
+				"provider_type": providerType,
+				"model":         attempt.Model,
+				"attempt":       attempt.Attempt,
+				"max_retries":   attempt.MaxRetries,
+				"status":        attempt.StatusCode,
+				"backoff_ms":    attempt.Backoff.Milliseconds(),
+				"retry_after":   attempt.RetryAfter,
+				"error":         errText,
+			})
+		},
+	})
+	adapter = wrapProviderForSession(adapter, providerCfg.EffectiveToolCallFormat(), r.providerSession, r.providerRecorder, r.providerReplays)
+	if stallMS := providerCfg.EffectiveStreamStallTimeoutMS(); stallMS > 0 {
+		stallProviderID := strings.TrimSpace(providerCfg.ID)
+		adapter = &streamStallProvider{
+			inner:    adapter,
+			interval: time.Duration(stallMS) * time.Millisecond,
+			onStall: func(stall providerStreamStall) {
+				r.persistProviderStreamStall(stallProviderID, providerType, stall)
+			},
+		}
+	}
+	if r.serviceMetrics != nil {
+		adapter = &metricsProvider{inner: adapter, metrics: r.serviceMetrics, providerType: providerType}
+	}
+	if r.providerCircuits != nil {
+		adapter = &circuitBreakerProvider{
+			inner:      adapter,
+			breakers:   r.providerCircuits,
+			providerID: strings.TrimSpace(providerCfg.ID),
+			policy:     circuitPolicy,
+			onTransition: func(transition providerCircuitTransition) {
+				r.persistProviderCircuitTransition(providerType, transition)
+			},
+		}
+	}
+	if r.tracer != nil {
+		adapter = &tracedProvider{
+			inner:        adapter,
+			tracer:       r.tracer,
+			providerID:   strings.TrimSpace(providerCfg.ID),
+			providerType: providerType,
+		}
+	}
 
 	// Configure web search enablement once per run (tools are fixed for a given run).
 	// prefer_openai: prefer OpenAI built-in web search when using official OpenAI endpoints; otherwise use Brave web.search.
@@ -1766,6 +2254,7 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 		}
 	}
 	r.openAIWebSearchEnabled = enableOpenAIWebSearch
+	r.imageOutputEnabled = capability.SupportsImageOutput
 	r.webSearchToolEnabled = enableWebSearchTool
 	r.persistRunEvent("web_search.config", RealtimeStreamKindLifecycle, map[string]any{
 		"requested":         webSearchProvider,
@@ -1778,7 +2267,7 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 		"provider_base_url": strings.TrimSpace(providerCfg.BaseURL),
 	})
 
-	r.persistRunEvent("native.runtime.start", RealtimeStreamKindLifecycle, map[string]any{
+	runtimeStart := map[string]any{
 		"provider_type":                providerType,
 		"model":                        modelName,
 		"max_steps":                    maxSteps,
@@ -1787,7 +2276,28 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 		"execution_contract":           executionContract,
 		"complexity":                   taskComplexity,
 		"interaction_contract_enabled": normalizeInteractionContract(req.InteractionContract).Enabled,
-	})
+		"loop_profile":                 loopProfile.ID,
+		"prompt_profile":               promptProfile.ID,
+		"max_wall_time_ms":             r.maxWallTime.Milliseconds(),
+		"wall_time_limit_ms":           r.wallTimeLimit.Milliseconds(),
+		"max_idle_time_ms":             r.idleTimeout.Milliseconds(),
+	}
+	if req.Options.Seed != nil {
+		runtimeStart["seed"] = *req.Options.Seed
+	}
+	if rule := req.Options.RoutingRule.eventPayload(); rule != nil {
+		runtimeStart["routing_rule"] = rule
+	}
+	toolAllowlist, toolAllowlistBuiltinOnly, hasToolAllowlist := r.resolveToolAllowlist(mode)
+	if hasToolAllowlist {
+		names := make([]string, 0, len(toolAllowlist))
+		for name := range toolAllowlist {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		runtimeStart["tool_allowlist"] = names
+	}
+	r.persistRunEvent("native.runtime.start", RealtimeStreamKindLifecycle, runtimeStart)
 
 	if intent == RunIntentSocial {
 		return r.runNativeSocial(execCtx, adapter, providerCfg, providerType, modelName, mode, req)
@@ -1805,50 +2315,54 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 	if err := registerBuiltInTools(registry, r); err != nil {
 		return r.failRun("Failed to initialize tool registry", err)
 	}
+	if err := registerExternalTools(registry, r, req.Options.ExternalTools); err != nil {
+		return r.failRun("Failed to initialize tool registry", err)
+	}
 	protocolProfile := resolveRunProtocolProfile(capability)
 	r.persistRunEvent("protocol.profile.resolved", RealtimeStreamKindLifecycle, protocolProfile.eventPayload())
 	modeFilter := newModeToolFilter(r.cfg, protocolProfile, !r.noUserInteraction)
-	if len(r.toolAllowlist) > 0 {
-		allow := make(map[string]struct{}, len(r.toolAllowlist))
-		for name := range r.toolAllowlist {
-			name = strings.TrimSpace(name)
-			if name == "" {
-				continue
-			}
-			allow[name] = struct{}{}
-		}
-		modeFilter = allowlistModeToolFilter{base: modeFilter, allowlist: allow}
+	if hasToolAllowlist {
+		modeFilter = allowlistModeToolFilter{base: modeFilter, allowlist: toolAllowlist, builtinOnly: toolAllowlistBuiltinOnly}
 	}
 	scheduler, err := NewCoreToolScheduler(registry, modeFilter)
 	if err != nil {
 		return r.failRun("Failed to initialize tool scheduler", err)
 	}
+	scheduler.rateLimit = r.newToolRateLimitBinding()
+	if scheduler.approval, err = r.newToolApprovalBinding(); err != nil {
+		return r.failRun("Failed to initialize tool scheduler", err)
+	}
+	scheduler.tracer = r.tracer
+	scheduler.enableToolCallTimeouts(r.toolCallTimeout, func(timeout toolCallTimeout) {
+		r.persistRunEvent("tool.timeout", RealtimeStreamKindLifecycle, map[string]any{
+			"tool_id":    timeout.ToolID,
+			"tool_name":  timeout.ToolName,
+			"elapsed_ms": timeout.Elapsed.Milliseconds(),
+			"timeout_ms": timeout.Timeout.Milliseconds(),
+		})
+	})
+	if req.Options.AllowParallelToolCalls {
+		scheduler.enableParallelToolCalls(defaultParallelToolCallConcurrency, func(batch toolParallelDispatch) {
+			r.persistRunEvent("tool.parallel_dispatch", RealtimeStreamKindLifecycle, map[string]any{
+				"tool_ids":        batch.ToolIDs,
+				"tool_names":      batch.ToolNames,
+				"calls":           len(batch.ToolIDs),
+				"concurrency":     batch.Concurrency,
+				"max_concurrency": batch.Limit,
+			})
+		})
+	}
 	capabilityContract := resolveRunCapabilityContract(r, protocolProfile, scheduler.ActiveTools(mode), req.ModelCapability.SupportsAskUserQuestionBatches)
 	r.persistRunEvent("capability.contract.resolved", RealtimeStreamKindLifecycle, capabilityContract.eventPayload())
 	r.ensureSkillManager()
 
-	loop := AgentLoop{
-		runID:  strings.TrimSpace(r.id),
-		parent: nil,
-		depth:  0,
-		budget: LoopBudget{MaxSteps: maxSteps},
-		deriveBudget: func(parent LoopBudget, hint BudgetHint) LoopBudget {
-			child := parent
-			if hint.MaxSteps > 0 && hint.MaxSteps < child.MaxSteps {
-				child.MaxSteps = hint.MaxSteps
-			}
-			if child.MaxSteps <= 0 {
-				child.MaxSteps = nativeDefaultMaxSteps
-			}
-			return child
-		},
-	}
-	_ = loop
+	r.agentLoop = newAgentLoop(r.id, r.parentLoop, LoopBudget{MaxSteps: maxSteps})
 
 	if strings.TrimSpace(req.ContextPack.Objective) != "" {
 		taskObjective = strings.TrimSpace(req.ContextPack.Objective)
 	}
 	state := newRuntimeState(taskObjective)
+	toolCallLimits := normalizeToolCallLimits(req.Options.ToolCallLimits)
 	state.ExecutionContract = executionContract
 	state.TodoPolicy = normalizeTodoPolicy(req.Options.TodoPolicy)
 	state.MinimumTodoItems = normalizeMinimumTodoItems(state.TodoPolicy, req.Options.MinimumTodoItems)
@@ -1863,6 +2377,9 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 			"todo_version":     state.TodoSnapshotVersion,
 		})
 	}
+	r.hydrateScratchpadRuntimeState(execCtx, &state)
+	req, historyTrim := applyRunHistoryWindow(req)
+	r.persistHistoryTrim(historyTrim)
 	messages := buildMessagesForRun(req)
 	resumeState, resumeStateErr := r.loadProviderTurnResumeState(execCtx, providerCfg, providerType, modelName)
 	if resumeStateErr != nil {
@@ -1894,16 +2411,37 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 	}
 	inputContextLimit := resolveInputContextLimit(contextWindow, req.Options.MaxInputTokens)
 	windowBasedThreshold := deriveModelWindowCompactionThreshold(contextWindow, inputContextLimit)
+	compactionCfg := resolveCompactionConfig(req.Options.Compaction, contextWindow)
 	runtimeCompactor := contextcompactor.New(nil)
+	var compactionSummarize compactionSummarizer
+	if req.Options.CompactionStrategy == CompactionStrategyModelSummary {
+		compactionSummarize = newProviderCompactionSummarizer(adapter, modelName)
+	}
 
 	recoveryCount := 0
 	noToolRounds := 0
 	todoSetupNudges := 0
 	emptyTaskCompleteRejects := 0
+	guardAskUserBounces := 0
+	askUserBounceExceeded := false
+	completionSelfChecks := 0
+	completionQualityRejects := 0
 	lastSignature := ""
 	signatureHits := map[string]int{}
+	streamHealth := newStreamHealthMonitor()
 	askUserRejectionHits := map[string]int{}
 	failedSignatures := map[string]bool{}
+	if restored, ok := r.hydrateThreadRuntimeState(execCtx, &state, failedSignatures); ok {
+		r.persistRunEvent("runtime_state.hydrated", RealtimeStreamKindLifecycle, map[string]any{
+			"completed_action_facts": len(restored.CompletedActionFacts),
+			"blocked_action_facts":   len(restored.BlockedActionFacts),
+			"no_progress_signatures": len(restored.NoProgressSignatures),
+			"failed_signatures":      len(restored.FailedSignatures),
+		})
+	}
+	defer func() {
+		r.persistThreadRuntimeState(state, failedSignatures)
+	}()
 	mistakeWindow := make([]int, 0, 8)
 	exceptionOverlay := ""
 	isFirstRound := true
@@ -2065,6 +2603,20 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 		exceptionOverlay = recoveryOverlay
 		isFirstRound = false
 	}
+	recordGuardAskUserBounce := func(step int, source string, gateReason string) {
+		guardAskUserBounces++
+		if guardAskUserBounces <= nativeAskUserBounceLimit || askUserBounceExceeded {
+			return
+		}
+		askUserBounceExceeded = true
+		r.persistRunEvent("guard.ask_user_bounce", RealtimeStreamKindLifecycle, map[string]any{
+			"step_index":  step,
+			"source":      source,
+			"gate_reason": gateReason,
+			"count":       guardAskUserBounces,
+			"limit":       nativeAskUserBounceLimit,
+		})
+	}
 	tryAskUser := func(step int, signal askUserSignal, source string) (bool, error) {
 		signal = normalizeAskUserSignal(signal)
 		if signal.Question == "" {
@@ -2134,6 +2686,10 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 				if guardPassed {
 					return true, endAskUser(step, guardSignal, "ask_user_contract_loop")
 				}
+				recordGuardAskUserBounce(step, "ask_user_contract_loop", guardReason)
+			}
+			if source != "model_signal" {
+				recordGuardAskUserBounce(step, source, askReason)
 			}
 			rejectAskUser(source, askReason)
 			return false, nil
@@ -2181,6 +2737,32 @@ func (r *run) runNative(ctx context.Context, req RunRequest, providerCfg config.
 		})
 	}
 
+	allowObjectiveClarify := !r.noUserInteraction && !structuredResponseContinuation
+	if resolution, ok := r.resolveTaskObjective(execCtx, adapter, modelName, req, taskObjective, taskComplexity, allowObjectiveClarify); ok {
+		if resolution.Clarify {
+			r.persistRunEvent("objective.clarify_requested", RealtimeStreamKindLifecycle, map[string]any{
+				"complexity":    taskComplexity,
+				"objective_len": len([]rune(strings.TrimSpace(taskObjective))),
+			})
+			signal := defaultGuardAskUserSignal("What exactly should I accomplish in this task?", nil, objectiveClarifyAskUserSource)
+			return endAskUser(0, signal, objectiveClarifyAskUserSource)
+		}
+		taskObjective = resolution.Objective
+		state.ActiveObjectiveDigest = resolution.Objective
+		payload := map[string]any{
+			"source":        resolution.Source,
+			"objective_len": len([]rune(resolution.Objective)),
+		}
+		if resolution.Error != "" {
+			payload["extraction_error"] = resolution.Error
+		}
+		r.persistRunEvent("objective.derived", RealtimeStreamKindLifecycle, payload)
+	}
+
+	costBudgetExceeded := false
+	wallTimeExceeded := false
+	stopStep := nativeHardMaxSteps
+
 mainLoop:
 	for step := 0; ; step++ {
 		// Safety net — absolute maximum to prevent infinite loop bugs.
@@ -2189,25 +2771,44 @@ mainLoop:
 		if step >= nativeHardMaxSteps {
 			break
 		}
+		// The cost budget is checked before the next model call so the tripping turn's tools still finish.
+		if costBudgetExceeded {
+			stopStep = step
+			break
+		}
+		// The per-run wall time is checked at the same point; the hard deadline leaves room for the summary turn.
+		if r.wallTimeExceeded() {
+			wallTimeExceeded = true
+			stopStep = step
+			break
+		}
+		// Guard-originated ask_user kept bouncing off the gate; summarize instead of thrashing.
+		if askUserBounceExceeded {
+			stopStep = step
+			break
+		}
 		r.touchActivity()
 		if r.finalizeIfContextCanceledWithRuntimeCloseout(execCtx, step, state, taskComplexity, req.Options.Mode, capabilityContract.ProtocolProfile, req.Options.RequireUserConfirmOnTaskComplete) {
 			return nil
 		}
 
 		activeTools := scheduler.ActiveTools(mode)
+		r.setActiveToolNames(activeTools)
 		systemPrompt := r.buildLayeredSystemPrompt(taskObjective, mode, taskComplexity, step, maxSteps, isFirstRound, activeTools, state, exceptionOverlay, capabilityContract)
+		r.persistRunSystemPrompt(systemPrompt, runSystemPromptInfo{StepIndex: step, Mode: mode, Complexity: taskComplexity, ExceptionOverlay: exceptionOverlay})
 		turnMessages := composeTurnMessages(systemPrompt, messages)
 		turnReq := TurnRequest{
-			Model:            modelName,
-			Messages:         turnMessages,
-			Tools:            activeTools,
-			Budgets:          TurnBudgets{MaxSteps: maxSteps, MaxInputTokens: req.Options.MaxInputTokens, MaxOutputToken: req.Options.MaxOutputTokens, MaxCostUSD: req.Options.MaxCostUSD},
-			ModeFlags:        ModeFlags{Mode: mode, ReasoningOnly: req.Options.ReasoningOnly},
-			ProviderControls: ProviderControls{ThinkingBudgetTokens: req.Options.ThinkingBudgetTokens, CacheControl: req.Options.CacheControl, ResponseFormat: req.Options.ResponseFormat, Temperature: req.Options.Temperature, TopP: req.Options.TopP},
-			WebSearchEnabled: r.openAIWebSearchEnabled,
+			Model:              modelName,
+			Messages:           turnMessages,
+			Tools:              activeTools,
+			Budgets:            TurnBudgets{MaxSteps: maxSteps, MaxInputTokens: req.Options.MaxInputTokens, MaxOutputToken: req.Options.MaxOutputTokens, MaxCostUSD: req.Options.MaxCostUSD},
+			ModeFlags:          ModeFlags{Mode: mode, ReasoningOnly: req.Options.ReasoningOnly},
+			ProviderControls:   ProviderControls{ThinkingBudgetTokens: req.Options.ThinkingBudgetTokens, CacheControl: req.Options.CacheControl, ResponseFormat: req.Options.ResponseFormat, ResponseJSONSchema: req.Options.ResponseJSONSchema, Temperature: req.Options.Temperature, TopP: req.Options.TopP, Seed: req.Options.Seed, ParallelToolCalls: req.Options.AllowParallelToolCalls},
+			WebSearchEnabled:   r.openAIWebSearchEnabled,
+			ImageOutputEnabled: r.imageOutputEnabled,
 		}
 
-		estimateTokens, estimateSource := estimateTurnTokens(providerType, turnReq)
+		estimateTokens, estimateSource := countTurnTokens(execCtx, r.tokenCounter, providerType, turnReq)
 		state.EstimateSource = estimateSource
 		pressure := float64(estimateTokens) / float64(inputContextLimit)
 		compactThreshold := resolveCompactionThreshold(req.Options.CompactionThreshold, contextWindow, inputContextLimit)
@@ -2240,10 +2841,24 @@ mainLoop:
 				compactApplied = true
 			}
 
+			accountSummaryUsage := func(usage TurnUsage) {
+				if usage.InputTokens > 0 || usage.OutputTokens > 0 {
+					_ = r.accountTurnCost(step, providerCfg.ID, modelName, usage, req.Options.MaxCostUSD)
+				}
+			}
+
 			if req.ContextPack.ThreadID != "" {
 				compactStrategy = compactStrategy + "+prompt_pack"
 				targetTokens := inputContextLimit
-				compressed, changed, _, compactErr := runtimeCompactor.CompactPromptPack(execCtx, strings.TrimSpace(r.endpointID), targetTokens, req.ContextPack)
+				var compressed contextmodel.PromptPack
+				var changed bool
+				var compactErr error
+				if compactionSummarize != nil {
+					compactStrategy = compactStrategy + "+model_summary"
+					compressed, changed, _, compactErr = runtimeCompactor.CompactPromptPackWithSummarizer(execCtx, strings.TrimSpace(r.endpointID), targetTokens, req.ContextPack, promptPackSummarizer(compactionSummarize, accountSummaryUsage))
+				} else {
+					compressed, changed, _, compactErr = runtimeCompactor.CompactPromptPack(execCtx, strings.TrimSpace(r.endpointID), targetTokens, req.ContextPack)
+				}
 				if compactErr == nil && changed {
 					req.ContextPack = compressed
 					messages = buildMessagesFromPromptPack(req.ContextPack, req.Input.Text)
@@ -2261,14 +2876,38 @@ mainLoop:
 						"error":               sanitizeLogText(compactErr.Error(), 240),
 					})
 					compactStrategy = compactStrategy + "+round_boundary_fallback"
-					messages, compactStats = compactMessages(messages)
+					messages, compactStats = compactMessages(messages, compactionCfg)
 					if len(messages) != beforeCount || compactStats.hasChanges() {
 						compactApplied = true
 					}
 				}
+			} else if compactionSummarize != nil {
+				compactStrategy = compactStrategy + "+model_summary"
+				summarized, summaryStats, summaryUsage, summaryErr := compactMessagesWithModelSummary(execCtx, messages, compactionSummarize)
+				accountSummaryUsage(summaryUsage)
+				if summaryErr != nil {
+					r.emitContextCompactionEvent("context.compaction.failed", map[string]any{
+						"compaction_id":       compactionID,
+						"step_index":          step,
+						"strategy":            CompactionStrategyModelSummary,
+						"estimate_tokens":     beforeEstimateTokens,
+						"context_window":      contextWindow,
+						"context_limit":       inputContextLimit,
+						"pressure":            pressure,
+						"effective_threshold": compactThreshold,
+						"error":               sanitizeLogText(summaryErr.Error(), 240),
+					})
+					compactStrategy = compactStrategy + "+round_boundary_fallback"
+					messages, compactStats = compactMessages(messages, compactionCfg)
+				} else {
+					messages, compactStats = summarized, summaryStats
+				}
+				if len(messages) != beforeCount || compactStats.hasChanges() {
+					compactApplied = true
+				}
 			} else {
 				compactStrategy = compactStrategy + "+round_boundary"
-				messages, compactStats = compactMessages(messages)
+				messages, compactStats = compactMessages(messages, compactionCfg)
 				if len(messages) != beforeCount || compactStats.hasChanges() {
 					compactApplied = true
 				}
@@ -2296,8 +2935,18 @@ mainLoop:
 			}
 			turnMessages = composeTurnMessages(systemPrompt, messages)
 			turnReq.Messages = turnMessages
-			afterEstimateTokens, _ := estimateTurnTokens(providerType, turnReq)
+			afterEstimateTokens, _ := countTurnTokens(execCtx, r.tokenCounter, providerType, turnReq)
 			if compactApplied {
+				r.persistRunEvent("context.compact", RealtimeStreamKindContext, map[string]any{
+					"compaction_id":          compactionID,
+					"step_index":             step,
+					"compaction_strategy":    req.Options.CompactionStrategy,
+					"pipeline":               compactStrategy,
+					"messages_before":        beforeCount,
+					"messages_after":         len(messages),
+					"estimate_tokens_before": beforeEstimateTokens,
+					"estimate_tokens_after":  afterEstimateTokens,
+				})
 				r.emitContextCompactionEvent("context.compaction.applied", map[string]any{
 					"compaction_id":              compactionID,
 					"step_index":                 step,
@@ -2317,6 +2966,7 @@ mainLoop:
 					"tool_pruned_tokens_after":   pruneStats.PrunedTokensAfter,
 					"tool_result_prune_budget":   nativeToolResultPruneBudget,
 					"tool_result_protected_from": pruneStats.ProtectedStartIndex,
+					"keep_recent_messages":       compactionCfg.KeepRecentMessages,
 				})
 			} else {
 				r.emitContextCompactionEvent("context.compaction.skipped", map[string]any{
@@ -2365,7 +3015,7 @@ mainLoop:
 				"sent_messages":        len(turnReq.Messages),
 			})
 		}
-		estimateTokens, estimateSource = estimateTurnTokens(providerType, turnReq)
+		estimateTokens, estimateSource = countTurnTokens(execCtx, r.tokenCounter, providerType, turnReq)
 		state.EstimateSource = estimateSource
 		r.emitContextUsageEvent(contextUsageEventInput{
 			StepIndex:             step,
@@ -2383,15 +3033,34 @@ mainLoop:
 		})
 
 		turnTextSeen := false
+		repeatedDeltaTripped := false
 		runTurn := func(req TurnRequest) (TurnResult, error) {
+			r.metrics.recordAttempt()
 			endBusy := r.beginBusy()
-			result, err := adapter.StreamTurn(execCtx, req, func(event StreamEvent) {
+			streamHealth.resetDeltas()
+			turnCtx, cancelTurn := context.WithCancel(r.withTraceStepIndex(execCtx, step))
+			defer cancelTurn()
+			result, err := adapter.StreamTurn(turnCtx, req, func(event StreamEvent) {
 				switch event.Type {
 				case StreamEventTextDelta:
+					if repeatedDeltaTripped {
+						return
+					}
 					if strings.TrimSpace(event.Text) != "" {
 						turnTextSeen = true
 						r.touchActivity()
 						_ = r.appendTextDelta(event.Text)
+						if repeats, tripped := streamHealth.observeDelta(event.Text); tripped {
+							// The model is stuck emitting the same chunk; stop the stream instead of paying for more of it.
+							repeatedDeltaTripped = true
+							r.persistRunEvent("guard.repeated_delta", RealtimeStreamKindLifecycle, map[string]any{
+								"step_index": step,
+								"delta":      truncateRunes(event.Text, 200),
+								"repeats":    repeats,
+								"threshold":  repeatedDeltaGuardThreshold,
+							})
+							cancelTurn()
+						}
 					}
 				case StreamEventThinkingDelta:
 					if strings.TrimSpace(event.Text) != "" {
@@ -2403,6 +3072,12 @@ mainLoop:
 					if event.ToolCall != nil {
 						_ = scheduler.HandlePartial(execCtx, *event.ToolCall)
 					}
+				case StreamEventImage:
+					if event.Image != nil {
+						turnTextSeen = true
+						r.touchActivity()
+						r.appendGeneratedImage(execCtx, *event.Image)
+					}
 				}
 			})
 			endBusy()
@@ -2421,7 +3096,7 @@ mainLoop:
 			r.setProviderContinuationCandidate(threadstore.ThreadProviderContinuation{})
 			turnReq.Messages = baseTurnMessages
 			turnReq.ProviderControls.PreviousResponseID = ""
-			estimateTokens, estimateSource = estimateTurnTokens(providerType, turnReq)
+			estimateTokens, estimateSource = countTurnTokens(execCtx, r.tokenCounter, providerType, turnReq)
 			state.EstimateSource = estimateSource
 			turnTextSeen = false
 			stepResult, stepErr = runTurn(turnReq)
@@ -2431,6 +3106,10 @@ mainLoop:
 				"success":       stepErr == nil,
 			})
 		}
+		if repeatedDeltaTripped {
+			r.setFinalizationReason(finalizationReasonRepeatedDeltaGuard)
+			return r.failRun("The model kept repeating the same output, so the run was stopped", errors.New("repeated_delta_guard"))
+		}
 		if stepErr != nil && isProviderToolCallReferenceError(stepErr) {
 			r.persistRunEvent("provider.error.classified", RealtimeStreamKindLifecycle, map[string]any{
 				"step_index":    step,
@@ -2456,7 +3135,7 @@ mainLoop:
 					"prepended_declarations": stats.PrependedAssistantMessages,
 				})
 				turnReq.Messages = repaired
-				estimateTokens, estimateSource = estimateTurnTokens(providerType, turnReq)
+				estimateTokens, estimateSource = countTurnTokens(execCtx, r.tokenCounter, providerType, turnReq)
 				state.EstimateSource = estimateSource
 				r.emitContextUsageEvent(contextUsageEventInput{
 					StepIndex:             step,
@@ -2484,10 +3163,18 @@ mainLoop:
 		}
 		if stepErr != nil {
 			recoveryCount++
+			r.metrics.recordRecovery()
 			if r.finalizeIfContextCanceledWithRuntimeCloseout(execCtx, step, state, taskComplexity, req.Options.Mode, capabilityContract.ProtocolProfile, req.Options.RequireUserConfirmOnTaskComplete) {
 				return nil
 			}
-			if recoveryCount > 5 {
+			if errors.Is(stepErr, ErrProviderCircuitOpen) {
+				// The provider is disabled; further recovery turns would be rejected the same way.
+				return r.failRun("", stepErr)
+			}
+			if recoveryCount > loopProfile.RecoveryRetryLimit {
+				if r.canAutoRetryRun(execCtx) {
+					return &earlyRunFailure{message: "AI provider failed before the run made progress", cause: stepErr}
+				}
 				ended, askErr := tryAskUser(step, defaultGuardAskUserSignal(
 					fmt.Sprintf("I encountered repeated errors from the AI provider and cannot continue. Last error: %s", sanitizeLogText(stepErr.Error(), 200)),
 					nil,
@@ -2501,7 +3188,7 @@ mainLoop:
 				}
 				continue
 			}
-			exceptionOverlay = buildRecoveryOverlay(recoveryCount, 5, stepErr, lastSignature, capabilityContract.AllowUserInteraction)
+			exceptionOverlay = buildRecoveryOverlay(recoveryCount, loopProfile.RecoveryRetryLimit, stepErr, lastSignature, capabilityContract.AllowUserInteraction)
 			state.RecentErrors = appendLimited(state.RecentErrors, sanitizeLogText(stepErr.Error(), 300), 6)
 			time.Sleep(backoffDuration(recoveryCount))
 			continue
@@ -2523,8 +3210,14 @@ mainLoop:
 		))
 		finishReason := normalizeReplyFinishReason(stepResult.FinishReason)
 		r.recordRuntimeTurnUsage(stepResult.Usage, estimateTokens)
+		r.recordProviderTurnDiag(step, providerCfg.ID, providerType, modelName, stepResult)
+		if r.accountTurnCost(step, providerCfg.ID, modelName, stepResult.Usage, req.Options.MaxCostUSD) {
+			costBudgetExceeded = true
+		}
 		r.persistRunEvent("native.turn.result", RealtimeStreamKindLifecycle, map[string]any{
 			"step_index":    step,
+			"provider_id":   strings.TrimSpace(providerCfg.ID),
+			"model":         strings.TrimSpace(modelName),
 			"finish_reason": finishReason,
 			"tool_calls":    len(stepResult.ToolCalls),
 			"usage": map[string]any{
@@ -2534,6 +3227,7 @@ mainLoop:
 			},
 			"estimate_tokens": estimateTokens,
 			"estimate_source": estimateSource,
+			"sampling":        sampling.eventPayload(),
 		})
 		if len(stepResult.ToolCalls) == 0 {
 			r.setCanonicalMarkdownCandidate(r.canonicalAssistantMarkdownOrFallback(stepResult.Text))
@@ -2583,9 +3277,11 @@ mainLoop:
 		if len(normalCalls) > 0 {
 			promoteToAgenticLoop(step, "tool_execution_required")
 			noToolRounds = 0
+			guardAskUserBounces = 0
 			sigByCallID := make(map[string]string, len(normalCalls))
 			dispatchCalls := make([]ToolCall, 0, len(normalCalls))
 			guardedResults := make(map[string]ToolResult, 4) // tool_id -> result
+			limitedTools := map[string]int{}                 // tool name -> limit, for calls rejected by ToolCallLimits
 			hasFailedSignatureRetry := false
 			for _, call := range normalCalls {
 				sig := buildToolSignature(call)
@@ -2595,6 +3291,17 @@ mainLoop:
 					if failedSignatures[sig] {
 						hasFailedSignatureRetry = true
 					}
+					if count, tripped := streamHealth.observeToolSignature(sig); tripped {
+						r.persistRunEvent("guard.tool_signature", RealtimeStreamKindLifecycle, map[string]any{
+							"step_index": step,
+							"signature":  sig,
+							"count":      count,
+							"threshold":  toolSignatureGuardThreshold,
+							"tool_name":  strings.TrimSpace(call.Name),
+						})
+						r.setFinalizationReason(finalizationReasonToolSignatureGuard)
+						return r.failRun("The model kept proposing the same tool call, so the run was stopped", errors.New("tool_signature_guard"))
+					}
 					signatureHits[sig] = signatureHits[sig] + 1
 					hits := signatureHits[sig]
 					if hits >= 2 {
@@ -2605,7 +3312,7 @@ mainLoop:
 							"tool_name": strings.TrimSpace(call.Name),
 						})
 					}
-					if hits >= 3 {
+					if hits >= loopProfile.DoomLoopThreshold {
 						ended, askErr := tryAskUser(step, defaultGuardAskUserSignal(
 							fmt.Sprintf("The same tool call is repeating without progress (%s). Please clarify what should change or provide missing context.", strings.TrimSpace(call.Name)),
 							nil,
@@ -2634,6 +3341,21 @@ mainLoop:
 						continue
 					}
 				}
+				if limit, count, ok := state.admitToolCall(call.Name, toolCallLimits); !ok {
+					guardedResults[strings.TrimSpace(call.ID)] = toolCallLimitResult(call, limit)
+					if strings.TrimSpace(call.ID) != "" {
+						state.ToolCallLedger[strings.TrimSpace(call.ID)] = "aborted"
+					}
+					limitedTools[strings.TrimSpace(call.Name)] = limit
+					r.persistRunEvent("guard.tool_call_limit", RealtimeStreamKindLifecycle, map[string]any{
+						"step_index": step,
+						"tool_id":    strings.TrimSpace(call.ID),
+						"tool_name":  strings.TrimSpace(call.Name),
+						"limit":      limit,
+						"calls":      count,
+					})
+					continue
+				}
 				dispatchCalls = append(dispatchCalls, call)
 			}
 
@@ -2657,6 +3379,7 @@ mainLoop:
 				}
 			}
 			updateTodoRuntimeState(&state, normalCalls, toolResults, step)
+			updateScratchpadRuntimeState(&state, normalCalls, toolResults)
 			if state.TodoTrackingEnabled {
 				todoSetupNudges = 0
 			}
@@ -2668,6 +3391,7 @@ mainLoop:
 			hasSuccess := false
 			hasArgumentError := false
 			sawDoomLoopGuard := false
+			sawToolTimeout := false
 			for _, tr := range toolResults {
 				if tr.Status == toolResultStatusSuccess {
 					hasSuccess = true
@@ -2684,6 +3408,9 @@ mainLoop:
 				if tr.Summary == "guard.doom_loop" {
 					sawDoomLoopGuard = true
 				}
+				if tr.Summary == toolCallTimeoutSummary {
+					sawToolTimeout = true
+				}
 				state.BlockedActionFacts = appendLimited(state.BlockedActionFacts, tr.ToolName+": "+strings.TrimSpace(tr.Details), 12)
 				if tr.ToolID != "" {
 					state.BlockedEvidenceRefs = appendLimited(state.BlockedEvidenceRefs, "tool:"+strings.TrimSpace(tr.ToolID), 12)
@@ -2695,6 +3422,36 @@ mainLoop:
 				}
 			}
 
+			repeatedResultCalls := 0
+			repeatedResultTool := ""
+			for _, call := range normalCalls {
+				id := strings.TrimSpace(call.ID)
+				sig := strings.TrimSpace(sigByCallID[id])
+				tr, ok := resByID[id]
+				if sig == "" || !ok {
+					continue
+				}
+				fingerprint := toolResultFingerprint(call, tr)
+				distinct := state.observeToolResult(fingerprint, sig)
+				if distinct < repeatedResultGuardThreshold || distinct <= repeatedResultCalls {
+					continue
+				}
+				repeatedResultCalls = distinct
+				repeatedResultTool = strings.TrimSpace(call.Name)
+				state.NoProgressSignatures = appendLimited(state.NoProgressSignatures, sig, 8)
+				state.BlockedActionFacts = appendLimited(state.BlockedActionFacts, fmt.Sprintf("%s: %d different calls returned the same result", repeatedResultTool, distinct), 12)
+				if id != "" {
+					state.BlockedEvidenceRefs = appendLimited(state.BlockedEvidenceRefs, "tool:"+id, 12)
+				}
+				r.persistRunEvent("guard.repeated_result", RealtimeStreamKindLifecycle, map[string]any{
+					"step_index":     step,
+					"fingerprint":    fingerprint,
+					"distinct_calls": distinct,
+					"threshold":      repeatedResultGuardThreshold,
+					"tool_name":      repeatedResultTool,
+				})
+			}
+
 			for _, call := range normalCalls {
 				id := strings.TrimSpace(call.ID)
 				sig := strings.TrimSpace(sigByCallID[id])
@@ -2710,17 +3467,40 @@ mainLoop:
 
 			if hasError && !hasSuccess {
 				recoveryCount++
+				r.metrics.recordRecovery()
 				failure := errors.New("tool failure")
 				if sawDoomLoopGuard {
 					failure = errors.New("doom-loop guard hit")
+				} else if sawToolTimeout {
+					failure = errors.New("tool_timeout: narrow the call's scope or raise timeout_ms")
 				}
-				exceptionOverlay = buildRecoveryOverlay(recoveryCount, 5, failure, lastSignature, capabilityContract.AllowUserInteraction)
+				exceptionOverlay = buildRecoveryOverlay(recoveryCount, loopProfile.RecoveryRetryLimit, failure, lastSignature, capabilityContract.AllowUserInteraction)
 			} else {
 				recoveryCount = 0
 				if hasSuccess {
 					resetMistakes()
 				}
 			}
+			if limitOverlay := buildToolCallLimitOverlay(limitedTools); limitOverlay != "" {
+				exceptionOverlay = strings.TrimSpace(exceptionOverlay + "\n" + limitOverlay)
+			}
+			if repeatedResultCalls >= repeatedResultAskUserThreshold {
+				ended, askErr := tryAskUser(step, defaultGuardAskUserSignal(
+					fmt.Sprintf("Different %s calls keep returning the same result, so I am not making progress. Please clarify what should change or provide missing context.", repeatedResultTool),
+					nil,
+					"guard_repeated_result",
+					state.BlockedEvidenceRefs...,
+				), "guard_repeated_result")
+				if askErr != nil {
+					return askErr
+				}
+				if ended {
+					return nil
+				}
+			}
+			if repeatedResultCalls > 0 {
+				exceptionOverlay = strings.TrimSpace(exceptionOverlay + "\n" + buildRepeatedResultOverlay(repeatedResultTool, repeatedResultCalls))
+			}
 
 			if !hasSuccess {
 				stepMistake := 0
@@ -2731,7 +3511,7 @@ mainLoop:
 					stepMistake++
 				}
 				appendMistake(stepMistake)
-				if mistakeSum() >= 3 {
+				if mistakeSum() >= loopProfile.MistakeWindowLimit {
 					ended, askErr := tryAskUser(step, defaultGuardAskUserSignal(
 						"I am not making progress due to repeated tool mistakes. Please clarify the objective or provide additional context to proceed.",
 						nil,
@@ -2760,7 +3540,8 @@ mainLoop:
 			exitResult, exitErr := r.toolExitPlanMode(strings.TrimSpace(exitPlanModeCall.ID), exitArgs)
 			if exitErr != nil || exitResult.WaitingPrompt == nil {
 				recoveryCount++
-				exceptionOverlay = buildRecoveryOverlay(recoveryCount, 5, errors.New("exit_plan_mode failed"), lastSignature, capabilityContract.AllowUserInteraction)
+				r.metrics.recordRecovery()
+				exceptionOverlay = buildRecoveryOverlay(recoveryCount, loopProfile.RecoveryRetryLimit, errors.New("exit_plan_mode failed"), lastSignature, capabilityContract.AllowUserInteraction)
 				messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: "exit_plan_mode failed. Regenerate a concise reason and call exit_plan_mode again if act mode is still required."}}})
 				isFirstRound = false
 				continue
@@ -2845,14 +3626,20 @@ mainLoop:
 			for _, ref := range evidenceRefs {
 				r.addWebSource("", ref)
 			}
+			evidenceRefs = uniqueStrings(append(evidenceRefs, r.generatedImageRefs()...))
 			if req.Options.RequireUserConfirmOnTaskComplete {
 				approved, approveErr := r.waitForTaskCompleteConfirm(execCtx, resultText)
 				if approveErr != nil {
+					if r.finalizeIfContextCanceledWithRuntimeCloseout(execCtx, step, state, taskComplexity, req.Options.Mode, capabilityContract.ProtocolProfile, req.Options.RequireUserConfirmOnTaskComplete) {
+						return nil
+					}
 					recoveryCount++
+					r.metrics.recordRecovery()
 					exceptionOverlay = buildRecoveryOverlay(recoveryCount, 3, approveErr, lastSignature, capabilityContract.AllowUserInteraction)
 					continue
 				}
 				if !approved {
+					r.metrics.recordContinue(runContinueKindCompletion, "confirmation_rejected")
 					promoteToAgenticLoop(step, "completion_confirmation_rejected")
 					messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: "The user rejected completion. Continue the same objective with improved evidence."}}})
 					state.PendingUserInputQueue = appendLimited(state.PendingUserInputQueue, "completion_rejected", 4)
@@ -2870,8 +3657,10 @@ mainLoop:
 				"gate_reason":         gateReason,
 				"complexity":          taskComplexity,
 				"mode":                strings.TrimSpace(req.Options.Mode),
+				"evidence_refs":       evidenceRefs,
 			})
 			if !gatePassed {
+				r.metrics.recordContinue(runContinueKindCompletion, gateReason)
 				r.emitLifecyclePhase("finalizing", map[string]any{
 					"reason":                "completion_gate_rejected",
 					"step_index":            step,
@@ -2927,6 +3716,62 @@ mainLoop:
 				isFirstRound = false
 				continue
 			}
+			if qualityReason := completionQualityRejectReason(resultText, req.Options); qualityReason != "" {
+				accepted := completionQualityRejects >= maxCompletionQualityRejects
+				if !accepted {
+					completionQualityRejects++
+				}
+				r.persistRunEvent("completion.quality_guard", RealtimeStreamKindLifecycle, map[string]any{
+					"step_index":   step,
+					"attempt":      completionQualityRejects,
+					"reason":       qualityReason,
+					"result_chars": utf8.RuneCountInString(strings.TrimSpace(resultText)),
+					"min_chars":    req.Options.MinCompletionChars,
+					"accepted":     accepted,
+				})
+				if !accepted {
+					r.metrics.recordContinue(runContinueKindCompletion, qualityReason)
+					promoteToAgenticLoop(step, qualityReason)
+					rejectionMsg, recoveryOverlay := completionQualityRejectPrompts(qualityReason, resultText, req.Options.MinCompletionChars)
+					messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: rejectionMsg}}})
+					exceptionOverlay = recoveryOverlay
+					isFirstRound = false
+					continue
+				}
+			}
+			if req.Options.SelfCheckCompletion {
+				selfCheck := completionSelfCheckResult{Verdict: completionSelfCheckVerdictSkipped, Reason: "max_self_checks_reached"}
+				if completionSelfChecks < maxCompletionSelfChecks {
+					completionSelfChecks++
+					checked, usage, checkErr := runCompletionSelfCheck(execCtx, adapter, modelName, taskObjective, resultText, messages)
+					if usage.InputTokens > 0 || usage.OutputTokens > 0 {
+						if r.accountTurnCost(step, providerCfg.ID, modelName, usage, req.Options.MaxCostUSD) {
+							costBudgetExceeded = true
+						}
+					}
+					selfCheck = checked
+					if checkErr != nil {
+						// An unverifiable check must not block an otherwise valid completion.
+						selfCheck = completionSelfCheckResult{Verdict: completionSelfCheckVerdictError, Reason: sanitizeLogText(checkErr.Error(), 240)}
+					}
+				}
+				r.persistRunEvent("completion.self_check", RealtimeStreamKindLifecycle, map[string]any{
+					"step_index": step,
+					"attempt":    completionSelfChecks,
+					"verdict":    selfCheck.Verdict,
+					"reason":     selfCheck.Reason,
+					"evidence":   selfCheck.Evidence,
+				})
+				if selfCheck.Verdict == completionSelfCheckVerdictFail {
+					r.metrics.recordContinue(runContinueKindCompletion, "self_check_failed")
+					promoteToAgenticLoop(step, "completion_self_check_failed")
+					reason := firstNonEmpty(selfCheck.Reason, "the objective is not shown to be met")
+					messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: "task_complete was rejected by the completion self-check: " + reason + " Close the gap, then call task_complete again with concrete evidence."}}})
+					exceptionOverlay = "[RECOVERY] Completion self-check failed: " + reason + " Address it and call task_complete again with explicit evidence."
+					isFirstRound = false
+					continue
+				}
+			}
 			if strings.TrimSpace(resultText) != "" && strings.TrimSpace(stepResult.Text) == "" {
 				_ = r.appendTextDelta(strings.TrimSpace(resultText))
 			}
@@ -2986,8 +3831,9 @@ mainLoop:
 			// request a bounded continuation instead of silently finalizing a partial answer.
 			r.persistReplyContinuation(step, state.ExecutionContract, finishReason)
 			recoveryCount++
+			r.metrics.recordRecovery()
 			fail := errors.New("provider output truncated (length)")
-			exceptionOverlay = buildRecoveryOverlay(recoveryCount, 5, fail, "", capabilityContract.AllowUserInteraction)
+			exceptionOverlay = buildRecoveryOverlay(recoveryCount, loopProfile.RecoveryRetryLimit, fail, "", capabilityContract.AllowUserInteraction)
 			messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: replyContinuationPrompt}}})
 			isFirstRound = false
 			continue
@@ -3016,7 +3862,7 @@ mainLoop:
 
 		if !turnTextSeen {
 			appendMistake(1)
-			if mistakeSum() >= 3 {
+			if mistakeSum() >= loopProfile.MistakeWindowLimit {
 				ended, askErr := tryAskUser(step, defaultGuardAskUserSignal(
 					"I am not getting usable output and cannot proceed safely. Please clarify the objective or provide more context.",
 					nil,
@@ -3031,7 +3877,8 @@ mainLoop:
 				continue
 			}
 			recoveryCount++
-			if recoveryCount > 5 {
+			r.metrics.recordRecovery()
+			if recoveryCount > loopProfile.RecoveryRetryLimit {
 				ended, askErr := tryAskUser(step, defaultGuardAskUserSignal(
 					"I have been unable to produce output after multiple attempts. Please check the AI provider configuration or try rephrasing your request.",
 					nil,
@@ -3045,7 +3892,7 @@ mainLoop:
 				}
 				continue
 			}
-			exceptionOverlay = buildRecoveryOverlay(recoveryCount, 5, errors.New("empty output"), lastSignature, capabilityContract.AllowUserInteraction)
+			exceptionOverlay = buildRecoveryOverlay(recoveryCount, loopProfile.RecoveryRetryLimit, errors.New("empty output"), lastSignature, capabilityContract.AllowUserInteraction)
 			isFirstRound = false
 			continue
 		}
@@ -3075,6 +3922,7 @@ mainLoop:
 		})
 		preferStructuredSignalRecovery := guidedStructuredContinuationActive() && capabilityContract.AllowUserInteraction && noToolRounds >= maxNoToolRounds
 		if noToolRounds < maxNoToolRounds || (noToolRounds == maxNoToolRounds && !preferStructuredSignalRecovery) {
+			r.metrics.recordContinue(runContinueKindTask, "no_tool_call")
 			if noToolRounds == maxNoToolRounds {
 				exceptionOverlay = fmt.Sprintf("[COMPLETION REQUIRED] You have produced no-tool rounds (%d/%d). Unless an external blocker exists, finalize with task_complete now after summarizing verified outcomes.", noToolRounds, maxNoToolRounds)
 				nudgeText := "Try to finish autonomously in this run. If done, call task_complete now with concrete evidence."
@@ -3120,6 +3968,7 @@ mainLoop:
 			forcedOverlay = "[STRUCTURED SIGNAL REQUIRED] Active interaction contract plus text-only continuation. You MUST emit an explicit signal now. Call ask_user to continue the guided interaction, or call task_complete only if the objective is complete. Do not output a plain-text questionnaire."
 		}
 		forcedSystemPrompt := r.buildLayeredSystemPrompt(taskObjective, mode, taskComplexity, step, maxSteps, false, scheduler.ActiveTools(mode), state, forcedOverlay, capabilityContract)
+		r.persistRunSystemPrompt(forcedSystemPrompt, runSystemPromptInfo{StepIndex: step, Mode: mode, Complexity: taskComplexity, ExceptionOverlay: forcedOverlay})
 		forcedTurnMessages := composeTurnMessages(forcedSystemPrompt, messages)
 		forcedToolNames := make([]string, 0, len(forcedSignalTools))
 		for _, tool := range forcedSignalTools {
@@ -3141,7 +3990,7 @@ mainLoop:
 			Tools:            forcedSignalTools,
 			Budgets:          TurnBudgets{MaxSteps: 1, MaxInputTokens: req.Options.MaxInputTokens, MaxOutputToken: req.Options.MaxOutputTokens, MaxCostUSD: req.Options.MaxCostUSD},
 			ModeFlags:        ModeFlags{Mode: mode},
-			ProviderControls: ProviderControls{ThinkingBudgetTokens: req.Options.ThinkingBudgetTokens, CacheControl: req.Options.CacheControl, ResponseFormat: req.Options.ResponseFormat, Temperature: req.Options.Temperature, TopP: req.Options.TopP},
+			ProviderControls: ProviderControls{ThinkingBudgetTokens: req.Options.ThinkingBudgetTokens, CacheControl: req.Options.CacheControl, ResponseFormat: req.Options.ResponseFormat, ResponseJSONSchema: req.Options.ResponseJSONSchema, Temperature: req.Options.Temperature, TopP: req.Options.TopP, Seed: req.Options.Seed},
 		}
 		endForcedBusy := r.beginBusy()
 		forcedResult, forcedErr := adapter.StreamTurn(execCtx, forcedReq, func(event StreamEvent) {
@@ -3219,20 +4068,60 @@ mainLoop:
 		continue
 	}
 
-	// Safety net reached (nativeHardMaxSteps). This should rarely happen in
-	// normal operation — the loop is task-driven and exits via task_complete
-	// or ask_user. Reaching here indicates a bug or a
-	// genuinely very long task.
-	r.persistRunEvent("guard.hard_max_steps", RealtimeStreamKindLifecycle, map[string]any{
-		"hard_max_steps": nativeHardMaxSteps,
-	})
+	// Safety net reached (nativeHardMaxSteps), the run cost budget was exceeded, the per-run wall time elapsed,
+	// or guard-originated ask_user kept bouncing off the gate.
+	// The hard cap should rarely happen in normal operation — the loop is
+	// task-driven and exits via task_complete or ask_user. Reaching it
+	// indicates a bug or a genuinely very long task.
+	forcedFinalReason := "task_complete_forced"
+	summaryFailedSource := "hard_max_summary_failed"
+	stopSource := "hard_max_steps"
+	summaryMsg := "You have reached the absolute step limit. Summarize what you accomplished and what remains, then call task_complete."
+	summaryOverlay := "[FINAL SUMMARY] You have exhausted the hard step limit. You MUST call task_complete now with a detailed summary of what was done and what remains."
+	limitLabel := "maximum step limit"
+	switch {
+	case wallTimeExceeded:
+		forcedFinalReason = finalizationReasonWallTimeExceeded
+		summaryFailedSource = "wall_time_summary_failed"
+		stopSource = "wall_time_exceeded"
+		limitLabel = "wall time limit"
+		summaryMsg = "You have reached the time limit for this run. Summarize what you accomplished and what remains, then call task_complete."
+		summaryOverlay = "[FINAL SUMMARY] You have exhausted the run time limit. You MUST call task_complete now with a detailed summary of what was done and what remains."
+		r.persistRunEvent("guard.wall_time_exceeded", RealtimeStreamKindLifecycle, map[string]any{
+			"step_index":         stopStep,
+			"wall_time_limit_ms": r.wallTimeLimit.Milliseconds(),
+		})
+	case costBudgetExceeded:
+		forcedFinalReason = finalizationReasonCostBudgetExceeded
+		summaryFailedSource = "cost_budget_summary_failed"
+		stopSource = "cost_budget_exceeded"
+		limitLabel = "cost budget"
+		summaryMsg = "You have reached the cost budget for this run. Summarize what you accomplished and what remains, then call task_complete."
+		summaryOverlay = "[FINAL SUMMARY] You have exhausted the run cost budget. You MUST call task_complete now with a detailed summary of what was done and what remains."
+		r.persistRunEvent("guard.cost_budget_exceeded", RealtimeStreamKindLifecycle, map[string]any{
+			"step_index":           stopStep,
+			"accumulated_cost_usd": r.accumulatedCostUSD,
+			"max_cost_usd":         req.Options.MaxCostUSD,
+		})
+	case askUserBounceExceeded:
+		forcedFinalReason = finalizationReasonAskUserBounceGuard
+		summaryFailedSource = "ask_user_bounce_summary_failed"
+		stopSource = "ask_user_bounce_guard"
+		limitLabel = "ask_user retry limit"
+		summaryMsg = "Repeated attempts to ask the user for input were rejected. Summarize what you accomplished and what remains, then call task_complete."
+		summaryOverlay = "[FINAL SUMMARY] Repeated ask_user attempts were rejected. You MUST call task_complete now with a detailed summary of what was done and what remains."
+	default:
+		r.metrics.recordLoopExhausted()
+		r.persistRunEvent("guard.hard_max_steps", RealtimeStreamKindLifecycle, map[string]any{
+			"hard_max_steps": nativeHardMaxSteps,
+		})
+	}
 
 	// Attempt one final LLM turn to produce a summary. Only provide
 	// task_complete — no other tools — to force the LLM to summarize.
-	summaryMsg := "You have reached the absolute step limit. Summarize what you accomplished and what remains, then call task_complete."
 	messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: summaryMsg}}})
-	summaryOverlay := "[FINAL SUMMARY] You have exhausted the hard step limit. You MUST call task_complete now with a detailed summary of what was done and what remains."
-	summarySystemPrompt := r.buildLayeredSystemPrompt(taskObjective, mode, taskComplexity, nativeHardMaxSteps, maxSteps, false, scheduler.ActiveTools(mode), state, summaryOverlay, capabilityContract)
+	summarySystemPrompt := r.buildLayeredSystemPrompt(taskObjective, mode, taskComplexity, stopStep, maxSteps, false, scheduler.ActiveTools(mode), state, summaryOverlay, capabilityContract)
+	r.persistRunSystemPrompt(summarySystemPrompt, runSystemPromptInfo{StepIndex: stopStep, Mode: mode, Complexity: taskComplexity, ExceptionOverlay: summaryOverlay})
 	summaryTurnMessages := composeTurnMessages(summarySystemPrompt, messages)
 
 	signalOnlyTools := make([]ToolDef, 0, 1)
@@ -3248,7 +4137,7 @@ mainLoop:
 		Tools:            signalOnlyTools,
 		Budgets:          TurnBudgets{MaxSteps: 1, MaxInputTokens: req.Options.MaxInputTokens, MaxOutputToken: req.Options.MaxOutputTokens, MaxCostUSD: req.Options.MaxCostUSD},
 		ModeFlags:        ModeFlags{Mode: mode},
-		ProviderControls: ProviderControls{ResponseFormat: req.Options.ResponseFormat, Temperature: req.Options.Temperature, TopP: req.Options.TopP},
+		ProviderControls: ProviderControls{ResponseFormat: req.Options.ResponseFormat, ResponseJSONSchema: req.Options.ResponseJSONSchema, Temperature: req.Options.Temperature, TopP: req.Options.TopP, Seed: req.Options.Seed},
 	}
 	endBusy := r.beginBusy()
 	summaryResult, summaryErr := adapter.StreamTurn(execCtx, summaryReq, func(event StreamEvent) {
@@ -3271,7 +4160,7 @@ mainLoop:
 			if strings.TrimSpace(resultText) != "" {
 				gatePassed, gateReason := evaluateTaskCompletionGate(resultText, state, taskComplexity, req.Options.Mode)
 				r.persistRunEvent("completion.attempt", RealtimeStreamKindLifecycle, map[string]any{
-					"step_index":          nativeHardMaxSteps,
+					"step_index":          stopStep,
 					"attempt":             "task_complete_forced",
 					"completion_contract": currentCompletionContract(),
 					"gate_passed":         gatePassed,
@@ -3280,16 +4169,16 @@ mainLoop:
 					"complexity":          taskComplexity,
 					"mode":                strings.TrimSpace(req.Options.Mode),
 				})
-				// Hard-max completion is a safety net; do not block on the completion gate here.
+				// Forced completion is a safety net; do not block on the completion gate here.
 				if strings.TrimSpace(summaryResult.Text) == "" {
 					_ = r.appendTextDelta(strings.TrimSpace(resultText))
 				}
 				r.setCanonicalMarkdownCandidate(resultText)
 				r.reconcileCanonicalMarkdownMessage(resultText)
 				r.emitSourcesToolBlock("task_complete")
-				r.setFinalizationReason("task_complete_forced")
+				r.setFinalizationReason(forcedFinalReason)
 				r.setEndReason("complete")
-				r.emitLifecyclePhase("ended", map[string]any{"reason": "task_complete_forced", "step_index": nativeHardMaxSteps})
+				r.emitLifecyclePhase("ended", map[string]any{"reason": forcedFinalReason, "step_index": stopStep})
 				r.sendStreamEvent(streamEventMessageEnd{Type: "message-end", MessageID: r.messageID})
 				return nil
 			}
@@ -3298,13 +4187,16 @@ mainLoop:
 
 	if summaryErr != nil || strings.TrimSpace(summaryResult.Text) == "" {
 		// Summary turn failed — tell user via endAskUser with specific error,
-		// rather than producing a mechanical degradedSummary.
+		// rather than producing a mechanical degradedSummary, unless the config opts into that fallback.
+		if !r.hasNonEmptyAssistantText() && r.cfg != nil && r.cfg.EnableDegradedSummaryFallback {
+			return r.finishWithDegradedSummary(stopStep, state, taskObjective, limitLabel, summaryFailedSource, summaryErr)
+		}
 		if !r.hasNonEmptyAssistantText() {
-			errMsg := "The task reached the maximum step limit and the AI provider could not produce a summary."
+			errMsg := fmt.Sprintf("The task reached the %s and the AI provider could not produce a summary.", limitLabel)
 			if summaryErr != nil {
-				errMsg = fmt.Sprintf("The task reached the maximum step limit. Summary attempt failed: %s", sanitizeLogText(summaryErr.Error(), 200))
+				errMsg = fmt.Sprintf("The task reached the %s. Summary attempt failed: %s", limitLabel, sanitizeLogText(summaryErr.Error(), 200))
 			}
-			ended, askErr := tryAskUser(nativeHardMaxSteps, defaultGuardAskUserSignal(errMsg, nil, "hard_max_summary_failed"), "hard_max_summary_failed")
+			ended, askErr := tryAskUser(stopStep, defaultGuardAskUserSignal(errMsg, nil, summaryFailedSource), summaryFailedSource)
 			if askErr != nil {
 				return askErr
 			}
@@ -3314,25 +4206,43 @@ mainLoop:
 		}
 	}
 
+	gateReason := "hard_max_steps_reached"
+	askQuestion := "I reached the hard step limit before explicit completion. Please provide guidance for the next step and I will continue."
+	switch {
+	case wallTimeExceeded:
+		gateReason = "wall_time_exceeded"
+		askQuestion = "I reached the time limit for this run before explicit completion. Please provide guidance for the next step and I will continue."
+	case costBudgetExceeded:
+		gateReason = "cost_budget_exceeded"
+		askQuestion = "I reached the cost budget for this run before explicit completion. Please provide guidance for the next step and I will continue."
+	case askUserBounceExceeded:
+		gateReason = "ask_user_bounce_guard"
+		askQuestion = "I could not get a valid request for your input through after repeated attempts. Please provide guidance for the next step and I will continue."
+	}
 	r.persistRunEvent("completion.attempt", RealtimeStreamKindLifecycle, map[string]any{
-		"step_index":          nativeHardMaxSteps,
+		"step_index":          stopStep,
 		"attempt":             "implicit",
 		"completion_contract": currentCompletionContract(),
 		"gate_passed":         false,
-		"gate_reason":         "hard_max_steps_reached",
+		"gate_reason":         gateReason,
 		"complexity":          taskComplexity,
 	})
-	ended, askErr := tryAskUser(nativeHardMaxSteps, defaultGuardAskUserSignal(
-		"I reached the hard step limit before explicit completion. Please provide guidance for the next step and I will continue.",
-		nil,
-		"hard_max_steps",
-	), "hard_max_steps")
+	ended, askErr := tryAskUser(stopStep, defaultGuardAskUserSignal(askQuestion, nil, stopSource), stopSource)
 	if askErr != nil {
 		return askErr
 	}
 	if ended {
 		return nil
 	}
+	if wallTimeExceeded {
+		return r.failRun("Task exceeded the run wall time without an allowable termination path", errors.New("wall_time_exceeded_without_allowable_wait_user"))
+	}
+	if costBudgetExceeded {
+		return r.failRun("Task exceeded the run cost budget without an allowable termination path", errors.New("cost_budget_exceeded_without_allowable_wait_user"))
+	}
+	if askUserBounceExceeded {
+		return r.failRun("Task kept bouncing on ask_user without an allowable termination path", errors.New("ask_user_bounce_without_allowable_wait_user"))
+	}
 	return r.failRun("Task reached hard max steps without an allowable termination path", errors.New("hard_max_steps_without_allowable_wait_user"))
 }
 
@@ -3389,8 +4299,11 @@ func (r *run) runNativeConversational(
 		finalizationReason = "creative_reply"
 		fallbackText = "I can help with creative writing. Tell me the style, tone, and length you want."
 	}
+	r.persistRunSystemPrompt(systemPrompt, runSystemPromptInfo{Mode: mode, Complexity: req.Options.Complexity})
 
 	r.emitLifecyclePhase("synthesizing", map[string]any{"intent": intent})
+	req, historyTrim := applyRunHistoryWindow(req)
+	r.persistHistoryTrim(historyTrim)
 	messages := buildMessagesForRun(req)
 	resumeState, resumeStateErr := r.loadProviderTurnResumeState(execCtx, providerCfg, providerType, modelName)
 	if resumeStateErr != nil {
@@ -3427,7 +4340,7 @@ func (r *run) runNativeConversational(
 			Tools:            nil,
 			Budgets:          TurnBudgets{MaxSteps: 1, MaxInputTokens: req.Options.MaxInputTokens, MaxOutputToken: req.Options.MaxOutputTokens, MaxCostUSD: req.Options.MaxCostUSD},
 			ModeFlags:        ModeFlags{Mode: mode, ReasoningOnly: true},
-			ProviderControls: ProviderControls{ThinkingBudgetTokens: req.Options.ThinkingBudgetTokens, CacheControl: req.Options.CacheControl, ResponseFormat: req.Options.ResponseFormat, Temperature: req.Options.Temperature, TopP: req.Options.TopP},
+			ProviderControls: ProviderControls{ThinkingBudgetTokens: req.Options.ThinkingBudgetTokens, CacheControl: req.Options.CacheControl, ResponseFormat: req.Options.ResponseFormat, ResponseJSONSchema: req.Options.ResponseJSONSchema, Temperature: req.Options.Temperature, TopP: req.Options.TopP, Seed: req.Options.Seed},
 		}
 		baseTurnMessages := turnReq.Messages
 		resumeTurn := step == 0 && resumeState.Enabled && strings.TrimSpace(resumeState.PreviousResponseID) != ""
@@ -3442,7 +4355,7 @@ func (r *run) runNativeConversational(
 				"intent":               intent,
 			})
 		}
-		estimateTokens, estimateSource := estimateTurnTokens(providerType, turnReq)
+		estimateTokens, estimateSource := countTurnTokens(execCtx, r.tokenCounter, providerType, turnReq)
 		contextWindow := nativeDefaultContextLimit
 		if req.ModelCapability.MaxContextTokens > 0 {
 			contextWindow = req.ModelCapability.MaxContextTokens
@@ -3494,7 +4407,7 @@ func (r *run) runNativeConversational(
 			r.setProviderContinuationCandidate(threadstore.ThreadProviderContinuation{})
 			turnReq.Messages = baseTurnMessages
 			turnReq.ProviderControls.PreviousResponseID = ""
-			estimateTokens, estimateSource = estimateTurnTokens(providerType, turnReq)
+			estimateTokens, estimateSource = countTurnTokens(execCtx, r.tokenCounter, providerType, turnReq)
 			endBusy = r.beginBusy()
 			stepResult, stepErr = adapter.StreamTurn(execCtx, turnReq, func(event StreamEvent) {
 				switch event.Type {
@@ -3524,8 +4437,10 @@ func (r *run) runNativeConversational(
 			if r.finalizeIfContextCanceled(execCtx) {
 				return nil
 			}
-			return r.failRun("Failed to generate conversational response", stepErr)
+			return r.failRunOrAutoRetry(execCtx, "Failed to generate conversational response", stepErr)
 		}
+		r.recordProviderTurnDiag(step, providerCfg.ID, providerType, modelName, stepResult)
+		_ = r.accountTurnCost(step, providerCfg.ID, modelName, stepResult.Usage, req.Options.MaxCostUSD)
 
 		r.setProviderContinuationCandidate(buildProviderContinuationCandidate(
 			strings.TrimSpace(providerCfg.ID),
@@ -3538,6 +4453,8 @@ func (r *run) runNativeConversational(
 		r.recordRuntimeTurnUsage(stepResult.Usage, estimateTokens)
 		r.persistRunEvent("native.turn.result", RealtimeStreamKindLifecycle, map[string]any{
 			"step_index":    step,
+			"provider_id":   strings.TrimSpace(providerCfg.ID),
+			"model":         strings.TrimSpace(modelName),
 			"finish_reason": finishReason,
 			"tool_calls":    len(stepResult.ToolCalls),
 			"usage": map[string]any{
@@ -3620,26 +4537,27 @@ func buildInitialMessages(history []RunHistoryMsg, userInput string) []Message {
 
 func buildMessagesForRun(req RunRequest) []Message {
 	if strings.TrimSpace(req.ContextPack.ThreadID) != "" {
-		return buildMessagesFromPromptPack(req.ContextPack, req.Input.Text)
+		return appendAttachmentLimitNote(buildMessagesFromPromptPack(req.ContextPack, req.Input.Text), req.AttachmentLimitNote)
 	}
 	messages := buildInitialMessages(req.History, req.Input.Text)
-	if len(req.Input.Attachments) > 0 {
-		for _, it := range req.Input.Attachments {
-			if strings.TrimSpace(it.URL) == "" {
-				continue
-			}
-			messages = append(messages, Message{
-				Role: "user",
-				Content: []ContentPart{{
-					Type:     "file",
-					FileURI:  strings.TrimSpace(it.URL),
-					MimeType: strings.TrimSpace(it.MimeType),
-					Text:     strings.TrimSpace(it.Name),
-				}},
-			})
+	messages = append(messages, buildAttachmentMessages(runAttachmentParts(req.Input.Attachments))...)
+	return appendAttachmentLimitNote(messages, req.AttachmentLimitNote)
+}
+
+func runAttachmentParts(items []RunAttachmentIn) []ContentPart {
+	parts := make([]ContentPart, 0, len(items))
+	for _, it := range items {
+		if strings.TrimSpace(it.URL) == "" {
+			continue
 		}
+		parts = append(parts, ContentPart{
+			Type:     "file",
+			FileURI:  strings.TrimSpace(it.URL),
+			MimeType: strings.TrimSpace(it.MimeType),
+			Text:     strings.TrimSpace(it.Name),
+		})
 	}
-	return messages
+	return parts
 }
 
 func buildResumeMessagesForRun(req RunRequest) []Message {
@@ -3648,25 +4566,12 @@ func buildResumeMessagesForRun(req RunRequest) []Message {
 			IncludeRecentDialogue: false,
 		})
 	}
-	messages := make([]Message, 0, 1+len(req.Input.Attachments))
+	messages := make([]Message, 0, 2+len(req.Input.Attachments))
 	if txt := strings.TrimSpace(req.Input.Text); txt != "" {
 		messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: txt}}})
 	}
-	for _, it := range req.Input.Attachments {
-		if strings.TrimSpace(it.URL) == "" {
-			continue
-		}
-		messages = append(messages, Message{
-			Role: "user",
-			Content: []ContentPart{{
-				Type:     "file",
-				FileURI:  strings.TrimSpace(it.URL),
-				MimeType: strings.TrimSpace(it.MimeType),
-				Text:     strings.TrimSpace(it.Name),
-			}},
-		})
-	}
-	return messages
+	messages = append(messages, buildAttachmentMessages(runAttachmentParts(req.Input.Attachments))...)
+	return appendAttachmentLimitNote(messages, req.AttachmentLimitNote)
 }
 
 func buildMessagesFromPromptPack(pack contextmodel.PromptPack, currentUserInput string) []Message {
@@ -3794,6 +4699,7 @@ func buildMessagesFromPromptPackWithOptions(pack contextmodel.PromptPack, curren
 		}
 	}
 
+	attachmentParts := make([]ContentPart, 0, len(pack.AttachmentsManifest))
 	for _, att := range pack.AttachmentsManifest {
 		url := strings.TrimSpace(att.URL)
 		if url == "" {
@@ -3809,11 +4715,12 @@ func buildMessagesFromPromptPackWithOptions(pack contextmodel.PromptPack, curren
 			if reference != url {
 				msg += " (" + url + ")"
 			}
-			messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: msg}}})
+			attachmentParts = append(attachmentParts, ContentPart{Type: "text", Text: msg})
 			continue
 		}
-		messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "file", FileURI: url, MimeType: strings.TrimSpace(att.MimeType), Text: strings.TrimSpace(att.Name)}}})
+		attachmentParts = append(attachmentParts, ContentPart{Type: "file", FileURI: url, MimeType: strings.TrimSpace(att.MimeType), Text: strings.TrimSpace(att.Name)})
 	}
+	messages = append(messages, buildAttachmentMessages(attachmentParts)...)
 
 	if txt := strings.TrimSpace(currentUserInput); txt != "" {
 		messages = append(messages, Message{Role: "user", Content: []ContentPart{{Type: "text", Text: txt}}})
@@ -3943,7 +4850,7 @@ func composeTurnMessages(systemPrompt string, history []Message) []Message {
 func estimateTurnTokens(providerType string, req TurnRequest) (int, string) {
 	providerType = strings.ToLower(strings.TrimSpace(providerType))
 	factor := 4.0
-	if providerType == "anthropic" {
+	if providerType == "anthropic" || providerType == "bedrock" {
 		factor = 3.8
 	}
 	chars := 0
@@ -3963,7 +4870,7 @@ func estimateTurnTokens(providerType string, req TurnRequest) (int, string) {
 	if estimate < 0 {
 		estimate = 0
 	}
-	return estimate, "heuristic"
+	return estimate, estimateSourceHeuristic
 }
 
 func estimateTextTokens(text string) int {
@@ -4246,17 +5153,14 @@ func (s toolReferenceIntegrityStats) hasChanges() bool {
 	return len(s.OrphanToolCallIDs) > 0 || s.PrependedAssistantMessages > 0 || s.DroppedToolResultParts > 0 || s.DroppedToolMessages > 0
 }
 
-func compactMessages(messages []Message) ([]Message, toolReferenceIntegrityStats) {
+func compactMessages(messages []Message, cfg CompactionConfig) ([]Message, toolReferenceIntegrityStats) {
 	stats := toolReferenceIntegrityStats{}
-	if len(messages) <= 12 {
+	keepRecent := max(1, cfg.KeepRecentMessages)
+	if len(messages) <= keepRecent+2 {
 		out := cloneMessages(messages)
 		out, gateStats := enforceToolReferenceIntegrity(out, nil)
 		return out, mergeToolReferenceStats(stats, gateStats)
 	}
-	keepRecent := 10
-	if keepRecent > len(messages) {
-		keepRecent = len(messages)
-	}
 	archived := cloneMessages(messages[:len(messages)-keepRecent])
 	recent := cloneMessages(messages[len(messages)-keepRecent:])
 	summaryLines := make([]string, 0, len(archived))
@@ -4277,15 +5181,15 @@ func compactMessages(messages []Message) ([]Message, toolReferenceIntegrityStats
 		if txt == "" {
 			continue
 		}
-		if len([]rune(txt)) > 100 {
-			txt = string([]rune(txt)[:100]) + " ..."
+		if cfg.SummaryLineRunes > 0 && len([]rune(txt)) > cfg.SummaryLineRunes {
+			txt = string([]rune(txt)[:cfg.SummaryLineRunes]) + " ..."
 		}
 		summaryLines = append(summaryLines, "- "+role+": "+txt)
 	}
 	compacted := make([]Message, 0, len(recent)+1)
 	if len(summaryLines) > 0 {
-		if len(summaryLines) > 12 {
-			summaryLines = summaryLines[len(summaryLines)-12:]
+		if cfg.MaxSummaryLines > 0 && len(summaryLines) > cfg.MaxSummaryLines {
+			summaryLines = summaryLines[len(summaryLines)-cfg.MaxSummaryLines:]
 		}
 		compacted = append(compacted, Message{
 			Role: "system",
@@ -4298,8 +5202,8 @@ func compactMessages(messages []Message) ([]Message, toolReferenceIntegrityStats
 	for i := range recent {
 		for j := range recent[i].Content {
 			part := &recent[i].Content[j]
-			if strings.ToLower(strings.TrimSpace(part.Type)) == "tool_result" {
-				trimmed, truncated := truncateByRunes(part.Text, 500)
+			if cfg.ToolResultRunes > 0 && strings.ToLower(strings.TrimSpace(part.Type)) == "tool_result" {
+				trimmed, truncated := truncateByRunes(part.Text, cfg.ToolResultRunes)
 				if truncated {
 					part.Text = trimmed + " ... [compressed]"
 				}
@@ -4835,6 +5739,7 @@ func (r *run) persistReplyContinuation(step int, executionContract string, finis
 	if r == nil {
 		return
 	}
+	r.metrics.recordContinue(runContinueKindCompletion, "reply_"+normalizeReplyFinishReason(finishReason))
 	r.persistRunEvent("reply.continuation_requested", RealtimeStreamKindLifecycle, map[string]any{
 		"step_index":          step,
 		"execution_contract":  normalizeExecutionContractValue(executionContract),
@@ -4887,7 +5792,7 @@ func finalizationReasonForAskUserSource(source string) string {
 func evaluateGuardAskUserGate(source string, state runtimeState, complexity string) (bool, string) {
 	source = strings.TrimSpace(source)
 	switch source {
-	case "provider_repeated_error", "complex_task_missing_todos", "hard_max_summary_failed", "hard_max_steps":
+	case "provider_repeated_error", "complex_task_missing_todos", "hard_max_summary_failed", "hard_max_steps", "cost_budget_summary_failed", "cost_budget_exceeded", "wall_time_summary_failed", "wall_time_exceeded", "ask_user_bounce_summary_failed", "ask_user_bounce_guard":
 		return true, "ok"
 	}
 	signal := defaultGuardAskUserSignal("guard check", nil, source, state.BlockedEvidenceRefs...)
@@ -5315,7 +6220,7 @@ func buildSkillOverlayPrompt(active []SkillActivation) string {
 			sb.WriteString("(no content)\n")
 			continue
 		}
-		sb.WriteString(truncateRunes(content, 1200))
+		sb.WriteString(truncateRunes(content, skillOverlayMaxRunes))
 		sb.WriteString("\n")
 	}
 	return strings.TrimSpace(sb.String())
@@ -5378,7 +6283,10 @@ func (r *run) waitForTaskCompleteConfirm(ctx context.Context, resultText string)
 	timer := time.NewTimer(timeout)
 	defer timer.Stop()
 	select {
-	case approved := <-ch:
+	case approved, open := <-ch:
+		if !open {
+			return false, context.Canceled
+		}
 		if approved {
 			block.ApprovalState = "approved"
 			block.Status = ToolCallStatusSuccess
@@ -5511,6 +6419,29 @@ func requestUserInputQuestionChoiceCount(questions []RequestUserInputQuestion) i
 	return total
 }
 
+// finishWithDegradedSummary ends the run with degradedSummary as the final answer after the summary turn failed.
+// The summary is derived from recorded facts only, so it needs no provider call.
+func (r *run) finishWithDegradedSummary(stopStep int, state runtimeState, objective string, limitLabel string, source string, summaryErr error) error {
+	payload := map[string]any{
+		"step_index": stopStep,
+		"source":     source,
+	}
+	if summaryErr != nil {
+		payload["error"] = sanitizeLogText(summaryErr.Error(), 200)
+	}
+	r.persistRunEvent("completion.degraded_summary", RealtimeStreamKindLifecycle, payload)
+
+	text := fmt.Sprintf("The task reached the %s and the AI provider could not produce a summary. This summary is built from the recorded progress.\n\n%s", limitLabel, r.degradedSummary(state, objective))
+	_ = r.appendTextDelta(text)
+	r.setCanonicalMarkdownCandidate(text)
+	r.reconcileCanonicalMarkdownMessage(text)
+	r.setFinalizationReason(finalizationReasonDegradedSummary)
+	r.setEndReason("complete")
+	r.emitLifecyclePhase("ended", map[string]any{"reason": finalizationReasonDegradedSummary, "step_index": stopStep})
+	r.sendStreamEvent(streamEventMessageEnd{Type: "message-end", MessageID: r.messageID})
+	return nil
+}
+
 func (r *run) degradedSummary(state runtimeState, objective string) string {
 	done := strings.Join(state.CompletedActionFacts, "\n- ")
 	notDone := strings.Join(state.BlockedActionFacts, "\n- ")
@@ -5561,6 +6492,35 @@ func extractOpenAIResponseText(resp oresponses.Response) string {
 	return sb.String()
 }
 
+// extractOpenAIResponseImages returns the completed image_generation_call outputs as data URLs.
+func extractOpenAIResponseImages(resp oresponses.Response) []GeneratedImage {
+	var out []GeneratedImage
+	for _, item := range resp.Output {
+		if strings.TrimSpace(item.Type) != "image_generation_call" {
+			continue
+		}
+		call := item.AsImageGenerationCall()
+		if strings.EqualFold(strings.TrimSpace(call.Status), "failed") {
+			continue
+		}
+		encoded := strings.TrimSpace(call.Result)
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) == 0 {
+			continue
+		}
+		mimeType := http.DetectContentType(raw)
+		if !strings.HasPrefix(mimeType, "image/") {
+			mimeType = "image/png"
+		}
+		out = append(out, GeneratedImage{
+			ID:       strings.TrimSpace(call.ID),
+			MimeType: mimeType,
+			DataURL:  "data:" + mimeType + ";base64," + encoded,
+		})
+	}
+	return out
+}
+
 func extractOpenAIURLSources(resp oresponses.Response) []SourceRef {
 	out := make([]SourceRef, 0, 8)
 	seen := make(map[string]struct{}, 8)
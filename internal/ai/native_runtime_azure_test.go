@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+type azureCapturedRequest struct {
+	path       string
+	apiVersion string
+	apiKey     string
+	auth       string
+	model      string
+}
+
+func newAzureCaptureServer(t *testing.T) (*httptest.Server, <-chan azureCapturedRequest) {
+	t.Helper()
+	captured := make(chan azureCapturedRequest, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload struct {
+			Model string `json:"model"`
+		}
+		_ = json.Unmarshal(body, &payload)
+		captured <- azureCapturedRequest{
+			path:       r.URL.Path,
+			apiVersion: r.URL.Query().Get("api-version"),
+			apiKey:     r.Header.Get("api-key"),
+			auth:       r.Header.Get("Authorization"),
+			model:      payload.Model,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"error":{"message":"captured","type":"invalid_request_error"}}`)
+	}))
+	return srv, captured
+}
+
+func TestAzureOpenAIProvider_RequestURLAndAuthHeader(t *testing.T) {
+	// Not parallel: OPENAI_API_KEY must not leak into Azure requests as a bearer token.
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+
+	cases := []struct {
+		name        string
+		baseURL     string
+		deployment  string
+		apiVersion  string
+		wantVersion string
+		wantModel   string
+	}{
+		{name: "deployment", deployment: "prod-gpt5", apiVersion: "2025-03-01-preview", wantVersion: "2025-03-01-preview", wantModel: "prod-gpt5"},
+		{name: "model_as_deployment", wantVersion: config.DefaultAzureOpenAIAPIVersion, wantModel: "gpt-5-mini"},
+		{name: "endpoint_with_openai_suffix", baseURL: "/openai/", wantVersion: config.DefaultAzureOpenAIAPIVersion, wantModel: "gpt-5-mini"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, captured := newAzureCaptureServer(t)
+			defer srv.Close()
+
+			provider, err := newProviderAdapterForConfig(config.AIProvider{
+				ID:         "azure",
+				Type:       "azure_openai",
+				BaseURL:    srv.URL + tc.baseURL,
+				Deployment: tc.deployment,
+				APIVersion: tc.apiVersion,
+			}, "azure-key", nil)
+			if err != nil {
+				t.Fatalf("newProviderAdapterForConfig: %v", err)
+			}
+			configureProviderRetry(provider, providerRetryPolicy{})
+			_, _ = provider.StreamTurn(context.Background(), TurnRequest{
+				Model:    "gpt-5-mini",
+				Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "hi"}}}},
+			}, nil)
+
+			var got azureCapturedRequest
+			select {
+			case got = <-captured:
+			default:
+				t.Fatalf("no request reached the azure endpoint")
+			}
+			if got.path != "/openai/responses" {
+				t.Fatalf("path=%q, want /openai/responses", got.path)
+			}
+			if got.apiVersion != tc.wantVersion {
+				t.Fatalf("api-version=%q, want %q", got.apiVersion, tc.wantVersion)
+			}
+			if got.apiKey != "azure-key" || got.auth != "" {
+				t.Fatalf("api-key=%q authorization=%q, want api-key only", got.apiKey, got.auth)
+			}
+			if got.model != tc.wantModel {
+				t.Fatalf("model=%q, want %q", got.model, tc.wantModel)
+			}
+		})
+	}
+}
+
+func TestAzureOpenAIProvider_ConfigAndStrictPolicy(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newProviderAdapterForConfig(config.AIProvider{Type: "azure_openai", BaseURL: "https://res.openai.azure.com"}, "", nil); err == nil {
+		t.Fatalf("expected missing api key error")
+	}
+	if _, err := newProviderAdapterForConfig(config.AIProvider{Type: "azure_openai"}, "azure-key", nil); err == nil {
+		t.Fatalf("expected missing endpoint error")
+	}
+	if got, err := azureOpenAIBaseURL("https://res.openai.azure.com/"); err != nil || got != "https://res.openai.azure.com/openai/" {
+		t.Fatalf("azureOpenAIBaseURL=%q err=%v", got, err)
+	}
+
+	provider, err := newProviderAdapterForConfig(config.AIProvider{Type: "azure_openai", BaseURL: "https://res.openai.azure.com"}, "azure-key", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapterForConfig: %v", err)
+	}
+	if p, ok := provider.(*openAIProvider); !ok || p.strictToolSchema {
+		t.Fatalf("azure provider=%T strict=%v, want non-strict openAIProvider", provider, ok && p.strictToolSchema)
+	}
+	provider, err = newProviderAdapterForConfig(config.AIProvider{Type: "azure_openai", BaseURL: "https://res.openai.azure.com", StrictToolSchema: boolPtr(true)}, "azure-key", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapterForConfig strict override: %v", err)
+	}
+	if p := provider.(*openAIProvider); !p.strictToolSchema {
+		t.Fatalf("strict override ignored")
+	}
+}
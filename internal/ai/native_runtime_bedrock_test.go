@@ -0,0 +1,242 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const (
+	bedrockTestAccessKey    = "AKIDBEDROCKTEST"
+	bedrockTestSecretKey    = "bedrock-test-secret"
+	bedrockTestSessionToken = "bedrock-test-session"
+	bedrockTestModelID      = "us.anthropic.claude-sonnet-4-5-20250929-v1:0"
+)
+
+type bedrockCapturedRequest struct {
+	method   string
+	rawPath  string
+	header   http.Header
+	host     string
+	body     []byte
+	sigError string
+}
+
+// newBedrockFixtureServer answers every request with the recorded event stream in testdata/bedrock
+// and verifies the SigV4 signature of the request it received.
+func newBedrockFixtureServer(t *testing.T) (*httptest.Server, <-chan bedrockCapturedRequest) {
+	t.Helper()
+	frames := encodeBedrockStreamFixture(t, filepath.Join("testdata", "bedrock", "invoke_stream.jsonl"))
+	captured := make(chan bedrockCapturedRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got := bedrockCapturedRequest{
+			method:  r.Method,
+			rawPath: r.URL.EscapedPath(),
+			header:  r.Header.Clone(),
+			host:    r.Host,
+			body:    body,
+		}
+		if err := verifyBedrockSigV4(r, body); err != "" {
+			got.sigError = err
+		}
+		captured <- got
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(frames)
+	}))
+	return srv, captured
+}
+
+// encodeBedrockStreamFixture wraps each recorded Anthropic stream event in a Bedrock "chunk" event-stream frame.
+func encodeBedrockStreamFixture(t *testing.T, path string) []byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer f.Close()
+	var out bytes.Buffer
+	enc := eventstream.NewEncoder()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		payload, _ := json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString(line)})
+		msg := eventstream.Message{Payload: payload}
+		msg.Headers.Set(":message-type", eventstream.StringValue("event"))
+		msg.Headers.Set(":event-type", eventstream.StringValue("chunk"))
+		msg.Headers.Set(":content-type", eventstream.StringValue("application/json"))
+		if err := enc.Encode(&out, msg); err != nil {
+			t.Fatalf("encode frame: %v", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return out.Bytes()
+}
+
+// verifyBedrockSigV4 re-signs the received request with the test credentials at its X-Amz-Date and
+// compares the result with the Authorization header the client sent.
+func verifyBedrockSigV4(r *http.Request, body []byte) string {
+	auth := r.Header.Get("Authorization")
+	_, signedPart, ok := strings.Cut(auth, "SignedHeaders=")
+	if !ok {
+		return "missing SignedHeaders in " + auth
+	}
+	signedHeaders, _, _ := strings.Cut(signedPart, ",")
+	signingTime, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return "invalid X-Amz-Date: " + err.Error()
+	}
+	replay, err := http.NewRequest(r.Method, "http://"+r.Host+r.RequestURI, bytes.NewReader(body))
+	if err != nil {
+		return err.Error()
+	}
+	for _, name := range strings.Split(signedHeaders, ";") {
+		if name == "host" || name == "content-length" {
+			continue
+		}
+		for _, v := range r.Header.Values(name) {
+			replay.Header.Add(name, v)
+		}
+	}
+	sum := sha256.Sum256(body)
+	creds := aws.Credentials{AccessKeyID: bedrockTestAccessKey, SecretAccessKey: bedrockTestSecretKey, SessionToken: bedrockTestSessionToken}
+	if err := v4.NewSigner().SignHTTP(context.Background(), creds, replay, hex.EncodeToString(sum[:]), "bedrock", "us-west-2", signingTime); err != nil {
+		return err.Error()
+	}
+	if want := replay.Header.Get("Authorization"); want != auth {
+		return "signature mismatch:\n got " + auth + "\nwant " + want
+	}
+	return ""
+}
+
+func TestBedrockProvider_SigV4AndRequestShape(t *testing.T) {
+	// Not parallel: credentials come from the AWS environment, and ANTHROPIC_API_KEY must not leak to AWS.
+	t.Setenv("AWS_ACCESS_KEY_ID", bedrockTestAccessKey)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", bedrockTestSecretKey)
+	t.Setenv("AWS_SESSION_TOKEN", bedrockTestSessionToken)
+	t.Setenv("AWS_REGION", "eu-central-1")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_BEARER_TOKEN_BEDROCK", "")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-env")
+
+	srv, captured := newBedrockFixtureServer(t)
+	defer srv.Close()
+
+	provider, err := newProviderAdapterForConfig(config.AIProvider{
+		ID:      "bedrock",
+		Type:    "bedrock",
+		Region:  "us-west-2",
+		BaseURL: srv.URL,
+	}, "", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapterForConfig: %v", err)
+	}
+	configureProviderRetry(provider, providerRetryPolicy{})
+
+	result, err := provider.StreamTurn(context.Background(), TurnRequest{
+		Model: bedrockTestModelID,
+		Messages: []Message{
+			{Role: "system", Content: []ContentPart{{Type: "text", Text: "You are a careful operator."}}},
+			{Role: "user", Content: []ContentPart{{Type: "text", Text: "List the workspace files."}}},
+		},
+		Tools: []ToolDef{{
+			Name:        "terminal.exec",
+			Description: "Run a shell command.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"command":{"type":"string"}},"required":["command"]}`),
+		}},
+		Budgets: TurnBudgets{MaxOutputToken: 1024},
+	}, nil)
+	if err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+
+	var got bedrockCapturedRequest
+	select {
+	case got = <-captured:
+	default:
+		t.Fatalf("no request reached the bedrock endpoint")
+	}
+	if got.sigError != "" {
+		t.Fatalf("sigv4: %s", got.sigError)
+	}
+	if got.method != http.MethodPost || got.rawPath != "/model/us.anthropic.claude-sonnet-4-5-20250929-v1%3A0/invoke-with-response-stream" {
+		t.Fatalf("request=%s %s", got.method, got.rawPath)
+	}
+	auth := got.header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+bedrockTestAccessKey+"/") || !strings.Contains(auth, "/us-west-2/bedrock/aws4_request") {
+		t.Fatalf("authorization=%q, want a us-west-2 bedrock SigV4 credential scope", auth)
+	}
+	if got.header.Get("X-Amz-Security-Token") != bedrockTestSessionToken || got.header.Get("X-Amz-Date") == "" {
+		t.Fatalf("x-amz headers=%v", got.header)
+	}
+	if v := got.header.Get("X-Api-Key"); v != "" {
+		t.Fatalf("x-api-key=%q leaked to bedrock", v)
+	}
+
+	wantBody, err := os.ReadFile(filepath.Join("testdata", "bedrock", "invoke_request.json"))
+	if err != nil {
+		t.Fatalf("read request fixture: %v", err)
+	}
+	var gotShape, wantShape any
+	if err := json.Unmarshal(got.body, &gotShape); err != nil {
+		t.Fatalf("request body: %v", err)
+	}
+	if err := json.Unmarshal(wantBody, &wantShape); err != nil {
+		t.Fatalf("request fixture: %v", err)
+	}
+	if !reflect.DeepEqual(gotShape, wantShape) {
+		t.Fatalf("request body=%s, want the recorded shape %s", got.body, wantBody)
+	}
+
+	if strings.TrimSpace(result.Text) != "Checking the workspace." {
+		t.Fatalf("text=%q", result.Text)
+	}
+	if result.FinishReason != "tool_calls" {
+		t.Fatalf("finish_reason=%q, want tool_calls", result.FinishReason)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Name != "terminal.exec" || result.ToolCalls[0].Args["command"] != "ls -la" {
+		t.Fatalf("tool_calls=%+v", result.ToolCalls)
+	}
+	if result.Usage.InputTokens != 412 || result.Usage.OutputTokens != 37 {
+		t.Fatalf("usage=%+v", result.Usage)
+	}
+}
+
+func TestBedrockProvider_Config(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newBedrockProviderWithAWSConfig(aws.Config{}, "", providerRetryPolicy{}, nil); err == nil {
+		t.Fatalf("expected missing region error")
+	}
+	if got := bedrockRuntimeEndpoint(" us-east-1 "); got != "https://bedrock-runtime.us-east-1.amazonaws.com" {
+		t.Fatalf("bedrockRuntimeEndpoint=%q", got)
+	}
+}
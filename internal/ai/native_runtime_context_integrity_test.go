@@ -37,7 +37,7 @@ func TestCompactMessages_PrependsDeclarationForRetainedToolResult(t *testing.T)
 		{Role: "user", Content: []ContentPart{{Type: "text", Text: "filler-9"}}},
 	}
 
-	compacted, stats := compactMessages(messages)
+	compacted, stats := compactMessages(messages, resolveCompactionConfig(nil, nativeDefaultContextLimit))
 	if stats.PrependedAssistantMessages < 1 {
 		t.Fatalf("prepended_assistant_messages=%d, want >=1", stats.PrependedAssistantMessages)
 	}
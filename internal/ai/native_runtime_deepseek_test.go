@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeepSeekProvider_StreamTurn_ReasoningContent(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(strings.TrimSpace(r.URL.Path), "/chat/completions") {
+			t.Errorf("path=%s, want /chat/completions", r.URL.Path)
+		}
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if !anyBool(req["stream"]) {
+			t.Errorf("stream=%v, want true", req["stream"])
+		}
+
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatalf("response writer does not support flushing")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk := func(delta map[string]any, finish any) map[string]any {
+			return map[string]any{
+				"id":      "chatcmpl_deepseek_1",
+				"object":  "chat.completion.chunk",
+				"created": 123,
+				"model":   "deepseek-reasoner",
+				"choices": []any{map[string]any{"index": 0, "finish_reason": finish, "delta": delta}},
+			}
+		}
+		writeOpenAISSEJSON(w, f, chunk(map[string]any{"role": "assistant", "content": nil, "reasoning_content": "Check"}, nil))
+		writeOpenAISSEJSON(w, f, chunk(map[string]any{"content": nil, "reasoning_content": " the units."}, nil))
+		writeOpenAISSEJSON(w, f, chunk(map[string]any{"content": "42", "reasoning_content": nil}, nil))
+		writeOpenAISSEJSON(w, f, chunk(map[string]any{"content": " km"}, "length"))
+		writeOpenAISSEJSON(w, f, map[string]any{
+			"id":      "chatcmpl_deepseek_1",
+			"object":  "chat.completion.chunk",
+			"created": 123,
+			"model":   "deepseek-reasoner",
+			"choices": []any{},
+			"usage": map[string]any{
+				"prompt_tokens":     12,
+				"completion_tokens": 9,
+				"total_tokens":      21,
+				"completion_tokens_details": map[string]any{
+					"reasoning_tokens": 5,
+				},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	provider, err := newProviderAdapter("deepseek", srv.URL+"/v1", "sk-test", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapter: %v", err)
+	}
+	p, ok := provider.(*deepseekProvider)
+	if !ok {
+		t.Fatalf("provider=%T, want *deepseekProvider", provider)
+	}
+	if p.strictToolSchema {
+		t.Fatalf("deepseek must default to non-strict tool schema")
+	}
+
+	events := make([]StreamEvent, 0, 8)
+	result, err := provider.StreamTurn(context.Background(), TurnRequest{
+		Model:    "deepseek-reasoner",
+		Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "How far?"}}}},
+	}, func(event StreamEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+	if result.Text != "42 km" {
+		t.Fatalf("text=%q, want %q", result.Text, "42 km")
+	}
+	if result.Reasoning != "Check the units." {
+		t.Fatalf("reasoning=%q, want %q", result.Reasoning, "Check the units.")
+	}
+	if got := strings.Join(streamEventTexts(events, StreamEventThinkingDelta), ""); got != "Check the units." {
+		t.Fatalf("thinking deltas=%q, want %q", got, "Check the units.")
+	}
+	if countStreamEvent(events, StreamEventThinkingDelta) != 2 || countStreamEvent(events, StreamEventTextDelta) != 2 {
+		t.Fatalf("thinking=%d text=%d, want incremental deltas", countStreamEvent(events, StreamEventThinkingDelta), countStreamEvent(events, StreamEventTextDelta))
+	}
+	if result.FinishReason != "length" {
+		t.Fatalf("finish_reason=%q, want length", result.FinishReason)
+	}
+	if result.Usage.InputTokens != 12 || result.Usage.OutputTokens != 9 || result.Usage.ReasoningTokens != 5 {
+		t.Fatalf("usage=%+v", result.Usage)
+	}
+}
@@ -0,0 +1,182 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	openai "github.com/openai/openai-go"
+)
+
+var mistralToolCallIDPattern = regexp.MustCompile(`^[A-Za-z0-9]{9}$`)
+
+func twoToolCallHistory() []Message {
+	return []Message{
+		{Role: "user", Content: []ContentPart{{Type: "text", Text: "compare the two files"}}},
+		{Role: "assistant", Content: []ContentPart{
+			{Type: "reasoning", Text: "read both files first"},
+			{Type: "tool_call", ToolCallID: "call_readme_1", ToolName: "file.read", ArgsJSON: `{"file_path":"README.md"}`},
+			{Type: "tool_call", ToolCallID: "call_license_2", ToolName: "file.read", ArgsJSON: `{"file_path":"LICENSE"}`},
+		}},
+		{Role: "tool", Content: []ContentPart{
+			{Type: "tool_result", ToolCallID: "call_readme_1", Text: "# readme"},
+			{Type: "tool_result", ToolCallID: "call_license_2", Text: "MIT"},
+		}},
+	}
+}
+
+// assertChatToolPairing checks that the assistant turn is followed by one tool message per tool call, in call order.
+func assertChatToolPairing(t *testing.T, messages []openai.ChatCompletionMessageParamUnion) []string {
+	t.Helper()
+	if len(messages) != 4 {
+		t.Fatalf("messages=%d, want user + assistant + 2 tool", len(messages))
+	}
+	assistant := messages[1].OfAssistant
+	if assistant == nil || len(assistant.ToolCalls) != 2 {
+		t.Fatalf("messages[1] is not an assistant turn with two tool calls: %+v", messages[1])
+	}
+	ids := make([]string, 0, 2)
+	for i, call := range assistant.ToolCalls {
+		tool := messages[2+i].OfTool
+		if tool == nil {
+			t.Fatalf("messages[%d] is not a tool message", 2+i)
+		}
+		if tool.ToolCallID != call.ID {
+			t.Fatalf("tool message %d references %q, want assistant call id %q", i, tool.ToolCallID, call.ID)
+		}
+		ids = append(ids, call.ID)
+	}
+	return ids
+}
+
+func TestBuildOpenAIChatMessages_TwoToolCallPairing(t *testing.T) {
+	t.Parallel()
+
+	messages := buildOpenAIChatMessages(twoToolCallHistory())
+	if ids := assertChatToolPairing(t, messages); ids[0] != "call_readme_1" || ids[1] != "call_license_2" {
+		t.Fatalf("ids=%v, want the original call ids", ids)
+	}
+
+	mistral := normalizeMistralChatMessages(buildOpenAIChatMessages(twoToolCallHistory()))
+	ids := assertChatToolPairing(t, mistral)
+	for _, id := range ids {
+		if !mistralToolCallIDPattern.MatchString(id) {
+			t.Fatalf("mistral tool call id %q is not nine alphanumeric characters", id)
+		}
+	}
+	if ids[0] == ids[1] {
+		t.Fatalf("distinct call ids must stay distinct: %v", ids)
+	}
+	if got := mistralToolCallID("abcDEF123"); got != "abcDEF123" {
+		t.Fatalf("valid mistral id rewritten to %q", got)
+	}
+	raw, err := json.Marshal(mistral)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(raw), "reasoning_content") {
+		t.Fatalf("mistral history must not carry reasoning_content: %s", raw)
+	}
+}
+
+func TestMistralProvider_StreamTurn(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(strings.TrimSpace(r.URL.Path), "/chat/completions") {
+			t.Errorf("path=%s, want /chat/completions", r.URL.Path)
+		}
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if _, ok := req["stream_options"]; ok {
+			t.Errorf("mistral request must not send stream_options")
+		}
+		raw, _ := json.Marshal(req["messages"])
+		if strings.Contains(string(raw), "reasoning_content") || strings.Contains(string(raw), "call_readme_1") {
+			t.Errorf("history was not normalized for mistral: %s", raw)
+		}
+
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Errorf("response writer does not support flushing")
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk := func(delta map[string]any, finish any) map[string]any {
+			return map[string]any{
+				"id":      "cmpl_mistral_1",
+				"object":  "chat.completion.chunk",
+				"created": 123,
+				"model":   "mistral-large-latest",
+				"choices": []any{map[string]any{"index": 0, "finish_reason": finish, "delta": delta}},
+			}
+		}
+		// Mistral sends whole tool calls and repeats index 0 for each of them.
+		for _, call := range []struct{ id, path string }{{"Ab3dE6gH9", "README.md"}, {"Zy8xW7vU6", "LICENSE"}} {
+			writeOpenAISSEJSON(w, f, chunk(map[string]any{"role": "assistant", "tool_calls": []any{map[string]any{
+				"index":    0,
+				"id":       call.id,
+				"type":     "function",
+				"function": map[string]any{"name": sanitizeProviderToolName("file.read"), "arguments": `{"file_path":"` + call.path + `"}`},
+			}}}, nil))
+		}
+		writeOpenAISSEJSON(w, f, chunk(map[string]any{}, "tool_calls"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+		f.Flush()
+	}))
+	defer srv.Close()
+
+	provider, err := newProviderAdapter("mistral", srv.URL+"/v1", "sk-mistral", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapter: %v", err)
+	}
+	p, ok := provider.(*mistralProvider)
+	if !ok {
+		t.Fatalf("provider=%T, want *mistralProvider", provider)
+	}
+	if p.strictToolSchema {
+		t.Fatalf("mistral must default to non-strict tool schema")
+	}
+	history := append(twoToolCallHistory(), Message{Role: "user", Content: []ContentPart{{Type: "text", Text: "now read them again"}}})
+	result, err := provider.StreamTurn(context.Background(), TurnRequest{
+		Model:    "mistral-large-latest",
+		Messages: history,
+		Tools: []ToolDef{{
+			Name:        "file.read",
+			Description: "Read a file",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"file_path":{"type":"string"}},"required":["file_path"]}`),
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+	if len(result.ToolCalls) != 2 {
+		t.Fatalf("tool_calls=%+v, want 2 calls split by id", result.ToolCalls)
+	}
+	if c := result.ToolCalls[0]; c.ID != "Ab3dE6gH9" || c.Name != "file.read" || c.Args["file_path"] != "README.md" {
+		t.Fatalf("first call=%+v", c)
+	}
+	if c := result.ToolCalls[1]; c.ID != "Zy8xW7vU6" || c.Args["file_path"] != "LICENSE" {
+		t.Fatalf("second call=%+v", c)
+	}
+	if result.FinishReason != "tool_calls" {
+		t.Fatalf("finish_reason=%q, want tool_calls", result.FinishReason)
+	}
+}
+
+func TestMapOpenAIChatFinishReason_MistralModelLength(t *testing.T) {
+	t.Parallel()
+
+	if got := mapOpenAIChatFinishReason("model_length"); got != "length" {
+		t.Fatalf("model_length mapped to %q, want length", got)
+	}
+	if got := mapOpenAIChatFinishReason("error"); got != "unknown" {
+		t.Fatalf("error mapped to %q, want unknown", got)
+	}
+}
@@ -819,3 +819,115 @@ func anyBool(v any) bool {
 		return false
 	}
 }
+
+func TestMoonshotProvider_StreamTurn_FallsBackWhenGatewayRejectsStreaming(t *testing.T) {
+	t.Parallel()
+
+	var streamCalls, blockingCalls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if anyBool(req["stream"]) {
+			streamCalls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"stream mode is not supported","type":"invalid_request_error"}}`))
+			return
+		}
+		blockingCalls.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl_blocking_1",
+			"object":  "chat.completion",
+			"created": 123,
+			"model":   "kimi-k2.5",
+			"choices": []any{
+				map[string]any{
+					"index":         0,
+					"finish_reason": "tool_calls",
+					"message": map[string]any{
+						"role":              "assistant",
+						"content":           "Checking.",
+						"reasoning_content": "Need the file first.",
+						"tool_calls": []any{
+							map[string]any{
+								"id":       "",
+								"type":     "function",
+								"function": map[string]any{"name": "file_read", "arguments": `{"path":"README.md"}`},
+							},
+						},
+					},
+				},
+			},
+			"usage": map[string]any{"prompt_tokens": 7, "completion_tokens": 4, "total_tokens": 11},
+		})
+	}))
+	defer srv.Close()
+
+	provider, err := newProviderAdapter("moonshot", srv.URL+"/v1", "sk-test", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapter: %v", err)
+	}
+	turn := func() (TurnResult, []StreamEvent) {
+		t.Helper()
+		events := make([]StreamEvent, 0, 8)
+		result, err := provider.StreamTurn(context.Background(), TurnRequest{
+			Model:    "kimi-k2.5",
+			Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "read it"}}}},
+			Tools:    []ToolDef{{Name: "file.read", InputSchema: json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}}}`)}},
+		}, func(event StreamEvent) {
+			events = append(events, event)
+		})
+		if err != nil {
+			t.Fatalf("StreamTurn: %v", err)
+		}
+		return result, events
+	}
+
+	result, events := turn()
+	if result.Text != "Checking." || result.Reasoning != "Need the file first." {
+		t.Fatalf("text=%q reasoning=%q", result.Text, result.Reasoning)
+	}
+	if result.FinishReason != "tool_calls" || len(result.ToolCalls) != 1 {
+		t.Fatalf("finish=%q tool_calls=%+v", result.FinishReason, result.ToolCalls)
+	}
+	call := result.ToolCalls[0]
+	if call.ID != "moonshot_call_1" || call.Name != "file.read" || call.Args["path"] != "README.md" {
+		t.Fatalf("tool call=%+v", call)
+	}
+	if result.RawProviderDiag["stream_fallback"] != true {
+		t.Fatalf("missing stream_fallback diag: %v", result.RawProviderDiag)
+	}
+	if got := strings.Join(streamEventTexts(events, StreamEventTextDelta), ""); got != "Checking." {
+		t.Fatalf("text deltas=%q", got)
+	}
+	if got := strings.Join(streamEventTexts(events, StreamEventThinkingDelta), ""); got != "Need the file first." {
+		t.Fatalf("thinking deltas=%q", got)
+	}
+	if !containsStreamEvent(events, StreamEventToolCallEnd) || !containsStreamEvent(events, StreamEventFinishReason) {
+		t.Fatalf("missing replayed tool call or finish events")
+	}
+
+	_, _ = turn()
+	if got := streamCalls.Load(); got != 1 {
+		t.Fatalf("stream attempts=%d, want 1 (later turns should skip streaming)", got)
+	}
+	if got := blockingCalls.Load(); got != 2 {
+		t.Fatalf("blocking calls=%d, want 2", got)
+	}
+}
+
+func TestIsChatStreamRejected(t *testing.T) {
+	t.Parallel()
+
+	if !isChatStreamRejected(errChatStreamNoChunks) {
+		t.Fatalf("a response without stream chunks should count as rejected streaming")
+	}
+	if isChatStreamRejected(fmt.Errorf("dial tcp: connection refused")) {
+		t.Fatalf("network errors must not trigger the blocking fallback")
+	}
+	if isChatStreamRejected(nil) {
+		t.Fatalf("nil error is not a rejection")
+	}
+}
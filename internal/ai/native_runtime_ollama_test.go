@@ -0,0 +1,201 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newOllamaToolCallServer(t *testing.T, toolName string, callID string, argChunks ...string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(strings.TrimSpace(r.URL.Path), "/chat/completions") {
+			t.Errorf("path=%s, want /chat/completions", r.URL.Path)
+		}
+		if got := strings.TrimSpace(r.Header.Get("Authorization")); got != "Bearer ollama" {
+			t.Errorf("authorization=%q, want placeholder Bearer ollama", got)
+		}
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if got := extractOpenAIToolNames(req); len(got) != 1 || got[0] != toolName {
+			t.Errorf("tools=%v, want [%s]", got, toolName)
+		}
+
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Errorf("response writer does not support flushing")
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk := func(delta map[string]any, finish any) map[string]any {
+			return map[string]any{
+				"id":      "chatcmpl_ollama_1",
+				"object":  "chat.completion.chunk",
+				"created": 123,
+				"model":   "qwen2.5-coder",
+				"choices": []any{map[string]any{"index": 0, "finish_reason": finish, "delta": delta}},
+			}
+		}
+		for i, args := range argChunks {
+			fn := map[string]any{"arguments": args}
+			tc := map[string]any{"index": 0, "function": fn}
+			if i == 0 {
+				fn["name"] = toolName
+				tc["type"] = "function"
+				if callID != "" {
+					tc["id"] = callID
+				}
+			}
+			writeOpenAISSEJSON(w, f, chunk(map[string]any{"role": "assistant", "tool_calls": []any{tc}}, nil))
+		}
+		writeOpenAISSEJSON(w, f, chunk(map[string]any{}, "tool_calls"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+		f.Flush()
+	}))
+}
+
+func ollamaToolTurnRequest() TurnRequest {
+	return TurnRequest{
+		Model:    "qwen2.5-coder",
+		Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "read the readme"}}}},
+		Tools: []ToolDef{{
+			Name:        "file.read",
+			Description: "Read a file",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}},"required":["path"]}`),
+		}},
+	}
+}
+
+func TestOllamaProvider_StreamTurn_ToolCall(t *testing.T) {
+	t.Parallel()
+
+	srv := newOllamaToolCallServer(t, sanitizeProviderToolName("file.read"), "call_1", `{"path":`, `"README.md"}`)
+	defer srv.Close()
+
+	provider, err := newProviderAdapter("ollama", srv.URL+"/v1", "", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapter without api key: %v", err)
+	}
+	result, err := provider.StreamTurn(context.Background(), ollamaToolTurnRequest(), nil)
+	if err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+	if len(result.ToolCalls) != 1 {
+		t.Fatalf("tool_calls=%d, want 1", len(result.ToolCalls))
+	}
+	call := result.ToolCalls[0]
+	if call.ID != "call_1" || call.Name != "file.read" || call.Args["path"] != "README.md" {
+		t.Fatalf("unexpected tool call: %+v", call)
+	}
+	if result.FinishReason != "tool_calls" {
+		t.Fatalf("finish_reason=%q, want tool_calls", result.FinishReason)
+	}
+	if _, ok := result.RawProviderDiag["malformed_tool_args"]; ok {
+		t.Fatalf("well-formed args must not be reported as malformed: %+v", result.RawProviderDiag)
+	}
+}
+
+func TestOllamaProvider_StreamTurn_MalformedToolArgs(t *testing.T) {
+	t.Parallel()
+
+	srv := newOllamaToolCallServer(t, sanitizeProviderToolName("file.read"), "", `{"path": "README.md"`)
+	defer srv.Close()
+
+	provider, err := newProviderAdapter("ollama", srv.URL+"/v1", "", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapter: %v", err)
+	}
+	result, err := provider.StreamTurn(context.Background(), ollamaToolTurnRequest(), nil)
+	if err != nil {
+		t.Fatalf("StreamTurn must degrade instead of failing: %v", err)
+	}
+	if len(result.ToolCalls) != 1 {
+		t.Fatalf("tool_calls=%d, want 1", len(result.ToolCalls))
+	}
+	call := result.ToolCalls[0]
+	if call.ID != "ollama_call_1" || call.Name != "file.read" || len(call.Args) != 0 {
+		t.Fatalf("unexpected tool call: %+v", call)
+	}
+	if got := result.RawProviderDiag["malformed_tool_args"]; got != 1 {
+		t.Fatalf("malformed_tool_args=%v, want 1", got)
+	}
+}
+
+func TestOllamaProvider_StreamTurn_RepairsToolArgs(t *testing.T) {
+	t.Parallel()
+
+	srv := newOllamaToolCallServer(t, sanitizeProviderToolName("file.read"), "call_1", `{'path': 'README.md',`, `}`)
+	defer srv.Close()
+
+	provider, err := newProviderAdapter("ollama", srv.URL+"/v1", "", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapter: %v", err)
+	}
+	result, err := provider.StreamTurn(context.Background(), ollamaToolTurnRequest(), nil)
+	if err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Args["path"] != "README.md" {
+		t.Fatalf("unexpected tool calls: %+v", result.ToolCalls)
+	}
+	if got := result.RawProviderDiag["repaired_tool_args"]; got != 1 {
+		t.Fatalf("repaired_tool_args=%v, want 1", got)
+	}
+	if _, ok := result.RawProviderDiag["malformed_tool_args"]; ok {
+		t.Fatalf("repaired args must not be reported as malformed: %+v", result.RawProviderDiag)
+	}
+}
+
+func TestNewProviderAdapter_APIKeyRequirement(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newProviderAdapter("openai_compatible", "https://example.com/v1", "", nil); err == nil {
+		t.Fatalf("expected missing api key error for openai_compatible")
+	}
+	provider, err := newProviderAdapter("ollama", "", "", nil)
+	if err != nil {
+		t.Fatalf("ollama with default base url: %v", err)
+	}
+	p, ok := provider.(*ollamaProvider)
+	if !ok {
+		t.Fatalf("provider=%T, want *ollamaProvider", provider)
+	}
+	if p.strictToolSchema {
+		t.Fatalf("ollama must default to non-strict tool schema")
+	}
+}
+
+func TestMoonshotProvider_StreamTurn_ParallelToolCallsParam(t *testing.T) {
+	t.Parallel()
+
+	for _, allow := range []bool{false, true} {
+		var got any
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decode request: %v", err)
+			}
+			got = req["parallel_tool_calls"]
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"created\":1,\"model\":\"m\",\"choices\":[{\"index\":0,\"finish_reason\":\"stop\",\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n"))
+		}))
+		provider, err := newProviderAdapter("moonshot", srv.URL+"/v1", "sk-test", nil)
+		if err != nil {
+			t.Fatalf("newProviderAdapter: %v", err)
+		}
+		req := ollamaToolTurnRequest()
+		req.ProviderControls.ParallelToolCalls = allow
+		if _, err := provider.StreamTurn(context.Background(), req, nil); err != nil {
+			t.Fatalf("StreamTurn: %v", err)
+		}
+		srv.Close()
+		if got != allow {
+			t.Fatalf("parallel_tool_calls=%v, want %v", got, allow)
+		}
+	}
+}
@@ -52,6 +52,7 @@ func TestNewProviderAdapter_OpenAIStrictPolicy(t *testing.T) {
 		{name: "deepseek", typ: "deepseek", baseURL: "https://api.deepseek.com", expected: false},
 		{name: "qwen", typ: "qwen", baseURL: "https://dashscope-intl.aliyuncs.com/compatible-mode/v1", expected: false},
 		{name: "moonshot", typ: "moonshot", baseURL: "https://api.moonshot.cn/v1", expected: false},
+		{name: "azure_openai", typ: "azure_openai", baseURL: "https://res.openai.azure.com", expected: false},
 		{name: "openai_custom_gateway_override_true", typ: "openai", baseURL: "https://gateway.example/v1", override: boolPtr(true), expected: true},
 		{name: "openai_official_override_false", typ: "openai", baseURL: "https://api.openai.com/v1", override: boolPtr(false), expected: false},
 	}
@@ -70,6 +71,8 @@ func TestNewProviderAdapter_OpenAIStrictPolicy(t *testing.T) {
 				strict = p.strictToolSchema
 			case *moonshotProvider:
 				strict = p.strictToolSchema
+			case *deepseekProvider:
+				strict = p.strictToolSchema
 			default:
 				t.Fatalf("unexpected provider type %T", provider)
 			}
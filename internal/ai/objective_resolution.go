@@ -0,0 +1,146 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const (
+	objectiveWeakMinRunes         = 12
+	objectiveDerivedMaxRunes      = 240
+	objectiveExtractionSourceMax  = 2000
+	objectiveExtractionMaxOutput  = 160
+	objectiveExtractionTimeout    = 10 * time.Second
+	objectiveClarifyAskUserSource = "objective_clarify"
+)
+
+// objectiveResolution is the outcome of resolving a missing or weak task objective.
+type objectiveResolution struct {
+	Objective string
+	// Source is one of: model, first_user_message, input, clarify.
+	Source  string
+	Clarify bool
+	Error   string
+}
+
+// isWeakTaskObjective reports whether an objective is too thin to anchor guard decisions.
+func isWeakTaskObjective(objective string) bool {
+	return len([]rune(strings.TrimSpace(objective))) < objectiveWeakMinRunes
+}
+
+// firstUserHistoryText returns the first non-empty user message in the run history.
+func firstUserHistoryText(history []RunHistoryMsg) string {
+	for _, msg := range history {
+		if !strings.EqualFold(strings.TrimSpace(msg.Role), "user") {
+			continue
+		}
+		if text := strings.TrimSpace(msg.Text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+func buildObjectiveExtractionMessages(firstUserText string, latestUserText string) []Message {
+	system := strings.Join([]string{
+		"You extract the task objective for an on-device coding assistant.",
+		"Return one plain-text sentence that states what the user wants done.",
+		"Stay in the same language as the user text.",
+		"Do not add steps, explanations, markdown, or quotes.",
+		"If the user text contains no actionable request, return an empty line.",
+	}, "\n")
+	lines := make([]string, 0, 6)
+	if firstUserText != "" {
+		lines = append(lines, "First user message:", truncateRunes(firstUserText, objectiveExtractionSourceMax))
+	}
+	if latestUserText != "" && latestUserText != firstUserText {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "Latest user message:", truncateRunes(latestUserText, objectiveExtractionSourceMax))
+	}
+	return []Message{
+		{Role: "system", Content: []ContentPart{{Type: "text", Text: system}}},
+		{Role: "user", Content: []ContentPart{{Type: "text", Text: strings.Join(lines, "\n")}}},
+	}
+}
+
+func normalizeDerivedObjective(raw string) string {
+	text := strings.TrimSpace(raw)
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.Trim(text, "\"'` ")
+	return strings.TrimSpace(truncateRunes(text, objectiveDerivedMaxRunes))
+}
+
+// resolveTaskObjective applies the configured empty-objective policy.
+//
+// It returns ok=false when the current objective is usable or the policy is off.
+func (r *run) resolveTaskObjective(
+	ctx context.Context,
+	adapter Provider,
+	modelName string,
+	req RunRequest,
+	objective string,
+	complexity string,
+	allowClarify bool,
+) (objectiveResolution, bool) {
+	if r == nil || !isWeakTaskObjective(objective) {
+		return objectiveResolution{}, false
+	}
+	policy := r.cfg.EffectiveEmptyObjectivePolicy()
+	if policy == config.AIEmptyObjectiveOff {
+		return objectiveResolution{}, false
+	}
+	if policy == config.AIEmptyObjectiveClarify && allowClarify && normalizeTaskComplexity(complexity) == TaskComplexityComplex {
+		return objectiveResolution{Source: "clarify", Clarify: true}, true
+	}
+
+	firstUserText := firstUserHistoryText(req.History)
+	latestUserText := strings.TrimSpace(req.Input.Text)
+	if firstUserText == "" && latestUserText == "" {
+		return objectiveResolution{}, false
+	}
+	out := objectiveResolution{}
+	derived, err := deriveTaskObjectiveByModel(ctx, adapter, modelName, firstUserText, latestUserText)
+	if err != nil {
+		out.Error = sanitizeLogText(err.Error(), 240)
+	}
+	switch {
+	case derived != "":
+		out.Objective, out.Source = derived, "model"
+	case firstUserText != "":
+		out.Objective, out.Source = normalizeDerivedObjective(firstUserText), "first_user_message"
+	default:
+		out.Objective, out.Source = normalizeDerivedObjective(latestUserText), "input"
+	}
+	if out.Objective == "" {
+		return objectiveResolution{}, false
+	}
+	return out, true
+}
+
+// deriveTaskObjectiveByModel runs a cheap single-turn, tool-less extraction.
+func deriveTaskObjectiveByModel(ctx context.Context, adapter Provider, modelName string, firstUserText string, latestUserText string) (string, error) {
+	if adapter == nil {
+		return "", errors.New("missing objective provider")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	extractCtx, cancel := context.WithTimeout(ctx, objectiveExtractionTimeout)
+	defer cancel()
+	result, err := adapter.StreamTurn(extractCtx, TurnRequest{
+		Model:     strings.TrimSpace(modelName),
+		Messages:  buildObjectiveExtractionMessages(firstUserText, latestUserText),
+		Budgets:   TurnBudgets{MaxSteps: 1, MaxOutputToken: objectiveExtractionMaxOutput},
+		ModeFlags: ModeFlags{Mode: config.AIModePlan},
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return normalizeDerivedObjective(result.Text), nil
+}
@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestResolveTaskObjective_OffKeepsObjective(t *testing.T) {
+	t.Parallel()
+
+	r := &run{cfg: &config.AIConfig{}}
+	provider := &scriptedTextProvider{deltas: []string{"unused"}}
+	if _, ok := r.resolveTaskObjective(context.Background(), provider, "m", RunRequest{}, "", TaskComplexityStandard, true); ok {
+		t.Fatalf("expected policy off to skip objective resolution")
+	}
+	if len(provider.lastReq.Messages) != 0 {
+		t.Fatalf("provider should not be called when policy is off")
+	}
+}
+
+func TestResolveTaskObjective_DeriveFromFirstUserMessage(t *testing.T) {
+	t.Parallel()
+
+	r := &run{cfg: &config.AIConfig{EmptyObjectivePolicy: config.AIEmptyObjectiveDerive}}
+	provider := &scriptedTextProvider{deltas: []string{"  \"Fix the flaky login test in auth_test.go\"\n"}}
+	req := RunRequest{
+		History: []RunHistoryMsg{
+			{Role: "assistant", Text: "hello"},
+			{Role: "user", Text: "the login test in auth_test.go keeps failing on CI, can you fix it?"},
+		},
+		Input: RunInput{Text: "continue"},
+	}
+	got, ok := r.resolveTaskObjective(context.Background(), provider, "m", req, "continue", TaskComplexityComplex, true)
+	if !ok {
+		t.Fatalf("expected weak objective to be resolved")
+	}
+	if got.Source != "model" || got.Objective != "Fix the flaky login test in auth_test.go" {
+		t.Fatalf("unexpected resolution: %+v", got)
+	}
+	if len(provider.lastReq.Tools) != 0 || provider.lastReq.Budgets.MaxSteps != 1 {
+		t.Fatalf("extraction must be a single tool-less turn: %+v", provider.lastReq.Budgets)
+	}
+	userText := provider.lastReq.Messages[1].Content[0].Text
+	if !strings.Contains(userText, "keeps failing on CI") || !strings.Contains(userText, "Latest user message:") {
+		t.Fatalf("unexpected extraction prompt: %q", userText)
+	}
+}
+
+func TestResolveTaskObjective_DeriveFallsBackToUserText(t *testing.T) {
+	t.Parallel()
+
+	r := &run{cfg: &config.AIConfig{EmptyObjectivePolicy: config.AIEmptyObjectiveDerive}}
+	provider := &scriptedTextProvider{}
+	req := RunRequest{History: []RunHistoryMsg{{Role: "user", Text: "rename   the config loader"}}}
+	got, ok := r.resolveTaskObjective(context.Background(), provider, "m", req, "", TaskComplexityStandard, true)
+	if !ok || got.Source != "first_user_message" || got.Objective != "rename the config loader" {
+		t.Fatalf("unexpected fallback resolution: ok=%v %+v", ok, got)
+	}
+}
+
+func TestResolveTaskObjective_ClarifyOnlyForComplexTasks(t *testing.T) {
+	t.Parallel()
+
+	r := &run{cfg: &config.AIConfig{EmptyObjectivePolicy: config.AIEmptyObjectiveClarify}}
+	req := RunRequest{Input: RunInput{Text: "do it"}}
+
+	got, ok := r.resolveTaskObjective(context.Background(), &scriptedTextProvider{}, "m", req, "do it", TaskComplexityComplex, true)
+	if !ok || !got.Clarify {
+		t.Fatalf("expected clarify for complex task: ok=%v %+v", ok, got)
+	}
+
+	got, ok = r.resolveTaskObjective(context.Background(), &scriptedTextProvider{}, "m", req, "do it", TaskComplexityComplex, false)
+	if !ok || got.Clarify || got.Source != "input" {
+		t.Fatalf("expected derive when clarification is not allowed: ok=%v %+v", ok, got)
+	}
+
+	got, ok = r.resolveTaskObjective(context.Background(), &scriptedTextProvider{}, "m", req, "do it", TaskComplexitySimple, true)
+	if !ok || got.Clarify {
+		t.Fatalf("expected derive for simple task: ok=%v %+v", ok, got)
+	}
+}
+
+func TestResolveTaskObjective_ClearObjectiveIsKept(t *testing.T) {
+	t.Parallel()
+
+	r := &run{cfg: &config.AIConfig{EmptyObjectivePolicy: config.AIEmptyObjectiveClarify}}
+	if _, ok := r.resolveTaskObjective(context.Background(), nil, "m", RunRequest{}, "Add pagination to the thread list API", TaskComplexityComplex, true); ok {
+		t.Fatalf("expected clear objective to be kept")
+	}
+}
@@ -7,7 +7,8 @@ import (
 )
 
 var (
-	errRWXPermissionDenied = errors.New("read/write/execute permission denied")
+	errRWXPermissionDenied     = errors.New("read/write/execute permission denied")
+	errExecutePermissionDenied = errors.New("execute permission denied")
 )
 
 func requireRWX(meta *session.Meta) error {
@@ -19,3 +20,14 @@ func requireRWX(meta *session.Meta) error {
 	}
 	return nil
 }
+
+// requireExecute is the narrower check for actions that only stop work already started, like cancelling a run.
+func requireExecute(meta *session.Meta) error {
+	if meta == nil {
+		return errors.New("missing session metadata")
+	}
+	if !meta.CanExecute {
+		return errExecutePermissionDenied
+	}
+	return nil
+}
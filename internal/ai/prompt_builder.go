@@ -28,6 +28,11 @@ type promptTodoStatus struct {
 	LastUpdatedRound int
 }
 
+type promptScratchpadStatus struct {
+	KeyCount   int
+	TotalBytes int64
+}
+
 type promptRuntimeSnapshot struct {
 	WorkingDir                     string
 	LocalTime                      promptLocalTimeContext
@@ -43,6 +48,7 @@ type promptRuntimeSnapshot struct {
 	TodoPolicy                     string
 	RequiredTodoMinimum            int
 	TodoStatus                     promptTodoStatus
+	ScratchpadStatus               promptScratchpadStatus
 	RecentErrors                   []string
 	AvailableToolNames             string
 	AvailableSkills                []SkillMeta
@@ -54,6 +60,8 @@ type promptRuntimeSnapshot struct {
 	AllowUserInteraction           bool
 	SupportsAskUserQuestionBatches bool
 	ExceptionOverlay               string
+	// SystemPromptProfile is the RunOptions.PromptProfile overlay applied to the static sections.
+	SystemPromptProfile string
 }
 
 type cachedPromptPrefixKey struct {
@@ -65,6 +73,7 @@ type cachedPromptPrefixKey struct {
 	ProtocolSurface                RunProtocolSurface
 	ProtocolCompletionMode         RunCompletionMode
 	ProtocolWaitingMode            RunWaitingMode
+	SystemPromptProfile            string
 }
 
 type promptStaticPrefixCache struct {
@@ -226,6 +235,10 @@ func buildPromptRuntimeSnapshot(r *run, objective string, mode string, complexit
 		activeSkills = r.activeSkills()
 	}
 	protocolProfile := normalizeRunProtocolProfile(capability.ProtocolProfile)
+	systemPromptProfile := ""
+	if r != nil {
+		systemPromptProfile = r.promptProfile
+	}
 
 	return promptRuntimeSnapshot{
 		WorkingDir:          cwd,
@@ -248,6 +261,10 @@ func buildPromptRuntimeSnapshot(r *run, objective string, mode string, complexit
 			SnapshotVersion:  state.TodoSnapshotVersion,
 			LastUpdatedRound: state.TodoLastUpdatedRound,
 		},
+		ScratchpadStatus: promptScratchpadStatus{
+			KeyCount:   state.ScratchpadKeyCount,
+			TotalBytes: state.ScratchpadTotalBytes,
+		},
 		RecentErrors:                   cloneStringSlice(state.RecentErrors),
 		AvailableToolNames:             availableToolNames,
 		AvailableSkills:                availableSkills,
@@ -259,12 +276,13 @@ func buildPromptRuntimeSnapshot(r *run, objective string, mode string, complexit
 		AllowUserInteraction:           allowUserInteraction,
 		SupportsAskUserQuestionBatches: capability.SupportsAskUserQuestionBatches,
 		ExceptionOverlay:               strings.TrimSpace(exceptionOverlay),
+		SystemPromptProfile:            systemPromptProfile,
 	}
 }
 
 func buildPromptDocument(snapshot promptRuntimeSnapshot) promptDocument {
 	spec := resolvePromptProfileSpec(snapshot.PromptProfile)
-	staticSections := buildPromptStaticSections(spec, snapshot)
+	staticSections := applyPromptProfile(snapshot.SystemPromptProfile, buildPromptStaticSections(spec, snapshot))
 	dynamicSections := buildPromptDynamicSections(snapshot)
 	overlaySections := []promptSection{}
 	if overlay := newPromptSectionFromText("exception_overlay", snapshot.ExceptionOverlay); !overlay.isEmpty() {
@@ -279,7 +297,7 @@ func buildPromptDocument(snapshot promptRuntimeSnapshot) promptDocument {
 
 func buildPromptStaticSections(spec promptProfileSpec, snapshot promptRuntimeSnapshot) []promptSection {
 	sections := []promptSection{
-		buildPromptMandateSection(spec),
+		buildPromptMandateSection(spec, snapshot),
 		buildPromptProtocolSurfaceSection(snapshot),
 		buildPromptToolUsageSection(snapshot),
 	}
@@ -335,17 +353,28 @@ func buildPromptDynamicSections(snapshot promptRuntimeSnapshot) []promptSection
 	return sections
 }
 
-func buildPromptMandateSection(spec promptProfileSpec) promptSection {
+func buildPromptMandateSection(spec promptProfileSpec, snapshot promptRuntimeSnapshot) promptSection {
 	lines := []string{"# Identity & Mandate"}
 	lines = append(lines, spec.IdentityLines...)
+	boundary := "The working directory defines the active project boundary for file tools and terminal cwd/workdir. The runtime home is only the outer sandbox; do not assume access outside the active project."
+	if snapshot.WorkspaceContext.Environment.StrictWorkspaceSandbox {
+		boundary = "The working directory is a hard sandbox: file tools, terminal cwd/workdir, cd targets, and absolute path arguments that resolve outside it are rejected."
+	}
 	lines = append(lines,
 		"Operate within the available tools and permission policy for this session.",
-		"The working directory defines the active project boundary for file tools and terminal cwd/workdir. The runtime home is only the outer sandbox; do not assume access outside the active project.",
+		boundary,
 	)
 	lines = append(lines, spec.StrategyLines...)
 	return newPromptSection("identity_mandate", lines...)
 }
 
+func promptFileBoundaryRule(snapshot promptRuntimeSnapshot) string {
+	if snapshot.WorkspaceContext.Environment.StrictWorkspaceSandbox {
+		return "- Keep every path inside the working directory; the strict workspace sandbox rejects anything outside it."
+	}
+	return "- Keep file paths inside the active project boundary; the runtime home is only the outer sandbox."
+}
+
 func buildPromptProtocolSurfaceSection(snapshot promptRuntimeSnapshot) promptSection {
 	lines := []string{"# Active Protocol Surface"}
 	switch snapshot.ProtocolSurface {
@@ -463,7 +492,7 @@ func buildPromptMandatoryRulesSection(snapshot promptRuntimeSnapshot) promptSect
 			"- Prefer file.read for direct file inspection before falling back to shell-based file dumps.",
 			"- Prefer file.edit and file.write for normal file mutations instead of shell redirection or ad-hoc overwrite commands.",
 			"- When the task asks for verification or a verification command, use terminal.exec for that verification; file.read can supplement inspection but does not replace a real verification command.",
-			"- Keep file paths inside the active project boundary; the runtime home is only the outer sandbox.",
+			promptFileBoundaryRule(snapshot),
 			"- Treat the current working directory and any terminal.exec cwd/workdir as the same active project boundary; they must resolve to the current project root rather than some sibling path.",
 			"- Use apply_patch only when the structured file tools are insufficient or you truly need patch semantics.",
 			"- If you call apply_patch, send exactly one canonical patch document from `*** Begin Patch` to `*** End Patch` with relative paths.",
@@ -593,6 +622,7 @@ func buildPromptAskUserPolicySection(snapshot promptRuntimeSnapshot) promptSecti
 		"- Use `response_mode:\"select\"` only when fixed choices are genuinely exhaustive by construction and you set `choices_exhaustive:true`.",
 		"- Use `response_mode:\"select_or_write\"` when fixed choices are not exhaustive and you set `choices_exhaustive:false`, so the UI preserves a standardized typed fallback.",
 		"- Use `response_mode:\"write\"` for direct-input questions with no fixed choices.",
+		"- When a written answer must be a number, date, file path, one of a fixed set of values, or match a format, add `input_spec` (for example {type:\"number\",min:1,integer:true}) so the UI renders the right control and the answer is validated before you resume.",
 		"- For guided questionnaires, quizzes, guessing games, or hidden-target inference turns that narrow hypotheses about the user's real situation, default to a few fixed select choices plus a typed fallback instead of a pure write-only question.",
 		"- If the user explicitly asks for answer choices, fixed options, buttons, or clickable options, do NOT downgrade the question into pure `response_mode:\"write\"`; keep fixed choices and add a typed fallback via `response_mode:\"select_or_write\"` when needed.",
 		"- `choices[]` contains fixed options only. Do not encode the typed fallback as a fake write choice inside `choices[]`.",
@@ -721,6 +751,7 @@ func buildPromptRuntimeContextSection(snapshot promptRuntimeSnapshot) promptSect
 		fmt.Sprintf("- Objective: %s", snapshot.Objective),
 		fmt.Sprintf("- Recent errors: %s", recentErrors),
 		fmt.Sprintf("- Todo tracking: %s", todoStatus),
+		fmt.Sprintf("- Scratchpad: keys=%d,bytes=%d/%d", snapshot.ScratchpadStatus.KeyCount, snapshot.ScratchpadStatus.TotalBytes, scratchpadMaxTotalBytes),
 	)
 	lines = append(lines, interactionContractRuntimeLines(snapshot.InteractionContract)...)
 	if snapshot.AllowUserInteraction {
@@ -750,6 +781,7 @@ func promptStaticPrefixCacheKey(snapshot promptRuntimeSnapshot) cachedPromptPref
 		ProtocolSurface:                snapshot.ProtocolSurface,
 		ProtocolCompletionMode:         snapshot.ProtocolCompletionMode,
 		ProtocolWaitingMode:            snapshot.ProtocolWaitingMode,
+		SystemPromptProfile:            snapshot.SystemPromptProfile,
 	}
 }
 
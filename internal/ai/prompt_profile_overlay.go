@@ -0,0 +1,126 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+)
+
+// Prompt profiles select a system-prompt overlay for A/B evaluation. They are
+// independent of the interaction prompt profiles (main_interactive, ...), which
+// the runtime derives from the run's interaction policy.
+const (
+	PromptProfileDefault           = "default"
+	PromptProfileNaturalEvidenceV2 = "natural_evidence_v2"
+	PromptProfileConciseFinishV1   = "concise_finish_v1"
+)
+
+// PromptProfileSection is one named block of system-prompt lines.
+type PromptProfileSection struct {
+	Name  string   `json:"name"`
+	Lines []string `json:"lines"`
+}
+
+// PromptProfile rewrites the static sections of the layered system prompt.
+type PromptProfile struct {
+	ID string `json:"id"`
+	// Prepend holds sections rendered before every static section.
+	Prepend []PromptProfileSection `json:"prepend,omitempty"`
+	// Replace swaps the lines of existing static sections with the same name.
+	// Sections that are absent from a run (for example plan_mode_rules in act mode) stay absent.
+	Replace []PromptProfileSection `json:"replace,omitempty"`
+}
+
+// ChangedSections lists the names of the sections this profile adds or replaces, sorted.
+func (p PromptProfile) ChangedSections() []string {
+	out := make([]string, 0, len(p.Prepend)+len(p.Replace))
+	for _, section := range p.Prepend {
+		out = append(out, section.Name)
+	}
+	for _, section := range p.Replace {
+		out = append(out, section.Name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+var promptProfileRegistry = map[string]PromptProfile{
+	PromptProfileDefault: {ID: PromptProfileDefault},
+	PromptProfileNaturalEvidenceV2: {
+		ID: PromptProfileNaturalEvidenceV2,
+		Prepend: []PromptProfileSection{{
+			Name: "evidence_style",
+			Lines: []string{
+				"# Evidence Style",
+				"- Ground every claim about the workspace in something you inspected during this run.",
+				"- Weave evidence into natural sentences: name the file, command, or source that supports each point instead of listing raw tool output.",
+				"- Quote only the few lines that matter, and say what they show.",
+				"- When evidence is missing or inconclusive, say so plainly instead of guessing.",
+			},
+		}},
+		Replace: []PromptProfileSection{{
+			Name: "anti_patterns",
+			Lines: []string{
+				"# Anti-Patterns (NEVER do these)",
+				"- Do NOT respond with only text when tools could answer the question.",
+				"- Do NOT call task_complete without first verifying your work.",
+				"- Do NOT give up after a tool error — try a different approach.",
+				"- Do NOT repeat the same tool call with identical arguments.",
+				"- Do NOT pad the final answer with step-by-step narration of the commands you ran.",
+			},
+		}},
+	},
+	PromptProfileConciseFinishV1: {
+		ID: PromptProfileConciseFinishV1,
+		Replace: []PromptProfileSection{{
+			Name: "complexity_policy",
+			Lines: []string{
+				"# Complexity Policy",
+				"- Classify the current request as simple, standard, or complex, and use the fewest tool rounds that give reliable evidence.",
+				"- simple: answer after one focused inspection; skip planning and todos.",
+				"- standard: inspect, act, verify once, then finish.",
+				"- complex: keep a short plan, verify each risky change, and stop as soon as the objective is met.",
+				"- Keep the final answer short: the outcome first, then only the details the user needs to act on it.",
+			},
+		}},
+	},
+}
+
+// LookupPromptProfile returns the registered profile for id. An empty id resolves to the default profile.
+func LookupPromptProfile(id string) (PromptProfile, bool) {
+	id = strings.TrimSpace(strings.ToLower(id))
+	if id == "" {
+		id = PromptProfileDefault
+	}
+	profile, ok := promptProfileRegistry[id]
+	return profile, ok
+}
+
+// resolvePromptProfile falls back to the default profile for unknown ids.
+func resolvePromptProfile(id string) (PromptProfile, bool) {
+	if profile, ok := LookupPromptProfile(id); ok {
+		return profile, true
+	}
+	return promptProfileRegistry[PromptProfileDefault], false
+}
+
+func applyPromptProfile(profileID string, sections []promptSection) []promptSection {
+	profile, _ := resolvePromptProfile(profileID)
+	if len(profile.Prepend) == 0 && len(profile.Replace) == 0 {
+		return sections
+	}
+	replace := make(map[string][]string, len(profile.Replace))
+	for _, section := range profile.Replace {
+		replace[section.Name] = section.Lines
+	}
+	out := make([]promptSection, 0, len(profile.Prepend)+len(sections))
+	for _, section := range profile.Prepend {
+		out = append(out, newPromptSection(section.Name, section.Lines...))
+	}
+	for _, section := range sections {
+		if lines, ok := replace[section.Name]; ok {
+			section = newPromptSection(section.Name, lines...)
+		}
+		out = append(out, section)
+	}
+	return out
+}
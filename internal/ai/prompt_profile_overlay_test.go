@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestBuildLayeredSystemPrompt_AppliesPromptProfile(t *testing.T) {
+	t.Parallel()
+
+	r := newRun(runOptions{Log: slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})), AgentHomeDir: t.TempDir()})
+	contract := resolveRunCapabilityContract(r, defaultStructuredProtocolProfile(), nil, false)
+	build := func() string {
+		return r.buildLayeredSystemPrompt("objective", "act", TaskComplexityStandard, 0, 8, true, nil, newRuntimeState("objective"), "", contract)
+	}
+
+	base := build()
+	if strings.Contains(base, "# Evidence Style") {
+		t.Fatalf("default prompt must not include profile sections")
+	}
+
+	r.promptProfile = PromptProfileNaturalEvidenceV2
+	evidence := build()
+	if !strings.HasPrefix(evidence, "# Evidence Style") {
+		t.Fatalf("natural_evidence_v2 should prepend evidence_style, got prefix %q", evidence[:min(len(evidence), 80)])
+	}
+	if !strings.Contains(evidence, "Do NOT pad the final answer") || !strings.Contains(evidence, "# Identity & Mandate") {
+		t.Fatalf("natural_evidence_v2 should replace anti_patterns and keep other sections")
+	}
+
+	r.promptProfile = PromptProfileConciseFinishV1
+	concise := build()
+	if !strings.Contains(concise, "use the fewest tool rounds") || strings.Contains(concise, "provide deeper investigation") {
+		t.Fatalf("concise_finish_v1 should replace complexity_policy")
+	}
+}
+
+func TestLookupPromptProfile(t *testing.T) {
+	t.Parallel()
+
+	if p, ok := LookupPromptProfile(""); !ok || p.ID != PromptProfileDefault || len(p.ChangedSections()) != 0 {
+		t.Fatalf("empty id=%+v ok=%v, want default without changes", p, ok)
+	}
+	p, ok := LookupPromptProfile(" Natural_Evidence_V2 ")
+	if !ok || strings.Join(p.ChangedSections(), ",") != "anti_patterns,evidence_style" {
+		t.Fatalf("natural_evidence_v2=%+v ok=%v", p.ChangedSections(), ok)
+	}
+	if _, ok := LookupPromptProfile("turbo_v9"); ok {
+		t.Fatalf("unknown id must not resolve")
+	}
+	if p, known := resolvePromptProfile("turbo_v9"); known || p.ID != PromptProfileDefault {
+		t.Fatalf("unknown id should fall back to default, got %q known=%v", p.ID, known)
+	}
+}
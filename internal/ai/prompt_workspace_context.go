@@ -43,6 +43,7 @@ type promptEnvironmentFacts struct {
 	UserInteractionEnabled  bool
 	ToolApprovalEnabled     bool
 	DangerousCommandBlocked bool
+	StrictWorkspaceSandbox  bool
 	WebSearchProvider       string
 	SubagentDelegation      bool
 }
@@ -113,6 +114,7 @@ func collectPromptEnvironmentFacts(r *run, capability runCapabilityContract) pro
 	if r.cfg != nil {
 		out.ToolApprovalEnabled = r.cfg.EffectiveRequireUserApproval()
 		out.DangerousCommandBlocked = r.cfg.EffectiveBlockDangerousCommands()
+		out.StrictWorkspaceSandbox = r.cfg.EffectiveStrictWorkspaceSandbox()
 		out.WebSearchProvider = r.cfg.EffectiveWebSearchProvider()
 	}
 	out.SubagentDelegation = r.allowSubagentDelegate
@@ -471,6 +473,9 @@ func renderPromptEnvironmentFactsLines(env promptEnvironmentFacts) []string {
 		fmt.Sprintf("- Mutating tool approval required: %t", env.ToolApprovalEnabled),
 		fmt.Sprintf("- Dangerous terminal commands hard-blocked: %t", env.DangerousCommandBlocked),
 	)
+	if env.StrictWorkspaceSandbox {
+		lines = append(lines, "- Strict workspace sandbox: true (paths outside the working directory are rejected)")
+	}
 	if provider := strings.TrimSpace(env.WebSearchProvider); provider != "" {
 		lines = append(lines, fmt.Sprintf("- Web search provider: %s", provider))
 	}
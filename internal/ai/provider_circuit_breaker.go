@@ -0,0 +1,325 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+// ErrProviderCircuitOpen is returned when a provider's circuit breaker rejects a run or a model turn.
+var ErrProviderCircuitOpen = errors.New("provider_circuit_open")
+
+const (
+	ProviderCircuitClosed   = "closed"
+	ProviderCircuitOpen     = "open"
+	ProviderCircuitHalfOpen = "half_open"
+)
+
+// ProviderCircuitStatus is the breaker state of one configured provider.
+type ProviderCircuitStatus struct {
+	ProviderID          string `json:"provider_id"`
+	ProviderType        string `json:"provider_type"`
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	FailureThreshold    int    `json:"failure_threshold"`
+	LastFailureAtUnixMs int64  `json:"last_failure_at_unix_ms,omitempty"`
+	OpenedAtUnixMs      int64  `json:"opened_at_unix_ms,omitempty"`
+	RetryAfterMS        int64  `json:"retry_after_ms,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+}
+
+type providerCircuitPolicy struct {
+	FailureThreshold int
+	Window           time.Duration
+	Cooldown         time.Duration
+}
+
+func providerCircuitPolicyFromConfig(cfg *config.AIConfig) providerCircuitPolicy {
+	return providerCircuitPolicy{
+		FailureThreshold: cfg.EffectiveProviderCircuitFailureThreshold(),
+		Window:           time.Duration(cfg.EffectiveProviderCircuitWindowSeconds()) * time.Second,
+		Cooldown:         time.Duration(cfg.EffectiveProviderCircuitCooldownSeconds()) * time.Second,
+	}
+}
+
+// providerCircuitTransition describes a breaker state change, persisted as provider.circuit.state_change.
+type providerCircuitTransition struct {
+	ProviderID          string
+	From                string
+	To                  string
+	ConsecutiveFailures int
+	LastError           string
+}
+
+type providerCircuit struct {
+	state         string
+	failures      int
+	lastFailureAt time.Time
+	openedAt      time.Time
+	lastError     string
+	// trialInFlight is set while the single half-open trial run is running.
+	trialInFlight bool
+}
+
+// providerCircuitBreakers is a process-wide set of breakers keyed by provider id.
+//
+// It is shared by every run (and subagent) of a Service, so one run's failures protect the next runs.
+type providerCircuitBreakers struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	circuits map[string]*providerCircuit
+}
+
+func newProviderCircuitBreakers() *providerCircuitBreakers {
+	return &providerCircuitBreakers{
+		now:      time.Now,
+		circuits: make(map[string]*providerCircuit),
+	}
+}
+
+func (b *providerCircuitBreakers) circuitLocked(providerID string) *providerCircuit {
+	c := b.circuits[providerID]
+	if c == nil {
+		c = &providerCircuit{state: ProviderCircuitClosed}
+		b.circuits[providerID] = c
+	}
+	return c
+}
+
+// admit decides whether a new run may use providerID.
+//
+// An open breaker rejects runs until the cooldown elapses; the first run after that becomes the half-open
+// trial (trial=true) and must call release when it ends. Other runs are rejected while the trial runs.
+func (b *providerCircuitBreakers) admit(providerID string, policy providerCircuitPolicy) (trial bool, transition *providerCircuitTransition, err error) {
+	if b == nil || policy.FailureThreshold <= 0 {
+		return false, nil, nil
+	}
+	providerID = strings.TrimSpace(providerID)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuitLocked(providerID)
+	switch c.state {
+	case ProviderCircuitOpen:
+		wait := c.openedAt.Add(policy.Cooldown).Sub(b.now())
+		if wait > 0 {
+			return false, nil, providerCircuitOpenError(providerID, c, wait)
+		}
+		c.state = ProviderCircuitHalfOpen
+		c.trialInFlight = true
+		return true, &providerCircuitTransition{
+			ProviderID:          providerID,
+			From:                ProviderCircuitOpen,
+			To:                  ProviderCircuitHalfOpen,
+			ConsecutiveFailures: c.failures,
+			LastError:           c.lastError,
+		}, nil
+	case ProviderCircuitHalfOpen:
+		if c.trialInFlight {
+			return false, nil, providerCircuitOpenError(providerID, c, 0)
+		}
+		c.trialInFlight = true
+		return true, nil, nil
+	default:
+		return false, nil, nil
+	}
+}
+
+// allowTurn rejects a model turn while the breaker is open and cooling down.
+//
+// Runs admitted before the breaker opened stop calling the provider instead of spending their recovery budget.
+func (b *providerCircuitBreakers) allowTurn(providerID string, policy providerCircuitPolicy) error {
+	if b == nil || policy.FailureThreshold <= 0 {
+		return nil
+	}
+	providerID = strings.TrimSpace(providerID)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuits[providerID]
+	if c == nil || c.state != ProviderCircuitOpen {
+		return nil
+	}
+	if wait := c.openedAt.Add(policy.Cooldown).Sub(b.now()); wait > 0 {
+		return providerCircuitOpenError(providerID, c, wait)
+	}
+	return nil
+}
+
+// record feeds one model turn outcome into the breaker. Context cancellation never counts as a failure.
+func (b *providerCircuitBreakers) record(providerID string, policy providerCircuitPolicy, turnErr error) *providerCircuitTransition {
+	if b == nil || policy.FailureThreshold <= 0 {
+		return nil
+	}
+	if turnErr != nil && (errors.Is(turnErr, context.Canceled) || errors.Is(turnErr, context.DeadlineExceeded) || errors.Is(turnErr, ErrProviderCircuitOpen)) {
+		return nil
+	}
+	providerID = strings.TrimSpace(providerID)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuitLocked(providerID)
+	from := c.state
+	now := b.now()
+	if turnErr == nil {
+		c.failures = 0
+		c.lastError = ""
+		c.state = ProviderCircuitClosed
+		c.trialInFlight = false
+		if from == ProviderCircuitClosed {
+			return nil
+		}
+		return &providerCircuitTransition{ProviderID: providerID, From: from, To: ProviderCircuitClosed}
+	}
+
+	if !c.lastFailureAt.IsZero() && now.Sub(c.lastFailureAt) > policy.Window {
+		c.failures = 0
+	}
+	c.failures++
+	c.lastFailureAt = now
+	c.lastError = sanitizeProviderTestError(turnErr.Error(), "")
+	if from == ProviderCircuitOpen || (from == ProviderCircuitClosed && c.failures < policy.FailureThreshold) {
+		return nil
+	}
+	c.state = ProviderCircuitOpen
+	c.openedAt = now
+	c.trialInFlight = false
+	return &providerCircuitTransition{
+		ProviderID:          providerID,
+		From:                from,
+		To:                  ProviderCircuitOpen,
+		ConsecutiveFailures: c.failures,
+		LastError:           c.lastError,
+	}
+}
+
+// release ends a half-open trial run that finished without a model turn outcome.
+func (b *providerCircuitBreakers) release(providerID string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.circuits[strings.TrimSpace(providerID)]; c != nil {
+		c.trialInFlight = false
+	}
+}
+
+func (b *providerCircuitBreakers) status(provider config.AIProvider, policy providerCircuitPolicy) ProviderCircuitStatus {
+	providerID := strings.TrimSpace(provider.ID)
+	out := ProviderCircuitStatus{
+		ProviderID:       providerID,
+		ProviderType:     strings.ToLower(strings.TrimSpace(provider.Type)),
+		State:            ProviderCircuitClosed,
+		FailureThreshold: policy.FailureThreshold,
+	}
+	if b == nil {
+		return out
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuits[providerID]
+	if c == nil {
+		return out
+	}
+	out.State = c.state
+	out.ConsecutiveFailures = c.failures
+	out.LastError = c.lastError
+	if !c.lastFailureAt.IsZero() {
+		out.LastFailureAtUnixMs = c.lastFailureAt.UnixMilli()
+	}
+	if c.state == ProviderCircuitOpen {
+		out.OpenedAtUnixMs = c.openedAt.UnixMilli()
+		if wait := c.openedAt.Add(policy.Cooldown).Sub(b.now()); wait > 0 {
+			out.RetryAfterMS = wait.Milliseconds()
+		} else {
+			// The next run becomes the half-open trial.
+			out.State = ProviderCircuitHalfOpen
+		}
+	}
+	return out
+}
+
+func providerCircuitOpenError(providerID string, c *providerCircuit, wait time.Duration) error {
+	msg := fmt.Sprintf("provider %q is disabled after %d consecutive failures", providerID, c.failures)
+	if wait > 0 {
+		msg += fmt.Sprintf("; retry in %s", wait.Round(time.Second))
+	} else {
+		msg += "; a trial run is in progress"
+	}
+	if c.lastError != "" {
+		msg += " (last error: " + c.lastError + ")"
+	}
+	return fmt.Errorf("%w: %s", ErrProviderCircuitOpen, msg)
+}
+
+// circuitBreakerProvider reports every turn outcome of inner to the shared breakers.
+type circuitBreakerProvider struct {
+	inner      Provider
+	breakers   *providerCircuitBreakers
+	providerID string
+	policy     providerCircuitPolicy
+	// onTransition persists breaker state changes caused by this run.
+	onTransition func(providerCircuitTransition)
+}
+
+func (p *circuitBreakerProvider) StreamTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	if err := p.breakers.allowTurn(p.providerID, p.policy); err != nil {
+		return TurnResult{}, err
+	}
+	result, err := p.inner.StreamTurn(ctx, req, onEvent)
+	if err != nil && ctx.Err() != nil {
+		return result, err
+	}
+	if transition := p.breakers.record(p.providerID, p.policy, err); transition != nil && p.onTransition != nil {
+		p.onTransition(*transition)
+	}
+	return result, err
+}
+
+// ProviderCircuitStatus reports the circuit breaker state of every configured provider, in config order.
+func (s *Service) ProviderCircuitStatus() ([]ProviderCircuitStatus, error) {
+	if s == nil {
+		return nil, ErrNotConfigured
+	}
+	s.mu.Lock()
+	cfg := s.cfg
+	s.mu.Unlock()
+	if cfg == nil {
+		return nil, ErrNotConfigured
+	}
+	policy := providerCircuitPolicyFromConfig(cfg)
+	out := make([]ProviderCircuitStatus, 0, len(cfg.Providers))
+	for _, provider := range cfg.Providers {
+		if strings.TrimSpace(provider.ID) == "" {
+			continue
+		}
+		out = append(out, s.providerCircuits.status(provider, policy))
+	}
+	return out, nil
+}
+
+func (r *run) persistProviderCircuitTransition(providerType string, transition providerCircuitTransition) {
+	if r == nil {
+		return
+	}
+	r.debug("ai.provider.circuit.state_change",
+		"provider_id", transition.ProviderID,
+		"provider_type", providerType,
+		"from", transition.From,
+		"to", transition.To,
+		"consecutive_failures", transition.ConsecutiveFailures,
+	)
+	r.persistRunEvent("provider.circuit.state_change", RealtimeStreamKindLifecycle, map[string]any{
+		"provider_id":          transition.ProviderID,
+		"provider_type":        providerType,
+		"from":                 transition.From,
+		"to":                   transition.To,
+		"consecutive_failures": transition.ConsecutiveFailures,
+		"error":                transition.LastError,
+	})
+}
@@ -0,0 +1,141 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+type failingCircuitProvider struct {
+	err   error
+	calls int
+}
+
+func (p *failingCircuitProvider) StreamTurn(context.Context, TurnRequest, func(StreamEvent)) (TurnResult, error) {
+	p.calls++
+	if p.err != nil {
+		return TurnResult{}, p.err
+	}
+	return TurnResult{FinishReason: "stop", Text: "ok"}, nil
+}
+
+func TestProviderCircuitBreakers_OpenHalfOpenClose(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	breakers := newProviderCircuitBreakers()
+	breakers.now = func() time.Time { return now }
+	policy := providerCircuitPolicy{FailureThreshold: 3, Window: time.Minute, Cooldown: 30 * time.Second}
+
+	var transitions []providerCircuitTransition
+	inner := &failingCircuitProvider{err: errors.New("401 invalid api key")}
+	provider := &circuitBreakerProvider{
+		inner:        inner,
+		breakers:     breakers,
+		providerID:   "openai",
+		policy:       policy,
+		onTransition: func(tr providerCircuitTransition) { transitions = append(transitions, tr) },
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := provider.StreamTurn(context.Background(), TurnRequest{}, nil); err == nil {
+			t.Fatalf("turn %d: expected provider error", i)
+		}
+	}
+	if len(transitions) != 1 || transitions[0].To != ProviderCircuitOpen || transitions[0].ConsecutiveFailures != 3 {
+		t.Fatalf("transitions=%+v", transitions)
+	}
+
+	// Open: new runs and further turns fail fast without calling the provider.
+	if _, _, err := breakers.admit("openai", policy); !errors.Is(err, ErrProviderCircuitOpen) || !strings.Contains(err.Error(), "provider_circuit_open") {
+		t.Fatalf("admit while open err=%v", err)
+	}
+	if _, err := provider.StreamTurn(context.Background(), TurnRequest{}, nil); !errors.Is(err, ErrProviderCircuitOpen) || inner.calls != 3 {
+		t.Fatalf("turn while open err=%v calls=%d", err, inner.calls)
+	}
+	if st := breakers.status(providerCfgForTest("openai"), policy); st.State != ProviderCircuitOpen || st.RetryAfterMS != 30_000 || st.LastError == "" {
+		t.Fatalf("status while open=%+v", st)
+	}
+
+	// After the cooldown one trial run is admitted; others wait for its outcome.
+	now = now.Add(31 * time.Second)
+	trial, transition, err := breakers.admit("openai", policy)
+	if err != nil || !trial || transition == nil || transition.To != ProviderCircuitHalfOpen {
+		t.Fatalf("trial admit=%v transition=%+v err=%v", trial, transition, err)
+	}
+	if _, _, err := breakers.admit("openai", policy); !errors.Is(err, ErrProviderCircuitOpen) {
+		t.Fatalf("second admit during trial err=%v", err)
+	}
+
+	// A failed trial reopens the breaker.
+	transitions = nil
+	if _, err := provider.StreamTurn(context.Background(), TurnRequest{}, nil); err == nil {
+		t.Fatalf("trial turn: expected provider error")
+	}
+	if len(transitions) != 1 || transitions[0].From != ProviderCircuitHalfOpen || transitions[0].To != ProviderCircuitOpen {
+		t.Fatalf("trial failure transitions=%+v", transitions)
+	}
+
+	// A successful trial closes it.
+	now = now.Add(31 * time.Second)
+	if trial, _, err := breakers.admit("openai", policy); err != nil || !trial {
+		t.Fatalf("second trial admit=%v err=%v", trial, err)
+	}
+	inner.err = nil
+	transitions = nil
+	if _, err := provider.StreamTurn(context.Background(), TurnRequest{}, nil); err != nil {
+		t.Fatalf("trial turn: %v", err)
+	}
+	if len(transitions) != 1 || transitions[0].To != ProviderCircuitClosed {
+		t.Fatalf("trial success transitions=%+v", transitions)
+	}
+	if st := breakers.status(providerCfgForTest("openai"), policy); st.State != ProviderCircuitClosed || st.ConsecutiveFailures != 0 {
+		t.Fatalf("status after close=%+v", st)
+	}
+}
+
+func TestProviderCircuitBreakers_IgnoresCancellationAndStaleFailures(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	breakers := newProviderCircuitBreakers()
+	breakers.now = func() time.Time { return now }
+	policy := providerCircuitPolicy{FailureThreshold: 2, Window: time.Minute, Cooldown: time.Minute}
+
+	for _, err := range []error{context.Canceled, fmt.Errorf("stream: %w", context.DeadlineExceeded)} {
+		if tr := breakers.record("openai", policy, err); tr != nil {
+			t.Fatalf("cancellation tripped breaker: %+v", tr)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	provider := &circuitBreakerProvider{inner: &failingCircuitProvider{err: errors.New("connection reset")}, breakers: breakers, providerID: "openai", policy: policy}
+	_, _ = provider.StreamTurn(ctx, TurnRequest{}, nil)
+	if st := breakers.status(providerCfgForTest("openai"), policy); st.ConsecutiveFailures != 0 {
+		t.Fatalf("canceled run counted as failure: %+v", st)
+	}
+
+	if tr := breakers.record("openai", policy, errors.New("500")); tr != nil {
+		t.Fatalf("first failure transition=%+v", tr)
+	}
+	now = now.Add(2 * time.Minute)
+	if tr := breakers.record("openai", policy, errors.New("500")); tr != nil {
+		t.Fatalf("failure outside the window opened the breaker: %+v", tr)
+	}
+	if tr := breakers.record("openai", policy, errors.New("500")); tr == nil || tr.To != ProviderCircuitOpen {
+		t.Fatalf("second consecutive failure transition=%+v", tr)
+	}
+
+	disabled := providerCircuitPolicy{}
+	if _, _, err := breakers.admit("openai", disabled); err != nil {
+		t.Fatalf("disabled breaker rejected run: %v", err)
+	}
+}
+
+func providerCfgForTest(id string) config.AIProvider {
+	return config.AIProvider{ID: id, Type: "openai"}
+}
@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const providerDiagMaxStringRunes = 240
+
+// ProviderQuirkReport aggregates provider diagnostics across the model turns of one run.
+type ProviderQuirkReport struct {
+	ProviderID               string         `json:"provider_id"`
+	ProviderType             string         `json:"provider_type"`
+	Turns                    int            `json:"turns"`
+	MissingResponseCompleted int            `json:"missing_response_completed"`
+	MissingResponseID        int            `json:"missing_response_id"`
+	FinishReasons            map[string]int `json:"finish_reasons,omitempty"`
+	LastResponseID           string         `json:"last_response_id,omitempty"`
+}
+
+// RunMetrics aggregates per-run counters that are reported when the run ends.
+//
+// The zero value is ready to use.
+type RunMetrics struct {
+	mu        sync.Mutex
+	providers map[string]*ProviderQuirkReport
+	loop      runLoopCounters
+}
+
+func (m *RunMetrics) recordProviderTurn(providerID string, providerType string, finishReason string, diag map[string]any) {
+	if m == nil {
+		return
+	}
+	providerID = strings.TrimSpace(providerID)
+	providerType = strings.TrimSpace(providerType)
+	key := providerID + "|" + providerType
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.providers == nil {
+		m.providers = make(map[string]*ProviderQuirkReport)
+	}
+	report := m.providers[key]
+	if report == nil {
+		report = &ProviderQuirkReport{ProviderID: providerID, ProviderType: providerType}
+		m.providers[key] = report
+	}
+	report.Turns++
+	if missing, _ := diag["missing_response_completed"].(bool); missing {
+		report.MissingResponseCompleted++
+	}
+	if responseID := providerDiagResponseID(diag); responseID != "" {
+		report.LastResponseID = responseID
+	} else {
+		report.MissingResponseID++
+	}
+	if finishReason = strings.TrimSpace(finishReason); finishReason != "" {
+		if report.FinishReasons == nil {
+			report.FinishReasons = make(map[string]int)
+		}
+		report.FinishReasons[finishReason]++
+	}
+}
+
+// ProviderQuirks returns a stable-ordered copy of the per-provider reports.
+func (m *RunMetrics) ProviderQuirks() []ProviderQuirkReport {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.providers) == 0 {
+		return nil
+	}
+	out := make([]ProviderQuirkReport, 0, len(m.providers))
+	for _, report := range m.providers {
+		cp := *report
+		if len(report.FinishReasons) > 0 {
+			cp.FinishReasons = make(map[string]int, len(report.FinishReasons))
+			for k, v := range report.FinishReasons {
+				cp.FinishReasons[k] = v
+			}
+		}
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ProviderID != out[j].ProviderID {
+			return out[i].ProviderID < out[j].ProviderID
+		}
+		return out[i].ProviderType < out[j].ProviderType
+	})
+	return out
+}
+
+func providerDiagResponseID(diag map[string]any) string {
+	for _, key := range []string{"response_id", "message_id"} {
+		if v, _ := diag[key].(string); strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// redactProviderDiagForPersist drops sensitive keys and bounds string sizes before diagnostics hit the store.
+func redactProviderDiagForPersist(diag map[string]any) map[string]any {
+	if len(diag) == 0 {
+		return map[string]any{}
+	}
+	out := make(map[string]any, len(diag))
+	for k, v := range diag {
+		redacted := redactAnyForPersist(k, v, 0)
+		if s, ok := redacted.(string); ok {
+			redacted = sanitizeLogText(s, providerDiagMaxStringRunes)
+		}
+		out[k] = redacted
+	}
+	return out
+}
+
+// recordProviderTurnDiag aggregates and persists one turn's provider diagnostics when enabled.
+func (r *run) recordProviderTurnDiag(step int, providerID string, providerType string, model string, result TurnResult) {
+	if r == nil || !r.cfg.EffectivePersistProviderDiagnostics() {
+		return
+	}
+	finishReason := strings.TrimSpace(result.FinishReason)
+	r.metrics.recordProviderTurn(providerID, providerType, finishReason, result.RawProviderDiag)
+	r.persistRunEvent("provider.turn.diag", RealtimeStreamKindLifecycle, map[string]any{
+		"step_index":    step,
+		"provider_id":   strings.TrimSpace(providerID),
+		"provider_type": strings.TrimSpace(providerType),
+		"model":         strings.TrimSpace(model),
+		"finish_reason": finishReason,
+		"response_id":   providerDiagResponseID(result.RawProviderDiag),
+		"diag":          redactProviderDiagForPersist(result.RawProviderDiag),
+	})
+}
@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunMetrics_ProviderQuirks(t *testing.T) {
+	t.Parallel()
+
+	var m RunMetrics
+	if quirks := m.ProviderQuirks(); quirks != nil {
+		t.Fatalf("zero RunMetrics quirks=%v, want nil", quirks)
+	}
+	m.recordProviderTurn("gw", "openai_compatible", "tool_calls", map[string]any{"missing_response_completed": true})
+	m.recordProviderTurn("gw", "openai_compatible", "stop", map[string]any{"response_id": "resp_2"})
+	m.recordProviderTurn("claude", "anthropic", "stop", map[string]any{"message_id": "msg_1"})
+
+	quirks := m.ProviderQuirks()
+	if len(quirks) != 2 {
+		t.Fatalf("quirks len=%d, want 2", len(quirks))
+	}
+	if quirks[0].ProviderID != "claude" || quirks[0].LastResponseID != "msg_1" || quirks[0].MissingResponseID != 0 {
+		t.Fatalf("unexpected anthropic report: %+v", quirks[0])
+	}
+	gw := quirks[1]
+	if gw.Turns != 2 || gw.MissingResponseCompleted != 1 || gw.MissingResponseID != 1 || gw.LastResponseID != "resp_2" {
+		t.Fatalf("unexpected gateway report: %+v", gw)
+	}
+	if gw.FinishReasons["stop"] != 1 || gw.FinishReasons["tool_calls"] != 1 {
+		t.Fatalf("unexpected finish reasons: %+v", gw.FinishReasons)
+	}
+
+	gw.FinishReasons["stop"] = 99
+	if again := m.ProviderQuirks(); again[1].FinishReasons["stop"] != 1 {
+		t.Fatalf("ProviderQuirks must return copies")
+	}
+}
+
+func TestRedactProviderDiagForPersist(t *testing.T) {
+	t.Parallel()
+
+	out := redactProviderDiagForPersist(map[string]any{
+		"response_id":   "resp_1",
+		"authorization": "Bearer sk-secret",
+		"note":          strings.Repeat("x", 1000),
+		"missing":       true,
+	})
+	if out["response_id"] != "resp_1" || out["missing"] != true {
+		t.Fatalf("safe values must be kept: %+v", out)
+	}
+	if s, _ := out["authorization"].(string); strings.Contains(s, "sk-secret") {
+		t.Fatalf("authorization must be redacted: %q", s)
+	}
+	if s, _ := out["note"].(string); len([]rune(s)) > providerDiagMaxStringRunes+len("... (truncated)") {
+		t.Fatalf("long strings must be bounded, got %d runes", len([]rune(s)))
+	}
+}
@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const (
+	providerHTTPDialTimeout         = 10 * time.Second
+	providerHTTPTLSHandshakeTimeout = 10 * time.Second
+	providerHTTPIdleConnTimeout     = 90 * time.Second
+	providerHTTPMaxIdleConns        = 32
+	providerHTTPMaxIdleConnsPerHost = 8
+)
+
+// newProviderHTTPClient builds the tuned client a provider's SDK adapter sends requests with.
+func newProviderHTTPClient(provider config.AIProvider) *http.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   providerHTTPDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSClientConfig:       &tls.Config{MinVersion: tls.VersionTLS12},
+		TLSHandshakeTimeout:   providerHTTPTLSHandshakeTimeout,
+		MaxIdleConns:          providerHTTPMaxIdleConns,
+		MaxIdleConnsPerHost:   providerHTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:       providerHTTPIdleConnTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(provider.EffectiveRequestTimeoutMS()) * time.Millisecond,
+	}
+}
+
+// providerHTTPClientFingerprint covers every input that makes a cached client unusable for the provider.
+// The API key is hashed so it is never held as a map key.
+func providerHTTPClientFingerprint(provider config.AIProvider, apiKey string) string {
+	keySum := sha256.Sum256([]byte(strings.TrimSpace(apiKey)))
+	return strings.Join([]string{
+		strings.ToLower(strings.TrimSpace(provider.Type)),
+		strings.TrimSpace(provider.BaseURL),
+		strconv.Itoa(provider.EffectiveRequestTimeoutMS()),
+		hex.EncodeToString(keySum[:]),
+	}, "|")
+}
+
+type providerHTTPClientEntry struct {
+	fingerprint string
+	client      *http.Client
+}
+
+// providerHTTPClients shares one HTTP client per provider across runs so connections are reused.
+//
+// A client is replaced when the provider's type, base URL, request timeout, or API key changes.
+type providerHTTPClients struct {
+	mu      sync.Mutex
+	clients map[string]providerHTTPClientEntry // provider id -> client
+	// traceContext adds the traceparent header of the turn span to provider requests (ai.tracing).
+	traceContext bool
+}
+
+func newProviderHTTPClients() *providerHTTPClients {
+	return &providerHTTPClients{clients: make(map[string]providerHTTPClientEntry)}
+}
+
+func (c *providerHTTPClients) get(provider config.AIProvider, apiKey string) *http.Client {
+	if c == nil {
+		return nil
+	}
+	providerID := strings.TrimSpace(provider.ID)
+	fingerprint := providerHTTPClientFingerprint(provider, apiKey)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.clients[providerID]; ok {
+		if entry.fingerprint == fingerprint {
+			return entry.client
+		}
+		entry.client.CloseIdleConnections()
+	}
+	client := newProviderHTTPClient(provider)
+	if c.traceContext {
+		client.Transport = &traceContextTransport{base: client.Transport}
+	}
+	c.clients[providerID] = providerHTTPClientEntry{fingerprint: fingerprint, client: client}
+	return client
+}
+
+// reset drops every cached client, for example after the AI config changes.
+// In-flight requests keep their client; only its idle connections are closed.
+func (c *providerHTTPClients) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for providerID, entry := range c.clients {
+		entry.client.CloseIdleConnections()
+		delete(c.clients, providerID)
+	}
+}
@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestProviderHTTPClients_ReusesUntilProviderOrKeyChanges(t *testing.T) {
+	t.Parallel()
+
+	clients := newProviderHTTPClients()
+	timeoutMS := 30_000
+	provider := config.AIProvider{ID: "openai", Type: "openai", BaseURL: "https://api.example.com/v1", RequestTimeoutMS: &timeoutMS}
+
+	first := clients.get(provider, "sk-one")
+	if first == nil || first.Timeout != 30*time.Second {
+		t.Fatalf("client=%+v, want the configured request timeout", first)
+	}
+	if again := clients.get(provider, "sk-one"); again != first {
+		t.Fatalf("same provider and key must reuse the client")
+	}
+	if other := clients.get(config.AIProvider{ID: "other", Type: "openai"}, "sk-one"); other == first {
+		t.Fatalf("providers must not share a client")
+	}
+
+	rotated := clients.get(provider, "sk-two")
+	if rotated == first {
+		t.Fatalf("a new api key must replace the client")
+	}
+	provider.BaseURL = "https://gateway.example.com/v1"
+	moved := clients.get(provider, "sk-two")
+	if moved == rotated {
+		t.Fatalf("a new base url must replace the client")
+	}
+
+	clients.reset()
+	if fresh := clients.get(provider, "sk-two"); fresh == moved {
+		t.Fatalf("reset must drop cached clients")
+	}
+}
+
+type countingRoundTripper struct {
+	calls atomic.Int32
+	next  http.RoundTripper
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.calls.Add(1)
+	return c.next.RoundTrip(req)
+}
+
+func TestNewProviderAdapterForConfig_SendsThroughHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"nope"}}`, http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	for _, providerType := range []string{"openai", "anthropic"} {
+		transport := &countingRoundTripper{next: http.DefaultTransport}
+		adapter, err := newProviderAdapterForConfig(config.AIProvider{ID: providerType, Type: providerType, BaseURL: srv.URL}, "sk-test", &http.Client{Transport: transport})
+		if err != nil {
+			t.Fatalf("%s: newProviderAdapterForConfig: %v", providerType, err)
+		}
+		configureProviderRetry(adapter, providerRetryPolicy{})
+		_, _ = adapter.StreamTurn(context.Background(), TurnRequest{
+			Model:    "test-model",
+			Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "hi"}}}},
+		}, nil)
+		if transport.calls.Load() == 0 {
+			t.Fatalf("%s: adapter did not send through the provided http client", providerType)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderRecord is one recorded provider turn: the normalized request, the stream events in
+// emission order, and the final result or error. Records are stored as JSONL.
+type ProviderRecord struct {
+	// Session groups the turns of one independent conversation (ai-loop-eval uses the task id).
+	Session  string        `json:"session,omitempty"`
+	Seq      int           `json:"seq"`
+	AtUnixMs int64         `json:"at_unix_ms"`
+	Request  TurnRequest   `json:"request"`
+	Events   []StreamEvent `json:"events,omitempty"`
+	Result   TurnResult    `json:"result"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ProviderRecorder appends every provider turn of the runs it is attached to (see Options.ProviderRecorder)
+// to a JSONL file. It is safe to share across services.
+type ProviderRecorder struct {
+	mu     sync.Mutex
+	f      *os.File
+	w      *bufio.Writer
+	seq    map[string]int
+	closed bool
+}
+
+// NewProviderRecorder creates (or truncates) the recording file at path.
+func NewProviderRecorder(path string) (*ProviderRecorder, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, errors.New("missing recording path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderRecorder{f: f, w: bufio.NewWriter(f), seq: make(map[string]int)}, nil
+}
+
+// Close flushes pending records and closes the file.
+func (r *ProviderRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	flushErr := r.w.Flush()
+	closeErr := r.f.Close()
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+// Wrap returns a provider that records every turn of inner under session.
+func (r *ProviderRecorder) Wrap(inner Provider, session string) Provider {
+	if r == nil || inner == nil {
+		return inner
+	}
+	return &recordingProvider{inner: inner, recorder: r, session: strings.TrimSpace(session)}
+}
+
+func (r *ProviderRecorder) append(rec ProviderRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errors.New("provider recorder closed")
+	}
+	rec.Seq = r.seq[rec.Session]
+	r.seq[rec.Session]++
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := r.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	// Flush per record so an interrupted eval still leaves a usable recording.
+	return r.w.Flush()
+}
+
+type recordingProvider struct {
+	inner    Provider
+	recorder *ProviderRecorder
+	session  string
+}
+
+func (p *recordingProvider) StreamTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	var events []StreamEvent
+	result, err := p.inner.StreamTurn(ctx, req, func(event StreamEvent) {
+		events = append(events, event)
+		emitProviderEvent(onEvent, event)
+	})
+	rec := ProviderRecord{
+		Session:  p.session,
+		AtUnixMs: time.Now().UnixMilli(),
+		Request:  req,
+		Events:   events,
+		Result:   result,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	// A recording failure must not change the live run; replay reports the gap instead.
+	_ = p.recorder.append(rec)
+	return result, err
+}
+
+// ProviderReplayer implements Provider by feeding back the turns of a provider recording.
+//
+// Turns are replayed per session in recorded order, with the recorded stream events, tool-call ids, and
+// errors, so a run driven by the replayer follows the recorded run without network access.
+type ProviderReplayer struct {
+	tape    *providerReplayTape
+	session string
+}
+
+type providerReplayTape struct {
+	path     string
+	modTime  time.Time
+	size     int64
+	mu       sync.Mutex
+	sessions map[string][]ProviderRecord
+	cursor   map[string]int
+}
+
+// NewProviderReplayer loads the recording at path. Each replayer keeps its own cursor per session;
+// runs of one Service share cursors through the Service's replay tapes (see wrapProviderForSession).
+func NewProviderReplayer(path string) (*ProviderReplayer, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, errors.New("missing recording path")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	tape, err := loadProviderReplayTape(abs)
+	if err != nil {
+		return nil, err
+	}
+	tape.modTime = info.ModTime()
+	tape.size = info.Size()
+	return &ProviderReplayer{tape: tape}, nil
+}
+
+// providerReplayTapes holds the replay tapes of one Service, so consecutive runs continue each
+// session where the previous run stopped.
+type providerReplayTapes struct {
+	mu     sync.Mutex
+	byPath map[string]*providerReplayTape
+}
+
+func newProviderReplayTapes() *providerReplayTapes {
+	return &providerReplayTapes{byPath: make(map[string]*providerReplayTape)}
+}
+
+// share returns a replayer on the tape already held for p's recording. A recording that is new,
+// or whose size or modification time changed, replaces the held tape with p's fresh cursors.
+func (t *providerReplayTapes) share(p *ProviderReplayer) *ProviderReplayer {
+	if t == nil || p == nil || p.tape == nil {
+		return p
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if held := t.byPath[p.tape.path]; held != nil && held.size == p.tape.size && held.modTime.Equal(p.tape.modTime) {
+		return &ProviderReplayer{tape: held, session: p.session}
+	}
+	t.byPath[p.tape.path] = p.tape
+	return p
+}
+
+func loadProviderReplayTape(path string) (*providerReplayTape, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	tape := &providerReplayTape{path: path, sessions: make(map[string][]ProviderRecord), cursor: make(map[string]int)}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var rec ProviderRecord
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		rec.Session = strings.TrimSpace(rec.Session)
+		recs := tape.sessions[rec.Session]
+		if rec.Seq != len(recs) {
+			return nil, fmt.Errorf("%s:%d: session %q seq=%d, want %d", path, line, rec.Session, rec.Seq, len(recs))
+		}
+		tape.sessions[rec.Session] = append(recs, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return tape, nil
+}
+
+// wrapProviderForSession applies the tool-call format to adapter, binds it to session, and records it.
+// A replayer is moved onto the matching tape in replays, so it continues the cursor of earlier runs.
+//
+// Recordings are taken after tool-call normalization, and a replayer stands in at that same layer, so
+// replayed turns are never normalized twice and keep their recorded tool-call ids.
+func wrapProviderForSession(adapter Provider, toolCallFormat string, session string, recorder *ProviderRecorder, replays *providerReplayTapes) Provider {
+	if replayer, ok := adapter.(*ProviderReplayer); ok {
+		adapter = replays.share(replayer).ForSession(session)
+	}
+	adapter = wrapProviderToolCallFormat(adapter, toolCallFormat)
+	return recorder.Wrap(adapter, session)
+}
+
+// ForSession returns a replayer that serves the turns recorded under session.
+func (p *ProviderReplayer) ForSession(session string) *ProviderReplayer {
+	if p == nil {
+		return nil
+	}
+	return &ProviderReplayer{tape: p.tape, session: strings.TrimSpace(session)}
+}
+
+func (p *ProviderReplayer) StreamTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	if p == nil || p.tape == nil {
+		return TurnResult{}, errors.New("nil provider")
+	}
+	if err := ctx.Err(); err != nil {
+		return TurnResult{}, err
+	}
+	rec, err := p.tape.next(p.session)
+	if err != nil {
+		return TurnResult{}, err
+	}
+	for _, event := range rec.Events {
+		emitProviderEvent(onEvent, event)
+	}
+	if rec.Error != "" {
+		return rec.Result, errors.New(rec.Error)
+	}
+	return rec.Result, nil
+}
+
+func (t *providerReplayTape) next(session string) (ProviderRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	recs := t.sessions[session]
+	i := t.cursor[session]
+	if i >= len(recs) {
+		return ProviderRecord{}, fmt.Errorf("provider recording %s exhausted for session %q after %d turns", t.path, session, len(recs))
+	}
+	t.cursor[session] = i + 1
+	return recs[i], nil
+}
@@ -0,0 +1,272 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+type scriptedRecordingProvider struct {
+	turns []scriptedRecordingTurn
+	calls int
+}
+
+type scriptedRecordingTurn struct {
+	events []StreamEvent
+	result TurnResult
+	err    error
+}
+
+func (p *scriptedRecordingProvider) StreamTurn(_ context.Context, _ TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	turn := p.turns[p.calls]
+	p.calls++
+	for _, event := range turn.events {
+		emitProviderEvent(onEvent, event)
+	}
+	return turn.result, turn.err
+}
+
+func TestProviderRecorder_ReplayPreservesToolCallsAndOrder(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "provider.jsonl")
+	recorder, err := NewProviderRecorder(path)
+	if err != nil {
+		t.Fatalf("NewProviderRecorder: %v", err)
+	}
+
+	toolTurn := scriptedRecordingTurn{
+		events: []StreamEvent{
+			{Type: StreamEventToolCallStart, ToolCall: &PartialToolCall{ID: "call_b", Name: "terminal.exec"}},
+			{Type: StreamEventToolCallStart, ToolCall: &PartialToolCall{ID: "call_a", Name: "file.read"}},
+			{Type: StreamEventToolCallEnd, ToolCall: &PartialToolCall{ID: "call_b", Name: "terminal.exec", ArgumentsJSON: `{"command":"ls"}`}},
+			{Type: StreamEventToolCallEnd, ToolCall: &PartialToolCall{ID: "call_a", Name: "file.read", ArgumentsJSON: `{"path":"README.md"}`}},
+			{Type: StreamEventFinishReason, FinishHint: "tool_calls"},
+		},
+		result: TurnResult{
+			FinishReason: "tool_calls",
+			ToolCalls: []ToolCall{
+				{ID: "call_b", Name: "terminal.exec", Args: map[string]any{"command": "ls"}},
+				{ID: "call_a", Name: "file.read", Args: map[string]any{"path": "README.md"}},
+			},
+			Usage: TurnUsage{InputTokens: 120, OutputTokens: 30},
+		},
+	}
+	textTurn := scriptedRecordingTurn{
+		events: []StreamEvent{{Type: StreamEventTextDelta, Text: "done"}},
+		result: TurnResult{FinishReason: "stop", Text: "done"},
+	}
+	failedTurn := scriptedRecordingTurn{err: errors.New("upstream 500")}
+
+	taskA := recorder.Wrap(&scriptedRecordingProvider{turns: []scriptedRecordingTurn{toolTurn, textTurn}}, "task_a")
+	taskB := recorder.Wrap(&scriptedRecordingProvider{turns: []scriptedRecordingTurn{failedTurn}}, "task_b")
+	req := TurnRequest{Model: "gpt-5-mini", Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "hi"}}}}}
+	if _, err := taskA.StreamTurn(context.Background(), req, nil); err != nil {
+		t.Fatalf("record task_a turn 1: %v", err)
+	}
+	if _, err := taskB.StreamTurn(context.Background(), req, nil); err == nil {
+		t.Fatalf("record task_b: expected error")
+	}
+	if _, err := taskA.StreamTurn(context.Background(), req, nil); err != nil {
+		t.Fatalf("record task_a turn 2: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	adapter, err := newProviderAdapterForConfig(config.AIProvider{Type: "replay", RecordingPath: path}, "", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapterForConfig replay: %v", err)
+	}
+	replayA := adapter.(*ProviderReplayer).ForSession("task_a")
+
+	var events []StreamEvent
+	got, err := replayA.StreamTurn(context.Background(), req, func(event StreamEvent) { events = append(events, event) })
+	if err != nil {
+		t.Fatalf("replay turn 1: %v", err)
+	}
+	if !reflect.DeepEqual(events, toolTurn.events) {
+		t.Fatalf("replayed events=%+v, want %+v", events, toolTurn.events)
+	}
+	if len(got.ToolCalls) != 2 || got.ToolCalls[0].ID != "call_b" || got.ToolCalls[1].ID != "call_a" || got.ToolCalls[1].Args["path"] != "README.md" {
+		t.Fatalf("replayed tool calls=%+v", got.ToolCalls)
+	}
+	if got.Usage != toolTurn.result.Usage {
+		t.Fatalf("replayed usage=%+v", got.Usage)
+	}
+
+	if got, err := replayA.StreamTurn(context.Background(), req, nil); err != nil || got.Text != "done" {
+		t.Fatalf("replay turn 2=%+v err=%v", got, err)
+	}
+	if _, err := replayA.StreamTurn(context.Background(), req, nil); err == nil {
+		t.Fatalf("expected exhausted recording error")
+	}
+
+	// A second replayer of the same recording keeps its own cursors.
+	again, err := NewProviderReplayer(path)
+	if err != nil {
+		t.Fatalf("NewProviderReplayer: %v", err)
+	}
+	if got, err := again.ForSession("task_a").StreamTurn(context.Background(), req, nil); err != nil || len(got.ToolCalls) != 2 {
+		t.Fatalf("independent replay turn 1=%+v err=%v", got, err)
+	}
+	if _, err := again.ForSession("task_b").StreamTurn(context.Background(), req, nil); err == nil || err.Error() != "upstream 500" {
+		t.Fatalf("replayed error=%v, want upstream 500", err)
+	}
+}
+
+func TestWrapProviderForSession_SharesReplayCursorWithinService(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "provider.jsonl")
+	recorder, err := NewProviderRecorder(path)
+	if err != nil {
+		t.Fatalf("NewProviderRecorder: %v", err)
+	}
+	live := recorder.Wrap(&scriptedRecordingProvider{turns: []scriptedRecordingTurn{
+		{result: TurnResult{FinishReason: "stop", Text: "first"}},
+		{result: TurnResult{FinishReason: "stop", Text: "second"}},
+	}}, "task")
+	req := TurnRequest{Model: "m"}
+	for i := 0; i < 2; i++ {
+		if _, err := live.StreamTurn(context.Background(), req, nil); err != nil {
+			t.Fatalf("record turn %d: %v", i+1, err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// replayTurn builds the adapter of one run the way the runtime does: a fresh replayer per run.
+	replayTurn := func(replays *providerReplayTapes) string {
+		t.Helper()
+		replayer, err := NewProviderReplayer(path)
+		if err != nil {
+			t.Fatalf("NewProviderReplayer: %v", err)
+		}
+		got, err := wrapProviderForSession(replayer, "", "task", nil, replays).StreamTurn(context.Background(), req, nil)
+		if err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+		return got.Text
+	}
+
+	serviceA := newProviderReplayTapes()
+	if got := replayTurn(serviceA); got != "first" {
+		t.Fatalf("service A run 1=%q, want first", got)
+	}
+	if got := replayTurn(serviceA); got != "second" {
+		t.Fatalf("service A run 2=%q, want second", got)
+	}
+	// Another service replays the same recording from the start.
+	if got := replayTurn(newProviderReplayTapes()); got != "first" {
+		t.Fatalf("service B run 1=%q, want first", got)
+	}
+}
+
+func TestNewProviderReplayer_MissingRecording(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewProviderReplayer(""); err == nil {
+		t.Fatalf("expected missing path error")
+	}
+	if _, err := newProviderAdapterForConfig(config.AIProvider{Type: "replay", RecordingPath: filepath.Join(t.TempDir(), "missing.jsonl")}, "", nil); err == nil {
+		t.Fatalf("expected missing recording error")
+	}
+}
+
+func TestWrapProviderForSession_ReplaysReActTurnsAtRecordedLayer(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "provider.jsonl")
+	recorder, err := NewProviderRecorder(path)
+	if err != nil {
+		t.Fatalf("NewProviderRecorder: %v", err)
+	}
+	raw := "Checking.\n<tool_call>{\"name\":\"file.read\",\"arguments\":{\"path\":\"README.md\"}}</tool_call>"
+	live := wrapProviderForSession(&scriptedRecordingProvider{turns: []scriptedRecordingTurn{{
+		events: []StreamEvent{{Type: StreamEventTextDelta, Text: raw}},
+		result: TurnResult{FinishReason: "stop", Text: raw},
+	}}}, config.AIToolCallFormatReActText, "task_react", recorder, nil)
+	req := TurnRequest{Model: "m", Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "hi"}}}}}
+	recorded, err := live.StreamTurn(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("live turn: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(recorded.ToolCalls) != 1 {
+		t.Fatalf("live tool calls=%+v", recorded.ToolCalls)
+	}
+
+	replayer, err := NewProviderReplayer(path)
+	if err != nil {
+		t.Fatalf("NewProviderReplayer: %v", err)
+	}
+	// The first turn must come from the bound session, and the recorded (already normalized) output is not parsed again.
+	replay := wrapProviderForSession(replayer, config.AIToolCallFormatReActText, "task_react", nil, nil)
+	got, err := replay.StreamTurn(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("replay turn: %v", err)
+	}
+	if got.Text != recorded.Text || strings.Contains(got.Text, "<tool_call>") {
+		t.Fatalf("replayed text=%q, want %q", got.Text, recorded.Text)
+	}
+	if len(got.ToolCalls) != 1 || got.ToolCalls[0].ID != recorded.ToolCalls[0].ID || got.ToolCalls[0].Args["path"] != "README.md" {
+		t.Fatalf("replayed tool calls=%+v, want %+v", got.ToolCalls, recorded.ToolCalls)
+	}
+}
+
+func TestNewProviderReplayer_ReloadsChangedRecording(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "provider.jsonl")
+	writeRecording := func(text string, modTime time.Time) {
+		t.Helper()
+		recorder, err := NewProviderRecorder(path)
+		if err != nil {
+			t.Fatalf("NewProviderRecorder: %v", err)
+		}
+		provider := recorder.Wrap(&scriptedRecordingProvider{turns: []scriptedRecordingTurn{{result: TurnResult{FinishReason: "stop", Text: text}}}}, "task")
+		if _, err := provider.StreamTurn(context.Background(), TurnRequest{Model: "m"}, nil); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+		if err := recorder.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+	replays := newProviderReplayTapes()
+	replayText := func() string {
+		t.Helper()
+		replayer, err := NewProviderReplayer(path)
+		if err != nil {
+			t.Fatalf("NewProviderReplayer: %v", err)
+		}
+		got, err := replays.share(replayer).ForSession("task").StreamTurn(context.Background(), TurnRequest{Model: "m"}, nil)
+		if err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+		return got.Text
+	}
+
+	base := time.Now().Add(-time.Hour)
+	writeRecording("first", base)
+	if got := replayText(); got != "first" {
+		t.Fatalf("replay=%q, want first", got)
+	}
+	writeRecording("second", base.Add(time.Minute))
+	if got := replayText(); got != "second" {
+		t.Fatalf("replay after rewrite=%q, want second from a fresh cursor", got)
+	}
+}
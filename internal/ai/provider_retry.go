@@ -0,0 +1,216 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	openai "github.com/openai/openai-go"
+)
+
+const (
+	defaultProviderRetries        = 2
+	defaultProviderInitialBackoff = 500 * time.Millisecond
+	maxProviderRetryBackoff       = 30 * time.Second
+)
+
+// providerRetryPolicy controls how provider adapters retry transient HTTP and transport failures within one model turn.
+//
+// The SDK clients are built with their own retries disabled so this policy is the only retry layer.
+type providerRetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	// OnRetry, when set, is called before waiting for each retry.
+	OnRetry func(providerRetryAttempt)
+}
+
+// providerRetryAttempt describes one scheduled retry.
+type providerRetryAttempt struct {
+	Model      string
+	Attempt    int
+	MaxRetries int
+	StatusCode int
+	Backoff    time.Duration
+	RetryAfter bool
+	Err        error
+}
+
+func defaultProviderRetryPolicy() providerRetryPolicy {
+	return providerRetryPolicy{MaxRetries: defaultProviderRetries, InitialBackoff: defaultProviderInitialBackoff}
+}
+
+// retryConfigurableProvider is implemented by the built-in provider adapters.
+type retryConfigurableProvider interface {
+	setRetryPolicy(policy providerRetryPolicy)
+}
+
+// configureProviderRetry applies policy to provider and reports whether the provider supports it.
+func configureProviderRetry(provider Provider, policy providerRetryPolicy) bool {
+	configurable, ok := provider.(retryConfigurableProvider)
+	if !ok {
+		return false
+	}
+	configurable.setRetryPolicy(policy)
+	return true
+}
+
+func (p *openAIProvider) setRetryPolicy(policy providerRetryPolicy)    { p.retry = policy }
+func (p *moonshotProvider) setRetryPolicy(policy providerRetryPolicy)  { p.retry = policy }
+func (p *anthropicProvider) setRetryPolicy(policy providerRetryPolicy) { p.retry = policy }
+
+// withProviderRetry runs turn and retries it on HTTP 429/5xx and on transport failures such as resets and timeouts.
+//
+// A turn is retried only while it has not delivered any stream event, so callers never see duplicated deltas.
+// Context cancellation abandons the retry immediately.
+func withProviderRetry(ctx context.Context, policy providerRetryPolicy, model string, onEvent func(StreamEvent), turn func(onEvent func(StreamEvent)) (TurnResult, error)) (TurnResult, error) {
+	for attempt := 1; ; attempt++ {
+		emitted := false
+		forward := onEvent
+		if onEvent != nil {
+			forward = func(event StreamEvent) {
+				emitted = true
+				onEvent(event)
+			}
+		}
+		result, err := turn(forward)
+		if err == nil || emitted || attempt > policy.MaxRetries || ctx.Err() != nil {
+			return result, err
+		}
+		status, header := providerErrorHTTPStatus(err)
+		if !isTransientProviderError(err, status) {
+			return result, err
+		}
+		backoff := providerRetryBackoff(policy.InitialBackoff, attempt)
+		retryAfter, hasRetryAfter := parseProviderRetryAfter(header, time.Now())
+		if hasRetryAfter {
+			if retryAfter > maxProviderRetryBackoff {
+				// The provider asked for a longer pause than one turn should block; surface the error instead.
+				return result, err
+			}
+			backoff = retryAfter
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(providerRetryAttempt{
+				Model:      strings.TrimSpace(model),
+				Attempt:    attempt,
+				MaxRetries: policy.MaxRetries,
+				StatusCode: status,
+				Backoff:    backoff,
+				RetryAfter: hasRetryAfter,
+				Err:        err,
+			})
+		}
+		if backoff <= 0 {
+			continue
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return TurnResult{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// providerErrorHTTPStatus extracts the HTTP status and response headers from an SDK API error.
+func providerErrorHTTPStatus(err error) (int, http.Header) {
+	var openAIErr *openai.Error
+	if errors.As(err, &openAIErr) && openAIErr != nil {
+		if openAIErr.Response != nil {
+			return openAIErr.StatusCode, openAIErr.Response.Header
+		}
+		return openAIErr.StatusCode, nil
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) && anthropicErr != nil {
+		if anthropicErr.Response != nil {
+			return anthropicErr.StatusCode, anthropicErr.Response.Header
+		}
+		return anthropicErr.StatusCode, nil
+	}
+	return 0, nil
+}
+
+func isTransientProviderStatus(status int) bool {
+	return status == http.StatusTooManyRequests || (status >= 500 && status <= 599)
+}
+
+// isTransientProviderError reports whether err is worth retrying.
+//
+// API errors are judged by status. Errors without a status are transport failures (resets, refused or dropped
+// connections, dial errors, timeouts) that the SDKs would have retried before their own retries were disabled.
+func isTransientProviderError(err error, status int) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if status != 0 {
+		return isTransientProviderStatus(status)
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Err != nil {
+		// *url.Error satisfies net.Error for every failed request, including TLS and URL errors; judge what it wraps.
+		var inner net.Error
+		return urlErr.Timeout() || errors.As(urlErr.Err, &inner)
+	}
+	return true
+}
+
+// providerRetryBackoff doubles initial per attempt, caps it, and applies equal jitter (half fixed, half random).
+func providerRetryBackoff(initial time.Duration, attempt int) time.Duration {
+	if initial <= 0 {
+		return 0
+	}
+	backoff := initial
+	for i := 1; i < attempt && backoff < maxProviderRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxProviderRetryBackoff {
+		backoff = maxProviderRetryBackoff
+	}
+	half := backoff / 2
+	return half + rand.N(backoff-half+1)
+}
+
+// parseProviderRetryAfter reads retry-after-ms, or Retry-After in seconds or as an HTTP date.
+func parseProviderRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if header == nil {
+		return 0, false
+	}
+	if raw := strings.TrimSpace(header.Get("Retry-After-Ms")); raw != "" {
+		if ms, err := strconv.ParseFloat(raw, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	raw := strings.TrimSpace(header.Get("Retry-After"))
+	if raw == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseFloat(raw, 64); err == nil && secs >= 0 {
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	if at, err := http.ParseTime(raw); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,276 @@
+package ai
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseProviderRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{name: "missing", header: http.Header{}, ok: false},
+		{name: "seconds", header: http.Header{"Retry-After": {"2"}}, want: 2 * time.Second, ok: true},
+		{name: "milliseconds_preferred", header: http.Header{"Retry-After": {"2"}, "Retry-After-Ms": {"150"}}, want: 150 * time.Millisecond, ok: true},
+		{name: "http_date", header: http.Header{"Retry-After": {now.Add(3 * time.Second).Format(http.TimeFormat)}}, want: 3 * time.Second, ok: true},
+		{name: "garbage", header: http.Header{"Retry-After": {"soon"}}, ok: false},
+	}
+	for _, tc := range cases {
+		got, ok := parseProviderRetryAfter(tc.header, now)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("%s: got (%v,%v), want (%v,%v)", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestProviderRetryBackoff_JitterBounds(t *testing.T) {
+	t.Parallel()
+
+	for attempt := 1; attempt <= 8; attempt++ {
+		base := 100 * time.Millisecond << (attempt - 1)
+		if base > maxProviderRetryBackoff {
+			base = maxProviderRetryBackoff
+		}
+		for i := 0; i < 20; i++ {
+			got := providerRetryBackoff(100*time.Millisecond, attempt)
+			if got < base/2 || got > base {
+				t.Fatalf("attempt %d backoff=%v, want in [%v,%v]", attempt, got, base/2, base)
+			}
+		}
+	}
+	if got := providerRetryBackoff(0, 2); got != 0 {
+		t.Fatalf("zero initial backoff must stay zero, got %v", got)
+	}
+}
+
+// newFlakyChatServer fails the first len(statuses) requests with the given statuses, then streams "ok".
+func newFlakyChatServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statuses[n-1])
+			_, _ = w.Write([]byte(`{"error":{"message":"try again","type":"server_error"}}`))
+			return
+		}
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Errorf("response writer does not support flushing")
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk := func(delta map[string]any, finish any) map[string]any {
+			return map[string]any{
+				"id":      "chatcmpl_retry",
+				"object":  "chat.completion.chunk",
+				"created": 123,
+				"model":   "kimi-k2",
+				"choices": []any{map[string]any{"index": 0, "finish_reason": finish, "delta": delta}},
+			}
+		}
+		writeOpenAISSEJSON(w, f, chunk(map[string]any{"role": "assistant", "content": "ok"}, nil))
+		writeOpenAISSEJSON(w, f, chunk(map[string]any{}, "stop"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+		f.Flush()
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newRetryTestProvider(t *testing.T, baseURL string, maxRetries int, onRetry func(providerRetryAttempt)) Provider {
+	t.Helper()
+	provider, err := newProviderAdapter("moonshot", baseURL, "sk-test", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapter: %v", err)
+	}
+	if !configureProviderRetry(provider, providerRetryPolicy{MaxRetries: maxRetries, InitialBackoff: time.Millisecond, OnRetry: onRetry}) {
+		t.Fatalf("provider %T does not accept a retry policy", provider)
+	}
+	return provider
+}
+
+func retryTestTurnRequest() TurnRequest {
+	return TurnRequest{
+		Model:    "kimi-k2",
+		Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "hi"}}}},
+	}
+}
+
+func TestProviderStreamTurn_RetriesTransientStatus(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := newFlakyChatServer(t, "", http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	var attempts []providerRetryAttempt
+	provider := newRetryTestProvider(t, srv.URL+"/v1", 2, func(a providerRetryAttempt) { attempts = append(attempts, a) })
+
+	var deltas []string
+	result, err := provider.StreamTurn(context.Background(), retryTestTurnRequest(), func(ev StreamEvent) {
+		if ev.Type == StreamEventTextDelta {
+			deltas = append(deltas, ev.Text)
+		}
+	})
+	if err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+	if result.Text != "ok" || len(deltas) != 1 {
+		t.Fatalf("text=%q deltas=%v", result.Text, deltas)
+	}
+	if calls.Load() != 3 || len(attempts) != 2 {
+		t.Fatalf("calls=%d retries=%d, want 3/2", calls.Load(), len(attempts))
+	}
+	if attempts[0].StatusCode != http.StatusTooManyRequests || attempts[1].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected retry statuses: %+v", attempts)
+	}
+	if attempts[1].Attempt != 2 || attempts[1].Model != "kimi-k2" {
+		t.Fatalf("unexpected retry attempt: %+v", attempts[1])
+	}
+}
+
+func TestProviderStreamTurn_DoesNotRetryClientErrors(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := newFlakyChatServer(t, "", http.StatusBadRequest)
+	provider := newRetryTestProvider(t, srv.URL+"/v1", 3, nil)
+	if _, err := provider.StreamTurn(context.Background(), retryTestTurnRequest(), nil); err == nil {
+		t.Fatalf("expected 400 to surface")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls=%d, want 1", calls.Load())
+	}
+}
+
+func TestProviderStreamTurn_RetryExhaustedAndLongRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := newFlakyChatServer(t, "", http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	provider := newRetryTestProvider(t, srv.URL+"/v1", 1, nil)
+	if _, err := provider.StreamTurn(context.Background(), retryTestTurnRequest(), nil); err == nil {
+		t.Fatalf("expected error after retries are exhausted")
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("calls=%d, want 2", calls.Load())
+	}
+
+	slow, slowCalls := newFlakyChatServer(t, "120", http.StatusTooManyRequests)
+	provider = newRetryTestProvider(t, slow.URL+"/v1", 3, nil)
+	if _, err := provider.StreamTurn(context.Background(), retryTestTurnRequest(), nil); err == nil {
+		t.Fatalf("expected error when Retry-After exceeds the backoff cap")
+	}
+	if slowCalls.Load() != 1 {
+		t.Fatalf("calls=%d, want 1", slowCalls.Load())
+	}
+}
+
+func TestProviderStreamTurn_CancelAbandonsRetry(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := newFlakyChatServer(t, "10", http.StatusServiceUnavailable)
+	ctx, cancel := context.WithCancel(context.Background())
+	provider := newRetryTestProvider(t, srv.URL+"/v1", 3, func(providerRetryAttempt) { cancel() })
+
+	start := time.Now()
+	_, err := provider.StreamTurn(ctx, retryTestTurnRequest(), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err=%v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("cancellation waited %v for Retry-After", elapsed)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls=%d, want 1", calls.Load())
+	}
+}
+
+func TestIsTransientProviderError(t *testing.T) {
+	t.Parallel()
+
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		want   bool
+	}{
+		{name: "rate_limited", err: errors.New("api error"), status: http.StatusTooManyRequests, want: true},
+		{name: "bad_request", err: errors.New("api error"), status: http.StatusBadRequest, want: false},
+		{name: "dial", err: &url.Error{Op: "Post", URL: "https://api.example.com", Err: dialErr}, want: true},
+		{name: "reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "unexpected_eof", err: fmt.Errorf("stream: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "timeout", err: &url.Error{Op: "Post", URL: "https://api.example.com", Err: context.DeadlineExceeded}, want: true},
+		{name: "tls", err: &url.Error{Op: "Post", URL: "https://api.example.com", Err: x509.UnknownAuthorityError{}}, want: false},
+		{name: "canceled", err: &url.Error{Op: "Post", URL: "https://api.example.com", Err: context.Canceled}, want: false},
+		{name: "plain", err: errors.New("invalid tool schema"), want: false},
+	}
+	for _, tc := range cases {
+		if got := isTransientProviderError(tc.err, tc.status); got != tc.want {
+			t.Fatalf("%s: isTransientProviderError=%v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestProviderStreamTurn_RetriesDroppedConnection(t *testing.T) {
+	t.Parallel()
+
+	flaky, calls := newFlakyChatServer(t, "")
+	var dropped atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if dropped.Add(1) == 1 {
+			// Close the connection without a response, like a proxy reset.
+			hj, ok := w.(http.Hijacker)
+			if !ok {
+				t.Errorf("response writer does not support hijacking")
+				return
+			}
+			conn, _, err := hj.Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		resp, err := http.Post(flaky.URL+r.URL.Path, r.Header.Get("Content-Type"), r.Body)
+		if err != nil {
+			t.Errorf("proxy: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+		w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(srv.Close)
+
+	var attempts []providerRetryAttempt
+	provider := newRetryTestProvider(t, srv.URL+"/v1", 2, func(a providerRetryAttempt) { attempts = append(attempts, a) })
+	result, err := provider.StreamTurn(context.Background(), retryTestTurnRequest(), nil)
+	if err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+	if result.Text != "ok" {
+		t.Fatalf("text=%q, want ok", result.Text)
+	}
+	if dropped.Load() != 2 || calls.Load() != 1 || len(attempts) != 1 || attempts[0].StatusCode != 0 {
+		t.Fatalf("dropped=%d upstream=%d retries=%+v, want one transport retry", dropped.Load(), calls.Load(), attempts)
+	}
+}
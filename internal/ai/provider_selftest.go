@@ -0,0 +1,247 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const (
+	providerSelfTestTimeout        = 20 * time.Second
+	providerSelfTestMaxOutputToken = 16
+)
+
+// ErrProviderNotFound is returned when a provider id is not present in the AI config.
+var ErrProviderNotFound = errors.New("provider not found")
+
+// Normalized provider self-test error codes.
+const (
+	ProviderTestErrMissingAPIKey = "missing_api_key"
+	ProviderTestErrMissingModel  = "missing_model"
+	ProviderTestErrInvalidConfig = "invalid_config"
+	ProviderTestErrUnauthorized  = "unauthorized"
+	ProviderTestErrNotFound      = "not_found"
+	ProviderTestErrRateLimited   = "rate_limited"
+	ProviderTestErrServerError   = "server_error"
+	ProviderTestErrBadRequest    = "bad_request"
+	ProviderTestErrTimeout       = "timeout"
+	ProviderTestErrUnreachable   = "unreachable"
+	ProviderTestErrUnknown       = "unknown"
+)
+
+// ProviderTestResult is the outcome of one provider connectivity self-test.
+//
+// Error is sanitized: it never contains the provider API key or bearer credentials.
+type ProviderTestResult struct {
+	ProviderID       string `json:"provider_id"`
+	ProviderType     string `json:"provider_type"`
+	Model            string `json:"model,omitempty"`
+	OK               bool   `json:"ok"`
+	LatencyMS        int64  `json:"latency_ms"`
+	BaseURL          string `json:"base_url"`
+	StrictToolSchema bool   `json:"strict_tool_schema"`
+	HTTPStatus       int    `json:"http_status,omitempty"`
+	ErrorCode        string `json:"error_code,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// TestProvider sends a minimal one-turn request to providerID through the same adapter runs use.
+//
+// Connectivity failures are reported in the result; the error is only set when the provider cannot be resolved.
+func (s *Service) TestProvider(ctx context.Context, providerID string) (ProviderTestResult, error) {
+	if s == nil {
+		return ProviderTestResult{}, ErrNotConfigured
+	}
+	s.mu.Lock()
+	cfg := s.cfg
+	s.mu.Unlock()
+	if cfg == nil {
+		return ProviderTestResult{}, ErrNotConfigured
+	}
+	return TestProviderConnectivity(ctx, cfg, providerID, s.resolveProviderKey)
+}
+
+// TestProviderConnectivity is TestProvider without a running service, for the CLI.
+func TestProviderConnectivity(ctx context.Context, cfg *config.AIConfig, providerID string, resolveKey func(providerID string) (string, bool, error)) (ProviderTestResult, error) {
+	if cfg == nil {
+		return ProviderTestResult{}, ErrNotConfigured
+	}
+	providerID = strings.TrimSpace(providerID)
+	var providerCfg *config.AIProvider
+	for i := range cfg.Providers {
+		if strings.TrimSpace(cfg.Providers[i].ID) == providerID {
+			providerCfg = &cfg.Providers[i]
+			break
+		}
+	}
+	if providerID == "" || providerCfg == nil {
+		return ProviderTestResult{}, fmt.Errorf("%w: %s", ErrProviderNotFound, providerID)
+	}
+
+	providerType := strings.ToLower(strings.TrimSpace(providerCfg.Type))
+	baseURL := strings.TrimSpace(providerCfg.BaseURL)
+	out := ProviderTestResult{
+		ProviderID:       providerID,
+		ProviderType:     providerType,
+		BaseURL:          effectiveProviderBaseURL(providerType, baseURL),
+		StrictToolSchema: resolveStrictToolSchema(providerType, baseURL, providerCfg.StrictToolSchema),
+	}
+	if providerType == "anthropic" || providerType == "bedrock" {
+		// The anthropic adapter, which bedrock also runs on, has no strict/non-strict split.
+		out.StrictToolSchema = false
+	}
+	if providerType == "bedrock" && out.BaseURL == "" && strings.TrimSpace(providerCfg.Region) != "" {
+		out.BaseURL = bedrockRuntimeEndpoint(providerCfg.Region)
+	}
+	for _, m := range providerCfg.Models {
+		if name := strings.TrimSpace(m.ModelName); name != "" {
+			out.Model = name
+			break
+		}
+	}
+	if out.Model == "" {
+		out.ErrorCode = ProviderTestErrMissingModel
+		out.Error = "provider has no configured models"
+		return out, nil
+	}
+
+	apiKey := ""
+	if resolveKey != nil {
+		key, ok, err := resolveKey(providerID)
+		if err != nil {
+			out.ErrorCode = ProviderTestErrInvalidConfig
+			out.Error = sanitizeProviderTestError("resolve provider key failed: "+err.Error(), "")
+			return out, nil
+		}
+		if ok {
+			apiKey = strings.TrimSpace(key)
+		}
+	}
+	if apiKey == "" && config.AIProviderTypeRequiresAPIKey(providerType) {
+		out.ErrorCode = ProviderTestErrMissingAPIKey
+		out.Error = fmt.Sprintf("missing api key for provider %q", providerID)
+		return out, nil
+	}
+
+	// The self-test may run against an unsaved config, so it never takes or replaces a shared client.
+	adapter, err := newProviderAdapterForConfig(*providerCfg, apiKey, newProviderHTTPClient(*providerCfg))
+	if err != nil {
+		out.ErrorCode = ProviderTestErrInvalidConfig
+		out.Error = sanitizeProviderTestError(err.Error(), apiKey)
+		return out, nil
+	}
+	adapter = wrapProviderToolCallFormat(adapter, providerCfg.EffectiveToolCallFormat())
+	// A self-test reports the first failure as-is instead of retrying it away.
+	configureProviderRetry(adapter, providerRetryPolicy{})
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	testCtx := ctx
+	cancel := func() {}
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		testCtx, cancel = context.WithTimeout(ctx, providerSelfTestTimeout)
+	}
+	defer cancel()
+
+	started := time.Now()
+	_, err = adapter.StreamTurn(testCtx, TurnRequest{
+		Model: out.Model,
+		Messages: []Message{
+			{Role: "user", Content: []ContentPart{{Type: "text", Text: "Reply with the single word: ok"}}},
+		},
+		Budgets:   TurnBudgets{MaxSteps: 1, MaxOutputToken: providerSelfTestMaxOutputToken},
+		ModeFlags: ModeFlags{Mode: config.AIModePlan},
+	}, nil)
+	out.LatencyMS = time.Since(started).Milliseconds()
+	if err != nil {
+		out.HTTPStatus, _ = providerErrorHTTPStatus(err)
+		out.ErrorCode = classifyProviderTestError(testCtx, err, out.HTTPStatus)
+		out.Error = sanitizeProviderTestError(err.Error(), apiKey)
+		return out, nil
+	}
+	out.OK = true
+	return out, nil
+}
+
+// effectiveProviderBaseURL is the endpoint the adapter talks to when the config leaves base_url empty.
+func effectiveProviderBaseURL(providerType string, baseURL string) string {
+	if baseURL = strings.TrimSpace(baseURL); baseURL != "" {
+		return baseURL
+	}
+	switch providerType {
+	case "openai":
+		return "https://api.openai.com/v1"
+	case "anthropic":
+		return "https://api.anthropic.com"
+	case "ollama":
+		return config.DefaultOllamaBaseURL
+	case "mistral":
+		return config.DefaultMistralBaseURL
+	default:
+		return ""
+	}
+}
+
+func classifyProviderTestError(ctx context.Context, err error, status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ProviderTestErrUnauthorized
+	case status == http.StatusNotFound:
+		return ProviderTestErrNotFound
+	case status == http.StatusTooManyRequests:
+		return ProviderTestErrRateLimited
+	case status >= 500:
+		return ProviderTestErrServerError
+	case status >= 400:
+		return ProviderTestErrBadRequest
+	}
+	if errors.Is(err, context.DeadlineExceeded) || (ctx != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+		return ProviderTestErrTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ProviderTestErrTimeout
+		}
+		return ProviderTestErrUnreachable
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return ProviderTestErrUnreachable
+	}
+	return ProviderTestErrUnknown
+}
+
+var providerTestSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)bearer\s+[^\s"',]+`),
+	regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_\-]{8,}`),
+	regexp.MustCompile(`(?i)((?:api[_-]?key|x-api-key|token|key)["']?\s*[:=]\s*["']?)[^\s"'&,]+`),
+}
+
+// sanitizeProviderTestError strips the API key and credential-looking tokens from a provider error message.
+func sanitizeProviderTestError(msg string, apiKey string) string {
+	if apiKey = strings.TrimSpace(apiKey); apiKey != "" {
+		msg = strings.ReplaceAll(msg, apiKey, "[redacted]")
+	}
+	for _, re := range providerTestSecretPatterns {
+		msg = re.ReplaceAllStringFunc(msg, func(match string) string {
+			if sub := re.FindStringSubmatch(match); len(sub) > 1 && sub[1] != "" {
+				return sub[1] + "[redacted]"
+			}
+			if strings.HasPrefix(strings.ToLower(match), "bearer") {
+				return "Bearer [redacted]"
+			}
+			return "[redacted]"
+		})
+	}
+	return sanitizeLogText(msg, 400)
+}
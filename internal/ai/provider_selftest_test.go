@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const providerSelfTestKey = "sk-selftest-0123456789abcdef"
+
+func providerSelfTestConfig(baseURL string) *config.AIConfig {
+	return &config.AIConfig{
+		Providers: []config.AIProvider{
+			{
+				ID:      "openai",
+				Type:    "openai",
+				BaseURL: baseURL,
+				Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+			{ID: "empty", Type: "openai"},
+		},
+	}
+}
+
+func providerSelfTestKeyResolver(string) (string, bool, error) {
+	return providerSelfTestKey, true, nil
+}
+
+func TestTestProviderConnectivity_Success(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+providerSelfTestKey || !strings.HasSuffix(r.URL.Path, "/responses") {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if got, _ := body["max_output_tokens"].(float64); got != providerSelfTestMaxOutputToken {
+			http.Error(w, "unexpected max_output_tokens", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		write := func(v any) {
+			b, _ := json.Marshal(v)
+			_, _ = io.WriteString(w, "data: "+string(b)+"\n\n")
+		}
+		write(map[string]any{"type": "response.output_text.delta", "item_id": "msg_1", "delta": "ok"})
+		write(map[string]any{"type": "response.completed", "response": map[string]any{"id": "resp_1", "status": "completed"}})
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	result, err := TestProviderConnectivity(context.Background(), providerSelfTestConfig(srv.URL+"/v1"), "openai", providerSelfTestKeyResolver)
+	if err != nil {
+		t.Fatalf("TestProviderConnectivity: %v", err)
+	}
+	if !result.OK || result.ErrorCode != "" || result.Model != "gpt-5-mini" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.BaseURL != srv.URL+"/v1" || result.StrictToolSchema {
+		t.Fatalf("custom openai gateway should resolve non-strict schema: %+v", result)
+	}
+}
+
+func TestTestProviderConnectivity_SanitizesProviderErrors(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"error":{"message":"Incorrect API key provided: `+providerSelfTestKey+`; header Authorization: Bearer `+providerSelfTestKey+`","type":"invalid_request_error"}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	result, err := TestProviderConnectivity(context.Background(), providerSelfTestConfig(srv.URL+"/v1"), "openai", providerSelfTestKeyResolver)
+	if err != nil {
+		t.Fatalf("TestProviderConnectivity: %v", err)
+	}
+	if result.OK || result.ErrorCode != ProviderTestErrUnauthorized || result.HTTPStatus != http.StatusUnauthorized {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if strings.Contains(result.Error, "0123456789abcdef") || !strings.Contains(result.Error, "[redacted]") {
+		t.Fatalf("error should be sanitized, got %q", result.Error)
+	}
+}
+
+func TestTestProviderConnectivity_ConfigErrors(t *testing.T) {
+	t.Parallel()
+
+	cfg := providerSelfTestConfig("http://127.0.0.1:1/v1")
+	if _, err := TestProviderConnectivity(context.Background(), cfg, "missing", providerSelfTestKeyResolver); !errors.Is(err, ErrProviderNotFound) {
+		t.Fatalf("err=%v, want ErrProviderNotFound", err)
+	}
+	result, err := TestProviderConnectivity(context.Background(), cfg, "empty", providerSelfTestKeyResolver)
+	if err != nil || result.ErrorCode != ProviderTestErrMissingModel {
+		t.Fatalf("missing model result=%+v err=%v", result, err)
+	}
+	result, err = TestProviderConnectivity(context.Background(), cfg, "openai", func(string) (string, bool, error) { return "", false, nil })
+	if err != nil || result.ErrorCode != ProviderTestErrMissingAPIKey {
+		t.Fatalf("missing key result=%+v err=%v", result, err)
+	}
+	result, err = TestProviderConnectivity(context.Background(), cfg, "openai", providerSelfTestKeyResolver)
+	if err != nil || result.OK || result.ErrorCode != ProviderTestErrUnreachable {
+		t.Fatalf("unreachable result=%+v err=%v", result, err)
+	}
+}
+
+func TestSanitizeProviderTestError(t *testing.T) {
+	t.Parallel()
+
+	got := sanitizeProviderTestError("POST https://gw.example/v1?api_key=abc123xyz failed: Bearer tok_live_1 and sk-ant-abcdefghijkl", "")
+	for _, leaked := range []string{"abc123xyz", "tok_live_1", "sk-ant-abcdefghijkl"} {
+		if strings.Contains(got, leaked) {
+			t.Fatalf("sanitized message leaked %q: %q", leaked, got)
+		}
+	}
+	if !strings.Contains(got, "api_key=[redacted]") || !strings.Contains(got, "Bearer [redacted]") {
+		t.Fatalf("unexpected sanitized message: %q", got)
+	}
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// errProviderStreamStall is returned when a provider turn delivered no stream event within the stall interval.
+var errProviderStreamStall = errors.New("provider_stream_stall")
+
+// providerStreamStall describes one turn aborted by the stream stall watchdog.
+type providerStreamStall struct {
+	Model      string
+	Interval   time.Duration
+	EventsSeen int64
+	Elapsed    time.Duration
+}
+
+// streamStallProvider aborts a turn when its stream goes silent for longer than interval.
+//
+// Some gateways stop sending without closing the connection. The run is busy while it waits on a
+// turn, so nothing else would end it before the wall time; the stall error sends the loop down its
+// recovery path instead.
+type streamStallProvider struct {
+	inner    Provider
+	interval time.Duration
+	onStall  func(providerStreamStall)
+}
+
+func (p *streamStallProvider) StreamTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	turnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	started := time.Now()
+	activity := make(chan struct{}, 1)
+	done := make(chan struct{})
+	var (
+		stalled atomic.Bool
+		events  atomic.Int64
+	)
+	go func() {
+		timer := time.NewTimer(p.interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-turnCtx.Done():
+				return
+			case <-activity:
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(p.interval)
+			case <-timer.C:
+				stalled.Store(true)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	result, err := p.inner.StreamTurn(turnCtx, req, func(event StreamEvent) {
+		events.Add(1)
+		select {
+		case activity <- struct{}{}:
+		default:
+		}
+		if onEvent != nil {
+			onEvent(event)
+		}
+	})
+	close(done)
+	if err == nil || !stalled.Load() || ctx.Err() != nil {
+		return result, err
+	}
+	stall := providerStreamStall{
+		Model:      strings.TrimSpace(req.Model),
+		Interval:   p.interval,
+		EventsSeen: events.Load(),
+		Elapsed:    time.Since(started),
+	}
+	if p.onStall != nil {
+		p.onStall(stall)
+	}
+	return TurnResult{}, fmt.Errorf("%w: no stream event within %s", errProviderStreamStall, p.interval)
+}
+
+func (r *run) persistProviderStreamStall(providerID string, providerType string, stall providerStreamStall) {
+	if r == nil {
+		return
+	}
+	r.debug("ai.provider.stream_stall",
+		"provider_id", providerID,
+		"provider_type", providerType,
+		"model", stall.Model,
+		"interval_ms", stall.Interval.Milliseconds(),
+		"events_seen", stall.EventsSeen,
+	)
+	r.persistRunEvent("provider.stream_stall", RealtimeStreamKindLifecycle, map[string]any{
+		"provider_id":   providerID,
+		"provider_type": providerType,
+		"model":         stall.Model,
+		"interval_ms":   stall.Interval.Milliseconds(),
+		"events_seen":   stall.EventsSeen,
+		"elapsed_ms":    stall.Elapsed.Milliseconds(),
+	})
+}
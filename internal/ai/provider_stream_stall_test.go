@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// scriptedStallProvider emits one event per gap, then blocks until its context ends.
+type scriptedStallProvider struct {
+	gaps []time.Duration
+}
+
+func (p *scriptedStallProvider) StreamTurn(ctx context.Context, _ TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	for _, gap := range p.gaps {
+		select {
+		case <-ctx.Done():
+			return TurnResult{}, ctx.Err()
+		case <-time.After(gap):
+		}
+		onEvent(StreamEvent{Type: StreamEventTextDelta, Text: "."})
+	}
+	<-ctx.Done()
+	return TurnResult{}, ctx.Err()
+}
+
+func TestStreamStallProvider_AbortsSilentStream(t *testing.T) {
+	t.Parallel()
+
+	var stalls []providerStreamStall
+	events := 0
+	provider := &streamStallProvider{
+		// Each gap is shorter than the interval, so only the final silence trips the watchdog.
+		inner:    &scriptedStallProvider{gaps: []time.Duration{30 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond}},
+		interval: 60 * time.Millisecond,
+		onStall:  func(stall providerStreamStall) { stalls = append(stalls, stall) },
+	}
+	_, err := provider.StreamTurn(context.Background(), TurnRequest{Model: " gpt-5 "}, func(StreamEvent) { events++ })
+	if !errors.Is(err, errProviderStreamStall) {
+		t.Fatalf("err=%v, want provider_stream_stall", err)
+	}
+	if events != 3 {
+		t.Fatalf("events=%d, want 3", events)
+	}
+	if len(stalls) != 1 || stalls[0].EventsSeen != 3 || stalls[0].Model != "gpt-5" || stalls[0].Interval != 60*time.Millisecond || stalls[0].Elapsed < 150*time.Millisecond {
+		t.Fatalf("stalls=%+v", stalls)
+	}
+}
+
+func TestStreamStallProvider_PassesThroughParentCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	stalled := false
+	provider := &streamStallProvider{
+		inner:    &scriptedStallProvider{},
+		interval: time.Minute,
+		onStall:  func(providerStreamStall) { stalled = true },
+	}
+	_, err := provider.StreamTurn(ctx, TurnRequest{}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errProviderStreamStall) || stalled {
+		t.Fatalf("err=%v stalled=%v, want the parent deadline", err, stalled)
+	}
+}
@@ -0,0 +1,347 @@
+package ai
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const (
+	reactToolCallOpenTag  = "<tool_call>"
+	reactToolCallCloseTag = "</tool_call>"
+)
+
+// reactTextProvider adapts a provider without reliable native function calling to a ReAct-style
+// text protocol: tools are described in the system prompt, the model writes <tool_call> JSON blocks,
+// and the adapter parses those blocks back into ToolCalls. Tool results are fed back as text.
+type reactTextProvider struct {
+	inner Provider
+}
+
+// wrapProviderToolCallFormat applies the configured tool-calling format to a native adapter.
+//
+// A replayer is returned as is: recordings already hold the normalized output (see wrapProviderForSession).
+func wrapProviderToolCallFormat(adapter Provider, toolCallFormat string) Provider {
+	if adapter == nil {
+		return nil
+	}
+	if _, ok := adapter.(*ProviderReplayer); ok {
+		return adapter
+	}
+	if strings.TrimSpace(toolCallFormat) != config.AIToolCallFormatReActText {
+		return adapter
+	}
+	return &reactTextProvider{inner: adapter}
+}
+
+func (p *reactTextProvider) StreamTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	if p == nil || p.inner == nil {
+		return TurnResult{}, fmt.Errorf("react text provider not initialized")
+	}
+	tools := append([]ToolDef(nil), req.Tools...)
+	innerReq := req
+	innerReq.Tools = nil
+	innerReq.Messages = buildReActTextMessages(req.Messages, tools)
+
+	filter := &reactTextStreamFilter{}
+	innerResult, err := p.inner.StreamTurn(ctx, innerReq, func(event StreamEvent) {
+		switch event.Type {
+		case StreamEventTextDelta:
+			if visible := filter.push(event.Text); visible != "" {
+				emitProviderEvent(onEvent, StreamEvent{Type: StreamEventTextDelta, Text: visible})
+			}
+		case StreamEventFinishReason:
+			// Re-emitted below once tool calls have been parsed from the text.
+		default:
+			emitProviderEvent(onEvent, event)
+		}
+	})
+	if err != nil {
+		return innerResult, err
+	}
+	if visible := filter.flush(); visible != "" {
+		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventTextDelta, Text: visible})
+	}
+
+	text, calls := parseReActToolCalls(innerResult.Text)
+	result := innerResult
+	result.Text = text
+	result.ToolCalls = append(append([]ToolCall(nil), innerResult.ToolCalls...), calls...)
+	for _, call := range calls {
+		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallStart, ToolCall: &PartialToolCall{ID: call.ID, Name: call.Name}})
+		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallDelta, ToolCall: &PartialToolCall{ID: call.ID, Name: call.Name, Arguments: cloneAnyMap(call.Args)}})
+		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventToolCallEnd, ToolCall: &PartialToolCall{ID: call.ID, Name: call.Name, Arguments: cloneAnyMap(call.Args)}})
+	}
+	if len(result.ToolCalls) > 0 {
+		result.FinishReason = "tool_calls"
+	}
+	if result.RawProviderDiag == nil {
+		result.RawProviderDiag = map[string]any{}
+	}
+	result.RawProviderDiag["tool_call_format"] = config.AIToolCallFormatReActText
+	result.RawProviderDiag["react_tool_calls"] = len(calls)
+	emitProviderEvent(onEvent, StreamEvent{Type: StreamEventFinishReason, FinishHint: result.FinishReason})
+	return result, nil
+}
+
+// buildReActTextMessages rewrites native tool-call history into the ReAct text protocol.
+func buildReActTextMessages(messages []Message, tools []ToolDef) []Message {
+	protocol := buildReActToolProtocolPrompt(tools)
+	out := make([]Message, 0, len(messages)+1)
+	systemPatched := false
+	for _, msg := range messages {
+		role := strings.ToLower(strings.TrimSpace(msg.Role))
+		switch role {
+		case "system":
+			next := Message{Role: msg.Role, Content: append([]ContentPart(nil), msg.Content...)}
+			if !systemPatched {
+				next.Content = append(next.Content, ContentPart{Type: "text", Text: protocol})
+				systemPatched = true
+			}
+			out = append(out, next)
+		case "assistant":
+			parts := make([]ContentPart, 0, len(msg.Content))
+			for _, part := range msg.Content {
+				if strings.ToLower(strings.TrimSpace(part.Type)) != "tool_call" {
+					parts = append(parts, part)
+					continue
+				}
+				parts = append(parts, ContentPart{Type: "text", Text: formatReActToolCall(part)})
+			}
+			out = append(out, Message{Role: msg.Role, Content: parts})
+		case "tool":
+			parts := make([]ContentPart, 0, len(msg.Content))
+			for _, part := range msg.Content {
+				if strings.ToLower(strings.TrimSpace(part.Type)) != "tool_result" {
+					continue
+				}
+				parts = append(parts, ContentPart{Type: "text", Text: formatReActToolResult(part)})
+			}
+			if len(parts) == 0 {
+				continue
+			}
+			// Merge consecutive tool results into one user turn.
+			if n := len(out); n > 0 && out[n-1].Role == "user" && isReActToolResultMessage(out[n-1]) {
+				out[n-1].Content = append(out[n-1].Content, parts...)
+				continue
+			}
+			out = append(out, Message{Role: "user", Content: parts})
+		default:
+			out = append(out, msg)
+		}
+	}
+	if !systemPatched && protocol != "" {
+		out = append([]Message{{Role: "system", Content: []ContentPart{{Type: "text", Text: protocol}}}}, out...)
+	}
+	return out
+}
+
+func isReActToolResultMessage(msg Message) bool {
+	if len(msg.Content) == 0 {
+		return false
+	}
+	for _, part := range msg.Content {
+		if !strings.HasPrefix(strings.TrimSpace(part.Text), "<tool_result") {
+			return false
+		}
+	}
+	return true
+}
+
+func buildReActToolProtocolPrompt(tools []ToolDef) string {
+	if len(tools) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("## Tool calling protocol\n")
+	sb.WriteString("Native function calling is unavailable. To call a tool, write a block exactly like:\n")
+	sb.WriteString(reactToolCallOpenTag + `{"name": "<tool name>", "arguments": {<JSON arguments>}}` + reactToolCallCloseTag + "\n")
+	sb.WriteString("Rules:\n")
+	sb.WriteString("- Emit one block per tool call; several blocks in one reply are allowed.\n")
+	sb.WriteString("- The block content must be a single valid JSON object.\n")
+	sb.WriteString("- Stop writing after your tool call blocks; results arrive in the next user message as <tool_result> blocks.\n")
+	sb.WriteString("- Never write <tool_result> blocks yourself.\n")
+	sb.WriteString("- When no tool is needed, reply normally without any tool call block.\n")
+	sb.WriteString("Available tools:\n")
+	for _, tool := range tools {
+		name := strings.TrimSpace(tool.Name)
+		if name == "" {
+			continue
+		}
+		sb.WriteString("- ")
+		sb.WriteString(name)
+		if desc := strings.TrimSpace(tool.Description); desc != "" {
+			sb.WriteString(": ")
+			sb.WriteString(desc)
+		}
+		sb.WriteString("\n")
+		if schema := strings.TrimSpace(string(tool.InputSchema)); schema != "" {
+			sb.WriteString("  arguments schema: ")
+			sb.WriteString(schema)
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func formatReActToolCall(part ContentPart) string {
+	name := strings.TrimSpace(part.ToolName)
+	if name == "" {
+		name = strings.TrimSpace(part.Text)
+	}
+	argsRaw := strings.TrimSpace(part.ArgsJSON)
+	if argsRaw == "" && len(part.JSON) > 0 {
+		argsRaw = strings.TrimSpace(string(part.JSON))
+	}
+	if argsRaw == "" || !json.Valid([]byte(argsRaw)) {
+		argsRaw = "{}"
+	}
+	payload, err := json.Marshal(map[string]any{"name": name, "arguments": json.RawMessage(argsRaw)})
+	if err != nil {
+		payload = []byte(`{"name":` + fmt.Sprintf("%q", name) + `,"arguments":{}}`)
+	}
+	return reactToolCallOpenTag + string(payload) + reactToolCallCloseTag
+}
+
+func formatReActToolResult(part ContentPart) string {
+	callID := strings.TrimSpace(part.ToolCallID)
+	if callID == "" {
+		callID = strings.TrimSpace(part.ToolUseID)
+	}
+	output := strings.TrimSpace(part.Text)
+	if output == "" && len(part.JSON) > 0 {
+		output = string(part.JSON)
+	}
+	if output == "" {
+		output = "{}"
+	}
+	if name := strings.TrimSpace(part.ToolName); name != "" {
+		return fmt.Sprintf("<tool_result name=%q id=%q>\n%s\n</tool_result>", name, callID, output)
+	}
+	return fmt.Sprintf("<tool_result id=%q>\n%s\n</tool_result>", callID, output)
+}
+
+// parseReActToolCalls extracts <tool_call> blocks and returns the remaining visible text.
+func parseReActToolCalls(text string) (string, []ToolCall) {
+	var (
+		visible strings.Builder
+		calls   []ToolCall
+	)
+	rest := text
+	for {
+		start := strings.Index(rest, reactToolCallOpenTag)
+		if start < 0 {
+			visible.WriteString(rest)
+			break
+		}
+		visible.WriteString(rest[:start])
+		rest = rest[start+len(reactToolCallOpenTag):]
+		body := rest
+		if end := strings.Index(rest, reactToolCallCloseTag); end >= 0 {
+			body = rest[:end]
+			rest = rest[end+len(reactToolCallCloseTag):]
+		} else {
+			rest = ""
+		}
+		if call, ok := parseReActToolCallBody(body); ok {
+			calls = append(calls, call)
+		}
+	}
+	return strings.TrimSpace(visible.String()), calls
+}
+
+func parseReActToolCallBody(body string) (ToolCall, bool) {
+	body = strings.TrimSpace(body)
+	body = strings.TrimPrefix(body, "```json")
+	body = strings.TrimPrefix(body, "```")
+	body = strings.TrimSuffix(body, "```")
+	body = strings.TrimSpace(body)
+	var payload struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		repaired, ok := repairJSONObject(body)
+		if !ok || json.Unmarshal([]byte(repaired), &payload) != nil {
+			return ToolCall{}, false
+		}
+	}
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		return ToolCall{}, false
+	}
+	args := map[string]any{}
+	if raw := strings.TrimSpace(string(payload.Arguments)); raw != "" && raw != "null" {
+		if err := json.Unmarshal([]byte(raw), &args); err != nil {
+			// Some models double-encode arguments as a JSON string.
+			var encoded string
+			if json.Unmarshal([]byte(raw), &encoded) == nil {
+				args = (*toolArgsRepairStats)(nil).parse(encoded)
+			}
+		}
+	}
+	return ToolCall{ID: newReActToolCallID(), Name: name, Args: args}, true
+}
+
+func newReActToolCallID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "call_react"
+	}
+	return "call_react_" + hex.EncodeToString(b)
+}
+
+// reactTextStreamFilter hides <tool_call> blocks from streamed text deltas.
+type reactTextStreamFilter struct {
+	buf    string
+	inCall bool
+}
+
+func (f *reactTextStreamFilter) push(delta string) string {
+	f.buf += delta
+	var out strings.Builder
+	for {
+		if f.inCall {
+			end := strings.Index(f.buf, reactToolCallCloseTag)
+			if end < 0 {
+				return out.String()
+			}
+			f.buf = f.buf[end+len(reactToolCallCloseTag):]
+			f.inCall = false
+			continue
+		}
+		start := strings.Index(f.buf, reactToolCallOpenTag)
+		if start >= 0 {
+			out.WriteString(f.buf[:start])
+			f.buf = f.buf[start+len(reactToolCallOpenTag):]
+			f.inCall = true
+			continue
+		}
+		// Hold back a trailing partial open tag until the next delta decides it.
+		keep := 0
+		for n := len(reactToolCallOpenTag) - 1; n > 0; n-- {
+			if strings.HasSuffix(f.buf, reactToolCallOpenTag[:n]) {
+				keep = n
+				break
+			}
+		}
+		out.WriteString(f.buf[:len(f.buf)-keep])
+		f.buf = f.buf[len(f.buf)-keep:]
+		return out.String()
+	}
+}
+
+func (f *reactTextStreamFilter) flush() string {
+	if f.inCall {
+		f.buf = ""
+		return ""
+	}
+	out := f.buf
+	f.buf = ""
+	return out
+}
@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+type scriptedTextProvider struct {
+	deltas  []string
+	lastReq TurnRequest
+}
+
+func (p *scriptedTextProvider) StreamTurn(_ context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	p.lastReq = req
+	var sb strings.Builder
+	for _, delta := range p.deltas {
+		sb.WriteString(delta)
+		emitProviderEvent(onEvent, StreamEvent{Type: StreamEventTextDelta, Text: delta})
+	}
+	emitProviderEvent(onEvent, StreamEvent{Type: StreamEventFinishReason, FinishHint: "stop"})
+	return TurnResult{FinishReason: "stop", Text: sb.String()}, nil
+}
+
+func TestWrapProviderToolCallFormat_NativeIsPassthrough(t *testing.T) {
+	t.Parallel()
+
+	inner := &scriptedTextProvider{}
+	if got := wrapProviderToolCallFormat(inner, config.AIToolCallFormatNative); got != Provider(inner) {
+		t.Fatalf("native format should return the inner adapter, got %T", got)
+	}
+}
+
+func TestReActTextProvider_ParsesStreamedToolCalls(t *testing.T) {
+	t.Parallel()
+
+	inner := &scriptedTextProvider{deltas: []string{
+		"Let me check. <tool",
+		`_call>{"name": "terminal.exec", "arguments": {"command": "ls"}}</tool`,
+		"_call>",
+	}}
+	adapter := wrapProviderToolCallFormat(inner, config.AIToolCallFormatReActText)
+
+	var (
+		visible    strings.Builder
+		finishHint string
+		toolEnds   int
+	)
+	result, err := adapter.StreamTurn(context.Background(), TurnRequest{
+		Messages: []Message{
+			{Role: "system", Content: []ContentPart{{Type: "text", Text: "You are Flower."}}},
+			{Role: "user", Content: []ContentPart{{Type: "text", Text: "list files"}}},
+			{Role: "assistant", Content: []ContentPart{{Type: "tool_call", ToolCallID: "call_prev", ToolName: "terminal.exec", ArgsJSON: `{"command":"pwd"}`}}},
+			{Role: "tool", Content: []ContentPart{{Type: "tool_result", ToolCallID: "call_prev", Text: `{"status":"success"}`}}},
+		},
+		Tools: []ToolDef{{Name: "terminal.exec", Description: "Run a shell command.", InputSchema: []byte(`{"type":"object"}`)}},
+	}, func(event StreamEvent) {
+		switch event.Type {
+		case StreamEventTextDelta:
+			visible.WriteString(event.Text)
+		case StreamEventToolCallEnd:
+			toolEnds++
+		case StreamEventFinishReason:
+			finishHint = event.FinishHint
+		}
+	})
+	if err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+
+	if got := visible.String(); got != "Let me check. " {
+		t.Fatalf("visible text=%q, want tool block hidden", got)
+	}
+	if result.Text != "Let me check." {
+		t.Fatalf("result text=%q", result.Text)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Name != "terminal.exec" || result.ToolCalls[0].Args["command"] != "ls" {
+		t.Fatalf("tool calls=%+v", result.ToolCalls)
+	}
+	if strings.TrimSpace(result.ToolCalls[0].ID) == "" {
+		t.Fatalf("parsed tool call should have an id")
+	}
+	if result.FinishReason != "tool_calls" || finishHint != "tool_calls" || toolEnds != 1 {
+		t.Fatalf("finish=%q hint=%q toolEnds=%d", result.FinishReason, finishHint, toolEnds)
+	}
+
+	if len(inner.lastReq.Tools) != 0 {
+		t.Fatalf("inner request should not carry native tools")
+	}
+	msgs := inner.lastReq.Messages
+	if len(msgs) != 4 {
+		t.Fatalf("inner messages=%d, want 4", len(msgs))
+	}
+	if !strings.Contains(joinMessageText(msgs[0]), "terminal.exec: Run a shell command.") {
+		t.Fatalf("system prompt should describe tools, got %q", joinMessageText(msgs[0]))
+	}
+	if !strings.Contains(joinMessageText(msgs[2]), `<tool_call>{"arguments":{"command":"pwd"},"name":"terminal.exec"}</tool_call>`) {
+		t.Fatalf("assistant tool history not rewritten: %q", joinMessageText(msgs[2]))
+	}
+	if msgs[3].Role != "user" || !strings.Contains(joinMessageText(msgs[3]), `<tool_result id="call_prev">`) {
+		t.Fatalf("tool result not rewritten: %+v", msgs[3])
+	}
+}
+
+func TestParseReActToolCalls_IgnoresMalformedBlocks(t *testing.T) {
+	t.Parallel()
+
+	text, calls := parseReActToolCalls("Done.<tool_call>not json</tool_call>")
+	if text != "Done." || len(calls) != 0 {
+		t.Fatalf("text=%q calls=%+v", text, calls)
+	}
+}
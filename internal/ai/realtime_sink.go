@@ -12,6 +12,7 @@ import (
 
 	"github.com/floegence/flowersec/flowersec-go/rpc"
 	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
 )
 
 func (s *Service) ListActiveThreadRuns(endpointID string) []ActiveThreadRun {
@@ -201,6 +202,12 @@ func (s *Service) persistRealtimeEvent(ev RealtimeEvent) {
 	if !shouldPersistRealtimeEvent(ev) {
 		return
 	}
+	s.mu.Lock()
+	summaryOnly := s.cfg.EffectivePersistenceMode() == config.AIPersistenceModeSummaryOnly
+	s.mu.Unlock()
+	if summaryOnly && ev.EventType != RealtimeEventTypeThreadState {
+		return
+	}
 	payload := map[string]any{
 		"event_type":     ev.EventType,
 		"stream_kind":    ev.StreamKind,
@@ -217,7 +224,7 @@ func (s *Service) persistRealtimeEvent(ev RealtimeEvent) {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), s.persistOpTO)
 	defer cancel()
-	_ = s.threadsDB.AppendRunEvent(ctx, threadstore.RunEventRecord{
+	err = s.threadsDB.AppendRunEvent(ctx, threadstore.RunEventRecord{
 		EndpointID:  ev.EndpointID,
 		ThreadID:    ev.ThreadID,
 		RunID:       ev.RunID,
@@ -226,6 +233,9 @@ func (s *Service) persistRealtimeEvent(ev RealtimeEvent) {
 		PayloadJSON: truncateRunes(string(b), 6000),
 		AtUnixMs:    ev.AtUnixMs,
 	})
+	if err == nil {
+		s.runEvents.notify(runEventFeedKey(ev.EndpointID, ev.RunID))
+	}
 }
 
 func (s *Service) broadcastRealtimeEvent(ev RealtimeEvent) {
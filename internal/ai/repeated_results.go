@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// repeatedResultGuardThreshold is the number of different tool calls returning the same result
+	// that counts as no progress and injects the repeated-result overlay.
+	repeatedResultGuardThreshold = 3
+	// repeatedResultAskUserThreshold escalates the same no-progress pattern to ask_user.
+	repeatedResultAskUserThreshold = 5
+	// maxRepeatedResultFingerprints bounds the fingerprints tracked per run.
+	maxRepeatedResultFingerprints = 64
+)
+
+// toolResultFingerprint hashes a successful read-only result by tool name, normalized summary, and data,
+// so calls whose arguments differ but whose output does not map to the same fingerprint.
+// Mutating calls are skipped: writes legitimately return the same acknowledgement for different inputs.
+func toolResultFingerprint(call ToolCall, tr ToolResult) string {
+	if tr.Status != toolResultStatusSuccess || isMutatingInvocation(call.Name, call.Args) {
+		return ""
+	}
+	name := strings.TrimSpace(tr.ToolName)
+	if name == "" {
+		name = strings.TrimSpace(call.Name)
+	}
+	if name == "" {
+		return ""
+	}
+	summary := strings.Join(strings.Fields(strings.ToLower(tr.Summary)), " ")
+	data, err := canonicalJSON(tr.Data)
+	if err != nil {
+		data = "null"
+	}
+	sum := sha256.Sum256([]byte(name + "|" + summary + "|" + data))
+	return hex.EncodeToString(sum[:])
+}
+
+// observeToolResult records that the call with signature returned fingerprint and returns how many
+// different signatures have returned it so far in this run.
+func (s *runtimeState) observeToolResult(fingerprint string, signature string) int {
+	fingerprint = strings.TrimSpace(fingerprint)
+	signature = strings.TrimSpace(signature)
+	if s == nil || fingerprint == "" || signature == "" {
+		return 0
+	}
+	if s.ResultFingerprints == nil {
+		s.ResultFingerprints = make(map[string][]string)
+	}
+	sigs, tracked := s.ResultFingerprints[fingerprint]
+	if !tracked && len(s.ResultFingerprints) >= maxRepeatedResultFingerprints {
+		return 0
+	}
+	for _, existing := range sigs {
+		if existing == signature {
+			return len(sigs)
+		}
+	}
+	s.ResultFingerprints[fingerprint] = append(sigs, signature)
+	return len(sigs) + 1
+}
+
+func buildRepeatedResultOverlay(toolName string, distinctCalls int) string {
+	return fmt.Sprintf("[NO PROGRESS] %d different %s calls returned the same result. Varying arguments is not producing new information.\nDo NOT call %s again with another variation. Change strategy: inspect a different source, use a different tool, or finalize with the evidence you already have.", distinctCalls, strings.TrimSpace(toolName), strings.TrimSpace(toolName))
+}
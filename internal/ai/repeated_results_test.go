@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolResultFingerprint(t *testing.T) {
+	t.Parallel()
+
+	list := func(args map[string]any) ToolCall {
+		return ToolCall{ID: "call", Name: "terminal.exec", Args: args}
+	}
+	empty := ToolResult{ToolName: "terminal.exec", Status: toolResultStatusSuccess, Summary: "Command  finished", Data: map[string]any{"stdout": "", "exit_code": 0}}
+	a := toolResultFingerprint(list(map[string]any{"command": "ls empty"}), empty)
+	b := toolResultFingerprint(list(map[string]any{"command": "ls -la empty"}), ToolResult{ToolName: "terminal.exec", Status: toolResultStatusSuccess, Summary: "command finished", Data: map[string]any{"exit_code": 0, "stdout": ""}})
+	if a == "" || a != b {
+		t.Fatalf("same result with different args must share a fingerprint: %q vs %q", a, b)
+	}
+	other := toolResultFingerprint(list(map[string]any{"command": "ls src"}), ToolResult{ToolName: "terminal.exec", Status: toolResultStatusSuccess, Summary: "command finished", Data: map[string]any{"stdout": "main.go", "exit_code": 0}})
+	if other == a {
+		t.Fatalf("different data must not share a fingerprint")
+	}
+	if got := toolResultFingerprint(list(map[string]any{"command": "ls"}), ToolResult{ToolName: "terminal.exec", Status: toolResultStatusError, Summary: "failed"}); got != "" {
+		t.Fatalf("failed result fingerprint=%q, want empty", got)
+	}
+	write := ToolCall{ID: "call", Name: "file.write", Args: map[string]any{"file_path": "a.txt", "content": "x"}}
+	if got := toolResultFingerprint(write, ToolResult{ToolName: "file.write", Status: toolResultStatusSuccess, Summary: "written"}); got != "" {
+		t.Fatalf("mutating call fingerprint=%q, want empty", got)
+	}
+}
+
+func TestRuntimeState_ObserveToolResult(t *testing.T) {
+	t.Parallel()
+
+	state := newRuntimeState("objective")
+	if got := state.observeToolResult("fp", "sig_1"); got != 1 {
+		t.Fatalf("first=%d", got)
+	}
+	if got := state.observeToolResult("fp", "sig_1"); got != 1 {
+		t.Fatalf("same signature must not count twice: %d", got)
+	}
+	if got := state.observeToolResult("fp", "sig_2"); got != 2 {
+		t.Fatalf("second signature=%d", got)
+	}
+	if got := state.observeToolResult("", "sig_3"); got != 0 {
+		t.Fatalf("empty fingerprint=%d", got)
+	}
+
+	for i := 0; len(state.ResultFingerprints) < maxRepeatedResultFingerprints; i++ {
+		state.observeToolResult("fill_"+strings.Repeat("x", i), "sig")
+	}
+	if got := state.observeToolResult("untracked", "sig"); got != 0 {
+		t.Fatalf("fingerprint past the cap=%d, want 0", got)
+	}
+	if got := state.observeToolResult("fp", "sig_3"); got != 3 {
+		t.Fatalf("tracked fingerprint past the cap=%d, want 3", got)
+	}
+}
+
+func TestRepeatedResultGuardSignals(t *testing.T) {
+	t.Parallel()
+
+	overlay := buildRepeatedResultOverlay("terminal.exec", 3)
+	if !strings.Contains(overlay, "[NO PROGRESS] 3 different terminal.exec calls") {
+		t.Fatalf("overlay=%q", overlay)
+	}
+	signal := defaultGuardAskUserSignal("stuck", nil, "guard_repeated_result", "tool:call_1")
+	if signal.ReasonCode != AskUserReasonConflictingWork || len(signal.EvidenceRefs) != 1 {
+		t.Fatalf("signal=%+v", signal)
+	}
+	if got := noUserInteractionFallbackReasonCode("guard_repeated_result"); got != subagentFailureReasonRepeatedToolFailures {
+		t.Fatalf("fallback reason=%q", got)
+	}
+}
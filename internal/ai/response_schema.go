@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+	oresponses "github.com/openai/openai-go/responses"
+	oshared "github.com/openai/openai-go/shared"
+)
+
+var responseJSONSchemaNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// responseJSONSchema is a user-supplied structured output format (RunOptions.ResponseJSONSchema).
+//
+// The wire shape follows OpenAI's response_format.json_schema object:
+// {"name": "...", "description": "...", "strict": true, "schema": {...}}.
+type responseJSONSchema struct {
+	Name        string
+	Description string
+	Strict      bool
+	Schema      map[string]any
+}
+
+// parseResponseJSONSchema validates a structured output format before it reaches a provider.
+func parseResponseJSONSchema(raw json.RawMessage) (responseJSONSchema, error) {
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return responseJSONSchema{}, errors.New("missing response_json_schema")
+	}
+	var wire struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Strict      *bool           `json:"strict"`
+		Schema      json.RawMessage `json:"schema"`
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
+		return responseJSONSchema{}, errors.New("invalid response_json_schema: must be a JSON object")
+	}
+	if err := json.Unmarshal(raw, &wire); err != nil {
+		return responseJSONSchema{}, fmt.Errorf("invalid response_json_schema: %w", err)
+	}
+	name := strings.TrimSpace(wire.Name)
+	if name == "" {
+		return responseJSONSchema{}, errors.New("invalid response_json_schema: missing name")
+	}
+	if !responseJSONSchemaNamePattern.MatchString(name) {
+		return responseJSONSchema{}, fmt.Errorf("invalid response_json_schema: name %q must be 1-64 letters, digits, underscores, or dashes", name)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(wire.Schema, &schema); err != nil || schema == nil {
+		return responseJSONSchema{}, errors.New("invalid response_json_schema: schema must be a JSON object")
+	}
+	strict := true
+	if wire.Strict != nil {
+		strict = *wire.Strict
+	}
+	return responseJSONSchema{
+		Name:        name,
+		Description: strings.TrimSpace(wire.Description),
+		Strict:      strict,
+		Schema:      schema,
+	}, nil
+}
+
+// ValidateRunOptions rejects run options that can never reach a provider, before a run is queued.
+//
+// An empty ResponseJSONSchema is allowed; json_schema without one keeps the old behavior of leaving the format unset.
+func ValidateRunOptions(opts RunOptions) error {
+	if err := validateExternalTools(opts.ExternalTools); err != nil {
+		return err
+	}
+	if _, err := parseForceIntent(opts.ForceIntent); err != nil {
+		return err
+	}
+	if opts.MinCompletionChars < 0 {
+		return fmt.Errorf("invalid min_completion_chars: %d", opts.MinCompletionChars)
+	}
+	if len(strings.TrimSpace(string(opts.ResponseJSONSchema))) == 0 {
+		return nil
+	}
+	_, err := parseResponseJSONSchema(opts.ResponseJSONSchema)
+	return err
+}
+
+// requestedResponseJSONSchema returns the schema to attach for this turn, if json_schema output was requested.
+func requestedResponseJSONSchema(controls ProviderControls) (responseJSONSchema, bool) {
+	if !strings.EqualFold(strings.TrimSpace(controls.ResponseFormat), "json_schema") {
+		return responseJSONSchema{}, false
+	}
+	if len(strings.TrimSpace(string(controls.ResponseJSONSchema))) == 0 {
+		return responseJSONSchema{}, false
+	}
+	spec, err := parseResponseJSONSchema(controls.ResponseJSONSchema)
+	if err != nil {
+		return responseJSONSchema{}, false
+	}
+	return spec, true
+}
+
+func (s responseJSONSchema) openAIResponsesFormat() oresponses.ResponseFormatTextConfigUnionParam {
+	cfg := oresponses.ResponseFormatTextJSONSchemaConfigParam{
+		Name:   s.Name,
+		Schema: s.Schema,
+		Strict: openai.Bool(s.Strict),
+	}
+	if s.Description != "" {
+		cfg.Description = openai.String(s.Description)
+	}
+	return oresponses.ResponseFormatTextConfigUnionParam{OfJSONSchema: &cfg}
+}
+
+func (s responseJSONSchema) openAIChatFormat() openai.ChatCompletionNewParamsResponseFormatUnion {
+	cfg := oshared.ResponseFormatJSONSchemaJSONSchemaParam{
+		Name:   s.Name,
+		Schema: s.Schema,
+		Strict: openai.Bool(s.Strict),
+	}
+	if s.Description != "" {
+		cfg.Description = openai.String(s.Description)
+	}
+	return openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &oshared.ResponseFormatJSONSchemaParam{JSONSchema: cfg},
+	}
+}
+
+func (s responseJSONSchema) anthropicOutputConfig() anthropic.OutputConfigParam {
+	return anthropic.OutputConfigParam{Format: anthropic.JSONOutputFormatParam{Schema: s.Schema}}
+}
@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateRunOptions_ResponseJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	valid := `{"name":"extraction","description":"Extracted fields","schema":{"type":"object","properties":{"title":{"type":"string"}},"required":["title"],"additionalProperties":false}}`
+	if err := ValidateRunOptions(RunOptions{ResponseFormat: "json_schema", ResponseJSONSchema: json.RawMessage(valid)}); err != nil {
+		t.Fatalf("valid schema rejected: %v", err)
+	}
+	if err := ValidateRunOptions(RunOptions{ResponseFormat: "json_schema"}); err != nil {
+		t.Fatalf("missing schema should keep the old behavior: %v", err)
+	}
+
+	for name, raw := range map[string]string{
+		"array":          `[{"name":"x"}]`,
+		"string":         `"schema"`,
+		"null":           `null`,
+		"missing name":   `{"schema":{"type":"object"}}`,
+		"bad name":       `{"name":"has space","schema":{"type":"object"}}`,
+		"missing schema": `{"name":"extraction"}`,
+		"schema array":   `{"name":"extraction","schema":[1,2]}`,
+	} {
+		if err := ValidateRunOptions(RunOptions{ResponseFormat: "json_schema", ResponseJSONSchema: json.RawMessage(raw)}); err == nil {
+			t.Fatalf("%s: expected rejection for %s", name, raw)
+		}
+	}
+
+	spec, err := parseResponseJSONSchema(json.RawMessage(valid))
+	if err != nil {
+		t.Fatalf("parseResponseJSONSchema: %v", err)
+	}
+	if spec.Name != "extraction" || !spec.Strict || spec.Schema["type"] != "object" {
+		t.Fatalf("spec=%+v", spec)
+	}
+	spec, _ = parseResponseJSONSchema(json.RawMessage(`{"name":"loose","strict":false,"schema":{"type":"object"}}`))
+	if spec.Strict {
+		t.Fatalf("explicit strict=false should be kept")
+	}
+}
+
+func TestRequestedResponseJSONSchema_ProviderFormats(t *testing.T) {
+	t.Parallel()
+
+	controls := ProviderControls{
+		ResponseFormat:     "json_schema",
+		ResponseJSONSchema: json.RawMessage(`{"name":"extraction","schema":{"type":"object","properties":{"title":{"type":"string"}}}}`),
+	}
+	spec, ok := requestedResponseJSONSchema(controls)
+	if !ok {
+		t.Fatalf("expected schema for json_schema controls")
+	}
+	if _, ok := requestedResponseJSONSchema(ProviderControls{ResponseFormat: "json_object", ResponseJSONSchema: controls.ResponseJSONSchema}); ok {
+		t.Fatalf("schema should only apply to json_schema")
+	}
+
+	var chat map[string]any
+	b, _ := json.Marshal(spec.openAIChatFormat())
+	_ = json.Unmarshal(b, &chat)
+	inner, _ := chat["json_schema"].(map[string]any)
+	if chat["type"] != "json_schema" || inner["name"] != "extraction" || inner["strict"] != true || inner["schema"] == nil {
+		t.Fatalf("chat response_format=%s", b)
+	}
+
+	var responses map[string]any
+	b, _ = json.Marshal(spec.openAIResponsesFormat())
+	_ = json.Unmarshal(b, &responses)
+	if responses["type"] != "json_schema" || responses["name"] != "extraction" || responses["schema"] == nil {
+		t.Fatalf("responses text.format=%s", b)
+	}
+
+	var output map[string]any
+	b, _ = json.Marshal(spec.anthropicOutputConfig())
+	_ = json.Unmarshal(b, &output)
+	format, _ := output["format"].(map[string]any)
+	if format["type"] != "json_schema" || format["schema"] == nil {
+		t.Fatalf("anthropic output_config=%s", b)
+	}
+}
+
+func TestMoonshotProvider_StreamTurn_AttachesResponseJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	gotFormat := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		format, _ := req["response_format"].(map[string]any)
+		gotFormat <- format
+
+		f, _ := w.(http.Flusher)
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeOpenAISSEJSON(w, f, map[string]any{
+			"id":      "chatcmpl_schema",
+			"object":  "chat.completion.chunk",
+			"created": 123,
+			"model":   "kimi-k2.5",
+			"choices": []any{map[string]any{"index": 0, "finish_reason": "stop", "delta": map[string]any{"role": "assistant", "content": `{"title":"ok"}`}}},
+		})
+	}))
+	defer srv.Close()
+
+	provider, err := newProviderAdapter("moonshot", srv.URL+"/v1", "sk-test", nil)
+	if err != nil {
+		t.Fatalf("newProviderAdapter: %v", err)
+	}
+	result, err := provider.StreamTurn(context.Background(), TurnRequest{
+		Model:    "kimi-k2.5",
+		Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "extract"}}}},
+		ProviderControls: ProviderControls{
+			ResponseFormat:     "json_schema",
+			ResponseJSONSchema: json.RawMessage(`{"name":"extraction","schema":{"type":"object"}}`),
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+	if strings.TrimSpace(result.Text) != `{"title":"ok"}` {
+		t.Fatalf("text=%q", result.Text)
+	}
+	format := <-gotFormat
+	inner, _ := format["json_schema"].(map[string]any)
+	if format["type"] != "json_schema" || inner["name"] != "extraction" {
+		t.Fatalf("response_format=%v, want json_schema extraction", format)
+	}
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+// routingRuleMatch is the ai.routing_rules entry that routed one run.
+type routingRuleMatch struct {
+	Index   int
+	Pattern string
+	Intent  string
+	Mode    string
+	// Trigger is the input text the pattern matched; it is removed before the input reaches the model.
+	Trigger string
+}
+
+func (m *routingRuleMatch) eventPayload() map[string]any {
+	if m == nil {
+		return nil
+	}
+	return map[string]any{
+		"rule":    fmt.Sprintf("routing_rules[%d]", m.Index),
+		"pattern": m.Pattern,
+		"intent":  m.Intent,
+		"mode":    m.Mode,
+		"trigger": m.Trigger,
+	}
+}
+
+// matchRoutingRule returns the first rule whose pattern matches the trimmed input, and the input with
+// the matched trigger removed. When removing the trigger would leave nothing, the input is kept as is.
+func matchRoutingRule(rules []config.AIRoutingRule, text string) (*routingRuleMatch, string) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return nil, text
+	}
+	for i, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			// Validate rejects invalid patterns; a config that bypassed it never matches.
+			continue
+		}
+		loc := re.FindStringIndex(trimmed)
+		if loc == nil {
+			continue
+		}
+		match := &routingRuleMatch{
+			Index:   i,
+			Pattern: rule.Pattern,
+			Intent:  strings.ToLower(strings.TrimSpace(rule.Intent)),
+			Mode:    strings.ToLower(strings.TrimSpace(rule.Mode)),
+			Trigger: trimmed[loc[0]:loc[1]],
+		}
+		stripped := strings.TrimSpace(strings.TrimRight(trimmed[:loc[0]], " \t") + " " + strings.TrimLeft(trimmed[loc[1]:], " \t"))
+		if stripped == "" {
+			return match, text
+		}
+		return match, stripped
+	}
+	return nil, text
+}
+
+// applyRoutingRule routes one run by ai.routing_rules: the matched rule's mode replaces the thread's
+// mode, and its intent pins the run intent unless the caller already forced one. Structured responses
+// answer a waiting prompt and are never routed.
+func applyRoutingRule(cfg *config.AIConfig, req *RunStartRequest) *routingRuleMatch {
+	if cfg == nil || req == nil || len(cfg.RoutingRules) == 0 || req.Input.StructuredResponse != nil {
+		return nil
+	}
+	match, text := matchRoutingRule(cfg.RoutingRules, req.Input.Text)
+	if match == nil {
+		return nil
+	}
+	req.Input.Text = text
+	if match.Mode != "" {
+		req.Options.Mode = match.Mode
+	}
+	if match.Intent != "" && strings.TrimSpace(req.Options.ForceIntent) == "" {
+		req.Options.ForceIntent = match.Intent
+	}
+	req.Options.RoutingRule = match
+	return match
+}
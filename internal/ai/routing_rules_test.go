@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestMatchRoutingRule_FirstMatchStripsTrigger(t *testing.T) {
+	t.Parallel()
+
+	rules := []config.AIRoutingRule{
+		{Pattern: `^/plan\b`, Mode: "plan"},
+		{Pattern: `^/(chat|hi)\b`, Intent: "Social"},
+		{Pattern: `(?i)\bbrainstorm:`, Intent: "creative", Mode: "act"},
+	}
+
+	match, text := matchRoutingRule(rules, "  /plan refactor the config loader ")
+	if match == nil || match.Index != 0 || match.Mode != "plan" || match.Trigger != "/plan" {
+		t.Fatalf("match=%+v", match)
+	}
+	if text != "refactor the config loader" {
+		t.Fatalf("text=%q", text)
+	}
+
+	match, text = matchRoutingRule(rules, "/chat how are you")
+	if match == nil || match.Index != 1 || match.Intent != RunIntentSocial || text != "how are you" {
+		t.Fatalf("match=%+v text=%q", match, text)
+	}
+
+	match, text = matchRoutingRule(rules, "ideas please, Brainstorm: names for the CLI")
+	if match == nil || match.Index != 2 || text != "ideas please, names for the CLI" {
+		t.Fatalf("match=%+v text=%q", match, text)
+	}
+
+	if match, text := matchRoutingRule(rules, "/plan"); match == nil || text != "/plan" {
+		t.Fatalf("trigger-only input: match=%+v text=%q, want the input kept", match, text)
+	}
+	if match, text := matchRoutingRule(rules, "explain /plan mode"); match != nil || text != "explain /plan mode" {
+		t.Fatalf("unmatched input: match=%+v text=%q", match, text)
+	}
+}
+
+func TestApplyRoutingRule_RespectsForcedIntentAndStructuredResponses(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.AIConfig{RoutingRules: []config.AIRoutingRule{{Pattern: `^/write\b`, Intent: "creative", Mode: "plan"}}}
+
+	req := RunStartRequest{Input: RunInput{Text: "/write a haiku"}, Options: RunOptions{Mode: "act"}}
+	if match := applyRoutingRule(cfg, &req); match == nil {
+		t.Fatalf("expected a routing match")
+	}
+	if req.Input.Text != "a haiku" || req.Options.Mode != "plan" || req.Options.ForceIntent != RunIntentCreative || req.Options.RoutingRule == nil {
+		t.Fatalf("routed req=%+v", req)
+	}
+
+	req = RunStartRequest{Input: RunInput{Text: "/write a haiku"}, Options: RunOptions{ForceIntent: "task"}}
+	applyRoutingRule(cfg, &req)
+	if req.Options.ForceIntent != "task" {
+		t.Fatalf("force_intent=%q, want the caller's value kept", req.Options.ForceIntent)
+	}
+
+	req = RunStartRequest{Input: RunInput{Text: "/write a haiku", StructuredResponse: &RequestUserInputResponseRecord{}}}
+	if match := applyRoutingRule(cfg, &req); match != nil || req.Input.Text != "/write a haiku" {
+		t.Fatalf("structured response routed: match=%+v text=%q", match, req.Input.Text)
+	}
+}
@@ -484,12 +484,15 @@ func toAIRPCError(err error) *rpc.Error {
 	case errors.Is(err, ErrNotConfigured):
 		return &rpc.Error{Code: 503, Message: "ai not configured"}
 	case errors.Is(err, ErrThreadBusy),
+		errors.Is(err, ErrThreadArchived),
 		errors.Is(err, ErrRunChanged),
 		errors.Is(err, ErrWaitingPromptChanged),
 		errors.Is(err, ErrModelLockViolation),
 		errors.Is(err, ErrModelSwitchRequiresExplicitRestart),
 		errors.Is(err, ErrFollowupsRevisionChanged):
 		return &rpc.Error{Code: 409, Message: msg}
+	case errors.Is(err, ErrInvalidPromptAnswer):
+		return &rpc.Error{Code: 400, Message: msg}
 	}
 
 	s := strings.ToLower(msg)
@@ -21,11 +21,13 @@ import (
 
 	"github.com/floegence/redeven/internal/ai/threadstore"
 	aitools "github.com/floegence/redeven/internal/ai/tools"
+	"github.com/floegence/redeven/internal/auditlog"
 	"github.com/floegence/redeven/internal/config"
 	"github.com/floegence/redeven/internal/knowledge"
 	"github.com/floegence/redeven/internal/pathutil"
 	"github.com/floegence/redeven/internal/session"
 	"github.com/floegence/redeven/internal/websearch"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type runOptions struct {
@@ -49,6 +51,7 @@ type runOptions struct {
 	MessageID    string
 
 	MaxWallTime         time.Duration
+	WallTimeLimit       time.Duration
 	IdleTimeout         time.Duration
 	ToolApprovalTimeout time.Duration
 	StreamWriteTimeout  time.Duration
@@ -59,6 +62,8 @@ type runOptions struct {
 
 	OnStreamEvent func(any)
 	Writer        http.ResponseWriter
+	// OnRunEventPersisted is called after each run event is stored.
+	OnRunEventPersisted func()
 
 	SubagentDepth         int
 	AllowSubagentDelegate bool
@@ -66,8 +71,27 @@ type runOptions struct {
 	ForceReadonlyExec     bool
 	NoUserInteraction     bool
 	SkillManager          *skillManager
+	ToolRateLimiter       *toolRateLimiter
+	WebSearchCache        *websearch.Cache
+	ProviderRecorder      *ProviderRecorder
+	ProviderSession       string
+	ProviderReplayTapes   *providerReplayTapes
+	// RunWebhooks notifies the namespace's run webhook when the run ends. Nil for subagent runs.
+	RunWebhooks      *runWebhookNotifier
+	ProviderCircuits *providerCircuitBreakers
+	// Audit records terminal_command_policy matches. Optional.
+	Audit *auditlog.Store
+	// ProviderHTTPClients shares provider HTTP clients across runs. Nil uses the SDK default clients.
+	ProviderHTTPClients *providerHTTPClients
+	// Tracer records the run, turn, and tool spans. Nil disables tracing.
+	Tracer trace.Tracer
+	// Metrics receives tool call, token, and provider error counts for the metrics endpoint. Optional.
+	Metrics *serviceMetrics
+	// ParentLoop is set for spawn_subtask children and links their loop to the parent's.
+	ParentLoop *AgentLoop
 
 	terminalExecRunner func(ctx context.Context, inv terminalExecInvocation) (terminalExecOutcome, error)
+	tokenCounter       TokenCounter
 }
 
 type run struct {
@@ -92,35 +116,66 @@ type run struct {
 	messageID    string
 
 	maxWallTime    time.Duration
+	wallTimeLimit  time.Duration
+	wallDeadline   time.Time
 	idleTimeout    time.Duration
 	toolApprovalTO time.Duration
 	activityCh     chan struct{}
 	doneCh         chan struct{}
 	doneOnce       sync.Once
 
-	muCancel           sync.Mutex
-	cancelReason       string // "canceled"|"timed_out"|""
-	endReason          string // "complete"|"canceled"|"timed_out"|"disconnected"|"error"
-	cancelRequested    bool
-	cancelFn           context.CancelFunc
-	detached           atomic.Bool // hard-canceled: stop emitting realtime events and skip thread state updates
-	busyCount          atomic.Int32
-	runtimeToolCalls   atomic.Int64
-	runtimeTokens      atomic.Int64
-	assistantPersisted atomic.Bool
-
-	uploadsDir       string
-	threadsDB        *threadstore.Store
-	persistOpTimeout time.Duration
-
-	onStreamEvent func(any)
-	w             http.ResponseWriter
-	stream        *ndjsonStream
+	muCancel         sync.Mutex
+	cancelReason     string // "canceled"|"timed_out"|"service_shutdown"|""
+	endReason        string // "complete"|"canceled"|"timed_out"|"disconnected"|"error"
+	cancelRequested  bool
+	cancelFn         context.CancelFunc
+	detached         atomic.Bool // hard-canceled: stop emitting realtime events and skip thread state updates
+	busyCount        atomic.Int32
+	runtimeToolCalls atomic.Int64
+	runtimeTokens    atomic.Int64
+	// Provider-reported usage totals, reported in the run webhook.
+	runtimeInputTokens     atomic.Int64
+	runtimeOutputTokens    atomic.Int64
+	runtimeReasoningTokens atomic.Int64
+	assistantPersisted     atomic.Bool
+	metrics                RunMetrics
+
+	// Cost accounting is only touched from the run loop goroutine.
+	accumulatedCostUSD       float64
+	costPricingMissingLogged bool
+	autoRetriesUsed          int
+	systemPromptPersisted    bool   // run loop goroutine only; see persistRunSystemPrompt
+	systemPromptVersions     int    // run loop goroutine only; see recordRunSystemPromptVersion
+	lastSystemPromptHash     string // run loop goroutine only
+	lastSystemPrompt         string // run loop goroutine only
+
+	uploadsDir         string
+	threadsDB          *threadstore.Store
+	persistOpTimeout   time.Duration
+	summaryOnlyPersist bool
+	toolRateLimiter    *toolRateLimiter
+	webSearchCache     *websearch.Cache
+	providerRecorder   *ProviderRecorder
+	providerSession    string
+	providerReplays    *providerReplayTapes
+	providerCircuits   *providerCircuitBreakers
+	providerHTTP       *providerHTTPClients
+	runWebhooks        *runWebhookNotifier
+	audit              *auditlog.Store
+	tracer             trace.Tracer
+	serviceMetrics     *serviceMetrics
+
+	onStreamEvent       func(any)
+	onRunEventPersisted func()
+	w                   http.ResponseWriter
+	stream              *ndjsonStream
 
 	mu              sync.Mutex
 	toolApprovals   map[string]chan bool // tool_id -> decision channel
 	toolBlockIndex  map[string]int       // tool_id -> blockIndex
 	waitingApproval bool
+	// externalToolWaits holds the result channel of each external tool call waiting for ProvideToolResult.
+	externalToolWaits map[string]chan ExternalToolResult
 
 	muLifecycle         sync.Mutex
 	lastLifecyclePhase  string
@@ -142,9 +197,17 @@ type run struct {
 	finalizationReason string
 	executionContract  string
 	currentModelID     string
+	promptProfile      string
 
 	webSearchToolEnabled   bool
 	openAIWebSearchEnabled bool
+	// approvedToolNames records tools the user approved in this run (tool_approval first_time_per_run).
+	approvedToolNames map[string]bool
+	// activeToolNames is the tool set offered to the model on the current turn (nil before the first turn).
+	activeToolNames map[string]struct{}
+	// imageOutputEnabled lets the model return generated images (ModelCapability.SupportsImageOutput).
+	imageOutputEnabled bool
+	generatedImageURLs []string
 
 	collectedWebSources        map[string]SourceRef // url -> source
 	collectedWebSourceOrder    []string
@@ -158,8 +221,11 @@ type run struct {
 
 	skillManager    *skillManager
 	subagentManager *subagentManager
+	parentLoop      *AgentLoop
+	agentLoop       *AgentLoop
 
 	terminalExecRunner func(ctx context.Context, inv terminalExecInvocation) (terminalExecOutcome, error)
+	tokenCounter       TokenCounter
 }
 
 type assistantAnswerState struct {
@@ -205,11 +271,26 @@ func newRun(opts runOptions) *run {
 		uploadsDir:                strings.TrimSpace(opts.UploadsDir),
 		threadsDB:                 opts.ThreadsDB,
 		persistOpTimeout:          opts.PersistOpTimeout,
+		summaryOnlyPersist:        opts.AIConfig.EffectivePersistenceMode() == config.AIPersistenceModeSummaryOnly,
+		toolRateLimiter:           opts.ToolRateLimiter,
+		webSearchCache:            opts.WebSearchCache,
+		providerRecorder:          opts.ProviderRecorder,
+		providerSession:           strings.TrimSpace(opts.ProviderSession),
+		providerReplays:           opts.ProviderReplayTapes,
+		providerCircuits:          opts.ProviderCircuits,
+		providerHTTP:              opts.ProviderHTTPClients,
+		runWebhooks:               opts.RunWebhooks,
+		audit:                     opts.Audit,
+		tracer:                    opts.Tracer,
+		serviceMetrics:            opts.Metrics,
 		onStreamEvent:             opts.OnStreamEvent,
+		onRunEventPersisted:       opts.OnRunEventPersisted,
 		w:                         opts.Writer,
 		toolApprovals:             make(map[string]chan bool),
+		externalToolWaits:         make(map[string]chan ExternalToolResult),
 		toolBlockIndex:            make(map[string]int),
 		maxWallTime:               opts.MaxWallTime,
+		wallTimeLimit:             opts.WallTimeLimit,
 		idleTimeout:               opts.IdleTimeout,
 		toolApprovalTO:            opts.ToolApprovalTimeout,
 		doneCh:                    make(chan struct{}),
@@ -221,6 +302,7 @@ func newRun(opts runOptions) *run {
 		forceReadonlyExec:         opts.ForceReadonlyExec,
 		skillManager:              opts.SkillManager,
 		noUserInteraction:         opts.NoUserInteraction,
+		parentLoop:                opts.ParentLoop,
 		allowSubagentDelegate: func() bool {
 			if opts.AllowSubagentDelegate {
 				return true
@@ -228,6 +310,7 @@ func newRun(opts runOptions) *run {
 			return opts.SubagentDepth <= 0
 		}(),
 		terminalExecRunner: opts.terminalExecRunner,
+		tokenCounter:       opts.tokenCounter,
 	}
 	if r.terminalExecRunner == nil {
 		r.terminalExecRunner = defaultTerminalExecRunner
@@ -335,6 +418,7 @@ func (r *run) requestCancel(reason string) {
 	cancelFn := r.cancelFn
 	r.muCancel.Unlock()
 	if alreadyRequested || cancelFn == nil {
+		r.releasePendingApprovals()
 		if r.subagentManager != nil {
 			r.subagentManager.closeAll()
 		}
@@ -345,6 +429,8 @@ func (r *run) requestCancel(reason string) {
 	// - signal: cancel context immediately to stop new sampling/tool dispatch
 	// - grace/force: re-signal after a short delay in case something is stuck
 	cancelFn()
+	// Approvals are released after the context is canceled so waiters observe the cancel reason.
+	r.releasePendingApprovals()
 	if r.subagentManager != nil {
 		r.subagentManager.closeAll()
 	}
@@ -445,17 +531,21 @@ func (r *run) getExecutionContract() string {
 	return v
 }
 
-func (r *run) recordRuntimeToolCall() {
+func (r *run) recordRuntimeToolCall(toolName string) {
 	if r == nil {
 		return
 	}
 	r.runtimeToolCalls.Add(1)
+	r.serviceMetrics.recordToolCall(toolName)
 }
 
 func (r *run) recordRuntimeTurnUsage(usage TurnUsage, estimateTokens int) {
 	if r == nil {
 		return
 	}
+	r.runtimeInputTokens.Add(usage.InputTokens)
+	r.runtimeOutputTokens.Add(usage.OutputTokens)
+	r.runtimeReasoningTokens.Add(usage.ReasoningTokens)
 	total := usage.InputTokens + usage.OutputTokens + usage.ReasoningTokens
 	if total <= 0 && estimateTokens > 0 {
 		total = int64(estimateTokens)
@@ -473,6 +563,25 @@ func (r *run) runtimeStatsSnapshot() (toolCalls int64, tokens int64) {
 	return r.runtimeToolCalls.Load(), r.runtimeTokens.Load()
 }
 
+// releasePendingApprovals closes every pending approval and external tool channel so their waits return at once.
+//
+// A closed channel reads as "canceled", never as a user decision or a tool result.
+func (r *run) releasePendingApprovals() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for toolID, ch := range r.toolApprovals {
+		close(ch)
+		delete(r.toolApprovals, toolID)
+	}
+	for toolID, ch := range r.externalToolWaits {
+		close(ch)
+		delete(r.externalToolWaits, toolID)
+	}
+}
+
 func (r *run) cancel() {
 	if r == nil {
 		return
@@ -733,6 +842,25 @@ func (r *run) persistRunRecord(state RunState, errCode string, errMessage string
 	_ = r.threadsDB.UpsertRun(ctx, rec)
 }
 
+// persistRunSystemPrompt stores the system prompt of the run's first model turn for run exports,
+// and records every later prompt that differs from the one before it.
+func (r *run) persistRunSystemPrompt(prompt string, info runSystemPromptInfo) {
+	if r == nil || r.threadsDB == nil || r.summaryOnlyPersist {
+		return
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.persistTimeout())
+	defer cancel()
+	if !r.systemPromptPersisted {
+		r.systemPromptPersisted = true
+		_ = r.threadsDB.SetRunSystemPrompt(ctx, r.endpointID, r.id, prompt)
+	}
+	r.recordRunSystemPromptVersion(ctx, prompt, info)
+}
+
 func (r *run) persistRunEvent(eventType string, streamKind RealtimeStreamKind, payload map[string]any) {
 	if r == nil || r.threadsDB == nil {
 		return
@@ -741,6 +869,9 @@ func (r *run) persistRunEvent(eventType string, streamKind RealtimeStreamKind, p
 	if eventType == "" {
 		return
 	}
+	if r.summaryOnlyPersist && !isSummaryOnlyRunEventType(eventType) {
+		return
+	}
 	if payload == nil {
 		payload = map[string]any{}
 	}
@@ -750,7 +881,7 @@ func (r *run) persistRunEvent(eventType string, streamKind RealtimeStreamKind, p
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), r.persistTimeout())
 	defer cancel()
-	_ = r.threadsDB.AppendRunEvent(ctx, threadstore.RunEventRecord{
+	err = r.threadsDB.AppendRunEvent(ctx, threadstore.RunEventRecord{
 		EndpointID:  strings.TrimSpace(r.endpointID),
 		ThreadID:    strings.TrimSpace(r.threadID),
 		RunID:       strings.TrimSpace(r.id),
@@ -759,12 +890,18 @@ func (r *run) persistRunEvent(eventType string, streamKind RealtimeStreamKind, p
 		PayloadJSON: truncateRunes(string(b), 6000),
 		AtUnixMs:    time.Now().UnixMilli(),
 	})
+	if err == nil && r.onRunEventPersisted != nil {
+		r.onRunEventPersisted()
+	}
 }
 
 func (r *run) persistToolCall(rec threadstore.ToolCallRecord) {
 	if r == nil || r.threadsDB == nil {
 		return
 	}
+	if r.summaryOnlyPersist {
+		rec = minimizeToolCallRecordForPersist(rec)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), r.persistTimeout())
 	defer cancel()
 	_ = r.threadsDB.UpsertToolCall(ctx, rec)
@@ -782,6 +919,9 @@ func (r *run) persistExecutionSpan(rec threadstore.ExecutionSpanRecord) {
 	if r == nil || r.threadsDB == nil {
 		return
 	}
+	if r.summaryOnlyPersist {
+		rec.PayloadJSON = ""
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), r.persistTimeout())
 	defer cancel()
 	_ = r.threadsDB.UpsertExecutionSpan(ctx, rec)
@@ -1000,9 +1140,10 @@ func (r *run) approveTool(toolID string, approved bool) error {
 		return errors.New("missing tool_id")
 	}
 
+	// The send happens under r.mu so it cannot race releasePendingApprovals closing the channel.
 	r.mu.Lock()
+	defer r.mu.Unlock()
 	ch := r.toolApprovals[toolID]
-	r.mu.Unlock()
 	if ch == nil {
 		return errors.New("tool not pending approval")
 	}
@@ -1027,11 +1168,15 @@ func (r *run) run(ctx context.Context, req RunRequest) (retErr error) {
 	r.setFinalizationReason("")
 	r.setExecutionContract(req.Options.ExecutionContract)
 	startedAt := time.Now()
+	ctx, runSpan := r.startRunSpan(ctx, req)
 	r.persistRunRecord(RunStateRunning, "", "", startedAt.UnixMilli(), 0)
 	runStartPayload := map[string]any{
 		"model":         strings.TrimSpace(req.Model),
 		"history_count": len(req.History),
 	}
+	if runSpan != nil {
+		runStartPayload["trace_id"] = runSpan.SpanContext().TraceID().String()
+	}
 	r.persistRunEvent("run.start", RealtimeStreamKindLifecycle, runStartPayload)
 	defer func() {
 		endReason := strings.TrimSpace(r.getEndReason())
@@ -1103,7 +1248,7 @@ func (r *run) run(ctx context.Context, req RunRequest) (retErr error) {
 			errCode = string(aitools.ErrorCodeUnknown)
 		}
 		r.persistRunRecord(state, errCode, errMsg, startedAt.UnixMilli(), time.Now().UnixMilli())
-		r.persistRunEvent(eventType, RealtimeStreamKindLifecycle, map[string]any{
+		endPayload := map[string]any{
 			"state":               string(state),
 			"error_code":          errCode,
 			"error":               errMsg,
@@ -1111,7 +1256,36 @@ func (r *run) run(ctx context.Context, req RunRequest) (retErr error) {
 			"finalization_class":  finalizationClass,
 			"execution_contract":  executionContract,
 			"completion_contract": completionContract,
-		})
+			"model":               strings.TrimSpace(r.currentModelID),
+			"mode":                strings.TrimSpace(req.Options.Mode),
+		}
+		if r.accumulatedCostUSD > 0 {
+			endPayload["accumulated_cost_usd"] = r.accumulatedCostUSD
+		}
+		if quirks := r.metrics.ProviderQuirks(); len(quirks) > 0 {
+			endPayload["provider_quirks"] = quirks
+		}
+		if r.autoRetriesUsed > 0 {
+			endPayload["auto_retries"] = r.autoRetriesUsed
+		}
+		if state == RunStateSuccess || state == RunStateWaitingUser {
+			if phrase, language, ok := r.fallbackPhraseCatalog().Match(r.assistantMarkdownTextSnapshot()); ok {
+				endPayload["fallback_final"] = true
+				endPayload["fallback_phrase"] = phrase
+				endPayload["fallback_language"] = language
+			}
+		}
+		r.persistRunMetrics(r.metricsSnapshot(state, finalizationReason))
+		if state == RunStateCanceled {
+			// A user cancel is its own outcome, not a provider or tool failure.
+			r.persistRunEvent("run.cancelled", RealtimeStreamKindLifecycle, map[string]any{
+				"cancel_reason":       r.getCancelReason(),
+				"finalization_reason": finalizationReason,
+			})
+		}
+		r.persistRunEvent(eventType, RealtimeStreamKindLifecycle, endPayload)
+		r.notifyRunWebhook(state, errCode, finalizationReason, startedAt)
+		endRunSpan(runSpan, state, finalizationReason, errMsg)
 		r.debug("ai.run.end",
 			"end_reason", endReason,
 			"finalization_reason", finalizationReason,
@@ -1143,6 +1317,9 @@ func (r *run) run(ctx context.Context, req RunRequest) (retErr error) {
 		execCtx, cancelMaxWall = context.WithTimeout(execCtx, r.maxWallTime)
 		defer cancelMaxWall()
 	}
+	if r.wallTimeLimit > 0 {
+		r.wallDeadline = time.Now().Add(r.wallTimeLimit)
+	}
 	if r.idleTimeout > 0 && r.activityCh != nil {
 		r.touchActivity()
 		go r.runIdleWatchdog(execCtx)
@@ -1203,7 +1380,7 @@ func (r *run) run(ctx context.Context, req RunRequest) (retErr error) {
 	if err != nil {
 		return r.failRun("Failed to load AI provider key", err)
 	}
-	if !ok || strings.TrimSpace(apiKey) == "" {
+	if (!ok || strings.TrimSpace(apiKey) == "") && config.AIProviderTypeRequiresAPIKey(providerCfg.Type) {
 		return r.failRun(
 			fmt.Sprintf("AI provider %q is missing API key. Open Settings to configure it.", providerDisplay),
 			fmt.Errorf("missing api key for provider %q", providerID),
@@ -1213,7 +1390,9 @@ func (r *run) run(ctx context.Context, req RunRequest) (retErr error) {
 	if !r.shouldUseNativeRuntime(providerCfg) {
 		return r.failRun("Unsupported AI provider type", fmt.Errorf("unsupported provider type %q", strings.TrimSpace(providerCfg.Type)))
 	}
-	return r.runNative(execCtx, req, *providerCfg, strings.TrimSpace(apiKey), strings.TrimSpace(taskObjective))
+	return r.runWithAutoRetry(execCtx, func() error {
+		return r.runNative(execCtx, req, *providerCfg, strings.TrimSpace(apiKey), strings.TrimSpace(taskObjective))
+	})
 }
 
 func (r *run) appendTextDelta(delta string) error {
@@ -1792,7 +1971,7 @@ func (r *run) persistSyntheticToolSuccess(toolID string, toolName string, args m
 	}
 	argsCopy := cloneAnyMap(args)
 	startedAt := time.Now()
-	r.recordRuntimeToolCall()
+	r.recordRuntimeToolCall(toolName)
 	r.persistRunEvent("tool.call", RealtimeStreamKindTool, map[string]any{
 		"tool_id":   toolID,
 		"tool_name": toolName,
@@ -1853,7 +2032,7 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 	if args == nil {
 		args = map[string]any{}
 	}
-	r.recordRuntimeToolCall()
+	r.recordRuntimeToolCall(toolName)
 
 	argsForPersist := args
 	if toolName == "terminal.exec" {
@@ -1874,6 +2053,7 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 	requireUserApproval := r.cfg.EffectiveRequireUserApproval()
 	blockDangerousCommands := r.cfg.EffectiveBlockDangerousCommands()
 	isPlanMode := strings.TrimSpace(strings.ToLower(r.runMode)) == config.AIModePlan
+	sandboxViolation := r.workspaceSandboxViolation(toolName, args)
 	denyDangerous := blockDangerousCommands && dangerous
 	denyPlanMutating := isPlanMode && mutating
 	commandProfile := aitools.InvocationCommandProfile(toolName, args)
@@ -1889,11 +2069,34 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 	}
 	readonlyRisk := string(aitools.TerminalCommandRiskReadonly)
 	denyReadonlyExec := r.forceReadonlyExec && toolName == "terminal.exec" && commandRisk != "" && commandRisk != readonlyRisk
-	requireApprovalForInvocation := requireUserApproval && needsApproval && !denyReadonlyExec
+	requireApprovalForInvocation := requireUserApproval && needsApproval && !denyReadonlyExec && sandboxViolation == ""
+	approvalReason := ""
+	// The scheduler evaluates ai.tool_approval before dispatch and attaches its decision to ctx.
+	if approvalDecision, ok := toolApprovalDecisionFromContext(ctx); ok && approvalDecision.Matched {
+		requireApprovalForInvocation = approvalDecision.Require && !denyReadonlyExec && sandboxViolation == ""
+		approvalReason = approvalDecision.Reason
+	} else if requireApprovalForInvocation {
+		approvalReason = "required: execution_policy.require_user_approval"
+	}
+	var commandDecision terminalCommandDecision
+	if toolName == "terminal.exec" && r.cfg != nil {
+		commandDecision = evaluateTerminalCommandPolicy(r.cfg.TerminalCommandPolicy, readStringField(args, "command"))
+	}
+	commandPolicyWarnOnly := commandDecision.Denied && r.cfg.EffectiveTerminalCommandPolicyWarnOnly()
+	denyCommand := commandDecision.Denied && !commandPolicyWarnOnly
+	if denyCommand {
+		requireApprovalForInvocation = false
+	}
 	denyNoUserInteractionApproval := r.noUserInteraction && requireApprovalForInvocation
 	policyDecision := "allow"
 	policyReason := "none"
-	if denyNoUserInteractionApproval {
+	if sandboxViolation != "" {
+		policyDecision = "deny"
+		policyReason = sandboxViolationSummary
+	} else if denyCommand {
+		policyDecision = "deny"
+		policyReason = commandDeniedSummary
+	} else if denyNoUserInteractionApproval {
 		policyDecision = "deny"
 		policyReason = "no_user_interaction_policy"
 	} else if denyReadonlyExec {
@@ -1927,6 +2130,8 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 			"classification_reason":           classificationReason,
 			"policy_decision":                 policyDecision,
 			"policy_reason":                   policyReason,
+			"approval_reason":                 approvalReason,
+			"command_policy_rule":             commandDecision.Rule,
 			"policy_force_readonly_exec":      r.forceReadonlyExec,
 			"policy_require_user_approval":    requireUserApproval,
 			"policy_no_user_interaction":      r.noUserInteraction,
@@ -1938,6 +2143,9 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 			"timeout_max_ms":                  terminalTimeoutDecision.MaxMS,
 			"timeout_source":                  terminalTimeoutDecision.Source,
 		})
+		if commandDecision.Denied {
+			r.recordTerminalCommandDenied(toolID, readStringField(args, "command"), commandDecision, commandPolicyWarnOnly)
+		}
 	}
 	toolCallPayload := map[string]any{
 		"tool_id":   toolID,
@@ -1974,6 +2182,7 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 		"normalized_command", normalizedCommand,
 		"policy_decision", policyDecision,
 		"policy_reason", policyReason,
+		"approval_reason", approvalReason,
 		"args_preview", previewAnyForLog(redactToolArgsForLog(toolName, args), 512),
 	)
 
@@ -2001,6 +2210,7 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 		block.RequiresApproval = true
 		block.ApprovalState = "required"
 	}
+	block.ApprovalReason = approvalReason
 
 	r.emitPersistedToolBlockSet(idx, block)
 	persistResult := any(nil)
@@ -2049,6 +2259,7 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 		}
 		r.emitPersistedToolBlockSet(idx, block)
 		r.persistToolCallSnapshot(toolID, toolName, block.Status, args, errorResult, toolErr, recoveryAction, toolStartedAt, time.Now())
+		r.metrics.recordToolError()
 		r.persistRunEvent("tool.error", RealtimeStreamKindTool, map[string]any{
 			"tool_id":   toolID,
 			"tool_name": toolName,
@@ -2086,6 +2297,34 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 		return outcome, nil
 	}
 
+	if sandboxViolation != "" {
+		toolErr := &aitools.ToolError{
+			Code:      aitools.ErrorCodeSandboxViolation,
+			Message:   "Blocked by the strict workspace sandbox: " + sandboxViolation,
+			Retryable: false,
+			SuggestedFixes: []string{
+				"Use paths inside the working directory.",
+				"Do not cd or reference absolute paths outside the working directory in terminal.exec.",
+			},
+		}
+		setToolError(toolErr, "", nil)
+		return outcome, nil
+	}
+
+	if denyCommand {
+		toolErr := &aitools.ToolError{
+			Code:      aitools.ErrorCodeCommandDenied,
+			Message:   "Command blocked by " + commandDecision.Rule,
+			Retryable: false,
+			SuggestedFixes: []string{
+				"Do not retry the same command; this environment forbids it.",
+				"Reach the goal with a different command or tool, or report the blocker.",
+			},
+		}
+		setToolError(toolErr, "", nil)
+		return outcome, nil
+	}
+
 	if denyReadonlyExec {
 		toolErr := &aitools.ToolError{
 			Code:      aitools.ErrorCodePermissionDenied,
@@ -2150,13 +2389,30 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 		return outcome, nil
 	}
 
+	// With ai.apply_patch_preview, a patch is dry-run first so reviewers approve the exact diff.
+	var patchPreview map[string]any
+	if toolName == "apply_patch" && meta.CanWrite && r.cfg.EffectiveApplyPatchPreview() {
+		preview, err := r.previewApplyPatch(ctx, readStringField(args, "patch"))
+		if err != nil {
+			toolErr := aitools.ClassifyError(aitools.Invocation{ToolName: toolName, Args: args, WorkingDir: r.workingDir, AgentHomeDir: r.agentHomeDir}, err)
+			setToolError(toolErr, "", nil)
+			return outcome, nil
+		}
+		patchPreview = preview
+		previewEvent := patchStatsEventPayload(toolID, preview)
+		previewEvent["requires_approval"] = block.RequiresApproval
+		r.persistRunEvent("tool.patch.preview", RealtimeStreamKindTool, previewEvent)
+		block.Result = preview
+		r.emitPersistedToolBlockSet(idx, block)
+	}
+
 	if block.RequiresApproval {
 		ch := make(chan bool, 1)
 		r.mu.Lock()
 		r.toolApprovals[toolID] = ch
 		r.waitingApproval = true
 		r.mu.Unlock()
-		r.persistRunEvent("tool.approval.requested", RealtimeStreamKindLifecycle, map[string]any{"tool_id": toolID, "tool_name": toolName})
+		r.persistRunEvent("tool.approval.requested", RealtimeStreamKindLifecycle, map[string]any{"tool_id": toolID, "tool_name": toolName, "approval_reason": approvalReason})
 		r.debug("ai.run.tool.approval.requested", "tool_id", toolID, "tool_name", toolName)
 
 		approved := false
@@ -2168,13 +2424,21 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 		}
 		timer := time.NewTimer(to)
 		defer timer.Stop()
+		// Waiting for the user does not count against the tool call's own timeout.
+		resumeTimeout := pauseToolCallTimeout(ctx)
 		select {
-		case approved = <-ch:
+		case decision, open := <-ch:
+			if open {
+				approved = decision
+			} else {
+				waitErr = "canceled"
+			}
 		case <-ctx.Done():
 			waitErr = "canceled"
 		case <-timer.C:
 			timedOut = true
 		}
+		resumeTimeout()
 
 		r.mu.Lock()
 		delete(r.toolApprovals, toolID)
@@ -2198,12 +2462,24 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 		}
 		if !approved {
 			toolErr := &aitools.ToolError{Code: aitools.ErrorCodePermissionDenied, Message: "Rejected by user", Retryable: false}
+			if patchPreview != nil {
+				toolErr = &aitools.ToolError{
+					Code:      aitools.ErrorCodePatchRejected,
+					Message:   "Patch rejected by user after preview",
+					Retryable: false,
+					SuggestedFixes: []string{
+						"Do not resend the same patch.",
+						"Replan the change, or ask the user what they expect instead.",
+					},
+				}
+			}
 			block.ApprovalState = "rejected"
-			setToolError(toolErr, "", nil)
+			setToolError(toolErr, "", patchPreview)
 			return outcome, nil
 		}
 
 		block.ApprovalState = "approved"
+		r.markToolApproved(toolName)
 		r.persistRunEvent("tool.approval.approved", RealtimeStreamKindLifecycle, map[string]any{"tool_id": toolID, "tool_name": toolName})
 		r.debug("ai.run.tool.approval.approved", "tool_id", toolID, "tool_name", toolName)
 	}
@@ -2245,6 +2521,10 @@ func (r *run) handleToolCall(ctx context.Context, toolID string, toolName string
 		}
 	}
 
+	if patchPreview != nil {
+		r.persistRunEvent("tool.patch.applied", RealtimeStreamKindTool, patchStatsEventPayload(toolID, result))
+	}
+
 	block.Status = ToolCallStatusSuccess
 	block.Result = result
 	block.Error = ""
@@ -2521,6 +2801,17 @@ func (r *run) snapshotAssistantMessageJSON() (string, string, int64, error) {
 	return r.snapshotAssistantMessageJSONWithStatus("complete")
 }
 
+// assistantMessageJSONForPersist returns the assistant message as it should be written to the thread store.
+//
+// In summary-only persistence mode, thinking and tool bodies are dropped; only the final answer and
+// waiting-user prompts (needed to resume ask_user flows) are kept. Live clients still receive the full message.
+func (r *run) assistantMessageJSONForPersist(messageJSON string) string {
+	if r == nil || !r.summaryOnlyPersist {
+		return messageJSON
+	}
+	return minimizeAssistantMessageJSONForPersist(messageJSON)
+}
+
 func extractAskUserSummaryFromBlock(block any) string {
 	switch v := block.(type) {
 	case ToolCallBlock:
@@ -3288,7 +3579,19 @@ func (r *run) execTool(ctx context.Context, meta *session.Meta, toolID string, t
 		ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutMS)*time.Millisecond)
 		defer cancel()
 
-		return websearch.Search(ctx, provider, key, websearch.SearchRequest{Query: query, Count: p.Count})
+		searchReq := websearch.SearchRequest{Query: query, Count: p.Count}
+		cacheTTL := time.Duration(r.cfg.EffectiveWebSearchCacheTTLSeconds()) * time.Second
+		if cacheTTL > 0 {
+			if cached, ok := r.webSearchCache.Get(provider, searchReq); ok {
+				return cached, nil
+			}
+		}
+		result, err := websearch.Search(ctx, provider, key, searchReq)
+		if err != nil {
+			return nil, err
+		}
+		r.webSearchCache.Put(provider, searchReq, result, cacheTTL, r.cfg.EffectiveWebSearchCacheMaxEntries())
+		return result, nil
 
 	case "knowledge.search":
 		if meta == nil || !meta.CanRead {
@@ -3313,6 +3616,21 @@ func (r *run) execTool(ctx context.Context, meta *session.Meta, toolID string, t
 			Tags:       p.Tags,
 		})
 
+	case "read_tool_output":
+		if meta == nil || !meta.CanRead {
+			return nil, errors.New("read permission denied")
+		}
+		var p struct {
+			ContentRef string `json:"content_ref"`
+			Offset     int    `json:"offset"`
+			Limit      int    `json:"limit"`
+		}
+		b, _ := json.Marshal(args)
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, errors.New("invalid args")
+		}
+		return r.toolReadToolOutput(ctx, p.ContentRef, p.Offset, p.Limit)
+
 	case "write_todos":
 		var p struct {
 			Todos           []TodoItem `json:"todos"`
@@ -3325,6 +3643,18 @@ func (r *run) execTool(ctx context.Context, meta *session.Meta, toolID string, t
 		}
 		return r.toolWriteTodos(ctx, toolID, p.Todos, p.ExpectedVersion, p.Explanation)
 
+	case "scratchpad":
+		var p struct {
+			Op    string `json:"op"`
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		}
+		b, _ := json.Marshal(args)
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, errors.New("invalid args")
+		}
+		return r.toolScratchpad(ctx, p.Op, p.Key, p.Value)
+
 	case "exit_plan_mode":
 		var p ExitPlanModeArgs
 		b, _ := json.Marshal(args)
@@ -3366,6 +3696,12 @@ func (r *run) execTool(ctx context.Context, meta *session.Meta, toolID string, t
 		if reason != "" {
 			out["reason"] = reason
 		}
+		if len(activation.Prerequisites) > 0 {
+			out["prerequisites"] = activation.Prerequisites
+		}
+		if len(activation.RequiredTools) > 0 {
+			out["required_tools"] = activation.RequiredTools
+		}
 		if len(activation.Dependencies) > 0 {
 			deps := make([]map[string]any, 0, len(activation.Dependencies))
 			for _, dep := range activation.Dependencies {
@@ -3387,6 +3723,12 @@ func (r *run) execTool(ctx context.Context, meta *session.Meta, toolID string, t
 		}
 		return r.manageSubagents(ctx, cloneAnyMap(args))
 
+	case "spawn_subtask":
+		if meta == nil || !meta.CanExecute {
+			return nil, errors.New("execute permission denied")
+		}
+		return r.spawnSubtask(ctx, cloneAnyMap(args))
+
 	default:
 		return nil, fmt.Errorf("unknown tool: %s", toolName)
 	}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+const maxRunAutoRetryBackoff = 30 * time.Second
+
+// earlyRunFailure is returned instead of failing the run when the failure happened before any progress
+// and the run may be retried from scratch.
+type earlyRunFailure struct {
+	message string
+	cause   error
+}
+
+func (e *earlyRunFailure) Error() string {
+	if e == nil {
+		return ""
+	}
+	if e.cause != nil {
+		return e.cause.Error()
+	}
+	return e.message
+}
+
+func (e *earlyRunFailure) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.cause
+}
+
+// hasRunProgress reports whether the run already produced assistant text or invoked any tool.
+//
+// Any tool call counts, not only successful ones: a failed call may still have had side effects.
+func (r *run) hasRunProgress() bool {
+	if r == nil {
+		return false
+	}
+	return r.runtimeToolCalls.Load() > 0 || r.hasNonEmptyAssistantText()
+}
+
+// canAutoRetryRun reports whether a failure right now may restart the whole run instead of surfacing.
+func (r *run) canAutoRetryRun(ctx context.Context) bool {
+	if r == nil || (ctx != nil && ctx.Err() != nil) {
+		return false
+	}
+	return r.autoRetriesUsed < r.cfg.EffectiveRunAutoRetryMaxRetries() && !r.hasRunProgress()
+}
+
+// failRunOrAutoRetry fails the run, unless it can still be retried from scratch.
+func (r *run) failRunOrAutoRetry(ctx context.Context, errMsg string, cause error) error {
+	if r.canAutoRetryRun(ctx) {
+		return &earlyRunFailure{message: strings.TrimSpace(errMsg), cause: cause}
+	}
+	return r.failRun(errMsg, cause)
+}
+
+func runAutoRetryBackoff(initial time.Duration, attempt int) time.Duration {
+	if initial <= 0 {
+		return 0
+	}
+	backoff := initial
+	for i := 1; i < attempt && backoff < maxRunAutoRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRunAutoRetryBackoff {
+		return maxRunAutoRetryBackoff
+	}
+	return backoff
+}
+
+// runWithAutoRetry runs attempt and restarts it with exponential backoff while it reports an early failure.
+func (r *run) runWithAutoRetry(ctx context.Context, attempt func() error) error {
+	for {
+		err := attempt()
+		var early *earlyRunFailure
+		if r == nil || !errors.As(err, &early) {
+			return err
+		}
+		r.autoRetriesUsed++
+		initial := time.Duration(r.cfg.EffectiveRunAutoRetryInitialBackoffMS()) * time.Millisecond
+		backoff := runAutoRetryBackoff(initial, r.autoRetriesUsed)
+		errText := sanitizeLogText(errorString(early.cause), 240)
+		r.debug("ai.run.auto_retried",
+			"attempt", r.autoRetriesUsed,
+			"backoff_ms", backoff.Milliseconds(),
+			"reason", early.message,
+			"error", errText,
+		)
+		r.persistRunEvent("run.auto_retried", RealtimeStreamKindLifecycle, map[string]any{
+			"attempt":     r.autoRetriesUsed,
+			"max_retries": r.cfg.EffectiveRunAutoRetryMaxRetries(),
+			"backoff_ms":  backoff.Milliseconds(),
+			"reason":      early.message,
+			"error":       errText,
+		})
+		if backoff <= 0 {
+			continue
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if r.finalizeIfContextCanceled(ctx) {
+				return nil
+			}
+			return r.failRun(early.message, early.cause)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestRunAutoRetryBackoff(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 10, want: maxRunAutoRetryBackoff},
+	}
+	for _, tc := range cases {
+		if got := runAutoRetryBackoff(time.Second, tc.attempt); got != tc.want {
+			t.Fatalf("runAutoRetryBackoff(1s, %d)=%v, want %v", tc.attempt, got, tc.want)
+		}
+	}
+	if got := runAutoRetryBackoff(0, 3); got != 0 {
+		t.Fatalf("zero initial backoff must stay zero, got %v", got)
+	}
+}
+
+func newAutoRetryTestRun(t *testing.T, maxRetries int) (*run, *threadstore.Store) {
+	t.Helper()
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	noBackoff := 0
+	r := newRun(runOptions{
+		AIConfig:         &config.AIConfig{RunAutoRetry: &config.AIRunAutoRetryPolicy{MaxRetries: maxRetries, InitialBackoffMS: &noBackoff}},
+		RunID:            "run_retry",
+		EndpointID:       "env_retry",
+		ThreadID:         "th_retry",
+		MessageID:        "msg_retry",
+		ThreadsDB:        db,
+		PersistOpTimeout: 2 * time.Second,
+	})
+	return r, db
+}
+
+func countAutoRetriedEvents(t *testing.T, db *threadstore.Store) int {
+	t.Helper()
+	events, err := db.ListRunEvents(context.Background(), "env_retry", "run_retry", 100)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	n := 0
+	for _, ev := range events {
+		if ev.EventType == "run.auto_retried" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRunWithAutoRetry_RecoversFromEarlyFailure(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r, db := newAutoRetryTestRun(t, 2)
+	attempts := 0
+	err := r.runWithAutoRetry(ctx, func() error {
+		attempts++
+		if attempts < 3 {
+			return r.failRunOrAutoRetry(ctx, "Provider failed", errors.New("transient 503"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runWithAutoRetry: %v", err)
+	}
+	if attempts != 3 || r.autoRetriesUsed != 2 {
+		t.Fatalf("attempts=%d autoRetriesUsed=%d, want 3/2", attempts, r.autoRetriesUsed)
+	}
+	if got := countAutoRetriedEvents(t, db); got != 2 {
+		t.Fatalf("run.auto_retried events=%d, want 2", got)
+	}
+}
+
+func TestRunWithAutoRetry_SurfacesFailureWhenExhausted(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r, db := newAutoRetryTestRun(t, 1)
+	cause := errors.New("transient 503")
+	attempts := 0
+	err := r.runWithAutoRetry(ctx, func() error {
+		attempts++
+		return r.failRunOrAutoRetry(ctx, "Provider failed", cause)
+	})
+	if !errors.Is(err, cause) {
+		t.Fatalf("err=%v, want the original cause", err)
+	}
+	var early *earlyRunFailure
+	if errors.As(err, &early) {
+		t.Fatalf("exhausted retries must surface a normal failure")
+	}
+	if attempts != 2 || countAutoRetriedEvents(t, db) != 1 {
+		t.Fatalf("attempts=%d, want 2 with one run.auto_retried event", attempts)
+	}
+}
+
+func TestRunCanAutoRetryRun_RequiresNoProgress(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r, _ := newAutoRetryTestRun(t, 3)
+	if !r.canAutoRetryRun(ctx) {
+		t.Fatalf("fresh run should be retryable")
+	}
+	r.recordRuntimeToolCall("terminal.exec")
+	if r.canAutoRetryRun(ctx) {
+		t.Fatalf("run with tool calls must not be retried")
+	}
+
+	disabled, _ := newAutoRetryTestRun(t, 0)
+	if disabled.canAutoRetryRun(ctx) {
+		t.Fatalf("max_retries=0 must disable auto-retry")
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	fresh, _ := newAutoRetryTestRun(t, 3)
+	if fresh.canAutoRetryRun(canceled) {
+		t.Fatalf("canceled context must not be retried")
+	}
+}
@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	aitools "github.com/floegence/redeven/internal/ai/tools"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestRequestCancel_ReleasesPendingToolApproval(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	r := newPolicyTestRun(t, workspace, config.AIModeAct, &config.AIExecutionPolicy{
+		RequireUserApproval: true,
+	}, "msg_cancel_approval")
+
+	done := make(chan *toolCallOutcome, 1)
+	go func() {
+		outcome, _ := r.handleToolCall(context.Background(), "tool_cancel_approval", "terminal.exec", map[string]any{
+			"command": "printf 'never' > note.txt",
+		})
+		done <- outcome
+	}()
+	waitApprovalRequested(t, r, "tool_cancel_approval")
+
+	r.requestCancel("canceled")
+	select {
+	case outcome := <-done:
+		if outcome == nil || outcome.Success {
+			t.Fatalf("canceled approval must not run the tool: %+v", outcome)
+		}
+		if outcome.ToolError == nil || outcome.ToolError.Code != aitools.ErrorCodeCanceled {
+			t.Fatalf("tool error=%+v, want canceled", outcome.ToolError)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("approval wait did not return after cancel")
+	}
+	assertNoApprovalWait(t, r, "tool_cancel_approval")
+	if err := r.approveTool("tool_cancel_approval", true); err == nil {
+		t.Fatalf("approving a released tool should fail")
+	}
+}
+
+func TestRequestCancel_ReleasesTaskCompleteConfirm(t *testing.T) {
+	t.Parallel()
+
+	r := newRun(runOptions{Log: slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))})
+	type result struct {
+		approved bool
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		approved, err := r.waitForTaskCompleteConfirm(context.Background(), "done")
+		done <- result{approved: approved, err: err}
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !r.isWaitingApproval() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	r.requestCancel("canceled")
+	select {
+	case res := <-done:
+		if res.approved || !errors.Is(res.err, context.Canceled) {
+			t.Fatalf("got approved=%v err=%v, want context.Canceled", res.approved, res.err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("task_complete confirmation did not return after cancel")
+	}
+	if r.getCancelReason() != "canceled" {
+		t.Fatalf("cancel reason=%q", r.getCancelReason())
+	}
+}
+
+func TestService_CancelRunRequiresExecutePermission(t *testing.T) {
+	t.Parallel()
+
+	svc := &Service{}
+	if err := svc.CancelRun(&session.Meta{EndpointID: "env_1", CanRead: true, CanWrite: true}, "run_1"); !errors.Is(err, errExecutePermissionDenied) {
+		t.Fatalf("err=%v, want execute permission denied", err)
+	}
+	if err := svc.CancelRun(&session.Meta{EndpointID: "env_1", CanExecute: true}, "run_1"); err != nil {
+		t.Fatalf("execute-only session should be able to cancel: %v", err)
+	}
+}
+
+func TestIsSummaryOnlyRunEventType_KeepsRunCancelled(t *testing.T) {
+	t.Parallel()
+
+	if !isSummaryOnlyRunEventType("run.cancelled") {
+		t.Fatalf("run.cancelled should survive summary-only persistence")
+	}
+}
@@ -0,0 +1,269 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	contextcompactor "github.com/floegence/redeven/internal/ai/context/compactor"
+	contextmodel "github.com/floegence/redeven/internal/ai/context/model"
+	"github.com/floegence/redeven/internal/config"
+)
+
+// Compaction strategies accepted by RunOptions.CompactionStrategy.
+const (
+	// CompactionStrategyTruncate folds archived context into truncated bullets (default).
+	CompactionStrategyTruncate = "truncate"
+	// CompactionStrategyModelSummary asks the run's model for a dense summary of archived context.
+	CompactionStrategyModelSummary = "model_summary"
+)
+
+const (
+	modelSummaryKeepRecentMessages = 10
+	modelSummaryMinMessages        = 12
+	modelSummaryPartMaxRunes       = 4000
+	modelSummaryTranscriptMaxRunes = 48000
+	modelSummaryMaxOutputTokens    = 1500
+	modelSummaryTimeout            = 60 * time.Second
+)
+
+// CompactionConfig tunes the truncate compaction strategy. Zero fields take defaults scaled to the model's
+// context window: the defaults below apply at 128k tokens, shrink to half on small windows, and grow up to
+// four times on large ones.
+type CompactionConfig struct {
+	// KeepRecentMessages is how many of the newest messages are kept verbatim (default 10).
+	KeepRecentMessages int `json:"keep_recent_messages,omitempty"`
+	// SummaryLineRunes caps each archived message in the summary (default 100 runes).
+	SummaryLineRunes int `json:"summary_line_runes,omitempty"`
+	// MaxSummaryLines caps the summary to the newest archived messages (default 12 lines).
+	MaxSummaryLines int `json:"max_summary_lines,omitempty"`
+	// ToolResultRunes truncates tool results in kept messages, marking them [compressed] (default 500 runes).
+	ToolResultRunes int `json:"tool_result_runes,omitempty"`
+}
+
+const (
+	compactionDefaultKeepRecentMessages = 10
+	compactionDefaultSummaryLineRunes   = 100
+	compactionDefaultMaxSummaryLines    = 12
+	compactionDefaultToolResultRunes    = 500
+	compactionMinScale                  = 0.5
+	compactionMaxScale                  = 4.0
+)
+
+// resolveCompactionConfig fills unset fields of opts with defaults scaled to maxContextTokens.
+func resolveCompactionConfig(opts *CompactionConfig, maxContextTokens int) CompactionConfig {
+	scale := 1.0
+	if maxContextTokens > 0 {
+		scale = clampFloat(float64(maxContextTokens)/float64(nativeDefaultContextLimit), compactionMinScale, compactionMaxScale)
+	}
+	scaled := func(base int) int {
+		return max(1, int(float64(base)*scale+0.5))
+	}
+	out := CompactionConfig{
+		KeepRecentMessages: scaled(compactionDefaultKeepRecentMessages),
+		SummaryLineRunes:   scaled(compactionDefaultSummaryLineRunes),
+		MaxSummaryLines:    scaled(compactionDefaultMaxSummaryLines),
+		ToolResultRunes:    scaled(compactionDefaultToolResultRunes),
+	}
+	if opts == nil {
+		return out
+	}
+	if opts.KeepRecentMessages > 0 {
+		out.KeepRecentMessages = opts.KeepRecentMessages
+	}
+	if opts.SummaryLineRunes > 0 {
+		out.SummaryLineRunes = opts.SummaryLineRunes
+	}
+	if opts.MaxSummaryLines > 0 {
+		out.MaxSummaryLines = opts.MaxSummaryLines
+	}
+	if opts.ToolResultRunes > 0 {
+		out.ToolResultRunes = opts.ToolResultRunes
+	}
+	return out
+}
+
+func normalizeCompactionStrategy(raw string) string {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case CompactionStrategyModelSummary:
+		return CompactionStrategyModelSummary
+	default:
+		return CompactionStrategyTruncate
+	}
+}
+
+// compactionSummarizer condenses an archived transcript into a dense summary.
+type compactionSummarizer func(ctx context.Context, transcript string) (string, TurnUsage, error)
+
+// newProviderCompactionSummarizer summarizes through the run's own adapter with a short, tool-free turn.
+func newProviderCompactionSummarizer(adapter Provider, model string) compactionSummarizer {
+	return func(ctx context.Context, transcript string) (string, TurnUsage, error) {
+		if adapter == nil {
+			return "", TurnUsage{}, errors.New("missing compaction provider")
+		}
+		summaryCtx := ctx
+		cancel := func() {}
+		if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+			summaryCtx, cancel = context.WithTimeout(ctx, modelSummaryTimeout)
+		}
+		defer cancel()
+
+		result, err := adapter.StreamTurn(summaryCtx, TurnRequest{
+			Model:     strings.TrimSpace(model),
+			Messages:  buildCompactionSummaryMessages(transcript),
+			Budgets:   TurnBudgets{MaxSteps: 1, MaxOutputToken: modelSummaryMaxOutputTokens},
+			ModeFlags: ModeFlags{Mode: config.AIModePlan},
+		}, nil)
+		if err != nil {
+			return "", result.Usage, err
+		}
+		summary := strings.TrimSpace(result.Text)
+		if summary == "" {
+			return "", result.Usage, errors.New("empty compaction summary")
+		}
+		return summary, result.Usage, nil
+	}
+}
+
+func buildCompactionSummaryMessages(transcript string) []Message {
+	system := strings.Join([]string{
+		"You compress the earlier part of an agent's working context so the agent can continue the task.",
+		"Write a dense summary in the language of the transcript, using short bullet points.",
+		"Keep every concrete finding from tool results: file paths, symbols, commands, exit codes, error messages, numbers, and decisions.",
+		"Keep open questions, the user's requirements, and what remains to be done.",
+		"Drop greetings, repetition, and reasoning that led nowhere.",
+		"Do not invent facts and do not call tools. Output only the summary.",
+	}, "\n")
+	return []Message{
+		{Role: "system", Content: []ContentPart{{Type: "text", Text: system}}},
+		{Role: "user", Content: []ContentPart{{Type: "text", Text: "Transcript to summarize:\n\n" + transcript}}},
+	}
+}
+
+// compactMessagesWithModelSummary replaces archived messages with one model-written summary message.
+//
+// Unlike compactMessages, the most recent messages are kept verbatim, tool results included.
+func compactMessagesWithModelSummary(ctx context.Context, messages []Message, summarize compactionSummarizer) ([]Message, toolReferenceIntegrityStats, TurnUsage, error) {
+	stats := toolReferenceIntegrityStats{}
+	if len(messages) <= modelSummaryMinMessages {
+		out, gateStats := enforceToolReferenceIntegrity(cloneMessages(messages), nil)
+		return out, mergeToolReferenceStats(stats, gateStats), TurnUsage{}, nil
+	}
+	if summarize == nil {
+		return nil, stats, TurnUsage{}, errors.New("missing compaction summarizer")
+	}
+	cut := len(messages) - modelSummaryKeepRecentMessages
+	archived := cloneMessages(messages[:cut])
+	recent := cloneMessages(messages[cut:])
+
+	transcript := renderCompactionTranscript(archived)
+	if transcript == "" {
+		out, gateStats := enforceToolReferenceIntegrity(cloneMessages(messages), nil)
+		return out, mergeToolReferenceStats(stats, gateStats), TurnUsage{}, nil
+	}
+	summary, usage, err := summarize(ctx, transcript)
+	if err != nil {
+		return nil, stats, usage, err
+	}
+
+	var repairStats toolReferenceIntegrityStats
+	recent, repairStats = enforceToolReferenceIntegrity(recent, archived)
+	stats = mergeToolReferenceStats(stats, repairStats)
+	compacted := make([]Message, 0, len(recent)+1)
+	compacted = append(compacted, Message{
+		Role:    "system",
+		Content: []ContentPart{{Type: "text", Text: "Compressed context summary:\n" + summary}},
+	})
+	compacted = append(compacted, recent...)
+	compacted, gateStats := enforceToolReferenceIntegrity(compacted, nil)
+	stats = mergeToolReferenceStats(stats, gateStats)
+	return compacted, stats, usage, nil
+}
+
+// renderCompactionTranscript flattens messages for the summarizer, keeping the newest text when it is too long.
+func renderCompactionTranscript(messages []Message) string {
+	blocks := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		role := strings.ToLower(strings.TrimSpace(msg.Role))
+		for _, part := range msg.Content {
+			var line string
+			switch strings.ToLower(strings.TrimSpace(part.Type)) {
+			case "text":
+				line = role + ": " + strings.TrimSpace(part.Text)
+			case "tool_call":
+				line = fmt.Sprintf("tool call %s: %s", strings.TrimSpace(part.ToolName), strings.TrimSpace(part.ArgsJSON))
+			case "tool_result":
+				line = fmt.Sprintf("tool result %s: %s", strings.TrimSpace(part.ToolName), strings.TrimSpace(part.Text))
+			default:
+				continue
+			}
+			if strings.TrimSpace(line) == "" || strings.HasSuffix(line, ": ") {
+				continue
+			}
+			if trimmed, truncated := truncateByRunes(line, modelSummaryPartMaxRunes); truncated {
+				line = trimmed + " ... [truncated]"
+			}
+			blocks = append(blocks, line)
+		}
+	}
+	return joinCompactionTranscript(blocks)
+}
+
+// renderPromptPackCompactionTranscript flattens archived dialogue turns and execution evidence for the summarizer.
+func renderPromptPackCompactionTranscript(turns []contextmodel.DialogueTurn, evidence []contextmodel.ExecutionEvidence) string {
+	blocks := make([]string, 0, len(turns)*2+len(evidence))
+	for _, turn := range turns {
+		if txt := strings.TrimSpace(turn.UserText); txt != "" {
+			blocks = append(blocks, "user: "+txt)
+		}
+		if txt := strings.TrimSpace(turn.AssistantText); txt != "" {
+			blocks = append(blocks, "assistant: "+txt)
+		}
+	}
+	for _, ev := range evidence {
+		line := fmt.Sprintf("%s %s [%s]: %s", strings.TrimSpace(ev.Kind), strings.TrimSpace(ev.Name), strings.TrimSpace(ev.Status), strings.TrimSpace(ev.Summary))
+		if payload := strings.TrimSpace(ev.PayloadJSON); payload != "" {
+			line += "\n" + payload
+		}
+		blocks = append(blocks, line)
+	}
+	for i := range blocks {
+		if trimmed, truncated := truncateByRunes(blocks[i], modelSummaryPartMaxRunes); truncated {
+			blocks[i] = trimmed + " ... [truncated]"
+		}
+	}
+	return joinCompactionTranscript(blocks)
+}
+
+func joinCompactionTranscript(blocks []string) string {
+	total := 0
+	start := len(blocks)
+	for start > 0 {
+		size := len([]rune(blocks[start-1])) + 2
+		if total+size > modelSummaryTranscriptMaxRunes && start < len(blocks) {
+			break
+		}
+		total += size
+		start--
+	}
+	return strings.TrimSpace(strings.Join(blocks[start:], "\n\n"))
+}
+
+// promptPackSummarizer adapts a compactionSummarizer to the prompt-pack compactor.
+//
+// onUsage receives the token usage of the summary turn so it can be billed to the run.
+func promptPackSummarizer(summarize compactionSummarizer, onUsage func(TurnUsage)) contextcompactor.Summarizer {
+	return func(ctx context.Context, archived []contextmodel.DialogueTurn, evidence []contextmodel.ExecutionEvidence) (string, error) {
+		transcript := renderPromptPackCompactionTranscript(archived, evidence)
+		if transcript == "" {
+			return "", nil
+		}
+		summary, usage, err := summarize(ctx, transcript)
+		if onUsage != nil {
+			onUsage(usage)
+		}
+		return summary, err
+	}
+}
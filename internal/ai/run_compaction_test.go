@@ -0,0 +1,210 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	contextcompactor "github.com/floegence/redeven/internal/ai/context/compactor"
+	contextmodel "github.com/floegence/redeven/internal/ai/context/model"
+)
+
+func compactionTestMessages(toolRounds int) []Message {
+	messages := []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "Find why the config loader panics."}}}}
+	for i := 0; i < toolRounds; i++ {
+		callID := fmt.Sprintf("call_%d", i)
+		messages = append(messages,
+			Message{Role: "assistant", Content: []ContentPart{{Type: "tool_call", ToolCallID: callID, ToolName: "file.read", ArgsJSON: fmt.Sprintf(`{"path":"internal/config/file_%d.go"}`, i)}}},
+			Message{Role: "tool", Content: []ContentPart{{Type: "tool_result", ToolCallID: callID, ToolName: "file.read", Text: fmt.Sprintf("evidence_%d ", i) + strings.Repeat("x", 900)}}},
+		)
+	}
+	return append(messages, Message{Role: "assistant", Content: []ContentPart{{Type: "text", Text: "Still investigating."}}})
+}
+
+func TestCompactMessagesWithModelSummary_KeepsRecentVerbatim(t *testing.T) {
+	t.Parallel()
+
+	messages := compactionTestMessages(8)
+	var transcript string
+	summarize := func(_ context.Context, in string) (string, TurnUsage, error) {
+		transcript = in
+		return "- file_0.go and file_1.go read; no panic source yet", TurnUsage{InputTokens: 100, OutputTokens: 20}, nil
+	}
+
+	out, _, usage, err := compactMessagesWithModelSummary(context.Background(), messages, summarize)
+	if err != nil {
+		t.Fatalf("compactMessagesWithModelSummary: %v", err)
+	}
+	if usage.InputTokens != 100 {
+		t.Fatalf("usage=%+v, want summarizer usage", usage)
+	}
+	if !strings.Contains(transcript, "tool result file.read: evidence_0") || !strings.Contains(transcript, "user: Find why the config loader panics.") {
+		t.Fatalf("transcript should carry archived tool evidence, got %q", transcript)
+	}
+	if strings.Contains(transcript, "evidence_7") {
+		t.Fatalf("recent messages should not be summarized: %q", transcript)
+	}
+	if out[0].Role != "system" || !strings.Contains(joinMessageText(out[0]), "no panic source yet") {
+		t.Fatalf("first message should be the model summary, got %+v", out[0])
+	}
+	systemCount := 0
+	for _, msg := range out {
+		if msg.Role == "system" {
+			systemCount++
+		}
+	}
+	if systemCount != 1 {
+		t.Fatalf("want one summary message, got %d", systemCount)
+	}
+	last := messages[len(messages)-2].Content[0].Text
+	found := false
+	for _, msg := range out {
+		for _, part := range msg.Content {
+			if part.Type == "tool_result" && part.Text == last {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("recent tool results should stay verbatim")
+	}
+	if len(out) >= len(messages) {
+		t.Fatalf("compaction should shrink history: before=%d after=%d", len(messages), len(out))
+	}
+}
+
+func TestCompactMessagesWithModelSummary_PropagatesSummarizerError(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("provider down")
+	_, _, _, err := compactMessagesWithModelSummary(context.Background(), compactionTestMessages(8), func(context.Context, string) (string, TurnUsage, error) {
+		return "", TurnUsage{}, boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err=%v, want summarizer error", err)
+	}
+
+	short := compactionTestMessages(2)
+	out, _, _, err := compactMessagesWithModelSummary(context.Background(), short, nil)
+	if err != nil || len(out) != len(short) {
+		t.Fatalf("short history should pass through: len=%d err=%v", len(out), err)
+	}
+}
+
+func TestCompactPromptPackWithSummarizer_FoldsArchivedTurns(t *testing.T) {
+	t.Parallel()
+
+	pack := contextmodel.PromptPack{ThreadID: "th_1", Objective: "fix loader"}
+	for i := 0; i < 8; i++ {
+		pack.RecentDialogue = append(pack.RecentDialogue, contextmodel.DialogueTurn{
+			UserText:      fmt.Sprintf("question %d %s", i, strings.Repeat("q", 400)),
+			AssistantText: fmt.Sprintf("answer %d %s", i, strings.Repeat("a", 400)),
+		})
+	}
+	pack.ExecutionEvidence = []contextmodel.ExecutionEvidence{{Kind: "tool", Name: "terminal.exec", Status: "failed", Summary: "go test ./internal/config: exit 1"}}
+
+	var transcript string
+	var billed TurnUsage
+	summarize := promptPackSummarizer(func(_ context.Context, in string) (string, TurnUsage, error) {
+		transcript = in
+		return "- loader panics on empty provider list", TurnUsage{OutputTokens: 12}, nil
+	}, func(u TurnUsage) { billed = u })
+
+	out, changed, _, err := contextcompactor.New(nil).CompactPromptPackWithSummarizer(context.Background(), "env_1", 500, pack, summarize)
+	if err != nil || !changed {
+		t.Fatalf("changed=%v err=%v", changed, err)
+	}
+	if !strings.Contains(out.ThreadSnapshot, "loader panics on empty provider list") || len(out.RecentDialogue) != 4 {
+		t.Fatalf("snapshot=%q recent=%d", out.ThreadSnapshot, len(out.RecentDialogue))
+	}
+	if !strings.Contains(transcript, "terminal.exec [failed]: go test ./internal/config: exit 1") || !strings.Contains(transcript, "question 0") {
+		t.Fatalf("transcript should include archived turns and evidence: %q", transcript)
+	}
+	if billed.OutputTokens != 12 {
+		t.Fatalf("summary usage should be reported, got %+v", billed)
+	}
+
+	_, changed, _, err = contextcompactor.New(nil).CompactPromptPackWithSummarizer(context.Background(), "env_1", 500, pack, func(context.Context, []contextmodel.DialogueTurn, []contextmodel.ExecutionEvidence) (string, error) {
+		return "", errors.New("summary failed")
+	})
+	if err == nil || changed {
+		t.Fatalf("summarizer failure should abort compaction: changed=%v err=%v", changed, err)
+	}
+}
+
+func TestNormalizeCompactionStrategy(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"":               CompactionStrategyTruncate,
+		"truncate":       CompactionStrategyTruncate,
+		" Model_Summary": CompactionStrategyModelSummary,
+		"semantic":       CompactionStrategyTruncate,
+	}
+	for in, want := range cases {
+		if got := normalizeCompactionStrategy(in); got != want {
+			t.Fatalf("normalizeCompactionStrategy(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCompactMessages_KeepRecentAndToolResultThreshold(t *testing.T) {
+	t.Parallel()
+
+	messages := compactionTestMessages(12)
+	countKept := func(compacted []Message) (kept int, compressed int) {
+		for _, msg := range compacted {
+			if msg.Role == "system" {
+				continue
+			}
+			for _, part := range msg.Content {
+				if part.Type != "tool_result" {
+					continue
+				}
+				kept++
+				if strings.HasSuffix(part.Text, " ... [compressed]") {
+					compressed++
+				}
+			}
+		}
+		return kept, compressed
+	}
+
+	small, _ := compactMessages(messages, resolveCompactionConfig(&CompactionConfig{KeepRecentMessages: 6}, nativeDefaultContextLimit))
+	large, _ := compactMessages(messages, resolveCompactionConfig(&CompactionConfig{KeepRecentMessages: 16}, nativeDefaultContextLimit))
+	smallKept, smallCompressed := countKept(small)
+	largeKept, largeCompressed := countKept(large)
+	if largeKept <= smallKept {
+		t.Fatalf("kept tool results small=%d large=%d, want the larger keep-recent to keep more", smallKept, largeKept)
+	}
+	if smallCompressed != smallKept || largeCompressed != largeKept {
+		t.Fatalf("compressed tool results small=%d/%d large=%d/%d, want every 900+ rune result compressed", smallCompressed, smallKept, largeCompressed, largeKept)
+	}
+
+	// Results under the threshold are kept as they are.
+	roomy, _ := compactMessages(messages, resolveCompactionConfig(&CompactionConfig{KeepRecentMessages: 6, ToolResultRunes: 2000}, nativeDefaultContextLimit))
+	if kept, compressed := countKept(roomy); kept == 0 || compressed != 0 {
+		t.Fatalf("tool results kept=%d compressed=%d, want none compressed under a 2000 rune threshold", kept, compressed)
+	}
+}
+
+func TestResolveCompactionConfig_ScalesWithContextWindow(t *testing.T) {
+	t.Parallel()
+
+	base := resolveCompactionConfig(nil, nativeDefaultContextLimit)
+	want := CompactionConfig{KeepRecentMessages: 10, SummaryLineRunes: 100, MaxSummaryLines: 12, ToolResultRunes: 500}
+	if base != want {
+		t.Fatalf("default config=%+v, want %+v", base, want)
+	}
+	if large := resolveCompactionConfig(nil, 1_000_000); large.KeepRecentMessages != 40 || large.ToolResultRunes != 2000 {
+		t.Fatalf("1M window config=%+v, want 4x defaults", large)
+	}
+	if small := resolveCompactionConfig(nil, 8000); small.KeepRecentMessages != 5 || small.SummaryLineRunes != 50 {
+		t.Fatalf("8k window config=%+v, want half defaults", small)
+	}
+	if got := resolveCompactionConfig(&CompactionConfig{MaxSummaryLines: 3}, 1_000_000); got.MaxSummaryLines != 3 || got.KeepRecentMessages != 40 {
+		t.Fatalf("override config=%+v, want explicit max_summary_lines with scaled defaults", got)
+	}
+}
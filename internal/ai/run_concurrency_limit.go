@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+// ErrTooManyRuns is returned when starting a run would exceed ai.run_concurrency.
+var ErrTooManyRuns = errors.New("too_many_runs")
+
+// RunLimitRetryAfter is the retry hint returned to clients whose run was rejected by ai.run_concurrency.
+const RunLimitRetryAfter = 5 * time.Second
+
+// RunConcurrencyStatus reports how many runs are executing against the configured ai.run_concurrency caps.
+type RunConcurrencyStatus struct {
+	ActiveRuns          int    `json:"active_runs"`
+	NamespaceActiveRuns int    `json:"namespace_active_runs"`
+	MaxRuns             int    `json:"max_runs"`
+	MaxRunsPerNamespace int    `json:"max_runs_per_namespace"`
+	OnLimit             string `json:"on_limit"`
+}
+
+// countActiveRunsLocked returns the number of runs holding a thread slot, overall and within namespace.
+//
+// Callers must hold s.mu.
+func (s *Service) countActiveRunsLocked(namespace string) (int, int) {
+	namespace = strings.TrimSpace(namespace)
+	total, inNamespace := 0, 0
+	for _, runID := range s.activeRunByTh {
+		runID = strings.TrimSpace(runID)
+		if runID == "" {
+			continue
+		}
+		total++
+		if r := s.runs[runID]; r != nil && r.sessionMeta != nil && strings.TrimSpace(r.sessionMeta.NamespacePublicID) == namespace {
+			inNamespace++
+		}
+	}
+	return total, inNamespace
+}
+
+// runSlotAvailableLocked reports whether one more run may start in namespace under ai.run_concurrency.
+//
+// Callers must hold s.mu.
+func (s *Service) runSlotAvailableLocked(namespace string) bool {
+	maxRuns, maxPerNamespace := s.cfg.EffectiveRunConcurrencyLimits()
+	if maxRuns <= 0 && maxPerNamespace <= 0 {
+		return true
+	}
+	total, inNamespace := s.countActiveRunsLocked(namespace)
+	if maxRuns > 0 && total >= maxRuns {
+		return false
+	}
+	if maxPerNamespace > 0 && inNamespace >= maxPerNamespace {
+		return false
+	}
+	return true
+}
+
+// notifyRunSlotReleasedLocked wakes run starts queued on ai.run_concurrency.
+//
+// Callers must hold s.mu.
+func (s *Service) notifyRunSlotReleasedLocked() {
+	if s.runSlotReleasedCh != nil {
+		close(s.runSlotReleasedCh)
+		s.runSlotReleasedCh = nil
+	}
+}
+
+// runSlotReleasedLocked returns a channel that is closed once any active run is released.
+//
+// Callers must hold s.mu.
+func (s *Service) runSlotReleasedLocked() <-chan struct{} {
+	if s.runSlotReleasedCh == nil {
+		s.runSlotReleasedCh = make(chan struct{})
+	}
+	return s.runSlotReleasedCh
+}
+
+// waitRunSlotReleased blocks until an active run is released, the queue deadline passes, or ctx is done.
+func waitRunSlotReleased(ctx context.Context, released <-chan struct{}, deadline time.Time) error {
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		return ErrTooManyRuns
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-released:
+		return nil
+	case <-timer.C:
+		return ErrTooManyRuns
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CheckRunCapacity returns ErrTooManyRuns when ai.run_concurrency rejects new runs and no slot is free.
+//
+// It lets callers fail fast before committing to a streaming response; StartRun re-checks atomically.
+func (s *Service) CheckRunCapacity(meta *session.Meta) error {
+	if s == nil || meta == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg == nil || s.cfg.EffectiveRunLimitPolicy() == config.AIRunLimitQueue {
+		return nil
+	}
+	if !s.runSlotAvailableLocked(meta.NamespacePublicID) {
+		return ErrTooManyRuns
+	}
+	return nil
+}
+
+// RunConcurrencyStatus reports the active run counts for the caller's namespace and the agent as a whole.
+func (s *Service) RunConcurrencyStatus(meta *session.Meta) (RunConcurrencyStatus, error) {
+	if s == nil {
+		return RunConcurrencyStatus{}, ErrNotConfigured
+	}
+	namespace := ""
+	if meta != nil {
+		namespace = meta.NamespacePublicID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg == nil {
+		return RunConcurrencyStatus{}, ErrNotConfigured
+	}
+	total, inNamespace := s.countActiveRunsLocked(namespace)
+	maxRuns, maxPerNamespace := s.cfg.EffectiveRunConcurrencyLimits()
+	return RunConcurrencyStatus{
+		ActiveRuns:          total,
+		NamespaceActiveRuns: inNamespace,
+		MaxRuns:             maxRuns,
+		MaxRunsPerNamespace: maxPerNamespace,
+		OnLimit:             s.cfg.EffectiveRunLimitPolicy(),
+	}, nil
+}
@@ -0,0 +1,207 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func newRunLimitTestRequest(t *testing.T, svc *Service, meta *session.Meta, title string) RunStartRequest {
+	t.Helper()
+	thread, err := svc.CreateThread(context.Background(), meta, title, "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	return RunStartRequest{ThreadID: thread.ThreadID, Model: "openai/gpt-5-mini", Input: RunInput{Text: "hello"}, Options: RunOptions{MaxSteps: 1}}
+}
+
+func TestPrepareRun_RejectsRunsOverGlobalCap(t *testing.T) {
+	t.Parallel()
+
+	const maxRuns = 2
+	svc := newRealtimeTestService(t, 2*time.Second)
+	svc.mu.Lock()
+	svc.cfg.RunConcurrency = &config.AIRunConcurrencyPolicy{MaxRuns: maxRuns}
+	svc.mu.Unlock()
+	meta := newThreadRunConcurrencyTestMeta("env_run_limit_reject")
+
+	prepared := make([]*preparedRun, 0, maxRuns)
+	for i := 0; i < maxRuns; i++ {
+		req := newRunLimitTestRequest(t, svc, meta, fmt.Sprintf("run limit %d", i))
+		p, err := svc.prepareRun(context.Background(), meta, fmt.Sprintf("run_limit_reject_%d", i), req, nil, nil)
+		if err != nil {
+			t.Fatalf("prepareRun %d: %v", i, err)
+		}
+		prepared = append(prepared, p)
+	}
+	t.Cleanup(func() {
+		for _, p := range prepared[1:] {
+			releasePreparedRunForTest(svc, p)
+		}
+	})
+
+	overflow := newRunLimitTestRequest(t, svc, meta, "run limit overflow")
+	if _, err := svc.prepareRun(context.Background(), meta, "run_limit_reject_overflow", overflow, nil, nil); !errors.Is(err, ErrTooManyRuns) {
+		t.Fatalf("prepareRun over cap err=%v, want ErrTooManyRuns", err)
+	}
+	if err := svc.CheckRunCapacity(meta); !errors.Is(err, ErrTooManyRuns) {
+		t.Fatalf("CheckRunCapacity err=%v, want ErrTooManyRuns", err)
+	}
+	status, err := svc.RunConcurrencyStatus(meta)
+	if err != nil {
+		t.Fatalf("RunConcurrencyStatus: %v", err)
+	}
+	if status.ActiveRuns != maxRuns || status.NamespaceActiveRuns != maxRuns || status.MaxRuns != maxRuns || status.OnLimit != config.AIRunLimitReject {
+		t.Fatalf("RunConcurrencyStatus=%+v", status)
+	}
+
+	releasePreparedRunForTest(svc, prepared[0])
+	next, err := svc.prepareRun(context.Background(), meta, "run_limit_reject_after_release", overflow, nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun after release: %v", err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, next) })
+}
+
+func TestPrepareRun_PerNamespaceCapLeavesOtherNamespacesFree(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	svc.mu.Lock()
+	svc.cfg.RunConcurrency = &config.AIRunConcurrencyPolicy{MaxRunsPerNamespace: 1}
+	svc.mu.Unlock()
+	metaA := newThreadRunConcurrencyTestMeta("env_run_limit_ns_a")
+	metaB := newThreadRunConcurrencyTestMeta("env_run_limit_ns_b")
+
+	first, err := svc.prepareRun(context.Background(), metaA, "run_limit_ns_a_1", newRunLimitTestRequest(t, svc, metaA, "ns a 1"), nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun namespace a: %v", err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, first) })
+
+	if _, err := svc.prepareRun(context.Background(), metaA, "run_limit_ns_a_2", newRunLimitTestRequest(t, svc, metaA, "ns a 2"), nil, nil); !errors.Is(err, ErrTooManyRuns) {
+		t.Fatalf("prepareRun namespace a over cap err=%v, want ErrTooManyRuns", err)
+	}
+	other, err := svc.prepareRun(context.Background(), metaB, "run_limit_ns_b_1", newRunLimitTestRequest(t, svc, metaB, "ns b 1"), nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun namespace b: %v", err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, other) })
+}
+
+func TestPrepareRun_RunLimitQueuePolicyWaitsForFreeSlot(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	svc.mu.Lock()
+	svc.cfg.RunConcurrency = &config.AIRunConcurrencyPolicy{MaxRuns: 1, OnLimit: config.AIRunLimitQueue}
+	svc.mu.Unlock()
+	meta := newThreadRunConcurrencyTestMeta("env_run_limit_queue")
+
+	first, err := svc.prepareRun(context.Background(), meta, "run_limit_queue_1", newRunLimitTestRequest(t, svc, meta, "queue 1"), nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun first: %v", err)
+	}
+	if err := svc.CheckRunCapacity(meta); err != nil {
+		t.Fatalf("CheckRunCapacity under queue policy err=%v, want nil", err)
+	}
+
+	type result struct {
+		prepared *preparedRun
+		err      error
+	}
+	secondReq := newRunLimitTestRequest(t, svc, meta, "queue 2")
+	secondCh := make(chan result, 1)
+	go func() {
+		prepared, err := svc.prepareRun(context.Background(), meta, "run_limit_queue_2", secondReq, nil, nil)
+		secondCh <- result{prepared: prepared, err: err}
+	}()
+
+	select {
+	case res := <-secondCh:
+		t.Fatalf("second run started while the cap was full: %+v", res)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	releasePreparedRunForTest(svc, first)
+
+	var second result
+	select {
+	case second = <-secondCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("queued run did not start after a slot was released")
+	}
+	if second.err != nil {
+		t.Fatalf("prepareRun second: %v", second.err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, second.prepared) })
+}
+
+func TestPrepareRun_RunLimitQueuePolicyTimesOut(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	timeoutMS := 50
+	svc.mu.Lock()
+	svc.cfg.RunConcurrency = &config.AIRunConcurrencyPolicy{MaxRuns: 1, OnLimit: config.AIRunLimitQueue, QueueTimeoutMS: &timeoutMS}
+	svc.mu.Unlock()
+	meta := newThreadRunConcurrencyTestMeta("env_run_limit_queue_timeout")
+
+	first, err := svc.prepareRun(context.Background(), meta, "run_limit_timeout_1", newRunLimitTestRequest(t, svc, meta, "timeout 1"), nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun first: %v", err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, first) })
+
+	if _, err := svc.prepareRun(context.Background(), meta, "run_limit_timeout_2", newRunLimitTestRequest(t, svc, meta, "timeout 2"), nil, nil); !errors.Is(err, ErrTooManyRuns) {
+		t.Fatalf("prepareRun second err=%v, want ErrTooManyRuns", err)
+	}
+}
+
+func TestPrepareRun_RunLimitQueueStopsWhenCallerCancels(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	svc.mu.Lock()
+	svc.cfg.RunConcurrency = &config.AIRunConcurrencyPolicy{MaxRuns: 1, OnLimit: config.AIRunLimitQueue}
+	svc.mu.Unlock()
+	meta := newThreadRunConcurrencyTestMeta("env_run_limit_queue_cancel")
+
+	first, err := svc.prepareRun(context.Background(), meta, "run_limit_cancel_1", newRunLimitTestRequest(t, svc, meta, "cancel 1"), nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun first: %v", err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, first) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	secondReq := newRunLimitTestRequest(t, svc, meta, "cancel 2")
+	errCh := make(chan error, 1)
+	go func() {
+		prepared, err := svc.prepareRun(ctx, meta, "run_limit_cancel_2", secondReq, nil, nil)
+		if prepared != nil {
+			releasePreparedRunForTest(svc, prepared)
+		}
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("queued run returned before cancel: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("prepareRun second err=%v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("queued run kept waiting for a slot after its caller canceled")
+	}
+}
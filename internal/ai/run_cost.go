@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"strings"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const finalizationReasonCostBudgetExceeded = "cost_budget_exceeded"
+
+// modelPricing returns the configured token pricing for providerID's modelName.
+func modelPricing(cfg *config.AIConfig, providerID string, modelName string) (config.AIModelPricing, bool) {
+	if cfg == nil {
+		return config.AIModelPricing{}, false
+	}
+	providerID = strings.TrimSpace(providerID)
+	for _, provider := range cfg.Providers {
+		if strings.TrimSpace(provider.ID) == providerID {
+			return provider.PricingForModel(modelName)
+		}
+	}
+	return config.AIModelPricing{}, false
+}
+
+// turnCostUSD prices one turn's token usage.
+//
+// Providers report reasoning tokens as a subset of output tokens, so only the remainder is billed at the output rate.
+func turnCostUSD(pricing config.AIModelPricing, usage TurnUsage) float64 {
+	const perMTok = 1_000_000.0
+	visibleOutput := usage.OutputTokens - usage.ReasoningTokens
+	if visibleOutput < 0 {
+		visibleOutput = 0
+	}
+	return float64(usage.InputTokens)*pricing.InputUSDPerMTok/perMTok +
+		float64(visibleOutput)*pricing.OutputUSDPerMTok/perMTok +
+		float64(usage.ReasoningTokens)*pricing.EffectiveReasoningUSDPerMTok()/perMTok
+}
+
+// accountTurnCost adds one turn's cost to the run total and reports whether maxCostUSD is now exceeded.
+//
+// Unknown pricing never trips the budget; a cost_pricing_missing event is recorded once per run instead.
+func (r *run) accountTurnCost(step int, providerID string, modelName string, usage TurnUsage, maxCostUSD float64) bool {
+	if r == nil {
+		return false
+	}
+	pricing, ok := modelPricing(r.cfg, providerID, modelName)
+	if !ok {
+		if !r.costPricingMissingLogged {
+			r.costPricingMissingLogged = true
+			r.debug("ai.run.cost_pricing_missing", "provider_id", strings.TrimSpace(providerID), "model", strings.TrimSpace(modelName))
+			r.persistRunEvent("cost_pricing_missing", RealtimeStreamKindLifecycle, map[string]any{
+				"step_index":   step,
+				"provider_id":  strings.TrimSpace(providerID),
+				"model":        strings.TrimSpace(modelName),
+				"max_cost_usd": maxCostUSD,
+			})
+		}
+		return false
+	}
+	cost := turnCostUSD(pricing, usage)
+	r.accumulatedCostUSD += cost
+	exceeded := maxCostUSD > 0 && r.accumulatedCostUSD > maxCostUSD
+	r.persistRunEvent("native.turn.cost", RealtimeStreamKindLifecycle, map[string]any{
+		"step_index":           step,
+		"provider_id":          strings.TrimSpace(providerID),
+		"model":                strings.TrimSpace(modelName),
+		"turn_cost_usd":        cost,
+		"accumulated_cost_usd": r.accumulatedCostUSD,
+		"max_cost_usd":         maxCostUSD,
+		"budget_exceeded":      exceeded,
+	})
+	return exceeded
+}
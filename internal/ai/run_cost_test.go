@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestTurnCostUSD(t *testing.T) {
+	t.Parallel()
+
+	pricing := config.AIModelPricing{ModelName: "m", InputUSDPerMTok: 2, OutputUSDPerMTok: 10}
+	got := turnCostUSD(pricing, TurnUsage{InputTokens: 500_000, OutputTokens: 100_000, ReasoningTokens: 50_000})
+	// 0.5M*2 + 0.05M*10 visible output + 0.05M*10 (reasoning falls back to output price)
+	if want := 2.0; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("turnCostUSD=%v, want %v", got, want)
+	}
+}
+
+func TestTurnCostUSD_BillsReasoningOnceAtReasoningRate(t *testing.T) {
+	t.Parallel()
+
+	reasoningPrice := 20.0
+	pricing := config.AIModelPricing{ModelName: "m", InputUSDPerMTok: 1, OutputUSDPerMTok: 10, ReasoningUSDPerMTok: &reasoningPrice}
+	// OutputTokens already include the 300k reasoning tokens.
+	got := turnCostUSD(pricing, TurnUsage{InputTokens: 1_000_000, OutputTokens: 400_000, ReasoningTokens: 300_000})
+	// 1M*1 + 0.1M*10 visible output + 0.3M*20 reasoning
+	if want := 8.0; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("turnCostUSD=%v, want %v", got, want)
+	}
+	if got := turnCostUSD(pricing, TurnUsage{OutputTokens: 100, ReasoningTokens: 1_000_000}); math.Abs(got-20) > 1e-9 {
+		t.Fatalf("turnCostUSD with reasoning > output=%v, want 20", got)
+	}
+}
+
+func TestRunAccountTurnCost(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	r := newRun(runOptions{
+		AIConfig: &config.AIConfig{Providers: []config.AIProvider{{
+			ID:           "openai",
+			Type:         "openai",
+			Models:       []config.AIProviderModel{{ModelName: "priced"}, {ModelName: "unpriced"}},
+			ModelPricing: []config.AIModelPricing{{ModelName: "priced", InputUSDPerMTok: 1, OutputUSDPerMTok: 4}},
+		}}},
+		RunID:            "run_cost",
+		EndpointID:       "env_cost",
+		ThreadID:         "th_cost",
+		MessageID:        "msg_cost",
+		ThreadsDB:        db,
+		PersistOpTimeout: 2 * time.Second,
+	})
+
+	for step := 0; step < 2; step++ {
+		if r.accountTurnCost(step, "openai", "unpriced", TurnUsage{InputTokens: 10_000_000}, 0.01) {
+			t.Fatalf("unknown pricing must never trip the budget")
+		}
+	}
+	usage := TurnUsage{InputTokens: 100_000, OutputTokens: 10_000} // 0.1 + 0.04 USD
+	if r.accountTurnCost(2, "openai", "priced", usage, 0.2) {
+		t.Fatalf("first priced turn should stay under budget")
+	}
+	if !r.accountTurnCost(3, "openai", "priced", usage, 0.2) {
+		t.Fatalf("second priced turn should exceed the budget")
+	}
+	if math.Abs(r.accumulatedCostUSD-0.28) > 1e-9 {
+		t.Fatalf("accumulatedCostUSD=%v, want 0.28", r.accumulatedCostUSD)
+	}
+
+	events, err := db.ListRunEvents(ctx, "env_cost", "run_cost", 100)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	counts := map[string]int{}
+	for _, ev := range events {
+		counts[ev.EventType]++
+	}
+	if counts["cost_pricing_missing"] != 1 || counts["native.turn.cost"] != 2 {
+		t.Fatalf("unexpected event counts: %+v", counts)
+	}
+}
@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/floegence/redeven/internal/session"
+)
+
+// runEventFeed signals in-process watchers when a run event has been persisted.
+//
+// It carries no event data: watchers read the new rows from the thread store, so events are stored once.
+type runEventFeed struct {
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{} // <endpoint_id>:<run_id> -> set(signal)
+}
+
+func runEventFeedKey(endpointID string, runID string) string {
+	endpointID = strings.TrimSpace(endpointID)
+	runID = strings.TrimSpace(runID)
+	if endpointID == "" || runID == "" {
+		return ""
+	}
+	return endpointID + ":" + runID
+}
+
+func (f *runEventFeed) subscribe(key string) (chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	f.mu.Lock()
+	if f.subs == nil {
+		f.subs = make(map[string]map[chan struct{}]struct{})
+	}
+	set := f.subs[key]
+	if set == nil {
+		set = make(map[chan struct{}]struct{})
+		f.subs[key] = set
+	}
+	set[ch] = struct{}{}
+	f.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if set := f.subs[key]; set != nil {
+				delete(set, ch)
+				if len(set) == 0 {
+					delete(f.subs, key)
+				}
+			}
+		})
+	}
+}
+
+// notify wakes every watcher of key. Signals coalesce: a watcher that has not drained its channel gets one wakeup.
+func (f *runEventFeed) notify(key string) {
+	if key == "" {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// WatchRunEvents returns a channel that is signaled whenever an event for runID is persisted, and a func to stop watching.
+//
+// Callers read the new events with ListRunEventsWithQuery from their last cursor.
+func (s *Service) WatchRunEvents(meta *session.Meta, runID string) (<-chan struct{}, func(), error) {
+	if s == nil {
+		return nil, nil, errors.New("nil service")
+	}
+	if meta == nil {
+		return nil, nil, errors.New("missing session metadata")
+	}
+	key := runEventFeedKey(meta.EndpointID, runID)
+	if key == "" {
+		return nil, nil, errors.New("missing run_id")
+	}
+	ch, stop := s.runEvents.subscribe(key)
+	return ch, stop, nil
+}
+
+// IsRunActive reports whether runID is still executing in this process.
+func (s *Service) IsRunActive(runID string) bool {
+	if s == nil {
+		return false
+	}
+	runID = strings.TrimSpace(runID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[runID] != nil
+}
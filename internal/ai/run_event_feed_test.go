@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestRunEventFeed_NotifyCoalescesAndStops(t *testing.T) {
+	t.Parallel()
+
+	var feed runEventFeed
+	key := runEventFeedKey("env_1", "run_1")
+	ch, stop := feed.subscribe(key)
+	other, stopOther := feed.subscribe(runEventFeedKey("env_1", "run_2"))
+	defer stopOther()
+
+	feed.notify(key)
+	feed.notify(key)
+	select {
+	case <-ch:
+	default:
+		t.Fatalf("expected a signal after notify")
+	}
+	select {
+	case <-ch:
+		t.Fatalf("signals should coalesce into one wakeup")
+	default:
+	}
+	select {
+	case <-other:
+		t.Fatalf("notify should not wake watchers of another run")
+	default:
+	}
+
+	stop()
+	stop()
+	feed.notify(key)
+	select {
+	case <-ch:
+		t.Fatalf("stopped watcher should not be signaled")
+	default:
+	}
+	if _, ok := feed.subs[key]; ok {
+		t.Fatalf("stop should drop the empty subscriber set")
+	}
+}
+
+func TestService_WatchRunEventsValidatesInput(t *testing.T) {
+	t.Parallel()
+
+	svc := &Service{}
+	if _, _, err := svc.WatchRunEvents(nil, "run_1"); err == nil {
+		t.Fatalf("expected error for missing meta")
+	}
+	if _, _, err := svc.WatchRunEvents(&session.Meta{EndpointID: "env_1"}, "  "); err == nil {
+		t.Fatalf("expected error for missing run_id")
+	}
+	ch, stop, err := svc.WatchRunEvents(&session.Meta{EndpointID: "env_1"}, "run_1")
+	if err != nil {
+		t.Fatalf("WatchRunEvents: %v", err)
+	}
+	defer stop()
+	svc.runEvents.notify(runEventFeedKey("env_1", "run_1"))
+	select {
+	case <-ch:
+	default:
+		t.Fatalf("expected a signal for the watched run")
+	}
+	if svc.IsRunActive("run_1") {
+		t.Fatalf("unknown run should not be active")
+	}
+}
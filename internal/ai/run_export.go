@@ -0,0 +1,249 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/session"
+)
+
+// RunExportFormatVersion is bumped when the export bundle layout changes incompatibly.
+const RunExportFormatVersion = 1
+
+const runExportRedactedPath = "[redacted-path]"
+
+// ErrRunNotFound is returned by ExportRun when the run does not exist on the endpoint.
+var ErrRunNotFound = errors.New("run not found")
+
+// runExportAbsPathRe matches absolute POSIX paths that are not part of a URL or a relative path.
+var runExportAbsPathRe = regexp.MustCompile(`(^|[^A-Za-z0-9._/~-])(/[A-Za-z0-9._@+~-]+(?:/[A-Za-z0-9._@+~-]+)*/?)`)
+
+// RunExportOptions tunes ExportRun.
+type RunExportOptions struct {
+	// RedactExternalPaths replaces absolute paths outside the agent home directory with a placeholder.
+	RedactExternalPaths bool
+}
+
+// RunExportManifest identifies the run and the agent build that produced a run export.
+type RunExportManifest struct {
+	FormatVersion      int    `json:"format_version"`
+	ExportedAtUnixMs   int64  `json:"exported_at_unix_ms"`
+	AgentVersion       string `json:"agent_version,omitempty"`
+	RunID              string `json:"run_id"`
+	ThreadID           string `json:"thread_id"`
+	MessageID          string `json:"message_id,omitempty"`
+	ProviderID         string `json:"provider_id,omitempty"`
+	Model              string `json:"model,omitempty"`
+	RunState           string `json:"run_state"`
+	FinalizationReason string `json:"finalization_reason,omitempty"`
+	StartedAtUnixMs    int64  `json:"started_at_unix_ms"`
+	EndedAtUnixMs      int64  `json:"ended_at_unix_ms,omitempty"`
+	PathsRedacted      bool   `json:"paths_redacted,omitempty"`
+}
+
+// RunExportBundle is a standalone transcript of one run.
+//
+// Served inside the gateway's {"ok":true,"data":...} envelope it has the shape of a message.log,
+// so a saved export can be fed to ai-loop-replay as-is.
+type RunExportBundle struct {
+	Manifest RunExportManifest `json:"manifest"`
+	// Messages are the thread's transcript messages up to and including the run's assistant message.
+	Messages     []any          `json:"messages"`
+	SystemPrompt string         `json:"system_prompt,omitempty"`
+	Events       []RunEventView `json:"events"`
+	// ProviderDiagnostics are the run's provider.turn.diag payloads with sensitive keys redacted.
+	ProviderDiagnostics []any `json:"provider_diagnostics,omitempty"`
+}
+
+// ExportRun assembles the transcript bundle of runID for support tickets and offline replay.
+func (s *Service) ExportRun(ctx context.Context, meta *session.Meta, runID string, opts RunExportOptions) (*RunExportBundle, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	agentHomeDir := s.agentHomeDir
+	agentVersion := s.agentVersion
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	runID = strings.TrimSpace(runID)
+	if runID == "" {
+		return nil, errors.New("missing run_id")
+	}
+	endpointID := strings.TrimSpace(meta.EndpointID)
+
+	rec, err := db.GetRun(ctx, endpointID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, ErrRunNotFound
+	}
+	th, err := db.GetThread(ctx, endpointID, rec.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if th == nil {
+		return nil, errors.New("thread not found")
+	}
+
+	decode := func(raw string) any {
+		var v any
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return nil
+		}
+		if opts.RedactExternalPaths {
+			v = redactRunExportValue(v, agentHomeDir)
+		}
+		return v
+	}
+
+	bundle := &RunExportBundle{
+		Manifest: RunExportManifest{
+			FormatVersion:    RunExportFormatVersion,
+			ExportedAtUnixMs: time.Now().UnixMilli(),
+			AgentVersion:     agentVersion,
+			RunID:            rec.RunID,
+			ThreadID:         rec.ThreadID,
+			MessageID:        rec.MessageID,
+			Model:            strings.TrimSpace(th.ModelID),
+			RunState:         rec.State,
+			StartedAtUnixMs:  rec.StartedAtUnixMs,
+			EndedAtUnixMs:    rec.EndedAtUnixMs,
+			PathsRedacted:    opts.RedactExternalPaths,
+		},
+		Messages: make([]any, 0, 16),
+		Events:   make([]RunEventView, 0, 64),
+	}
+
+	messages, err := listRunExportMessages(ctx, db, endpointID, rec.ThreadID, rec.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range messages {
+		if msg := decode(strings.TrimSpace(m.MessageJSON)); msg != nil {
+			bundle.Messages = append(bundle.Messages, msg)
+		}
+	}
+
+	prompt, err := db.GetRunSystemPrompt(ctx, endpointID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RedactExternalPaths {
+		prompt = redactRunExportPaths(prompt, agentHomeDir)
+	}
+	bundle.SystemPrompt = prompt
+
+	query := threadstore.RunEventsQuery{Limit: 2000}
+	for {
+		recs, nextCursor, hasMore, err := db.ListRunEventsPage(ctx, endpointID, runID, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, ev := range recs {
+			payload := decode(strings.TrimSpace(ev.PayloadJSON))
+			eventType := strings.TrimSpace(ev.EventType)
+			switch eventType {
+			case "provider.turn.diag":
+				if obj, ok := payload.(map[string]any); ok {
+					payload = redactAnyForPersist("", obj, 0)
+					bundle.Manifest.ProviderID = firstNonEmpty(anyToString(obj["provider_id"]), bundle.Manifest.ProviderID)
+					bundle.Manifest.Model = firstNonEmpty(anyToString(obj["model"]), bundle.Manifest.Model)
+				}
+				bundle.ProviderDiagnostics = append(bundle.ProviderDiagnostics, payload)
+			case runMetricsEventType:
+				if obj, ok := payload.(map[string]any); ok {
+					bundle.Manifest.FinalizationReason = strings.TrimSpace(anyToString(obj["finalization_reason"]))
+				}
+			}
+			bundle.Events = append(bundle.Events, RunEventView{
+				EventID:    ev.ID,
+				RunID:      strings.TrimSpace(ev.RunID),
+				ThreadID:   strings.TrimSpace(ev.ThreadID),
+				StreamKind: strings.TrimSpace(ev.StreamKind),
+				EventType:  eventType,
+				AtUnixMs:   ev.AtUnixMs,
+				Payload:    payload,
+			})
+		}
+		if !hasMore {
+			break
+		}
+		query.Cursor = nextCursor
+	}
+	return bundle, nil
+}
+
+// listRunExportMessages returns the thread transcript in order, ending at the run's assistant message when it was persisted.
+func listRunExportMessages(ctx context.Context, db *threadstore.Store, endpointID string, threadID string, runMessageID string) ([]threadstore.Message, error) {
+	runMessageID = strings.TrimSpace(runMessageID)
+	out := make([]threadstore.Message, 0, 16)
+	afterID := int64(0)
+	for {
+		page, nextAfterID, hasMore, err := db.ListMessagesAfter(ctx, endpointID, threadID, 500, afterID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range page {
+			out = append(out, m)
+			if runMessageID != "" && strings.TrimSpace(m.MessageID) == runMessageID {
+				return out, nil
+			}
+		}
+		if !hasMore || len(page) == 0 {
+			return out, nil
+		}
+		afterID = nextAfterID
+	}
+}
+
+// redactRunExportValue applies redactRunExportPaths to every string of a decoded JSON value.
+func redactRunExportValue(v any, root string) any {
+	switch x := v.(type) {
+	case string:
+		return redactRunExportPaths(x, root)
+	case map[string]any:
+		for k, vv := range x {
+			x[k] = redactRunExportValue(vv, root)
+		}
+		return x
+	case []any:
+		for i, vv := range x {
+			x[i] = redactRunExportValue(vv, root)
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+// redactRunExportPaths replaces absolute paths in raw that are outside root.
+func redactRunExportPaths(raw string, root string) string {
+	root = strings.TrimSpace(root)
+	if root != "" {
+		root = filepath.Clean(root)
+	}
+	return runExportAbsPathRe.ReplaceAllStringFunc(raw, func(match string) string {
+		sub := runExportAbsPathRe.FindStringSubmatch(match)
+		if len(sub) != 3 {
+			return match
+		}
+		p := filepath.Clean(sub[2])
+		if root != "" && (p == root || strings.HasPrefix(p, root+string(filepath.Separator))) {
+			return match
+		}
+		return sub[1] + runExportRedactedPath
+	})
+}
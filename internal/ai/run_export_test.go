@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+)
+
+func TestService_ExportRun(t *testing.T) {
+	t.Parallel()
+
+	svc := newSendTurnTestService(t)
+	svc.agentVersion = "v1.2.3"
+	meta := testSendTurnMeta()
+	ctx := context.Background()
+	db := svc.threadsDB
+
+	th, err := svc.CreateThread(ctx, meta, "export me", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	runID := "run_export_1"
+	if err := db.UpsertRun(ctx, threadstore.RunRecord{
+		RunID:      runID,
+		EndpointID: meta.EndpointID,
+		ThreadID:   th.ThreadID,
+		MessageID:  "msg_export_assistant",
+		State:      "success",
+	}); err != nil {
+		t.Fatalf("UpsertRun: %v", err)
+	}
+	insidePath := filepath.Join(svc.agentHomeDir, "workspace", "main.go")
+	if err := db.SetRunSystemPrompt(ctx, meta.EndpointID, runID, "You are Flower. Secrets live in /etc/redeven/secrets.json."); err != nil {
+		t.Fatalf("SetRunSystemPrompt: %v", err)
+	}
+	appendMessage := func(messageID string, role string, text string) {
+		t.Helper()
+		msg := map[string]any{
+			"id":     messageID,
+			"role":   role,
+			"status": "complete",
+			"blocks": []any{map[string]any{"type": "markdown", "content": text}},
+		}
+		b, _ := json.Marshal(msg)
+		if _, err := db.AppendMessage(ctx, meta.EndpointID, th.ThreadID, threadstore.Message{
+			MessageID:   messageID,
+			Role:        role,
+			Status:      "complete",
+			TextContent: text,
+			MessageJSON: string(b),
+		}, meta.UserPublicID, meta.UserEmail); err != nil {
+			t.Fatalf("AppendMessage %s: %v", messageID, err)
+		}
+	}
+	appendMessage("msg_export_user", "user", "Fix the build in "+insidePath)
+	appendMessage("msg_export_assistant", "assistant", "Patched "+insidePath+" and checked /home/alice/.ssh/config.\n/opt/tool/bin/run was not needed. See https://example.com/docs/build.")
+	appendMessage("msg_export_later", "user", "a later turn that belongs to another run")
+
+	appendEvent := func(eventType string, payload map[string]any) {
+		t.Helper()
+		b, _ := json.Marshal(payload)
+		if err := db.AppendRunEvent(ctx, threadstore.RunEventRecord{
+			EndpointID:  meta.EndpointID,
+			ThreadID:    th.ThreadID,
+			RunID:       runID,
+			StreamKind:  string(RealtimeStreamKindLifecycle),
+			EventType:   eventType,
+			PayloadJSON: string(b),
+		}); err != nil {
+			t.Fatalf("AppendRunEvent %s: %v", eventType, err)
+		}
+	}
+	appendEvent("provider.turn.diag", map[string]any{
+		"provider_id": "openai",
+		"model":       "gpt-5-mini",
+		"diag":        map[string]any{"response_id": "resp_1", "api_key": "sk-should-not-leak"},
+	})
+	appendEvent(runMetricsEventType, map[string]any{"finalization_reason": "task_complete"})
+
+	bundle, err := svc.ExportRun(ctx, meta, runID, RunExportOptions{RedactExternalPaths: true})
+	if err != nil {
+		t.Fatalf("ExportRun: %v", err)
+	}
+	m := bundle.Manifest
+	if m.FormatVersion != RunExportFormatVersion || m.AgentVersion != "v1.2.3" || m.RunID != runID || m.ThreadID != th.ThreadID ||
+		m.ProviderID != "openai" || m.Model != "gpt-5-mini" || m.RunState != "success" || m.FinalizationReason != "task_complete" || !m.PathsRedacted {
+		t.Fatalf("manifest=%+v", m)
+	}
+	if len(bundle.Messages) != 2 {
+		t.Fatalf("messages=%d, want the two messages up to the run's assistant message", len(bundle.Messages))
+	}
+	if len(bundle.Events) != 2 || len(bundle.ProviderDiagnostics) != 1 {
+		t.Fatalf("events=%d diagnostics=%d", len(bundle.Events), len(bundle.ProviderDiagnostics))
+	}
+
+	raw, err := json.Marshal(map[string]any{"ok": true, "data": bundle})
+	if err != nil {
+		t.Fatalf("marshal bundle: %v", err)
+	}
+	text := string(raw)
+	for _, leaked := range []string{"sk-should-not-leak", "/home/alice", "/opt/tool", "/etc/redeven"} {
+		if strings.Contains(text, leaked) {
+			t.Fatalf("export leaked %q: %s", leaked, text)
+		}
+	}
+	for _, kept := range []string{insidePath, "https://example.com/docs/build", runExportRedactedPath} {
+		if !strings.Contains(text, kept) {
+			t.Fatalf("export missing %q: %s", kept, text)
+		}
+	}
+
+	// The gateway envelope must stay loadable as an ai-loop-replay message.log.
+	var envelope struct {
+		OK   bool `json:"ok"`
+		Data struct {
+			Messages []struct {
+				Role   string `json:"role"`
+				Blocks []any  `json:"blocks"`
+			} `json:"messages"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		t.Fatalf("decode envelope: %v", err)
+	}
+	if !envelope.OK || len(envelope.Data.Messages) != 2 || envelope.Data.Messages[1].Role != "assistant" || len(envelope.Data.Messages[1].Blocks) != 1 {
+		t.Fatalf("envelope=%+v", envelope)
+	}
+
+	plain, err := svc.ExportRun(ctx, meta, runID, RunExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportRun without redaction: %v", err)
+	}
+	if !strings.Contains(plain.SystemPrompt, "/etc/redeven/secrets.json") {
+		t.Fatalf("unredacted export should keep paths, system_prompt=%q", plain.SystemPrompt)
+	}
+
+	if _, err := svc.ExportRun(ctx, meta, "run_missing", RunExportOptions{}); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("missing run err=%v, want ErrRunNotFound", err)
+	}
+}
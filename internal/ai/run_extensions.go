@@ -45,15 +45,64 @@ func (r *run) activateSkill(name string) (SkillActivation, bool, error) {
 	if mgr == nil {
 		return SkillActivation{}, false, errors.New("skill manager unavailable")
 	}
+	chain, err := mgr.Requirements(name, r.runMode, false)
+	if err != nil {
+		r.persistRunEvent("skill.activate.error", RealtimeStreamKindLifecycle, map[string]any{"name": strings.TrimSpace(name), "error": err.Error()})
+		return SkillActivation{}, false, err
+	}
+	requiredTools := make([]string, 0, 4)
+	for _, meta := range chain {
+		requiredTools = append(requiredTools, meta.RequiredTools...)
+	}
+	if missing := r.missingActiveTools(requiredTools); len(missing) > 0 {
+		err := fmt.Errorf("skill %q requires tools that are not available in %s mode: %s", strings.TrimSpace(name), r.runMode, strings.Join(missing, ", "))
+		r.persistRunEvent("skill.activate.error", RealtimeStreamKindLifecycle, map[string]any{"name": strings.TrimSpace(name), "missing_tools": missing, "error": err.Error()})
+		return SkillActivation{}, false, err
+	}
 	activation, alreadyActive, err := mgr.Activate(name, r.runMode, false)
 	if err != nil {
 		r.persistRunEvent("skill.activate.error", RealtimeStreamKindLifecycle, map[string]any{"name": strings.TrimSpace(name), "error": err.Error()})
 		return SkillActivation{}, false, err
 	}
-	r.persistRunEvent("skill.activated", RealtimeStreamKindLifecycle, map[string]any{"name": activation.Name, "activation_id": activation.ActivationID, "already_active": alreadyActive})
+	r.persistRunEvent("skill.activated", RealtimeStreamKindLifecycle, map[string]any{"name": activation.Name, "activation_id": activation.ActivationID, "already_active": alreadyActive, "prerequisites": activation.Prerequisites})
 	return activation, alreadyActive, nil
 }
 
+func (r *run) setActiveToolNames(tools []ToolDef) {
+	if r == nil {
+		return
+	}
+	names := make(map[string]struct{}, len(tools))
+	for _, tool := range tools {
+		names[strings.TrimSpace(tool.Name)] = struct{}{}
+	}
+	r.mu.Lock()
+	r.activeToolNames = names
+	r.mu.Unlock()
+}
+
+// missingActiveTools returns the required tools the model is not offered on the current turn, sorted.
+//
+// Before the first turn the tool set is unknown and nothing is reported missing.
+func (r *run) missingActiveTools(required []string) []string {
+	if r == nil || len(required) == 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.activeToolNames == nil {
+		return nil
+	}
+	missing := make([]string, 0, len(required))
+	for _, name := range uniqueStrings(required) {
+		if _, ok := r.activeToolNames[strings.TrimSpace(name)]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
 func (r *run) ensureSubagentManager() *subagentManager {
 	if r == nil {
 		return nil
@@ -0,0 +1,220 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/session"
+)
+
+const (
+	runMetricsEventType         = "run.metrics"
+	runMetricsMaxContinueReason = 32
+
+	// Continue kinds recorded in RunMetricsSnapshot.ContinueReasons as "<kind>:<reason>".
+	runContinueKindCompletion = "completion"
+	runContinueKindTask       = "task"
+)
+
+// ErrRunMetricsNotFound is returned when a run has no persisted metrics snapshot
+// (the run is still active, predates run metrics, or its events were pruned).
+var ErrRunMetricsNotFound = errors.New("run metrics not found")
+
+// RunMetricsSnapshot is the typed per-run aggregate persisted as a run.metrics event when the run ends.
+//
+// The counters are maintained by the runtime where the behavior happens, so consumers do not need
+// to reconstruct them from event type names.
+type RunMetricsSnapshot struct {
+	RunID string `json:"run_id"`
+	// AttemptCount is the number of model turns issued by the native loop.
+	AttemptCount   int   `json:"attempt_count"`
+	ToolCallCount  int64 `json:"tool_call_count"`
+	ToolErrorCount int   `json:"tool_error_count"`
+	RecoveryCount  int   `json:"recovery_count"`
+	// CompletionContinues counts turns the loop continued after a rejected or incomplete completion.
+	CompletionContinues int `json:"completion_continues"`
+	// TaskLoopContinues counts turns the loop continued because the model answered without a tool call.
+	TaskLoopContinues int `json:"task_loop_continues"`
+	// ContinueReasons lists the continue reasons in order as "completion:<reason>" or "task:<reason>",
+	// capped at the first 32.
+	ContinueReasons    []string `json:"continue_reasons,omitempty"`
+	LoopExhausted      bool     `json:"loop_exhausted"`
+	AutoRetries        int      `json:"auto_retries,omitempty"`
+	FinalizationReason string   `json:"finalization_reason,omitempty"`
+	EndState           string   `json:"end_state,omitempty"`
+}
+
+// runLoopCounters holds the native loop counters behind RunMetrics.mu.
+type runLoopCounters struct {
+	attempts            int
+	toolErrors          int
+	recoveries          int
+	completionContinues int
+	taskLoopContinues   int
+	continueReasons     []string
+	loopExhausted       bool
+}
+
+func (m *RunMetrics) recordAttempt() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.loop.attempts++
+	m.mu.Unlock()
+}
+
+func (m *RunMetrics) recordToolError() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.loop.toolErrors++
+	m.mu.Unlock()
+}
+
+func (m *RunMetrics) recordRecovery() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.loop.recoveries++
+	m.mu.Unlock()
+}
+
+func (m *RunMetrics) recordLoopExhausted() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.loop.loopExhausted = true
+	m.mu.Unlock()
+}
+
+// recordContinue notes that the loop continued instead of finishing; kind is runContinueKindCompletion or runContinueKindTask.
+func (m *RunMetrics) recordContinue(kind string, reason string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch kind {
+	case runContinueKindCompletion:
+		m.loop.completionContinues++
+	case runContinueKindTask:
+		m.loop.taskLoopContinues++
+	default:
+		return
+	}
+	if len(m.loop.continueReasons) < runMetricsMaxContinueReason {
+		reason = strings.TrimSpace(reason)
+		if reason == "" {
+			reason = "unspecified"
+		}
+		m.loop.continueReasons = append(m.loop.continueReasons, kind+":"+reason)
+	}
+}
+
+func (m *RunMetrics) loopSnapshot() runLoopCounters {
+	if m == nil {
+		return runLoopCounters{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.loop
+	out.continueReasons = append([]string(nil), m.loop.continueReasons...)
+	return out
+}
+
+// metricsSnapshot builds the run's metrics snapshot for the given terminal state.
+func (r *run) metricsSnapshot(endState RunState, finalizationReason string) RunMetricsSnapshot {
+	if r == nil {
+		return RunMetricsSnapshot{}
+	}
+	loop := r.metrics.loopSnapshot()
+	snapshot := RunMetricsSnapshot{
+		RunID:               strings.TrimSpace(r.id),
+		AttemptCount:        loop.attempts,
+		ToolCallCount:       r.runtimeToolCalls.Load(),
+		ToolErrorCount:      loop.toolErrors,
+		RecoveryCount:       loop.recoveries,
+		CompletionContinues: loop.completionContinues,
+		TaskLoopContinues:   loop.taskLoopContinues,
+		ContinueReasons:     loop.continueReasons,
+		LoopExhausted:       loop.loopExhausted,
+		AutoRetries:         r.autoRetriesUsed,
+		FinalizationReason:  strings.TrimSpace(finalizationReason),
+		EndState:            string(endState),
+	}
+	if snapshot.FinalizationReason == "task_turn_limit_reached" {
+		snapshot.LoopExhausted = true
+	}
+	return snapshot
+}
+
+func (r *run) persistRunMetrics(snapshot RunMetricsSnapshot) {
+	if r == nil {
+		return
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return
+	}
+	r.persistRunEvent(runMetricsEventType, RealtimeStreamKindLifecycle, payload)
+}
+
+// RunMetricsSnapshot returns the metrics snapshot persisted when the run ended.
+//
+// It returns ErrRunMetricsNotFound when the run has no snapshot.
+func (s *Service) RunMetricsSnapshot(ctx context.Context, meta *session.Meta, runID string) (*RunMetricsSnapshot, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if meta == nil {
+		return nil, errors.New("missing session metadata")
+	}
+	runID = strings.TrimSpace(runID)
+	if runID == "" {
+		return nil, errors.New("missing run_id")
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+
+	var found *RunMetricsSnapshot
+	query := threadstore.RunEventsQuery{Limit: 2000}
+	for {
+		recs, nextCursor, hasMore, err := db.ListRunEventsPage(ctx, strings.TrimSpace(meta.EndpointID), runID, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range recs {
+			if strings.TrimSpace(rec.EventType) != runMetricsEventType {
+				continue
+			}
+			var snapshot RunMetricsSnapshot
+			if err := json.Unmarshal([]byte(rec.PayloadJSON), &snapshot); err != nil {
+				continue
+			}
+			found = &snapshot
+		}
+		if !hasMore {
+			break
+		}
+		query.Cursor = nextCursor
+	}
+	if found == nil {
+		return nil, ErrRunMetricsNotFound
+	}
+	return found, nil
+}
@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestRunMetrics_RecordContinueCapsReasons(t *testing.T) {
+	t.Parallel()
+
+	var m RunMetrics
+	m.recordContinue(runContinueKindCompletion, "empty_result")
+	m.recordContinue(runContinueKindTask, "")
+	m.recordContinue("unknown", "ignored")
+	for i := 0; i < runMetricsMaxContinueReason+5; i++ {
+		m.recordContinue(runContinueKindTask, "no_tool_call")
+	}
+	loop := m.loopSnapshot()
+	if loop.completionContinues != 1 || loop.taskLoopContinues != runMetricsMaxContinueReason+6 {
+		t.Fatalf("continues completion=%d task=%d", loop.completionContinues, loop.taskLoopContinues)
+	}
+	if len(loop.continueReasons) != runMetricsMaxContinueReason {
+		t.Fatalf("continue reasons=%d, want cap %d", len(loop.continueReasons), runMetricsMaxContinueReason)
+	}
+	if loop.continueReasons[0] != "completion:empty_result" || loop.continueReasons[1] != "task:unspecified" {
+		t.Fatalf("unexpected reason flow: %v", loop.continueReasons[:2])
+	}
+}
+
+func TestServiceRunMetricsSnapshot_ReadsPersistedSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	// Summary-only persistence must keep the metrics snapshot.
+	r := newRun(runOptions{
+		AIConfig:         &config.AIConfig{PersistenceMode: config.AIPersistenceModeSummaryOnly},
+		RunID:            "run_metrics",
+		EndpointID:       "env_metrics",
+		ThreadID:         "th_metrics",
+		MessageID:        "msg_metrics",
+		ThreadsDB:        db,
+		PersistOpTimeout: 2 * time.Second,
+	})
+	r.metrics.recordAttempt()
+	r.metrics.recordAttempt()
+	r.metrics.recordRecovery()
+	r.metrics.recordToolError()
+	r.metrics.recordContinue(runContinueKindCompletion, "empty_result")
+	r.runtimeToolCalls.Add(3)
+	r.persistRunMetrics(r.metricsSnapshot(RunStateSuccess, "task_turn_limit_reached"))
+
+	svc := &Service{threadsDB: db}
+	meta := &session.Meta{EndpointID: "env_metrics"}
+	got, err := svc.RunMetricsSnapshot(ctx, meta, "run_metrics")
+	if err != nil {
+		t.Fatalf("RunMetricsSnapshot: %v", err)
+	}
+	if got.RunID != "run_metrics" || got.AttemptCount != 2 || got.ToolCallCount != 3 || got.ToolErrorCount != 1 || got.RecoveryCount != 1 {
+		t.Fatalf("unexpected counters: %+v", got)
+	}
+	if got.CompletionContinues != 1 || len(got.ContinueReasons) != 1 || got.ContinueReasons[0] != "completion:empty_result" {
+		t.Fatalf("unexpected continues: %+v", got)
+	}
+	if !got.LoopExhausted || got.FinalizationReason != "task_turn_limit_reached" || got.EndState != string(RunStateSuccess) {
+		t.Fatalf("unexpected finalization: %+v", got)
+	}
+
+	if _, err := svc.RunMetricsSnapshot(ctx, meta, "run_missing"); !errors.Is(err, ErrRunMetricsNotFound) {
+		t.Fatalf("missing run err=%v, want ErrRunMetricsNotFound", err)
+	}
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+)
+
+// patchRejectedSummary is the tool result summary for previewed apply_patch calls the user rejected.
+const patchRejectedSummary = "patch_rejected"
+
+// applyPatchPreviewMaxDiffBytes bounds the unified diff attached to an apply_patch preview.
+const applyPatchPreviewMaxDiffBytes = 64 * 1024
+
+// previewApplyPatch computes what apply_patch would change without writing to disk.
+func (r *run) previewApplyPatch(ctx context.Context, patchText string) (map[string]any, error) {
+	patchText = strings.TrimSpace(patchText)
+	if patchText == "" {
+		return nil, errors.New("missing patch")
+	}
+	workingDirAbs, err := r.workingDirAbs()
+	if err != nil {
+		return nil, mapToolCwdError(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	parsed, plans, err := planUnifiedDiff(workingDirAbs, patchText)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff strings.Builder
+	diffTruncated := false
+	for i, plan := range plans {
+		fd := parsed.files[i]
+		before := string(plan.original)
+		after := string(plan.contents)
+		if plan.delete {
+			b, err := os.ReadFile(plan.oldAbs)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			before, after = string(b), ""
+		}
+		beforeLabel := patchPreviewDiffLabel("a/", fd.oldPath)
+		afterLabel := patchPreviewDiffLabel("b/", fd.newPath)
+		fileDiff, _ := renderUnifiedContentDiff(before, after, beforeLabel, afterLabel, 0)
+		if fileDiff == "" && beforeLabel != afterLabel {
+			fileDiff = "--- " + beforeLabel + "\n+++ " + afterLabel + "\n"
+		}
+		if diff.Len()+len(fileDiff) > applyPatchPreviewMaxDiffBytes {
+			if cut := strings.LastIndexByte(fileDiff[:applyPatchPreviewMaxDiffBytes-diff.Len()], '\n'); cut >= 0 {
+				diff.WriteString(fileDiff[:cut+1])
+			}
+			diffTruncated = true
+			break
+		}
+		diff.WriteString(fileDiff)
+	}
+
+	filesChanged, hunks, additions, deletions, files := summarizePatchFiles(parsed.files)
+	return map[string]any{
+		"preview":        true,
+		"files_changed":  filesChanged,
+		"hunks":          hunks,
+		"additions":      additions,
+		"deletions":      deletions,
+		"files":          files,
+		"diff":           diff.String(),
+		"diff_truncated": diffTruncated,
+	}, nil
+}
+
+func patchPreviewDiffLabel(prefix string, patchPath string) string {
+	patchPath = strings.TrimSpace(patchPath)
+	if patchPath == "" || patchPath == "/dev/null" {
+		return "/dev/null"
+	}
+	return prefix + patchPath
+}
+
+// patchStatsEventPayload extracts the file counts and line stats of an apply_patch preview or result.
+func patchStatsEventPayload(toolID string, result any) map[string]any {
+	data, _ := result.(map[string]any)
+	return map[string]any{
+		"tool_id":       toolID,
+		"files_changed": readAnyInt(data["files_changed"]),
+		"hunks":         readAnyInt(data["hunks"]),
+		"additions":     readAnyInt(data["additions"]),
+		"deletions":     readAnyInt(data["deletions"]),
+	}
+}
@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	aitools "github.com/floegence/redeven/internal/ai/tools"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+const testPreviewPatch = "*** Begin Patch\n*** Update File: notes.txt\n@@\n one\n-two\n+TWO\n three\n*** Add File: added.txt\n+fresh\n*** End Patch"
+
+func TestPreviewApplyPatch_RendersDiffWithoutWriting(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workingDir, "notes.txt"), []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	r := &run{agentHomeDir: workingDir, workingDir: workingDir}
+	preview, err := r.previewApplyPatch(context.Background(), testPreviewPatch)
+	if err != nil {
+		t.Fatalf("previewApplyPatch: %v", err)
+	}
+	want := "--- a/notes.txt\n+++ b/notes.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+TWO\n three\n" +
+		"--- /dev/null\n+++ b/added.txt\n@@ -0,0 +1,1 @@\n+fresh\n"
+	if preview["diff"] != want {
+		t.Fatalf("diff=\n%s\nwant\n%s", preview["diff"], want)
+	}
+	if preview["files_changed"] != 2 || preview["additions"] != 2 || preview["deletions"] != 1 {
+		t.Fatalf("preview stats=%v", preview)
+	}
+	if b, _ := os.ReadFile(filepath.Join(workingDir, "notes.txt")); string(b) != "one\ntwo\nthree\n" {
+		t.Fatalf("preview modified notes.txt: %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(workingDir, "added.txt")); !os.IsNotExist(err) {
+		t.Fatalf("preview created added.txt: err=%v", err)
+	}
+}
+
+func TestHandleToolCall_ApplyPatchPreviewAppliesOnlyAfterApproval(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	notes := filepath.Join(workingDir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	r := newRun(runOptions{
+		Log:          slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
+		AgentHomeDir: workingDir,
+		WorkingDir:   workingDir,
+		Shell:        "bash",
+		AIConfig: &config.AIConfig{
+			ApplyPatchPreview: true,
+			ExecutionPolicy:   &config.AIExecutionPolicy{RequireUserApproval: true},
+		},
+		SessionMeta: &session.Meta{CanRead: true, CanWrite: true, CanExecute: true},
+		MessageID:   "msg_patch_preview",
+	})
+	r.runMode = config.AIModeAct
+	handler := &builtInToolHandler{r: r, toolName: "apply_patch"}
+
+	call := func(toolID string, approve bool) ToolResult {
+		t.Helper()
+		done := make(chan ToolResult, 1)
+		go func() {
+			res, err := handler.Execute(context.Background(), ToolCall{ID: toolID, Name: "apply_patch", Args: map[string]any{"patch": testPreviewPatch}})
+			if err != nil {
+				t.Errorf("Execute: %v", err)
+			}
+			done <- res
+		}()
+		waitApprovalRequested(t, r, toolID)
+		if b, _ := os.ReadFile(notes); string(b) != "one\ntwo\nthree\n" {
+			t.Fatalf("patch applied before approval: %q", b)
+		}
+		if block := findToolCallBlock(r, toolID); block == nil || !strings.Contains(anyToString(block.Result.(map[string]any)["diff"]), "+TWO") {
+			t.Fatalf("pending block missing the preview diff: %+v", block)
+		}
+		if err := r.approveTool(toolID, approve); err != nil {
+			t.Fatalf("approveTool: %v", err)
+		}
+		return <-done
+	}
+
+	rejected := call("tool_patch_rejected", false)
+	if rejected.Status != toolResultStatusAborted || rejected.Summary != patchRejectedSummary || rejected.Error == nil || rejected.Error.Code != aitools.ErrorCodePatchRejected {
+		t.Fatalf("rejected result=%+v", rejected)
+	}
+	if b, _ := os.ReadFile(notes); string(b) != "one\ntwo\nthree\n" {
+		t.Fatalf("rejected patch modified notes.txt: %q", b)
+	}
+
+	applied := call("tool_patch_applied", true)
+	if applied.Status != toolResultStatusSuccess {
+		t.Fatalf("approved result=%+v", applied)
+	}
+	if b, _ := os.ReadFile(notes); string(b) != "one\nTWO\nthree\n" {
+		t.Fatalf("notes.txt after approval=%q", b)
+	}
+}
+
+func findToolCallBlock(r *run, toolID string) *ToolCallBlock {
+	r.muAssistant.Lock()
+	defer r.muAssistant.Unlock()
+	for _, raw := range r.assistantBlocks {
+		if block, ok := raw.(ToolCallBlock); ok && block.ToolID == toolID {
+			return &block
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+)
+
+// isSummaryOnlyRunEventType reports whether a run event is kept in summary-only persistence mode.
+//
+// Only coarse lifecycle markers survive; per-turn, per-delta, and per-tool events are dropped.
+func isSummaryOnlyRunEventType(eventType string) bool {
+	switch strings.TrimSpace(eventType) {
+	case "run.start", "run.end", "run.error", "run.cancelled", "run.metrics", "ask_user.waiting", "exit_plan_mode.waiting":
+		return true
+	default:
+		return false
+	}
+}
+
+// minimizeToolCallRecordForPersist keeps tool call metrics (status, timing, error code) but drops bodies.
+func minimizeToolCallRecordForPersist(rec threadstore.ToolCallRecord) threadstore.ToolCallRecord {
+	rec.ArgsJSON = ""
+	rec.ResultJSON = ""
+	rec.ErrorMessage = ""
+	return rec
+}
+
+// minimizeAssistantMessageJSONForPersist strips an assistant message down to its final answer.
+//
+// Markdown blocks are kept. Waiting-user tool blocks (ask_user, exit_plan_mode) are kept so
+// waiting prompts can still be recovered after a restart. Everything else is dropped.
+func minimizeAssistantMessageJSONForPersist(messageJSON string) string {
+	var msg struct {
+		ID        string           `json:"id"`
+		Role      string           `json:"role"`
+		Blocks    []map[string]any `json:"blocks"`
+		Status    string           `json:"status"`
+		Timestamp int64            `json:"timestamp"`
+		Error     string           `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(messageJSON), &msg); err != nil {
+		return messageJSON
+	}
+	blocks := make([]any, 0, len(msg.Blocks))
+	for _, block := range msg.Blocks {
+		if block == nil {
+			continue
+		}
+		blockType, _ := block["type"].(string)
+		switch strings.TrimSpace(blockType) {
+		case "markdown":
+			content, _ := block["content"].(string)
+			if strings.TrimSpace(content) == "" {
+				continue
+			}
+			blocks = append(blocks, &persistedMarkdownBlock{Type: "markdown", Content: content})
+		case "tool-call":
+			toolName, _ := block["toolName"].(string)
+			switch strings.TrimSpace(toolName) {
+			case "ask_user", "exit_plan_mode":
+				blocks = append(blocks, block)
+			}
+		}
+	}
+	if len(blocks) == 0 {
+		blocks = append(blocks, &persistedMarkdownBlock{Type: "markdown", Content: ""})
+	}
+	out := persistedMessage{
+		ID:        msg.ID,
+		Role:      msg.Role,
+		Blocks:    blocks,
+		Status:    msg.Status,
+		Timestamp: msg.Timestamp,
+		Error:     msg.Error,
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return messageJSON
+	}
+	return string(b)
+}
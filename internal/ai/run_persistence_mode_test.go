@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestMinimizeAssistantMessageJSONForPersist_KeepsAnswerAndWaitingPrompt(t *testing.T) {
+	t.Parallel()
+
+	raw, err := json.Marshal(persistedMessage{
+		ID:   "msg_summary_only",
+		Role: "assistant",
+		Blocks: []any{
+			&persistedThinkingBlock{Type: "thinking", Content: "private reasoning"},
+			ToolCallBlock{Type: "tool-call", ToolName: "terminal.exec", ToolID: "tool_exec", Args: map[string]any{"command": "cat secrets"}, Status: ToolCallStatusSuccess, Result: map[string]any{"stdout": "secret"}},
+			&persistedMarkdownBlock{Type: "markdown", Content: "Final answer."},
+			ToolCallBlock{Type: "tool-call", ToolName: "ask_user", ToolID: "tool_ask", Args: map[string]any{"question": "Which env?"}, Status: ToolCallStatusSuccess},
+		},
+		Status:    "complete",
+		Timestamp: 42,
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	var got struct {
+		ID        string           `json:"id"`
+		Timestamp int64            `json:"timestamp"`
+		Blocks    []map[string]any `json:"blocks"`
+	}
+	if err := json.Unmarshal([]byte(minimizeAssistantMessageJSONForPersist(string(raw))), &got); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if got.ID != "msg_summary_only" || got.Timestamp != 42 {
+		t.Fatalf("message identity not preserved: %+v", got)
+	}
+	if len(got.Blocks) != 2 {
+		t.Fatalf("blocks=%v, want markdown + ask_user", got.Blocks)
+	}
+	if got.Blocks[0]["type"] != "markdown" || got.Blocks[0]["content"] != "Final answer." {
+		t.Fatalf("blocks[0]=%v, want final markdown answer", got.Blocks[0])
+	}
+	if got.Blocks[1]["toolName"] != "ask_user" {
+		t.Fatalf("blocks[1]=%v, want ask_user block", got.Blocks[1])
+	}
+}
+
+func TestRunSummaryOnlyPersistence_DropsDetailedRecords(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	r := newRun(runOptions{
+		AIConfig:         &config.AIConfig{PersistenceMode: config.AIPersistenceModeSummaryOnly},
+		RunID:            "run_summary_only",
+		EndpointID:       "env_summary_only",
+		ThreadID:         "th_summary_only",
+		MessageID:        "msg_summary_only",
+		ThreadsDB:        db,
+		PersistOpTimeout: 2 * time.Second,
+	})
+	now := time.Now()
+	r.persistRunRecord(RunStateRunning, "", "", now.UnixMilli(), 0)
+	r.persistRunEvent("run.start", RealtimeStreamKindLifecycle, map[string]any{"objective": "hello"})
+	r.persistRunEvent("tool.result", RealtimeStreamKindTool, map[string]any{"stdout": "secret"})
+	r.persistToolCallSnapshot("tool_exec", "terminal.exec", ToolCallStatusSuccess, map[string]any{"command": "cat secrets"}, map[string]any{"stdout": "secret"}, nil, "", now, now.Add(time.Second))
+
+	events, err := db.ListRunEvents(ctx, "env_summary_only", "run_summary_only", 100)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].EventType != "run.start" {
+		t.Fatalf("events=%+v, want only run.start", events)
+	}
+
+	rec, err := db.GetToolCall(ctx, "env_summary_only", "run_summary_only", "tool_exec")
+	if err != nil {
+		t.Fatalf("GetToolCall: %v", err)
+	}
+	if rec == nil {
+		t.Fatalf("tool call metrics should still be persisted")
+	}
+	if rec.ResultJSON != "" || rec.ArgsJSON != "{}" {
+		t.Fatalf("tool bodies should be dropped, args=%q result=%q", rec.ArgsJSON, rec.ResultJSON)
+	}
+	if rec.LatencyMS != 1000 || rec.Status != string(ToolCallStatusSuccess) {
+		t.Fatalf("tool metrics not preserved: %+v", rec)
+	}
+}
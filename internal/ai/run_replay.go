@@ -0,0 +1,229 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/session"
+)
+
+const runReplayedFromEventType = "run.replayed_from"
+
+// RunReplayResult pairs a finished run with the fresh run that re-executes its task on another model.
+type RunReplayResult struct {
+	SourceRunID    string `json:"source_run_id"`
+	SourceThreadID string `json:"source_thread_id"`
+	SourceModel    string `json:"source_model,omitempty"`
+	RunID          string `json:"run_id"`
+	ThreadID       string `json:"thread_id"`
+	Model          string `json:"model"`
+	// ReplayedInputs counts the user inputs sent again, including the one that starts the new run.
+	ReplayedInputs int `json:"replayed_inputs"`
+}
+
+// ReplayRunWithModel re-executes the task of sourceRunID with newModel in a new thread.
+//
+// Only the user inputs that led up to the source run are carried over; the assistant turns are not,
+// so the new model answers the same task from scratch. Earlier inputs are written to the new thread
+// as history and the source run's own input starts a detached run there. The new run records a
+// run.replayed_from event that links it back to the source run.
+func (s *Service) ReplayRunWithModel(ctx context.Context, meta *session.Meta, sourceRunID string, newModel string) (*RunReplayResult, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	sourceRunID = strings.TrimSpace(sourceRunID)
+	if sourceRunID == "" {
+		return nil, errors.New("missing run_id")
+	}
+	newModel = strings.TrimSpace(newModel)
+	if newModel == "" {
+		return nil, errors.New("missing model")
+	}
+	endpointID := strings.TrimSpace(meta.EndpointID)
+
+	rec, err := db.GetRun(ctx, endpointID, sourceRunID)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, ErrRunNotFound
+	}
+	if rec.EndedAtUnixMs <= 0 {
+		return nil, errors.New("source run has not finished")
+	}
+	th, err := db.GetThread(ctx, endpointID, rec.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if th == nil || th.ArchivedAtUnixMs > 0 {
+		return nil, errors.New("thread not found")
+	}
+
+	messages, err := listRunExportMessages(ctx, db, endpointID, rec.ThreadID, rec.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	inputs := runReplayInputs(messages, rec.StartedAtUnixMs)
+	if len(inputs) == 0 {
+		return nil, errors.New("source run has no user input")
+	}
+
+	view, err := s.CreateThread(ctx, meta, "", newModel, th.ExecutionMode, th.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, input := range inputs[:len(inputs)-1] {
+		persisted, _, err := s.persistUserMessage(ctx, meta, endpointID, view.ThreadID, input)
+		if err != nil {
+			return nil, err
+		}
+		s.broadcastTranscriptMessage(endpointID, view.ThreadID, "", persisted.RowID, persisted.MessageJSON, persisted.CreatedAtUnixMs)
+	}
+
+	runID, err := NewRunID()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.StartRunDetached(meta, runID, RunStartRequest{
+		ThreadID: view.ThreadID,
+		Model:    newModel,
+		Input:    inputs[len(inputs)-1],
+	}); err != nil {
+		return nil, err
+	}
+
+	out := &RunReplayResult{
+		SourceRunID:    rec.RunID,
+		SourceThreadID: rec.ThreadID,
+		SourceModel:    runReplaySourceModel(ctx, db, endpointID, rec.RunID, th.ModelID),
+		RunID:          runID,
+		ThreadID:       view.ThreadID,
+		Model:          newModel,
+		ReplayedInputs: len(inputs),
+	}
+	payload, err := json.Marshal(map[string]any{
+		"source_run_id":    out.SourceRunID,
+		"source_thread_id": out.SourceThreadID,
+		"source_model":     out.SourceModel,
+		"model":            out.Model,
+		"replayed_inputs":  out.ReplayedInputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.AppendRunEvent(ctx, threadstore.RunEventRecord{
+		EndpointID:  endpointID,
+		ThreadID:    view.ThreadID,
+		RunID:       runID,
+		StreamKind:  string(RealtimeStreamKindLifecycle),
+		EventType:   runReplayedFromEventType,
+		PayloadJSON: string(payload),
+		AtUnixMs:    time.Now().UnixMilli(),
+	}); err != nil && s.log != nil {
+		s.log.Warn("ai run replay link not persisted", "run_id", runID, "source_run_id", out.SourceRunID, "error", err)
+	}
+	if s.log != nil {
+		s.log.Info("ai run replayed", "source_run_id", out.SourceRunID, "run_id", runID, "thread_id", view.ThreadID, "model", newModel)
+	}
+	return out, nil
+}
+
+// runReplayInputs rebuilds the user inputs of a run from its transcript, dropping every other role.
+//
+// Messages written after the run started belong to later turns and are skipped.
+func runReplayInputs(messages []threadstore.Message, startedAtUnixMs int64) []RunInput {
+	out := make([]RunInput, 0, len(messages))
+	for _, m := range messages {
+		if strings.TrimSpace(m.Role) != "user" {
+			continue
+		}
+		if startedAtUnixMs > 0 && m.CreatedAtUnixMs > startedAtUnixMs {
+			continue
+		}
+		input, ok := runReplayInputFromMessageJSON(m.MessageJSON)
+		if !ok {
+			text := strings.TrimSpace(m.TextContent)
+			if text == "" {
+				continue
+			}
+			input = RunInput{Text: text}
+		}
+		out = append(out, input)
+	}
+	return out
+}
+
+func runReplayInputFromMessageJSON(raw string) (RunInput, bool) {
+	var msg struct {
+		Blocks []struct {
+			Type     string `json:"type"`
+			Content  string `json:"content"`
+			Src      string `json:"src"`
+			Alt      string `json:"alt"`
+			Name     string `json:"name"`
+			MimeType string `json:"mimeType"`
+			URL      string `json:"url"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &msg); err != nil {
+		return RunInput{}, false
+	}
+	var input RunInput
+	texts := make([]string, 0, 1)
+	for _, b := range msg.Blocks {
+		switch strings.TrimSpace(b.Type) {
+		case "text":
+			if txt := strings.TrimSpace(b.Content); txt != "" {
+				texts = append(texts, txt)
+			}
+		case "image":
+			if src := strings.TrimSpace(b.Src); src != "" {
+				input.Attachments = append(input.Attachments, RunAttachmentIn{Name: strings.TrimSpace(b.Alt), URL: src})
+			}
+		case "file":
+			if u := strings.TrimSpace(b.URL); u != "" {
+				input.Attachments = append(input.Attachments, RunAttachmentIn{Name: strings.TrimSpace(b.Name), MimeType: strings.TrimSpace(b.MimeType), URL: u})
+			}
+		}
+	}
+	input.Text = strings.Join(texts, "\n\n")
+	if input.Text == "" && len(input.Attachments) == 0 {
+		return RunInput{}, false
+	}
+	return input, true
+}
+
+// runReplaySourceModel returns the model the source run started with, falling back to the thread model.
+func runReplaySourceModel(ctx context.Context, db *threadstore.Store, endpointID string, runID string, fallback string) string {
+	recs, _, _, err := db.ListRunEventsPage(ctx, endpointID, runID, threadstore.RunEventsQuery{Limit: 50})
+	if err == nil {
+		for _, ev := range recs {
+			if strings.TrimSpace(ev.EventType) != "run.start" {
+				continue
+			}
+			var payload map[string]any
+			if json.Unmarshal([]byte(ev.PayloadJSON), &payload) == nil {
+				if model := strings.TrimSpace(anyToString(payload["model"])); model != "" {
+					return model
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(fallback)
+}
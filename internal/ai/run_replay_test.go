@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestReplayRunWithModel_ReplaysUserInputsIntoNewThread(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	svc.mu.Lock()
+	svc.cfg.Providers[0].Models = append(svc.cfg.Providers[0].Models, config.AIProviderModel{ModelName: "gpt-5"})
+	svc.mu.Unlock()
+	ctx := context.Background()
+	meta := newThreadRunConcurrencyTestMeta("env_run_replay")
+	db := svc.threadsDB
+
+	src, err := svc.CreateThread(ctx, meta, "premium run", "openai/gpt-5", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	startedAt := time.Now().UnixMilli()
+	appendMessage := func(messageID string, role string, text string, atUnixMs int64) {
+		t.Helper()
+		b, _ := json.Marshal(map[string]any{
+			"id":     messageID,
+			"role":   role,
+			"status": "complete",
+			"blocks": []any{map[string]any{"type": "text", "content": text}},
+		})
+		if _, err := db.AppendMessage(ctx, meta.EndpointID, src.ThreadID, threadstore.Message{
+			MessageID:       messageID,
+			Role:            role,
+			Status:          "complete",
+			TextContent:     text,
+			MessageJSON:     string(b),
+			CreatedAtUnixMs: atUnixMs,
+			UpdatedAtUnixMs: atUnixMs,
+		}, meta.UserPublicID, meta.UserEmail); err != nil {
+			t.Fatalf("AppendMessage %s: %v", messageID, err)
+		}
+	}
+	appendMessage("msg_replay_user_1", "user", "List the repo layout", startedAt-2000)
+	appendMessage("msg_replay_assistant_1", "assistant", "It has cmd/ and internal/.", startedAt-1500)
+	appendMessage("msg_replay_user_2", "user", "Now fix the failing build", startedAt)
+	appendMessage("msg_replay_assistant_2", "assistant", "Fixed.", startedAt+500)
+	appendMessage("msg_replay_later", "user", "a later turn", startedAt+1000)
+
+	sourceRunID := "run_replay_source"
+	if err := db.UpsertRun(ctx, threadstore.RunRecord{
+		RunID:           sourceRunID,
+		EndpointID:      meta.EndpointID,
+		ThreadID:        src.ThreadID,
+		MessageID:       "msg_replay_assistant_2",
+		State:           "success",
+		StartedAtUnixMs: startedAt,
+		EndedAtUnixMs:   startedAt + 500,
+	}); err != nil {
+		t.Fatalf("UpsertRun: %v", err)
+	}
+	if err := db.AppendRunEvent(ctx, threadstore.RunEventRecord{
+		EndpointID:  meta.EndpointID,
+		ThreadID:    src.ThreadID,
+		RunID:       sourceRunID,
+		StreamKind:  string(RealtimeStreamKindLifecycle),
+		EventType:   "run.start",
+		PayloadJSON: `{"model":"openai/gpt-5"}`,
+	}); err != nil {
+		t.Fatalf("AppendRunEvent: %v", err)
+	}
+
+	out, err := svc.ReplayRunWithModel(ctx, meta, sourceRunID, "openai/gpt-5-mini")
+	if err != nil {
+		t.Fatalf("ReplayRunWithModel: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.CancelRun(meta, out.RunID) })
+	if out.SourceRunID != sourceRunID || out.SourceThreadID != src.ThreadID || out.SourceModel != "openai/gpt-5" {
+		t.Fatalf("source side=%+v", out)
+	}
+	if out.ThreadID == src.ThreadID || out.RunID == "" || out.Model != "openai/gpt-5-mini" || out.ReplayedInputs != 2 {
+		t.Fatalf("replay side=%+v", out)
+	}
+
+	// Only the earlier user input is written up front; the run persists its own input.
+	history, err := db.ListHistoryLite(ctx, meta.EndpointID, out.ThreadID, 10)
+	if err != nil {
+		t.Fatalf("ListHistoryLite: %v", err)
+	}
+	if len(history) == 0 || history[0].Role != "user" || history[0].TextContent != "List the repo layout" {
+		t.Fatalf("replay history=%+v", history)
+	}
+	for _, m := range history {
+		if m.Role == "assistant" && m.TextContent == "It has cmd/ and internal/." {
+			t.Fatalf("assistant turn was copied into the replay thread")
+		}
+	}
+
+	events, err := db.ListRunEvents(ctx, meta.EndpointID, out.RunID, 100)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	var link map[string]any
+	for _, ev := range events {
+		if ev.EventType == runReplayedFromEventType {
+			_ = json.Unmarshal([]byte(ev.PayloadJSON), &link)
+		}
+	}
+	if link["source_run_id"] != sourceRunID || link["model"] != "openai/gpt-5-mini" {
+		t.Fatalf("run.replayed_from payload=%v", link)
+	}
+}
+
+func TestReplayRunWithModel_Rejections(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	ctx := context.Background()
+	meta := newThreadRunConcurrencyTestMeta("env_run_replay_reject")
+	if _, err := svc.ReplayRunWithModel(ctx, meta, "run_missing", "openai/gpt-5-mini"); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("missing run err=%v, want ErrRunNotFound", err)
+	}
+
+	th, err := svc.CreateThread(ctx, meta, "running", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	if err := svc.threadsDB.UpsertRun(ctx, threadstore.RunRecord{
+		RunID:      "run_replay_running",
+		EndpointID: meta.EndpointID,
+		ThreadID:   th.ThreadID,
+		State:      "running",
+	}); err != nil {
+		t.Fatalf("UpsertRun: %v", err)
+	}
+	if _, err := svc.ReplayRunWithModel(ctx, meta, "run_replay_running", "openai/gpt-5-mini"); err == nil {
+		t.Fatalf("unfinished run replay err=nil")
+	}
+	if _, err := svc.ReplayRunWithModel(ctx, meta, "run_replay_running", ""); err == nil {
+		t.Fatalf("empty model replay err=nil")
+	}
+}
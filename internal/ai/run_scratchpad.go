@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+)
+
+const (
+	// scratchpadMaxTotalBytes caps the summed value size of one thread's scratchpad.
+	scratchpadMaxTotalBytes = 64 * 1024
+	scratchpadMaxKeyRunes   = 128
+	// scratchpadListPreviewRunes bounds the value preview the list op returns per key.
+	scratchpadListPreviewRunes = 200
+)
+
+// toolScratchpad reads and writes the thread-scoped keyed notes behind the scratchpad tool.
+func (r *run) toolScratchpad(ctx context.Context, op string, key string, value string) (map[string]any, error) {
+	if r == nil || r.threadsDB == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	endpointID := strings.TrimSpace(r.endpointID)
+	threadID := strings.TrimSpace(r.threadID)
+	if endpointID == "" || threadID == "" {
+		return nil, errors.New("invalid thread context")
+	}
+	op = strings.ToLower(strings.TrimSpace(op))
+	key = strings.TrimSpace(key)
+	if op != "list" {
+		if key == "" {
+			return nil, errors.New("missing key")
+		}
+		if utf8.RuneCountInString(key) > scratchpadMaxKeyRunes {
+			return nil, fmt.Errorf("key exceeds %d characters", scratchpadMaxKeyRunes)
+		}
+	}
+
+	switch op {
+	case "set", "append":
+		rec := threadstore.ScratchpadEntry{
+			EndpointID:      endpointID,
+			ThreadID:        threadID,
+			Key:             key,
+			Value:           value,
+			UpdatedAtUnixMs: time.Now().UnixMilli(),
+			UpdatedByRunID:  strings.TrimSpace(r.id),
+		}
+		var (
+			saved threadstore.ScratchpadEntry
+			usage threadstore.ScratchpadUsage
+			err   error
+		)
+		if op == "append" {
+			saved, usage, err = r.threadsDB.AppendScratchpadEntry(ctx, rec, scratchpadMaxTotalBytes)
+		} else {
+			saved, usage, err = r.threadsDB.SetScratchpadEntry(ctx, rec, scratchpadMaxTotalBytes)
+		}
+		if err != nil {
+			if errors.Is(err, threadstore.ErrScratchpadFull) {
+				return nil, fmt.Errorf("scratchpad is limited to %d bytes per thread: overwrite existing keys with shorter values: %w", scratchpadMaxTotalBytes, err)
+			}
+			return nil, err
+		}
+		r.persistRunEvent("scratchpad.updated", RealtimeStreamKindTool, map[string]any{
+			"op":              op,
+			"key":             saved.Key,
+			"size_bytes":      saved.SizeBytes,
+			"key_count":       usage.KeyCount,
+			"total_bytes":     usage.TotalBytes,
+			"max_total_bytes": scratchpadMaxTotalBytes,
+		})
+		return map[string]any{
+			"op":              op,
+			"key":             saved.Key,
+			"size_bytes":      saved.SizeBytes,
+			"key_count":       usage.KeyCount,
+			"total_bytes":     usage.TotalBytes,
+			"max_total_bytes": scratchpadMaxTotalBytes,
+		}, nil
+
+	case "get":
+		rec, err := r.threadsDB.GetScratchpadEntry(ctx, endpointID, threadID, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			return map[string]any{"op": op, "key": key, "found": false}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"op":                 op,
+			"key":                rec.Key,
+			"found":              true,
+			"value":              rec.Value,
+			"size_bytes":         rec.SizeBytes,
+			"updated_at_unix_ms": rec.UpdatedAtUnixMs,
+		}, nil
+
+	case "list":
+		entries, err := r.threadsDB.ListScratchpadEntries(ctx, endpointID, threadID)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]map[string]any, 0, len(entries))
+		var totalBytes int64
+		for _, rec := range entries {
+			totalBytes += rec.SizeBytes
+			items = append(items, map[string]any{
+				"key":                rec.Key,
+				"size_bytes":         rec.SizeBytes,
+				"updated_at_unix_ms": rec.UpdatedAtUnixMs,
+				"preview":            truncateRunes(rec.Value, scratchpadListPreviewRunes),
+			})
+		}
+		return map[string]any{
+			"op":              op,
+			"entries":         items,
+			"key_count":       len(entries),
+			"total_bytes":     totalBytes,
+			"max_total_bytes": scratchpadMaxTotalBytes,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported op %q: use set, get, append, or list", op)
+	}
+}
+
+// hydrateScratchpadRuntimeState loads the thread's scratchpad usage for the runtime context.
+func (r *run) hydrateScratchpadRuntimeState(ctx context.Context, state *runtimeState) {
+	if state == nil || r == nil || r.threadsDB == nil {
+		return
+	}
+	endpointID := strings.TrimSpace(r.endpointID)
+	threadID := strings.TrimSpace(r.threadID)
+	if endpointID == "" || threadID == "" {
+		return
+	}
+	readCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	usage, err := r.threadsDB.GetScratchpadUsage(readCtx, endpointID, threadID)
+	if err != nil {
+		return
+	}
+	state.ScratchpadKeyCount = usage.KeyCount
+	state.ScratchpadTotalBytes = usage.TotalBytes
+}
+
+// updateScratchpadRuntimeState tracks scratchpad usage reported by successful scratchpad calls.
+func updateScratchpadRuntimeState(state *runtimeState, calls []ToolCall, results []ToolResult) {
+	if state == nil || len(results) == 0 {
+		return
+	}
+	callNameByID := make(map[string]string, len(calls))
+	for _, call := range calls {
+		callNameByID[strings.TrimSpace(call.ID)] = strings.TrimSpace(call.Name)
+	}
+	for _, result := range results {
+		toolName := strings.TrimSpace(result.ToolName)
+		if toolName == "" {
+			toolName = callNameByID[strings.TrimSpace(result.ToolID)]
+		}
+		if toolName != "scratchpad" || strings.TrimSpace(result.Status) != toolResultStatusSuccess {
+			continue
+		}
+		data, ok := result.Data.(map[string]any)
+		if !ok || data == nil {
+			continue
+		}
+		if _, ok := data["key_count"]; !ok {
+			continue
+		}
+		state.ScratchpadKeyCount = readAnyInt(data["key_count"])
+		state.ScratchpadTotalBytes = int64(readAnyInt(data["total_bytes"]))
+	}
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+)
+
+func TestToolScratchpad_SetAppendGetList(t *testing.T) {
+	t.Parallel()
+
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	r := &run{threadsDB: db, endpointID: "env_1", threadID: "th_1", id: "run_1"}
+	if _, err := r.toolScratchpad(ctx, "set", "root_cause", "nil map in "); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	out, err := r.toolScratchpad(ctx, "append", "root_cause", "config loader")
+	if err != nil || out["key_count"] != 1 || out["total_bytes"] != int64(len("nil map in config loader")) {
+		t.Fatalf("append out=%v err=%v", out, err)
+	}
+	out, err = r.toolScratchpad(ctx, "get", "root_cause", "")
+	if err != nil || out["found"] != true || out["value"] != "nil map in config loader" {
+		t.Fatalf("get out=%v err=%v", out, err)
+	}
+	out, err = r.toolScratchpad(ctx, "get", "missing", "")
+	if err != nil || out["found"] != false {
+		t.Fatalf("get missing out=%v err=%v", out, err)
+	}
+	out, err = r.toolScratchpad(ctx, "list", "", "")
+	if err != nil || out["key_count"] != 1 {
+		t.Fatalf("list out=%v err=%v", out, err)
+	}
+
+	// The scratchpad is thread-scoped.
+	other := &run{threadsDB: db, endpointID: "env_1", threadID: "th_2", id: "run_2"}
+	if out, err := other.toolScratchpad(ctx, "get", "root_cause", ""); err != nil || out["found"] != false {
+		t.Fatalf("other thread get out=%v err=%v", out, err)
+	}
+
+	if _, err := r.toolScratchpad(ctx, "set", "dump", strings.Repeat("x", scratchpadMaxTotalBytes)); !errors.Is(err, threadstore.ErrScratchpadFull) {
+		t.Fatalf("set past cap err=%v, want ErrScratchpadFull", err)
+	}
+	if _, err := r.toolScratchpad(ctx, "delete", "root_cause", ""); err == nil {
+		t.Fatalf("expected an unsupported op to be rejected")
+	}
+	if _, err := r.toolScratchpad(ctx, "set", "", "v"); err == nil {
+		t.Fatalf("expected a missing key to be rejected")
+	}
+
+	events, err := db.ListRunEvents(ctx, "env_1", "run_1", 10)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	if len(events) != 2 || events[0].EventType != "scratchpad.updated" || !strings.Contains(events[1].PayloadJSON, `"op":"append"`) {
+		t.Fatalf("events=%+v, want set and append scratchpad.updated events", events)
+	}
+}
+
+func TestUpdateScratchpadRuntimeState_TracksUsageInPrompt(t *testing.T) {
+	t.Parallel()
+
+	state := newRuntimeState("investigate")
+	updateScratchpadRuntimeState(&state, []ToolCall{{ID: "call_1", Name: "scratchpad"}}, []ToolResult{{
+		ToolID: "call_1",
+		Status: toolResultStatusSuccess,
+		Data:   map[string]any{"op": "set", "key_count": 3, "total_bytes": int64(420)},
+	}})
+	if state.ScratchpadKeyCount != 3 || state.ScratchpadTotalBytes != 420 {
+		t.Fatalf("state keys=%d bytes=%d, want 3/420", state.ScratchpadKeyCount, state.ScratchpadTotalBytes)
+	}
+	section := buildPromptRuntimeContextSection(promptRuntimeSnapshot{ScratchpadStatus: promptScratchpadStatus{KeyCount: 3, TotalBytes: 420}})
+	if !strings.Contains(section.render(), "- Scratchpad: keys=3,bytes=420/65536") {
+		t.Fatalf("runtime context missing scratchpad line:\n%s", section.render())
+	}
+}
@@ -74,7 +74,7 @@ func TestPrepareRun_InitializesActiveRunSnapshotImmediately(t *testing.T) {
 	}
 
 	runID := "run_prepare_immediate_snapshot"
-	prepared, err := svc.prepareRun(meta, runID, RunStartRequest{
+	prepared, err := svc.prepareRun(context.Background(), meta, runID, RunStartRequest{
 		ThreadID: thread.ThreadID,
 		Model:    "openai/gpt-5-mini",
 		Input:    RunInput{Text: "hello"},
@@ -159,7 +159,7 @@ func TestPrepareRun_PropagatesInternalReadonlyRunOptions(t *testing.T) {
 	}
 
 	runID := "run_prepare_internal_options"
-	prepared, err := svc.prepareRun(meta, runID, RunStartRequest{
+	prepared, err := svc.prepareRun(context.Background(), meta, runID, RunStartRequest{
 		ThreadID: thread.ThreadID,
 		Model:    "openai/gpt-5-mini",
 		Input:    RunInput{Text: "hello"},
@@ -0,0 +1,247 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/session"
+)
+
+// maxRunSystemPromptVersions caps the prompt versions stored per run; later changes are not recorded.
+const maxRunSystemPromptVersions = 64
+
+const runSystemPromptRedacted = "[redacted]"
+
+// runSystemPromptSecretPatterns match credentials that must never be served back, even to full-permission callers.
+var runSystemPromptSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/=-]{16,}`),
+	regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_\-]{16,}`),
+}
+
+// runSystemPromptInfo describes the run state a system prompt was composed for.
+type runSystemPromptInfo struct {
+	StepIndex        int
+	Mode             string
+	Complexity       string
+	ExceptionOverlay string
+}
+
+// runSystemPromptMeta is the JSON stored with each system prompt version.
+type runSystemPromptMeta struct {
+	Mode         string   `json:"mode,omitempty"`
+	Complexity   string   `json:"complexity,omitempty"`
+	ActiveSkills []string `json:"active_skills,omitempty"`
+	Overlays     []string `json:"overlays,omitempty"`
+}
+
+// RunSystemPromptVersion is one distinct system prompt a run sent, starting at StepIndex.
+type RunSystemPromptVersion struct {
+	Version         int      `json:"version"`
+	StepIndex       int      `json:"step_index"`
+	PromptHash      string   `json:"prompt_hash"`
+	Prompt          string   `json:"prompt"`
+	Mode            string   `json:"mode,omitempty"`
+	Complexity      string   `json:"complexity,omitempty"`
+	ActiveSkills    []string `json:"active_skills"`
+	Overlays        []string `json:"overlays"`
+	CreatedAtUnixMs int64    `json:"created_at_unix_ms,omitempty"`
+}
+
+// RunSystemPromptView lists the system prompts of a run in the order the model saw them.
+type RunSystemPromptView struct {
+	RunID    string                   `json:"run_id"`
+	ThreadID string                   `json:"thread_id"`
+	Prompts  []RunSystemPromptVersion `json:"prompts"`
+}
+
+// recordRunSystemPromptVersion stores prompt as a new version when it differs from the run's previous prompt.
+func (r *run) recordRunSystemPromptVersion(ctx context.Context, prompt string, info runSystemPromptInfo) {
+	sum := sha256.Sum256([]byte(prompt))
+	hash := hex.EncodeToString(sum[:])
+	if hash == r.lastSystemPromptHash || r.systemPromptVersions >= maxRunSystemPromptVersions {
+		return
+	}
+	prefix, suffix := 0, 0
+	body := prompt
+	if r.systemPromptVersions > 0 {
+		prefix, suffix = diffSystemPromptBounds(r.lastSystemPrompt, prompt)
+		body = prompt[prefix : len(prompt)-suffix]
+	}
+	skills := []string{}
+	for _, skill := range r.activeSkills() {
+		if name := strings.TrimSpace(skill.Name); name != "" {
+			skills = append(skills, name)
+		}
+	}
+	sort.Strings(skills)
+	metaJSON, _ := json.Marshal(runSystemPromptMeta{
+		Mode:         strings.TrimSpace(info.Mode),
+		Complexity:   strings.TrimSpace(info.Complexity),
+		ActiveSkills: skills,
+		Overlays:     runSystemPromptOverlays(r.promptProfile, info.ExceptionOverlay),
+	})
+	err := r.threadsDB.AppendRunSystemPrompt(ctx, threadstore.RunSystemPromptRecord{
+		EndpointID:  r.endpointID,
+		ThreadID:    r.threadID,
+		RunID:       r.id,
+		Version:     r.systemPromptVersions + 1,
+		StepIndex:   info.StepIndex,
+		PromptHash:  hash,
+		PrefixBytes: prefix,
+		SuffixBytes: suffix,
+		Body:        body,
+		MetaJSON:    string(metaJSON),
+	})
+	if err != nil {
+		// Retry on the next turn rather than storing a diff against a version that was never written.
+		return
+	}
+	r.systemPromptVersions++
+	r.lastSystemPromptHash = hash
+	r.lastSystemPrompt = prompt
+}
+
+// runSystemPromptOverlays names the overlays applied on top of the base prompt.
+func runSystemPromptOverlays(promptProfile string, exceptionOverlay string) []string {
+	out := []string{}
+	if id := strings.TrimSpace(promptProfile); id != "" && id != PromptProfileDefault {
+		out = append(out, "prompt_profile:"+id)
+	}
+	if strings.TrimSpace(exceptionOverlay) != "" {
+		out = append(out, "exception_overlay")
+	}
+	return out
+}
+
+// diffSystemPromptBounds returns the byte lengths of the common prefix and suffix of prev and next.
+// Both end on rune boundaries and never overlap within next.
+func diffSystemPromptBounds(prev string, next string) (int, int) {
+	limit := len(prev)
+	if len(next) < limit {
+		limit = len(next)
+	}
+	prefix := 0
+	for prefix < limit && prev[prefix] == next[prefix] {
+		prefix++
+	}
+	for prefix > 0 && prefix < len(next) && !utf8.RuneStart(next[prefix]) {
+		prefix--
+	}
+	suffix := 0
+	for suffix < limit-prefix && prev[len(prev)-1-suffix] == next[len(next)-1-suffix] {
+		suffix++
+	}
+	for suffix > 0 && !utf8.RuneStart(next[len(next)-suffix]) {
+		suffix--
+	}
+	return prefix, suffix
+}
+
+// GetRunSystemPrompt returns every distinct system prompt runID sent to its model.
+//
+// Runs recorded before prompt versions were stored return their first prompt without metadata.
+func (s *Service) GetRunSystemPrompt(ctx context.Context, meta *session.Meta, runID string) (*RunSystemPromptView, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	runID = strings.TrimSpace(runID)
+	if runID == "" {
+		return nil, errors.New("missing run_id")
+	}
+	endpointID := strings.TrimSpace(meta.EndpointID)
+
+	rec, err := db.GetRun(ctx, endpointID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, ErrRunNotFound
+	}
+	view := &RunSystemPromptView{RunID: rec.RunID, ThreadID: rec.ThreadID, Prompts: []RunSystemPromptVersion{}}
+
+	recs, err := db.ListRunSystemPrompts(ctx, endpointID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		prompt, err := db.GetRunSystemPrompt(ctx, endpointID, runID)
+		if err != nil {
+			return nil, err
+		}
+		if prompt != "" {
+			sum := sha256.Sum256([]byte(prompt))
+			view.Prompts = append(view.Prompts, RunSystemPromptVersion{
+				Version:      1,
+				PromptHash:   hex.EncodeToString(sum[:]),
+				Prompt:       redactRunSystemPromptSecrets(prompt),
+				ActiveSkills: []string{},
+				Overlays:     []string{},
+			})
+		}
+		return view, nil
+	}
+
+	prev := ""
+	for _, pr := range recs {
+		prompt, err := applySystemPromptVersion(prev, pr)
+		if err != nil {
+			return nil, err
+		}
+		prev = prompt
+		var pm runSystemPromptMeta
+		_ = json.Unmarshal([]byte(pr.MetaJSON), &pm)
+		if pm.ActiveSkills == nil {
+			pm.ActiveSkills = []string{}
+		}
+		if pm.Overlays == nil {
+			pm.Overlays = []string{}
+		}
+		view.Prompts = append(view.Prompts, RunSystemPromptVersion{
+			Version:         pr.Version,
+			StepIndex:       pr.StepIndex,
+			PromptHash:      pr.PromptHash,
+			Prompt:          redactRunSystemPromptSecrets(prompt),
+			Mode:            pm.Mode,
+			Complexity:      pm.Complexity,
+			ActiveSkills:    pm.ActiveSkills,
+			Overlays:        pm.Overlays,
+			CreatedAtUnixMs: pr.CreatedAtUnixMs,
+		})
+	}
+	return view, nil
+}
+
+// applySystemPromptVersion rebuilds the prompt of rec from the previous version's prompt.
+func applySystemPromptVersion(prev string, rec threadstore.RunSystemPromptRecord) (string, error) {
+	if rec.Version <= 1 {
+		return rec.Body, nil
+	}
+	if rec.PrefixBytes+rec.SuffixBytes > len(prev) {
+		return "", errors.New("corrupt system prompt version")
+	}
+	return prev[:rec.PrefixBytes] + rec.Body + prev[len(prev)-rec.SuffixBytes:], nil
+}
+
+func redactRunSystemPromptSecrets(prompt string) string {
+	for _, re := range runSystemPromptSecretPatterns {
+		prompt = re.ReplaceAllString(prompt, runSystemPromptRedacted)
+	}
+	return prompt
+}
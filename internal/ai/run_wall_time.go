@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"fmt"
+	"time"
+)
+
+const finalizationReasonWallTimeExceeded = "wall_time_exceeded"
+
+// runWallTimeSummaryGrace is the time a run keeps after its per-run wall time to finish the
+// in-flight step and produce the final summary, before the hard deadline cancels it.
+const runWallTimeSummaryGrace = 90 * time.Second
+
+// runTimeLimits are the effective time limits of one run.
+type runTimeLimits struct {
+	// MaxWallTime is the hard deadline that cancels the run.
+	MaxWallTime time.Duration
+	// WallTimeLimit is the per-run wall time that stops the loop with a final summary (0 when unset).
+	WallTimeLimit time.Duration
+	IdleTimeout   time.Duration
+}
+
+// resolveRunTimeLimits applies the per-run overrides in opts to the service-level limits.
+//
+// Overrides can only tighten the service limits. A per-run wall time keeps a short grace
+// period for the summary turn, still capped at the service-level maximum.
+func resolveRunTimeLimits(serviceMaxWall time.Duration, serviceIdle time.Duration, opts RunOptions) (runTimeLimits, error) {
+	limits := runTimeLimits{MaxWallTime: serviceMaxWall, IdleTimeout: serviceIdle}
+	if opts.MaxWallTimeMs < 0 {
+		return runTimeLimits{}, fmt.Errorf("invalid max_wall_time_ms %d", opts.MaxWallTimeMs)
+	}
+	if opts.MaxIdleTimeMs < 0 {
+		return runTimeLimits{}, fmt.Errorf("invalid max_idle_time_ms %d", opts.MaxIdleTimeMs)
+	}
+	if opts.MaxWallTimeMs > 0 {
+		wall := time.Duration(opts.MaxWallTimeMs) * time.Millisecond
+		if serviceMaxWall > 0 && wall > serviceMaxWall {
+			return runTimeLimits{}, fmt.Errorf("max_wall_time_ms %d exceeds the service limit of %d", opts.MaxWallTimeMs, serviceMaxWall.Milliseconds())
+		}
+		limits.WallTimeLimit = wall
+		limits.MaxWallTime = wall + runWallTimeSummaryGrace
+		if serviceMaxWall > 0 && limits.MaxWallTime > serviceMaxWall {
+			limits.MaxWallTime = serviceMaxWall
+		}
+	}
+	if opts.MaxIdleTimeMs > 0 {
+		idle := time.Duration(opts.MaxIdleTimeMs) * time.Millisecond
+		if serviceIdle > 0 && idle > serviceIdle {
+			return runTimeLimits{}, fmt.Errorf("max_idle_time_ms %d exceeds the service limit of %d", opts.MaxIdleTimeMs, serviceIdle.Milliseconds())
+		}
+		limits.IdleTimeout = idle
+	}
+	return limits, nil
+}
+
+// wallTimeExceeded reports whether the run is past its per-run wall time.
+func (r *run) wallTimeExceeded() bool {
+	return r != nil && !r.wallDeadline.IsZero() && !time.Now().Before(r.wallDeadline)
+}
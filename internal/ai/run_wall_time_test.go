@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveRunTimeLimits(t *testing.T) {
+	t.Parallel()
+
+	serviceWall := 15 * time.Minute
+	serviceIdle := 2 * time.Minute
+
+	limits, err := resolveRunTimeLimits(serviceWall, serviceIdle, RunOptions{})
+	if err != nil || limits != (runTimeLimits{MaxWallTime: serviceWall, IdleTimeout: serviceIdle}) {
+		t.Fatalf("no overrides limits=%+v err=%v, want the service limits", limits, err)
+	}
+
+	limits, err = resolveRunTimeLimits(serviceWall, serviceIdle, RunOptions{MaxWallTimeMs: 60_000, MaxIdleTimeMs: 30_000})
+	if err != nil {
+		t.Fatalf("resolveRunTimeLimits: %v", err)
+	}
+	if limits.WallTimeLimit != time.Minute || limits.MaxWallTime != time.Minute+runWallTimeSummaryGrace || limits.IdleTimeout != 30*time.Second {
+		t.Fatalf("limits=%+v, want a 1m wall time with summary grace and a 30s idle timeout", limits)
+	}
+
+	limits, err = resolveRunTimeLimits(serviceWall, serviceIdle, RunOptions{MaxWallTimeMs: serviceWall.Milliseconds()})
+	if err != nil || limits.WallTimeLimit != serviceWall || limits.MaxWallTime != serviceWall {
+		t.Fatalf("limits=%+v err=%v, want the hard deadline capped at the service limit", limits, err)
+	}
+
+	for _, opts := range []RunOptions{
+		{MaxWallTimeMs: serviceWall.Milliseconds() + 1},
+		{MaxIdleTimeMs: serviceIdle.Milliseconds() + 1},
+		{MaxWallTimeMs: -1},
+		{MaxIdleTimeMs: -1},
+	} {
+		if _, err := resolveRunTimeLimits(serviceWall, serviceIdle, opts); err == nil {
+			t.Fatalf("opts=%+v: expected the override to be rejected", opts)
+		}
+	}
+}
+
+func TestRunWallTimeExceeded(t *testing.T) {
+	t.Parallel()
+
+	r := &run{}
+	if r.wallTimeExceeded() {
+		t.Fatalf("run without a wall time limit reported exceeded")
+	}
+	r.wallDeadline = time.Now().Add(time.Minute)
+	if r.wallTimeExceeded() {
+		t.Fatalf("wall time reported exceeded before the deadline")
+	}
+	r.wallDeadline = time.Now().Add(-time.Millisecond)
+	if !r.wallTimeExceeded() {
+		t.Fatalf("wall time not reported exceeded after the deadline")
+	}
+}
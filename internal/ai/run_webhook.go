@@ -0,0 +1,254 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/floegence/redeven/internal/auditlog"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+const (
+	runWebhookEventRunEnd    = "run.end"
+	runWebhookAuditAction    = "ai_run_webhook_delivery"
+	runWebhookMaxAttempts    = 4
+	runWebhookRequestTimeout = 10 * time.Second
+	runWebhookBaseBackoff    = time.Second
+	runWebhookMaxBackoff     = 30 * time.Second
+
+	// runWebhookSignatureHeader carries "sha256=<hex HMAC-SHA256 of the body>" when a secret is set.
+	runWebhookSignatureHeader = "X-Redeven-Signature-256"
+)
+
+// RunWebhookUsage is the token usage reported in a run webhook.
+type RunWebhookUsage struct {
+	InputTokens     int64 `json:"input_tokens"`
+	OutputTokens    int64 `json:"output_tokens"`
+	ReasoningTokens int64 `json:"reasoning_tokens"`
+}
+
+// RunWebhookPayload is the body POSTed to a namespace's run webhook when a run ends.
+//
+// It never carries message content, tool output, or provider keys.
+type RunWebhookPayload struct {
+	Event              string          `json:"event"`
+	RunID              string          `json:"run_id"`
+	ThreadID           string          `json:"thread_id"`
+	EndpointID         string          `json:"endpoint_id"`
+	State              string          `json:"state"`
+	ErrorCode          string          `json:"error_code,omitempty"`
+	FinalizationReason string          `json:"finalization_reason,omitempty"`
+	DurationMs         int64           `json:"duration_ms"`
+	EndedAtUnixMs      int64           `json:"ended_at_unix_ms"`
+	Usage              RunWebhookUsage `json:"usage"`
+}
+
+// runWebhookNotifier delivers run webhooks in the background and records one audit entry per delivery.
+type runWebhookNotifier struct {
+	log           *slog.Logger
+	audit         *auditlog.Store
+	resolveSecret func(namespacePublicID string) (string, bool, error)
+	client        *http.Client
+	maxAttempts   int
+	backoff       func(attempt int) time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newRunWebhookNotifier(log *slog.Logger, audit *auditlog.Store, resolveSecret func(string) (string, bool, error)) *runWebhookNotifier {
+	if log == nil {
+		log = slog.Default()
+	}
+	if resolveSecret == nil {
+		resolveSecret = func(string) (string, bool, error) { return "", false, nil }
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &runWebhookNotifier{
+		log:           log,
+		audit:         audit,
+		resolveSecret: resolveSecret,
+		client:        &http.Client{Timeout: runWebhookRequestTimeout},
+		maxAttempts:   runWebhookMaxAttempts,
+		backoff:       runWebhookBackoff,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// runWebhookBackoff doubles the wait after each failed attempt, capped at runWebhookMaxBackoff.
+func runWebhookBackoff(attempt int) time.Duration {
+	d := runWebhookBaseBackoff
+	for i := 1; i < attempt && d < runWebhookMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > runWebhookMaxBackoff {
+		d = runWebhookMaxBackoff
+	}
+	return d
+}
+
+// Close stops pending retries and waits for in-flight deliveries.
+func (n *runWebhookNotifier) Close() {
+	if n == nil {
+		return
+	}
+	n.cancel()
+	n.wg.Wait()
+}
+
+// notify delivers payload to the webhook configured for meta's namespace, if any.
+func (n *runWebhookNotifier) notify(cfg *config.AIConfig, meta *session.Meta, payload RunWebhookPayload) {
+	if n == nil || meta == nil {
+		return
+	}
+	hook, ok := cfg.RunWebhookForNamespace(meta.NamespacePublicID)
+	if !ok {
+		return
+	}
+	metaCopy := *meta
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliver(hook, &metaCopy, payload)
+	}()
+}
+
+func (n *runWebhookNotifier) deliver(hook config.AIRunWebhook, meta *session.Meta, payload RunWebhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		n.appendAudit(meta, hook, payload, 0, 0, err)
+		return
+	}
+	signature := ""
+	secret, ok, err := n.resolveSecret(meta.NamespacePublicID)
+	if err != nil {
+		n.appendAudit(meta, hook, payload, 0, 0, fmt.Errorf("resolve webhook secret: %w", err))
+		return
+	}
+	if ok {
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write(body)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var (
+		lastErr    error
+		statusCode int
+		attempt    int
+	)
+	for attempt = 1; ; attempt++ {
+		statusCode, lastErr = n.post(hook.URL, body, signature, payload.RunID)
+		if lastErr == nil || attempt >= n.maxAttempts {
+			break
+		}
+		timer := time.NewTimer(n.backoff(attempt))
+		select {
+		case <-n.ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if n.ctx.Err() != nil {
+			break
+		}
+	}
+	if lastErr != nil {
+		n.log.Warn("ai run webhook delivery failed", "run_id", payload.RunID, "attempts", attempt, "error", sanitizeLogText(lastErr.Error(), 200))
+	}
+	n.appendAudit(meta, hook, payload, attempt, statusCode, lastErr)
+}
+
+func (n *runWebhookNotifier) post(target string, body []byte, signature string, runID string) (int, error) {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Redeven-Event", runWebhookEventRunEnd)
+	req.Header.Set("X-Redeven-Delivery", runID)
+	if signature != "" {
+		req.Header.Set(runWebhookSignatureHeader, signature)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (n *runWebhookNotifier) appendAudit(meta *session.Meta, hook config.AIRunWebhook, payload RunWebhookPayload, attempts int, statusCode int, err error) {
+	if n.audit == nil {
+		return
+	}
+	status := "success"
+	errText := ""
+	if err != nil {
+		status = "failure"
+		errText = sanitizeLogText(err.Error(), 200)
+	}
+	// Only the host is recorded: webhook URLs often carry tokens in the path or query.
+	host := ""
+	if u, parseErr := url.Parse(hook.URL); parseErr == nil {
+		host = u.Host
+	}
+	n.audit.Append(auditlog.Entry{
+		Action:            runWebhookAuditAction,
+		Status:            status,
+		Error:             errText,
+		ChannelID:         strings.TrimSpace(meta.ChannelID),
+		EnvPublicID:       strings.TrimSpace(meta.EndpointID),
+		NamespacePublicID: strings.TrimSpace(meta.NamespacePublicID),
+		UserPublicID:      strings.TrimSpace(meta.UserPublicID),
+		UserEmail:         strings.TrimSpace(meta.UserEmail),
+		FloeApp:           strings.TrimSpace(meta.FloeApp),
+		Detail: map[string]any{
+			"run_id":       payload.RunID,
+			"thread_id":    payload.ThreadID,
+			"state":        payload.State,
+			"webhook_host": host,
+			"attempts":     attempts,
+			"status_code":  statusCode,
+		},
+	})
+}
+
+// notifyRunWebhook sends the run.end webhook of a top-level run.
+func (r *run) notifyRunWebhook(state RunState, errCode string, finalizationReason string, startedAt time.Time) {
+	if r == nil || r.runWebhooks == nil {
+		return
+	}
+	endedAt := time.Now()
+	r.runWebhooks.notify(r.cfg, r.sessionMeta, RunWebhookPayload{
+		Event:              runWebhookEventRunEnd,
+		RunID:              r.id,
+		ThreadID:           r.threadID,
+		EndpointID:         r.endpointID,
+		State:              string(state),
+		ErrorCode:          errCode,
+		FinalizationReason: finalizationReason,
+		DurationMs:         endedAt.Sub(startedAt).Milliseconds(),
+		EndedAtUnixMs:      endedAt.UnixMilli(),
+		Usage: RunWebhookUsage{
+			InputTokens:     r.runtimeInputTokens.Load(),
+			OutputTokens:    r.runtimeOutputTokens.Load(),
+			ReasoningTokens: r.runtimeReasoningTokens.Load(),
+		},
+	})
+}
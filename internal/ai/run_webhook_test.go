@@ -0,0 +1,143 @@
+package ai
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/auditlog"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func newRunWebhookTestNotifier(t *testing.T, secret string) (*runWebhookNotifier, *auditlog.Store) {
+	t.Helper()
+	audit, err := auditlog.New(auditlog.Options{StateDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("auditlog.New: %v", err)
+	}
+	n := newRunWebhookNotifier(nil, audit, func(namespacePublicID string) (string, bool, error) {
+		if namespacePublicID != "ns_hooked" || secret == "" {
+			return "", false, nil
+		}
+		return secret, true, nil
+	})
+	n.backoff = func(int) time.Duration { return time.Millisecond }
+	t.Cleanup(n.Close)
+	return n, audit
+}
+
+func TestRunWebhookNotifier_RetriesAndSignsDelivery(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	var gotBody []byte
+	var gotSignature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(runWebhookSignatureHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	n, audit := newRunWebhookTestNotifier(t, "s3cret")
+	cfg := &config.AIConfig{RunWebhooks: map[string]config.AIRunWebhook{"ns_hooked": {URL: srv.URL + "/hook?token=abc"}}}
+	meta := &session.Meta{EndpointID: "env_1", NamespacePublicID: "ns_hooked"}
+	n.notify(cfg, meta, RunWebhookPayload{
+		Event:              runWebhookEventRunEnd,
+		RunID:              "run_1",
+		ThreadID:           "th_1",
+		EndpointID:         "env_1",
+		State:              string(RunStateSuccess),
+		FinalizationReason: "task_complete",
+		DurationMs:         1200,
+		Usage:              RunWebhookUsage{InputTokens: 10, OutputTokens: 5},
+	})
+	n.notify(cfg, &session.Meta{EndpointID: "env_1", NamespacePublicID: "ns_other"}, RunWebhookPayload{RunID: "run_2"})
+	n.wg.Wait()
+
+	if calls.Load() != 2 {
+		t.Fatalf("webhook calls=%d, want one failed attempt and one retry", calls.Load())
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	_, _ = mac.Write(gotBody)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); gotSignature != want {
+		t.Fatalf("signature=%q, want %q", gotSignature, want)
+	}
+	var payload RunWebhookPayload
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if payload.RunID != "run_1" || payload.State != "success" || payload.Usage.InputTokens != 10 {
+		t.Fatalf("payload=%+v", payload)
+	}
+
+	entries, err := audit.List(10)
+	if err != nil {
+		t.Fatalf("audit.List: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != runWebhookAuditAction || entries[0].Status != "success" || entries[0].NamespacePublicID != "ns_hooked" {
+		t.Fatalf("audit entries=%+v, want one successful delivery", entries)
+	}
+	if attempts, _ := entries[0].Detail["attempts"].(float64); attempts != 2 {
+		t.Fatalf("audit attempts=%v, want 2", entries[0].Detail["attempts"])
+	}
+	if host, _ := entries[0].Detail["webhook_host"].(string); host == "" || host == srv.URL {
+		t.Fatalf("audit webhook_host=%q, want the host only", host)
+	}
+}
+
+func TestRunWebhookNotifier_GivesUpAfterMaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get(runWebhookSignatureHeader) != "" {
+			t.Errorf("unexpected signature without a secret")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	n, audit := newRunWebhookTestNotifier(t, "")
+	cfg := &config.AIConfig{RunWebhooks: map[string]config.AIRunWebhook{"ns_hooked": {URL: srv.URL}}}
+	n.notify(cfg, &session.Meta{EndpointID: "env_1", NamespacePublicID: "ns_hooked"}, RunWebhookPayload{RunID: "run_1", State: string(RunStateFailed)})
+	n.wg.Wait()
+
+	if calls.Load() != runWebhookMaxAttempts {
+		t.Fatalf("webhook calls=%d, want %d", calls.Load(), runWebhookMaxAttempts)
+	}
+	entries, err := audit.List(10)
+	if err != nil {
+		t.Fatalf("audit.List: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Status != "failure" || entries[0].Error == "" {
+		t.Fatalf("audit entries=%+v, want one failed delivery", entries)
+	}
+}
+
+func TestRunWebhookBackoff(t *testing.T) {
+	t.Parallel()
+
+	if got := runWebhookBackoff(1); got != time.Second {
+		t.Fatalf("backoff(1)=%v", got)
+	}
+	if got := runWebhookBackoff(3); got != 4*time.Second {
+		t.Fatalf("backoff(3)=%v", got)
+	}
+	if got := runWebhookBackoff(20); got != runWebhookMaxBackoff {
+		t.Fatalf("backoff(20)=%v, want the cap", got)
+	}
+}
@@ -57,6 +57,9 @@ func (s *Service) SendUserTurn(ctx context.Context, meta *session.Meta, req Send
 	if endpointID == "" || threadID == "" {
 		return SendUserTurnResponse{}, errors.New("invalid request")
 	}
+	if err := ValidateRunOptions(req.Options); err != nil {
+		return SendUserTurnResponse{}, err
+	}
 	if s.threadMgr == nil {
 		return SendUserTurnResponse{}, errors.New("thread manager not ready")
 	}
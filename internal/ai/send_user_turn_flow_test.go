@@ -551,7 +551,7 @@ func TestExecutePreparedRun_WithPersistedUserMessage_ReusesPersistedMessageID(t
 		Options: RunOptions{MaxSteps: 1},
 	}
 
-	prepared, err := svc.prepareRun(meta, "run_prepersist_reuse_user_msg", req, nil, &persisted)
+	prepared, err := svc.prepareRun(context.Background(), meta, "run_prepersist_reuse_user_msg", req, nil, &persisted)
 	if err != nil {
 		t.Fatalf("prepareRun: %v", err)
 	}
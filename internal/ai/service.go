@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -24,17 +25,25 @@ import (
 	contextretriever "github.com/floegence/redeven/internal/ai/context/retriever"
 	contextstore "github.com/floegence/redeven/internal/ai/context/store"
 	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/auditlog"
 	"github.com/floegence/redeven/internal/config"
 	"github.com/floegence/redeven/internal/pathutil"
 	"github.com/floegence/redeven/internal/session"
+	"github.com/floegence/redeven/internal/settings"
+	"github.com/floegence/redeven/internal/websearch"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	ErrNotConfigured                      = errors.New("ai not configured")
 	ErrRunActive                          = errors.New("run already active")
 	ErrThreadBusy                         = errors.New("thread already active")
+	ErrThreadArchived                     = errors.New("thread_archived")
+	ErrThreadTitleLocked                  = errors.New("thread title set by user")
+	ErrThreadTitleChanged                 = errors.New("thread title changed during retitle")
 	ErrModelLockViolation                 = errors.New("model lock violation")
 	ErrModelSwitchRequiresExplicitRestart = errors.New("model switch requires explicit restart")
+	ErrModelNotAllowedForNamespace        = errors.New("model not allowed for namespace")
 )
 
 type Options struct {
@@ -43,6 +52,8 @@ type Options struct {
 
 	AgentHomeDir string
 	Shell        string
+	// AgentVersion is recorded in run export manifests.
+	AgentVersion string
 
 	Config *config.AIConfig
 
@@ -70,6 +81,10 @@ type Options struct {
 	// When zero, it defaults to 5 seconds.
 	StreamWriteTimeout time.Duration
 
+	// Secrets is the secrets backend that provider API keys, web search keys, and run webhook
+	// secrets are read from. The Resolve* hooks below take precedence when set.
+	Secrets settings.SecretsBackend
+
 	// ResolveProviderAPIKey returns the API key for the given provider id.
 	//
 	// It should read from a local secrets store, not from config.json.
@@ -79,6 +94,21 @@ type Options struct {
 	//
 	// It should read from a local secrets store, not from config.json.
 	ResolveWebSearchProviderAPIKey func(providerID string) (string, bool, error)
+
+	// ResolveRunWebhookSecret returns the HMAC secret that signs the run webhooks of a namespace.
+	//
+	// It should read from a local secrets store, not from config.json.
+	ResolveRunWebhookSecret func(namespacePublicID string) (string, bool, error)
+	// Audit records run webhook deliveries and terminal_command_policy matches. Optional.
+	Audit *auditlog.Store
+
+	// ProviderRecorder, when set, records every provider turn of this service's runs (see ai-loop-eval -record).
+	ProviderRecorder *ProviderRecorder
+	// ProviderSession labels recorded turns and selects the turns served by a "replay" provider.
+	ProviderSession string
+
+	// TracerProvider, when set, receives the run, turn, and tool spans instead of the ai.tracing exporter.
+	TracerProvider trace.TracerProvider
 }
 
 type Service struct {
@@ -87,6 +117,7 @@ type Service struct {
 	stateDir     string
 	agentHomeDir string
 	shell        string
+	agentVersion string
 
 	cfg *config.AIConfig
 
@@ -102,6 +133,10 @@ type Service struct {
 
 	mu                      sync.Mutex
 	activeRunByTh           map[string]string // <endpoint_id>:<thread_id> -> run_id
+	threadRunReleasedCh     map[string]chan struct{}
+	runSlotReleasedCh       chan struct{}   // closed when any active run is released; see ai.run_concurrency
+	compactingByTh          map[string]bool // <endpoint_id>:<thread_id> under manual compaction
+	archivedByTh            map[string]bool // <endpoint_id>:<thread_id> archived in this process; guards StartRun before the store write lands, dropped on delete
 	suppressQueuedDrainByTh map[string]bool
 	runs                    map[string]*run
 
@@ -115,16 +150,32 @@ type Service struct {
 	realtimeByThread    map[string]map[*rpc.Server]struct{} // <endpoint_id>:<thread_id> -> set(stream)
 	realtimeThreadBySRV map[*rpc.Server]string
 
+	runEvents runEventFeed
+
 	uploadsDir string
 	threadsDB  *threadstore.Store
 
-	contextRepo        *contextstore.Repository
-	contextRetriever   *contextretriever.Retriever
-	contextPacker      *contextpacker.Builder
-	memoryExtractor    *contextextractor.MemoryExtractor
-	snapshotCompactor  *contextcompactor.SnapshotCompactor
-	capabilityResolver *contextadapter.Resolver
-	skillManager       *skillManager
+	contextRepo         *contextstore.Repository
+	contextRetriever    *contextretriever.Retriever
+	contextPacker       *contextpacker.Builder
+	memoryExtractor     *contextextractor.MemoryExtractor
+	snapshotCompactor   *contextcompactor.SnapshotCompactor
+	capabilityResolver  *contextadapter.Resolver
+	skillManager        *skillManager
+	toolRateLimiter     *toolRateLimiter
+	webSearchCache      *websearch.Cache
+	providerRecorder    *ProviderRecorder
+	providerSession     string
+	providerReplays     *providerReplayTapes
+	providerCircuits    *providerCircuitBreakers
+	providerHTTPClients *providerHTTPClients
+	runWebhooks         *runWebhookNotifier
+	audit               *auditlog.Store
+	backgroundRuns      *backgroundRuns
+	metrics             *serviceMetrics
+	// tracer is nil when tracing is off, so runs skip every span.
+	tracer         trace.Tracer
+	tracerShutdown func(context.Context) error
 
 	threadTitleCoordinator *autoThreadTitleCoordinator
 	maintenanceStopCh      chan struct{}
@@ -158,6 +209,40 @@ func runThreadKey(endpointID string, threadID string) string {
 	return endpointID + ":" + threadID
 }
 
+// releaseActiveThreadRunLocked clears the thread's active run mapping and wakes queued run starts.
+//
+// Callers must hold s.mu.
+func (s *Service) releaseActiveThreadRunLocked(thKey string) {
+	delete(s.activeRunByTh, thKey)
+	s.notifyThreadRunReleasedLocked(thKey)
+	s.notifyRunSlotReleasedLocked()
+}
+
+// notifyThreadRunReleasedLocked wakes run starts queued on the thread.
+//
+// Callers must hold s.mu.
+func (s *Service) notifyThreadRunReleasedLocked(thKey string) {
+	if ch, ok := s.threadRunReleasedCh[thKey]; ok {
+		close(ch)
+		delete(s.threadRunReleasedCh, thKey)
+	}
+}
+
+// threadRunReleasedLocked returns a channel that is closed once the thread's active run is released.
+//
+// Callers must hold s.mu.
+func (s *Service) threadRunReleasedLocked(thKey string) <-chan struct{} {
+	if s.threadRunReleasedCh == nil {
+		s.threadRunReleasedCh = make(map[string]chan struct{})
+	}
+	ch, ok := s.threadRunReleasedCh[thKey]
+	if !ok {
+		ch = make(chan struct{})
+		s.threadRunReleasedCh[thKey] = ch
+	}
+	return ch
+}
+
 func NewService(opts Options) (*Service, error) {
 	if strings.TrimSpace(opts.StateDir) == "" {
 		return nil, errors.New("missing StateDir")
@@ -187,10 +272,22 @@ func NewService(opts Options) (*Service, error) {
 	}
 
 	resolveProviderKey := opts.ResolveProviderAPIKey
+	resolveWebSearchKey := opts.ResolveWebSearchProviderAPIKey
+	resolveRunWebhookSecret := opts.ResolveRunWebhookSecret
+	if opts.Secrets != nil {
+		if resolveProviderKey == nil {
+			resolveProviderKey = opts.Secrets.GetAIProviderAPIKey
+		}
+		if resolveWebSearchKey == nil {
+			resolveWebSearchKey = opts.Secrets.GetWebSearchProviderAPIKey
+		}
+		if resolveRunWebhookSecret == nil {
+			resolveRunWebhookSecret = opts.Secrets.GetAIRunWebhookSecret
+		}
+	}
 	if resolveProviderKey == nil {
 		resolveProviderKey = func(string) (string, bool, error) { return "", false, nil }
 	}
-	resolveWebSearchKey := opts.ResolveWebSearchProviderAPIKey
 	if resolveWebSearchKey == nil {
 		resolveWebSearchKey = func(string) (string, bool, error) { return "", false, nil }
 	}
@@ -227,6 +324,24 @@ func NewService(opts Options) (*Service, error) {
 		logger.Info("ai: reset stale active thread run states after restart", "count", resetCount)
 	}
 
+	tracerProvider := opts.TracerProvider
+	var tracerShutdown func(context.Context) error
+	if tracerProvider == nil {
+		tp, err := newTracerProvider(context.Background(), opts.Config)
+		if err != nil {
+			logger.Warn("ai: tracing disabled, exporter setup failed", "error", err)
+		} else if tp != nil {
+			tracerProvider = tp
+			tracerShutdown = tp.Shutdown
+		}
+	}
+	var tracer trace.Tracer
+	if tracerProvider != nil {
+		tracer = tracerProvider.Tracer(aiTracerName)
+	}
+	providerHTTP := newProviderHTTPClients()
+	providerHTTP.traceContext = tracer != nil
+
 	contextRepo := contextstore.NewRepository(ts)
 	snapshotCompactor := contextcompactor.New(contextRepo)
 	contextRetriever := contextretriever.New(contextRepo)
@@ -239,6 +354,7 @@ func NewService(opts Options) (*Service, error) {
 		stateDir:                     strings.TrimSpace(opts.StateDir),
 		agentHomeDir:                 agentHomeDir,
 		shell:                        strings.TrimSpace(opts.Shell),
+		agentVersion:                 strings.TrimSpace(opts.AgentVersion),
 		cfg:                          opts.Config,
 		persistOpTO:                  persistTO,
 		runMaxWallTime:               maxWall,
@@ -248,6 +364,9 @@ func NewService(opts Options) (*Service, error) {
 		resolveProviderKey:           resolveProviderKey,
 		resolveWebSearchKey:          resolveWebSearchKey,
 		activeRunByTh:                make(map[string]string),
+		threadRunReleasedCh:          make(map[string]chan struct{}),
+		compactingByTh:               make(map[string]bool),
+		archivedByTh:                 make(map[string]bool),
 		runs:                         make(map[string]*run),
 		realtimeWriters:              make(map[*rpc.Server]*aiSinkWriter),
 		realtimeSummaryByEndpoint:    make(map[string]map[*rpc.Server]struct{}),
@@ -264,6 +383,19 @@ func NewService(opts Options) (*Service, error) {
 		snapshotCompactor:            snapshotCompactor,
 		capabilityResolver:           capabilityResolver,
 		skillManager:                 newSkillManager(agentHomeDir, strings.TrimSpace(opts.StateDir)),
+		toolRateLimiter:              newToolRateLimiter(),
+		webSearchCache:               websearch.NewCache(),
+		providerRecorder:             opts.ProviderRecorder,
+		providerSession:              strings.TrimSpace(opts.ProviderSession),
+		providerReplays:              newProviderReplayTapes(),
+		providerCircuits:             newProviderCircuitBreakers(),
+		providerHTTPClients:          providerHTTP,
+		runWebhooks:                  newRunWebhookNotifier(logger, opts.Audit, resolveRunWebhookSecret),
+		audit:                        opts.Audit,
+		backgroundRuns:               newBackgroundRuns(),
+		metrics:                      newServiceMetrics(),
+		tracer:                       tracer,
+		tracerShutdown:               tracerShutdown,
 		maintenanceStopCh:            make(chan struct{}),
 		maintenanceDoneCh:            make(chan struct{}),
 	}
@@ -284,6 +416,10 @@ func (s *Service) Close() error {
 	if s == nil {
 		return nil
 	}
+	// Background runs are canceled first so they can still persist their end state.
+	if !s.backgroundRuns.shutdown(backgroundRunShutdownGrace) && s.log != nil {
+		s.log.Warn("ai background runs did not stop before shutdown grace elapsed")
+	}
 	if s.threadMgr != nil {
 		s.threadMgr.Close()
 	}
@@ -322,6 +458,14 @@ func (s *Service) Close() error {
 	for _, w := range writers {
 		w.Close()
 	}
+	s.runWebhooks.Close()
+	s.providerHTTPClients.reset()
+	if s.tracerShutdown != nil {
+		// Flush the spans of the runs that just ended.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		_ = s.tracerShutdown(shutdownCtx)
+		cancel()
+	}
 	if ts != nil {
 		return ts.Close()
 	}
@@ -364,6 +508,8 @@ func (s *Service) UpdateConfig(next *config.AIConfig, persist func() error) erro
 	s.cfg = next
 	coordinator := s.threadTitleCoordinator
 	s.mu.Unlock()
+	// Provider clients are rebuilt on next use so base URL and timeout changes apply to future runs.
+	s.providerHTTPClients.reset()
 	if coordinator != nil {
 		coordinator.Wake()
 	}
@@ -475,7 +621,32 @@ func (s *Service) HasActiveThreadForEndpoint(endpointID string, threadID string)
 	return strings.TrimSpace(s.activeRunByTh[k]) != ""
 }
 
-func (s *Service) ListModels() (*ModelsResponse, error) {
+// checkNamespaceModel rejects modelID when ai.model_allowlist does not allow it for the caller's namespace.
+func checkNamespaceModel(cfg *config.AIConfig, meta *session.Meta, modelID string) error {
+	if cfg == nil || meta == nil || len(cfg.ModelAllowlist) == 0 {
+		return nil
+	}
+	modelID = strings.TrimSpace(modelID)
+	namespace := strings.TrimSpace(meta.NamespacePublicID)
+	if modelID == "" || cfg.IsModelAllowedForNamespace(namespace, modelID) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s (namespace %s)", ErrModelNotAllowedForNamespace, modelID, namespace)
+}
+
+// CheckModelAllowed returns ErrModelNotAllowedForNamespace when meta's namespace may not use modelID.
+func (s *Service) CheckModelAllowed(meta *session.Meta, modelID string) error {
+	if s == nil {
+		return ErrNotConfigured
+	}
+	s.mu.Lock()
+	cfg := s.cfg
+	s.mu.Unlock()
+	return checkNamespaceModel(cfg, meta, modelID)
+}
+
+// ListModels returns the configured models that meta's namespace may use (all models when meta is nil).
+func (s *Service) ListModels(meta *session.Meta) (*ModelsResponse, error) {
 	if s == nil {
 		return nil, ErrNotConfigured
 	}
@@ -519,6 +690,14 @@ func (s *Service) ListModels() (*ModelsResponse, error) {
 				}
 			case "moonshot":
 				name = "Moonshot"
+			case "ollama":
+				name = "Ollama"
+			case "azure_openai":
+				name = "Azure OpenAI"
+			case "mistral":
+				name = "Mistral"
+			case "bedrock":
+				name = "AWS Bedrock"
 			}
 		}
 		if name == "" {
@@ -551,6 +730,9 @@ func (s *Service) ListModels() (*ModelsResponse, error) {
 			if _, ok := seenModel[id]; ok {
 				continue
 			}
+			if checkNamespaceModel(cfg, meta, id) != nil {
+				continue
+			}
 			seenModel[id] = struct{}{}
 			modelOrder = append(modelOrder, id)
 			if strings.TrimSpace(label) == "" {
@@ -565,7 +747,7 @@ func (s *Service) ListModels() (*ModelsResponse, error) {
 	}
 
 	currentModelID := strings.TrimSpace(cfg.CurrentModelID)
-	if !cfg.IsAllowedModelID(currentModelID) {
+	if !cfg.IsAllowedModelID(currentModelID) || checkNamespaceModel(cfg, meta, currentModelID) != nil {
 		currentModelID = modelOrder[0]
 	}
 	if currentModelID == "" {
@@ -638,16 +820,29 @@ func (s *Service) PatchSkillToggles(patches []SkillTogglePatch) (*SkillCatalog,
 	return &catalog, nil
 }
 
-func (s *Service) CreateSkill(scope string, name string, description string, body string) (*SkillCatalog, error) {
+func (s *Service) CreateSkill(scope string, name string, description string, body string) (*SkillCreateResult, error) {
 	mgr, err := s.skills()
 	if err != nil {
 		return nil, err
 	}
-	catalog, err := mgr.Create(scope, name, description, body)
+	out, err := mgr.Create(scope, name, description, body)
 	if err != nil {
 		return nil, err
 	}
-	return &catalog, nil
+	return &out, nil
+}
+
+// ValidateSkill lints the SKILL.md content body of skill name in scope, without installing it.
+func (s *Service) ValidateSkill(scope string, name string, body string) (*SkillValidationResult, error) {
+	mgr, err := s.skills()
+	if err != nil {
+		return nil, err
+	}
+	out, err := mgr.Validate(scope, name, body)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
 }
 
 func (s *Service) DeleteSkill(scope string, name string) (*SkillCatalog, error) {
@@ -686,6 +881,19 @@ func (s *Service) ValidateGitHubSkillImport(req SkillGitHubImportRequest) (*Skil
 	return &out, nil
 }
 
+// PreviewGitHubSkillImport is ValidateGitHubSkillImport plus a per-skill comparison with the installed version.
+func (s *Service) PreviewGitHubSkillImport(req SkillGitHubImportRequest) (*SkillGitHubValidateResult, error) {
+	mgr, err := s.skills()
+	if err != nil {
+		return nil, err
+	}
+	out, err := mgr.PreviewGitHubImport(req)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 func (s *Service) ImportGitHubSkills(req SkillGitHubImportRequest) (*SkillGitHubImportResult, error) {
 	mgr, err := s.skills()
 	if err != nil {
@@ -698,6 +906,28 @@ func (s *Service) ImportGitHubSkills(req SkillGitHubImportRequest) (*SkillGitHub
 	return &out, nil
 }
 
+// ExportSkillsArchive writes the local-scope skills to w as a gzipped tarball and returns the skill count.
+func (s *Service) ExportSkillsArchive(w io.Writer) (int, error) {
+	mgr, err := s.skills()
+	if err != nil {
+		return 0, err
+	}
+	return mgr.ExportArchive(w)
+}
+
+// ImportSkillsArchive installs the skills of a tarball produced by ExportSkillsArchive.
+func (s *Service) ImportSkillsArchive(r io.Reader, scope string, overwrite bool) (*SkillArchiveImportResult, error) {
+	mgr, err := s.skills()
+	if err != nil {
+		return nil, err
+	}
+	out, err := mgr.ImportArchive(r, scope, overwrite)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 func (s *Service) ListSkillSources() (*SkillSourcesView, error) {
 	mgr, err := s.skills()
 	if err != nil {
@@ -795,7 +1025,10 @@ func (s *Service) StartRun(ctx context.Context, meta *session.Meta, runID string
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	prepared, err := s.prepareRun(meta, runID, req, w, nil)
+	if req.Options.Background {
+		return s.startBackgroundRun(ctx, meta, runID, req)
+	}
+	prepared, err := s.prepareRun(ctx, meta, runID, req, w, nil)
 	if err != nil {
 		return err
 	}
@@ -803,7 +1036,7 @@ func (s *Service) StartRun(ctx context.Context, meta *session.Meta, runID string
 }
 
 func (s *Service) StartRunDetached(meta *session.Meta, runID string, req RunStartRequest) error {
-	prepared, err := s.prepareRun(meta, runID, req, nil, nil)
+	prepared, err := s.prepareRun(context.Background(), meta, runID, req, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -818,7 +1051,7 @@ func (s *Service) StartRunDetached(meta *session.Meta, runID string, req RunStar
 }
 
 func (s *Service) StartRunDetachedWithPersisted(meta *session.Meta, runID string, req RunStartRequest, persisted persistedUserMessage) error {
-	prepared, err := s.prepareRun(meta, runID, req, nil, &persisted)
+	prepared, err := s.prepareRun(context.Background(), meta, runID, req, nil, &persisted)
 	if err != nil {
 		return err
 	}
@@ -832,7 +1065,7 @@ func (s *Service) StartRunDetachedWithPersisted(meta *session.Meta, runID string
 	return nil
 }
 
-func (s *Service) prepareRun(meta *session.Meta, runID string, req RunStartRequest, w http.ResponseWriter, persisted *persistedUserMessage) (*preparedRun, error) {
+func (s *Service) prepareRun(ctx context.Context, meta *session.Meta, runID string, req RunStartRequest, w http.ResponseWriter, persisted *persistedUserMessage) (*preparedRun, error) {
 	if s == nil {
 		return nil, errors.New("nil service")
 	}
@@ -855,6 +1088,13 @@ func (s *Service) prepareRun(meta *session.Meta, runID string, req RunStartReque
 	if endpointID == "" {
 		return nil, errors.New("missing endpoint_id")
 	}
+	if err := ValidateRunOptions(req.Options); err != nil {
+		return nil, err
+	}
+	timeLimits, err := resolveRunTimeLimits(s.runMaxWallTime, s.runIdleTimeout, req.Options)
+	if err != nil {
+		return nil, err
+	}
 
 	metaCopy := *meta
 	metaRef := &metaCopy
@@ -871,14 +1111,83 @@ func (s *Service) prepareRun(meta *session.Meta, runID string, req RunStartReque
 		return nil, errors.New("threads store not ready")
 	}
 
-	pctx, cancelPersist := context.WithTimeout(context.Background(), persistTO)
-	th, err := db.GetThread(pctx, endpointID, threadID)
-	cancelPersist()
-	if err != nil {
-		return nil, err
+	thKey := runThreadKey(endpointID, threadID)
+	if thKey == "" {
+		return nil, errors.New("invalid request")
 	}
-	if th == nil {
-		return nil, errors.New("thread not found")
+
+	var (
+		th               *threadstore.Thread
+		queueDeadline    time.Time
+		runLimitDeadline time.Time
+	)
+	for {
+		pctx, cancelPersist := context.WithTimeout(context.Background(), persistTO)
+		var err error
+		th, err = db.GetThread(pctx, endpointID, threadID)
+		cancelPersist()
+		if err != nil {
+			return nil, err
+		}
+		if th == nil {
+			return nil, errors.New("thread not found")
+		}
+		if th.ArchivedAtUnixMs > 0 {
+			return nil, ErrThreadArchived
+		}
+
+		s.mu.Lock()
+		if s.cfg == nil {
+			s.mu.Unlock()
+			return nil, ErrNotConfigured
+		}
+		if s.archivedByTh[thKey] {
+			s.mu.Unlock()
+			return nil, ErrThreadArchived
+		}
+		// Reject a model outside the namespace allowlist before the run claims the thread or calls a provider.
+		runModelID := strings.TrimSpace(req.Model)
+		if runModelID == "" || th.ModelLocked {
+			runModelID = strings.TrimSpace(th.ModelID)
+		}
+		if runModelID == "" {
+			runModelID, _ = s.cfg.ResolvedCurrentModelIDForNamespace(metaRef.NamespacePublicID)
+		}
+		if err := checkNamespaceModel(s.cfg, metaRef, runModelID); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		if existing := strings.TrimSpace(s.activeRunByTh[thKey]); existing == "" && !s.compactingByTh[thKey] {
+			if s.runSlotAvailableLocked(metaRef.NamespacePublicID) {
+				// Keep s.mu held: the slot is claimed below.
+				break
+			}
+			if s.cfg.EffectiveRunLimitPolicy() != config.AIRunLimitQueue {
+				s.mu.Unlock()
+				return nil, ErrTooManyRuns
+			}
+			if runLimitDeadline.IsZero() {
+				runLimitDeadline = time.Now().Add(time.Duration(s.cfg.EffectiveRunLimitQueueTimeoutMS()) * time.Millisecond)
+			}
+			released := s.runSlotReleasedLocked()
+			s.mu.Unlock()
+			if err := waitRunSlotReleased(ctx, released, runLimitDeadline); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if s.cfg.EffectiveThreadBusyPolicy() != config.AIThreadBusyQueue {
+			s.mu.Unlock()
+			return nil, ErrThreadBusy
+		}
+		if queueDeadline.IsZero() {
+			queueDeadline = time.Now().Add(time.Duration(s.cfg.EffectiveThreadBusyQueueTimeoutMS()) * time.Millisecond)
+		}
+		released := s.threadRunReleasedLocked(thKey)
+		s.mu.Unlock()
+		if err := waitThreadRunReleased(ctx, released, queueDeadline); err != nil {
+			return nil, err
+		}
 	}
 
 	runWorkingDir := strings.TrimSpace(th.WorkingDir)
@@ -886,20 +1195,6 @@ func (s *Service) prepareRun(meta *session.Meta, runID string, req RunStartReque
 		runWorkingDir = strings.TrimSpace(s.agentHomeDir)
 	}
 
-	s.mu.Lock()
-	if s.cfg == nil {
-		s.mu.Unlock()
-		return nil, ErrNotConfigured
-	}
-	thKey := runThreadKey(endpointID, threadID)
-	if thKey == "" {
-		s.mu.Unlock()
-		return nil, errors.New("invalid request")
-	}
-	if existing := strings.TrimSpace(s.activeRunByTh[thKey]); existing != "" {
-		s.mu.Unlock()
-		return nil, ErrThreadBusy
-	}
 	cfg := s.cfg
 	req.Options.Mode = normalizeRunMode(strings.TrimSpace(th.ExecutionMode), cfg.EffectiveMode())
 	uploadsDir := s.uploadsDir
@@ -924,8 +1219,9 @@ func (s *Service) prepareRun(meta *session.Meta, runID string, req RunStartReque
 		ChannelID:           channelID,
 		EndpointID:          endpointID,
 		ThreadID:            threadID,
-		MaxWallTime:         s.runMaxWallTime,
-		IdleTimeout:         s.runIdleTimeout,
+		MaxWallTime:         timeLimits.MaxWallTime,
+		WallTimeLimit:       timeLimits.WallTimeLimit,
+		IdleTimeout:         timeLimits.IdleTimeout,
 		ToolApprovalTimeout: s.approvalTimeout,
 		StreamWriteTimeout:  s.streamWriteTO,
 		UserPublicID:        strings.TrimSpace(metaRef.UserPublicID),
@@ -934,6 +1230,18 @@ func (s *Service) prepareRun(meta *session.Meta, runID string, req RunStartReque
 		ThreadsDB:           db,
 		PersistOpTimeout:    persistTO,
 		SkillManager:        s.skillManager,
+		ToolRateLimiter:     s.toolRateLimiter,
+		WebSearchCache:      s.webSearchCache,
+		ProviderRecorder:    s.providerRecorder,
+		ProviderSession:     s.providerSession,
+		ProviderReplayTapes: s.providerReplays,
+		ProviderCircuits:    s.providerCircuits,
+		ProviderHTTPClients: s.providerHTTPClients,
+		RunWebhooks:         s.runWebhooks,
+		Audit:               s.audit,
+		Tracer:              s.tracer,
+		Metrics:             s.metrics,
+		OnRunEventPersisted: func() { s.runEvents.notify(runEventFeedKey(endpointID, runID)) },
 		ToolAllowlist:       append([]string(nil), req.Options.ToolAllowlist...),
 		ForceReadonlyExec:   req.Options.ForceReadonlyExec,
 		NoUserInteraction:   req.Options.NoUserInteraction,
@@ -1018,6 +1326,25 @@ func (s *Service) prepareRun(meta *session.Meta, runID string, req RunStartReque
 	}, nil
 }
 
+// waitThreadRunReleased blocks until the active run on a thread is released, the queue deadline passes,
+// or ctx is done, so a caller that goes away while queued never starts a run.
+func waitThreadRunReleased(ctx context.Context, released <-chan struct{}, deadline time.Time) error {
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		return ErrThreadBusy
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-released:
+		return nil
+	case <-timer.C:
+		return ErrThreadBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (s *Service) executePreparedRun(ctx context.Context, prepared *preparedRun) (retErr error) {
 	if s == nil {
 		return errors.New("nil service")
@@ -1073,15 +1400,16 @@ func (s *Service) executePreparedRun(ctx context.Context, prepared *preparedRun)
 		s.mu.Lock()
 		delete(s.runs, runID)
 		if strings.TrimSpace(s.activeRunByTh[thKey]) == runID {
-			delete(s.activeRunByTh, thKey)
+			s.releaseActiveThreadRunLocked(thKey)
 		}
 		s.mu.Unlock()
 		r.markDone()
 
+		runStatus, runStatusErr := deriveThreadRunState(r.getEndReason(), r.getFinalizationReason(), retErr)
+		s.metrics.recordRunEnd(runStatus)
 		if r.isDetached() {
 			return
 		}
-		runStatus, runStatusErr := deriveThreadRunState(r.getEndReason(), r.getFinalizationReason(), retErr)
 		waitingPrompt := finalWaitingPromptForRunState(runStatus, r.snapshotWaitingPrompt(), assistantJSON)
 		if prepared.updateThreadRunState != nil {
 			prepared.updateThreadRunState(runStatus, runStatusErr, waitingPrompt)
@@ -1093,6 +1421,7 @@ func (s *Service) executePreparedRun(ctx context.Context, prepared *preparedRun)
 		}
 	}()
 
+	applyRoutingRule(cfg, &req)
 	effectiveCurrentInput := deriveEffectiveCurrentUserInput(req.Input)
 	pctx, cancelPersist := context.WithTimeout(context.Background(), persistTO)
 	existingOpenGoal := ""
@@ -1135,8 +1464,25 @@ func (s *Service) executePreparedRun(ctx context.Context, prepared *preparedRun)
 		"thread_locked":   prepared.threadModelLocked,
 	})
 
+	attachmentLimitNote, err := r.applyRunAttachmentLimit(cfg, &req.Input)
+	if err != nil {
+		return streamEarlyError(err)
+	}
+	imageNote, err := s.preflightImageAttachments(ctx, r, cfg, model, modelCapability, &req.Input)
+	if err != nil {
+		return streamEarlyError(err)
+	}
+	if imageNote != "" {
+		attachmentLimitNote = strings.TrimSpace(attachmentLimitNote + "\n\n" + imageNote)
+	}
+
 	structuredResponseContinuation := req.Input.StructuredResponse != nil && strings.TrimSpace(existingOpenGoal) != ""
-	policyDecision := classifyRunPolicy(effectiveCurrentInput.PublicText, req.Input.Attachments, existingOpenGoal, structuredResponseContinuation, func() (runPolicyDecision, error) {
+	policyDecision := classifyRunPolicy(runPolicyInput{
+		Attachments:        req.Input.Attachments,
+		OpenGoal:           existingOpenGoal,
+		StructuredResponse: structuredResponseContinuation,
+		ForceIntent:        req.Options.ForceIntent,
+	}, func() (runPolicyDecision, error) {
 		decision, classifyErr := s.classifyRunPolicyByModel(ctx, resolvedModel, effectiveCurrentInput.PublicText, existingOpenGoal, structuredResponseContinuation)
 		if classifyErr != nil && r.log != nil {
 			r.log.Warn("model policy classification failed",
@@ -1194,6 +1540,7 @@ func (s *Service) executePreparedRun(ctx context.Context, prepared *preparedRun)
 		"objective_mode":                   policyDecision.ObjectiveMode,
 		"intent_source":                    policyDecision.Source,
 		"intent_reason":                    policyDecision.Reason,
+		"intent_forced":                    policyDecision.Source == RunIntentSourceForced,
 		"mode":                             req.Options.Mode,
 		"structured_response_continuation": structuredResponseContinuation,
 	})
@@ -1325,6 +1672,7 @@ func (s *Service) executePreparedRun(ctx context.Context, prepared *preparedRun)
 		ContextPack:         promptPack,
 		ModelCapability:     modelCapability,
 		InteractionContract: normalizeInteractionContract(policyDecision.InteractionContract),
+		AttachmentLimitNote: attachmentLimitNote,
 	}
 	runErr := r.run(ctx, runReq)
 	finalErr := runErr
@@ -1378,7 +1726,7 @@ func (s *Service) executePreparedRun(ctx context.Context, prepared *preparedRun)
 		CreatedAtUnixMs: assistantAt,
 		UpdatedAtUnixMs: assistantAt,
 		TextContent:     assistantText,
-		MessageJSON:     assistantJSON,
+		MessageJSON:     r.assistantMessageJSONForPersist(assistantJSON),
 	}, meta.UserPublicID, meta.UserEmail)
 	cancelPersist()
 	if err != nil {
@@ -1398,6 +1746,9 @@ func (s *Service) executePreparedRun(ctx context.Context, prepared *preparedRun)
 	}
 
 	finalReason := strings.TrimSpace(r.getFinalizationReason())
+	if classifyFinalizationReason(finalReason) == finalizationClassSuccess {
+		s.scheduleExchangeThreadTitle(meta, threadID, runID)
+	}
 	if db != nil {
 		continuationCtx, cancelContinuation := context.WithTimeout(context.Background(), persistTO)
 		continuationCandidate := r.getProviderContinuationCandidate()
@@ -1746,11 +2097,14 @@ func isFinalizingLifecycleStreamEvent(ev any) bool {
 	}
 }
 
+// CancelRun stops an in-flight run. The run ends in the canceled state and records a run.cancelled event.
+//
+// Pending tool approvals are released so the run loop does not sit in an approval wait.
 func (s *Service) CancelRun(meta *session.Meta, runID string) error {
 	if s == nil {
 		return errors.New("nil service")
 	}
-	if err := requireRWX(meta); err != nil {
+	if err := requireExecute(meta); err != nil {
 		return err
 	}
 	runID = strings.TrimSpace(runID)
@@ -1778,7 +2132,7 @@ func (s *Service) CancelRun(meta *session.Meta, runID string) error {
 		if strings.TrimSpace(rid) != runID {
 			continue
 		}
-		delete(s.activeRunByTh, k)
+		s.releaseActiveThreadRunLocked(k)
 		if threadID == "" && strings.HasPrefix(k, endpointID+":") {
 			threadID = strings.TrimSpace(strings.TrimPrefix(k, endpointID+":"))
 		}
@@ -1834,3 +2188,35 @@ func (s *Service) ApproveTool(meta *session.Meta, runID string, toolID string, a
 	}
 	return nil
 }
+
+// ProvideToolResult delivers the result of an external tool call (RunOptions.ExternalTools) and resumes the run.
+func (s *Service) ProvideToolResult(meta *session.Meta, runID string, toolID string, result ExternalToolResult) error {
+	if s == nil {
+		return errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return err
+	}
+	runID = strings.TrimSpace(runID)
+	toolID = strings.TrimSpace(toolID)
+	endpointID := strings.TrimSpace(meta.EndpointID)
+	userID := strings.TrimSpace(meta.UserPublicID)
+	if endpointID == "" || userID == "" || runID == "" || toolID == "" {
+		return errors.New("invalid request")
+	}
+
+	s.mu.Lock()
+	r := s.runs[runID]
+	s.mu.Unlock()
+	if r == nil || strings.TrimSpace(r.endpointID) != endpointID || r.isDetached() {
+		return errors.New("run not found")
+	}
+	// Like approvals, results are accepted only from the run starter.
+	if strings.TrimSpace(r.userPublicID) != userID {
+		return errors.New("run not found")
+	}
+	if err := r.provideToolResult(toolID, result); err != nil {
+		return fmt.Errorf("provide tool result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxMetricsToolLabels bounds the tool label of redeven_ai_tool_calls_total. External and MCP tool names
+// come from callers, so names past the limit are counted as "other".
+const maxMetricsToolLabels = 128
+
+const metricsToolLabelOther = "other"
+
+// serviceMetrics is the process-wide registry behind GET /_redeven_proxy/api/metrics.
+//
+// Labels only carry bounded values (run states, tool names, provider types, error classes, token kinds);
+// thread and run ids never become labels.
+type serviceMetrics struct {
+	mu             sync.Mutex
+	runsByState    map[string]int64
+	toolCalls      map[string]int64
+	providerErrors map[[2]string]int64 // {provider_type, error_type}
+	tokens         map[string]int64    // token kind -> total
+}
+
+func newServiceMetrics() *serviceMetrics {
+	return &serviceMetrics{
+		runsByState:    make(map[string]int64),
+		toolCalls:      make(map[string]int64),
+		providerErrors: make(map[[2]string]int64),
+		tokens:         make(map[string]int64),
+	}
+}
+
+func (m *serviceMetrics) recordRunEnd(state string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.runsByState[strings.TrimSpace(state)]++
+	m.mu.Unlock()
+}
+
+func (m *serviceMetrics) recordToolCall(toolName string) {
+	if m == nil {
+		return
+	}
+	toolName = strings.TrimSpace(toolName)
+	m.mu.Lock()
+	if _, ok := m.toolCalls[toolName]; !ok && len(m.toolCalls) >= maxMetricsToolLabels {
+		toolName = metricsToolLabelOther
+	}
+	m.toolCalls[toolName]++
+	m.mu.Unlock()
+}
+
+func (m *serviceMetrics) recordProviderError(providerType string, errorType string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.providerErrors[[2]string{strings.TrimSpace(providerType), errorType}]++
+	m.mu.Unlock()
+}
+
+func (m *serviceMetrics) recordTokens(usage TurnUsage) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.tokens["input"] += max(usage.InputTokens, 0)
+	m.tokens["output"] += max(usage.OutputTokens, 0)
+	m.tokens["reasoning"] += max(usage.ReasoningTokens, 0)
+	m.mu.Unlock()
+}
+
+// write renders the registry in the Prometheus text exposition format (version 0.0.4).
+func (m *serviceMetrics) write(w io.Writer, activeRuns int) error {
+	m.mu.Lock()
+	runsByState := copyMetricCounts(m.runsByState)
+	toolCalls := copyMetricCounts(m.toolCalls)
+	tokens := copyMetricCounts(m.tokens)
+	providerErrors := make(map[[2]string]int64, len(m.providerErrors))
+	for k, v := range m.providerErrors {
+		providerErrors[k] = v
+	}
+	m.mu.Unlock()
+
+	var b strings.Builder
+	writeMetricHeader(&b, "redeven_ai_runs_active", "gauge", "Runs currently executing.")
+	fmt.Fprintf(&b, "redeven_ai_runs_active %d\n", activeRuns)
+
+	writeMetricHeader(&b, "redeven_ai_runs_total", "counter", "Finished runs by end state.")
+	for _, state := range sortedMetricKeys(runsByState) {
+		fmt.Fprintf(&b, "redeven_ai_runs_total{state=\"%s\"} %d\n", escapeMetricLabel(state), runsByState[state])
+	}
+
+	writeMetricHeader(&b, "redeven_ai_tool_calls_total", "counter", "Dispatched tool calls by tool name.")
+	for _, tool := range sortedMetricKeys(toolCalls) {
+		fmt.Fprintf(&b, "redeven_ai_tool_calls_total{tool=\"%s\"} %d\n", escapeMetricLabel(tool), toolCalls[tool])
+	}
+
+	writeMetricHeader(&b, "redeven_ai_provider_errors_total", "counter", "Failed provider turns by provider type and error type.")
+	errorKeys := make([][2]string, 0, len(providerErrors))
+	for k := range providerErrors {
+		errorKeys = append(errorKeys, k)
+	}
+	sort.Slice(errorKeys, func(i, j int) bool {
+		if errorKeys[i][0] != errorKeys[j][0] {
+			return errorKeys[i][0] < errorKeys[j][0]
+		}
+		return errorKeys[i][1] < errorKeys[j][1]
+	})
+	for _, k := range errorKeys {
+		fmt.Fprintf(&b, "redeven_ai_provider_errors_total{provider_type=\"%s\",error_type=\"%s\"} %d\n", escapeMetricLabel(k[0]), escapeMetricLabel(k[1]), providerErrors[k])
+	}
+
+	writeMetricHeader(&b, "redeven_ai_tokens_total", "counter", "Provider-reported tokens by kind (input, output, reasoning).")
+	for _, kind := range sortedMetricKeys(tokens) {
+		fmt.Fprintf(&b, "redeven_ai_tokens_total{kind=\"%s\"} %d\n", escapeMetricLabel(kind), tokens[kind])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMetricHeader(b *strings.Builder, name string, kind string, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func copyMetricCounts(in map[string]int64) map[string]int64 {
+	out := make(map[string]int64, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+func sortedMetricKeys(in map[string]int64) []string {
+	keys := make([]string, 0, len(in))
+	for k := range in {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeMetricLabel(v string) string {
+	return metricLabelEscaper.Replace(v)
+}
+
+// metricsProvider counts failed turns and reported token usage for the metrics endpoint.
+type metricsProvider struct {
+	inner        Provider
+	metrics      *serviceMetrics
+	providerType string
+}
+
+func (p *metricsProvider) StreamTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	result, err := p.inner.StreamTurn(ctx, req, onEvent)
+	if err != nil {
+		// A canceled run is not a provider failure.
+		if ctx.Err() == nil {
+			p.metrics.recordProviderError(p.providerType, classifyProviderMetricError(ctx, err))
+		}
+		return result, err
+	}
+	p.metrics.recordTokens(result.Usage)
+	return result, err
+}
+
+// classifyProviderMetricError maps a failed turn onto the provider self-test error codes, plus stream_stall.
+func classifyProviderMetricError(ctx context.Context, err error) string {
+	if errors.Is(err, errProviderStreamStall) {
+		return "stream_stall"
+	}
+	status, _ := providerErrorHTTPStatus(err)
+	return classifyProviderTestError(ctx, err, status)
+}
+
+// WriteMetrics writes the runtime counters in the Prometheus text exposition format.
+func (s *Service) WriteMetrics(w io.Writer) error {
+	if s == nil {
+		return ErrNotConfigured
+	}
+	s.mu.Lock()
+	activeRuns := len(s.runs)
+	s.mu.Unlock()
+	return s.metrics.write(w, activeRuns)
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type metricsStubProvider struct {
+	result TurnResult
+	err    error
+}
+
+func (p *metricsStubProvider) StreamTurn(context.Context, TurnRequest, func(StreamEvent)) (TurnResult, error) {
+	return p.result, p.err
+}
+
+func TestServiceMetrics_PrometheusExposition(t *testing.T) {
+	t.Parallel()
+
+	m := newServiceMetrics()
+	m.recordRunEnd("success")
+	m.recordRunEnd("success")
+	m.recordRunEnd("failed")
+	m.recordToolCall("terminal.exec")
+	m.recordToolCall("terminal.exec")
+	m.recordToolCall(`odd"tool`)
+
+	ok := &metricsProvider{inner: &metricsStubProvider{result: TurnResult{Usage: TurnUsage{InputTokens: 100, OutputTokens: 20, ReasoningTokens: 5}}}, metrics: m, providerType: "openai"}
+	if _, err := ok.StreamTurn(context.Background(), TurnRequest{}, nil); err != nil {
+		t.Fatalf("StreamTurn: %v", err)
+	}
+	stalled := &metricsProvider{inner: &metricsStubProvider{err: fmt.Errorf("%w: no stream event within 1s", errProviderStreamStall)}, metrics: m, providerType: "anthropic"}
+	_, _ = stalled.StreamTurn(context.Background(), TurnRequest{}, nil)
+	unknown := &metricsProvider{inner: &metricsStubProvider{err: errors.New("boom")}, metrics: m, providerType: "anthropic"}
+	_, _ = unknown.StreamTurn(context.Background(), TurnRequest{}, nil)
+
+	// A canceled run does not count as a provider error.
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _ = unknown.StreamTurn(canceledCtx, TurnRequest{}, nil)
+
+	var b strings.Builder
+	if err := m.write(&b, 2); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE redeven_ai_runs_active gauge\nredeven_ai_runs_active 2\n",
+		"# TYPE redeven_ai_runs_total counter\n",
+		`redeven_ai_runs_total{state="failed"} 1` + "\n" + `redeven_ai_runs_total{state="success"} 2` + "\n",
+		`redeven_ai_tool_calls_total{tool="odd\"tool"} 1`,
+		`redeven_ai_tool_calls_total{tool="terminal.exec"} 2`,
+		`redeven_ai_provider_errors_total{provider_type="anthropic",error_type="stream_stall"} 1`,
+		`redeven_ai_provider_errors_total{provider_type="anthropic",error_type="unknown"} 1` + "\n",
+		`redeven_ai_tokens_total{kind="input"} 100`,
+		`redeven_ai_tokens_total{kind="output"} 20`,
+		`redeven_ai_tokens_total{kind="reasoning"} 5`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `provider_type="openai"`) {
+		t.Fatalf("successful turns must not count as errors:\n%s", out)
+	}
+}
+
+func TestServiceMetrics_BoundsToolLabels(t *testing.T) {
+	t.Parallel()
+
+	m := newServiceMetrics()
+	for i := 0; i < maxMetricsToolLabels+10; i++ {
+		m.recordToolCall(fmt.Sprintf("mcp.tool_%d", i))
+	}
+	m.recordToolCall("mcp.tool_0")
+	if len(m.toolCalls) != maxMetricsToolLabels+1 {
+		t.Fatalf("tool labels=%d, want %d", len(m.toolCalls), maxMetricsToolLabels+1)
+	}
+	if m.toolCalls[metricsToolLabelOther] != 10 || m.toolCalls["mcp.tool_0"] != 2 {
+		t.Fatalf("other=%d tool_0=%d", m.toolCalls[metricsToolLabelOther], m.toolCalls["mcp.tool_0"])
+	}
+}
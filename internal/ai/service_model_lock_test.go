@@ -39,7 +39,7 @@ func TestExecutePreparedRun_InitializesThreadModelLock(t *testing.T) {
 		t.Fatalf("CreateThread: %v", err)
 	}
 
-	prepared, err := svc.prepareRun(meta, "run_model_lock_init", RunStartRequest{
+	prepared, err := svc.prepareRun(context.Background(), meta, "run_model_lock_init", RunStartRequest{
 		ThreadID: th.ThreadID,
 		Model:    "openai/gpt-5-mini",
 		Input:    RunInput{Text: "initialize lock"},
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
 )
 
 func TestService_ListModels_CurrentFirstAndDedup(t *testing.T) {
@@ -35,7 +36,7 @@ func TestService_ListModels_CurrentFirstAndDedup(t *testing.T) {
 		},
 	}
 
-	out, err := svc.ListModels()
+	out, err := svc.ListModels(nil)
 	if err != nil {
 		t.Fatalf("ListModels: %v", err)
 	}
@@ -85,7 +86,7 @@ func TestService_ListModels_InvalidCurrentFallsBackToFirst(t *testing.T) {
 		},
 	}
 
-	out, err := svc.ListModels()
+	out, err := svc.ListModels(nil)
 	if err != nil {
 		t.Fatalf("ListModels: %v", err)
 	}
@@ -115,7 +116,7 @@ func TestService_ListModels_ProviderNameFallbackDoesNotExposeProviderID(t *testi
 		},
 	}
 
-	out, err := svc.ListModels()
+	out, err := svc.ListModels(nil)
 	if err != nil {
 		t.Fatalf("ListModels: %v", err)
 	}
@@ -131,6 +132,97 @@ func TestService_ListModels_ProviderNameFallbackDoesNotExposeProviderID(t *testi
 	}
 }
 
+func TestService_ListModels_FiltersByNamespaceAllowlist(t *testing.T) {
+	t.Parallel()
+
+	svc := &Service{
+		cfg: &config.AIConfig{
+			CurrentModelID: "openai/gpt-5-mini",
+			Providers: []config.AIProvider{
+				{
+					ID:     "openai",
+					Type:   "openai",
+					Models: []config.AIProviderModel{{ModelName: "gpt-5-mini"}, {ModelName: "gpt-4o-mini"}},
+				},
+				{
+					ID:     "anthropic",
+					Type:   "anthropic",
+					Models: []config.AIProviderModel{{ModelName: "claude-sonnet-4-5"}},
+				},
+			},
+			ModelAllowlist: map[string][]string{
+				"ns_restricted": {"anthropic/claude-sonnet-4-5", "openai/gpt-4o-mini"},
+			},
+		},
+	}
+
+	out, err := svc.ListModels(&session.Meta{NamespacePublicID: "ns_restricted"})
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	gotIDs := make([]string, 0, len(out.Models))
+	for _, m := range out.Models {
+		gotIDs = append(gotIDs, m.ID)
+	}
+	// The global current model is not allowed, so the first allowed model becomes current.
+	wantIDs := []string{"openai/gpt-4o-mini", "anthropic/claude-sonnet-4-5"}
+	if out.CurrentModel != "openai/gpt-4o-mini" || !reflect.DeepEqual(gotIDs, wantIDs) {
+		t.Fatalf("current=%q ids=%v, want %q %v", out.CurrentModel, gotIDs, "openai/gpt-4o-mini", wantIDs)
+	}
+
+	out, err = svc.ListModels(&session.Meta{NamespacePublicID: "ns_other"})
+	if err != nil {
+		t.Fatalf("ListModels other namespace: %v", err)
+	}
+	if len(out.Models) != 3 || out.CurrentModel != "openai/gpt-5-mini" {
+		t.Fatalf("namespace without allowlist must see every model, got current=%q models=%d", out.CurrentModel, len(out.Models))
+	}
+}
+
+func TestService_NamespaceModelAllowlistRejectsModels(t *testing.T) {
+	t.Parallel()
+
+	svc := newSendTurnTestService(t)
+	meta := testSendTurnMeta()
+	ctx := context.Background()
+	svc.mu.Lock()
+	next := *svc.cfg
+	next.ModelAllowlist = map[string][]string{meta.NamespacePublicID: {"openai/gpt-4o-mini"}}
+	svc.cfg = &next
+	svc.mu.Unlock()
+
+	if _, err := svc.CreateThread(ctx, meta, "blocked", "openai/gpt-5-mini", "", ""); !errors.Is(err, ErrModelNotAllowedForNamespace) {
+		t.Fatalf("CreateThread err=%v, want %v", err, ErrModelNotAllowedForNamespace)
+	}
+	th, err := svc.CreateThread(ctx, meta, "default model", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	if th.ModelID != "openai/gpt-4o-mini" {
+		t.Fatalf("thread model=%q, want the namespace default %q", th.ModelID, "openai/gpt-4o-mini")
+	}
+	if err := svc.SetThreadModel(ctx, meta, th.ThreadID, "openai/gpt-5-mini"); !errors.Is(err, ErrModelNotAllowedForNamespace) {
+		t.Fatalf("SetThreadModel err=%v, want %v", err, ErrModelNotAllowedForNamespace)
+	}
+	if _, err := svc.prepareRun(context.Background(), meta, "run_namespace_model_blocked", RunStartRequest{
+		ThreadID: th.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "hello"},
+		Options:  RunOptions{MaxSteps: 1},
+	}, nil, nil); !errors.Is(err, ErrModelNotAllowedForNamespace) {
+		t.Fatalf("prepareRun err=%v, want %v", err, ErrModelNotAllowedForNamespace)
+	}
+	if svc.HasActiveThreadForEndpoint(meta.EndpointID, th.ThreadID) {
+		t.Fatalf("rejected run must not claim the thread")
+	}
+
+	other := *meta
+	other.NamespacePublicID = "ns_unrestricted"
+	if err := svc.CheckModelAllowed(&other, "openai/gpt-5-mini"); err != nil {
+		t.Fatalf("namespace without allowlist rejected: %v", err)
+	}
+}
+
 func TestDeriveThreadRunState(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,322 @@
+package ai
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxSkillArchiveBytes bounds both the uploaded skills archive and its extracted content.
+const MaxSkillArchiveBytes = 16 << 20
+
+const (
+	skillArchiveManifestName  = "manifest.json"
+	skillArchiveSchemaVersion = 1
+	maxSkillArchiveEntries    = 2048
+)
+
+// SkillArchiveManifest is the manifest.json written at the root of an exported skills archive.
+//
+// The archive stores every skill as <scope>/<name>/..., so the manifest is informational: import reads
+// the skill files themselves and never trusts the manifest.
+type SkillArchiveManifest struct {
+	SchemaVersion    int                         `json:"schema_version"`
+	ExportedAtUnixMs int64                       `json:"exported_at_unix_ms"`
+	Skills           []SkillArchiveManifestEntry `json:"skills"`
+}
+
+type SkillArchiveManifestEntry struct {
+	Name        string `json:"name"`
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+	Path        string `json:"path"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type SkillArchiveImportResult struct {
+	Catalog SkillCatalog             `json:"catalog"`
+	Imports []SkillArchiveImportItem `json:"imports"`
+}
+
+type SkillArchiveImportItem struct {
+	Name       string          `json:"name"`
+	Scope      string          `json:"scope"`
+	SkillPath  string          `json:"skill_path"`
+	SourceType SkillSourceType `json:"source_type"`
+	SourceID   string          `json:"source_id"`
+	// Warnings are the validation warnings of the imported SKILL.md.
+	Warnings []SkillValidationIssue `json:"warnings,omitempty"`
+}
+
+// ExportArchive writes the local-scope skills as a gzipped tarball to w and returns the number of skills.
+func (m *skillManager) ExportArchive(w io.Writer) (int, error) {
+	if m == nil {
+		return 0, newSkillError(ErrCodeAISkillsInternal, http.StatusServiceUnavailable, "skill manager unavailable", nil)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.discoverLocked()
+
+	manifest := SkillArchiveManifest{SchemaVersion: skillArchiveSchemaVersion, ExportedAtUnixMs: time.Now().UnixMilli()}
+	for _, entry := range m.catalogEntries {
+		manifest.Skills = append(manifest.Skills, SkillArchiveManifestEntry{
+			Name:        entry.Name,
+			Scope:       entry.Scope,
+			Description: entry.Description,
+			Path:        path.Join(entry.Scope, entry.Name),
+			Enabled:     entry.Enabled,
+		})
+	}
+	sort.Slice(manifest.Skills, func(i, j int) bool { return manifest.Skills[i].Path < manifest.Skills[j].Path })
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to encode archive manifest", err)
+	}
+	if err := writeSkillArchiveFile(tw, skillArchiveManifestName, manifestJSON); err != nil {
+		return 0, err
+	}
+	byPath := make(map[string]SkillCatalogEntry, len(m.catalogEntries))
+	for _, entry := range m.catalogEntries {
+		byPath[path.Join(entry.Scope, entry.Name)] = entry
+	}
+	for _, item := range manifest.Skills {
+		if err := writeSkillArchiveDir(tw, item.Path, filepath.Dir(byPath[item.Path].Path)); err != nil {
+			return 0, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return 0, newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to finish skills archive", err)
+	}
+	if err := gz.Close(); err != nil {
+		return 0, newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to finish skills archive", err)
+	}
+	return len(manifest.Skills), nil
+}
+
+func writeSkillArchiveDir(tw *tar.Writer, prefix string, dir string) error {
+	return filepath.WalkDir(dir, func(pathAbs string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to read skill directory", walkErr)
+		}
+		if !d.Type().IsRegular() {
+			// Directories are implied by file entries; symlinks and special files are not exported.
+			return nil
+		}
+		rel, err := filepath.Rel(dir, pathAbs)
+		if err != nil {
+			return newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to resolve skill file", err)
+		}
+		data, err := os.ReadFile(pathAbs)
+		if err != nil {
+			return newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to read skill file", err)
+		}
+		return writeSkillArchiveFile(tw, path.Join(prefix, filepath.ToSlash(rel)), data)
+	})
+}
+
+func writeSkillArchiveFile(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		Typeflag: tar.TypeReg,
+		ModTime:  time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to write skills archive", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to write skills archive", err)
+	}
+	return nil
+}
+
+// ImportArchive installs the skills of a gzipped tarball laid out as <scope>/<name>/...
+//
+// A non-empty scope installs every skill into that scope instead of the one recorded in the archive.
+// Every skill is validated the same way as Create before any is installed, so one broken skill imports nothing.
+func (m *skillManager) ImportArchive(r io.Reader, scope string, overwrite bool) (SkillArchiveImportResult, error) {
+	if m == nil {
+		return SkillArchiveImportResult{}, newSkillError(ErrCodeAISkillsInternal, http.StatusServiceUnavailable, "skill manager unavailable", nil)
+	}
+	scope = strings.TrimSpace(strings.ToLower(scope))
+
+	tmpRoot, err := os.MkdirTemp("", "redeven-skill-archive-*")
+	if err != nil {
+		return SkillArchiveImportResult{}, newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to allocate temp dir", err)
+	}
+	defer os.RemoveAll(tmpRoot)
+	extractRoot := filepath.Join(tmpRoot, "skills")
+	found, err := extractSkillArchive(r, extractRoot)
+	if err != nil {
+		return SkillArchiveImportResult{}, err
+	}
+	if len(found) == 0 {
+		return SkillArchiveImportResult{}, newSkillError(ErrCodeAISkillsArchiveInvalid, http.StatusUnprocessableEntity, "archive contains no skills", nil)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.discoverLocked()
+
+	type pending struct {
+		name     string
+		scope    string
+		srcDir   string
+		target   string
+		warnings []SkillValidationIssue
+	}
+	items := make([]pending, 0, len(found))
+	seen := make(map[string]bool, len(found))
+	for _, key := range found {
+		archiveScope, name, _ := strings.Cut(key, "/")
+		targetScope := archiveScope
+		if scope != "" {
+			targetScope = scope
+		}
+		root, err := m.scopeRootLocked(targetScope)
+		if err != nil {
+			return SkillArchiveImportResult{}, newSkillError(ErrCodeAISkillsInvalidScope, http.StatusBadRequest, err.Error(), nil)
+		}
+		target := filepath.Join(root, name)
+		if seen[target] {
+			return SkillArchiveImportResult{}, newSkillError(ErrCodeAISkillsArchiveInvalid, http.StatusUnprocessableEntity, fmt.Sprintf("archive contains skill %s more than once for scope %s", name, targetScope), nil)
+		}
+		seen[target] = true
+		srcDir := filepath.Join(extractRoot, archiveScope, name)
+		content, err := os.ReadFile(filepath.Join(srcDir, "SKILL.md"))
+		if err != nil {
+			return SkillArchiveImportResult{}, newSkillError(ErrCodeAISkillsArchiveInvalid, http.StatusUnprocessableEntity, fmt.Sprintf("archive skill %s has no SKILL.md", key), err)
+		}
+		res := lintSkillContent(targetScope, name, string(content))
+		if err := skillValidationError(name, res); err != nil {
+			return SkillArchiveImportResult{}, err
+		}
+		if _, err := os.Stat(target); err == nil && !overwrite {
+			return SkillArchiveImportResult{}, newSkillError(ErrCodeAISkillsSkillExists, http.StatusConflict, fmt.Sprintf("skill already exists: %s", name), nil)
+		}
+		items = append(items, pending{name: name, scope: targetScope, srcDir: srcDir, target: target, warnings: res.Warnings})
+	}
+
+	imports := make([]SkillArchiveImportItem, 0, len(items))
+	for _, item := range items {
+		if err := m.installOneSkillLocked(item.srcDir, item.target, overwrite); err != nil {
+			return SkillArchiveImportResult{}, err
+		}
+		skillPath := filepath.Clean(filepath.Join(item.target, "SKILL.md"))
+		sourceID := "archive:" + item.scope + ":" + item.name
+		now := time.Now().UnixMilli()
+		m.sources[skillPath] = SkillSourceRecord{
+			SkillPath:           skillPath,
+			SourceType:          SkillSourceTypeArchive,
+			SourceID:            sourceID,
+			InstalledAtUnixMs:   now,
+			LastCheckedAtUnixMs: now,
+		}
+		imports = append(imports, SkillArchiveImportItem{
+			Name:       item.name,
+			Scope:      item.scope,
+			SkillPath:  skillPath,
+			SourceType: SkillSourceTypeArchive,
+			SourceID:   sourceID,
+			Warnings:   item.warnings,
+		})
+	}
+	if err := m.saveSourcesLocked(); err != nil {
+		return SkillArchiveImportResult{}, newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to persist skill source metadata", err)
+	}
+	m.discoverLocked()
+	return SkillArchiveImportResult{Catalog: m.catalogLocked(), Imports: imports}, nil
+}
+
+// extractSkillArchive unpacks the regular files of a gzipped tarball under dst and returns the sorted
+// "<scope>/<name>" keys of the skills it contains. Entries that escape dst, links, and special files are
+// rejected, and the extracted size is capped at MaxSkillArchiveBytes.
+func extractSkillArchive(r io.Reader, dst string) ([]string, error) {
+	gz, err := gzip.NewReader(io.LimitReader(r, MaxSkillArchiveBytes+1))
+	if err != nil {
+		return nil, newSkillError(ErrCodeAISkillsArchiveInvalid, http.StatusUnprocessableEntity, "archive is not gzip", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var total int64
+	entries := 0
+	keys := map[string]bool{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, newSkillError(ErrCodeAISkillsArchiveInvalid, http.StatusUnprocessableEntity, "invalid skills archive", err)
+		}
+		entries++
+		if entries > maxSkillArchiveEntries {
+			return nil, newSkillError(ErrCodeAISkillsArchiveInvalid, http.StatusUnprocessableEntity, fmt.Sprintf("archive has more than %d entries", maxSkillArchiveEntries), nil)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, newSkillError(ErrCodeAISkillsArchiveInvalid, http.StatusUnprocessableEntity, fmt.Sprintf("archive entry %s is not a regular file", hdr.Name), nil)
+		}
+		rel, err := normalizeSkillRelativePath(hdr.Name, false)
+		if err != nil {
+			return nil, newSkillError(ErrCodeAISkillsPathEscape, http.StatusUnprocessableEntity, fmt.Sprintf("archive entry escapes the archive root: %s", hdr.Name), err)
+		}
+		if rel == skillArchiveManifestName {
+			continue
+		}
+		parts := strings.SplitN(rel, "/", 3)
+		if len(parts) < 3 || !skillNameRE.MatchString(parts[1]) {
+			return nil, newSkillError(ErrCodeAISkillsArchiveInvalid, http.StatusUnprocessableEntity, fmt.Sprintf("archive entry %s is not under <scope>/<skill>/", hdr.Name), nil)
+		}
+		switch parts[0] {
+		case "user", "user_agents":
+		default:
+			return nil, newSkillError(ErrCodeAISkillsInvalidScope, http.StatusUnprocessableEntity, fmt.Sprintf("archive entry %s has an invalid scope", hdr.Name), nil)
+		}
+		total += hdr.Size
+		if hdr.Size < 0 || total > MaxSkillArchiveBytes {
+			return nil, newSkillError(ErrCodeAISkillsFileTooLarge, http.StatusRequestEntityTooLarge, fmt.Sprintf("archive content exceeds %d bytes", MaxSkillArchiveBytes), nil)
+		}
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		if err := ensurePathWithinRoot(dst, target); err != nil {
+			return nil, newSkillError(ErrCodeAISkillsPathEscape, http.StatusUnprocessableEntity, "archive target escapes destination", err)
+		}
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, tr, hdr.Size); err != nil {
+			return nil, newSkillError(ErrCodeAISkillsArchiveInvalid, http.StatusUnprocessableEntity, "failed to extract archive entry", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to prepare target file directory", err)
+		}
+		if err := os.WriteFile(target, buf.Bytes(), 0o600); err != nil {
+			return nil, newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to write extracted skill file", err)
+		}
+		keys[parts[0]+"/"+parts[1]] = true
+	}
+	out := make([]string, 0, len(keys))
+	for key := range keys {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out, nil
+}
@@ -0,0 +1,126 @@
+package ai
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newSkillArchiveTestManager(t *testing.T) (*skillManager, string) {
+	t.Helper()
+	home := t.TempDir()
+	mgr := newSkillManager(home, t.TempDir())
+	mgr.userHome = home
+	mgr.Discover()
+	return mgr, home
+}
+
+func buildSkillArchive(t *testing.T, files map[string]string, extra ...*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	for _, hdr := range extra {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSkillManager_ArchiveExportImportRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	src, _ := newSkillArchiveTestManager(t)
+	if _, err := src.Create("user", "review", "Review pull requests", "# Review\n\nRead the diff first."); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := src.Create("user_agents", "deploy", "Deploy the service", "# Deploy"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	var archive bytes.Buffer
+	count, err := src.ExportArchive(&archive)
+	if err != nil || count != 2 {
+		t.Fatalf("ExportArchive count=%d err=%v", count, err)
+	}
+
+	dst, home := newSkillArchiveTestManager(t)
+	out, err := dst.ImportArchive(bytes.NewReader(archive.Bytes()), "", false)
+	if err != nil {
+		t.Fatalf("ImportArchive: %v", err)
+	}
+	if len(out.Imports) != 2 || out.Catalog.CatalogVersion == 0 || len(out.Catalog.Skills) != 2 {
+		t.Fatalf("import result=%+v", out)
+	}
+	if out.Imports[0].SourceType != SkillSourceTypeArchive || out.Imports[0].Scope != "user" || out.Imports[1].Scope != "user_agents" {
+		t.Fatalf("imports=%+v", out.Imports)
+	}
+	body, err := os.ReadFile(filepath.Join(home, ".redeven", "skills", "review", "SKILL.md"))
+	if err != nil || !strings.Contains(string(body), "Read the diff first.") {
+		t.Fatalf("imported body=%q err=%v", body, err)
+	}
+
+	if _, err := dst.ImportArchive(bytes.NewReader(archive.Bytes()), "", false); SkillErrorCode(err) != ErrCodeAISkillsSkillExists {
+		t.Fatalf("re-import err=%v, want skill exists", err)
+	}
+	if _, err := dst.ImportArchive(bytes.NewReader(archive.Bytes()), "", true); err != nil {
+		t.Fatalf("overwrite import: %v", err)
+	}
+	remapped, err := dst.ImportArchive(bytes.NewReader(archive.Bytes()), "user", true)
+	if err != nil {
+		t.Fatalf("scope override import: %v", err)
+	}
+	for _, item := range remapped.Imports {
+		if item.Scope != "user" {
+			t.Fatalf("scope override ignored: %+v", item)
+		}
+	}
+}
+
+func TestSkillManager_ArchiveImportRejectsUnsafeArchives(t *testing.T) {
+	t.Parallel()
+
+	valid := "---\nname: safe\ndescription: Safe skill\n---\n\n# Safe\n"
+	cases := []struct {
+		name  string
+		input []byte
+		code  string
+	}{
+		{"not gzip", []byte("plain text"), ErrCodeAISkillsArchiveInvalid},
+		{"path traversal", buildSkillArchive(t, map[string]string{"user/safe/SKILL.md": valid, "user/safe/../../../../etc/passwd": "x"}), ErrCodeAISkillsPathEscape},
+		{"absolute path", buildSkillArchive(t, map[string]string{"/user/safe/SKILL.md": valid}), ErrCodeAISkillsPathEscape},
+		{"symlink", buildSkillArchive(t, map[string]string{"user/safe/SKILL.md": valid}, &tar.Header{Name: "user/safe/link", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink}), ErrCodeAISkillsArchiveInvalid},
+		{"unknown scope", buildSkillArchive(t, map[string]string{"system/safe/SKILL.md": valid}), ErrCodeAISkillsInvalidScope},
+		{"oversized", buildSkillArchive(t, map[string]string{"user/safe/SKILL.md": valid, "user/safe/big.bin": strings.Repeat("a", MaxSkillArchiveBytes)}), ErrCodeAISkillsFileTooLarge},
+		{"invalid skill", buildSkillArchive(t, map[string]string{"user/broken/SKILL.md": "---\nname: broken\n---\n\nno description"}), ErrCodeAISkillsValidationFailed},
+		{"empty", buildSkillArchive(t, map[string]string{"manifest.json": "{}"}), ErrCodeAISkillsArchiveInvalid},
+	}
+	for _, tc := range cases {
+		mgr, home := newSkillArchiveTestManager(t)
+		_, err := mgr.ImportArchive(bytes.NewReader(tc.input), "", false)
+		if got := SkillErrorCode(err); got != tc.code {
+			t.Fatalf("%s: code=%q err=%v, want %q", tc.name, got, err, tc.code)
+		}
+		if entries, _ := os.ReadDir(filepath.Join(home, ".redeven", "skills")); len(entries) != 0 {
+			t.Fatalf("%s: rejected archive installed %d skills", tc.name, len(entries))
+		}
+	}
+}
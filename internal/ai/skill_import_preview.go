@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"net/http"
+	"os"
+	"path"
+)
+
+const (
+	SkillImportPreviewNew       = "new"
+	SkillImportPreviewIdentical = "identical"
+	SkillImportPreviewModified  = "modified"
+)
+
+// skillImportPreviewMaxDiffBytes bounds the diff returned for one skill.
+const skillImportPreviewMaxDiffBytes = 32 * 1024
+
+// SkillGitHubImportPreview describes what importing one resolved skill would change locally.
+type SkillGitHubImportPreview struct {
+	// Status is new, identical, or modified.
+	Status string `json:"status"`
+	// Diff is a unified diff from the installed SKILL.md to the remote one, set when Status is modified.
+	Diff          string `json:"diff,omitempty"`
+	DiffTruncated bool   `json:"diff_truncated,omitempty"`
+}
+
+func previewGitHubSkillImport(item SkillGitHubResolvedSkill) (SkillGitHubImportPreview, error) {
+	installed, err := os.ReadFile(item.TargetSkillPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return SkillGitHubImportPreview{Status: SkillImportPreviewNew}, nil
+		}
+		return SkillGitHubImportPreview{}, newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "failed to read installed skill", err)
+	}
+	if string(installed) == item.remoteSkillMarkdown {
+		return SkillGitHubImportPreview{Status: SkillImportPreviewIdentical}, nil
+	}
+	diff, truncated := renderUnifiedContentDiff(
+		string(installed),
+		item.remoteSkillMarkdown,
+		"installed/"+item.Name+"/SKILL.md",
+		item.Repo+"@"+item.Ref+"/"+path.Join(item.RepoPath, "SKILL.md"),
+		skillImportPreviewMaxDiffBytes,
+	)
+	return SkillGitHubImportPreview{Status: SkillImportPreviewModified, Diff: diff, DiffTruncated: truncated}, nil
+}
@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+// skillOverlayMaxRunes is how much of an active skill body the system prompt overlay keeps.
+const skillOverlayMaxRunes = 1200
+
+const (
+	skillContentMaxBytes       = 64 << 10
+	skillDescriptionMaxRunes   = 1024
+	skillValidationMaxMessages = 3
+)
+
+const (
+	SkillIssueInvalidScope       = "invalid_scope"
+	SkillIssueInvalidName        = "invalid_name"
+	SkillIssueMissingFrontmatter = "missing_frontmatter"
+	SkillIssueInvalidFrontmatter = "invalid_frontmatter"
+	SkillIssueMissingName        = "missing_name"
+	SkillIssueMissingDescription = "missing_description"
+	SkillIssueContentTooLarge    = "content_too_large"
+	SkillIssueNameMismatch       = "name_mismatch"
+	SkillIssueDescriptionLong    = "description_too_long"
+	SkillIssueUnknownModeHint    = "unknown_mode_hint"
+	SkillIssueEmptyBody          = "empty_body"
+	SkillIssueBodyTruncated      = "body_truncated"
+	SkillIssuePromptInjection    = "prompt_injection"
+)
+
+type SkillValidationIssue struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// SkillValidationResult lists the problems of one SKILL.md.
+//
+// Errors make the skill unusable and block create/import; warnings are reported but allowed.
+type SkillValidationResult struct {
+	Valid    bool                   `json:"valid"`
+	Errors   []SkillValidationIssue `json:"errors,omitempty"`
+	Warnings []SkillValidationIssue `json:"warnings,omitempty"`
+}
+
+// SkillCreateResult is the catalog after a skill was created, plus the validation warnings of the new skill.
+type SkillCreateResult struct {
+	SkillCatalog
+	Warnings []SkillValidationIssue `json:"warnings,omitempty"`
+}
+
+// skillPromptInjectionPatterns flag phrases that try to override the agent's instructions.
+var skillPromptInjectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+)?(of\s+)?(the\s+|your\s+)?(previous|prior|above|earlier|system)\s+(instructions|prompts?|rules|messages)`),
+	regexp.MustCompile(`(?i)\b(reveal|print|show|leak)\s+(the\s+|your\s+)?system\s+prompt`),
+	regexp.MustCompile(`(?i)\byou\s+are\s+no\s+longer\b`),
+	regexp.MustCompile(`(?i)\b(do\s+not|don't|never)\s+(tell|inform|show)\s+the\s+user\b`),
+	regexp.MustCompile(`(?i)\b(bypass|disable|override)\s+(the\s+)?(safety|security|approval|sandbox)\b`),
+}
+
+// lintSkillContent checks the full SKILL.md content (frontmatter and body) of a skill named name in scope.
+func lintSkillContent(scope string, name string, content string) SkillValidationResult {
+	var out SkillValidationResult
+	addError := func(code string, format string, args ...any) {
+		out.Errors = append(out.Errors, SkillValidationIssue{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+	addWarning := func(code string, format string, args ...any) {
+		out.Warnings = append(out.Warnings, SkillValidationIssue{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch strings.TrimSpace(strings.ToLower(scope)) {
+	case "user", "user_agents":
+	default:
+		addError(SkillIssueInvalidScope, "invalid scope: %s", scope)
+	}
+	name = strings.TrimSpace(name)
+	if !skillNameRE.MatchString(name) {
+		addError(SkillIssueInvalidName, "invalid skill name: %s", name)
+	}
+	if len(content) > skillContentMaxBytes {
+		addError(SkillIssueContentTooLarge, "SKILL.md is %d bytes; the limit is %d", len(content), skillContentMaxBytes)
+	}
+
+	var fm skillFrontmatter
+	frontmatterRaw, body, ok := splitFrontmatter(content)
+	if !ok {
+		addError(SkillIssueMissingFrontmatter, "missing frontmatter")
+	} else if err := yaml.Unmarshal([]byte(frontmatterRaw), &fm); err != nil {
+		addError(SkillIssueInvalidFrontmatter, "invalid frontmatter: %s", err.Error())
+	} else {
+		fmName := strings.TrimSpace(fm.Name)
+		description := strings.TrimSpace(fm.Description)
+		if fmName == "" {
+			addError(SkillIssueMissingName, "frontmatter is missing name")
+		} else if name != "" && fmName != name {
+			addWarning(SkillIssueNameMismatch, "frontmatter name %q differs from %q; the skill is listed as %q", fmName, name, fmName)
+		}
+		if description == "" {
+			addError(SkillIssueMissingDescription, "frontmatter is missing description")
+		} else if n := utf8.RuneCountInString(description); n > skillDescriptionMaxRunes {
+			addWarning(SkillIssueDescriptionLong, "description is %d characters; keep it under %d", n, skillDescriptionMaxRunes)
+		}
+		for _, hint := range fm.ModeHint {
+			switch strings.TrimSpace(strings.ToLower(hint)) {
+			case config.AIModeAct, config.AIModePlan:
+			default:
+				addWarning(SkillIssueUnknownModeHint, "unknown mode_hint %q; the skill never matches it", hint)
+			}
+		}
+	}
+
+	if body == "" {
+		addWarning(SkillIssueEmptyBody, "skill body is empty")
+	} else if n := utf8.RuneCountInString(body); n > skillOverlayMaxRunes {
+		addWarning(SkillIssueBodyTruncated, "body is %d characters; the active skill overlay keeps only the first %d", n, skillOverlayMaxRunes)
+	}
+	for _, re := range skillPromptInjectionPatterns {
+		if match := re.FindString(content); match != "" {
+			addWarning(SkillIssuePromptInjection, "possible prompt injection: %q", match)
+		}
+	}
+
+	out.Valid = len(out.Errors) == 0
+	return out
+}
+
+// skillValidationError turns the errors of a failed validation into one SkillError.
+func skillValidationError(name string, res SkillValidationResult) error {
+	if res.Valid {
+		return nil
+	}
+	messages := make([]string, 0, skillValidationMaxMessages)
+	for i, issue := range res.Errors {
+		if i == skillValidationMaxMessages {
+			messages = append(messages, fmt.Sprintf("and %d more", len(res.Errors)-i))
+			break
+		}
+		messages = append(messages, issue.Message)
+	}
+	return newSkillError(ErrCodeAISkillsValidationFailed, http.StatusUnprocessableEntity, fmt.Sprintf("skill %s failed validation: %s", strings.TrimSpace(name), strings.Join(messages, "; ")), nil)
+}
+
+// Validate lints SKILL.md content without writing anything.
+func (m *skillManager) Validate(scope string, name string, body string) (SkillValidationResult, error) {
+	if m == nil {
+		return SkillValidationResult{}, newSkillError(ErrCodeAISkillsInternal, http.StatusServiceUnavailable, "skill manager unavailable", nil)
+	}
+	return lintSkillContent(scope, name, body), nil
+}
@@ -33,18 +33,22 @@ type SkillMeta struct {
 	ModeHints               []string             `json:"mode_hints,omitempty"`
 	AllowImplicitInvocation bool                 `json:"allow_implicit_invocation"`
 	Dependencies            []SkillMCPDependency `json:"dependencies,omitempty"`
+	Requires                []string             `json:"requires,omitempty"`
+	RequiredTools           []string             `json:"required_tools,omitempty"`
 }
 
 type SkillActivation struct {
-	ActivationID string               `json:"activation_id"`
-	Name         string               `json:"name"`
-	RootDir      string               `json:"root_dir"`
-	Priority     int                  `json:"priority"`
-	Content      string               `json:"content"`
-	ContentRef   string               `json:"content_ref"`
-	ModeHints    []string             `json:"mode_hints,omitempty"`
-	Dependencies []SkillMCPDependency `json:"dependencies,omitempty"`
-	ActivatedAt  int64                `json:"activated_at_unix_ms"`
+	ActivationID  string               `json:"activation_id"`
+	Name          string               `json:"name"`
+	RootDir       string               `json:"root_dir"`
+	Priority      int                  `json:"priority"`
+	Content       string               `json:"content"`
+	ContentRef    string               `json:"content_ref"`
+	ModeHints     []string             `json:"mode_hints,omitempty"`
+	Dependencies  []SkillMCPDependency `json:"dependencies,omitempty"`
+	ActivatedAt   int64                `json:"activated_at_unix_ms"`
+	Prerequisites []string             `json:"prerequisites,omitempty"`
+	RequiredTools []string             `json:"required_tools,omitempty"`
 }
 
 type SkillCatalog struct {
@@ -64,6 +68,8 @@ type SkillCatalogEntry struct {
 	ModeHints               []string             `json:"mode_hints,omitempty"`
 	AllowImplicitInvocation bool                 `json:"allow_implicit_invocation"`
 	Dependencies            []SkillMCPDependency `json:"dependencies,omitempty"`
+	Requires                []string             `json:"requires,omitempty"`
+	RequiredTools           []string             `json:"required_tools,omitempty"`
 	DependencyState         string               `json:"dependency_state,omitempty"`
 	Enabled                 bool                 `json:"enabled"`
 	Effective               bool                 `json:"effective"`
@@ -98,6 +104,8 @@ type skillFrontmatter struct {
 	Dependencies struct {
 		MCPServers []SkillMCPDependency `yaml:"mcp_servers"`
 	} `yaml:"dependencies"`
+	Requires      []string `yaml:"requires"`
+	RequiredTools []string `yaml:"required_tools"`
 }
 
 type skillStateFile struct {
@@ -245,24 +253,24 @@ func (m *skillManager) PatchToggles(patches []SkillTogglePatch) (SkillCatalog, e
 	return m.catalogLocked(), nil
 }
 
-func (m *skillManager) Create(scope string, name string, description string, body string) (SkillCatalog, error) {
+func (m *skillManager) Create(scope string, name string, description string, body string) (SkillCreateResult, error) {
 	if m == nil {
-		return SkillCatalog{}, fmt.Errorf("nil skill manager")
+		return SkillCreateResult{}, fmt.Errorf("nil skill manager")
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	skillRoot, err := m.scopeRootLocked(scope)
 	if err != nil {
-		return SkillCatalog{}, err
+		return SkillCreateResult{}, err
 	}
 	name = strings.TrimSpace(name)
 	description = strings.TrimSpace(description)
 	if !skillNameRE.MatchString(name) {
-		return SkillCatalog{}, fmt.Errorf("invalid skill name: %s", name)
+		return SkillCreateResult{}, fmt.Errorf("invalid skill name: %s", name)
 	}
 	if description == "" {
-		return SkillCatalog{}, fmt.Errorf("missing description")
+		return SkillCreateResult{}, fmt.Errorf("missing description")
 	}
 	description = strings.ReplaceAll(description, "\n", " ")
 	description = strings.ReplaceAll(description, "\r", " ")
@@ -271,10 +279,7 @@ func (m *skillManager) Create(scope string, name string, description string, bod
 	skillDir := filepath.Join(skillRoot, name)
 	skillFile := filepath.Join(skillDir, "SKILL.md")
 	if _, err := os.Stat(skillFile); err == nil {
-		return SkillCatalog{}, fmt.Errorf("skill already exists: %s", name)
-	}
-	if err := os.MkdirAll(skillDir, 0o755); err != nil {
-		return SkillCatalog{}, err
+		return SkillCreateResult{}, fmt.Errorf("skill already exists: %s", name)
 	}
 
 	body = strings.TrimSpace(body)
@@ -282,8 +287,15 @@ func (m *skillManager) Create(scope string, name string, description string, bod
 		body = fmt.Sprintf("# %s\n\nAdd instructions for this skill.", name)
 	}
 	content := fmt.Sprintf("---\nname: %s\ndescription: %s\n---\n\n%s\n", name, description, body)
+	validation := lintSkillContent(scope, name, content)
+	if err := skillValidationError(name, validation); err != nil {
+		return SkillCreateResult{}, err
+	}
+	if err := os.MkdirAll(skillDir, 0o755); err != nil {
+		return SkillCreateResult{}, err
+	}
 	if err := os.WriteFile(skillFile, []byte(content), 0o600); err != nil {
-		return SkillCatalog{}, err
+		return SkillCreateResult{}, err
 	}
 	now := time.Now().UnixMilli()
 	m.sources[filepath.Clean(skillFile)] = SkillSourceRecord{
@@ -294,11 +306,11 @@ func (m *skillManager) Create(scope string, name string, description string, bod
 		LastCheckedAtUnixMs: now,
 	}
 	if err := m.saveSourcesLocked(); err != nil {
-		return SkillCatalog{}, err
+		return SkillCreateResult{}, err
 	}
 
 	m.discoverLocked()
-	return m.catalogLocked(), nil
+	return SkillCreateResult{SkillCatalog: m.catalogLocked(), Warnings: validation.Warnings}, nil
 }
 
 func (m *skillManager) Delete(scope string, name string) (SkillCatalog, error) {
@@ -433,6 +445,8 @@ func (m *skillManager) discoverLocked() {
 				ModeHints:               append([]string(nil), item.ModeHints...),
 				AllowImplicitInvocation: item.AllowImplicitInvocation,
 				Dependencies:            append([]SkillMCPDependency(nil), item.Dependencies...),
+				Requires:                append([]string(nil), item.Requires...),
+				RequiredTools:           append([]string(nil), item.RequiredTools...),
 				DependencyState:         dependencyState,
 				Enabled:                 enabled,
 				Effective:               effective,
@@ -441,6 +455,28 @@ func (m *skillManager) discoverLocked() {
 		}
 	}
 
+	// Skill prerequisites are checked against the effective skills, so a disabled or missing prerequisite
+	// is reported here instead of at use_skill time.
+	for _, name := range sortedSkillNames(grouped) {
+		meta, ok := effectiveByName[name]
+		if !ok || len(meta.Requires) == 0 {
+			continue
+		}
+		_, err := resolveSkillRequirements(name, func(name string) (SkillMeta, bool) {
+			meta, ok := effectiveByName[name]
+			return meta, ok
+		})
+		if err == nil {
+			continue
+		}
+		allErrors = append(allErrors, SkillCatalogNotice{Name: name, Path: meta.Path, Message: err.Error()})
+		for i := range entries {
+			if entries[i].Effective && entries[i].Name == name {
+				entries[i].DependencyState = "unsatisfied"
+			}
+		}
+	}
+
 	sort.Slice(entries, func(i, j int) bool {
 		if entries[i].Effective != entries[j].Effective {
 			return entries[i].Effective
@@ -583,10 +619,72 @@ func parseSkillFile(path string, scope string) (SkillMeta, string, error) {
 		ModeHints:               modeHints,
 		AllowImplicitInvocation: allowImplicit,
 		Dependencies:            deps,
+		Requires:                uniqueStrings(fm.Requires),
+		RequiredTools:           uniqueStrings(fm.RequiredTools),
 	}
 	return meta, strings.TrimSpace(body), nil
 }
 
+// resolveSkillRequirements returns name and its transitive prerequisites, prerequisites first.
+//
+// It fails on a dependency cycle or a prerequisite that lookup cannot resolve.
+func resolveSkillRequirements(name string, lookup func(name string) (SkillMeta, bool)) ([]SkillMeta, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	out := make([]SkillMeta, 0, 4)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("skill dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+		meta, ok := lookup(name)
+		if !ok {
+			if len(path) == 0 {
+				return fmt.Errorf("unknown skill: %s", name)
+			}
+			return fmt.Errorf("skill %q requires unavailable skill %q", path[len(path)-1], name)
+		}
+		state[name] = visiting
+		next := append(append([]string(nil), path...), name)
+		for _, required := range meta.Requires {
+			if err := visit(required, next); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		out = append(out, meta)
+		return nil
+	}
+	if err := visit(strings.TrimSpace(name), nil); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Requirements returns the skills activating name would activate, prerequisites first and name last.
+func (m *skillManager) Requirements(name string, mode string, implicit bool) ([]SkillMeta, error) {
+	if m == nil {
+		return nil, fmt.Errorf("nil skill manager")
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.requirementsLocked(name, mode, implicit)
+}
+
+func (m *skillManager) requirementsLocked(name string, mode string, implicit bool) ([]SkillMeta, error) {
+	root := strings.TrimSpace(name)
+	return resolveSkillRequirements(root, func(name string) (SkillMeta, bool) {
+		// Prerequisites are activated on the skill's behalf, so only the requested skill honors implicit.
+		return m.resolveCandidateLocked(name, mode, implicit && name == root)
+	})
+}
+
 func splitFrontmatter(raw string) (frontmatter string, body string, ok bool) {
 	raw = strings.ReplaceAll(raw, "\r\n", "\n")
 	raw = strings.ReplaceAll(raw, "\r", "\n")
@@ -649,27 +747,48 @@ func (m *skillManager) Activate(name string, mode string, implicit bool) (SkillA
 	if activation, ok := m.active[name]; ok {
 		return activation, true, nil
 	}
-	meta, ok := m.resolveCandidateLocked(name, mode, implicit)
-	if !ok {
-		return SkillActivation{}, false, fmt.Errorf("unknown skill: %s", name)
-	}
-	_, body, err := parseSkillFile(meta.Path, meta.Scope)
+	chain, err := m.requirementsLocked(name, mode, implicit)
 	if err != nil {
 		return SkillActivation{}, false, err
 	}
-	activationID := fmt.Sprintf("skill_%d", time.Now().UnixNano())
-	activation := SkillActivation{
-		ActivationID: activationID,
-		Name:         meta.Name,
-		RootDir:      filepath.Dir(meta.Path),
-		Priority:     meta.Priority,
-		Content:      body,
-		ContentRef:   meta.Path,
-		ModeHints:    append([]string(nil), meta.ModeHints...),
-		Dependencies: append([]SkillMCPDependency(nil), meta.Dependencies...),
-		ActivatedAt:  time.Now().UnixMilli(),
-	}
-	m.active[name] = activation
+	// Parse every skill in the chain before activating any, so a broken prerequisite activates nothing.
+	bodies := make([]string, len(chain))
+	for i, meta := range chain {
+		if _, active := m.active[meta.Name]; active {
+			continue
+		}
+		_, body, err := parseSkillFile(meta.Path, meta.Scope)
+		if err != nil {
+			return SkillActivation{}, false, err
+		}
+		bodies[i] = body
+	}
+	prerequisites := make([]string, 0, len(chain)-1)
+	var activation SkillActivation
+	for i, meta := range chain {
+		if i < len(chain)-1 {
+			prerequisites = append(prerequisites, meta.Name)
+		}
+		if _, active := m.active[meta.Name]; active {
+			continue
+		}
+		activation = SkillActivation{
+			ActivationID:  fmt.Sprintf("skill_%d", time.Now().UnixNano()),
+			Name:          meta.Name,
+			RootDir:       filepath.Dir(meta.Path),
+			Priority:      meta.Priority,
+			Content:       bodies[i],
+			ContentRef:    meta.Path,
+			ModeHints:     append([]string(nil), meta.ModeHints...),
+			Dependencies:  append([]SkillMCPDependency(nil), meta.Dependencies...),
+			ActivatedAt:   time.Now().UnixMilli(),
+			RequiredTools: append([]string(nil), meta.RequiredTools...),
+		}
+		if i == len(chain)-1 && len(prerequisites) > 0 {
+			activation.Prerequisites = prerequisites
+		}
+		m.active[meta.Name] = activation
+	}
 	return activation, false, nil
 }
 
@@ -807,6 +926,8 @@ func (m *skillManager) catalogLocked() SkillCatalog {
 		cloned := item
 		cloned.ModeHints = append([]string(nil), item.ModeHints...)
 		cloned.Dependencies = append([]SkillMCPDependency(nil), item.Dependencies...)
+		cloned.Requires = append([]string(nil), item.Requires...)
+		cloned.RequiredTools = append([]string(nil), item.RequiredTools...)
 		entries = append(entries, cloned)
 	}
 	conflicts := make([]SkillCatalogNotice, 0, len(m.catalogConflict))
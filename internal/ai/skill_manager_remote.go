@@ -36,6 +36,7 @@ const (
 	ErrCodeAISkillsArchiveInvalid    = "AI_SKILLS_ARCHIVE_INVALID"
 	ErrCodeAISkillsBrowseForbidden   = "AI_SKILLS_BROWSE_FORBIDDEN"
 	ErrCodeAISkillsFileTooLarge      = "AI_SKILLS_FILE_TOO_LARGE"
+	ErrCodeAISkillsValidationFailed  = "AI_SKILLS_VALIDATION_FAILED"
 	ErrCodeAISkillsInternal          = "AI_SKILLS_INTERNAL_ERROR"
 )
 
@@ -133,6 +134,7 @@ const (
 	SkillSourceTypeLocalManual SkillSourceType = "local_manual"
 	SkillSourceTypeGitHub      SkillSourceType = "github_import"
 	SkillSourceTypeSystem      SkillSourceType = "system_bundle"
+	SkillSourceTypeArchive     SkillSourceType = "archive_import"
 )
 
 type SkillSourceRecord struct {
@@ -213,6 +215,10 @@ type SkillGitHubResolvedSkill struct {
 	TargetDir       string `json:"target_dir"`
 	TargetSkillPath string `json:"target_skill_path"`
 	AlreadyExists   bool   `json:"already_exists"`
+	// Preview compares the remote SKILL.md with the installed one; it is only set by PreviewGitHubImport.
+	Preview *SkillGitHubImportPreview `json:"preview,omitempty"`
+
+	remoteSkillMarkdown string
 }
 
 type SkillGitHubImportResult struct {
@@ -228,6 +234,8 @@ type SkillGitHubImportItem struct {
 	SourceID        string          `json:"source_id"`
 	InstallMode     string          `json:"install_mode"`
 	InstalledCommit string          `json:"installed_commit,omitempty"`
+	// Warnings are the validation warnings of the imported SKILL.md.
+	Warnings []SkillValidationIssue `json:"warnings,omitempty"`
 }
 
 type SkillReinstallResult struct {
@@ -452,6 +460,23 @@ func (m *skillManager) ValidateGitHubImport(req SkillGitHubImportRequest) (Skill
 	return SkillGitHubValidateResult{Resolved: resolved}, nil
 }
 
+// PreviewGitHubImport resolves req like ValidateGitHubImport and reports, per skill, whether importing it
+// would add a new skill, leave the installed one unchanged, or modify it.
+func (m *skillManager) PreviewGitHubImport(req SkillGitHubImportRequest) (SkillGitHubValidateResult, error) {
+	out, err := m.ValidateGitHubImport(req)
+	if err != nil {
+		return SkillGitHubValidateResult{}, err
+	}
+	for i := range out.Resolved {
+		preview, err := previewGitHubSkillImport(out.Resolved[i])
+		if err != nil {
+			return SkillGitHubValidateResult{}, err
+		}
+		out.Resolved[i].Preview = &preview
+	}
+	return out, nil
+}
+
 func (m *skillManager) ImportFromGitHub(req SkillGitHubImportRequest) (SkillGitHubImportResult, error) {
 	if m == nil {
 		return SkillGitHubImportResult{}, newSkillError(ErrCodeAISkillsInternal, http.StatusServiceUnavailable, "skill manager unavailable", nil)
@@ -488,13 +513,29 @@ func (m *skillManager) ImportFromGitHub(req SkillGitHubImportRequest) (SkillGitH
 		return SkillGitHubImportResult{}, err
 	}
 
-	imports := make([]SkillGitHubImportItem, 0, len(resolved))
+	// Validate every skill before installing any, so one broken skill imports nothing.
+	warnings := make([][]SkillValidationIssue, len(resolved))
 	for i := range resolved {
 		item := resolved[i]
 		srcDir := extracted[item.RepoPath]
 		if strings.TrimSpace(srcDir) == "" {
 			return SkillGitHubImportResult{}, newSkillError(ErrCodeAISkillsInternal, http.StatusInternalServerError, "internal source mapping missing", nil)
 		}
+		content, err := os.ReadFile(filepath.Join(srcDir, "SKILL.md"))
+		if err != nil {
+			return SkillGitHubImportResult{}, newSkillError(ErrCodeAISkillsSkillNotFound, http.StatusNotFound, "source skill files incomplete", err)
+		}
+		res := lintSkillContent(input.scope, item.Name, string(content))
+		if err := skillValidationError(item.Name, res); err != nil {
+			return SkillGitHubImportResult{}, err
+		}
+		warnings[i] = res.Warnings
+	}
+
+	imports := make([]SkillGitHubImportItem, 0, len(resolved))
+	for i := range resolved {
+		item := resolved[i]
+		srcDir := extracted[item.RepoPath]
 		if err := m.installOneSkillLocked(srcDir, item.TargetDir, input.overwrite); err != nil {
 			return SkillGitHubImportResult{}, err
 		}
@@ -521,6 +562,7 @@ func (m *skillManager) ImportFromGitHub(req SkillGitHubImportRequest) (SkillGitH
 			SourceID:        sourceID,
 			InstallMode:     installMode,
 			InstalledCommit: commit,
+			Warnings:        warnings[i],
 		})
 	}
 
@@ -1026,6 +1068,8 @@ func (m *skillManager) resolveGitHubSkillsLocked(input resolvedGitHubImportInput
 			TargetDir:       targetDir,
 			TargetSkillPath: targetSkillPath,
 			AlreadyExists:   alreadyExists,
+
+			remoteSkillMarkdown: skillRaw,
 		})
 	}
 	sort.Slice(resolved, func(i, j int) bool {
@@ -165,6 +165,84 @@ description: Install Codex skills
 	}
 }
 
+func TestSkillManager_PreviewGitHubImportReportsNewIdenticalAndModified(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	stateDir := t.TempDir()
+
+	skillMD := `---
+name: skill-installer
+description: Install Codex skills
+---
+
+# Skill Installer
+
+Run scripts/install.sh.
+`
+	zipBytes := buildZipArchive(t, map[string]string{
+		"openai-skills-main/skills/.curated/skill-installer/SKILL.md": skillMD,
+	})
+	server := newGitHubFixtureServer(t, testGitHubFixture{skillMarkdown: skillMD, zipBytes: zipBytes})
+	defer server.Close()
+
+	mgr := newSkillManager(workspace, stateDir)
+	mgr.userHome = workspace
+	mgr.githubAPIBaseURL = server.URL
+	mgr.githubRawBaseURL = server.URL + "/raw"
+	mgr.githubRepoBaseURL = server.URL
+
+	req := SkillGitHubImportRequest{
+		Scope: "user",
+		Repo:  "openai/skills",
+		Ref:   "main",
+		Paths: []string{"skills/.curated/skill-installer"},
+	}
+	previewStatus := func() *SkillGitHubImportPreview {
+		t.Helper()
+		out, err := mgr.PreviewGitHubImport(req)
+		if err != nil {
+			t.Fatalf("PreviewGitHubImport: %v", err)
+		}
+		if len(out.Resolved) != 1 || out.Resolved[0].Preview == nil {
+			t.Fatalf("resolved=%+v, want one previewed skill", out.Resolved)
+		}
+		return out.Resolved[0].Preview
+	}
+
+	if got := previewStatus(); got.Status != SkillImportPreviewNew || got.Diff != "" {
+		t.Fatalf("preview before install=%+v, want new", got)
+	}
+	imported, err := mgr.ImportFromGitHub(req)
+	if err != nil {
+		t.Fatalf("ImportFromGitHub: %v", err)
+	}
+	if got := previewStatus(); got.Status != SkillImportPreviewIdentical {
+		t.Fatalf("preview after install=%+v, want identical", got)
+	}
+
+	local := strings.Replace(skillMD, "Run scripts/install.sh.", "Run scripts/install.sh --local.", 1)
+	if err := os.WriteFile(imported.Imports[0].SkillPath, []byte(local), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	got := previewStatus()
+	if got.Status != SkillImportPreviewModified || got.DiffTruncated {
+		t.Fatalf("preview after local edit=%+v, want modified", got)
+	}
+	want := `--- installed/skill-installer/SKILL.md
++++ openai/skills@main/skills/.curated/skill-installer/SKILL.md
+@@ -5,4 +5,4 @@
+ 
+ # Skill Installer
+ 
+-Run scripts/install.sh --local.
++Run scripts/install.sh.
+`
+	if got.Diff != want {
+		t.Fatalf("diff=\n%s\nwant:\n%s", got.Diff, want)
+	}
+}
+
 func TestSkillManager_ReinstallFromGitHubSource(t *testing.T) {
 	t.Parallel()
 
@@ -953,3 +953,119 @@ Use this handler skill.`, skillName)
 		t.Fatalf("unexpected skill content: %#v", data)
 	}
 }
+
+func writeTestSkill(t *testing.T, workspace string, name string, frontmatter string) {
+	t.Helper()
+	dir := filepath.Join(workspace, ".redeven", "skills", name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir skill dir: %v", err)
+	}
+	content := fmt.Sprintf("---\nname: %s\ndescription: %s test skill\n%s---\n\n# %s\n", name, name, frontmatter, name)
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0o600); err != nil {
+		t.Fatalf("write skill file: %v", err)
+	}
+}
+
+func TestSkillManager_ActivatesPrerequisites(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	writeTestSkill(t, workspace, "base-skill", "required_tools:\n  - terminal.exec\n")
+	writeTestSkill(t, workspace, "mid-skill", "requires:\n  - base-skill\n")
+	writeTestSkill(t, workspace, "top-skill", "requires:\n  - mid-skill\n  - base-skill\n")
+
+	mgr := newSkillManager(workspace, workspace)
+	mgr.userHome = workspace
+	catalog := mgr.Reload()
+	if len(catalog.Errors) != 0 {
+		t.Fatalf("unexpected catalog errors: %+v", catalog.Errors)
+	}
+
+	activation, alreadyActive, err := mgr.Activate("top-skill", "", false)
+	if err != nil || alreadyActive {
+		t.Fatalf("Activate err=%v alreadyActive=%v", err, alreadyActive)
+	}
+	if got := strings.Join(activation.Prerequisites, ","); got != "base-skill,mid-skill" {
+		t.Fatalf("prerequisites=%q, want base-skill,mid-skill", got)
+	}
+	active := map[string]bool{}
+	for _, item := range mgr.Active() {
+		active[item.Name] = true
+		if item.Name == "base-skill" && strings.Join(item.RequiredTools, ",") != "terminal.exec" {
+			t.Fatalf("base-skill required_tools=%v", item.RequiredTools)
+		}
+	}
+	if !active["top-skill"] || !active["mid-skill"] || !active["base-skill"] {
+		t.Fatalf("active skills=%v, want top, mid and base", active)
+	}
+}
+
+func TestSkillManager_CatalogReportsUnsatisfiableRequirements(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	writeTestSkill(t, workspace, "cycle-a", "requires:\n  - cycle-b\n")
+	writeTestSkill(t, workspace, "cycle-b", "requires:\n  - cycle-a\n")
+	writeTestSkill(t, workspace, "orphan-skill", "requires:\n  - missing-skill\n")
+
+	mgr := newSkillManager(workspace, workspace)
+	mgr.userHome = workspace
+	catalog := mgr.Reload()
+
+	messages := map[string]string{}
+	for _, notice := range catalog.Errors {
+		messages[notice.Name] = notice.Message
+	}
+	if !strings.Contains(messages["cycle-a"], "skill dependency cycle: cycle-a -> cycle-b -> cycle-a") {
+		t.Fatalf("cycle-a notice=%q", messages["cycle-a"])
+	}
+	if !strings.Contains(messages["orphan-skill"], `requires unavailable skill "missing-skill"`) {
+		t.Fatalf("orphan-skill notice=%q", messages["orphan-skill"])
+	}
+	for _, item := range catalog.Skills {
+		if item.DependencyState != "unsatisfied" {
+			t.Fatalf("skill %s dependency_state=%q, want unsatisfied", item.Name, item.DependencyState)
+		}
+	}
+
+	if _, _, err := mgr.Activate("cycle-a", "", false); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("Activate cycle err=%v", err)
+	}
+	if len(mgr.Active()) != 0 {
+		t.Fatalf("failed activation must not activate prerequisites: %+v", mgr.Active())
+	}
+}
+
+func TestUseSkillTool_ReportsMissingRequiredTools(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	writeTestSkill(t, workspace, "exec-skill", "required_tools:\n  - terminal.exec\n")
+	writeTestSkill(t, workspace, "wrapper-skill", "requires:\n  - exec-skill\n")
+
+	r := newRun(runOptions{Log: slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})), AgentHomeDir: workspace})
+	r.runMode = config.AIModePlan
+	r.skillManager = newSkillManager(workspace, workspace)
+	r.skillManager.userHome = workspace
+	r.skillManager.Discover()
+	r.setActiveToolNames([]ToolDef{{Name: "use_skill"}, {Name: "file.read"}})
+	meta := &session.Meta{CanRead: true, CanWrite: true, CanExecute: true}
+
+	_, err := r.execTool(context.Background(), meta, "tool_1", "use_skill", map[string]any{"name": "wrapper-skill"})
+	if err == nil || !strings.Contains(err.Error(), "requires tools that are not available in plan mode: terminal.exec") {
+		t.Fatalf("execTool err=%v", err)
+	}
+	if len(r.activeSkills()) != 0 {
+		t.Fatalf("skill must not activate when a required tool is missing")
+	}
+
+	r.setActiveToolNames([]ToolDef{{Name: "use_skill"}, {Name: "terminal.exec"}})
+	out, err := r.execTool(context.Background(), meta, "tool_2", "use_skill", map[string]any{"name": "wrapper-skill"})
+	if err != nil {
+		t.Fatalf("execTool: %v", err)
+	}
+	data, _ := out.(map[string]any)
+	if prerequisites, _ := data["prerequisites"].([]string); strings.Join(prerequisites, ",") != "exec-skill" {
+		t.Fatalf("prerequisites=%#v", data["prerequisites"])
+	}
+}
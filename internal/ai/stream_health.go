@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	finalizationReasonRepeatedDeltaGuard = "repeated_delta_guard"
+	finalizationReasonToolSignatureGuard = "tool_signature_guard"
+
+	// repeatedDeltaGuardThreshold is the number of consecutive identical text deltas that aborts the run.
+	repeatedDeltaGuardThreshold = 10
+	// toolSignatureGuardThreshold is the number of times one tool signature may be proposed in a run.
+	//
+	// signatureHits already blocks and escalates repeats; this is the hard stop for runs that cannot
+	// ask the user and keep proposing the same call.
+	toolSignatureGuardThreshold = 16
+)
+
+// streamHealthMonitor detects degenerate model output inside a run.
+//
+// It is owned by the run loop goroutine and is not safe for concurrent use.
+type streamHealthMonitor struct {
+	lastDelta       string
+	deltaRepeats    int
+	signatureCounts map[string]int
+}
+
+func newStreamHealthMonitor() *streamHealthMonitor {
+	return &streamHealthMonitor{signatureCounts: map[string]int{}}
+}
+
+// resetDeltas starts a new streamed turn; repeats are only counted within one turn.
+func (m *streamHealthMonitor) resetDeltas() {
+	m.lastDelta = ""
+	m.deltaRepeats = 0
+}
+
+// observeDelta records one text delta and returns its consecutive repeat count and whether the guard tripped.
+func (m *streamHealthMonitor) observeDelta(delta string) (int, bool) {
+	normalized := normalizeStreamHealthDelta(delta)
+	if normalized == "" {
+		return 0, false
+	}
+	if normalized == m.lastDelta {
+		m.deltaRepeats++
+	} else {
+		m.lastDelta = normalized
+		m.deltaRepeats = 1
+	}
+	return m.deltaRepeats, m.deltaRepeats >= repeatedDeltaGuardThreshold
+}
+
+// observeToolSignature records one proposed tool call and returns the run-wide count and whether the guard tripped.
+func (m *streamHealthMonitor) observeToolSignature(signature string) (int, bool) {
+	signature = strings.TrimSpace(signature)
+	if signature == "" {
+		return 0, false
+	}
+	m.signatureCounts[signature]++
+	count := m.signatureCounts[signature]
+	return count, count > toolSignatureGuardThreshold
+}
+
+func normalizeStreamHealthDelta(delta string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(delta)), " ")
+	if utf8.RuneCountInString(normalized) > 500 {
+		normalized = string([]rune(normalized)[:500])
+	}
+	return normalized
+}
@@ -0,0 +1,199 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestStreamHealthMonitor_RepeatedDelta(t *testing.T) {
+	t.Parallel()
+
+	m := newStreamHealthMonitor()
+	for i := 1; i < repeatedDeltaGuardThreshold; i++ {
+		delta := "Checking the file"
+		if i%2 == 0 {
+			delta = "  checking   THE file\n"
+		}
+		repeats, tripped := m.observeDelta(delta)
+		if tripped || repeats != i {
+			t.Fatalf("delta %d: repeats=%d tripped=%v", i, repeats, tripped)
+		}
+	}
+	if _, tripped := m.observeDelta("   "); tripped {
+		t.Fatalf("whitespace delta must be ignored")
+	}
+	if repeats, tripped := m.observeDelta("checking the file"); !tripped || repeats != repeatedDeltaGuardThreshold {
+		t.Fatalf("repeats=%d tripped=%v, want trip at threshold", repeats, tripped)
+	}
+
+	m.resetDeltas()
+	if repeats, tripped := m.observeDelta("checking the file"); tripped || repeats != 1 {
+		t.Fatalf("after reset: repeats=%d tripped=%v", repeats, tripped)
+	}
+	if repeats, _ := m.observeDelta("something else"); repeats != 1 {
+		t.Fatalf("a different delta must restart the count, got %d", repeats)
+	}
+}
+
+func TestStreamHealthMonitor_ToolSignature(t *testing.T) {
+	t.Parallel()
+
+	m := newStreamHealthMonitor()
+	for i := 1; i <= toolSignatureGuardThreshold; i++ {
+		if _, tripped := m.observeToolSignature("terminal.exec|{\"command\":\"ls\"}"); tripped {
+			t.Fatalf("tripped early at %d", i)
+		}
+		if _, tripped := m.observeToolSignature("file.read|{\"path\":\"a\"}"); tripped {
+			t.Fatalf("unrelated signature tripped at %d", i)
+		}
+	}
+	if count, tripped := m.observeToolSignature("terminal.exec|{\"command\":\"ls\"}"); !tripped || count != toolSignatureGuardThreshold+1 {
+		t.Fatalf("count=%d tripped=%v", count, tripped)
+	}
+	if _, tripped := m.observeToolSignature(""); tripped {
+		t.Fatalf("empty signature must be ignored")
+	}
+}
+
+type openAIRepeatedDeltaMock struct{}
+
+func (m *openAIRepeatedDeltaMock) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	var req map[string]any
+	_ = json.Unmarshal(body, &req)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	if isIntentClassifierRequest(req) {
+		writeOpenAISSEJSON(w, f, map[string]any{
+			"type":  "response.output_text.delta",
+			"delta": classifyIntentResponseToken(req),
+		})
+		writeOpenAISSEJSON(w, f, map[string]any{
+			"type":     "response.completed",
+			"response": map[string]any{"id": "resp_repeated_delta_intent", "model": "gpt-5-mini", "status": "completed"},
+		})
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+		f.Flush()
+		return
+	}
+	for i := 0; i < repeatedDeltaGuardThreshold*3; i++ {
+		writeOpenAISSEJSON(w, f, map[string]any{
+			"type":  "response.output_text.delta",
+			"delta": "Let me check again. ",
+		})
+	}
+	writeOpenAISSEJSON(w, f, map[string]any{
+		"type":     "response.completed",
+		"response": map[string]any{"id": "resp_repeated_delta", "model": "gpt-5-mini", "status": "completed", "finish_reason": "stop"},
+	})
+	_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	f.Flush()
+}
+
+func TestIntegration_NativeSDK_OpenAI_RepeatedDeltaGuard(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc((&openAIRepeatedDeltaMock{}).handle))
+	t.Cleanup(srv.Close)
+
+	cfg := &config.AIConfig{
+		Providers: []config.AIProvider{
+			{
+				ID:      "openai",
+				Name:    "OpenAI",
+				Type:    "openai",
+				BaseURL: strings.TrimSuffix(srv.URL, "/") + "/v1",
+				Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+	}
+	meta := session.Meta{
+		EndpointID:        "env_test",
+		NamespacePublicID: "ns_test",
+		ChannelID:         "ch_test_repeated_delta",
+		UserPublicID:      "u_test",
+		UserEmail:         "u_test@example.com",
+		CanRead:           true,
+		CanWrite:          true,
+		CanExecute:        true,
+		CanAdmin:          true,
+	}
+	svc, err := NewService(Options{
+		Logger:              slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo})),
+		StateDir:            t.TempDir(),
+		AgentHomeDir:        t.TempDir(),
+		Shell:               "bash",
+		Config:              cfg,
+		RunMaxWallTime:      30 * time.Second,
+		RunIdleTimeout:      10 * time.Second,
+		ToolApprovalTimeout: 5 * time.Second,
+		ResolveProviderAPIKey: func(providerID string) (string, bool, error) {
+			return "sk-test", strings.TrimSpace(providerID) == "openai", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	th, err := svc.CreateThread(ctx, &meta, "repeated delta", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	runID := "run_test_native_openai_repeated_delta_1"
+	rr := httptest.NewRecorder()
+	_ = svc.StartRun(ctx, &meta, runID, RunStartRequest{
+		ThreadID: th.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "create a note and summarize it"},
+		Options:  RunOptions{MaxSteps: 4},
+	}, rr)
+
+	snapshot, err := svc.RunMetricsSnapshot(ctx, &meta, runID)
+	if err != nil {
+		t.Fatalf("RunMetricsSnapshot: %v", err)
+	}
+	if snapshot.FinalizationReason != finalizationReasonRepeatedDeltaGuard {
+		t.Fatalf("finalization_reason=%q, want %q", snapshot.FinalizationReason, finalizationReasonRepeatedDeltaGuard)
+	}
+
+	events, err := svc.ListRunEvents(ctx, &meta, runID, 2000)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	found := false
+	for _, ev := range events.Events {
+		if strings.TrimSpace(ev.EventType) != "guard.repeated_delta" {
+			continue
+		}
+		payload, _ := ev.Payload.(map[string]any)
+		if repeats, _ := payload["repeats"].(float64); int(repeats) != repeatedDeltaGuardThreshold {
+			t.Fatalf("guard.repeated_delta payload=%+v", payload)
+		}
+		found = true
+	}
+	if !found {
+		t.Fatalf("missing guard.repeated_delta event")
+	}
+}
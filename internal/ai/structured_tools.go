@@ -14,6 +14,8 @@ import (
 const (
 	defaultFileReadLimit = 200
 	maxFileReadLimit     = 400
+	// unifiedContentDiffContext is the number of unchanged lines rendered around a changed region.
+	unifiedContentDiffContext = 3
 )
 
 type DiffHunkView struct {
@@ -174,6 +176,54 @@ func buildStructuredDiff(before string, after string) []DiffHunkView {
 	}}
 }
 
+// renderUnifiedContentDiff renders the changed region between before and after as a single unified diff hunk.
+//
+// A positive maxBytes cuts the output at a line boundary once it exceeds that size; the second result reports the cut.
+func renderUnifiedContentDiff(before string, after string, beforeLabel string, afterLabel string, maxBytes int) (string, bool) {
+	hunks := buildStructuredDiff(before, after)
+	if len(hunks) == 0 {
+		return "", false
+	}
+	hunk := hunks[0]
+	beforeLines := splitStructuredDiffLines(before)
+	changeStart := hunk.OldStart - 1
+	changeEnd := changeStart + hunk.OldLines
+	leading := beforeLines[max(changeStart-unifiedContentDiffContext, 0):changeStart]
+	trailing := beforeLines[changeEnd:min(changeEnd+unifiedContentDiffContext, len(beforeLines))]
+
+	oldLen := len(leading) + hunk.OldLines + len(trailing)
+	newLen := len(leading) + hunk.NewLines + len(trailing)
+	oldStart := changeStart - len(leading)
+	newStart := oldStart
+	if oldLen > 0 {
+		oldStart++
+	}
+	if newLen > 0 {
+		newStart++
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n@@ -%d,%d +%d,%d @@\n", beforeLabel, afterLabel, oldStart, oldLen, newStart, newLen)
+	for _, line := range leading {
+		b.WriteString(" " + line + "\n")
+	}
+	for _, line := range hunk.Before {
+		b.WriteString("-" + line + "\n")
+	}
+	for _, line := range hunk.After {
+		b.WriteString("+" + line + "\n")
+	}
+	for _, line := range trailing {
+		b.WriteString(" " + line + "\n")
+	}
+	out := b.String()
+	if maxBytes <= 0 || len(out) <= maxBytes {
+		return out, false
+	}
+	cut := strings.LastIndexByte(out[:maxBytes], '\n')
+	return out[:cut+1], true
+}
+
 func newFileMutationResult(filePath string, changeType string, before string, after string) FileMutationResult {
 	result := FileMutationResult{
 		FilePath:   strings.TrimSpace(filePath),
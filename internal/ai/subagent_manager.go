@@ -978,7 +978,7 @@ func sanitizeSubagentToolAllowlist(allowlist []string, fallback []string, readon
 
 func isSubagentDisallowedTool(name string) bool {
 	switch strings.TrimSpace(name) {
-	case "subagents", "write_todos", "ask_user":
+	case "subagents", "spawn_subtask", "write_todos", "scratchpad", "ask_user":
 		return true
 	default:
 		return false
@@ -1185,6 +1185,16 @@ func (m *subagentManager) runTask(task *subagentTask, firstInput string) {
 			ToolAllowlist:         append([]string(nil), task.allowedTools...),
 			ForceReadonlyExec:     task.forceReadonlyExec,
 			NoUserInteraction:     true,
+			ToolRateLimiter:       m.parent.toolRateLimiter,
+			WebSearchCache:        m.parent.webSearchCache,
+			ProviderRecorder:      m.parent.providerRecorder,
+			ProviderSession:       m.parent.providerSession,
+			ProviderReplayTapes:   m.parent.providerReplays,
+			ProviderCircuits:      m.parent.providerCircuits,
+			ProviderHTTPClients:   m.parent.providerHTTP,
+			Audit:                 m.parent.audit,
+			Tracer:                m.parent.tracer,
+			Metrics:               m.parent.serviceMetrics,
 		})
 
 		req := RunRequest{
@@ -1196,6 +1206,7 @@ func (m *subagentManager) runTask(task *subagentTask, firstInput string) {
 				Mode:            task.mode,
 				MaxSteps:        task.maxSteps,
 				MaxNoToolRounds: nativeDefaultNoToolRounds,
+				PromptProfile:   m.parent.promptProfile,
 			},
 		}
 
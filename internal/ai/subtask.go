@@ -0,0 +1,254 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	aitools "github.com/floegence/redeven/internal/ai/tools"
+	"github.com/floegence/redeven/internal/config"
+)
+
+// maxSubtaskDepth bounds spawn_subtask nesting. The root loop is depth 0.
+const maxSubtaskDepth = 2
+
+const (
+	subtaskStatusCompleted = "completed"
+	subtaskStatusFailed    = "failed"
+	subtaskStatusCanceled  = "canceled"
+	subtaskStatusTimedOut  = "timed_out"
+)
+
+func deriveChildLoopBudget(parent LoopBudget, hint BudgetHint) LoopBudget {
+	child := parent
+	if hint.MaxSteps > 0 && hint.MaxSteps < child.MaxSteps {
+		child.MaxSteps = hint.MaxSteps
+	}
+	if child.MaxSteps <= 0 {
+		child.MaxSteps = nativeDefaultMaxSteps
+	}
+	return child
+}
+
+func newAgentLoop(runID string, parent *AgentLoop, budget LoopBudget) *AgentLoop {
+	loop := &AgentLoop{
+		runID:        strings.TrimSpace(runID),
+		parent:       parent,
+		budget:       budget,
+		deriveBudget: deriveChildLoopBudget,
+	}
+	if parent != nil {
+		loop.depth = parent.depth + 1
+		if parent.deriveBudget != nil {
+			loop.deriveBudget = parent.deriveBudget
+		}
+	}
+	return loop
+}
+
+// subtaskBudgetHint caps a child at half of the parent's steps, so each level
+// of nesting gets strictly less room than the loop that spawned it.
+func subtaskBudgetHint(parent LoopBudget, requested int) BudgetHint {
+	ceiling := parent.MaxSteps / 2
+	if ceiling < 1 {
+		ceiling = 1
+	}
+	if requested <= 0 || requested > ceiling {
+		requested = ceiling
+	}
+	return BudgetHint{MaxSteps: requested}
+}
+
+func (r *run) subtaskDepth() int {
+	if r == nil || r.parentLoop == nil {
+		return 0
+	}
+	return r.parentLoop.depth + 1
+}
+
+func (r *run) canSpawnSubtask() bool {
+	return r != nil && r.subtaskDepth() < maxSubtaskDepth
+}
+
+// subtaskMayMutate reports whether a spawn_subtask child of r could change the workspace.
+//
+// The child inherits r's mode and tool allowlist, so it is read-only only in plan mode or when every
+// allowlisted tool is a known non-mutating built-in (terminal.exec counts unless exec is forced read-only).
+func (r *run) subtaskMayMutate() bool {
+	if r == nil || strings.EqualFold(strings.TrimSpace(r.runMode), config.AIModePlan) {
+		return false
+	}
+	if len(r.toolAllowlist) == 0 {
+		return true
+	}
+	for name := range r.toolAllowlist {
+		def, ok := aitools.LookupDefinition(name)
+		if !ok || def.Mutating || (name == "terminal.exec" && !r.forceReadonlyExec) {
+			return true
+		}
+	}
+	return false
+}
+
+func subtaskEventPayload(runID string, depth int, budget LoopBudget) map[string]any {
+	return map[string]any{
+		"subtask_run_id": runID,
+		"depth":          depth,
+		"budget": map[string]any{
+			"max_steps": budget.MaxSteps,
+		},
+	}
+}
+
+// spawnSubtask runs an isolated child native loop for one objective and blocks
+// until it ends. The child inherits the parent's model, mode, and tool limits,
+// and stops as soon as the parent's tool context is canceled.
+func (r *run) spawnSubtask(ctx context.Context, args map[string]any) (map[string]any, error) {
+	if r == nil {
+		return nil, errors.New("nil run")
+	}
+	loop := r.agentLoop
+	if loop == nil {
+		return nil, errors.New("spawn_subtask is unavailable in this run")
+	}
+	depth := loop.depth + 1
+	if depth > maxSubtaskDepth {
+		return nil, fmt.Errorf("subtask depth limit reached (max %d)", maxSubtaskDepth)
+	}
+	objective := strings.TrimSpace(anyToString(args["objective"]))
+	if objective == "" {
+		return nil, errors.New("missing objective")
+	}
+	input := objective
+	if extra := strings.TrimSpace(anyToString(args["context"])); extra != "" {
+		input = objective + "\n\nContext:\n" + extra
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	modelID := strings.TrimSpace(r.currentModelID)
+	if modelID == "" && r.cfg != nil {
+		if def, ok := r.cfg.ResolvedCurrentModelID(); ok {
+			modelID = def
+		}
+	}
+	if modelID == "" {
+		return nil, errors.New("missing model for subtask")
+	}
+	budget := loop.deriveBudget(loop.budget, subtaskBudgetHint(loop.budget, parseIntArg(args, "max_steps", 0)))
+
+	runID, err := NewRunID()
+	if err != nil {
+		return nil, err
+	}
+	messageID, err := newMessageID()
+	if err != nil {
+		return nil, err
+	}
+	allowlist := make([]string, 0, len(r.toolAllowlist))
+	for name := range r.toolAllowlist {
+		allowlist = append(allowlist, name)
+	}
+	sort.Strings(allowlist)
+
+	child := newRun(runOptions{
+		Log:                   r.log,
+		StateDir:              r.stateDir,
+		AgentHomeDir:          r.agentHomeDir,
+		WorkingDir:            r.workingDir,
+		Shell:                 r.shell,
+		AIConfig:              r.cfg,
+		SessionMeta:           r.sessionMeta,
+		ResolveProviderKey:    r.resolveProviderKey,
+		ResolveWebSearchKey:   r.resolveWebSearchKey,
+		RunID:                 runID,
+		ChannelID:             r.channelID,
+		EndpointID:            r.endpointID,
+		ThreadID:              r.threadID,
+		UserPublicID:          r.userPublicID,
+		MessageID:             messageID,
+		MaxWallTime:           r.maxWallTime,
+		IdleTimeout:           r.idleTimeout,
+		ToolApprovalTimeout:   r.toolApprovalTO,
+		SubagentDepth:         r.subagentDepth + 1,
+		AllowSubagentDelegate: false,
+		ToolAllowlist:         allowlist,
+		ForceReadonlyExec:     r.forceReadonlyExec,
+		NoUserInteraction:     true,
+		ToolRateLimiter:       r.toolRateLimiter,
+		WebSearchCache:        r.webSearchCache,
+		ProviderRecorder:      r.providerRecorder,
+		ProviderSession:       r.providerSession,
+		ProviderReplayTapes:   r.providerReplays,
+		ProviderCircuits:      r.providerCircuits,
+		ProviderHTTPClients:   r.providerHTTP,
+		Audit:                 r.audit,
+		Tracer:                r.tracer,
+		Metrics:               r.serviceMetrics,
+		ParentLoop:            loop,
+		terminalExecRunner:    r.terminalExecRunner,
+		tokenCounter:          r.tokenCounter,
+	})
+
+	spawned := subtaskEventPayload(runID, depth, budget)
+	spawned["objective"] = truncateRunes(objective, 240)
+	r.persistRunEvent("subtask.spawned", RealtimeStreamKindLifecycle, spawned)
+
+	startedAt := time.Now()
+	runErr := child.run(ctx, RunRequest{
+		Model:     modelID,
+		Objective: objective,
+		Input:     RunInput{Text: input},
+		Options: RunOptions{
+			Mode:            r.runMode,
+			MaxSteps:        budget.MaxSteps,
+			MaxNoToolRounds: nativeDefaultNoToolRounds,
+			PromptProfile:   r.promptProfile,
+		},
+	})
+	assistantMessageJSON, assistantText, _, snapshotErr := child.snapshotAssistantMessageJSON()
+	if snapshotErr != nil {
+		assistantMessageJSON = ""
+		assistantText = ""
+	}
+	completion := extractSubagentCompletionPayload(assistantMessageJSON, assistantText)
+	stats := collectSubagentExecutionStats(child, assistantMessageJSON)
+	finalReason := strings.TrimSpace(child.getFinalizationReason())
+
+	status := subtaskStatusCompleted
+	errText := ""
+	switch {
+	case runErr != nil && errors.Is(runErr, context.DeadlineExceeded):
+		status = subtaskStatusTimedOut
+	case runErr != nil && errors.Is(runErr, context.Canceled):
+		status = subtaskStatusCanceled
+	case runErr != nil:
+		status = subtaskStatusFailed
+		_, errText = subagentFailureFromRunError(runErr)
+	case classifyFinalizationReason(finalReason) != finalizationClassSuccess:
+		status = subtaskStatusFailed
+	}
+
+	completed := subtaskEventPayload(runID, depth, budget)
+	completed["status"] = status
+	completed["finalization_reason"] = finalReason
+	completed["tool_calls"] = stats.toolCalls
+	completed["tokens"] = stats.tokens
+	completed["elapsed_ms"] = time.Since(startedAt).Milliseconds()
+	r.persistRunEvent("subtask.completed", RealtimeStreamKindLifecycle, completed)
+
+	if status == subtaskStatusCanceled && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	out := cloneAnyMap(completed)
+	out["summary"] = completion.summary
+	out["evidence_refs"] = cloneStringSlice(completion.evidenceRefs)
+	if errText != "" {
+		out["error"] = errText
+	}
+	return out, nil
+}
@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestSubtaskBudgetHint_LowersChildSteps(t *testing.T) {
+	t.Parallel()
+
+	parent := LoopBudget{MaxSteps: 24}
+	cases := []struct {
+		requested int
+		want      int
+	}{
+		{requested: 0, want: 12},
+		{requested: 5, want: 5},
+		{requested: 40, want: 12},
+	}
+	for _, tc := range cases {
+		got := deriveChildLoopBudget(parent, subtaskBudgetHint(parent, tc.requested))
+		if got.MaxSteps != tc.want {
+			t.Fatalf("requested=%d max_steps=%d, want %d", tc.requested, got.MaxSteps, tc.want)
+		}
+	}
+	if got := deriveChildLoopBudget(LoopBudget{MaxSteps: 1}, subtaskBudgetHint(LoopBudget{MaxSteps: 1}, 0)); got.MaxSteps != 1 {
+		t.Fatalf("single-step parent max_steps=%d, want 1", got.MaxSteps)
+	}
+
+	root := newAgentLoop("run_root", nil, parent)
+	child := newAgentLoop("run_child", root, LoopBudget{MaxSteps: 12})
+	if root.depth != 0 || child.depth != 1 || child.parent != root {
+		t.Fatalf("root depth=%d child depth=%d", root.depth, child.depth)
+	}
+}
+
+func TestRegisterBuiltInTools_SpawnSubtaskHiddenAtMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	root := newAgentLoop("run_root", nil, LoopBudget{MaxSteps: 24})
+	child := newAgentLoop("run_child", root, LoopBudget{MaxSteps: 12})
+	for _, tc := range []struct {
+		parent *AgentLoop
+		want   bool
+	}{
+		{parent: nil, want: true},
+		{parent: root, want: true},
+		{parent: child, want: false},
+	} {
+		reg := NewInMemoryToolRegistry()
+		if err := registerBuiltInTools(reg, &run{parentLoop: tc.parent}); err != nil {
+			t.Fatalf("registerBuiltInTools: %v", err)
+		}
+		if _, _, ok := reg.resolve("spawn_subtask"); ok != tc.want {
+			t.Fatalf("subtask depth=%d spawn_subtask registered=%v, want %v", (&run{parentLoop: tc.parent}).subtaskDepth(), ok, tc.want)
+		}
+	}
+}
+
+func TestSpawnSubtask_RejectsDepthLimitAndCanceledParent(t *testing.T) {
+	t.Parallel()
+
+	root := newAgentLoop("run_root", nil, LoopBudget{MaxSteps: 24})
+	deep := newAgentLoop("run_deep", newAgentLoop("run_child", root, LoopBudget{MaxSteps: 12}), LoopBudget{MaxSteps: 6})
+	r := newRun(runOptions{Log: slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))})
+	r.agentLoop = deep
+	if _, err := r.spawnSubtask(context.Background(), map[string]any{"objective": "inspect"}); err == nil || !strings.Contains(err.Error(), "depth limit") {
+		t.Fatalf("err=%v, want depth limit", err)
+	}
+
+	r.agentLoop = root
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.spawnSubtask(ctx, map[string]any{"objective": "inspect"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err=%v, want context.Canceled", err)
+	}
+}
+
+func TestSpawnSubtask_RunsChildAndPersistsEvents(t *testing.T) {
+	t.Parallel()
+
+	mock := &subagentOpenAISimpleMock{}
+	srv := httptest.NewServer(http.HandlerFunc(mock.handle))
+	t.Cleanup(srv.Close)
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	meta := &session.Meta{EndpointID: "env_subtask", ChannelID: "ch_subtask", UserPublicID: "u_test", CanRead: true, CanWrite: true, CanExecute: true}
+	r := newRun(runOptions{
+		Log:          slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
+		AgentHomeDir: t.TempDir(),
+		Shell:        "bash",
+		AIConfig: &config.AIConfig{
+			Providers: []config.AIProvider{{
+				ID:      "openai",
+				Type:    "openai",
+				BaseURL: strings.TrimSuffix(srv.URL, "/") + "/v1",
+				Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+			}},
+		},
+		SessionMeta: meta,
+		ResolveProviderKey: func(providerID string) (string, bool, error) {
+			return "sk-test", true, nil
+		},
+		RunID:            "run_subtask_parent",
+		ChannelID:        meta.ChannelID,
+		EndpointID:       meta.EndpointID,
+		ThreadID:         "th_subtask",
+		UserPublicID:     meta.UserPublicID,
+		MessageID:        "m_subtask_parent",
+		ThreadsDB:        db,
+		PersistOpTimeout: 2 * time.Second,
+	})
+	r.currentModelID = "openai/gpt-5-mini"
+	r.runMode = config.AIModePlan
+	r.agentLoop = newAgentLoop(r.id, nil, LoopBudget{MaxSteps: 8})
+
+	out, err := r.spawnSubtask(context.Background(), map[string]any{"objective": "Summarize the workspace.", "max_steps": 20})
+	if err != nil {
+		t.Fatalf("spawnSubtask: %v", err)
+	}
+	if out["status"] != subtaskStatusCompleted || out["depth"] != 1 {
+		t.Fatalf("result=%#v", out)
+	}
+	if budget, _ := out["budget"].(map[string]any); budget["max_steps"] != 4 {
+		t.Fatalf("budget=%#v, want max_steps 4", out["budget"])
+	}
+	if !strings.Contains(anyToString(out["summary"]), "Subagent completed.") {
+		t.Fatalf("summary=%q", anyToString(out["summary"]))
+	}
+
+	events, err := db.ListRunEvents(context.Background(), "env_subtask", "run_subtask_parent", 100)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, ev := range events {
+		if strings.HasPrefix(ev.EventType, "subtask.") {
+			if !strings.Contains(ev.PayloadJSON, `"depth":1`) || !strings.Contains(ev.PayloadJSON, `"max_steps":4`) {
+				t.Fatalf("%s payload=%s", ev.EventType, ev.PayloadJSON)
+			}
+			seen[ev.EventType] = true
+		}
+	}
+	if !seen["subtask.spawned"] || !seen["subtask.completed"] {
+		t.Fatalf("subtask events=%v", seen)
+	}
+}
@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	aitools "github.com/floegence/redeven/internal/ai/tools"
+	"github.com/floegence/redeven/internal/auditlog"
+	"github.com/floegence/redeven/internal/config"
+)
+
+// commandDeniedSummary is the tool result summary for terminal.exec calls rejected by ai.terminal_command_policy.
+const commandDeniedSummary = "command_denied"
+
+const terminalCommandPolicyAuditAction = "ai_terminal_command_denied"
+
+// terminalCommandDecision is the outcome of ai.terminal_command_policy for one command.
+type terminalCommandDecision struct {
+	Denied bool
+	// Rule names the rule that denied the command, for example `terminal_command_policy.deny[0] "^sudo\b"`.
+	Rule string
+}
+
+// evaluateTerminalCommandPolicy checks each simple command of command against the deny list, then the allow list.
+//
+// The command is split into segments on `;`, `&&`, `||`, `|`, and `&`, with subshells, command
+// substitutions, and `bash -c` wrappers expanded, so a chain cannot hide a denied command behind an
+// allowed one. Deny wins over allow: the command is denied when any segment matches a deny expression.
+// A non-empty allow list denies the command unless every segment matches one of its expressions.
+// The expressions are compiled once by config validation; a policy that fails to compile denies every command.
+func evaluateTerminalCommandPolicy(policy *config.AITerminalCommandPolicy, command string) terminalCommandDecision {
+	compiled, err := policy.Compiled()
+	if err != nil {
+		return terminalCommandDecision{Denied: true, Rule: err.Error()}
+	}
+	if compiled == nil {
+		return terminalCommandDecision{}
+	}
+	segments := aitools.TerminalCommandSegments(command)
+	if len(segments) == 0 {
+		segments = []string{strings.TrimSpace(command)}
+	}
+	for _, segment := range segments {
+		for i, re := range compiled.Deny {
+			if re.MatchString(segment) {
+				return terminalCommandDecision{Denied: true, Rule: fmt.Sprintf("terminal_command_policy.deny[%d] %q", i, re.String())}
+			}
+		}
+	}
+	if len(compiled.Allow) == 0 {
+		return terminalCommandDecision{}
+	}
+	for _, segment := range segments {
+		if !matchesAnyTerminalCommandExpression(compiled.Allow, segment) {
+			return terminalCommandDecision{Denied: true, Rule: "terminal_command_policy.allow (no expression matched)"}
+		}
+	}
+	return terminalCommandDecision{}
+}
+
+func matchesAnyTerminalCommandExpression(expressions []*regexp.Regexp, segment string) bool {
+	for _, re := range expressions {
+		if re.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordTerminalCommandDenied persists and audits one terminal_command_policy match.
+//
+// warnOnly marks matches that were only logged because the policy runs in "warn" mode.
+func (r *run) recordTerminalCommandDenied(toolID string, command string, decision terminalCommandDecision, warnOnly bool) {
+	if r == nil {
+		return
+	}
+	r.persistRunEvent("tool.command_denied", RealtimeStreamKindLifecycle, map[string]any{
+		"tool_id":   toolID,
+		"tool_name": "terminal.exec",
+		"command":   sanitizeLogText(command, 200),
+		"rule":      decision.Rule,
+		"warn_only": warnOnly,
+	})
+	if r.log != nil {
+		r.log.Warn("ai terminal command matched terminal_command_policy",
+			"run_id", r.id,
+			"thread_id", r.threadID,
+			"tool_id", toolID,
+			"rule", decision.Rule,
+			"warn_only", warnOnly,
+		)
+	}
+	meta := r.sessionMeta
+	if r.audit == nil || meta == nil {
+		return
+	}
+	status := "failure"
+	if warnOnly {
+		status = "success"
+	}
+	r.audit.Append(auditlog.Entry{
+		Action:            terminalCommandPolicyAuditAction,
+		Status:            status,
+		Error:             decision.Rule,
+		ChannelID:         strings.TrimSpace(meta.ChannelID),
+		EnvPublicID:       strings.TrimSpace(meta.EndpointID),
+		NamespacePublicID: strings.TrimSpace(meta.NamespacePublicID),
+		UserPublicID:      strings.TrimSpace(meta.UserPublicID),
+		UserEmail:         strings.TrimSpace(meta.UserEmail),
+		FloeApp:           strings.TrimSpace(meta.FloeApp),
+		Detail: map[string]any{
+			"run_id":    r.id,
+			"thread_id": r.threadID,
+			"tool_id":   toolID,
+			"rule":      decision.Rule,
+			"warn_only": warnOnly,
+			"command":   sanitizeLogText(command, 200),
+		},
+	})
+}
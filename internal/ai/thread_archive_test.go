@@ -0,0 +1,215 @@
+package ai
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestArchiveThread_CancelsActiveRunAndRejectsNewRuns(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	ctx := context.Background()
+	meta := newThreadRunConcurrencyTestMeta("env_thread_archive_active")
+	thread, err := svc.CreateThread(ctx, meta, "archive active", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	req := RunStartRequest{ThreadID: thread.ThreadID, Model: "openai/gpt-5-mini", Input: RunInput{Text: "hello"}, Options: RunOptions{MaxSteps: 1}}
+
+	active, err := svc.prepareRun(context.Background(), meta, "run_archive_active", req, nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun: %v", err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, active) })
+
+	canceled, err := svc.ArchiveThread(ctx, meta, thread.ThreadID)
+	if err != nil {
+		t.Fatalf("ArchiveThread: %v", err)
+	}
+	if canceled != 1 {
+		t.Fatalf("canceled runs=%d, want 1", canceled)
+	}
+	if reason := active.r.getCancelReason(); reason != "canceled" {
+		t.Fatalf("active run cancel reason=%q, want canceled", reason)
+	}
+	if svc.HasActiveThreadForEndpoint(meta.EndpointID, thread.ThreadID) {
+		t.Fatalf("thread still has an active run after archive")
+	}
+
+	if _, err := svc.prepareRun(context.Background(), meta, "run_archive_after", req, nil, nil); !errors.Is(err, ErrThreadArchived) {
+		t.Fatalf("prepareRun after archive err=%v, want ErrThreadArchived", err)
+	}
+
+	view, err := svc.GetThread(ctx, meta, thread.ThreadID)
+	if err != nil || view != nil {
+		t.Fatalf("GetThread after archive view=%+v err=%v, want hidden", view, err)
+	}
+	list, err := svc.ListThreads(ctx, meta, 10, "")
+	if err != nil {
+		t.Fatalf("ListThreads: %v", err)
+	}
+	if len(list.Threads) != 0 {
+		t.Fatalf("ListThreads after archive=%+v, want none", list.Threads)
+	}
+
+	// History stays in the store; only the archive marker changes.
+	stored, err := svc.threadsDB.GetThread(ctx, meta.EndpointID, thread.ThreadID)
+	if err != nil || stored == nil {
+		t.Fatalf("store GetThread stored=%v err=%v", stored, err)
+	}
+	if stored.ArchivedAtUnixMs <= 0 {
+		t.Fatalf("archived_at_unix_ms=%d, want > 0", stored.ArchivedAtUnixMs)
+	}
+}
+
+func TestArchiveThread_RejectsQueuedRunWaitingOnThread(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	svc.mu.Lock()
+	svc.cfg.ThreadConcurrency = &config.AIThreadConcurrencyPolicy{OnBusy: config.AIThreadBusyQueue}
+	svc.mu.Unlock()
+
+	ctx := context.Background()
+	meta := newThreadRunConcurrencyTestMeta("env_thread_archive_queued")
+	thread, err := svc.CreateThread(ctx, meta, "archive queued", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	req := RunStartRequest{ThreadID: thread.ThreadID, Model: "openai/gpt-5-mini", Input: RunInput{Text: "hello"}, Options: RunOptions{MaxSteps: 1}}
+
+	active, err := svc.prepareRun(context.Background(), meta, "run_archive_queued_1", req, nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun first: %v", err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, active) })
+
+	queuedErr := make(chan error, 1)
+	go func() {
+		prepared, err := svc.prepareRun(context.Background(), meta, "run_archive_queued_2", req, nil, nil)
+		if prepared != nil {
+			releasePreparedRunForTest(svc, prepared)
+		}
+		queuedErr <- err
+	}()
+	select {
+	case err := <-queuedErr:
+		t.Fatalf("queued run returned before archive: %v", err)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	if _, err := svc.ArchiveThread(ctx, meta, thread.ThreadID); err != nil {
+		t.Fatalf("ArchiveThread: %v", err)
+	}
+
+	select {
+	case err := <-queuedErr:
+		if !errors.Is(err, ErrThreadArchived) {
+			t.Fatalf("queued run err=%v, want ErrThreadArchived", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("queued run did not return after archive")
+	}
+}
+
+func TestArchiveThread_MissingThread(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	meta := newThreadRunConcurrencyTestMeta("env_thread_archive_missing")
+	if _, err := svc.ArchiveThread(context.Background(), meta, "th_missing"); err == nil {
+		t.Fatalf("ArchiveThread missing thread err=nil")
+	}
+	svc.mu.Lock()
+	claimed := svc.archivedByTh[runThreadKey(meta.EndpointID, "th_missing")]
+	svc.mu.Unlock()
+	if claimed {
+		t.Fatalf("failed archive left the thread claimed")
+	}
+}
+
+func TestDeleteThread_DropsArchiveClaim(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	ctx := context.Background()
+	meta := newThreadRunConcurrencyTestMeta("env_thread_archive_delete")
+	thread, err := svc.CreateThread(ctx, meta, "archive then delete", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	if _, err := svc.ArchiveThread(ctx, meta, thread.ThreadID); err != nil {
+		t.Fatalf("ArchiveThread: %v", err)
+	}
+	thKey := runThreadKey(meta.EndpointID, thread.ThreadID)
+	svc.mu.Lock()
+	claimed := svc.archivedByTh[thKey]
+	svc.mu.Unlock()
+	if !claimed {
+		t.Fatalf("archive did not claim the thread")
+	}
+
+	if err := svc.DeleteThread(ctx, meta, thread.ThreadID, false); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+	svc.mu.Lock()
+	_, kept := svc.archivedByTh[thKey]
+	svc.mu.Unlock()
+	if kept {
+		t.Fatalf("hard delete left the archive claim in memory")
+	}
+}
+
+func TestArchiveThread_HidesThreadFromSearchForkAndMessages(t *testing.T) {
+	t.Parallel()
+
+	svc := newSendTurnTestService(t)
+	meta := testSendTurnMeta()
+	ctx := context.Background()
+
+	thread, err := svc.CreateThread(ctx, meta, "archived search", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	if _, err := svc.threadsDB.AppendMessage(ctx, meta.EndpointID, thread.ThreadID, threadstore.Message{
+		MessageID:   "msg_archived_assistant",
+		Role:        "assistant",
+		Status:      "complete",
+		TextContent: "The migration rollback needs a feature flag.",
+		MessageJSON: `{"id":"msg_archived_assistant","role":"assistant","blocks":[{"type":"markdown","content":"The migration rollback needs a feature flag."}],"status":"complete"}`,
+	}, meta.UserPublicID, meta.UserEmail); err != nil {
+		t.Fatalf("AppendMessage: %v", err)
+	}
+	out, err := svc.SearchThreadMessages(ctx, meta, "rollback", 0)
+	if err != nil || len(out.Hits) != 1 {
+		t.Fatalf("search before archive out=%+v err=%v, want 1 hit", out, err)
+	}
+
+	if _, err := svc.ArchiveThread(ctx, meta, thread.ThreadID); err != nil {
+		t.Fatalf("ArchiveThread: %v", err)
+	}
+
+	out, err = svc.SearchThreadMessages(ctx, meta, "rollback", 0)
+	if err != nil {
+		t.Fatalf("SearchThreadMessages after archive: %v", err)
+	}
+	if len(out.Hits) != 0 {
+		t.Fatalf("search after archive hits=%+v, want none", out.Hits)
+	}
+	if _, err := svc.ForkThread(ctx, meta, thread.ThreadID, "msg_archived_assistant"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ForkThread after archive err=%v, want sql.ErrNoRows", err)
+	}
+	if _, err := svc.ListThreadMessages(ctx, meta, thread.ThreadID, 10, 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ListThreadMessages after archive err=%v, want sql.ErrNoRows", err)
+	}
+	if _, err := svc.ListThreadMessagesSince(ctx, meta, thread.ThreadID, 0, 10); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ListThreadMessagesSince after archive err=%v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,182 @@
+package ai
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	contextcompactor "github.com/floegence/redeven/internal/ai/context/compactor"
+	contextmodel "github.com/floegence/redeven/internal/ai/context/model"
+	contextpacker "github.com/floegence/redeven/internal/ai/context/packer"
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/session"
+)
+
+// threadCompactedEventType marks a manual compaction of a thread's context.
+//
+// The event is recorded under a synthetic run id ("compact_" + random suffix) because no run produced it.
+const threadCompactedEventType = "thread.compacted"
+
+// CompactThread compacts the stored context of threadID now instead of waiting for a run to hit
+// context pressure.
+//
+// It assembles the thread's prompt pack, compacts it toward targetTokens (half of the current
+// estimate when 0) with the given strategy, and persists the resulting thread snapshot so the next
+// run starts from the compacted context. The thread counts as busy while compaction runs: it returns
+// ErrThreadBusy when a run is active, and run starts on the thread are rejected or queued per the
+// thread busy policy until it finishes. It returns sql.ErrNoRows when the thread does not exist.
+func (s *Service) CompactThread(ctx context.Context, meta *session.Meta, threadID string, targetTokens int, strategy string) (*CompactThreadResponse, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	endpointID := strings.TrimSpace(meta.EndpointID)
+	threadID = strings.TrimSpace(threadID)
+	if threadID == "" {
+		return nil, errors.New("missing thread_id")
+	}
+	if targetTokens < 0 {
+		return nil, errors.New("invalid target_tokens")
+	}
+	strategy = normalizeCompactionStrategy(strategy)
+
+	s.mu.Lock()
+	db := s.threadsDB
+	cfg := s.cfg
+	packer := s.contextPacker
+	compactor := s.snapshotCompactor
+	s.mu.Unlock()
+	if db == nil || packer == nil || compactor == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	th, err := db.GetThread(ctx, endpointID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if th == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	thKey := runThreadKey(endpointID, threadID)
+	s.mu.Lock()
+	if strings.TrimSpace(s.activeRunByTh[thKey]) != "" || s.compactingByTh[thKey] {
+		s.mu.Unlock()
+		return nil, ErrThreadBusy
+	}
+	if s.compactingByTh == nil {
+		s.compactingByTh = make(map[string]bool)
+	}
+	s.compactingByTh[thKey] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.compactingByTh, thKey)
+		s.notifyThreadRunReleasedLocked(thKey)
+		s.mu.Unlock()
+	}()
+
+	var (
+		resolved  resolvedRunModel
+		summarize contextcompactor.Summarizer
+	)
+	if cfg != nil {
+		var resolveErr error
+		resolved, resolveErr = s.resolveRunModel(ctx, cfg, "", th.ModelID, th.ModelLocked, nil)
+		if resolveErr != nil && strategy == CompactionStrategyModelSummary {
+			return nil, resolveErr
+		}
+	} else if strategy == CompactionStrategyModelSummary {
+		return nil, ErrNotConfigured
+	}
+	if strategy == CompactionStrategyModelSummary {
+		adapter, _, err := s.initStructuredOutputProvider(resolved)
+		if err != nil {
+			return nil, err
+		}
+		summarize = promptPackSummarizer(newProviderCompactionSummarizer(adapter, resolved.ModelName), nil)
+	}
+
+	pack, err := packer.BuildPromptPack(ctx, contextpacker.BuildInput{
+		EndpointID: endpointID,
+		ThreadID:   threadID,
+		Capability: resolved.Capability,
+	})
+	if err != nil {
+		return nil, err
+	}
+	providerType := strings.TrimSpace(resolved.Provider.Type)
+	beforeTokens := estimatePromptPackTokens(providerType, pack)
+	if targetTokens == 0 {
+		targetTokens = beforeTokens / 2
+	}
+
+	compacted, changed, verify, err := compactor.CompactPromptPackWithSummarizer(ctx, endpointID, targetTokens, pack, summarize)
+	if err != nil {
+		return nil, err
+	}
+	out := &CompactThreadResponse{
+		ThreadID:     threadID,
+		Strategy:     strategy,
+		Changed:      changed,
+		BeforeTokens: beforeTokens,
+		AfterTokens:  beforeTokens,
+		TargetTokens: targetTokens,
+	}
+	if changed {
+		out.AfterTokens = estimatePromptPackTokens(providerType, compacted)
+		out.SavingRatio = verify.SavingRatio
+	}
+
+	s.appendThreadCompactedEvent(db, endpointID, threadID, out)
+	if s.log != nil {
+		s.log.Info("ai thread compacted", "thread_id", threadID, "strategy", strategy, "changed", changed, "before_tokens", out.BeforeTokens, "after_tokens", out.AfterTokens)
+	}
+	return out, nil
+}
+
+// estimatePromptPackTokens estimates the input tokens a run would send for pack, the same way the
+// runtime estimates context pressure.
+func estimatePromptPackTokens(providerType string, pack contextmodel.PromptPack) int {
+	tokens, _ := estimateTurnTokens(providerType, TurnRequest{Messages: buildMessagesFromPromptPack(pack, "")})
+	return tokens
+}
+
+func (s *Service) appendThreadCompactedEvent(db *threadstore.Store, endpointID string, threadID string, out *CompactThreadResponse) {
+	runID, err := NewRunID()
+	if err != nil {
+		return
+	}
+	payload, err := json.Marshal(map[string]any{
+		"strategy":      out.Strategy,
+		"changed":       out.Changed,
+		"before_tokens": out.BeforeTokens,
+		"after_tokens":  out.AfterTokens,
+		"target_tokens": out.TargetTokens,
+		"saving_ratio":  out.SavingRatio,
+	})
+	if err != nil {
+		return
+	}
+	persistTO := s.persistOpTO
+	if persistTO <= 0 {
+		persistTO = defaultPersistOpTimeout
+	}
+	pctx, cancel := context.WithTimeout(context.Background(), persistTO)
+	defer cancel()
+	if err := db.AppendRunEvent(pctx, threadstore.RunEventRecord{
+		EndpointID:  endpointID,
+		ThreadID:    threadID,
+		RunID:       "compact_" + strings.TrimPrefix(runID, "run_"),
+		StreamKind:  "lifecycle",
+		EventType:   threadCompactedEventType,
+		PayloadJSON: string(payload),
+		AtUnixMs:    time.Now().UnixMilli(),
+	}); err != nil && s.log != nil {
+		s.log.Warn("persist thread compacted event failed", "thread_id", threadID, "error", err)
+	}
+}
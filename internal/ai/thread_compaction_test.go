@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+)
+
+func seedCompactionTestTurns(t *testing.T, svc *Service, endpointID string, threadID string, turns int) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	long := strings.Repeat("the build log repeats the same linker warning again ", 40)
+	for i := 0; i < turns; i++ {
+		userID := fmt.Sprintf("msg_user_%d", i)
+		assistantID := fmt.Sprintf("msg_assistant_%d", i)
+		for _, msg := range []threadstore.Message{
+			{MessageID: userID, Role: "user", TextContent: fmt.Sprintf("step %d: %s", i, long)},
+			{MessageID: assistantID, Role: "assistant", TextContent: fmt.Sprintf("answer %d: %s", i, long)},
+		} {
+			msg.ThreadID = threadID
+			msg.EndpointID = endpointID
+			msg.Status = "complete"
+			msg.CreatedAtUnixMs = now + int64(i)
+			msg.UpdatedAtUnixMs = now + int64(i)
+			msg.MessageJSON = fmt.Sprintf(`{"id":%q,"role":%q,"blocks":[{"type":"markdown","content":%q}],"status":"complete","timestamp":%d}`, msg.MessageID, msg.Role, msg.TextContent, msg.CreatedAtUnixMs)
+			if _, err := svc.threadsDB.AppendMessage(ctx, endpointID, threadID, msg, "u", "u@example.com"); err != nil {
+				t.Fatalf("AppendMessage: %v", err)
+			}
+		}
+		if err := svc.threadsDB.AppendConversationTurn(ctx, threadstore.ConversationTurn{
+			TurnID:             fmt.Sprintf("turn_%d", i),
+			EndpointID:         endpointID,
+			ThreadID:           threadID,
+			RunID:              fmt.Sprintf("run_%d", i),
+			UserMessageID:      userID,
+			AssistantMessageID: assistantID,
+			CreatedAtUnixMs:    now + int64(i),
+		}); err != nil {
+			t.Fatalf("AppendConversationTurn: %v", err)
+		}
+	}
+}
+
+func TestCompactThread_PersistsSnapshotAndEmitsEvent(t *testing.T) {
+	t.Parallel()
+
+	svc := newSendTurnTestService(t)
+	ctx := context.Background()
+	meta := testSendTurnMeta()
+	thread, err := svc.CreateThread(ctx, meta, "long thread", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	seedCompactionTestTurns(t, svc, meta.EndpointID, thread.ThreadID, 10)
+
+	out, err := svc.CompactThread(ctx, meta, thread.ThreadID, 0, "")
+	if err != nil {
+		t.Fatalf("CompactThread: %v", err)
+	}
+	if !out.Changed || out.Strategy != CompactionStrategyTruncate || out.AfterTokens >= out.BeforeTokens || out.TargetTokens != out.BeforeTokens/2 {
+		t.Fatalf("result=%+v, want a truncate compaction that shrinks the context", out)
+	}
+
+	snapshot, err := svc.contextRepo.LatestSnapshot(ctx, meta.EndpointID, thread.ThreadID, "thread")
+	if err != nil {
+		t.Fatalf("LatestSnapshot: %v", err)
+	}
+	if !strings.Contains(snapshot, "Episode snapshot:") {
+		t.Fatalf("thread snapshot=%q, want the folded turns", snapshot)
+	}
+
+	raw, err := sql.Open("sqlite", filepath.Join(svc.stateDir, "ai", "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+	var events int
+	if err := raw.QueryRowContext(ctx, `SELECT COUNT(1) FROM ai_run_events WHERE endpoint_id = ? AND thread_id = ? AND event_type = ? AND run_id LIKE 'compact_%'`, meta.EndpointID, thread.ThreadID, threadCompactedEventType).Scan(&events); err != nil {
+		t.Fatalf("count events: %v", err)
+	}
+	if events != 1 {
+		t.Fatalf("thread.compacted events=%d, want 1", events)
+	}
+}
+
+func TestCompactThread_SerializesWithRuns(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	ctx := context.Background()
+	meta := newThreadRunConcurrencyTestMeta("env_thread_compact_busy")
+	thread, err := svc.CreateThread(ctx, meta, "compact busy", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	req := RunStartRequest{ThreadID: thread.ThreadID, Model: "openai/gpt-5-mini", Input: RunInput{Text: "hello"}, Options: RunOptions{MaxSteps: 1}}
+
+	first, err := svc.prepareRun(context.Background(), meta, "run_compact_busy_1", req, nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun: %v", err)
+	}
+	if _, err := svc.CompactThread(ctx, meta, thread.ThreadID, 0, ""); !errors.Is(err, ErrThreadBusy) {
+		t.Fatalf("CompactThread during a run err=%v, want ErrThreadBusy", err)
+	}
+	releasePreparedRunForTest(svc, first)
+
+	thKey := runThreadKey(meta.EndpointID, thread.ThreadID)
+	svc.mu.Lock()
+	svc.compactingByTh[thKey] = true
+	svc.mu.Unlock()
+	if _, err := svc.prepareRun(context.Background(), meta, "run_compact_busy_2", req, nil, nil); !errors.Is(err, ErrThreadBusy) {
+		t.Fatalf("prepareRun during compaction err=%v, want ErrThreadBusy", err)
+	}
+	if _, err := svc.CompactThread(ctx, meta, thread.ThreadID, 0, ""); !errors.Is(err, ErrThreadBusy) {
+		t.Fatalf("concurrent CompactThread err=%v, want ErrThreadBusy", err)
+	}
+	svc.mu.Lock()
+	delete(svc.compactingByTh, thKey)
+	svc.mu.Unlock()
+
+	if _, err := svc.CompactThread(ctx, meta, thread.ThreadID, 0, ""); err != nil {
+		t.Fatalf("CompactThread on an idle thread: %v", err)
+	}
+	second, err := svc.prepareRun(context.Background(), meta, "run_compact_busy_3", req, nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun after compaction: %v", err)
+	}
+	releasePreparedRunForTest(svc, second)
+}
@@ -0,0 +1,207 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func newThreadRunConcurrencyTestMeta(endpointID string) *session.Meta {
+	return &session.Meta{
+		EndpointID:        endpointID,
+		NamespacePublicID: "ns_" + endpointID,
+		ChannelID:         "ch_" + endpointID,
+		UserPublicID:      "user_" + endpointID,
+		UserEmail:         endpointID + "@example.com",
+		CanRead:           true,
+		CanWrite:          true,
+		CanExecute:        true,
+		CanAdmin:          true,
+	}
+}
+
+func releasePreparedRunForTest(svc *Service, prepared *preparedRun) {
+	svc.mu.Lock()
+	delete(svc.runs, prepared.runID)
+	if svc.activeRunByTh[prepared.thKey] == prepared.runID {
+		svc.releaseActiveThreadRunLocked(prepared.thKey)
+	}
+	svc.mu.Unlock()
+	prepared.r.markDone()
+}
+
+func TestPrepareRun_RejectsSecondRunOnBusyThreadByDefault(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	ctx := context.Background()
+	meta := newThreadRunConcurrencyTestMeta("env_thread_busy_reject")
+	thread, err := svc.CreateThread(ctx, meta, "busy reject", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	req := RunStartRequest{ThreadID: thread.ThreadID, Model: "openai/gpt-5-mini", Input: RunInput{Text: "hello"}, Options: RunOptions{MaxSteps: 1}}
+
+	first, err := svc.prepareRun(context.Background(), meta, "run_busy_reject_1", req, nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun first: %v", err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, first) })
+
+	if _, err := svc.prepareRun(context.Background(), meta, "run_busy_reject_2", req, nil, nil); !errors.Is(err, ErrThreadBusy) {
+		t.Fatalf("prepareRun second err=%v, want ErrThreadBusy", err)
+	}
+
+	list, err := svc.ListThreads(ctx, meta, 10, "")
+	if err != nil {
+		t.Fatalf("ListThreads: %v", err)
+	}
+	if len(list.Threads) != 1 || !list.Threads[0].Busy {
+		t.Fatalf("ListThreads busy state=%+v, want one busy thread", list.Threads)
+	}
+}
+
+func TestPrepareRun_QueuePolicySerializesRunsOnSameThread(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	svc.mu.Lock()
+	svc.cfg.ThreadConcurrency = &config.AIThreadConcurrencyPolicy{OnBusy: config.AIThreadBusyQueue}
+	svc.mu.Unlock()
+
+	ctx := context.Background()
+	meta := newThreadRunConcurrencyTestMeta("env_thread_busy_queue")
+	thread, err := svc.CreateThread(ctx, meta, "busy queue", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	req := RunStartRequest{ThreadID: thread.ThreadID, Model: "openai/gpt-5-mini", Input: RunInput{Text: "hello"}, Options: RunOptions{MaxSteps: 1}}
+
+	first, err := svc.prepareRun(context.Background(), meta, "run_busy_queue_1", req, nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun first: %v", err)
+	}
+
+	type result struct {
+		prepared *preparedRun
+		err      error
+	}
+	secondCh := make(chan result, 1)
+	go func() {
+		prepared, err := svc.prepareRun(context.Background(), meta, "run_busy_queue_2", req, nil, nil)
+		secondCh <- result{prepared: prepared, err: err}
+	}()
+
+	select {
+	case res := <-secondCh:
+		t.Fatalf("second run started while first run was active: %+v", res)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	releasePreparedRunForTest(svc, first)
+
+	var second result
+	select {
+	case second = <-secondCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("queued run did not start after the active run finished")
+	}
+	if second.err != nil {
+		t.Fatalf("prepareRun second: %v", second.err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, second.prepared) })
+
+	svc.mu.Lock()
+	active := svc.activeRunByTh[runThreadKey(meta.EndpointID, thread.ThreadID)]
+	svc.mu.Unlock()
+	if active != "run_busy_queue_2" {
+		t.Fatalf("active run=%q, want run_busy_queue_2", active)
+	}
+}
+
+func TestPrepareRun_QueuePolicyTimesOutWithThreadBusy(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	timeoutMS := 50
+	svc.mu.Lock()
+	svc.cfg.ThreadConcurrency = &config.AIThreadConcurrencyPolicy{OnBusy: config.AIThreadBusyQueue, QueueTimeoutMS: &timeoutMS}
+	svc.mu.Unlock()
+
+	ctx := context.Background()
+	meta := newThreadRunConcurrencyTestMeta("env_thread_busy_timeout")
+	thread, err := svc.CreateThread(ctx, meta, "busy timeout", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	req := RunStartRequest{ThreadID: thread.ThreadID, Model: "openai/gpt-5-mini", Input: RunInput{Text: "hello"}, Options: RunOptions{MaxSteps: 1}}
+
+	first, err := svc.prepareRun(context.Background(), meta, "run_busy_timeout_1", req, nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun first: %v", err)
+	}
+	t.Cleanup(func() { releasePreparedRunForTest(svc, first) })
+
+	if _, err := svc.prepareRun(context.Background(), meta, "run_busy_timeout_2", req, nil, nil); !errors.Is(err, ErrThreadBusy) {
+		t.Fatalf("prepareRun second err=%v, want ErrThreadBusy", err)
+	}
+}
+
+func TestPrepareRun_QueuedStartStopsWhenCallerCancels(t *testing.T) {
+	t.Parallel()
+
+	svc := newRealtimeTestService(t, 2*time.Second)
+	svc.mu.Lock()
+	svc.cfg.ThreadConcurrency = &config.AIThreadConcurrencyPolicy{OnBusy: config.AIThreadBusyQueue}
+	svc.mu.Unlock()
+
+	meta := newThreadRunConcurrencyTestMeta("env_thread_busy_cancel")
+	thread, err := svc.CreateThread(context.Background(), meta, "busy cancel", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	req := RunStartRequest{ThreadID: thread.ThreadID, Model: "openai/gpt-5-mini", Input: RunInput{Text: "hello"}, Options: RunOptions{MaxSteps: 1}}
+
+	first, err := svc.prepareRun(context.Background(), meta, "run_busy_cancel_1", req, nil, nil)
+	if err != nil {
+		t.Fatalf("prepareRun first: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		prepared, err := svc.prepareRun(ctx, meta, "run_busy_cancel_2", req, nil, nil)
+		if prepared != nil {
+			releasePreparedRunForTest(svc, prepared)
+		}
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("queued run returned before cancel: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("prepareRun second err=%v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("queued run kept waiting after its caller canceled")
+	}
+
+	releasePreparedRunForTest(svc, first)
+	svc.mu.Lock()
+	active := svc.activeRunByTh[runThreadKey(meta.EndpointID, thread.ThreadID)]
+	svc.mu.Unlock()
+	if active != "" {
+		t.Fatalf("active run=%q after cancel, want none", active)
+	}
+}
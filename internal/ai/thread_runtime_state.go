@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bounds for runtime state restored from a thread's previous run. They match the in-run limits
+// of runtimeState, so a long thread cannot grow the prompt or the loop guards without bound.
+const (
+	threadRuntimeStateMaxFacts            = 12
+	threadRuntimeStateMaxNoProgress       = 8
+	threadRuntimeStateMaxFailedSignatures = 32
+)
+
+// threadRuntimeState is the part of runtimeState that carries over between runs on the same thread.
+type threadRuntimeState struct {
+	CompletedActionFacts []string `json:"completed_action_facts,omitempty"`
+	BlockedActionFacts   []string `json:"blocked_action_facts,omitempty"`
+	NoProgressSignatures []string `json:"no_progress_signatures,omitempty"`
+	FailedSignatures     []string `json:"failed_signatures,omitempty"`
+}
+
+func (s threadRuntimeState) isEmpty() bool {
+	return len(s.CompletedActionFacts) == 0 && len(s.BlockedActionFacts) == 0 && len(s.NoProgressSignatures) == 0 && len(s.FailedSignatures) == 0
+}
+
+func (s threadRuntimeState) bounded() threadRuntimeState {
+	s.CompletedActionFacts = tailStrings(compactStrings(s.CompletedActionFacts), threadRuntimeStateMaxFacts)
+	s.BlockedActionFacts = tailStrings(compactStrings(s.BlockedActionFacts), threadRuntimeStateMaxFacts)
+	s.NoProgressSignatures = tailStrings(compactStrings(s.NoProgressSignatures), threadRuntimeStateMaxNoProgress)
+	s.FailedSignatures = tailStrings(compactStrings(s.FailedSignatures), threadRuntimeStateMaxFailedSignatures)
+	return s
+}
+
+func compactStrings(items []string) []string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// carriesThreadRuntimeState reports whether the run loads and saves thread runtime state.
+// Subagent runs share the parent's thread but keep their state to themselves.
+func (r *run) carriesThreadRuntimeState() bool {
+	return r != nil && r.threadsDB != nil && r.subagentDepth == 0 && strings.TrimSpace(r.endpointID) != "" && strings.TrimSpace(r.threadID) != ""
+}
+
+// hydrateThreadRuntimeState restores the runtime state saved by the thread's previous run into state and failedSignatures.
+func (r *run) hydrateThreadRuntimeState(ctx context.Context, state *runtimeState, failedSignatures map[string]bool) (threadRuntimeState, bool) {
+	if !r.carriesThreadRuntimeState() || state == nil {
+		return threadRuntimeState{}, false
+	}
+	readCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	raw, err := r.threadsDB.GetThreadRuntimeStateJSON(readCtx, r.endpointID, r.threadID)
+	if err != nil || raw == "" {
+		return threadRuntimeState{}, false
+	}
+	var saved threadRuntimeState
+	if err := json.Unmarshal([]byte(raw), &saved); err != nil {
+		return threadRuntimeState{}, false
+	}
+	saved = saved.bounded()
+	if saved.isEmpty() {
+		return threadRuntimeState{}, false
+	}
+	state.CompletedActionFacts = append(state.CompletedActionFacts, saved.CompletedActionFacts...)
+	state.BlockedActionFacts = append(state.BlockedActionFacts, saved.BlockedActionFacts...)
+	state.NoProgressSignatures = append(state.NoProgressSignatures, saved.NoProgressSignatures...)
+	for _, sig := range saved.FailedSignatures {
+		failedSignatures[sig] = true
+	}
+	return saved, true
+}
+
+// persistThreadRuntimeState saves the carry-over part of state for the next run on the thread.
+//
+// In summary-only persistence mode the action facts, which quote tool details, are not saved;
+// the signatures are opaque hashes and are kept.
+func (r *run) persistThreadRuntimeState(state runtimeState, failedSignatures map[string]bool) {
+	if !r.carriesThreadRuntimeState() {
+		return
+	}
+	saved := threadRuntimeState{NoProgressSignatures: state.NoProgressSignatures}
+	if !r.summaryOnlyPersist {
+		saved.CompletedActionFacts = state.CompletedActionFacts
+		saved.BlockedActionFacts = state.BlockedActionFacts
+	}
+	for sig, failed := range failedSignatures {
+		if failed {
+			saved.FailedSignatures = append(saved.FailedSignatures, sig)
+		}
+	}
+	sort.Strings(saved.FailedSignatures)
+	saved = saved.bounded()
+	raw := ""
+	if !saved.isEmpty() {
+		b, err := json.Marshal(saved)
+		if err != nil {
+			return
+		}
+		raw = string(b)
+	}
+	writeCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := r.threadsDB.SetThreadRuntimeStateJSON(writeCtx, r.endpointID, r.threadID, raw); err != nil {
+		r.debug("ai.run.runtime_state.persist_failed", "error", sanitizeLogText(err.Error(), 200))
+	}
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+)
+
+func TestThreadRuntimeState_PersistAndHydrate(t *testing.T) {
+	t.Parallel()
+
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	first := &run{threadsDB: db, endpointID: "env_1", threadID: "th_1"}
+	state := newRuntimeState("fix the build")
+	for i := 0; i < 20; i++ {
+		state.CompletedActionFacts = append(state.CompletedActionFacts, fmt.Sprintf("terminal.exec: step %d", i))
+	}
+	state.BlockedActionFacts = []string{"apply_patch: hunk did not match"}
+	state.NoProgressSignatures = []string{"sig_loop"}
+	failed := map[string]bool{"sig_loop": true, "sig_fixed": false}
+	for i := 0; i < 40; i++ {
+		failed[fmt.Sprintf("sig_%02d", i)] = true
+	}
+	first.persistThreadRuntimeState(state, failed)
+
+	next := &run{threadsDB: db, endpointID: "env_1", threadID: "th_1"}
+	restoredState := newRuntimeState("continue")
+	restoredFailed := map[string]bool{}
+	restored, ok := next.hydrateThreadRuntimeState(context.Background(), &restoredState, restoredFailed)
+	if !ok {
+		t.Fatalf("expected runtime state to be hydrated")
+	}
+	if len(restoredState.CompletedActionFacts) != threadRuntimeStateMaxFacts || restoredState.CompletedActionFacts[threadRuntimeStateMaxFacts-1] != "terminal.exec: step 19" {
+		t.Fatalf("completed facts=%v, want the last %d", restoredState.CompletedActionFacts, threadRuntimeStateMaxFacts)
+	}
+	if len(restoredState.BlockedActionFacts) != 1 || len(restoredState.NoProgressSignatures) != 1 {
+		t.Fatalf("restored state=%+v", restoredState)
+	}
+	if len(restoredFailed) != threadRuntimeStateMaxFailedSignatures || len(restored.FailedSignatures) != threadRuntimeStateMaxFailedSignatures {
+		t.Fatalf("failed signatures=%d, want %d", len(restoredFailed), threadRuntimeStateMaxFailedSignatures)
+	}
+	if restoredFailed["sig_fixed"] {
+		t.Fatalf("a signature that later succeeded must not be restored as failed")
+	}
+
+	// Subagent runs share the thread but neither read nor overwrite its runtime state.
+	child := &run{threadsDB: db, endpointID: "env_1", threadID: "th_1", subagentDepth: 1}
+	child.persistThreadRuntimeState(newRuntimeState("child"), nil)
+	childState := newRuntimeState("child")
+	if _, ok := child.hydrateThreadRuntimeState(context.Background(), &childState, map[string]bool{}); ok {
+		t.Fatalf("subagent run must not hydrate thread runtime state")
+	}
+	if _, ok := next.hydrateThreadRuntimeState(context.Background(), &restoredState, map[string]bool{}); !ok {
+		t.Fatalf("subagent run must not clear the thread runtime state")
+	}
+}
+
+func TestThreadRuntimeState_SummaryOnlyKeepsSignaturesOnly(t *testing.T) {
+	t.Parallel()
+
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	r := &run{threadsDB: db, endpointID: "env_1", threadID: "th_1", summaryOnlyPersist: true}
+	state := newRuntimeState("objective")
+	state.CompletedActionFacts = []string{"terminal.exec: cat /etc/hosts"}
+	r.persistThreadRuntimeState(state, map[string]bool{"sig_a": true})
+
+	raw, err := db.GetThreadRuntimeStateJSON(context.Background(), "env_1", "th_1")
+	if err != nil {
+		t.Fatalf("GetThreadRuntimeStateJSON: %v", err)
+	}
+	if raw != `{"failed_signatures":["sig_a"]}` {
+		t.Fatalf("saved=%s, want only the failed signature", raw)
+	}
+}
@@ -2,9 +2,11 @@ package ai
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
@@ -21,8 +23,17 @@ const (
 	autoThreadTitleMaxOutputHigh = 4096
 	autoThreadTitleMaxAttempts   = 3
 	autoThreadTitleRecoveryLimit = 128
+
+	exchangeThreadTitlePromptVersion     = "thread_title_exchange_v1"
+	exchangeThreadTitleAssistantMaxRunes = 2000
+
+	threadRetitledEventType           = "thread.retitled"
+	threadRetitleTriggerFirstExchange = "first_exchange"
+	threadRetitleTriggerManual        = "manual"
 )
 
+var errThreadRetitleNotNeeded = errors.New("thread already titled from its first exchange")
+
 type autoThreadTitleDecision struct {
 	Title  string
 	Reason string
@@ -100,6 +111,37 @@ func buildAutoThreadTitleMessages(userInput string) []Message {
 	}
 }
 
+// buildExchangeThreadTitleMessages asks for a title that reflects both the request and what the assistant did.
+func buildExchangeThreadTitleMessages(userInput string, assistantText string) []Message {
+	system := strings.Join([]string{
+		exchangeThreadTitlePromptVersion,
+		"You generate concise collaborative thread titles for an on-device coding assistant.",
+		"Return exactly one JSON object with keys: title, reason.",
+		"title must summarize the task of the exchange: the user's intent, sharpened by what the assistant found or did.",
+		"title must stay in the same language as the user text.",
+		"title must be plain text, a single line, and no more than 80 Unicode characters.",
+		"title must be specific enough for a history sidebar.",
+		"title must not mention chat, thread, assistant, or tool names unless they are central to the request.",
+		"title must not include secrets, credentials, or private values.",
+		"reason must be a short snake_case phrase.",
+		"Do not include markdown or extra text.",
+	}, "\n")
+	assistantText, _ = truncateByRunes(strings.TrimSpace(assistantText), exchangeThreadTitleAssistantMaxRunes)
+	user := strings.Join([]string{
+		"Return JSON only: output exactly one JSON object.",
+		"",
+		"Public user text:",
+		strings.TrimSpace(userInput),
+		"",
+		"Assistant answer:",
+		assistantText,
+	}, "\n")
+	return []Message{
+		{Role: "system", Content: []ContentPart{{Type: "text", Text: system}}},
+		{Role: "user", Content: []ContentPart{{Type: "text", Text: user}}},
+	}
+}
+
 func parseAutoThreadTitleDecision(raw string) (autoThreadTitleDecision, error) {
 	candidate := strings.TrimSpace(raw)
 	if candidate == "" {
@@ -180,7 +222,7 @@ func (s *Service) initStructuredOutputProvider(resolved resolvedRunModel) (Provi
 	}
 	providerType := strings.ToLower(strings.TrimSpace(resolved.Provider.Type))
 	switch providerType {
-	case "openai", "anthropic", "moonshot", "chatglm", "deepseek", "qwen", "openai_compatible":
+	case "openai", "anthropic", "moonshot", "chatglm", "deepseek", "qwen", "openai_compatible", "ollama", "azure_openai", "mistral", "bedrock":
 	default:
 		return nil, "", fmt.Errorf("unsupported provider type %q", strings.TrimSpace(resolved.Provider.Type))
 	}
@@ -191,16 +233,17 @@ func (s *Service) initStructuredOutputProvider(resolved resolvedRunModel) (Provi
 	if err != nil {
 		return nil, "", fmt.Errorf("resolve provider key failed: %w", err)
 	}
-	if !ok || strings.TrimSpace(apiKey) == "" {
+	if (!ok || strings.TrimSpace(apiKey) == "") && config.AIProviderTypeRequiresAPIKey(providerType) {
 		return nil, "", fmt.Errorf("missing api key for provider %q", resolved.ProviderID)
 	}
-	adapter, err := newProviderAdapter(providerType, strings.TrimSpace(resolved.Provider.BaseURL), strings.TrimSpace(apiKey), resolved.Provider.StrictToolSchema)
+	adapter, err := newProviderAdapterForConfig(resolved.Provider, strings.TrimSpace(apiKey), s.providerHTTPClients.get(resolved.Provider, strings.TrimSpace(apiKey)))
 	if err != nil {
 		return nil, "", fmt.Errorf("init provider adapter failed: %w", err)
 	}
+	adapter = wrapProviderToolCallFormat(adapter, resolved.Provider.EffectiveToolCallFormat())
 	responseFormat := "json_object"
 	switch providerType {
-	case "openai_compatible", "moonshot", "chatglm", "deepseek", "qwen":
+	case "openai_compatible", "moonshot", "chatglm", "deepseek", "qwen", "ollama", "mistral":
 		// Some OpenAI-compatible gateways return empty/incomplete outputs under forced
 		// json_object mode. Keep prompt-level JSON constraints and parse the text payload.
 		//
@@ -212,6 +255,10 @@ func (s *Service) initStructuredOutputProvider(resolved resolvedRunModel) (Provi
 }
 
 func (s *Service) generateAutoThreadTitleByModel(ctx context.Context, resolved resolvedRunModel, userInput string) (autoThreadTitleDecision, error) {
+	return s.generateThreadTitleByModel(ctx, resolved, buildAutoThreadTitleMessages(userInput))
+}
+
+func (s *Service) generateThreadTitleByModel(ctx context.Context, resolved resolvedRunModel, messages []Message) (autoThreadTitleDecision, error) {
 	if s == nil {
 		return autoThreadTitleDecision{}, errors.New("nil service")
 	}
@@ -233,7 +280,7 @@ func (s *Service) generateAutoThreadTitleByModel(ctx context.Context, resolved r
 	}
 	var lastErr error
 	for idx, attempt := range attempts {
-		result, runErr := s.runAutoThreadTitleAttempt(titleCtx, adapter, responseFormat, resolved, userInput, attempt)
+		result, runErr := s.runAutoThreadTitleAttempt(titleCtx, adapter, responseFormat, resolved, messages, attempt)
 		if runErr != nil {
 			return autoThreadTitleDecision{}, runErr
 		}
@@ -257,7 +304,7 @@ func (s *Service) runAutoThreadTitleAttempt(
 	adapter Provider,
 	responseFormat string,
 	resolved resolvedRunModel,
-	userInput string,
+	messages []Message,
 	attempt autoThreadTitleGenerationAttempt,
 ) (TurnResult, error) {
 	if s == nil {
@@ -274,7 +321,7 @@ func (s *Service) runAutoThreadTitleAttempt(
 
 	return adapter.StreamTurn(ctx, TurnRequest{
 		Model:            strings.TrimSpace(resolved.ModelName),
-		Messages:         buildAutoThreadTitleMessages(userInput),
+		Messages:         messages,
 		Budgets:          TurnBudgets{MaxSteps: 1, MaxOutputToken: maxOutputTokens},
 		ModeFlags:        ModeFlags{Mode: config.AIModePlan},
 		ProviderControls: ProviderControls{ResponseFormat: responseFormat},
@@ -423,6 +470,18 @@ func (c *autoThreadTitleCoordinator) ScheduleRecovery() {
 	}()
 }
 
+// Go runs fn on a coordinator worker that Close waits for.
+func (c *autoThreadTitleCoordinator) Go(fn func()) {
+	if c == nil || fn == nil {
+		return
+	}
+	c.workerWG.Add(1)
+	go func() {
+		defer c.workerWG.Done()
+		fn()
+	}()
+}
+
 func (c *autoThreadTitleCoordinator) recoverPending() {
 	if c == nil || c.svc == nil {
 		return
@@ -1005,3 +1064,204 @@ func (s *Service) applyFallbackThreadTitleOnce(ctx context.Context, req autoThre
 	s.broadcastThreadSummary(req.EndpointID, req.ThreadID)
 	return autoThreadTitleApplyResult{Status: autoThreadTitleApplyStatusApplied, Reason: "fallback_applied"}
 }
+
+// threadTitleReplaceable reports whether a generated title may replace th's title: it is empty, generated, or a
+// placeholder such as "New chat". Any other title set by the user, including one they cleared, is kept.
+func threadTitleReplaceable(th *threadstore.Thread, cfg *config.AIConfig) bool {
+	if th == nil {
+		return false
+	}
+	source := strings.TrimSpace(th.TitleSource)
+	switch source {
+	case threadstore.ThreadTitleSourceAuto, threadstore.ThreadTitleSourceAutoFallback:
+		return true
+	}
+	title := strings.TrimSpace(th.Title)
+	if title == "" {
+		return source != threadstore.ThreadTitleSourceUser
+	}
+	return cfg.IsPlaceholderThreadTitle(title)
+}
+
+// RetitleThread regenerates a thread's title from its first exchange and returns the updated thread.
+//
+// It fails with ErrThreadTitleLocked when the user set the title.
+func (s *Service) RetitleThread(ctx context.Context, meta *session.Meta, threadID string) (*ThreadView, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	threadID = strings.TrimSpace(threadID)
+	if threadID == "" {
+		return nil, errors.New("missing thread_id")
+	}
+	if _, err := s.retitleThreadFromExchange(ctxOrBackground(ctx), threadRetitleRequest{
+		EndpointID:     strings.TrimSpace(meta.EndpointID),
+		ThreadID:       threadID,
+		Trigger:        threadRetitleTriggerManual,
+		UpdatedByID:    strings.TrimSpace(meta.UserPublicID),
+		UpdatedByEmail: strings.TrimSpace(meta.UserEmail),
+	}); err != nil {
+		return nil, err
+	}
+	return s.GetThread(ctx, meta, threadID)
+}
+
+// scheduleExchangeThreadTitle retitles a thread in the background after a successful run when
+// thread_titles.from_first_exchange is on.
+func (s *Service) scheduleExchangeThreadTitle(meta *session.Meta, threadID string, runID string) {
+	if s == nil || meta == nil {
+		return
+	}
+	s.mu.Lock()
+	cfg := s.cfg
+	coordinator := s.threadTitleCoordinator
+	s.mu.Unlock()
+	if coordinator == nil || !cfg.EffectiveThreadTitleFromFirstExchange() {
+		return
+	}
+	req := threadRetitleRequest{
+		EndpointID:     strings.TrimSpace(meta.EndpointID),
+		ThreadID:       strings.TrimSpace(threadID),
+		RunID:          strings.TrimSpace(runID),
+		Trigger:        threadRetitleTriggerFirstExchange,
+		UpdatedByID:    strings.TrimSpace(meta.UserPublicID),
+		UpdatedByEmail: strings.TrimSpace(meta.UserEmail),
+	}
+	coordinator.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, err := s.retitleThreadFromExchange(ctx, req); err != nil && s.log != nil {
+			level := slog.LevelWarn
+			if errors.Is(err, errThreadRetitleNotNeeded) || errors.Is(err, ErrThreadTitleLocked) {
+				level = slog.LevelDebug
+			}
+			s.log.Log(ctx, level, "thread exchange title skipped",
+				"endpoint_id", req.EndpointID,
+				"thread_id", req.ThreadID,
+				"run_id", req.RunID,
+				"error", err,
+			)
+		}
+	})
+}
+
+type threadRetitleRequest struct {
+	EndpointID     string
+	ThreadID       string
+	RunID          string
+	Trigger        string
+	UpdatedByID    string
+	UpdatedByEmail string
+}
+
+// retitleThreadFromExchange generates a title from the first user message and the first assistant answer after
+// it, swaps it in, and records a thread.retitled event. The automatic trigger runs once per thread.
+func (s *Service) retitleThreadFromExchange(ctx context.Context, req threadRetitleRequest) (string, error) {
+	s.mu.Lock()
+	db := s.threadsDB
+	cfg := s.cfg
+	persistTO := s.persistOpTO
+	s.mu.Unlock()
+	if db == nil || cfg == nil {
+		return "", errors.New("threads store not ready")
+	}
+	if persistTO <= 0 {
+		persistTO = defaultPersistOpTimeout
+	}
+	if req.EndpointID == "" || req.ThreadID == "" {
+		return "", errors.New("invalid request")
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, persistTO)
+	th, err := db.GetThread(loadCtx, req.EndpointID, req.ThreadID)
+	var firstUser, firstAssistant *threadstore.Message
+	if err == nil && th != nil {
+		firstUser, err = db.GetFirstUserThreadMessage(loadCtx, req.EndpointID, req.ThreadID)
+	}
+	if err == nil && firstUser != nil {
+		firstAssistant, err = db.GetFirstAssistantThreadMessageAfter(loadCtx, req.EndpointID, req.ThreadID, firstUser.ID)
+	}
+	cancel()
+	if err != nil {
+		return "", err
+	}
+	if th == nil || th.ArchivedAtUnixMs > 0 {
+		return "", sql.ErrNoRows
+	}
+	if !threadTitleReplaceable(th, cfg) {
+		return "", ErrThreadTitleLocked
+	}
+	if req.Trigger == threadRetitleTriggerFirstExchange && strings.TrimSpace(th.TitlePromptVersion) == exchangeThreadTitlePromptVersion {
+		return "", errThreadRetitleNotNeeded
+	}
+	if firstUser == nil || firstAssistant == nil {
+		return "", errors.New("thread has no completed exchange")
+	}
+
+	var resolved resolvedRunModel
+	if model := cfg.EffectiveThreadTitleModel(); model != "" {
+		resolved, err = s.resolveRunModel(ctx, cfg, model, "", false, nil)
+	} else {
+		resolved, err = s.resolveRunModel(ctx, cfg, "", strings.TrimSpace(th.ModelID), th.ModelLocked, nil)
+	}
+	if err != nil {
+		return "", err
+	}
+	decision, err := s.generateThreadTitleByModel(ctx, resolved, buildExchangeThreadTitleMessages(firstUser.TextContent, firstAssistant.TextContent))
+	if err != nil {
+		return "", err
+	}
+
+	previousTitle := strings.TrimSpace(th.Title)
+	now := time.Now().UnixMilli()
+	saveCtx, cancel := context.WithTimeout(ctx, persistTO)
+	defer cancel()
+	updated, err := db.ReplaceThreadTitle(saveCtx, req.EndpointID, req.ThreadID, previousTitle, th.TitleSource, decision.Title, strings.TrimSpace(firstUser.MessageID), resolved.ID, exchangeThreadTitlePromptVersion, now, req.UpdatedByID, req.UpdatedByEmail)
+	if err != nil {
+		return "", err
+	}
+	if !updated {
+		return "", ErrThreadTitleChanged
+	}
+
+	eventRunID := req.RunID
+	if eventRunID == "" {
+		// Manual retitles are not produced by a run; use a synthetic run id like thread.forked does.
+		eventRunID = "retitle_" + req.ThreadID
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"title":                decision.Title,
+		"previous_title":       previousTitle,
+		"trigger":              req.Trigger,
+		"model_id":             resolved.ID,
+		"prompt_version":       exchangeThreadTitlePromptVersion,
+		"input_message_id":     strings.TrimSpace(firstUser.MessageID),
+		"assistant_message_id": strings.TrimSpace(firstAssistant.MessageID),
+		"decision_reason":      decision.Reason,
+	})
+	if err := db.AppendRunEvent(saveCtx, threadstore.RunEventRecord{
+		EndpointID:  req.EndpointID,
+		ThreadID:    req.ThreadID,
+		RunID:       eventRunID,
+		StreamKind:  string(RealtimeStreamKindLifecycle),
+		EventType:   threadRetitledEventType,
+		PayloadJSON: string(payload),
+		AtUnixMs:    now,
+	}); err != nil && s.log != nil {
+		s.log.Warn("thread retitle event persist failed", "endpoint_id", req.EndpointID, "thread_id", req.ThreadID, "error", err)
+	}
+	if s.log != nil {
+		s.log.Info("thread retitled",
+			"endpoint_id", req.EndpointID,
+			"thread_id", req.ThreadID,
+			"trigger", req.Trigger,
+			"model_id", resolved.ID,
+			"prompt_version", exchangeThreadTitlePromptVersion,
+		)
+	}
+	s.broadcastThreadSummary(req.EndpointID, req.ThreadID)
+	return decision.Title, nil
+}
@@ -3,6 +3,7 @@ package ai
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
@@ -956,3 +957,148 @@ func TestNewService_RecoversPendingAutoThreadTitles(t *testing.T) {
 
 	t.Fatalf("recovery auto title was not applied")
 }
+
+func appendExchangeForTitleTest(t *testing.T, svc *Service, meta session.Meta, threadID string, userText string, assistantText string) {
+	t.Helper()
+	for i, msg := range []threadstore.Message{
+		{MessageID: "msg_exchange_user", Role: "user", TextContent: userText},
+		{MessageID: "msg_exchange_assistant", Role: "assistant", TextContent: assistantText},
+	} {
+		msg.ThreadID = threadID
+		msg.EndpointID = meta.EndpointID
+		msg.Status = "complete"
+		msg.CreatedAtUnixMs = int64(100 + i)
+		msg.UpdatedAtUnixMs = msg.CreatedAtUnixMs
+		msg.MessageJSON = "{}"
+		if _, err := svc.threadsDB.AppendMessage(context.Background(), meta.EndpointID, threadID, msg, meta.UserPublicID, meta.UserEmail); err != nil {
+			t.Fatalf("AppendMessage(%s): %v", msg.Role, err)
+		}
+	}
+}
+
+func TestRetitleThread_ReplacesPlaceholderAndRecordsEvent(t *testing.T) {
+	t.Parallel()
+
+	mock := &autoTitleMock{token: `{"title":"Fix flaky CI retry test","reason":"exchange_summary"}`}
+	svc, meta := newAutoTitleTestService(t, mock)
+
+	ctx := context.Background()
+	thread, err := svc.CreateThread(ctx, &meta, "New chat", "openai/gpt-5-mini", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	appendExchangeForTitleTest(t, svc, meta, thread.ThreadID, "the retry test keeps failing", "The retry test races on a shared timer; I made the timer per-test.")
+
+	view, err := svc.RetitleThread(ctx, &meta, thread.ThreadID)
+	if err != nil {
+		t.Fatalf("RetitleThread: %v", err)
+	}
+	if view == nil || view.Title != "Fix flaky CI retry test" {
+		t.Fatalf("view=%+v, want generated title", view)
+	}
+	th, err := svc.threadsDB.GetThread(ctx, meta.EndpointID, thread.ThreadID)
+	if err != nil || th == nil {
+		t.Fatalf("GetThread th=%v err=%v", th, err)
+	}
+	if th.TitleSource != threadstore.ThreadTitleSourceAuto || th.TitlePromptVersion != exchangeThreadTitlePromptVersion || th.TitleInputMessageID != "msg_exchange_user" {
+		t.Fatalf("title metadata source=%q version=%q input=%q", th.TitleSource, th.TitlePromptVersion, th.TitleInputMessageID)
+	}
+
+	events, err := svc.threadsDB.ListRunEvents(ctx, meta.EndpointID, "retitle_"+thread.ThreadID, 10)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].EventType != threadRetitledEventType {
+		t.Fatalf("events=%+v, want one thread.retitled", events)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(events[0].PayloadJSON), &payload); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if payload["previous_title"] != "New chat" || payload["trigger"] != threadRetitleTriggerManual || payload["assistant_message_id"] != "msg_exchange_assistant" {
+		t.Fatalf("payload=%v", payload)
+	}
+}
+
+func TestRetitleThread_NeverOverwritesUserTitle(t *testing.T) {
+	t.Parallel()
+
+	mock := &autoTitleMock{token: `{"title":"Should not apply","reason":"exchange_summary"}`}
+	svc, meta := newAutoTitleTestService(t, mock)
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name   string
+		create string
+		rename string
+	}{
+		{name: "title passed at creation", create: "Release checklist"},
+		{name: "cleared by the user", create: "New chat", rename: " "},
+	} {
+		thread, err := svc.CreateThread(ctx, &meta, tc.create, "openai/gpt-5-mini", "", "")
+		if err != nil {
+			t.Fatalf("%s: CreateThread: %v", tc.name, err)
+		}
+		if tc.rename != "" {
+			// RenameThread with a blank title marks the title as deliberately empty.
+			if err := svc.RenameThread(ctx, &meta, thread.ThreadID, tc.rename); err != nil {
+				t.Fatalf("%s: RenameThread: %v", tc.name, err)
+			}
+		}
+		appendExchangeForTitleTest(t, svc, meta, thread.ThreadID, "check the release", "Done.")
+		if _, err := svc.RetitleThread(ctx, &meta, thread.ThreadID); !errors.Is(err, ErrThreadTitleLocked) {
+			t.Fatalf("%s: RetitleThread err=%v, want ErrThreadTitleLocked", tc.name, err)
+		}
+	}
+	if mock.count() != 0 {
+		t.Fatalf("requestCount=%d, want 0", mock.count())
+	}
+}
+
+func TestScheduleExchangeThreadTitle_OptInAndOnce(t *testing.T) {
+	t.Parallel()
+
+	mock := &autoTitleMock{token: `{"title":"Explain the build cache","reason":"exchange_summary"}`}
+	svc, meta := newAutoTitleTestService(t, mock)
+	// Let the startup recovery scan finish so it cannot queue a first-message title for this thread.
+	svc.threadTitleCoordinator.workerWG.Wait()
+
+	ctx := context.Background()
+	thread, err := svc.CreateThread(ctx, &meta, "", "openai/gpt-5-mini", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	appendExchangeForTitleTest(t, svc, meta, thread.ThreadID, "how does the build cache work", "It keys outputs by input hashes.")
+
+	// Off by default.
+	svc.scheduleExchangeThreadTitle(&meta, thread.ThreadID, "run_exchange_1")
+	svc.threadTitleCoordinator.workerWG.Wait()
+	if mock.count() != 0 {
+		t.Fatalf("requestCount=%d with thread_titles unset, want 0", mock.count())
+	}
+
+	svc.mu.Lock()
+	svc.cfg.ThreadTitles = &config.AIThreadTitlePolicy{FromFirstExchange: true}
+	svc.mu.Unlock()
+	svc.scheduleExchangeThreadTitle(&meta, thread.ThreadID, "run_exchange_1")
+	svc.threadTitleCoordinator.workerWG.Wait()
+	th, err := svc.threadsDB.GetThread(ctx, meta.EndpointID, thread.ThreadID)
+	if err != nil || th == nil {
+		t.Fatalf("GetThread th=%v err=%v", th, err)
+	}
+	if th.Title != "Explain the build cache" {
+		t.Fatalf("Title=%q, want exchange title", th.Title)
+	}
+	events, err := svc.threadsDB.ListRunEvents(ctx, meta.EndpointID, "run_exchange_1", 10)
+	if err != nil || len(events) != 1 || events[0].EventType != threadRetitledEventType {
+		t.Fatalf("events=%+v err=%v, want thread.retitled on the run", events, err)
+	}
+
+	// Later runs keep the exchange title.
+	calls := mock.count()
+	svc.scheduleExchangeThreadTitle(&meta, thread.ThreadID, "run_exchange_2")
+	svc.threadTitleCoordinator.workerWG.Wait()
+	if mock.count() != calls {
+		t.Fatalf("requestCount=%d after second run, want %d", mock.count(), calls)
+	}
+}
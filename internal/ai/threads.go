@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -109,7 +110,7 @@ func (s *Service) GetThread(ctx context.Context, meta *session.Meta, threadID st
 	if err != nil {
 		return nil, err
 	}
-	if th == nil {
+	if th == nil || th.ArchivedAtUnixMs > 0 {
 		return nil, nil
 	}
 	queuedTurnCount, err := db.CountFollowupsByLane(ctx, endpointID, threadID, threadstore.FollowupLaneQueued)
@@ -118,7 +119,8 @@ func (s *Service) GetThread(ctx context.Context, meta *session.Meta, threadID st
 	}
 
 	runStatus, runError := normalizeThreadRunState(th.RunStatus, th.RunError)
-	if s.HasActiveThreadForEndpoint(strings.TrimSpace(meta.EndpointID), strings.TrimSpace(th.ThreadID)) {
+	busy := s.HasActiveThreadForEndpoint(strings.TrimSpace(meta.EndpointID), strings.TrimSpace(th.ThreadID))
+	if busy {
 		runStatus, runError = activeThreadEffectiveRunState(th.RunStatus, th.RunError)
 	}
 
@@ -138,8 +140,11 @@ func (s *Service) GetThread(ctx context.Context, meta *session.Meta, threadID st
 		RunStatus:           runStatus,
 		RunUpdatedAtUnixMs:  th.RunUpdatedAtUnixMs,
 		RunError:            runError,
+		Busy:                busy,
 		WaitingPrompt:       s.threadWaitingPrompt(ctx, th, runStatus),
 		LastContextRunID:    strings.TrimSpace(th.LastContextRunID),
+		ForkedFromThreadID:  strings.TrimSpace(th.ForkedFromThreadID),
+		ForkedFromMessageID: strings.TrimSpace(th.ForkedFromMessageID),
 		CreatedAtUnixMs:     th.CreatedAtUnixMs,
 		UpdatedAtUnixMs:     th.UpdatedAtUnixMs,
 		LastMessageAtUnixMs: th.LastMessageAtUnixMs,
@@ -188,7 +193,8 @@ func (s *Service) ListThreads(ctx context.Context, meta *session.Meta, limit int
 	out := &ListThreadsResponse{Threads: make([]ThreadView, 0, len(list)), NextCursor: strings.TrimSpace(next)}
 	for _, t := range list {
 		runStatus, runError := normalizeThreadRunState(t.RunStatus, t.RunError)
-		if _, ok := activeThreads[strings.TrimSpace(t.ThreadID)]; ok {
+		_, busy := activeThreads[strings.TrimSpace(t.ThreadID)]
+		if busy {
 			runStatus, runError = activeThreadEffectiveRunState(t.RunStatus, t.RunError)
 		}
 		workingDir := strings.TrimSpace(t.WorkingDir)
@@ -206,8 +212,11 @@ func (s *Service) ListThreads(ctx context.Context, meta *session.Meta, limit int
 			RunStatus:           runStatus,
 			RunUpdatedAtUnixMs:  t.RunUpdatedAtUnixMs,
 			RunError:            runError,
+			Busy:                busy,
 			WaitingPrompt:       s.threadWaitingPrompt(ctx, &t, runStatus),
 			LastContextRunID:    strings.TrimSpace(t.LastContextRunID),
+			ForkedFromThreadID:  strings.TrimSpace(t.ForkedFromThreadID),
+			ForkedFromMessageID: strings.TrimSpace(t.ForkedFromMessageID),
 			CreatedAtUnixMs:     t.CreatedAtUnixMs,
 			UpdatedAtUnixMs:     t.UpdatedAtUnixMs,
 			LastMessageAtUnixMs: t.LastMessageAtUnixMs,
@@ -250,9 +259,12 @@ func (s *Service) CreateThread(ctx context.Context, meta *session.Meta, title st
 		if cfg != nil && !cfg.IsAllowedModelID(modelID) {
 			return nil, fmt.Errorf("model not allowed: %s", modelID)
 		}
+		if err := checkNamespaceModel(cfg, meta, modelID); err != nil {
+			return nil, err
+		}
 	}
 	if modelID == "" && cfg != nil {
-		if id, ok := cfg.ResolvedCurrentModelID(); ok {
+		if id, ok := cfg.ResolvedCurrentModelIDForNamespace(meta.NamespacePublicID); ok {
 			modelID = id
 		}
 	}
@@ -312,6 +324,59 @@ func (s *Service) CreateThread(ctx context.Context, meta *session.Meta, title st
 	}, nil
 }
 
+// ForkThread creates a new thread from the history of sourceThreadID up to and including upToMessageID.
+//
+// The fork gets its own copy of the transcript, todos, and context state, so later runs on either thread
+// do not affect the other. It returns sql.ErrNoRows when the thread or the message does not exist.
+func (s *Service) ForkThread(ctx context.Context, meta *session.Meta, sourceThreadID string, upToMessageID string) (*ThreadView, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	sourceThreadID = strings.TrimSpace(sourceThreadID)
+	if sourceThreadID == "" {
+		return nil, errors.New("missing thread_id")
+	}
+	upToMessageID = strings.TrimSpace(upToMessageID)
+	if upToMessageID == "" {
+		return nil, errors.New("missing message_id")
+	}
+
+	id, err := NewThreadID()
+	if err != nil {
+		return nil, err
+	}
+	forked, err := db.ForkThread(ctx, strings.TrimSpace(meta.EndpointID), sourceThreadID, upToMessageID, threadstore.Thread{
+		ThreadID:              id,
+		CreatedByUserPublicID: strings.TrimSpace(meta.UserPublicID),
+		CreatedByUserEmail:    strings.TrimSpace(meta.UserEmail),
+		UpdatedByUserPublicID: strings.TrimSpace(meta.UserPublicID),
+		UpdatedByUserEmail:    strings.TrimSpace(meta.UserEmail),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.log != nil {
+		s.log.Info("ai thread forked", "source_thread_id", sourceThreadID, "message_id", upToMessageID, "thread_id", forked.ThreadID)
+	}
+	view, err := s.GetThread(ctx, meta, forked.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if view == nil {
+		return nil, sql.ErrNoRows
+	}
+	return view, nil
+}
+
 func (s *Service) ValidateWorkingDir(workingDir string) (string, error) {
 	if s == nil {
 		return "", errors.New("nil service")
@@ -407,6 +472,9 @@ func (s *Service) SetThreadModel(ctx context.Context, meta *session.Meta, thread
 	if !cfg.IsAllowedModelID(modelID) {
 		return fmt.Errorf("model not allowed: %s", modelID)
 	}
+	if err := checkNamespaceModel(cfg, meta, modelID); err != nil {
+		return err
+	}
 	th, err := db.GetThread(ctx, endpointID, threadID)
 	if err != nil {
 		return err
@@ -556,7 +624,7 @@ func (s *Service) DeleteThread(ctx context.Context, meta *session.Meta, threadID
 		}
 		s.mu.Lock()
 		if strings.TrimSpace(s.activeRunByTh[runThreadKey(endpointID, threadID)]) == runID {
-			delete(s.activeRunByTh, runThreadKey(endpointID, threadID))
+			s.releaseActiveThreadRunLocked(runThreadKey(endpointID, threadID))
 		}
 		s.mu.Unlock()
 	}
@@ -577,6 +645,10 @@ func (s *Service) DeleteThread(ctx context.Context, meta *session.Meta, threadID
 	if err != nil {
 		return err
 	}
+	// The thread row is gone, so its archive claim can no longer race a StartRun.
+	s.mu.Lock()
+	delete(s.archivedByTh, runThreadKey(endpointID, threadID))
+	s.mu.Unlock()
 	if _, err := s.processUploadCleanupCandidates(ctx, result.UploadsToDelete); err != nil {
 		return err
 	}
@@ -585,6 +657,128 @@ func (s *Service) DeleteThread(ctx context.Context, meta *session.Meta, threadID
 	return nil
 }
 
+// CancelThreadRuns cancels every in-flight run of a thread and returns how many runs were asked to stop.
+//
+// Unlike CancelThread it does not stop at the thread's active slot: detached runs that are still winding
+// down for the same thread are canceled too.
+func (s *Service) CancelThreadRuns(meta *session.Meta, threadID string) (int, error) {
+	if s == nil {
+		return 0, errors.New("nil service")
+	}
+	if err := requireExecute(meta); err != nil {
+		return 0, err
+	}
+	threadID = strings.TrimSpace(threadID)
+	endpointID := strings.TrimSpace(meta.EndpointID)
+	if endpointID == "" || threadID == "" {
+		return 0, errors.New("invalid request")
+	}
+
+	s.mu.Lock()
+	runIDs := make([]string, 0, 1)
+	if active := strings.TrimSpace(s.activeRunByTh[runThreadKey(endpointID, threadID)]); active != "" {
+		runIDs = append(runIDs, active)
+	}
+	for runID, r := range s.runs {
+		if r == nil || strings.TrimSpace(r.endpointID) != endpointID || strings.TrimSpace(r.threadID) != threadID {
+			continue
+		}
+		if !slices.Contains(runIDs, runID) {
+			runIDs = append(runIDs, runID)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, runID := range runIDs {
+		if err := s.CancelRun(meta, runID); err != nil {
+			return 0, err
+		}
+	}
+	return len(runIDs), nil
+}
+
+// ArchiveThread soft-deletes a thread. New runs are rejected with ErrThreadArchived from the moment it is
+// called, in-flight runs are canceled, and the thread is hidden from listings while its history stays in the
+// store. It returns the number of runs that were canceled.
+func (s *Service) ArchiveThread(ctx context.Context, meta *session.Meta, threadID string) (int, error) {
+	if s == nil {
+		return 0, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return 0, err
+	}
+	threadID = strings.TrimSpace(threadID)
+	if threadID == "" {
+		return 0, errors.New("missing thread_id")
+	}
+	endpointID := strings.TrimSpace(meta.EndpointID)
+	thKey := runThreadKey(endpointID, threadID)
+	if thKey == "" {
+		return 0, errors.New("invalid request")
+	}
+
+	s.mu.Lock()
+	db := s.threadsDB
+	persistTO := s.persistOpTO
+	if db != nil {
+		// Claim the archive before canceling so a StartRun racing with this call cannot slip in between.
+		if s.archivedByTh == nil {
+			s.archivedByTh = make(map[string]bool)
+		}
+		s.archivedByTh[thKey] = true
+	}
+	s.mu.Unlock()
+	if db == nil {
+		return 0, errors.New("threads store not ready")
+	}
+	unclaim := func() {
+		s.mu.Lock()
+		delete(s.archivedByTh, thKey)
+		s.mu.Unlock()
+	}
+	if persistTO <= 0 {
+		persistTO = defaultPersistOpTimeout
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctxOrBackground(ctx), persistTO)
+	th, err := db.GetThread(lookupCtx, endpointID, threadID)
+	cancel()
+	if err == nil && th == nil {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		unclaim()
+		return 0, err
+	}
+
+	canceled, err := s.CancelThreadRuns(meta, threadID)
+	if err != nil {
+		unclaim()
+		return 0, err
+	}
+
+	archiveCtx, cancel := context.WithTimeout(ctxOrBackground(ctx), persistTO)
+	err = db.ArchiveThread(archiveCtx, endpointID, threadID, time.Now().UnixMilli())
+	cancel()
+	if err != nil {
+		unclaim()
+		return canceled, err
+	}
+	return canceled, nil
+}
+
+// requireVisibleThread returns an error wrapping sql.ErrNoRows when the thread does not exist or is archived.
+func requireVisibleThread(ctx context.Context, db *threadstore.Store, endpointID string, threadID string) error {
+	th, err := db.GetThread(ctx, strings.TrimSpace(endpointID), threadID)
+	if err != nil {
+		return err
+	}
+	if th == nil || th.ArchivedAtUnixMs > 0 {
+		return fmt.Errorf("thread not found: %w", sql.ErrNoRows)
+	}
+	return nil
+}
+
 func (s *Service) ListThreadMessages(ctx context.Context, meta *session.Meta, threadID string, limit int, beforeID int64) (*ListThreadMessagesResponse, error) {
 	if s == nil {
 		return nil, errors.New("nil service")
@@ -603,6 +797,10 @@ func (s *Service) ListThreadMessages(ctx context.Context, meta *session.Meta, th
 		return nil, errors.New("missing thread_id")
 	}
 
+	if err := requireVisibleThread(ctx, db, meta.EndpointID, threadID); err != nil {
+		return nil, err
+	}
+
 	msgs, nextBeforeID, hasMore, err := db.ListMessages(ctx, meta.EndpointID, threadID, limit, beforeID)
 	if err != nil {
 		return nil, err
@@ -623,6 +821,93 @@ func (s *Service) ListThreadMessages(ctx context.Context, meta *session.Meta, th
 	return out, nil
 }
 
+// ListThreadMessagesSince returns the messages appended to a thread after sinceSeq, oldest first.
+//
+// Clients tail a thread by passing back LatestSeq; unlike ListThreadMessages, no message is skipped or
+// repeated while a run is appending.
+func (s *Service) ListThreadMessagesSince(ctx context.Context, meta *session.Meta, threadID string, sinceSeq int64, limit int) (*ListThreadMessagesSinceResponse, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	threadID = strings.TrimSpace(threadID)
+	if threadID == "" {
+		return nil, errors.New("missing thread_id")
+	}
+	if sinceSeq < 0 {
+		return nil, errors.New("invalid since_seq")
+	}
+
+	if err := requireVisibleThread(ctx, db, meta.EndpointID, threadID); err != nil {
+		return nil, err
+	}
+
+	msgs, latestSeq, hasMore, err := db.ListMessagesSince(ctx, meta.EndpointID, threadID, sinceSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := &ListThreadMessagesSinceResponse{
+		Messages:  make([]any, 0, len(msgs)),
+		LatestSeq: latestSeq,
+		HasMore:   hasMore,
+	}
+	for _, m := range msgs {
+		raw := strings.TrimSpace(m.MessageJSON)
+		if raw == "" {
+			continue
+		}
+		out.Messages = append(out.Messages, json.RawMessage(raw))
+	}
+	return out, nil
+}
+
+// SearchThreadMessages runs a full-text search over the message text of the caller's threads.
+//
+// Results are scoped to meta's endpoint and, when set, its namespace; best matches come first.
+func (s *Service) SearchThreadMessages(ctx context.Context, meta *session.Meta, query string, limit int) (*SearchThreadMessagesResponse, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("missing query")
+	}
+
+	hits, err := db.SearchMessages(ctx, meta.EndpointID, meta.NamespacePublicID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := &SearchThreadMessagesResponse{Query: query, Hits: make([]ThreadMessageSearchHit, 0, len(hits))}
+	for _, hit := range hits {
+		out.Hits = append(out.Hits, ThreadMessageSearchHit{
+			ThreadID:        hit.ThreadID,
+			ThreadTitle:     hit.ThreadTitle,
+			MessageID:       hit.MessageID,
+			Role:            hit.Role,
+			CreatedAtUnixMs: hit.CreatedAtUnixMs,
+			Snippet:         hit.Snippet,
+		})
+	}
+	return out, nil
+}
+
 func (s *Service) GetThreadTodos(ctx context.Context, meta *session.Meta, threadID string) (*ThreadTodosView, error) {
 	if s == nil {
 		return nil, errors.New("nil service")
@@ -667,6 +952,83 @@ func (s *Service) GetThreadTodos(ctx context.Context, meta *session.Meta, thread
 	}, nil
 }
 
+// GetThreadUsage returns the per-model token and cost rollup for a thread.
+func (s *Service) GetThreadUsage(ctx context.Context, meta *session.Meta, threadID string) (*threadstore.ThreadUsage, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	threadID = strings.TrimSpace(threadID)
+	if threadID == "" {
+		return nil, errors.New("missing thread_id")
+	}
+	endpointID := strings.TrimSpace(meta.EndpointID)
+	if endpointID == "" {
+		return nil, errors.New("invalid request")
+	}
+	th, err := db.GetThread(ctx, endpointID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if th == nil {
+		return nil, sql.ErrNoRows
+	}
+	return db.GetThreadUsage(ctx, endpointID, threadID)
+}
+
+type ListUsageQuery struct {
+	SinceUnixMs int64
+	Cursor      int64
+	Limit       int
+}
+
+// ListUsagePage returns one page of the endpoint-wide usage rollup.
+func (s *Service) ListUsagePage(ctx context.Context, meta *session.Meta, query ListUsageQuery) (*threadstore.UsagePage, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	return db.ListUsagePage(ctx, strings.TrimSpace(meta.EndpointID), threadstore.UsageQuery{
+		SinceUnixMs: query.SinceUnixMs,
+		Cursor:      query.Cursor,
+		Limit:       query.Limit,
+	})
+}
+
+// FinalizationStats counts how the endpoint's runs ended since sinceUnixMs, by finalization reason,
+// end state, model, and mode.
+func (s *Service) FinalizationStats(ctx context.Context, meta *session.Meta, sinceUnixMs int64) (*threadstore.FinalizationStats, error) {
+	if s == nil {
+		return nil, errors.New("nil service")
+	}
+	if err := requireRWX(meta); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	db := s.threadsDB
+	s.mu.Unlock()
+	if db == nil {
+		return nil, errors.New("threads store not ready")
+	}
+	return db.GetFinalizationStats(ctx, strings.TrimSpace(meta.EndpointID), sinceUnixMs)
+}
+
 func (s *Service) ListRecentThreadToolCalls(ctx context.Context, meta *session.Meta, threadID string, limit int) ([]threadstore.ToolCallRecord, error) {
 	if s == nil {
 		return nil, errors.New("nil service")
@@ -783,6 +1145,9 @@ type ListRunEventsQuery struct {
 	Cursor   int64
 	Limit    int
 	Category string
+	// Types and StreamKind filter in the store; see threadstore.RunEventsQuery.
+	Types      []string
+	StreamKind string
 }
 
 func (s *Service) ListRunEventsWithQuery(ctx context.Context, meta *session.Meta, runID string, query ListRunEventsQuery) (*ListRunEventsResponse, error) {
@@ -804,9 +1169,11 @@ func (s *Service) ListRunEventsWithQuery(ctx context.Context, meta *session.Meta
 	}
 
 	recs, nextCursor, hasMore, err := db.ListRunEventsPage(ctx, strings.TrimSpace(meta.EndpointID), runID, threadstore.RunEventsQuery{
-		Cursor:   query.Cursor,
-		Limit:    query.Limit,
-		Category: query.Category,
+		Cursor:     query.Cursor,
+		Limit:      query.Limit,
+		Category:   query.Category,
+		Types:      query.Types,
+		StreamKind: query.StreamKind,
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+)
+
+func TestService_SearchThreadMessages(t *testing.T) {
+	t.Parallel()
+
+	svc := newSendTurnTestService(t)
+	meta := testSendTurnMeta()
+	ctx := context.Background()
+
+	th, err := svc.CreateThread(ctx, meta, "flaky test hunt", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	if _, err := svc.threadsDB.AppendMessage(ctx, meta.EndpointID, th.ThreadID, threadstore.Message{
+		MessageID:   "msg_search_assistant",
+		Role:        "assistant",
+		Status:      "complete",
+		TextContent: "We fixed the flaky test by retrying the port probe.",
+		MessageJSON: `{"id":"msg_search_assistant","role":"assistant","blocks":[{"type":"markdown","content":"We fixed the flaky test by retrying the port probe."}],"status":"complete"}`,
+	}, meta.UserPublicID, meta.UserEmail); err != nil {
+		t.Fatalf("AppendMessage: %v", err)
+	}
+
+	out, err := svc.SearchThreadMessages(ctx, meta, "flaky port", 0)
+	if err != nil {
+		t.Fatalf("SearchThreadMessages: %v", err)
+	}
+	if len(out.Hits) != 1 {
+		t.Fatalf("hits=%+v, want 1", out.Hits)
+	}
+	hit := out.Hits[0]
+	if hit.ThreadID != th.ThreadID || hit.MessageID != "msg_search_assistant" || hit.Role != "assistant" || hit.ThreadTitle != "flaky test hunt" {
+		t.Fatalf("unexpected hit: %+v", hit)
+	}
+
+	other := *meta
+	other.NamespacePublicID = "ns_someone_else"
+	out, err = svc.SearchThreadMessages(ctx, &other, "flaky", 0)
+	if err != nil {
+		t.Fatalf("SearchThreadMessages other namespace: %v", err)
+	}
+	if len(out.Hits) != 0 {
+		t.Fatalf("other namespace must not see hits: %+v", out.Hits)
+	}
+
+	readonly := *meta
+	readonly.CanExecute = false
+	if _, err := svc.SearchThreadMessages(ctx, &readonly, "flaky", 0); err == nil {
+		t.Fatalf("expected permission error")
+	}
+}
@@ -0,0 +1,168 @@
+package threadstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+const (
+	finalizationRunEndEventType   = "run.end"
+	finalizationRunErrorEventType = "run.error"
+)
+
+// FinalizationCount is the number of runs that share one finalization reason or end state.
+type FinalizationCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// FinalizationBreakdown counts runs by finalization reason and by end state, most frequent first.
+type FinalizationBreakdown struct {
+	Runs                int64               `json:"runs"`
+	FinalizationReasons []FinalizationCount `json:"finalization_reasons"`
+	EndStates           []FinalizationCount `json:"end_states"`
+}
+
+// FinalizationGroup is the breakdown of the runs of one model or one mode.
+//
+// Runs recorded before the model and mode were persisted on run.end land in a group with an empty key.
+type FinalizationGroup struct {
+	Key string `json:"key"`
+	FinalizationBreakdown
+}
+
+// FinalizationStats aggregates how runs ended, from their run.end and run.error events.
+type FinalizationStats struct {
+	SinceUnixMs int64 `json:"since_unix_ms"`
+	FinalizationBreakdown
+	ByModel []FinalizationGroup `json:"by_model"`
+	ByMode  []FinalizationGroup `json:"by_mode"`
+}
+
+type finalizationCounter struct {
+	runs    int64
+	reasons map[string]int64
+	states  map[string]int64
+}
+
+func newFinalizationCounter() *finalizationCounter {
+	return &finalizationCounter{reasons: make(map[string]int64), states: make(map[string]int64)}
+}
+
+func (c *finalizationCounter) add(reason string, state string) {
+	c.runs++
+	c.reasons[reason]++
+	c.states[state]++
+}
+
+func sortedFinalizationCounts(counts map[string]int64) []FinalizationCount {
+	out := make([]FinalizationCount, 0, len(counts))
+	for key, count := range counts {
+		out = append(out, FinalizationCount{Key: key, Count: count})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+func (c *finalizationCounter) breakdown() FinalizationBreakdown {
+	return FinalizationBreakdown{
+		Runs:                c.runs,
+		FinalizationReasons: sortedFinalizationCounts(c.reasons),
+		EndStates:           sortedFinalizationCounts(c.states),
+	}
+}
+
+func addFinalizationGroup(groups map[string]*finalizationCounter, key string, reason string, state string) {
+	key = strings.TrimSpace(key)
+	c := groups[key]
+	if c == nil {
+		c = newFinalizationCounter()
+		groups[key] = c
+	}
+	c.add(reason, state)
+}
+
+func finalizationGroups(groups map[string]*finalizationCounter) []FinalizationGroup {
+	out := make([]FinalizationGroup, 0, len(groups))
+	for key, c := range groups {
+		out = append(out, FinalizationGroup{Key: key, FinalizationBreakdown: c.breakdown()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// GetFinalizationStats counts the runs of an endpoint that ended at or after sinceUnixMs.
+//
+// It reads the persisted run.end and run.error events, so the stats cover the same window as run event retention.
+func (s *Store) GetFinalizationStats(ctx context.Context, endpointID string, sinceUnixMs int64) (*FinalizationStats, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	if endpointID == "" {
+		return nil, errors.New("invalid request")
+	}
+	if sinceUnixMs < 0 {
+		sinceUnixMs = 0
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT run_id, payload_json
+FROM ai_run_events
+WHERE endpoint_id = ? AND at_unix_ms >= ? AND event_type IN (?, ?)
+ORDER BY id ASC
+`, endpointID, sinceUnixMs, finalizationRunEndEventType, finalizationRunErrorEventType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	total := newFinalizationCounter()
+	byModel := make(map[string]*finalizationCounter)
+	byMode := make(map[string]*finalizationCounter)
+	seen := make(map[string]struct{})
+	for rows.Next() {
+		var runID, payloadJSON string
+		if err := rows.Scan(&runID, &payloadJSON); err != nil {
+			return nil, err
+		}
+		if _, dup := seen[runID]; dup {
+			continue
+		}
+		var payload struct {
+			State              string `json:"state"`
+			FinalizationReason string `json:"finalization_reason"`
+			Model              string `json:"model"`
+			Mode               string `json:"mode"`
+		}
+		if err := json.Unmarshal([]byte(payloadJSON), &payload); err != nil {
+			continue
+		}
+		seen[runID] = struct{}{}
+		reason := strings.TrimSpace(payload.FinalizationReason)
+		state := strings.TrimSpace(payload.State)
+		total.add(reason, state)
+		addFinalizationGroup(byModel, payload.Model, reason, state)
+		addFinalizationGroup(byMode, payload.Mode, reason, state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &FinalizationStats{
+		SinceUnixMs:           sinceUnixMs,
+		FinalizationBreakdown: total.breakdown(),
+		ByModel:               finalizationGroups(byModel),
+		ByMode:                finalizationGroups(byMode),
+	}, nil
+}
@@ -0,0 +1,69 @@
+package threadstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_GetFinalizationStats(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	appendEvent := func(endpointID string, runID string, eventType string, payload string, at int64) {
+		t.Helper()
+		if err := s.AppendRunEvent(ctx, RunEventRecord{
+			EndpointID:  endpointID,
+			ThreadID:    "th_1",
+			RunID:       runID,
+			StreamKind:  "lifecycle",
+			EventType:   eventType,
+			PayloadJSON: payload,
+			AtUnixMs:    at,
+		}); err != nil {
+			t.Fatalf("AppendRunEvent(%s): %v", eventType, err)
+		}
+	}
+
+	old := now - int64(time.Hour/time.Millisecond)
+	appendEvent("env_1", "run_old", "run.end", `{"state":"success","finalization_reason":"task_complete","model":"openai/gpt-5","mode":"act"}`, old)
+	appendEvent("env_1", "run_1", "run.end", `{"state":"success","finalization_reason":"task_complete","model":"openai/gpt-5","mode":"act"}`, now)
+	appendEvent("env_1", "run_2", "run.end", `{"state":"waiting_user","finalization_reason":"ask_user_waiting_model","model":"openai/gpt-5","mode":"plan"}`, now)
+	appendEvent("env_1", "run_3", "run.error", `{"state":"failed","finalization_reason":"","model":"moonshot/kimi-k2.5","mode":"act"}`, now)
+	appendEvent("env_1", "run_4", "run.end", `{"state":"success","finalization_reason":"task_complete"}`, now)
+	appendEvent("env_1", "run_4", "native.turn.result", `{"model":"openai/gpt-5"}`, now)
+	appendEvent("env_2", "run_5", "run.end", `{"state":"success","finalization_reason":"task_complete","model":"openai/gpt-5","mode":"act"}`, now)
+
+	stats, err := s.GetFinalizationStats(ctx, "env_1", now-1000)
+	if err != nil {
+		t.Fatalf("GetFinalizationStats: %v", err)
+	}
+	if stats.Runs != 4 {
+		t.Fatalf("runs=%d, want 4 in the window", stats.Runs)
+	}
+	if got := stats.FinalizationReasons[0]; got.Key != "task_complete" || got.Count != 2 {
+		t.Fatalf("top reason=%+v, want task_complete x2", got)
+	}
+	if len(stats.EndStates) != 3 || stats.EndStates[0].Key != "success" || stats.EndStates[0].Count != 2 {
+		t.Fatalf("end states=%+v", stats.EndStates)
+	}
+	if len(stats.ByModel) != 3 || stats.ByModel[0].Key != "" || stats.ByModel[2].Key != "openai/gpt-5" || stats.ByModel[2].Runs != 2 {
+		t.Fatalf("by model=%+v, want legacy, moonshot, and openai groups", stats.ByModel)
+	}
+	if len(stats.ByMode) != 3 || stats.ByMode[1].Key != "act" || stats.ByMode[1].Runs != 2 || stats.ByMode[2].Key != "plan" {
+		t.Fatalf("by mode=%+v", stats.ByMode)
+	}
+
+	all, err := s.GetFinalizationStats(ctx, "env_1", 0)
+	if err != nil || all.Runs != 5 {
+		t.Fatalf("all runs=%+v err=%v, want 5", all, err)
+	}
+}
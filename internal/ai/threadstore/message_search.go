@@ -0,0 +1,97 @@
+package threadstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+const (
+	defaultMessageSearchLimit = 20
+	maxMessageSearchLimit     = 100
+
+	// MessageSearchHighlightStart and MessageSearchHighlightEnd wrap matched terms in snippets.
+	MessageSearchHighlightStart = "**"
+	MessageSearchHighlightEnd   = "**"
+)
+
+// MessageSearchHit is one transcript message matching a full-text query.
+type MessageSearchHit struct {
+	ID              int64  `json:"id"`
+	ThreadID        string `json:"thread_id"`
+	ThreadTitle     string `json:"thread_title"`
+	MessageID       string `json:"message_id"`
+	Role            string `json:"role"`
+	CreatedAtUnixMs int64  `json:"created_at_unix_ms"`
+	Snippet         string `json:"snippet"`
+}
+
+// buildMessageSearchQuery turns free text into an FTS5 query that matches messages containing every term.
+//
+// Terms are quoted so FTS5 operators and punctuation in user input are matched literally.
+func buildMessageSearchQuery(raw string) string {
+	fields := strings.Fields(raw)
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		terms = append(terms, `"`+strings.ReplaceAll(field, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// SearchMessages returns transcript messages of endpointID whose text matches query, best match first.
+//
+// When namespacePublicID is set, only threads of that namespace are searched. Archived threads are never searched.
+func (s *Store) SearchMessages(ctx context.Context, endpointID string, namespacePublicID string, query string, limit int) ([]MessageSearchHit, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	namespacePublicID = strings.TrimSpace(namespacePublicID)
+	if endpointID == "" {
+		return nil, errors.New("invalid request")
+	}
+	match := buildMessageSearchQuery(query)
+	if match == "" {
+		return nil, errors.New("missing query")
+	}
+	if limit <= 0 {
+		limit = defaultMessageSearchLimit
+	}
+	if limit > maxMessageSearchLimit {
+		limit = maxMessageSearchLimit
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT m.id, m.thread_id, t.title, m.message_id, m.role, m.created_at_unix_ms,
+       snippet(transcript_messages_fts, 0, ?, ?, '…', 16)
+FROM transcript_messages_fts
+JOIN transcript_messages m ON m.id = transcript_messages_fts.rowid
+JOIN ai_threads t ON t.endpoint_id = m.endpoint_id AND t.thread_id = m.thread_id
+WHERE transcript_messages_fts MATCH ?
+  AND m.endpoint_id = ?
+  AND t.archived_at_unix_ms = 0
+  AND (? = '' OR t.namespace_public_id = ?)
+ORDER BY bm25(transcript_messages_fts), m.id DESC
+LIMIT ?
+`, MessageSearchHighlightStart, MessageSearchHighlightEnd, match, endpointID, namespacePublicID, namespacePublicID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]MessageSearchHit, 0, limit)
+	for rows.Next() {
+		var hit MessageSearchHit
+		if err := rows.Scan(&hit.ID, &hit.ThreadID, &hit.ThreadTitle, &hit.MessageID, &hit.Role, &hit.CreatedAtUnixMs, &hit.Snippet); err != nil {
+			return nil, err
+		}
+		out = append(out, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
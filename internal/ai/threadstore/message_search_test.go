@@ -0,0 +1,94 @@
+package threadstore
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_SearchMessages(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	threads := []Thread{
+		{ThreadID: "th_ci", EndpointID: "env_1", NamespacePublicID: "ns_1", Title: "CI cleanup"},
+		{ThreadID: "th_docs", EndpointID: "env_1", NamespacePublicID: "ns_1", Title: "Docs"},
+		{ThreadID: "th_other_ns", EndpointID: "env_1", NamespacePublicID: "ns_2", Title: "Other namespace"},
+		{ThreadID: "th_other_env", EndpointID: "env_2", NamespacePublicID: "ns_1", Title: "Other endpoint"},
+	}
+	for _, th := range threads {
+		if err := s.CreateThread(ctx, th); err != nil {
+			t.Fatalf("CreateThread %s: %v", th.ThreadID, err)
+		}
+	}
+	appendText := func(endpointID string, threadID string, messageID string, role string, text string) {
+		t.Helper()
+		if _, err := s.AppendMessage(ctx, endpointID, threadID, Message{
+			MessageID:   messageID,
+			Role:        role,
+			Status:      "complete",
+			TextContent: text,
+			MessageJSON: `{"id":"` + messageID + `"}`,
+		}, "u1", "u1@example.com"); err != nil {
+			t.Fatalf("AppendMessage %s: %v", messageID, err)
+		}
+	}
+	appendText("env_1", "th_ci", "msg_user", "user", "The integration suite has a flaky test again.")
+	appendText("env_1", "th_ci", "msg_assistant", "assistant", "Fixed the flaky test by waiting for the server to listen.")
+	appendText("env_1", "th_docs", "msg_docs", "assistant", "Updated the README install section.")
+	appendText("env_1", "th_other_ns", "msg_other_ns", "assistant", "Another flaky test fix.")
+	appendText("env_2", "th_other_env", "msg_other_env", "assistant", "Flaky test fixed elsewhere.")
+
+	hits, err := s.SearchMessages(ctx, "env_1", "ns_1", "fixed flaky", 10)
+	if err != nil {
+		t.Fatalf("SearchMessages: %v", err)
+	}
+	if len(hits) != 1 || hits[0].MessageID != "msg_assistant" || hits[0].ThreadID != "th_ci" || hits[0].ThreadTitle != "CI cleanup" || hits[0].Role != "assistant" {
+		t.Fatalf("hits=%+v", hits)
+	}
+	if !strings.Contains(hits[0].Snippet, "**flaky**") {
+		t.Fatalf("snippet=%q, want highlighted term", hits[0].Snippet)
+	}
+
+	hits, err = s.SearchMessages(ctx, "env_1", "ns_1", "flaky", 10)
+	if err != nil {
+		t.Fatalf("SearchMessages flaky: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("namespace-scoped hits=%+v, want the two ns_1 messages", hits)
+	}
+	hits, err = s.SearchMessages(ctx, "env_1", "", "flaky", 10)
+	if err != nil {
+		t.Fatalf("SearchMessages without namespace: %v", err)
+	}
+	if len(hits) != 3 {
+		t.Fatalf("endpoint-scoped hits=%d, want 3", len(hits))
+	}
+
+	// FTS5 syntax in user input is matched literally instead of failing the query.
+	if _, err := s.SearchMessages(ctx, "env_1", "ns_1", `flaky" OR (NEAR`, 10); err != nil {
+		t.Fatalf("SearchMessages with operators: %v", err)
+	}
+	if _, err := s.SearchMessages(ctx, "env_1", "ns_1", "   ", 10); err == nil {
+		t.Fatalf("expected error for empty query")
+	}
+
+	// The index follows deletes.
+	if err := s.DeleteThread(ctx, "env_1", "th_ci"); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+	hits, err = s.SearchMessages(ctx, "env_1", "ns_1", "flaky", 10)
+	if err != nil {
+		t.Fatalf("SearchMessages after delete: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Fatalf("hits after delete=%+v", hits)
+	}
+}
@@ -0,0 +1,237 @@
+package threadstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/floegence/redeven/internal/testutil/legacydb"
+)
+
+func seqTestMessage(id string) Message {
+	return Message{
+		MessageID:   id,
+		Role:        "user",
+		Status:      "complete",
+		TextContent: id,
+		MessageJSON: fmt.Sprintf(`{"id":%q,"role":"user","blocks":[],"status":"complete"}`, id),
+	}
+}
+
+func TestStore_ListMessagesSince_PagesAndResumes(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	for _, threadID := range []string{"th_1", "th_2"} {
+		if err := s.CreateThread(ctx, Thread{ThreadID: threadID, EndpointID: "env_1"}); err != nil {
+			t.Fatalf("CreateThread %s: %v", threadID, err)
+		}
+	}
+	for i := 1; i <= 5; i++ {
+		for _, threadID := range []string{"th_1", "th_2"} {
+			if _, err := s.AppendMessage(ctx, "env_1", threadID, seqTestMessage(fmt.Sprintf("%s_m%d", threadID, i)), "", ""); err != nil {
+				t.Fatalf("AppendMessage: %v", err)
+			}
+		}
+	}
+
+	page, latest, hasMore, err := s.ListMessagesSince(ctx, "env_1", "th_1", 0, 2)
+	if err != nil || len(page) != 2 || latest != 2 || !hasMore {
+		t.Fatalf("first page=%d latest=%d hasMore=%t err=%v", len(page), latest, hasMore, err)
+	}
+	if page[0].MessageID != "th_1_m1" || page[0].Seq != 1 || page[1].Seq != 2 {
+		t.Fatalf("first page messages=%+v", page)
+	}
+	page, latest, hasMore, err = s.ListMessagesSince(ctx, "env_1", "th_1", latest, 10)
+	if err != nil || len(page) != 3 || latest != 5 || hasMore {
+		t.Fatalf("second page=%d latest=%d hasMore=%t err=%v", len(page), latest, hasMore, err)
+	}
+	page, latest, hasMore, err = s.ListMessagesSince(ctx, "env_1", "th_1", latest, 10)
+	if err != nil || len(page) != 0 || latest != 5 || hasMore {
+		t.Fatalf("caught-up page=%d latest=%d hasMore=%t err=%v", len(page), latest, hasMore, err)
+	}
+
+	// Deleting messages never lets a sequence be reused.
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM transcript_messages WHERE endpoint_id = 'env_1' AND thread_id = 'th_1' AND seq > 3`); err != nil {
+		t.Fatalf("delete tail: %v", err)
+	}
+	if _, err := s.AppendMessage(ctx, "env_1", "th_1", seqTestMessage("th_1_m6"), "", ""); err != nil {
+		t.Fatalf("AppendMessage: %v", err)
+	}
+	page, latest, _, err = s.ListMessagesSince(ctx, "env_1", "th_1", 5, 10)
+	if err != nil || len(page) != 1 || page[0].MessageID != "th_1_m6" || latest != 6 {
+		t.Fatalf("after delete page=%+v latest=%d err=%v", page, latest, err)
+	}
+}
+
+func TestStore_ListMessagesSince_ConcurrentAppendsNoSkipOrDuplicate(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	if err := s.CreateThread(ctx, Thread{ThreadID: "th_1", EndpointID: "env_1"}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	const writers = 4
+	const perWriter = 25
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if _, err := s.AppendMessage(ctx, "env_1", "th_1", seqTestMessage(fmt.Sprintf("w%d_m%d", w, i)), "", ""); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	seen := make(map[string]bool, writers*perWriter)
+	var since int64
+	tail := func() {
+		for {
+			page, latest, hasMore, err := s.ListMessagesSince(ctx, "env_1", "th_1", since, 7)
+			if err != nil {
+				t.Fatalf("ListMessagesSince: %v", err)
+			}
+			for _, m := range page {
+				if m.Seq != since+1 {
+					t.Fatalf("seq=%d after %d: a message was skipped or repeated", m.Seq, since)
+				}
+				if seen[m.MessageID] {
+					t.Fatalf("message %s returned twice", m.MessageID)
+				}
+				seen[m.MessageID] = true
+				since = m.Seq
+			}
+			if since != latest {
+				t.Fatalf("latest=%d, want %d", latest, since)
+			}
+			if !hasMore {
+				return
+			}
+		}
+	}
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		tail()
+	}
+	close(errs)
+	for err := range errs {
+		t.Fatalf("AppendMessage: %v", err)
+	}
+	tail()
+	if len(seen) != writers*perWriter {
+		t.Fatalf("tailed %d messages, want %d", len(seen), writers*perWriter)
+	}
+}
+
+func TestStore_MigrateFromV15BackfillsMessageSeq(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "threads.sqlite")
+	if err := legacydb.SeedThreadstoreV15(dbPath); err != nil {
+		t.Fatalf("seed v15 db: %v", err)
+	}
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	if _, err := raw.Exec(`
+INSERT INTO ai_threads(thread_id, endpoint_id, title, created_at_unix_ms, updated_at_unix_ms)
+VALUES('th_1', 'env_1', 'chat', 1000, 1000), ('th_2', 'env_1', 'other', 1000, 1000);
+INSERT INTO transcript_messages(thread_id, endpoint_id, message_id, role, status, created_at_unix_ms, updated_at_unix_ms, message_json)
+VALUES('th_1', 'env_1', 'a1', 'user', 'complete', 1, 1, '{}'),
+      ('th_2', 'env_1', 'b1', 'user', 'complete', 2, 2, '{}'),
+      ('th_1', 'env_1', 'a2', 'assistant', 'complete', 3, 3, '{}');
+`); err != nil {
+		_ = raw.Close()
+		t.Fatalf("seed v15 rows: %v", err)
+	}
+	if err := raw.Close(); err != nil {
+		t.Fatalf("close seeded db: %v", err)
+	}
+
+	s, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	page, latest, _, err := s.ListMessagesSince(ctx, "env_1", "th_1", 0, 10)
+	if err != nil || len(page) != 2 || page[0].MessageID != "a1" || page[1].MessageID != "a2" || latest != 2 {
+		t.Fatalf("th_1 page=%+v latest=%d err=%v", page, latest, err)
+	}
+	if _, err := s.AppendMessage(ctx, "env_1", "th_1", seqTestMessage("a3"), "", ""); err != nil {
+		t.Fatalf("AppendMessage: %v", err)
+	}
+	page, latest, _, err = s.ListMessagesSince(ctx, "env_1", "th_1", 2, 10)
+	if err != nil || len(page) != 1 || page[0].MessageID != "a3" || latest != 3 {
+		t.Fatalf("after append page=%+v latest=%d err=%v", page, latest, err)
+	}
+	page, latest, _, err = s.ListMessagesSince(ctx, "env_1", "th_2", 0, 10)
+	if err != nil || len(page) != 1 || page[0].Seq != 1 || latest != 1 {
+		t.Fatalf("th_2 page=%+v latest=%d err=%v", page, latest, err)
+	}
+}
+
+func TestStore_ForkThread_ContinuesMessageSeq(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	if err := s.CreateThread(ctx, Thread{ThreadID: "th_src", EndpointID: "env_1"}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	for _, id := range []string{"m1", "m2", "m3"} {
+		if _, err := s.AppendMessage(ctx, "env_1", "th_src", seqTestMessage(id), "", ""); err != nil {
+			t.Fatalf("AppendMessage: %v", err)
+		}
+	}
+	if _, err := s.ForkThread(ctx, "env_1", "th_src", "m2", Thread{ThreadID: "th_fork"}); err != nil {
+		t.Fatalf("ForkThread: %v", err)
+	}
+	if _, err := s.AppendMessage(ctx, "env_1", "th_fork", seqTestMessage("f3"), "", ""); err != nil {
+		t.Fatalf("AppendMessage: %v", err)
+	}
+	page, latest, _, err := s.ListMessagesSince(ctx, "env_1", "th_fork", 0, 10)
+	if err != nil || len(page) != 3 || latest != 3 {
+		t.Fatalf("fork page=%+v latest=%d err=%v", page, latest, err)
+	}
+	if page[0].MessageID != "m1" || page[1].MessageID != "m2" || page[2].MessageID != "f3" {
+		t.Fatalf("fork messages=%+v", page)
+	}
+}
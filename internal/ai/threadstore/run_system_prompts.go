@@ -0,0 +1,100 @@
+package threadstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// RunSystemPromptRecord is one version of the system prompt a run sent to the provider.
+//
+// The prompt of version N is the previous version's first PrefixBytes bytes, then Body, then its last
+// SuffixBytes bytes. Version 1 has no previous version, so Body is the full prompt.
+type RunSystemPromptRecord struct {
+	EndpointID      string `json:"endpoint_id"`
+	ThreadID        string `json:"thread_id"`
+	RunID           string `json:"run_id"`
+	Version         int    `json:"version"`
+	StepIndex       int    `json:"step_index"`
+	PromptHash      string `json:"prompt_hash"`
+	PrefixBytes     int    `json:"prefix_bytes"`
+	SuffixBytes     int    `json:"suffix_bytes"`
+	Body            string `json:"body"`
+	MetaJSON        string `json:"meta_json"`
+	CreatedAtUnixMs int64  `json:"created_at_unix_ms"`
+}
+
+// AppendRunSystemPrompt stores one system prompt version of a run. The rows live and die with the thread.
+func (s *Store) AppendRunSystemPrompt(ctx context.Context, rec RunSystemPromptRecord) error {
+	if s == nil || s.db == nil {
+		return errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rec.EndpointID = strings.TrimSpace(rec.EndpointID)
+	rec.ThreadID = strings.TrimSpace(rec.ThreadID)
+	rec.RunID = strings.TrimSpace(rec.RunID)
+	rec.PromptHash = strings.TrimSpace(rec.PromptHash)
+	if rec.EndpointID == "" || rec.ThreadID == "" || rec.RunID == "" || rec.Version <= 0 || rec.PromptHash == "" {
+		return errors.New("invalid run system prompt")
+	}
+	if rec.PrefixBytes < 0 || rec.SuffixBytes < 0 {
+		return errors.New("invalid run system prompt")
+	}
+	if rec.CreatedAtUnixMs <= 0 {
+		rec.CreatedAtUnixMs = time.Now().UnixMilli()
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO ai_run_system_prompts(endpoint_id, thread_id, run_id, version, step_index, prompt_hash, prefix_bytes, suffix_bytes, body, meta_json, created_at_unix_ms)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, rec.EndpointID, rec.ThreadID, rec.RunID, rec.Version, rec.StepIndex, rec.PromptHash, rec.PrefixBytes, rec.SuffixBytes, rec.Body, rec.MetaJSON, rec.CreatedAtUnixMs)
+	return err
+}
+
+// ListRunSystemPrompts returns the stored system prompt versions of a run in version order.
+func (s *Store) ListRunSystemPrompts(ctx context.Context, endpointID string, runID string) ([]RunSystemPromptRecord, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	runID = strings.TrimSpace(runID)
+	if endpointID == "" || runID == "" {
+		return nil, errors.New("invalid request")
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT endpoint_id, thread_id, run_id, version, step_index, prompt_hash, prefix_bytes, suffix_bytes, body, meta_json, created_at_unix_ms
+FROM ai_run_system_prompts
+WHERE endpoint_id = ? AND run_id = ?
+ORDER BY version ASC
+`, endpointID, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]RunSystemPromptRecord, 0, 4)
+	for rows.Next() {
+		var rec RunSystemPromptRecord
+		if err := rows.Scan(
+			&rec.EndpointID,
+			&rec.ThreadID,
+			&rec.RunID,
+			&rec.Version,
+			&rec.StepIndex,
+			&rec.PromptHash,
+			&rec.PrefixBytes,
+			&rec.SuffixBytes,
+			&rec.Body,
+			&rec.MetaJSON,
+			&rec.CreatedAtUnixMs,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
@@ -9,7 +9,7 @@ import (
 
 const (
 	threadstoreSchemaKind           = "ai_threadstore"
-	threadstoreCurrentSchemaVersion = 22
+	threadstoreCurrentSchemaVersion = 31
 )
 
 // CurrentSchemaVersion returns the latest threadstore schema version expected by migrations.
@@ -50,6 +50,15 @@ func threadstoreSchemaSpec() sqliteutil.Spec {
 			{FromVersion: 19, ToVersion: 20, Apply: migrateThreadstoreToV20},
 			{FromVersion: 20, ToVersion: 21, Apply: migrateThreadstoreToV21},
 			{FromVersion: 21, ToVersion: 22, Apply: migrateThreadstoreToV22},
+			{FromVersion: 22, ToVersion: 23, Apply: migrateThreadstoreToV23},
+			{FromVersion: 23, ToVersion: 24, Apply: migrateThreadstoreToV24},
+			{FromVersion: 24, ToVersion: 25, Apply: migrateThreadstoreToV25},
+			{FromVersion: 25, ToVersion: 26, Apply: migrateThreadstoreToV26},
+			{FromVersion: 26, ToVersion: 27, Apply: migrateThreadstoreToV27},
+			{FromVersion: 27, ToVersion: 28, Apply: migrateThreadstoreToV28},
+			{FromVersion: 28, ToVersion: 29, Apply: migrateThreadstoreToV29},
+			{FromVersion: 29, ToVersion: 30, Apply: migrateThreadstoreToV30},
+			{FromVersion: 30, ToVersion: 31, Apply: migrateThreadstoreToV31},
 		},
 		Verify: verifyThreadstoreSchema,
 	}
@@ -213,6 +222,42 @@ func migrateThreadstoreToV22(tx *sql.Tx) error {
 	return ensureAIThreadStateContinuationColumnsTx(tx)
 }
 
+func migrateThreadstoreToV23(tx *sql.Tx) error {
+	return ensureAIThreadsForkLineageColumnsTx(tx)
+}
+
+func migrateThreadstoreToV24(tx *sql.Tx) error {
+	return ensureTranscriptMessagesFTSTx(tx)
+}
+
+func migrateThreadstoreToV25(tx *sql.Tx) error {
+	return ensureAIRunsSystemPromptTx(tx)
+}
+
+func migrateThreadstoreToV26(tx *sql.Tx) error {
+	return ensureAIThreadStateRuntimeStateTx(tx)
+}
+
+func migrateThreadstoreToV27(tx *sql.Tx) error {
+	return ensureToolResultContentsTx(tx)
+}
+
+func migrateThreadstoreToV28(tx *sql.Tx) error {
+	return ensureTranscriptMessageSeqTx(tx)
+}
+
+func migrateThreadstoreToV29(tx *sql.Tx) error {
+	return ensureThreadScratchpadTx(tx)
+}
+
+func migrateThreadstoreToV30(tx *sql.Tx) error {
+	return ensureAIThreadsArchivedAtTx(tx)
+}
+
+func migrateThreadstoreToV31(tx *sql.Tx) error {
+	return ensureRunSystemPromptsTx(tx)
+}
+
 func ensureAIThreadsModelIDTx(tx *sql.Tx) error {
 	return ensureColumnTx(tx, "ai_threads", "model_id", `ALTER TABLE ai_threads ADD COLUMN model_id TEXT NOT NULL DEFAULT ''`)
 }
@@ -276,6 +321,25 @@ func ensureAIThreadsLastContextRunIDTx(tx *sql.Tx) error {
 	return ensureColumnTx(tx, "ai_threads", "last_context_run_id", `ALTER TABLE ai_threads ADD COLUMN last_context_run_id TEXT NOT NULL DEFAULT ''`)
 }
 
+func ensureAIThreadsForkLineageColumnsTx(tx *sql.Tx) error {
+	if err := ensureColumnTx(tx, "ai_threads", "forked_from_thread_id", `ALTER TABLE ai_threads ADD COLUMN forked_from_thread_id TEXT NOT NULL DEFAULT ''`); err != nil {
+		return err
+	}
+	return ensureColumnTx(tx, "ai_threads", "forked_from_message_id", `ALTER TABLE ai_threads ADD COLUMN forked_from_message_id TEXT NOT NULL DEFAULT ''`)
+}
+
+func ensureAIThreadsArchivedAtTx(tx *sql.Tx) error {
+	return ensureColumnTx(tx, "ai_threads", "archived_at_unix_ms", `ALTER TABLE ai_threads ADD COLUMN archived_at_unix_ms INTEGER NOT NULL DEFAULT 0`)
+}
+
+func ensureAIRunsSystemPromptTx(tx *sql.Tx) error {
+	return ensureColumnTx(tx, "ai_runs", "system_prompt", `ALTER TABLE ai_runs ADD COLUMN system_prompt TEXT NOT NULL DEFAULT ''`)
+}
+
+func ensureAIThreadStateRuntimeStateTx(tx *sql.Tx) error {
+	return ensureColumnTx(tx, "ai_thread_state", "runtime_state_json", `ALTER TABLE ai_thread_state ADD COLUMN runtime_state_json TEXT NOT NULL DEFAULT ''`)
+}
+
 func ensureAIThreadStateContinuationColumnsTx(tx *sql.Tx) error {
 	stmts := []struct {
 		column string
@@ -692,6 +756,135 @@ CREATE INDEX IF NOT EXISTS idx_ai_upload_refs_upload ON ai_upload_refs(endpoint_
 	return nil
 }
 
+// ensureToolResultContentsTx creates the out-of-band store for large tool results, keyed by content_ref.
+func ensureToolResultContentsTx(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS ai_tool_result_contents (
+  content_ref TEXT PRIMARY KEY,
+  endpoint_id TEXT NOT NULL,
+  thread_id TEXT NOT NULL,
+  run_id TEXT NOT NULL,
+  tool_id TEXT NOT NULL,
+  tool_name TEXT NOT NULL DEFAULT '',
+  content TEXT NOT NULL,
+  size_bytes INTEGER NOT NULL DEFAULT 0,
+  created_at_unix_ms INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_ai_tool_result_contents_thread ON ai_tool_result_contents(endpoint_id, thread_id);
+`); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ensureThreadScratchpadTx creates the keyed notes the scratchpad tool keeps per thread.
+func ensureThreadScratchpadTx(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS ai_thread_scratchpad (
+  endpoint_id TEXT NOT NULL,
+  thread_id TEXT NOT NULL,
+  key TEXT NOT NULL,
+  value TEXT NOT NULL,
+  size_bytes INTEGER NOT NULL DEFAULT 0,
+  updated_at_unix_ms INTEGER NOT NULL,
+  updated_by_run_id TEXT NOT NULL DEFAULT '',
+  PRIMARY KEY(endpoint_id, thread_id, key)
+);
+`); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ensureRunSystemPromptsTx creates the per-run history of system prompt versions.
+//
+// Version 1 holds the full prompt; later versions hold only the bytes that changed from the previous one.
+func ensureRunSystemPromptsTx(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS ai_run_system_prompts (
+  endpoint_id TEXT NOT NULL,
+  thread_id TEXT NOT NULL,
+  run_id TEXT NOT NULL,
+  version INTEGER NOT NULL,
+  step_index INTEGER NOT NULL DEFAULT 0,
+  prompt_hash TEXT NOT NULL,
+  prefix_bytes INTEGER NOT NULL DEFAULT 0,
+  suffix_bytes INTEGER NOT NULL DEFAULT 0,
+  body TEXT NOT NULL,
+  meta_json TEXT NOT NULL DEFAULT '',
+  created_at_unix_ms INTEGER NOT NULL,
+  PRIMARY KEY(endpoint_id, run_id, version)
+);
+CREATE INDEX IF NOT EXISTS idx_ai_run_system_prompts_thread ON ai_run_system_prompts(endpoint_id, thread_id);
+`); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ensureTranscriptMessageSeqTx adds the per-thread message sequence used to tail a thread.
+//
+// ai_threads.message_seq is the last sequence handed out and only grows, so deleting messages never lets a
+// sequence be reused. Existing messages are numbered in insertion order.
+func ensureTranscriptMessageSeqTx(tx *sql.Tx) error {
+	if err := ensureColumnTx(tx, "ai_threads", "message_seq", `ALTER TABLE ai_threads ADD COLUMN message_seq INTEGER NOT NULL DEFAULT 0`); err != nil {
+		return err
+	}
+	if err := ensureColumnTx(tx, "transcript_messages", "seq", `ALTER TABLE transcript_messages ADD COLUMN seq INTEGER NOT NULL DEFAULT 0`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+UPDATE transcript_messages
+SET seq = (
+  SELECT numbered.seq
+  FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY endpoint_id, thread_id ORDER BY id ASC) AS seq
+    FROM transcript_messages
+  ) AS numbered
+  WHERE numbered.id = transcript_messages.id
+)
+WHERE seq = 0;
+UPDATE ai_threads
+SET message_seq = (
+  SELECT COALESCE(MAX(m.seq), 0)
+  FROM transcript_messages m
+  WHERE m.endpoint_id = ai_threads.endpoint_id AND m.thread_id = ai_threads.thread_id
+)
+WHERE message_seq = 0;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transcript_messages_thread_seq ON transcript_messages(endpoint_id, thread_id, seq);
+`)
+	return err
+}
+
+// ensureTranscriptMessagesFTSTx creates the full-text index over transcript message text.
+//
+// The index is an external-content FTS5 table kept in sync by triggers, so every write path
+// (append, fork, checkpoint restore, thread delete) updates it without extra code.
+func ensureTranscriptMessagesFTSTx(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+CREATE VIRTUAL TABLE IF NOT EXISTS transcript_messages_fts USING fts5(
+  text_content,
+  content='transcript_messages',
+  content_rowid='id',
+  tokenize='unicode61'
+);
+CREATE TRIGGER IF NOT EXISTS transcript_messages_fts_insert AFTER INSERT ON transcript_messages BEGIN
+  INSERT INTO transcript_messages_fts(rowid, text_content) VALUES (new.id, new.text_content);
+END;
+CREATE TRIGGER IF NOT EXISTS transcript_messages_fts_delete AFTER DELETE ON transcript_messages BEGIN
+  INSERT INTO transcript_messages_fts(transcript_messages_fts, rowid, text_content) VALUES ('delete', old.id, old.text_content);
+END;
+CREATE TRIGGER IF NOT EXISTS transcript_messages_fts_update AFTER UPDATE OF text_content ON transcript_messages BEGIN
+  INSERT INTO transcript_messages_fts(transcript_messages_fts, rowid, text_content) VALUES ('delete', old.id, old.text_content);
+  INSERT INTO transcript_messages_fts(rowid, text_content) VALUES (new.id, new.text_content);
+END;
+INSERT INTO transcript_messages_fts(transcript_messages_fts) VALUES ('rebuild');
+`); err != nil {
+		return err
+	}
+	return nil
+}
+
 func ensureColumnTx(tx *sql.Tx, tableName string, columnName string, stmt string) error {
 	has, err := columnExists(tx, tableName, columnName)
 	if err != nil {
@@ -725,6 +918,10 @@ func verifyThreadstoreSchema(tx *sql.Tx) error {
 		"provider_capabilities",
 		"ai_uploads",
 		"ai_upload_refs",
+		"transcript_messages_fts",
+		"ai_tool_result_contents",
+		"ai_thread_scratchpad",
+		"ai_run_system_prompts",
 	}
 	for _, tableName := range requiredTables {
 		exists, err := sqliteutil.TableExistsTx(tx, tableName)
@@ -751,9 +948,9 @@ func verifyThreadstoreSchema(tx *sql.Tx) error {
 			"execution_mode", "working_dir", "title", "title_source", "title_generated_at_unix_ms",
 			"title_input_message_id", "title_model_id", "title_prompt_version", "followups_revision",
 			"run_status", "run_updated_at_unix_ms", "run_error", "waiting_user_input_json", "last_context_run_id",
-			"created_by_user_public_id", "created_by_user_email", "updated_by_user_public_id",
+			"forked_from_thread_id", "forked_from_message_id", "created_by_user_public_id", "created_by_user_email", "updated_by_user_public_id",
 			"updated_by_user_email", "created_at_unix_ms", "updated_at_unix_ms",
-			"last_message_at_unix_ms", "last_message_preview",
+			"last_message_at_unix_ms", "last_message_preview", "message_seq", "archived_at_unix_ms",
 		},
 		"ai_messages": {
 			"id", "thread_id", "endpoint_id", "message_id", "role", "author_user_public_id",
@@ -778,7 +975,7 @@ func verifyThreadstoreSchema(tx *sql.Tx) error {
 			"endpoint_id", "thread_id", "open_goal", "last_assistant_summary",
 			"provider_continuation_kind", "provider_continuation_id", "provider_continuation_provider_id",
 			"provider_continuation_model", "provider_continuation_base_url",
-			"provider_continuation_updated_at_unix_ms", "updated_at_unix_ms",
+			"provider_continuation_updated_at_unix_ms", "runtime_state_json", "updated_at_unix_ms",
 		},
 		"ai_thread_todos": {
 			"endpoint_id", "thread_id", "version", "todos_json", "updated_at_unix_ms",
@@ -798,7 +995,7 @@ func verifyThreadstoreSchema(tx *sql.Tx) error {
 		"transcript_messages": {
 			"id", "thread_id", "endpoint_id", "message_id", "role", "author_user_public_id",
 			"author_user_email", "status", "created_at_unix_ms", "updated_at_unix_ms",
-			"text_content", "message_json",
+			"text_content", "message_json", "seq",
 		},
 		"conversation_turns": {
 			"id", "turn_id", "endpoint_id", "thread_id", "run_id", "user_message_id",
@@ -837,6 +1034,17 @@ func verifyThreadstoreSchema(tx *sql.Tx) error {
 		"ai_upload_refs": {
 			"id", "endpoint_id", "upload_id", "thread_id", "ref_kind", "ref_id", "created_at_unix_ms",
 		},
+		"ai_tool_result_contents": {
+			"content_ref", "endpoint_id", "thread_id", "run_id", "tool_id", "tool_name",
+			"content", "size_bytes", "created_at_unix_ms",
+		},
+		"ai_thread_scratchpad": {
+			"endpoint_id", "thread_id", "key", "value", "size_bytes", "updated_at_unix_ms", "updated_by_run_id",
+		},
+		"ai_run_system_prompts": {
+			"endpoint_id", "thread_id", "run_id", "version", "step_index", "prompt_hash",
+			"prefix_bytes", "suffix_bytes", "body", "meta_json", "created_at_unix_ms",
+		},
 	}
 	for tableName, columns := range requiredColumns {
 		for _, columnName := range columns {
@@ -864,6 +1072,7 @@ func verifyThreadstoreSchema(tx *sql.Tx) error {
 		"idx_ai_queued_turns_thread_lane_sort",
 		"idx_ai_queued_turns_message_id",
 		"idx_transcript_messages_thread_id",
+		"idx_transcript_messages_thread_seq",
 		"idx_conversation_turns_thread_id",
 		"idx_conversation_turns_run_id",
 		"idx_execution_spans_thread_started",
@@ -878,6 +1087,7 @@ func verifyThreadstoreSchema(tx *sql.Tx) error {
 		"idx_ai_upload_refs_unique_ref",
 		"idx_ai_upload_refs_thread_upload",
 		"idx_ai_upload_refs_upload",
+		"idx_ai_tool_result_contents_thread",
 	}
 	for _, indexName := range requiredIndexes {
 		exists, err := sqliteutil.IndexExistsTx(tx, indexName)
@@ -0,0 +1,217 @@
+package threadstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrScratchpadFull is returned when a write would push a thread's scratchpad past its size cap.
+var ErrScratchpadFull = errors.New("scratchpad full")
+
+// ScratchpadEntry is one keyed note in a thread's scratchpad.
+type ScratchpadEntry struct {
+	EndpointID      string `json:"endpoint_id"`
+	ThreadID        string `json:"thread_id"`
+	Key             string `json:"key"`
+	Value           string `json:"value"`
+	SizeBytes       int64  `json:"size_bytes"`
+	UpdatedAtUnixMs int64  `json:"updated_at_unix_ms"`
+	UpdatedByRunID  string `json:"updated_by_run_id"`
+}
+
+// ScratchpadUsage summarizes a thread's scratchpad.
+type ScratchpadUsage struct {
+	KeyCount   int   `json:"key_count"`
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+// SetScratchpadEntry stores rec.Value under rec.Key, replacing any previous value.
+//
+// maxTotalBytes caps the summed value size of the thread's entries; a write past it fails with ErrScratchpadFull.
+func (s *Store) SetScratchpadEntry(ctx context.Context, rec ScratchpadEntry, maxTotalBytes int64) (ScratchpadEntry, ScratchpadUsage, error) {
+	return s.writeScratchpadEntry(ctx, rec, false, maxTotalBytes)
+}
+
+// AppendScratchpadEntry appends rec.Value to the value stored under rec.Key, creating the entry when missing.
+func (s *Store) AppendScratchpadEntry(ctx context.Context, rec ScratchpadEntry, maxTotalBytes int64) (ScratchpadEntry, ScratchpadUsage, error) {
+	return s.writeScratchpadEntry(ctx, rec, true, maxTotalBytes)
+}
+
+func (s *Store) writeScratchpadEntry(ctx context.Context, rec ScratchpadEntry, appendValue bool, maxTotalBytes int64) (ScratchpadEntry, ScratchpadUsage, error) {
+	if s == nil || s.db == nil {
+		return ScratchpadEntry{}, ScratchpadUsage{}, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rec.EndpointID = strings.TrimSpace(rec.EndpointID)
+	rec.ThreadID = strings.TrimSpace(rec.ThreadID)
+	rec.Key = strings.TrimSpace(rec.Key)
+	rec.UpdatedByRunID = strings.TrimSpace(rec.UpdatedByRunID)
+	if rec.EndpointID == "" || rec.ThreadID == "" || rec.Key == "" {
+		return ScratchpadEntry{}, ScratchpadUsage{}, errors.New("invalid scratchpad entry")
+	}
+	if rec.UpdatedAtUnixMs <= 0 {
+		rec.UpdatedAtUnixMs = time.Now().UnixMilli()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ScratchpadEntry{}, ScratchpadUsage{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if appendValue {
+		var existing string
+		err := tx.QueryRowContext(ctx, `
+SELECT value FROM ai_thread_scratchpad WHERE endpoint_id = ? AND thread_id = ? AND key = ?
+`, rec.EndpointID, rec.ThreadID, rec.Key).Scan(&existing)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return ScratchpadEntry{}, ScratchpadUsage{}, err
+		}
+		rec.Value = existing + rec.Value
+	}
+	rec.SizeBytes = int64(len(rec.Value))
+
+	var otherBytes int64
+	if err := tx.QueryRowContext(ctx, `
+SELECT COALESCE(SUM(size_bytes), 0) FROM ai_thread_scratchpad WHERE endpoint_id = ? AND thread_id = ? AND key <> ?
+`, rec.EndpointID, rec.ThreadID, rec.Key).Scan(&otherBytes); err != nil {
+		return ScratchpadEntry{}, ScratchpadUsage{}, err
+	}
+	if maxTotalBytes > 0 && otherBytes+rec.SizeBytes > maxTotalBytes {
+		return ScratchpadEntry{}, ScratchpadUsage{}, ErrScratchpadFull
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO ai_thread_scratchpad(endpoint_id, thread_id, key, value, size_bytes, updated_at_unix_ms, updated_by_run_id)
+VALUES(?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(endpoint_id, thread_id, key) DO UPDATE SET
+  value=excluded.value,
+  size_bytes=excluded.size_bytes,
+  updated_at_unix_ms=excluded.updated_at_unix_ms,
+  updated_by_run_id=excluded.updated_by_run_id
+`, rec.EndpointID, rec.ThreadID, rec.Key, rec.Value, rec.SizeBytes, rec.UpdatedAtUnixMs, rec.UpdatedByRunID); err != nil {
+		return ScratchpadEntry{}, ScratchpadUsage{}, err
+	}
+	usage, err := scratchpadUsageTx(ctx, tx, rec.EndpointID, rec.ThreadID)
+	if err != nil {
+		return ScratchpadEntry{}, ScratchpadUsage{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return ScratchpadEntry{}, ScratchpadUsage{}, err
+	}
+	return rec, usage, nil
+}
+
+// GetScratchpadEntry returns the entry stored under key in the given thread.
+//
+// It returns sql.ErrNoRows when the key does not exist.
+func (s *Store) GetScratchpadEntry(ctx context.Context, endpointID string, threadID string, key string) (*ScratchpadEntry, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	key = strings.TrimSpace(key)
+	if endpointID == "" || threadID == "" || key == "" {
+		return nil, errors.New("invalid request")
+	}
+	var rec ScratchpadEntry
+	err := s.db.QueryRowContext(ctx, `
+SELECT endpoint_id, thread_id, key, value, size_bytes, updated_at_unix_ms, updated_by_run_id
+FROM ai_thread_scratchpad
+WHERE endpoint_id = ? AND thread_id = ? AND key = ?
+`, endpointID, threadID, key).Scan(
+		&rec.EndpointID,
+		&rec.ThreadID,
+		&rec.Key,
+		&rec.Value,
+		&rec.SizeBytes,
+		&rec.UpdatedAtUnixMs,
+		&rec.UpdatedByRunID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// ListScratchpadEntries returns the thread's scratchpad entries ordered by key.
+func (s *Store) ListScratchpadEntries(ctx context.Context, endpointID string, threadID string) ([]ScratchpadEntry, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	if endpointID == "" || threadID == "" {
+		return nil, errors.New("invalid request")
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT endpoint_id, thread_id, key, value, size_bytes, updated_at_unix_ms, updated_by_run_id
+FROM ai_thread_scratchpad
+WHERE endpoint_id = ? AND thread_id = ?
+ORDER BY key ASC
+`, endpointID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]ScratchpadEntry, 0, 8)
+	for rows.Next() {
+		var rec ScratchpadEntry
+		if err := rows.Scan(
+			&rec.EndpointID,
+			&rec.ThreadID,
+			&rec.Key,
+			&rec.Value,
+			&rec.SizeBytes,
+			&rec.UpdatedAtUnixMs,
+			&rec.UpdatedByRunID,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetScratchpadUsage returns the key count and total value size of the thread's scratchpad.
+func (s *Store) GetScratchpadUsage(ctx context.Context, endpointID string, threadID string) (ScratchpadUsage, error) {
+	if s == nil || s.db == nil {
+		return ScratchpadUsage{}, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	if endpointID == "" || threadID == "" {
+		return ScratchpadUsage{}, errors.New("invalid request")
+	}
+	var usage ScratchpadUsage
+	err := s.db.QueryRowContext(ctx, `
+SELECT COUNT(1), COALESCE(SUM(size_bytes), 0) FROM ai_thread_scratchpad WHERE endpoint_id = ? AND thread_id = ?
+`, endpointID, threadID).Scan(&usage.KeyCount, &usage.TotalBytes)
+	return usage, err
+}
+
+func scratchpadUsageTx(ctx context.Context, tx *sql.Tx, endpointID string, threadID string) (ScratchpadUsage, error) {
+	var usage ScratchpadUsage
+	err := tx.QueryRowContext(ctx, `
+SELECT COUNT(1), COALESCE(SUM(size_bytes), 0) FROM ai_thread_scratchpad WHERE endpoint_id = ? AND thread_id = ?
+`, endpointID, threadID).Scan(&usage.KeyCount, &usage.TotalBytes)
+	return usage, err
+}
@@ -0,0 +1,63 @@
+package threadstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_Scratchpad_SetAppendListAndCap(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	if err := s.CreateThread(ctx, Thread{ThreadID: "th_1", EndpointID: "env_1"}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	if _, _, err := s.SetScratchpadEntry(ctx, ScratchpadEntry{EndpointID: "env_1", ThreadID: "th_1", Key: "findings", Value: "a", UpdatedByRunID: "run_1"}, 16); err != nil {
+		t.Fatalf("SetScratchpadEntry: %v", err)
+	}
+	rec, usage, err := s.AppendScratchpadEntry(ctx, ScratchpadEntry{EndpointID: "env_1", ThreadID: "th_1", Key: "findings", Value: "bc", UpdatedByRunID: "run_2"}, 16)
+	if err != nil || rec.Value != "abc" || rec.SizeBytes != 3 || usage.KeyCount != 1 || usage.TotalBytes != 3 {
+		t.Fatalf("append rec=%+v usage=%+v err=%v", rec, usage, err)
+	}
+	if _, usage, err = s.SetScratchpadEntry(ctx, ScratchpadEntry{EndpointID: "env_1", ThreadID: "th_1", Key: "paths", Value: "0123456789"}, 16); err != nil || usage.KeyCount != 2 || usage.TotalBytes != 13 {
+		t.Fatalf("set paths usage=%+v err=%v", usage, err)
+	}
+
+	// The cap counts the replaced value only once.
+	if _, _, err := s.SetScratchpadEntry(ctx, ScratchpadEntry{EndpointID: "env_1", ThreadID: "th_1", Key: "paths", Value: "0123456789abc"}, 16); err != nil {
+		t.Fatalf("replace within cap: %v", err)
+	}
+	if _, _, err := s.AppendScratchpadEntry(ctx, ScratchpadEntry{EndpointID: "env_1", ThreadID: "th_1", Key: "findings", Value: "d"}, 16); !errors.Is(err, ErrScratchpadFull) {
+		t.Fatalf("append past cap err=%v, want ErrScratchpadFull", err)
+	}
+
+	got, err := s.GetScratchpadEntry(ctx, "env_1", "th_1", "findings")
+	if err != nil || got.Value != "abc" || got.UpdatedByRunID != "run_2" {
+		t.Fatalf("GetScratchpadEntry=%+v err=%v", got, err)
+	}
+	if _, err := s.GetScratchpadEntry(ctx, "env_1", "th_1", "missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("missing key err=%v, want sql.ErrNoRows", err)
+	}
+	list, err := s.ListScratchpadEntries(ctx, "env_1", "th_1")
+	if err != nil || len(list) != 2 || list[0].Key != "findings" || list[1].Key != "paths" {
+		t.Fatalf("ListScratchpadEntries=%+v err=%v", list, err)
+	}
+
+	if err := s.DeleteThread(ctx, "env_1", "th_1"); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+	usage, err = s.GetScratchpadUsage(ctx, "env_1", "th_1")
+	if err != nil || usage.KeyCount != 0 || usage.TotalBytes != 0 {
+		t.Fatalf("usage after delete=%+v err=%v", usage, err)
+	}
+}
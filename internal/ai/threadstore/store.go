@@ -90,6 +90,12 @@ type Thread struct {
 	RunError               string `json:"run_error"`
 	WaitingUserInputJSON   string `json:"waiting_user_input_json"`
 	LastContextRunID       string `json:"last_context_run_id"`
+	// ForkedFromThreadID and ForkedFromMessageID record fork lineage; both are empty for threads that were not forked.
+	ForkedFromThreadID  string `json:"forked_from_thread_id"`
+	ForkedFromMessageID string `json:"forked_from_message_id"`
+	// ArchivedAtUnixMs is set when the thread was deleted by a user; archived threads keep their history but are
+	// hidden from listings and reject new runs.
+	ArchivedAtUnixMs int64 `json:"archived_at_unix_ms,omitempty"`
 
 	CreatedByUserPublicID string `json:"created_by_user_public_id"`
 	CreatedByUserEmail    string `json:"created_by_user_email"`
@@ -111,6 +117,8 @@ type Message struct {
 	ID         int64  `json:"id"`
 	ThreadID   string `json:"thread_id"`
 	EndpointID string `json:"endpoint_id"`
+	// Seq is the message's position in its thread. It grows monotonically and is never reused.
+	Seq int64 `json:"seq"`
 
 	MessageID string `json:"message_id"`
 	Role      string `json:"role"`
@@ -159,16 +167,21 @@ const threadSelectColumnsSQL = `
   thread_id, endpoint_id, namespace_public_id, model_id, model_locked, execution_mode, working_dir, title,
   title_source, title_generated_at_unix_ms, title_input_message_id, title_model_id, title_prompt_version,
   run_status, run_updated_at_unix_ms, run_error,
-  waiting_user_input_json, last_context_run_id,
+  waiting_user_input_json, last_context_run_id, forked_from_thread_id, forked_from_message_id,
   created_by_user_public_id, created_by_user_email,
   updated_by_user_public_id, updated_by_user_email,
-  created_at_unix_ms, updated_at_unix_ms, last_message_at_unix_ms, last_message_preview
+  created_at_unix_ms, updated_at_unix_ms, last_message_at_unix_ms, last_message_preview,
+  archived_at_unix_ms
 `
 
 type rowScanner interface {
 	Scan(dest ...any) error
 }
 
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 func scanThreadRow(scan rowScanner, t *Thread) error {
 	if t == nil {
 		return errors.New("nil thread")
@@ -193,6 +206,8 @@ func scanThreadRow(scan rowScanner, t *Thread) error {
 		&t.RunError,
 		&t.WaitingUserInputJSON,
 		&t.LastContextRunID,
+		&t.ForkedFromThreadID,
+		&t.ForkedFromMessageID,
 		&t.CreatedByUserPublicID,
 		&t.CreatedByUserEmail,
 		&t.UpdatedByUserPublicID,
@@ -201,6 +216,7 @@ func scanThreadRow(scan rowScanner, t *Thread) error {
 		&t.UpdatedAtUnixMs,
 		&t.LastMessageAtUnixMs,
 		&t.LastMessagePreview,
+		&t.ArchivedAtUnixMs,
 	); err != nil {
 		return err
 	}
@@ -297,7 +313,7 @@ func (s *Store) ListThreads(ctx context.Context, endpointID string, limit int, c
 SELECT
 %s
 FROM ai_threads
-WHERE endpoint_id = ?
+WHERE endpoint_id = ? AND archived_at_unix_ms = 0
 %s
 ORDER BY updated_at_unix_ms DESC, thread_id DESC
 LIMIT ?
@@ -346,6 +362,7 @@ func (s *Store) ListAutoThreadTitleCandidates(ctx context.Context, limit int) ([
 SELECT endpoint_id, thread_id
 FROM ai_threads
 WHERE TRIM(COALESCE(title, '')) = ''
+  AND archived_at_unix_ms = 0
   AND LOWER(TRIM(COALESCE(title_source, ''))) != ?
   AND last_message_at_unix_ms > 0
 ORDER BY
@@ -434,6 +451,8 @@ func (s *Store) CreateThread(ctx context.Context, t Thread) error {
 	t.RunStatus = normalizeRunStatus(t.RunStatus)
 	t.RunError = strings.TrimSpace(t.RunError)
 	t.WaitingUserInputJSON = strings.TrimSpace(t.WaitingUserInputJSON)
+	t.ForkedFromThreadID = strings.TrimSpace(t.ForkedFromThreadID)
+	t.ForkedFromMessageID = strings.TrimSpace(t.ForkedFromMessageID)
 	t.CreatedByUserPublicID = strings.TrimSpace(t.CreatedByUserPublicID)
 	t.CreatedByUserEmail = strings.TrimSpace(t.CreatedByUserEmail)
 	t.UpdatedByUserPublicID = strings.TrimSpace(t.UpdatedByUserPublicID)
@@ -454,17 +473,21 @@ func (s *Store) CreateThread(ctx context.Context, t Thread) error {
 		t.RunUpdatedAtUnixMs = 0
 	}
 
-	_, err := s.db.ExecContext(ctx, `
+	return insertThreadRow(ctx, s.db, t)
+}
+
+func insertThreadRow(ctx context.Context, exec sqlExecer, t Thread) error {
+	_, err := exec.ExecContext(ctx, `
 	INSERT INTO ai_threads(
 	  thread_id, endpoint_id, namespace_public_id, model_id, model_locked, execution_mode, working_dir, title,
 	  title_source, title_generated_at_unix_ms, title_input_message_id, title_model_id, title_prompt_version,
 	  run_status, run_updated_at_unix_ms, run_error,
-	  waiting_user_input_json, last_context_run_id,
+	  waiting_user_input_json, last_context_run_id, forked_from_thread_id, forked_from_message_id,
 	  created_by_user_public_id, created_by_user_email,
 	  updated_by_user_public_id, updated_by_user_email,
 	  created_at_unix_ms, updated_at_unix_ms,
 	  last_message_at_unix_ms, last_message_preview
-	) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		t.ThreadID,
 		t.EndpointID,
@@ -484,6 +507,8 @@ func (s *Store) CreateThread(ctx context.Context, t Thread) error {
 		t.RunError,
 		t.WaitingUserInputJSON,
 		t.LastContextRunID,
+		t.ForkedFromThreadID,
+		t.ForkedFromMessageID,
 		t.CreatedByUserPublicID,
 		t.CreatedByUserEmail,
 		t.UpdatedByUserPublicID,
@@ -673,6 +698,49 @@ WHERE endpoint_id = ? AND thread_id = ?
 	return n > 0, nil
 }
 
+// ReplaceThreadTitle swaps a generated title in for expectedTitle. It is a compare-and-set on the title and its
+// source: nothing changes when either moved on meanwhile, and the result reports whether the row was updated.
+func (s *Store) ReplaceThreadTitle(ctx context.Context, endpointID string, threadID string, expectedTitle string, expectedSource string, title string, inputMessageID string, modelID string, promptVersion string, generatedAtUnixMs int64, updatedByID string, updatedByEmail string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	title = strings.TrimSpace(title)
+	if endpointID == "" || threadID == "" || title == "" {
+		return false, errors.New("invalid request")
+	}
+	if len(title) > 200 {
+		return false, errors.New("title too long")
+	}
+	if generatedAtUnixMs <= 0 {
+		generatedAtUnixMs = time.Now().UnixMilli()
+	}
+	res, err := s.db.ExecContext(ctx, `
+UPDATE ai_threads
+SET title = ?,
+    title_source = ?,
+    title_generated_at_unix_ms = ?,
+    title_input_message_id = ?,
+    title_model_id = ?,
+    title_prompt_version = ?,
+    updated_at_unix_ms = ?,
+    updated_by_user_public_id = ?,
+    updated_by_user_email = ?
+WHERE endpoint_id = ? AND thread_id = ?
+  AND TRIM(COALESCE(title, '')) = ?
+  AND LOWER(TRIM(COALESCE(title_source, ''))) = ?
+`, title, ThreadTitleSourceAuto, generatedAtUnixMs, strings.TrimSpace(inputMessageID), strings.TrimSpace(modelID), strings.TrimSpace(promptVersion), generatedAtUnixMs, strings.TrimSpace(updatedByID), strings.TrimSpace(updatedByEmail), endpointID, threadID, strings.TrimSpace(expectedTitle), normalizeThreadTitleSource(expectedSource))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
 func (s *Store) SetFallbackThreadTitle(ctx context.Context, endpointID string, threadID string, title string, inputMessageID string, generatedAtUnixMs int64, updatedByID string, updatedByEmail string) (bool, error) {
 	if s == nil || s.db == nil {
 		return false, errors.New("store not initialized")
@@ -875,6 +943,38 @@ func (s *Store) DeleteThread(ctx context.Context, endpointID string, threadID st
 	return tx.Commit()
 }
 
+// ArchiveThread soft-deletes a thread: it stays in the store with its history but is hidden from ListThreads.
+// Archiving an already archived thread keeps the original timestamp.
+func (s *Store) ArchiveThread(ctx context.Context, endpointID string, threadID string, archivedAtUnixMs int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	if endpointID == "" || threadID == "" {
+		return errors.New("invalid request")
+	}
+	if archivedAtUnixMs <= 0 {
+		archivedAtUnixMs = time.Now().UnixMilli()
+	}
+	res, err := s.db.ExecContext(ctx, `
+UPDATE ai_threads
+SET archived_at_unix_ms = CASE WHEN archived_at_unix_ms > 0 THEN archived_at_unix_ms ELSE ? END
+WHERE endpoint_id = ? AND thread_id = ?
+`, archivedAtUnixMs, endpointID, threadID)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (s *Store) GetQueuedTurn(ctx context.Context, endpointID string, threadID string, queueID string) (*QueuedTurn, error) {
 	if s == nil || s.db == nil {
 		return nil, errors.New("store not initialized")
@@ -1357,13 +1457,37 @@ func (s *Store) AppendMessage(ctx context.Context, endpointID string, threadID s
 }
 
 func appendMessageTx(ctx context.Context, tx *sql.Tx, endpointID string, threadID string, m Message, updatedByID string, updatedByEmail string, preview string) (int64, error) {
+	// Bumping the thread's sequence first also checks that the thread exists.
+	var seq int64
+	err := tx.QueryRowContext(ctx, `
+UPDATE ai_threads
+SET updated_at_unix_ms = ?,
+    updated_by_user_public_id = ?,
+    updated_by_user_email = ?,
+    last_message_at_unix_ms = ?,
+    last_message_preview = ?,
+    message_seq = message_seq + 1
+WHERE endpoint_id = ? AND thread_id = ?
+RETURNING message_seq
+`,
+		m.UpdatedAtUnixMs,
+		strings.TrimSpace(updatedByID),
+		strings.TrimSpace(updatedByEmail),
+		m.CreatedAtUnixMs,
+		preview,
+		endpointID,
+		threadID,
+	).Scan(&seq)
+	if err != nil {
+		return 0, err
+	}
 	res, err := tx.ExecContext(ctx, `
 INSERT INTO transcript_messages(
   thread_id, endpoint_id, message_id, role,
   author_user_public_id, author_user_email,
   status, created_at_unix_ms, updated_at_unix_ms,
-  text_content, message_json
-) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+  text_content, message_json, seq
+) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `,
 		threadID,
 		endpointID,
@@ -1376,34 +1500,12 @@ INSERT INTO transcript_messages(
 		m.UpdatedAtUnixMs,
 		m.TextContent,
 		m.MessageJSON,
+		seq,
 	)
 	if err != nil {
 		return 0, err
 	}
 	rowID, _ := res.LastInsertId()
-	updateRes, err := tx.ExecContext(ctx, `
-UPDATE ai_threads
-SET updated_at_unix_ms = ?,
-    updated_by_user_public_id = ?,
-    updated_by_user_email = ?,
-    last_message_at_unix_ms = ?,
-    last_message_preview = ?
-WHERE endpoint_id = ? AND thread_id = ?
-`,
-		m.UpdatedAtUnixMs,
-		strings.TrimSpace(updatedByID),
-		strings.TrimSpace(updatedByEmail),
-		m.CreatedAtUnixMs,
-		preview,
-		endpointID,
-		threadID,
-	)
-	if err != nil {
-		return 0, err
-	}
-	if n, _ := updateRes.RowsAffected(); n == 0 {
-		return 0, sql.ErrNoRows
-	}
 	return rowID, nil
 }
 
@@ -1584,6 +1686,85 @@ WHERE endpoint_id = ? AND thread_id = ? AND id > ?
 	return out, nextAfterID, hasMore, nil
 }
 
+// ListMessagesSince returns messages with seq > sinceSeq, in ascending seq order.
+//
+// Unlike the id and offset based listings, it is safe to poll while a run appends messages: sequences are
+// assigned in commit order, so a caller that passes back the returned latestSeq never skips or repeats a message.
+// latestSeq is the seq of the last returned message, or sinceSeq when nothing is new; hasMore reports that
+// more messages follow it.
+func (s *Store) ListMessagesSince(ctx context.Context, endpointID string, threadID string, sinceSeq int64, limit int) ([]Message, int64, bool, error) {
+	if s == nil || s.db == nil {
+		return nil, 0, false, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	if endpointID == "" || threadID == "" {
+		return nil, 0, false, errors.New("invalid request")
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	if sinceSeq < 0 {
+		sinceSeq = 0
+	}
+
+	// Fetch one extra row to learn whether more messages follow without a second query.
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, seq, thread_id, endpoint_id, message_id, role,
+       author_user_public_id, author_user_email,
+       status, created_at_unix_ms, updated_at_unix_ms,
+       text_content, message_json
+FROM transcript_messages
+WHERE endpoint_id = ? AND thread_id = ? AND seq > ?
+ORDER BY seq ASC
+LIMIT ?
+`, endpointID, threadID, sinceSeq, limit+1)
+	if err != nil {
+		return nil, sinceSeq, false, err
+	}
+	defer rows.Close()
+
+	out := make([]Message, 0, limit)
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(
+			&m.ID,
+			&m.Seq,
+			&m.ThreadID,
+			&m.EndpointID,
+			&m.MessageID,
+			&m.Role,
+			&m.AuthorUserPublicID,
+			&m.AuthorUserEmail,
+			&m.Status,
+			&m.CreatedAtUnixMs,
+			&m.UpdatedAtUnixMs,
+			&m.TextContent,
+			&m.MessageJSON,
+		); err != nil {
+			return nil, sinceSeq, false, err
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sinceSeq, false, err
+	}
+	hasMore := len(out) > limit
+	if hasMore {
+		out = out[:limit]
+	}
+	if len(out) == 0 {
+		return nil, sinceSeq, false, nil
+	}
+	return out, out[len(out)-1].Seq, hasMore, nil
+}
+
 // ListHistoryLite returns the latest messages as (role, status, text_content), in ascending order.
 func (s *Store) ListHistoryLite(ctx context.Context, endpointID string, threadID string, limit int) ([]Message, error) {
 	if s == nil || s.db == nil {
@@ -1664,6 +1845,16 @@ WHERE endpoint_id = ? AND thread_id = ? AND message_id = ?
 }
 
 func (s *Store) GetFirstUserThreadMessage(ctx context.Context, endpointID string, threadID string) (*Message, error) {
+	return s.getFirstThreadMessageByRole(ctx, endpointID, threadID, "user", 0)
+}
+
+// GetFirstAssistantThreadMessageAfter returns the oldest non-empty assistant message whose row id is greater
+// than afterRowID, or nil when there is none.
+func (s *Store) GetFirstAssistantThreadMessageAfter(ctx context.Context, endpointID string, threadID string, afterRowID int64) (*Message, error) {
+	return s.getFirstThreadMessageByRole(ctx, endpointID, threadID, "assistant", afterRowID)
+}
+
+func (s *Store) getFirstThreadMessageByRole(ctx context.Context, endpointID string, threadID string, role string, afterRowID int64) (*Message, error) {
 	if s == nil || s.db == nil {
 		return nil, errors.New("store not initialized")
 	}
@@ -1683,12 +1874,12 @@ SELECT id, thread_id, endpoint_id, message_id, role,
        status, created_at_unix_ms, updated_at_unix_ms,
        text_content, message_json
 FROM transcript_messages
-WHERE endpoint_id = ? AND thread_id = ?
-  AND LOWER(TRIM(COALESCE(role, ''))) = 'user'
+WHERE endpoint_id = ? AND thread_id = ? AND id > ?
+  AND LOWER(TRIM(COALESCE(role, ''))) = ?
   AND TRIM(COALESCE(text_content, '')) != ''
 ORDER BY id ASC
 LIMIT 1
-`, endpointID, threadID).Scan(
+`, endpointID, threadID, afterRowID, role).Scan(
 		&msg.ID,
 		&msg.ThreadID,
 		&msg.EndpointID,
@@ -1789,6 +1980,21 @@ type RunEventsQuery struct {
 	Cursor   int64
 	Limit    int
 	Category string
+	// Types keeps only these event types. An entry ending in ".*" matches every type with that prefix,
+	// for example "guard.*".
+	Types []string
+	// StreamKind keeps only events of one stream kind (lifecycle, assistant, tool, context).
+	StreamKind string
+}
+
+// RunEventsQueryMaxTypes bounds RunEventsQuery.Types so one filter stays a small query.
+const RunEventsQueryMaxTypes = 32
+
+var runEventStreamKinds = map[string]bool{
+	"lifecycle": true,
+	"assistant": true,
+	"tool":      true,
+	"context":   true,
 }
 
 type ThreadProviderContinuation struct {
@@ -2020,6 +2226,53 @@ WHERE endpoint_id = ? AND thread_id = ?
 	return err
 }
 
+// GetThreadRuntimeStateJSON returns the runtime state carried over from the thread's previous run, or "" when none was saved.
+func (s *Store) GetThreadRuntimeStateJSON(ctx context.Context, endpointID string, threadID string) (string, error) {
+	if s == nil || s.db == nil {
+		return "", errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	if endpointID == "" || threadID == "" {
+		return "", errors.New("invalid request")
+	}
+	var raw string
+	err := s.db.QueryRowContext(ctx, `SELECT runtime_state_json FROM ai_thread_state WHERE endpoint_id = ? AND thread_id = ?`, endpointID, threadID).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(raw), nil
+}
+
+// SetThreadRuntimeStateJSON stores the runtime state a later run on the thread should start from.
+func (s *Store) SetThreadRuntimeStateJSON(ctx context.Context, endpointID string, threadID string, stateJSON string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	if endpointID == "" || threadID == "" {
+		return errors.New("invalid request")
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO ai_thread_state(endpoint_id, thread_id, open_goal, last_assistant_summary, runtime_state_json, updated_at_unix_ms)
+VALUES(?, ?, '', '', ?, ?)
+ON CONFLICT(endpoint_id, thread_id) DO UPDATE SET
+  runtime_state_json=excluded.runtime_state_json,
+  updated_at_unix_ms=excluded.updated_at_unix_ms
+`, endpointID, threadID, strings.TrimSpace(stateJSON), time.Now().UnixMilli())
+	return err
+}
+
 func (s *Store) ClearThreadState(ctx context.Context, endpointID string, threadID string) error {
 	if s == nil || s.db == nil {
 		return errors.New("store not initialized")
@@ -2084,6 +2337,81 @@ ON CONFLICT(run_id) DO UPDATE SET
 	return err
 }
 
+// GetRun returns the run record, or nil when the run does not exist.
+func (s *Store) GetRun(ctx context.Context, endpointID string, runID string) (*RunRecord, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	runID = strings.TrimSpace(runID)
+	if endpointID == "" || runID == "" {
+		return nil, errors.New("invalid request")
+	}
+
+	var rec RunRecord
+	err := s.db.QueryRowContext(ctx, `
+SELECT run_id, endpoint_id, thread_id, message_id,
+       state, error_code, error_message, attempt_count,
+       started_at_unix_ms, ended_at_unix_ms, updated_at_unix_ms
+FROM ai_runs
+WHERE endpoint_id = ? AND run_id = ?
+`, endpointID, runID).Scan(
+		&rec.RunID, &rec.EndpointID, &rec.ThreadID, &rec.MessageID,
+		&rec.State, &rec.ErrorCode, &rec.ErrorMessage, &rec.AttemptCount,
+		&rec.StartedAtUnixMs, &rec.EndedAtUnixMs, &rec.UpdatedAtUnixMs,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// SetRunSystemPrompt stores the system prompt a run sent to the provider.
+//
+// It is kept outside RunRecord so UpsertRun state updates never rewrite the (large) prompt.
+func (s *Store) SetRunSystemPrompt(ctx context.Context, endpointID string, runID string, prompt string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	runID = strings.TrimSpace(runID)
+	if endpointID == "" || runID == "" {
+		return errors.New("invalid request")
+	}
+	_, err := s.db.ExecContext(ctx, `UPDATE ai_runs SET system_prompt = ? WHERE endpoint_id = ? AND run_id = ?`, prompt, endpointID, runID)
+	return err
+}
+
+// GetRunSystemPrompt returns the stored system prompt of a run, or "" when none was recorded.
+func (s *Store) GetRunSystemPrompt(ctx context.Context, endpointID string, runID string) (string, error) {
+	if s == nil || s.db == nil {
+		return "", errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	runID = strings.TrimSpace(runID)
+	if endpointID == "" || runID == "" {
+		return "", errors.New("invalid request")
+	}
+	var prompt string
+	err := s.db.QueryRowContext(ctx, `SELECT system_prompt FROM ai_runs WHERE endpoint_id = ? AND run_id = ?`, endpointID, runID).Scan(&prompt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return prompt, err
+}
+
 func (s *Store) UpsertToolCall(ctx context.Context, rec ToolCallRecord) error {
 	if s == nil || s.db == nil {
 		return errors.New("store not initialized")
@@ -2348,16 +2676,29 @@ AND (
   OR event_type LIKE 'context.compaction.%'
 )`
 	}
+	whereKind := ""
+	if kind := strings.TrimSpace(strings.ToLower(query.StreamKind)); kind != "" {
+		if !runEventStreamKinds[kind] {
+			return nil, 0, false, fmt.Errorf("unsupported run event stream kind: %s", kind)
+		}
+		whereKind = "\nAND stream_kind = ?"
+		args = append(args, kind)
+	}
+	whereTypes, typeArgs, err := runEventTypesFilter(query.Types)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	args = append(args, typeArgs...)
 	args = append(args, limit+1)
 
 	q := fmt.Sprintf(`
 SELECT id, endpoint_id, thread_id, run_id, stream_kind, event_type, payload_json, at_unix_ms
 FROM ai_run_events
 WHERE endpoint_id = ? AND run_id = ? AND id > ?
-%s
+%s%s%s
 ORDER BY id ASC
 LIMIT ?
-`, whereCategory)
+`, whereCategory, whereKind, whereTypes)
 	rows, err := s.db.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, 0, false, err
@@ -2385,6 +2726,41 @@ LIMIT ?
 	return out, nextCursor, hasMore, nil
 }
 
+// runEventTypesFilter builds the event type clause for RunEventsQuery.Types.
+// Prefix entries compare with substr instead of LIKE, so "_" in event types is matched literally.
+func runEventTypesFilter(types []string) (string, []any, error) {
+	var clauses []string
+	var args []any
+	seen := make(map[string]bool, len(types))
+	for _, raw := range types {
+		eventType := strings.TrimSpace(raw)
+		if eventType == "" || seen[eventType] {
+			continue
+		}
+		seen[eventType] = true
+		if len(seen) > RunEventsQueryMaxTypes {
+			return "", nil, fmt.Errorf("too many run event types (max %d)", RunEventsQueryMaxTypes)
+		}
+		if prefix, ok := strings.CutSuffix(eventType, "*"); ok {
+			if prefix == "" || !strings.HasSuffix(prefix, ".") || strings.Contains(prefix, "*") {
+				return "", nil, fmt.Errorf("invalid run event type pattern: %s", eventType)
+			}
+			clauses = append(clauses, "substr(event_type, 1, ?) = ?")
+			args = append(args, len(prefix), prefix)
+			continue
+		}
+		if strings.Contains(eventType, "*") {
+			return "", nil, fmt.Errorf("invalid run event type pattern: %s", eventType)
+		}
+		clauses = append(clauses, "event_type = ?")
+		args = append(args, eventType)
+	}
+	if len(clauses) == 0 {
+		return "", nil, nil
+	}
+	return "\nAND (" + strings.Join(clauses, " OR ") + ")", args, nil
+}
+
 func (s *Store) pruneRunEventsForThread(ctx context.Context, endpointID string, threadID string) error {
 	if s == nil || s.db == nil {
 		return errors.New("store not initialized")
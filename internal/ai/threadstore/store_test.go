@@ -1593,6 +1593,75 @@ func TestStore_ListRunEventsPage_ContextCategory(t *testing.T) {
 	}
 }
 
+func TestStore_ListRunEventsPage_TypeAndKindFilters(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	if err := s.CreateThread(ctx, Thread{ThreadID: "th_1", EndpointID: "env_1", Title: "chat"}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	for _, ev := range []struct{ kind, eventType string }{
+		{"lifecycle", "completion.attempt"},
+		{"lifecycle", "guard.doom_loop"},
+		{"tool", "tool.timeout"},
+		{"lifecycle", "completionXattempt"},
+		{"lifecycle", "guard.ask_user_bounce"},
+		{"lifecycle", "run.end"},
+		{"context", "guard.context_probe"},
+	} {
+		if err := s.AppendRunEvent(ctx, RunEventRecord{
+			EndpointID: "env_1",
+			ThreadID:   "th_1",
+			RunID:      "run_1",
+			StreamKind: ev.kind,
+			EventType:  ev.eventType,
+		}); err != nil {
+			t.Fatalf("AppendRunEvent(%s): %v", ev.eventType, err)
+		}
+	}
+	eventTypes := func(recs []RunEventRecord) string {
+		out := make([]string, 0, len(recs))
+		for _, rec := range recs {
+			out = append(out, rec.EventType)
+		}
+		return strings.Join(out, ",")
+	}
+
+	recs, _, _, err := s.ListRunEventsPage(ctx, "env_1", "run_1", RunEventsQuery{Types: []string{" guard.* ", "run.end", "completion.*"}})
+	if err != nil {
+		t.Fatalf("ListRunEventsPage types: %v", err)
+	}
+	if got := eventTypes(recs); got != "completion.attempt,guard.doom_loop,guard.ask_user_bounce,run.end,guard.context_probe" {
+		t.Fatalf("types filter=%s", got)
+	}
+
+	recs, next, hasMore, err := s.ListRunEventsPage(ctx, "env_1", "run_1", RunEventsQuery{Types: []string{"guard.*"}, StreamKind: "lifecycle", Limit: 1})
+	if err != nil || eventTypes(recs) != "guard.doom_loop" || !hasMore {
+		t.Fatalf("first guard page=%s hasMore=%v err=%v", eventTypes(recs), hasMore, err)
+	}
+	recs, _, hasMore, err = s.ListRunEventsPage(ctx, "env_1", "run_1", RunEventsQuery{Types: []string{"guard.*"}, StreamKind: "lifecycle", Cursor: next, Limit: 1})
+	if err != nil || eventTypes(recs) != "guard.ask_user_bounce" || hasMore {
+		t.Fatalf("second guard page=%s hasMore=%v err=%v", eventTypes(recs), hasMore, err)
+	}
+
+	for _, query := range []RunEventsQuery{
+		{StreamKind: "stream"},
+		{Types: []string{"guard*"}},
+		{Types: []string{"*"}},
+		{Types: []string{"guard.*.end"}},
+	} {
+		if _, _, _, err := s.ListRunEventsPage(ctx, "env_1", "run_1", query); err == nil {
+			t.Fatalf("query %+v: expected error", query)
+		}
+	}
+}
+
 func TestStore_AppendRunEvent_AgeRetention(t *testing.T) {
 	t.Parallel()
 
@@ -1949,6 +2018,79 @@ func TestStore_ThreadProviderContinuationCRUD(t *testing.T) {
 	}
 }
 
+func TestStore_ThreadRuntimeStateJSON(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	if got, err := s.GetThreadRuntimeStateJSON(ctx, "env_rt", "th_rt"); err != nil || got != "" {
+		t.Fatalf("GetThreadRuntimeStateJSON before save=%q err=%v, want empty", got, err)
+	}
+	if err := s.UpsertThreadState(ctx, ThreadState{EndpointID: "env_rt", ThreadID: "th_rt", OpenGoal: "ship it"}); err != nil {
+		t.Fatalf("UpsertThreadState: %v", err)
+	}
+	if err := s.SetThreadRuntimeStateJSON(ctx, "env_rt", "th_rt", `{"failed_signatures":["abc"]}`); err != nil {
+		t.Fatalf("SetThreadRuntimeStateJSON: %v", err)
+	}
+	got, err := s.GetThreadRuntimeStateJSON(ctx, "env_rt", "th_rt")
+	if err != nil || got != `{"failed_signatures":["abc"]}` {
+		t.Fatalf("GetThreadRuntimeStateJSON=%q err=%v", got, err)
+	}
+	state, err := s.GetThreadState(ctx, "env_rt", "th_rt")
+	if err != nil || state == nil || state.OpenGoal != "ship it" {
+		t.Fatalf("thread state after runtime save=%+v err=%v, want open goal kept", state, err)
+	}
+}
+
+func TestStore_ToolResultContent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	if err := s.CreateThread(ctx, Thread{ThreadID: "th_out", EndpointID: "env_out", Title: "t"}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	content := strings.Repeat("line of output\n", 500)
+	if err := s.PutToolResultContent(ctx, ToolResultContent{
+		ContentRef: "toolout_1",
+		EndpointID: "env_out",
+		ThreadID:   "th_out",
+		RunID:      "run_1",
+		ToolID:     "tool_1",
+		ToolName:   "terminal.exec",
+		Content:    content,
+	}); err != nil {
+		t.Fatalf("PutToolResultContent: %v", err)
+	}
+	got, err := s.GetToolResultContent(ctx, "env_out", "th_out", "toolout_1")
+	if err != nil {
+		t.Fatalf("GetToolResultContent: %v", err)
+	}
+	if got.Content != content || got.SizeBytes != int64(len(content)) || got.ToolName != "terminal.exec" {
+		t.Fatalf("content=%+v", got)
+	}
+	if _, err := s.GetToolResultContent(ctx, "env_out", "th_other", "toolout_1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetToolResultContent from another thread err=%v, want sql.ErrNoRows", err)
+	}
+
+	if err := s.DeleteThread(ctx, "env_out", "th_out"); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+	if _, err := s.GetToolResultContent(ctx, "env_out", "th_out", "toolout_1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetToolResultContent after thread delete err=%v, want sql.ErrNoRows", err)
+	}
+}
+
 func countRowsForTest(t *testing.T, db *sql.DB, query string, args ...any) int {
 	t.Helper()
 
@@ -0,0 +1,445 @@
+package threadstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ThreadForkedEventType marks a fork on both the source and the forked thread.
+//
+// The event is recorded under a synthetic run id ("fork_" + forked thread id) because no run produced it.
+const ThreadForkedEventType = "thread.forked"
+
+// ForkThread creates child as an independent copy of the source thread, truncated after upToMessageID.
+//
+// The copy includes transcript messages up to and including the cutoff, the conversation turns and
+// context snapshots that only reference copied messages, and structured user input answers. The todo
+// snapshot, scratchpad notes, and open goal / assistant summary are copied only when they were last
+// written before the message after the cutoff, so a fork never sees state from later turns; otherwise
+// the fork starts without them. A ThreadForkedEventType run event is written to both threads. Provider
+// continuations, runs, tool calls, run events, and memory items stay with the source thread. Copied rows
+// get child-scoped ids, so later writes to either thread never touch the other.
+//
+// It returns sql.ErrNoRows when the source thread does not exist or is archived, or the cutoff message does not exist.
+func (s *Store) ForkThread(ctx context.Context, endpointID string, sourceThreadID string, upToMessageID string, child Thread) (*Thread, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	sourceThreadID = strings.TrimSpace(sourceThreadID)
+	upToMessageID = strings.TrimSpace(upToMessageID)
+	child.ThreadID = strings.TrimSpace(child.ThreadID)
+	if endpointID == "" || sourceThreadID == "" || upToMessageID == "" || child.ThreadID == "" {
+		return nil, errors.New("invalid request")
+	}
+	if child.ThreadID == sourceThreadID {
+		return nil, errors.New("fork thread id must differ from source")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	source, err := s.getThreadTx(ctx, tx, endpointID, sourceThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil || source.ArchivedAtUnixMs > 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	var cutoffID int64
+	var cutoffRole, cutoffText, cutoffJSON string
+	var cutoffCreatedAt int64
+	err = tx.QueryRowContext(ctx, `
+SELECT id, role, text_content, message_json, created_at_unix_ms
+FROM transcript_messages
+WHERE endpoint_id = ? AND thread_id = ? AND message_id = ?
+`, endpointID, sourceThreadID, upToMessageID).Scan(&cutoffID, &cutoffRole, &cutoffText, &cutoffJSON, &cutoffCreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("message not found: %w", sql.ErrNoRows)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Thread state written at or after the first message past the cutoff belongs to later turns.
+	// 0 means the cutoff is the latest message, so the current state is the state as of the fork.
+	var stateCutoff sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `
+SELECT MIN(created_at_unix_ms)
+FROM transcript_messages
+WHERE endpoint_id = ? AND thread_id = ? AND id > ?
+`, endpointID, sourceThreadID, cutoffID).Scan(&stateCutoff); err != nil {
+		return nil, err
+	}
+	stateBefore := stateCutoff.Int64
+
+	now := time.Now().UnixMilli()
+	child.EndpointID = endpointID
+	child.NamespacePublicID = source.NamespacePublicID
+	child.ModelID = source.ModelID
+	child.ModelLocked = source.ModelLocked
+	child.ExecutionMode = source.ExecutionMode
+	child.WorkingDir = source.WorkingDir
+	if strings.TrimSpace(child.Title) == "" {
+		child.Title = source.Title
+		child.TitleSource = source.TitleSource
+		child.TitleGeneratedAtUnixMs = source.TitleGeneratedAtUnixMs
+		child.TitleInputMessageID = source.TitleInputMessageID
+		child.TitleModelID = source.TitleModelID
+		child.TitlePromptVersion = source.TitlePromptVersion
+	} else {
+		child.TitleSource = normalizeThreadTitleSource(child.TitleSource)
+	}
+	child.RunStatus = "idle"
+	child.RunUpdatedAtUnixMs = 0
+	child.RunError = ""
+	child.WaitingUserInputJSON = ""
+	child.LastContextRunID = ""
+	child.ForkedFromThreadID = sourceThreadID
+	child.ForkedFromMessageID = upToMessageID
+	child.CreatedAtUnixMs = now
+	child.UpdatedAtUnixMs = now
+	child.LastMessageAtUnixMs = cutoffCreatedAt
+	child.LastMessagePreview = buildPreview(cutoffRole, cutoffText, cutoffJSON)
+	if err := insertThreadRow(ctx, tx, child); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO transcript_messages(
+  thread_id, endpoint_id, message_id, role,
+  author_user_public_id, author_user_email,
+  status, created_at_unix_ms, updated_at_unix_ms,
+  text_content, message_json, seq
+)
+SELECT ?, endpoint_id, message_id, role,
+       author_user_public_id, author_user_email,
+       status, created_at_unix_ms, updated_at_unix_ms,
+       text_content, message_json, seq
+FROM transcript_messages
+WHERE endpoint_id = ? AND thread_id = ? AND id <= ?
+ORDER BY id ASC
+`, child.ThreadID, endpointID, sourceThreadID, cutoffID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+UPDATE ai_threads
+SET message_seq = (
+  SELECT COALESCE(MAX(seq), 0) FROM transcript_messages WHERE endpoint_id = ? AND thread_id = ?
+)
+WHERE endpoint_id = ? AND thread_id = ?
+`, endpointID, child.ThreadID, endpointID, child.ThreadID); err != nil {
+		return nil, err
+	}
+
+	copiedMessages, err := forkCopiedMessageIDsTx(ctx, tx, endpointID, child.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	turnIDs, err := forkConversationTurnsTx(ctx, tx, endpointID, sourceThreadID, child.ThreadID, copiedMessages)
+	if err != nil {
+		return nil, err
+	}
+	if err := forkContextSnapshotsTx(ctx, tx, endpointID, sourceThreadID, child.ThreadID, turnIDs); err != nil {
+		return nil, err
+	}
+	if err := forkStructuredUserInputsTx(ctx, tx, endpointID, sourceThreadID, child.ThreadID); err != nil {
+		return nil, err
+	}
+	if err := forkUploadRefsTx(ctx, tx, endpointID, sourceThreadID, child.ThreadID, copiedMessages, now); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO ai_thread_todos(
+  endpoint_id, thread_id, version, todos_json,
+  updated_at_unix_ms, updated_by_run_id, updated_by_tool_id
+)
+SELECT endpoint_id, ?, version, todos_json, updated_at_unix_ms, updated_by_run_id, updated_by_tool_id
+FROM ai_thread_todos
+WHERE endpoint_id = ? AND thread_id = ? AND (? = 0 OR updated_at_unix_ms < ?)
+`, child.ThreadID, endpointID, sourceThreadID, stateBefore, stateBefore); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO ai_thread_scratchpad(endpoint_id, thread_id, key, value, size_bytes, updated_at_unix_ms, updated_by_run_id)
+SELECT endpoint_id, ?, key, value, size_bytes, updated_at_unix_ms, updated_by_run_id
+FROM ai_thread_scratchpad
+WHERE endpoint_id = ? AND thread_id = ? AND (? = 0 OR updated_at_unix_ms < ?)
+`, child.ThreadID, endpointID, sourceThreadID, stateBefore, stateBefore); err != nil {
+		return nil, err
+	}
+	// The provider continuation points at the source thread's remote conversation, so the fork starts without one.
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO ai_thread_state(endpoint_id, thread_id, open_goal, last_assistant_summary, updated_at_unix_ms)
+SELECT endpoint_id, ?, open_goal, last_assistant_summary, ?
+FROM ai_thread_state
+WHERE endpoint_id = ? AND thread_id = ? AND (? = 0 OR updated_at_unix_ms < ?)
+`, child.ThreadID, now, endpointID, sourceThreadID, stateBefore, stateBefore); err != nil {
+		return nil, err
+	}
+
+	marker, err := json.Marshal(map[string]any{
+		"source_thread_id":  sourceThreadID,
+		"source_message_id": upToMessageID,
+		"forked_thread_id":  child.ThreadID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, threadID := range []string{sourceThreadID, child.ThreadID} {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO ai_run_events(endpoint_id, thread_id, run_id, stream_kind, event_type, payload_json, at_unix_ms)
+VALUES(?, ?, ?, ?, ?, ?, ?)
+`, endpointID, threadID, "fork_"+child.ThreadID, "lifecycle", ThreadForkedEventType, string(marker), now); err != nil {
+			return nil, err
+		}
+	}
+
+	forked, err := s.getThreadTx(ctx, tx, endpointID, child.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if forked == nil {
+		return nil, sql.ErrNoRows
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return forked, nil
+}
+
+// forkScopedID derives a globally unique id for a row copied into a forked thread.
+func forkScopedID(id string, childThreadID string) string {
+	return strings.TrimSpace(id) + "@" + strings.TrimSpace(childThreadID)
+}
+
+func forkCopiedMessageIDsTx(ctx context.Context, tx *sql.Tx, endpointID string, threadID string) (map[string]struct{}, error) {
+	rows, err := tx.QueryContext(ctx, `
+SELECT message_id
+FROM transcript_messages
+WHERE endpoint_id = ? AND thread_id = ?
+`, endpointID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]struct{})
+	for rows.Next() {
+		var messageID string
+		if err := rows.Scan(&messageID); err != nil {
+			return nil, err
+		}
+		out[strings.TrimSpace(messageID)] = struct{}{}
+	}
+	return out, rows.Err()
+}
+
+func forkIncludesMessage(copied map[string]struct{}, messageID string) bool {
+	messageID = strings.TrimSpace(messageID)
+	if messageID == "" {
+		return true
+	}
+	_, ok := copied[messageID]
+	return ok
+}
+
+// forkConversationTurnsTx copies the turns whose messages were all copied and returns a map from
+// source turn row ids to the new row ids, which context snapshots use for their coverage range.
+func forkConversationTurnsTx(ctx context.Context, tx *sql.Tx, endpointID string, sourceThreadID string, childThreadID string, copied map[string]struct{}) (map[int64]int64, error) {
+	type turnRow struct {
+		id                 int64
+		turnID             string
+		runID              string
+		userMessageID      string
+		assistantMessageID string
+		createdAtUnixMs    int64
+	}
+	rows, err := tx.QueryContext(ctx, `
+SELECT id, turn_id, run_id, user_message_id, assistant_message_id, created_at_unix_ms
+FROM conversation_turns
+WHERE endpoint_id = ? AND thread_id = ?
+ORDER BY id ASC
+`, endpointID, sourceThreadID)
+	if err != nil {
+		return nil, err
+	}
+	var turns []turnRow
+	for rows.Next() {
+		var row turnRow
+		if err := rows.Scan(&row.id, &row.turnID, &row.runID, &row.userMessageID, &row.assistantMessageID, &row.createdAtUnixMs); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		if row.userMessageID == "" && row.assistantMessageID == "" {
+			continue
+		}
+		if !forkIncludesMessage(copied, row.userMessageID) || !forkIncludesMessage(copied, row.assistantMessageID) {
+			continue
+		}
+		turns = append(turns, row)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	out := make(map[int64]int64, len(turns))
+	for _, row := range turns {
+		res, err := tx.ExecContext(ctx, `
+INSERT INTO conversation_turns(turn_id, endpoint_id, thread_id, run_id, user_message_id, assistant_message_id, created_at_unix_ms)
+VALUES(?, ?, ?, ?, ?, ?, ?)
+`, forkScopedID(row.turnID, childThreadID), endpointID, childThreadID, row.runID, row.userMessageID, row.assistantMessageID, row.createdAtUnixMs)
+		if err != nil {
+			return nil, err
+		}
+		newID, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		out[row.id] = newID
+	}
+	return out, nil
+}
+
+func forkContextSnapshotsTx(ctx context.Context, tx *sql.Tx, endpointID string, sourceThreadID string, childThreadID string, turnIDs map[int64]int64) error {
+	rows, err := tx.QueryContext(ctx, `
+SELECT snapshot_id, level, summary_text, covers_turn_from_id, covers_turn_to_id, quality_score, created_at_unix_ms
+FROM context_snapshots
+WHERE endpoint_id = ? AND thread_id = ?
+ORDER BY created_at_unix_ms ASC, snapshot_id ASC
+`, endpointID, sourceThreadID)
+	if err != nil {
+		return err
+	}
+	var snaps []ContextSnapshotRecord
+	for rows.Next() {
+		var rec ContextSnapshotRecord
+		if err := rows.Scan(&rec.SnapshotID, &rec.Level, &rec.SummaryText, &rec.CoversTurnFromID, &rec.CoversTurnToID, &rec.QualityScore, &rec.CreatedAtUnixMs); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		from, okFrom := turnIDs[rec.CoversTurnFromID]
+		to, okTo := turnIDs[rec.CoversTurnToID]
+		if !okFrom || !okTo {
+			// The snapshot summarizes turns past the cutoff.
+			continue
+		}
+		rec.CoversTurnFromID = from
+		rec.CoversTurnToID = to
+		snaps = append(snaps, rec)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	_ = rows.Close()
+
+	for _, rec := range snaps {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO context_snapshots(
+  snapshot_id, endpoint_id, thread_id,
+  level, summary_text,
+  covers_turn_from_id, covers_turn_to_id,
+  quality_score, created_at_unix_ms
+) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, forkScopedID(rec.SnapshotID, childThreadID), endpointID, childThreadID, rec.Level, rec.SummaryText, rec.CoversTurnFromID, rec.CoversTurnToID, rec.QualityScore, rec.CreatedAtUnixMs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func forkStructuredUserInputsTx(ctx context.Context, tx *sql.Tx, endpointID string, sourceThreadID string, childThreadID string) error {
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO structured_user_inputs(
+  endpoint_id, thread_id, response_message_id,
+  prompt_id, tool_id, reason_code, question_id,
+  header, question_text,
+  selected_option_id, selected_option_label,
+  answers_json, public_summary, contains_secret, created_at_unix_ms
+)
+SELECT endpoint_id, ?, response_message_id,
+       prompt_id, tool_id, reason_code, question_id,
+       header, question_text,
+       selected_option_id, selected_option_label,
+       answers_json, public_summary, contains_secret, created_at_unix_ms
+FROM structured_user_inputs
+WHERE endpoint_id = ? AND thread_id = ?
+  AND response_message_id IN (SELECT message_id FROM transcript_messages WHERE endpoint_id = ? AND thread_id = ?)
+ORDER BY id ASC
+`, childThreadID, endpointID, sourceThreadID, endpointID, childThreadID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO request_user_input_secret_answers(
+  endpoint_id, thread_id, response_message_id,
+  question_id, answer_index, answer_text, created_at_unix_ms
+)
+SELECT endpoint_id, ?, response_message_id, question_id, answer_index, answer_text, created_at_unix_ms
+FROM request_user_input_secret_answers
+WHERE endpoint_id = ? AND thread_id = ?
+  AND response_message_id IN (SELECT message_id FROM transcript_messages WHERE endpoint_id = ? AND thread_id = ?)
+ORDER BY id ASC
+`, childThreadID, endpointID, sourceThreadID, endpointID, childThreadID)
+	return err
+}
+
+// forkUploadRefsTx gives the fork its own references to uploads attached to copied messages,
+// so deleting either thread keeps the attachments alive for the other.
+func forkUploadRefsTx(ctx context.Context, tx *sql.Tx, endpointID string, sourceThreadID string, childThreadID string, copied map[string]struct{}, now int64) error {
+	rows, err := tx.QueryContext(ctx, `
+SELECT upload_id, ref_id
+FROM ai_upload_refs
+WHERE endpoint_id = ? AND thread_id = ? AND ref_kind = ?
+ORDER BY id ASC
+`, endpointID, sourceThreadID, UploadRefKindMessage)
+	if err != nil {
+		return err
+	}
+	type uploadRef struct {
+		uploadID string
+		refID    string
+	}
+	var refs []uploadRef
+	for rows.Next() {
+		var ref uploadRef
+		if err := rows.Scan(&ref.uploadID, &ref.refID); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if _, ok := copied[strings.TrimSpace(ref.refID)]; !ok {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	_ = rows.Close()
+
+	for _, ref := range refs {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO ai_upload_refs(endpoint_id, upload_id, thread_id, ref_kind, ref_id, created_at_unix_ms)
+VALUES(?, ?, ?, ?, ?, ?)
+ON CONFLICT(endpoint_id, upload_id, ref_kind, ref_id) DO NOTHING
+`, endpointID, ref.uploadID, childThreadID, UploadRefKindMessage, forkScopedID(ref.refID, childThreadID), now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
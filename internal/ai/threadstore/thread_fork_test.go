@@ -0,0 +1,200 @@
+package threadstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_ForkThreadCopiesHistoryUpToMessage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	endpointID := "env_fork"
+	sourceID := "th_source"
+	// The transcript predates the todo and state writes below, which therefore belong to the latest turn.
+	now := time.Now().Add(-time.Minute).UnixMilli()
+	if err := s.CreateThread(ctx, Thread{
+		ThreadID:          sourceID,
+		EndpointID:        endpointID,
+		NamespacePublicID: "ns_test",
+		ModelID:           "openai/gpt-5-mini",
+		ExecutionMode:     "plan",
+		WorkingDir:        "/tmp",
+		Title:             "Source thread",
+		RunStatus:         "success",
+		CreatedAtUnixMs:   now,
+		UpdatedAtUnixMs:   now,
+	}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	appendMessage := func(threadID string, messageID string, role string, text string, at int64) {
+		t.Helper()
+		if _, err := s.AppendMessage(ctx, endpointID, threadID, Message{
+			MessageID:       messageID,
+			Role:            role,
+			Status:          "complete",
+			CreatedAtUnixMs: at,
+			UpdatedAtUnixMs: at,
+			TextContent:     text,
+			MessageJSON:     `{"id":"` + messageID + `","role":"` + role + `"}`,
+		}, "u1", "u1@example.com"); err != nil {
+			t.Fatalf("AppendMessage(%s): %v", messageID, err)
+		}
+	}
+	appendMessage(sourceID, "m_user_1", "user", "first question", now+1)
+	appendMessage(sourceID, "m_asst_1", "assistant", "first answer", now+2)
+	appendMessage(sourceID, "m_user_2", "user", "second question", now+3)
+	appendMessage(sourceID, "m_asst_2", "assistant", "second answer", now+4)
+
+	for _, turn := range []ConversationTurn{
+		{TurnID: "turn_1", RunID: "run_1", UserMessageID: "m_user_1", AssistantMessageID: "m_asst_1"},
+		{TurnID: "turn_2", RunID: "run_2", UserMessageID: "m_user_2", AssistantMessageID: "m_asst_2"},
+	} {
+		turn.EndpointID = endpointID
+		turn.ThreadID = sourceID
+		if err := s.AppendConversationTurn(ctx, turn); err != nil {
+			t.Fatalf("AppendConversationTurn(%s): %v", turn.TurnID, err)
+		}
+	}
+	turns, err := s.ListConversationTurns(ctx, endpointID, sourceID, 10)
+	if err != nil || len(turns) != 2 {
+		t.Fatalf("ListConversationTurns source=%+v err=%v", turns, err)
+	}
+	for _, snap := range []ContextSnapshotRecord{
+		{SnapshotID: "snap_1", Level: "turn", SummaryText: "first turn", CoversTurnFromID: turns[0].ID, CoversTurnToID: turns[0].ID},
+		{SnapshotID: "snap_2", Level: "turn", SummaryText: "both turns", CoversTurnFromID: turns[0].ID, CoversTurnToID: turns[1].ID},
+	} {
+		snap.EndpointID = endpointID
+		snap.ThreadID = sourceID
+		snap.QualityScore = 0.5
+		snap.CreatedAtUnixMs = now
+		if err := s.InsertContextSnapshot(ctx, snap); err != nil {
+			t.Fatalf("InsertContextSnapshot(%s): %v", snap.SnapshotID, err)
+		}
+	}
+	if _, err := s.ReplaceThreadTodosSnapshot(ctx, ThreadTodosSnapshot{
+		EndpointID: endpointID,
+		ThreadID:   sourceID,
+		TodosJSON:  `[{"id":"t1","content":"inspect","status":"in_progress"}]`,
+	}, nil); err != nil {
+		t.Fatalf("ReplaceThreadTodosSnapshot: %v", err)
+	}
+	if err := s.UpsertThreadState(ctx, ThreadState{
+		EndpointID:           endpointID,
+		ThreadID:             sourceID,
+		OpenGoal:             "ship the fix",
+		LastAssistantSummary: "second answer",
+	}); err != nil {
+		t.Fatalf("UpsertThreadState: %v", err)
+	}
+	if err := s.SetThreadProviderContinuation(ctx, endpointID, sourceID, ThreadProviderContinuation{
+		Kind:           "openai_responses",
+		ContinuationID: "resp_1",
+		ProviderID:     "openai",
+		Model:          "gpt-5-mini",
+	}); err != nil {
+		t.Fatalf("SetThreadProviderContinuation: %v", err)
+	}
+
+	forked, err := s.ForkThread(ctx, endpointID, sourceID, "m_asst_1", Thread{ThreadID: "th_fork", CreatedByUserPublicID: "u2"})
+	if err != nil {
+		t.Fatalf("ForkThread: %v", err)
+	}
+	if forked.ForkedFromThreadID != sourceID || forked.ForkedFromMessageID != "m_asst_1" {
+		t.Fatalf("lineage=%q/%q", forked.ForkedFromThreadID, forked.ForkedFromMessageID)
+	}
+	if forked.Title != "Source thread" || forked.ExecutionMode != "plan" || forked.ModelID != "openai/gpt-5-mini" || forked.RunStatus != "idle" || forked.CreatedByUserPublicID != "u2" {
+		t.Fatalf("forked thread=%+v", forked)
+	}
+	if forked.LastMessagePreview != "first answer" || forked.LastMessageAtUnixMs != now+2 {
+		t.Fatalf("preview=%q at=%d", forked.LastMessagePreview, forked.LastMessageAtUnixMs)
+	}
+
+	msgs, _, _, err := s.ListMessages(ctx, endpointID, "th_fork", 10, 0)
+	if err != nil {
+		t.Fatalf("ListMessages fork: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].MessageID != "m_user_1" || msgs[1].MessageID != "m_asst_1" {
+		t.Fatalf("forked messages=%+v", msgs)
+	}
+	forkTurns, err := s.ListConversationTurns(ctx, endpointID, "th_fork", 10)
+	if err != nil || len(forkTurns) != 1 || forkTurns[0].UserMessageID != "m_user_1" || forkTurns[0].TurnID == "turn_1" {
+		t.Fatalf("forked turns=%+v err=%v", forkTurns, err)
+	}
+	snaps, err := s.ListContextSnapshots(ctx, endpointID, "th_fork", "turn", 10)
+	if err != nil || len(snaps) != 1 || snaps[0].SummaryText != "first turn" || snaps[0].CoversTurnFromID != forkTurns[0].ID {
+		t.Fatalf("forked snapshots=%+v err=%v", snaps, err)
+	}
+	// Todos and the open goal were written after m_user_2, so a fork at m_asst_1 must not see them.
+	todos, err := s.GetThreadTodosSnapshot(ctx, endpointID, "th_fork")
+	if err != nil || todos.Version != 0 {
+		t.Fatalf("early fork todos=%+v err=%v, want none", todos, err)
+	}
+	state, err := s.GetThreadState(ctx, endpointID, "th_fork")
+	if err != nil || (state != nil && (state.OpenGoal != "" || state.LastAssistantSummary != "")) {
+		t.Fatalf("early fork state=%+v err=%v, want none", state, err)
+	}
+
+	for _, threadID := range []string{sourceID, "th_fork"} {
+		var n int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM ai_run_events WHERE endpoint_id = ? AND thread_id = ? AND event_type = ?`, endpointID, threadID, ThreadForkedEventType).Scan(&n); err != nil || n != 1 {
+			t.Fatalf("%s thread.forked events=%d err=%v", threadID, n, err)
+		}
+	}
+
+	headFork, err := s.ForkThread(ctx, endpointID, sourceID, "m_asst_2", Thread{ThreadID: "th_fork_head"})
+	if err != nil {
+		t.Fatalf("ForkThread head: %v", err)
+	}
+	headTodos, err := s.GetThreadTodosSnapshot(ctx, endpointID, headFork.ThreadID)
+	if err != nil || headTodos.Version != 1 || headTodos.TodosJSON == "" {
+		t.Fatalf("head fork todos=%+v err=%v", headTodos, err)
+	}
+	headState, err := s.GetThreadState(ctx, endpointID, headFork.ThreadID)
+	if err != nil || headState == nil || headState.OpenGoal != "ship the fix" {
+		t.Fatalf("head fork state=%+v err=%v", headState, err)
+	}
+	if !headState.ProviderContinuation.IsZero() {
+		t.Fatalf("fork inherited provider continuation %+v", headState.ProviderContinuation)
+	}
+
+	// Writes to either thread stay local to it.
+	appendMessage("th_fork", "m_user_fork", "user", "branch question", now+10)
+	if _, err := s.ReplaceThreadTodosSnapshot(ctx, ThreadTodosSnapshot{EndpointID: endpointID, ThreadID: "th_fork", TodosJSON: `[]`}, nil); err != nil {
+		t.Fatalf("ReplaceThreadTodosSnapshot fork: %v", err)
+	}
+	appendMessage(sourceID, "m_user_3", "user", "third question", now+11)
+	sourceMsgs, _, _, err := s.ListMessages(ctx, endpointID, sourceID, 10, 0)
+	if err != nil || len(sourceMsgs) != 5 {
+		t.Fatalf("source messages=%d err=%v", len(sourceMsgs), err)
+	}
+	forkMsgs, _, _, err := s.ListMessages(ctx, endpointID, "th_fork", 10, 0)
+	if err != nil || len(forkMsgs) != 3 || forkMsgs[2].MessageID != "m_user_fork" {
+		t.Fatalf("fork messages=%+v err=%v", forkMsgs, err)
+	}
+	sourceTodos, err := s.GetThreadTodosSnapshot(ctx, endpointID, sourceID)
+	if err != nil || sourceTodos.Version != 1 {
+		t.Fatalf("source todos=%+v err=%v", sourceTodos, err)
+	}
+
+	if _, err := s.ForkThread(ctx, endpointID, sourceID, "m_missing", Thread{ThreadID: "th_fork_2"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("missing message err=%v, want sql.ErrNoRows", err)
+	}
+	if _, err := s.ForkThread(ctx, endpointID, "th_missing", "m_user_1", Thread{ThreadID: "th_fork_3"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("missing thread err=%v, want sql.ErrNoRows", err)
+	}
+	if th, err := s.GetThread(ctx, endpointID, "th_fork_2"); err != nil || th != nil {
+		t.Fatalf("failed fork left thread=%+v err=%v", th, err)
+	}
+}
@@ -45,6 +45,14 @@ WHERE id IN (
   WHERE r.endpoint_id = ? AND r.thread_id = ?
 )`,
 		},
+		{
+			name: "ai_tool_result_contents",
+			sql:  `DELETE FROM ai_tool_result_contents WHERE endpoint_id = ? AND thread_id = ?`,
+		},
+		{
+			name: "ai_run_system_prompts",
+			sql:  `DELETE FROM ai_run_system_prompts WHERE endpoint_id = ? AND thread_id = ?`,
+		},
 		{
 			name: "ai_thread_checkpoints",
 			sql:  `DELETE FROM ai_thread_checkpoints WHERE endpoint_id = ? AND thread_id = ?`,
@@ -83,6 +91,7 @@ func deleteThreadScopedRowsTx(ctx context.Context, tx *sql.Tx, endpointID string
 	for _, q := range []string{
 		`DELETE FROM ai_thread_state WHERE endpoint_id = ? AND thread_id = ?`,
 		`DELETE FROM ai_thread_todos WHERE endpoint_id = ? AND thread_id = ?`,
+		`DELETE FROM ai_thread_scratchpad WHERE endpoint_id = ? AND thread_id = ?`,
 		`DELETE FROM ai_queued_turns WHERE endpoint_id = ? AND thread_id = ?`,
 	} {
 		if _, err := tx.ExecContext(ctx, q, endpointID, threadID); err != nil {
@@ -0,0 +1,90 @@
+package threadstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ToolResultContent is the full payload of a tool result that was too large to inline into the conversation.
+type ToolResultContent struct {
+	ContentRef      string `json:"content_ref"`
+	EndpointID      string `json:"endpoint_id"`
+	ThreadID        string `json:"thread_id"`
+	RunID           string `json:"run_id"`
+	ToolID          string `json:"tool_id"`
+	ToolName        string `json:"tool_name"`
+	Content         string `json:"content"`
+	SizeBytes       int64  `json:"size_bytes"`
+	CreatedAtUnixMs int64  `json:"created_at_unix_ms"`
+}
+
+// PutToolResultContent stores rec under rec.ContentRef. The rows live and die with the thread.
+func (s *Store) PutToolResultContent(ctx context.Context, rec ToolResultContent) error {
+	if s == nil || s.db == nil {
+		return errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rec.ContentRef = strings.TrimSpace(rec.ContentRef)
+	rec.EndpointID = strings.TrimSpace(rec.EndpointID)
+	rec.ThreadID = strings.TrimSpace(rec.ThreadID)
+	rec.RunID = strings.TrimSpace(rec.RunID)
+	rec.ToolID = strings.TrimSpace(rec.ToolID)
+	rec.ToolName = strings.TrimSpace(rec.ToolName)
+	if rec.ContentRef == "" || rec.EndpointID == "" || rec.ThreadID == "" || rec.RunID == "" || rec.ToolID == "" {
+		return errors.New("invalid tool result content")
+	}
+	rec.SizeBytes = int64(len(rec.Content))
+	if rec.CreatedAtUnixMs <= 0 {
+		rec.CreatedAtUnixMs = time.Now().UnixMilli()
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO ai_tool_result_contents(content_ref, endpoint_id, thread_id, run_id, tool_id, tool_name, content, size_bytes, created_at_unix_ms)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(content_ref) DO UPDATE SET
+  content=excluded.content,
+  size_bytes=excluded.size_bytes
+`, rec.ContentRef, rec.EndpointID, rec.ThreadID, rec.RunID, rec.ToolID, rec.ToolName, rec.Content, rec.SizeBytes, rec.CreatedAtUnixMs)
+	return err
+}
+
+// GetToolResultContent returns the content stored under contentRef in the given thread.
+//
+// It returns sql.ErrNoRows when the ref does not exist or belongs to another thread.
+func (s *Store) GetToolResultContent(ctx context.Context, endpointID string, threadID string, contentRef string) (*ToolResultContent, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	contentRef = strings.TrimSpace(contentRef)
+	if endpointID == "" || threadID == "" || contentRef == "" {
+		return nil, errors.New("invalid request")
+	}
+	var rec ToolResultContent
+	err := s.db.QueryRowContext(ctx, `
+SELECT content_ref, endpoint_id, thread_id, run_id, tool_id, tool_name, content, size_bytes, created_at_unix_ms
+FROM ai_tool_result_contents
+WHERE content_ref = ? AND endpoint_id = ? AND thread_id = ?
+`, contentRef, endpointID, threadID).Scan(
+		&rec.ContentRef,
+		&rec.EndpointID,
+		&rec.ThreadID,
+		&rec.RunID,
+		&rec.ToolID,
+		&rec.ToolName,
+		&rec.Content,
+		&rec.SizeBytes,
+		&rec.CreatedAtUnixMs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
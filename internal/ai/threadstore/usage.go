@@ -0,0 +1,255 @@
+package threadstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+const (
+	usageTurnResultEventType = "native.turn.result"
+	usageTurnCostEventType   = "native.turn.cost"
+
+	defaultUsagePageLimit = 2000
+	maxUsagePageLimit     = 5000
+)
+
+// ModelUsage is the token and cost rollup for one provider/model pair.
+//
+// Turns recorded before the model was persisted on native.turn.result land in a bucket with an empty model.
+type ModelUsage struct {
+	ProviderID      string  `json:"provider_id"`
+	Model           string  `json:"model"`
+	Turns           int64   `json:"turns"`
+	InputTokens     int64   `json:"input_tokens"`
+	OutputTokens    int64   `json:"output_tokens"`
+	ReasoningTokens int64   `json:"reasoning_tokens"`
+	CostUSD         float64 `json:"cost_usd"`
+	// UnpricedTurns counts turns without a native.turn.cost event (no pricing configured for the model).
+	UnpricedTurns int64 `json:"unpriced_turns"`
+}
+
+type UsageTotals struct {
+	Runs            int64   `json:"runs"`
+	Turns           int64   `json:"turns"`
+	InputTokens     int64   `json:"input_tokens"`
+	OutputTokens    int64   `json:"output_tokens"`
+	ReasoningTokens int64   `json:"reasoning_tokens"`
+	CostUSD         float64 `json:"cost_usd"`
+	UnpricedTurns   int64   `json:"unpriced_turns"`
+}
+
+type ThreadUsage struct {
+	ThreadID string       `json:"thread_id"`
+	Models   []ModelUsage `json:"models"`
+	Totals   UsageTotals  `json:"totals"`
+}
+
+type UsageQuery struct {
+	SinceUnixMs int64
+	Cursor      int64
+	Limit       int
+}
+
+// UsagePage aggregates one page of usage events. Callers sum pages by following NextCursor while HasMore is set.
+//
+// Runs and Threads count distinct ids within the page, so a run that spans a page boundary is counted on both pages.
+type UsagePage struct {
+	Models     []ModelUsage `json:"models"`
+	Totals     UsageTotals  `json:"totals"`
+	Threads    int64        `json:"threads"`
+	NextCursor int64        `json:"next_cursor"`
+	HasMore    bool         `json:"has_more"`
+}
+
+type usageModelKey struct {
+	providerID string
+	model      string
+}
+
+type usageAccumulator struct {
+	models  map[usageModelKey]*ModelUsage
+	runs    map[string]struct{}
+	threads map[string]struct{}
+	costs   map[usageModelKey]int64
+}
+
+func newUsageAccumulator() *usageAccumulator {
+	return &usageAccumulator{
+		models:  make(map[usageModelKey]*ModelUsage),
+		runs:    make(map[string]struct{}),
+		threads: make(map[string]struct{}),
+		costs:   make(map[usageModelKey]int64),
+	}
+}
+
+func (a *usageAccumulator) bucket(key usageModelKey) *ModelUsage {
+	m := a.models[key]
+	if m == nil {
+		m = &ModelUsage{ProviderID: key.providerID, Model: key.model}
+		a.models[key] = m
+	}
+	return m
+}
+
+func (a *usageAccumulator) add(threadID string, runID string, eventType string, payloadJSON string) {
+	var payload struct {
+		ProviderID  string  `json:"provider_id"`
+		Model       string  `json:"model"`
+		TurnCostUSD float64 `json:"turn_cost_usd"`
+		Usage       struct {
+			InputTokens     int64 `json:"input_tokens"`
+			OutputTokens    int64 `json:"output_tokens"`
+			ReasoningTokens int64 `json:"reasoning_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(payloadJSON), &payload); err != nil {
+		return
+	}
+	key := usageModelKey{providerID: strings.TrimSpace(payload.ProviderID), model: strings.TrimSpace(payload.Model)}
+	switch eventType {
+	case usageTurnResultEventType:
+		m := a.bucket(key)
+		m.Turns++
+		m.InputTokens += payload.Usage.InputTokens
+		m.OutputTokens += payload.Usage.OutputTokens
+		m.ReasoningTokens += payload.Usage.ReasoningTokens
+		a.runs[runID] = struct{}{}
+		a.threads[threadID] = struct{}{}
+	case usageTurnCostEventType:
+		a.bucket(key).CostUSD += payload.TurnCostUSD
+		a.costs[key]++
+	}
+}
+
+func (a *usageAccumulator) result() ([]ModelUsage, UsageTotals) {
+	models := make([]ModelUsage, 0, len(a.models))
+	totals := UsageTotals{Runs: int64(len(a.runs))}
+	for key, m := range a.models {
+		if unpriced := m.Turns - a.costs[key]; unpriced > 0 {
+			m.UnpricedTurns = unpriced
+		}
+		models = append(models, *m)
+		totals.Turns += m.Turns
+		totals.InputTokens += m.InputTokens
+		totals.OutputTokens += m.OutputTokens
+		totals.ReasoningTokens += m.ReasoningTokens
+		totals.CostUSD += m.CostUSD
+		totals.UnpricedTurns += m.UnpricedTurns
+	}
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].ProviderID != models[j].ProviderID {
+			return models[i].ProviderID < models[j].ProviderID
+		}
+		return models[i].Model < models[j].Model
+	})
+	return models, totals
+}
+
+// GetThreadUsage sums token usage and estimated cost across every run of a thread.
+//
+// It reads the persisted native.turn.result and native.turn.cost run events, so the rollup
+// covers the same window as run event retention.
+func (s *Store) GetThreadUsage(ctx context.Context, endpointID string, threadID string) (*ThreadUsage, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	threadID = strings.TrimSpace(threadID)
+	if endpointID == "" || threadID == "" {
+		return nil, errors.New("invalid request")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT run_id, event_type, payload_json
+FROM ai_run_events
+WHERE endpoint_id = ? AND thread_id = ? AND event_type IN (?, ?)
+ORDER BY id ASC
+`, endpointID, threadID, usageTurnResultEventType, usageTurnCostEventType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	acc := newUsageAccumulator()
+	for rows.Next() {
+		var runID, eventType, payloadJSON string
+		if err := rows.Scan(&runID, &eventType, &payloadJSON); err != nil {
+			return nil, err
+		}
+		acc.add(threadID, runID, eventType, payloadJSON)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	models, totals := acc.result()
+	return &ThreadUsage{ThreadID: threadID, Models: models, Totals: totals}, nil
+}
+
+// ListUsagePage aggregates usage events across all threads of an endpoint, one bounded page at a time.
+func (s *Store) ListUsagePage(ctx context.Context, endpointID string, query UsageQuery) (*UsagePage, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	endpointID = strings.TrimSpace(endpointID)
+	if endpointID == "" {
+		return nil, errors.New("invalid request")
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultUsagePageLimit
+	}
+	if limit > maxUsagePageLimit {
+		limit = maxUsagePageLimit
+	}
+	cursor := query.Cursor
+	if cursor < 0 {
+		cursor = 0
+	}
+	since := query.SinceUnixMs
+	if since < 0 {
+		since = 0
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, thread_id, run_id, event_type, payload_json
+FROM ai_run_events
+WHERE endpoint_id = ? AND id > ? AND at_unix_ms >= ? AND event_type IN (?, ?)
+ORDER BY id ASC
+LIMIT ?
+`, endpointID, cursor, since, usageTurnResultEventType, usageTurnCostEventType, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	acc := newUsageAccumulator()
+	page := &UsagePage{NextCursor: cursor}
+	seen := 0
+	for rows.Next() {
+		var id int64
+		var threadID, runID, eventType, payloadJSON string
+		if err := rows.Scan(&id, &threadID, &runID, &eventType, &payloadJSON); err != nil {
+			return nil, err
+		}
+		if seen == limit {
+			page.HasMore = true
+			break
+		}
+		seen++
+		page.NextCursor = id
+		acc.add(threadID, runID, eventType, payloadJSON)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	page.Models, page.Totals = acc.result()
+	page.Threads = int64(len(acc.threads))
+	return page, nil
+}
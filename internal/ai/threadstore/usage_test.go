@@ -0,0 +1,100 @@
+package threadstore
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_GetThreadUsageAndListUsagePage(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	appendEvent := func(threadID string, runID string, eventType string, payload string, at int64) {
+		t.Helper()
+		if err := s.AppendRunEvent(ctx, RunEventRecord{
+			EndpointID:  "env_1",
+			ThreadID:    threadID,
+			RunID:       runID,
+			StreamKind:  "lifecycle",
+			EventType:   eventType,
+			PayloadJSON: payload,
+			AtUnixMs:    at,
+		}); err != nil {
+			t.Fatalf("AppendRunEvent(%s): %v", eventType, err)
+		}
+	}
+
+	old := now - int64(time.Hour/time.Millisecond)
+	appendEvent("th_1", "run_1", "native.turn.cost", `{"provider_id":"openai","model":"gpt-5","turn_cost_usd":0.25}`, old)
+	appendEvent("th_1", "run_1", "native.turn.result", `{"provider_id":"openai","model":"gpt-5","usage":{"input_tokens":100,"output_tokens":20,"reasoning_tokens":5}}`, old)
+	appendEvent("th_1", "run_2", "native.turn.result", `{"provider_id":"moonshot","model":"kimi-k2.5","usage":{"input_tokens":40,"output_tokens":10,"reasoning_tokens":0}}`, now)
+	appendEvent("th_1", "run_2", "native.turn.result", `{"usage":{"input_tokens":7,"output_tokens":3,"reasoning_tokens":1}}`, now)
+	appendEvent("th_1", "run_2", "run.end", `{}`, now)
+	appendEvent("th_2", "run_3", "native.turn.cost", `{"provider_id":"openai","model":"gpt-5","turn_cost_usd":0.5}`, now)
+	appendEvent("th_2", "run_3", "native.turn.result", `{"provider_id":"openai","model":"gpt-5","usage":{"input_tokens":200,"output_tokens":40,"reasoning_tokens":10}}`, now)
+
+	usage, err := s.GetThreadUsage(ctx, "env_1", "th_1")
+	if err != nil {
+		t.Fatalf("GetThreadUsage: %v", err)
+	}
+	if len(usage.Models) != 3 {
+		t.Fatalf("models=%+v, want 3 buckets", usage.Models)
+	}
+	if got := usage.Models[0]; got.Model != "" || got.Turns != 1 || got.InputTokens != 7 || got.UnpricedTurns != 1 {
+		t.Fatalf("legacy bucket=%+v", got)
+	}
+	if got := usage.Models[1]; got.ProviderID != "moonshot" || got.InputTokens != 40 || got.UnpricedTurns != 1 || got.CostUSD != 0 {
+		t.Fatalf("moonshot bucket=%+v", got)
+	}
+	if got := usage.Models[2]; got.ProviderID != "openai" || got.Turns != 1 || got.ReasoningTokens != 5 || got.UnpricedTurns != 0 || math.Abs(got.CostUSD-0.25) > 1e-9 {
+		t.Fatalf("openai bucket=%+v", got)
+	}
+	if got := usage.Totals; got.Runs != 2 || got.Turns != 3 || got.InputTokens != 147 || got.OutputTokens != 33 || got.ReasoningTokens != 6 || got.UnpricedTurns != 2 || math.Abs(got.CostUSD-0.25) > 1e-9 {
+		t.Fatalf("totals=%+v", got)
+	}
+
+	all, err := s.ListUsagePage(ctx, "env_1", UsageQuery{})
+	if err != nil {
+		t.Fatalf("ListUsagePage: %v", err)
+	}
+	if all.HasMore || all.Threads != 2 || all.Totals.Turns != 4 || math.Abs(all.Totals.CostUSD-0.75) > 1e-9 {
+		t.Fatalf("all=%+v", all)
+	}
+
+	recent, err := s.ListUsagePage(ctx, "env_1", UsageQuery{SinceUnixMs: now})
+	if err != nil {
+		t.Fatalf("ListUsagePage since: %v", err)
+	}
+	if recent.Totals.Turns != 3 || recent.Totals.InputTokens != 247 {
+		t.Fatalf("recent=%+v", recent)
+	}
+
+	var turns int64
+	var cursor int64
+	pages := 0
+	for {
+		page, err := s.ListUsagePage(ctx, "env_1", UsageQuery{Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("ListUsagePage cursor=%d: %v", cursor, err)
+		}
+		pages++
+		turns += page.Totals.Turns
+		cursor = page.NextCursor
+		if !page.HasMore {
+			break
+		}
+	}
+	if pages != 3 || turns != 4 {
+		t.Fatalf("pages=%d turns=%d, want 3 pages and 4 turns", pages, turns)
+	}
+}
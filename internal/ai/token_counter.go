@@ -0,0 +1,276 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	aoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Estimate sources recorded on context usage events and runtime state.
+const (
+	estimateSourceTokenizer = "tokenizer"
+	estimateSourceHeuristic = "heuristic"
+)
+
+const (
+	anthropicTokenCountTimeout = 3 * time.Second
+	tokenCountCacheSize        = 8
+
+	// Per-message and reply-priming overhead of the OpenAI chat format.
+	tiktokenTokensPerMessage = 3
+	tiktokenTokensPerReply   = 3
+)
+
+// errTokenizerUnavailable reports that no exact tokenizer exists for a model; callers fall back to the heuristic.
+var errTokenizerUnavailable = errors.New("tokenizer unavailable")
+
+// TokenCounter counts the input tokens of one turn request.
+//
+// Implementations return errTokenizerUnavailable (or any error) when they cannot count exactly,
+// and the runtime falls back to estimateTurnTokens.
+type TokenCounter interface {
+	CountTurnTokens(ctx context.Context, req TurnRequest) (int, error)
+}
+
+// newProviderTokenCounter picks the exact counter for a provider, or nil when only the heuristic applies.
+func newProviderTokenCounter(providerType string, baseURL string, apiKey string) TokenCounter {
+	switch strings.ToLower(strings.TrimSpace(providerType)) {
+	case "openai", "azure_openai":
+		return tiktokenCounter{}
+	case "anthropic":
+		if strings.TrimSpace(apiKey) == "" {
+			return nil
+		}
+		opts := []aoption.RequestOption{aoption.WithAPIKey(strings.TrimSpace(apiKey)), aoption.WithMaxRetries(0)}
+		if strings.TrimSpace(baseURL) != "" {
+			opts = append(opts, aoption.WithBaseURL(strings.TrimSpace(baseURL)))
+		}
+		return newCachingTokenCounter(&anthropicTokenCounter{client: anthropic.NewClient(opts...)})
+	default:
+		return nil
+	}
+}
+
+// countTurnTokens counts with counter when it can, and otherwise falls back to the character heuristic.
+func countTurnTokens(ctx context.Context, counter TokenCounter, providerType string, req TurnRequest) (int, string) {
+	if counter != nil {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if n, err := counter.CountTurnTokens(ctx, req); err == nil && n > 0 {
+			return n, estimateSourceTokenizer
+		}
+	}
+	return estimateTurnTokens(providerType, req)
+}
+
+var tiktokenEncoders = struct {
+	once sync.Once
+	mu   sync.Mutex
+	byID map[string]*tiktoken.Tiktoken
+}{byID: map[string]*tiktoken.Tiktoken{}}
+
+// tiktokenEncoderForModel returns the cached BPE encoder for an OpenAI model.
+//
+// Encoders are built once per encoding from the embedded offline vocabularies; building one
+// parses several megabytes of ranks, so it must not happen every turn.
+func tiktokenEncoderForModel(model string) (*tiktoken.Tiktoken, error) {
+	encoding := tiktokenEncodingForModel(model)
+	if encoding == "" {
+		return nil, fmt.Errorf("%w: model %q", errTokenizerUnavailable, strings.TrimSpace(model))
+	}
+	tiktokenEncoders.once.Do(func() {
+		tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+	})
+	tiktokenEncoders.mu.Lock()
+	defer tiktokenEncoders.mu.Unlock()
+	if enc := tiktokenEncoders.byID[encoding]; enc != nil {
+		return enc, nil
+	}
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errTokenizerUnavailable, err)
+	}
+	tiktokenEncoders.byID[encoding] = enc
+	return enc, nil
+}
+
+func tiktokenEncodingForModel(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" {
+		return ""
+	}
+	if enc, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return enc
+	}
+	for _, prefix := range []string{"gpt-5", "gpt-4.5", "gpt-4.1", "gpt-4o", "chatgpt-4o", "o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return tiktoken.MODEL_O200K_BASE
+		}
+	}
+	for _, prefix := range []string{"gpt-4", "gpt-3.5"} {
+		if strings.HasPrefix(model, prefix) {
+			return tiktoken.MODEL_CL100K_BASE
+		}
+	}
+	return ""
+}
+
+// tiktokenCounter counts OpenAI turns locally with the model's BPE encoding.
+type tiktokenCounter struct{}
+
+func (tiktokenCounter) CountTurnTokens(_ context.Context, req TurnRequest) (int, error) {
+	enc, err := tiktokenEncoderForModel(req.Model)
+	if err != nil {
+		return 0, err
+	}
+	count := func(text string) int {
+		if text == "" {
+			return 0
+		}
+		return len(enc.EncodeOrdinary(text))
+	}
+	total := tiktokenTokensPerReply
+	for _, msg := range req.Messages {
+		total += tiktokenTokensPerMessage + count(msg.Role)
+		for _, part := range msg.Content {
+			total += count(part.Text)
+			total += count(part.FileURI)
+			total += count(part.ToolName)
+			total += count(part.ArgsJSON)
+			total += count(string(part.JSON))
+		}
+	}
+	for _, tool := range req.Tools {
+		total += count(tool.Name) + count(tool.Description) + count(string(tool.InputSchema))
+	}
+	return total, nil
+}
+
+// anthropicTokenCounter asks the Anthropic token counting endpoint for a turn's input size.
+//
+// After the first failure it stops calling the endpoint for the rest of the run, so an
+// unsupported gateway costs one request instead of one per step.
+type anthropicTokenCounter struct {
+	client anthropic.Client
+
+	mu       sync.Mutex
+	disabled bool
+}
+
+func (c *anthropicTokenCounter) CountTurnTokens(ctx context.Context, req TurnRequest) (int, error) {
+	if c == nil {
+		return 0, errTokenizerUnavailable
+	}
+	c.mu.Lock()
+	disabled := c.disabled
+	c.mu.Unlock()
+	if disabled {
+		return 0, errTokenizerUnavailable
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return 0, errors.New("missing model")
+	}
+	unionTools, _ := buildAnthropicTools(req.Tools)
+	tools := make([]anthropic.MessageCountTokensToolUnionParam, 0, len(unionTools))
+	for _, tool := range unionTools {
+		if tool.OfTool != nil {
+			tools = append(tools, anthropic.MessageCountTokensToolUnionParam{OfTool: tool.OfTool})
+		}
+	}
+	params := anthropic.MessageCountTokensParams{
+		Model:    anthropic.Model(strings.TrimSpace(req.Model)),
+		Messages: buildAnthropicMessages(req.Messages),
+		Tools:    tools,
+	}
+	if system := collectSystemPrompt(req.Messages); strings.TrimSpace(system) != "" {
+		params.System = anthropic.MessageCountTokensParamsSystemUnion{
+			OfTextBlockArray: []anthropic.TextBlockParam{{Text: strings.TrimSpace(system)}},
+		}
+	}
+
+	countCtx, cancel := context.WithTimeout(ctx, anthropicTokenCountTimeout)
+	defer cancel()
+	res, err := c.client.Messages.CountTokens(countCtx, params)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.mu.Lock()
+			c.disabled = true
+			c.mu.Unlock()
+		}
+		return 0, err
+	}
+	if res == nil || res.InputTokens <= 0 {
+		return 0, errTokenizerUnavailable
+	}
+	return int(res.InputTokens), nil
+}
+
+// cachingTokenCounter remembers the last few counts so repeated estimates of the same request stay free.
+type cachingTokenCounter struct {
+	next TokenCounter
+
+	mu      sync.Mutex
+	order   []string
+	entries map[string]int
+}
+
+func newCachingTokenCounter(next TokenCounter) *cachingTokenCounter {
+	return &cachingTokenCounter{next: next, entries: map[string]int{}}
+}
+
+func (c *cachingTokenCounter) CountTurnTokens(ctx context.Context, req TurnRequest) (int, error) {
+	if c == nil || c.next == nil {
+		return 0, errTokenizerUnavailable
+	}
+	key, err := tokenCountCacheKey(req)
+	if err != nil {
+		return c.next.CountTurnTokens(ctx, req)
+	}
+	c.mu.Lock()
+	if n, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		return n, nil
+	}
+	c.mu.Unlock()
+
+	n, err := c.next.CountTurnTokens(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.order = append(c.order, key)
+		if len(c.order) > tokenCountCacheSize {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+	}
+	c.entries[key] = n
+	return n, nil
+}
+
+func tokenCountCacheKey(req TurnRequest) (string, error) {
+	b, err := json.Marshal(struct {
+		Model    string    `json:"model"`
+		Messages []Message `json:"messages"`
+		Tools    []ToolDef `json:"tools"`
+	}{Model: strings.TrimSpace(req.Model), Messages: req.Messages, Tools: req.Tools})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
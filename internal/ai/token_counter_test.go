@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTiktokenCounter_CountsOpenAIModels(t *testing.T) {
+	t.Parallel()
+
+	req := TurnRequest{
+		Model: "gpt-4o-mini",
+		Messages: []Message{
+			{Role: "system", Content: []ContentPart{{Type: "text", Text: "You are helpful."}}},
+			{Role: "user", Content: []ContentPart{{Type: "text", Text: "hello world"}}},
+		},
+	}
+	n, err := tiktokenCounter{}.CountTurnTokens(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CountTurnTokens: %v", err)
+	}
+	// "You are helpful." = 4, "hello world" = 2, roles = 2, framing = 2*3 + 3.
+	if n != 17 {
+		t.Fatalf("tokens=%d, want 17", n)
+	}
+
+	enc1, err := tiktokenEncoderForModel("gpt-4o")
+	if err != nil {
+		t.Fatalf("encoder: %v", err)
+	}
+	enc2, err := tiktokenEncoderForModel("gpt-5-mini")
+	if err != nil {
+		t.Fatalf("encoder: %v", err)
+	}
+	if enc1 != enc2 {
+		t.Fatalf("models sharing an encoding should reuse the cached encoder")
+	}
+}
+
+func TestTiktokenCounter_UnknownModelFallsBackToHeuristic(t *testing.T) {
+	t.Parallel()
+
+	req := TurnRequest{
+		Model:    "my-local-model",
+		Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: strings.Repeat("a", 400)}}}},
+	}
+	if _, err := (tiktokenCounter{}).CountTurnTokens(context.Background(), req); !errors.Is(err, errTokenizerUnavailable) {
+		t.Fatalf("err=%v, want errTokenizerUnavailable", err)
+	}
+	n, source := countTurnTokens(context.Background(), tiktokenCounter{}, "openai", req)
+	want, _ := estimateTurnTokens("openai", req)
+	if source != estimateSourceHeuristic || n != want {
+		t.Fatalf("got (%d, %q), want (%d, %q)", n, source, want, estimateSourceHeuristic)
+	}
+	if _, source := countTurnTokens(context.Background(), nil, "moonshot", req); source != estimateSourceHeuristic {
+		t.Fatalf("nil counter source=%q", source)
+	}
+	req.Model = "gpt-4.1"
+	if _, source := countTurnTokens(context.Background(), tiktokenCounter{}, "openai", req); source != estimateSourceTokenizer {
+		t.Fatalf("known model source=%q, want tokenizer", source)
+	}
+}
+
+type countingTokenCounter struct {
+	calls atomic.Int32
+}
+
+func (c *countingTokenCounter) CountTurnTokens(_ context.Context, req TurnRequest) (int, error) {
+	c.calls.Add(1)
+	return 10 * len(req.Messages), nil
+}
+
+func TestCachingTokenCounter_ReusesCountsForIdenticalRequests(t *testing.T) {
+	t.Parallel()
+
+	inner := &countingTokenCounter{}
+	counter := newCachingTokenCounter(inner)
+	req := TurnRequest{Model: "m", Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: "hi"}}}}}
+	for i := 0; i < 3; i++ {
+		if n, err := counter.CountTurnTokens(context.Background(), req); err != nil || n != 10 {
+			t.Fatalf("count=%d err=%v", n, err)
+		}
+	}
+	if got := inner.calls.Load(); got != 1 {
+		t.Fatalf("inner calls=%d, want 1", got)
+	}
+	req.Messages = append(req.Messages, Message{Role: "assistant", Content: []ContentPart{{Type: "text", Text: "hello"}}})
+	if n, _ := counter.CountTurnTokens(context.Background(), req); n != 20 {
+		t.Fatalf("count=%d, want 20", n)
+	}
+	for i := 0; i < tokenCountCacheSize+2; i++ {
+		req.Model = strings.Repeat("m", i+2)
+		_, _ = counter.CountTurnTokens(context.Background(), req)
+	}
+	if len(counter.entries) != tokenCountCacheSize || len(counter.order) != tokenCountCacheSize {
+		t.Fatalf("cache size entries=%d order=%d, want %d", len(counter.entries), len(counter.order), tokenCountCacheSize)
+	}
+}
+
+func TestAnthropicTokenCounter_UsesCountTokensEndpoint(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/messages/count_tokens") {
+			http.NotFound(w, r)
+			return
+		}
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"input_tokens":1234}`)
+	}))
+	defer srv.Close()
+
+	counter := newProviderTokenCounter("anthropic", srv.URL, "sk-test")
+	req := TurnRequest{
+		Model: "claude-sonnet-4-5",
+		Messages: []Message{
+			{Role: "system", Content: []ContentPart{{Type: "text", Text: "Be brief."}}},
+			{Role: "user", Content: []ContentPart{{Type: "text", Text: "hello"}}},
+		},
+		Tools: []ToolDef{{Name: "terminal.exec", Description: "Run a command", InputSchema: json.RawMessage(`{"type":"object","properties":{"command":{"type":"string"}},"required":["command"]}`)}},
+	}
+	for i := 0; i < 2; i++ {
+		n, source := countTurnTokens(context.Background(), counter, "anthropic", req)
+		if n != 1234 || source != estimateSourceTokenizer {
+			t.Fatalf("got (%d, %q), want (1234, tokenizer)", n, source)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("endpoint calls=%d, want 1 (second count should hit the cache)", got)
+	}
+	if gotBody["model"] != "claude-sonnet-4-5" {
+		t.Fatalf("model=%v", gotBody["model"])
+	}
+	if _, ok := gotBody["system"]; !ok {
+		t.Fatalf("expected system prompt in count request: %v", gotBody)
+	}
+	if tools, _ := gotBody["tools"].([]any); len(tools) != 1 {
+		t.Fatalf("tools=%v, want one tool", gotBody["tools"])
+	}
+}
+
+func TestAnthropicTokenCounter_DisablesAfterEndpointFailure(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	counter := newProviderTokenCounter("anthropic", srv.URL, "sk-test")
+	for i := 0; i < 3; i++ {
+		req := TurnRequest{Model: "claude-sonnet-4-5", Messages: []Message{{Role: "user", Content: []ContentPart{{Type: "text", Text: strings.Repeat("x", i+1)}}}}}
+		if _, source := countTurnTokens(context.Background(), counter, "anthropic", req); source != estimateSourceHeuristic {
+			t.Fatalf("source=%q, want heuristic", source)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("endpoint calls=%d, want 1", got)
+	}
+	if newProviderTokenCounter("anthropic", srv.URL, "") != nil {
+		t.Fatalf("anthropic counter without api key should be nil")
+	}
+	if newProviderTokenCounter("moonshot", "", "sk-test") != nil {
+		t.Fatalf("providers without a tokenizer should get a nil counter")
+	}
+}
@@ -0,0 +1,167 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+// toolApprovalDecision is the outcome of ai.tool_approval for one tool call.
+type toolApprovalDecision struct {
+	// Matched is false when no rule applied and the execution_policy default decides.
+	Matched bool
+	Require bool
+	// Reason names the decision and the matched rule; it is persisted as the tool block approvalReason.
+	Reason string
+}
+
+// toolApprovalBinding applies ai.tool_approval to the calls a CoreToolScheduler executes.
+type toolApprovalBinding struct {
+	rules []config.CompiledToolApprovalRule
+	// allowRelax lets rules skip approvals that requiredByDefault asks for.
+	allowRelax bool
+	// requiredByDefault reports whether execution_policy.require_user_approval asks approval for a call.
+	requiredByDefault func(toolName string, args map[string]any) bool
+	// approvedBefore reports whether the user already approved toolName earlier in the run.
+	approvedBefore func(toolName string) bool
+}
+
+// newToolApprovalBinding binds the compiled ai.tool_approval rules to this run; it returns nil without rules.
+func (r *run) newToolApprovalBinding() (*toolApprovalBinding, error) {
+	if r == nil || r.cfg == nil || r.cfg.ToolApproval == nil {
+		return nil, nil
+	}
+	rules, err := r.cfg.ToolApproval.CompiledRules()
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	cfg := r.cfg
+	return &toolApprovalBinding{
+		rules:      rules,
+		allowRelax: cfg.ToolApproval.AllowRelax,
+		requiredByDefault: func(toolName string, args map[string]any) bool {
+			return cfg.EffectiveRequireUserApproval() && requiresApproval(toolName, args)
+		},
+		approvedBefore: r.toolApprovedEarlier,
+	}, nil
+}
+
+// decide returns the ai.tool_approval decision for call.
+//
+// Without allow_relax a rule that skips approval cannot lift an approval the execution_policy requires.
+func (b *toolApprovalBinding) decide(call ToolCall) toolApprovalDecision {
+	if b == nil {
+		return toolApprovalDecision{}
+	}
+	toolName := strings.TrimSpace(call.Name)
+	approvedBefore := b.approvedBefore != nil && b.approvedBefore(toolName)
+	decision := evaluateToolApprovalRules(b.rules, toolName, call.Args, approvedBefore)
+	if !decision.Matched || decision.Require || b.allowRelax {
+		return decision
+	}
+	if b.requiredByDefault != nil && b.requiredByDefault(toolName, call.Args) {
+		return toolApprovalDecision{Matched: true, Require: true, Reason: "required: execution_policy.require_user_approval (tool_approval.allow_relax is off)"}
+	}
+	return decision
+}
+
+type toolApprovalDecisionKey struct{}
+
+// withToolApprovalDecision attaches the scheduler's approval decision to the context passed to the tool handler.
+func withToolApprovalDecision(ctx context.Context, decision toolApprovalDecision) context.Context {
+	return context.WithValue(ctx, toolApprovalDecisionKey{}, decision)
+}
+
+// toolApprovalDecisionFromContext returns the decision attached by withToolApprovalDecision.
+func toolApprovalDecisionFromContext(ctx context.Context) (toolApprovalDecision, bool) {
+	if ctx == nil {
+		return toolApprovalDecision{}, false
+	}
+	decision, ok := ctx.Value(toolApprovalDecisionKey{}).(toolApprovalDecision)
+	return decision, ok
+}
+
+// evaluateToolApprovalRules returns the decision of the first rule that matches toolName/args.
+//
+// approvedBefore reports whether the user already approved toolName earlier in the run
+// (used by first_time_per_run).
+func evaluateToolApprovalRules(rules []config.CompiledToolApprovalRule, toolName string, args map[string]any, approvedBefore bool) toolApprovalDecision {
+	toolName = strings.TrimSpace(toolName)
+	for i, rule := range rules {
+		if rule.Tool != "*" && rule.Tool != toolName {
+			continue
+		}
+		ruleRef := fmt.Sprintf("tool_approval.rules[%d] %s", i, rule.Mode)
+		switch rule.Mode {
+		case config.AIToolApprovalAlways:
+			return toolApprovalDecision{Matched: true, Require: true, Reason: "required: " + ruleRef}
+		case config.AIToolApprovalNever:
+			return toolApprovalDecision{Matched: true, Require: false, Reason: "skipped: " + ruleRef}
+		case config.AIToolApprovalFirstTimePerRun:
+			if approvedBefore {
+				return toolApprovalDecision{Matched: true, Require: false, Reason: "skipped: " + ruleRef + " (approved earlier in this run)"}
+			}
+			return toolApprovalDecision{Matched: true, Require: true, Reason: "required: " + ruleRef}
+		case config.AIToolApprovalMatchArgs:
+			if toolApprovalArgsMatch(rule, args) {
+				return toolApprovalDecision{Matched: true, Require: true, Reason: "required: " + ruleRef + " (" + strings.Join(rule.ArgNames, ", ") + ")"}
+			}
+		}
+	}
+	return toolApprovalDecision{}
+}
+
+// toolApprovalArgsMatch reports whether every argument expression of rule matches args.
+func toolApprovalArgsMatch(rule config.CompiledToolApprovalRule, args map[string]any) bool {
+	if len(rule.ArgNames) == 0 {
+		return false
+	}
+	for i, name := range rule.ArgNames {
+		value, ok := args[name]
+		if !ok || value == nil {
+			return false
+		}
+		if !rule.ArgExprs[i].MatchString(toolApprovalArgString(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+func toolApprovalArgString(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+func (r *run) toolApprovedEarlier(toolName string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.approvedToolNames[strings.TrimSpace(toolName)]
+}
+
+func (r *run) markToolApproved(toolName string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.approvedToolNames == nil {
+		r.approvedToolNames = make(map[string]bool)
+	}
+	r.approvedToolNames[strings.TrimSpace(toolName)] = true
+}
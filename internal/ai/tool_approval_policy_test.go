@@ -0,0 +1,236 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func compileToolApprovalPolicyForTest(t *testing.T, policy *config.AIToolApprovalPolicy) []config.CompiledToolApprovalRule {
+	t.Helper()
+	rules, err := policy.CompiledRules()
+	if err != nil {
+		t.Fatalf("CompiledRules: %v", err)
+	}
+	return rules
+}
+
+func TestEvaluateToolApprovalRules(t *testing.T) {
+	t.Parallel()
+
+	rules := compileToolApprovalPolicyForTest(t, &config.AIToolApprovalPolicy{Rules: []config.AIToolApprovalRule{
+		{Tool: "terminal.exec", Mode: config.AIToolApprovalMatchArgs, Args: map[string]string{"command": `\brm\s+-rf\b`}},
+		{Tool: "terminal.exec", Mode: config.AIToolApprovalNever},
+		{Tool: "apply_patch", Mode: config.AIToolApprovalAlways},
+		{Tool: "web.search", Mode: config.AIToolApprovalFirstTimePerRun},
+		{Tool: "*", Mode: config.AIToolApprovalNever},
+	}})
+
+	cases := []struct {
+		name           string
+		tool           string
+		args           map[string]any
+		approvedBefore bool
+		want           toolApprovalDecision
+	}{
+		{
+			name: "match_args regex matches",
+			tool: "terminal.exec",
+			args: map[string]any{"command": "cd /tmp && rm -rf build"},
+			want: toolApprovalDecision{Matched: true, Require: true, Reason: "required: tool_approval.rules[0] match_args (command)"},
+		},
+		{
+			name: "match_args miss falls through to the next rule",
+			tool: "terminal.exec",
+			args: map[string]any{"command": "ls -la"},
+			want: toolApprovalDecision{Matched: true, Require: false, Reason: "skipped: tool_approval.rules[1] never"},
+		},
+		{
+			name: "match_args missing argument falls through",
+			tool: "terminal.exec",
+			args: map[string]any{"cwd": "/tmp"},
+			want: toolApprovalDecision{Matched: true, Require: false, Reason: "skipped: tool_approval.rules[1] never"},
+		},
+		{
+			name: "always",
+			tool: "apply_patch",
+			want: toolApprovalDecision{Matched: true, Require: true, Reason: "required: tool_approval.rules[2] always"},
+		},
+		{
+			name: "first_time_per_run first call",
+			tool: "web.search",
+			want: toolApprovalDecision{Matched: true, Require: true, Reason: "required: tool_approval.rules[3] first_time_per_run"},
+		},
+		{
+			name:           "first_time_per_run after approval",
+			tool:           "web.search",
+			approvedBefore: true,
+			want:           toolApprovalDecision{Matched: true, Require: false, Reason: "skipped: tool_approval.rules[3] first_time_per_run (approved earlier in this run)"},
+		},
+		{
+			name: "wildcard",
+			tool: "write_todos",
+			want: toolApprovalDecision{Matched: true, Require: false, Reason: "skipped: tool_approval.rules[4] never"},
+		},
+	}
+	for _, tc := range cases {
+		got := evaluateToolApprovalRules(rules, tc.tool, tc.args, tc.approvedBefore)
+		if got != tc.want {
+			t.Fatalf("%s: got=%+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+
+	if got := evaluateToolApprovalRules(nil, "terminal.exec", nil, false); got.Matched {
+		t.Fatalf("no rules matched: %+v", got)
+	}
+	narrow := compileToolApprovalPolicyForTest(t, &config.AIToolApprovalPolicy{Rules: []config.AIToolApprovalRule{{Tool: "apply_patch", Mode: config.AIToolApprovalAlways}}})
+	if got := evaluateToolApprovalRules(narrow, "terminal.exec", nil, false); got.Matched {
+		t.Fatalf("unrelated rule matched: %+v", got)
+	}
+}
+
+func TestToolApprovalArgsMatch_NonStringValues(t *testing.T) {
+	t.Parallel()
+
+	rules := compileToolApprovalPolicyForTest(t, &config.AIToolApprovalPolicy{Rules: []config.AIToolApprovalRule{
+		{Tool: "terminal.exec", Mode: config.AIToolApprovalMatchArgs, Args: map[string]string{"timeout_ms": `^[0-9]{6,}$`, "command": `^git `}},
+	}})
+	if got := strings.Join(rules[0].ArgNames, ","); got != "command,timeout_ms" {
+		t.Fatalf("ArgNames=%q", got)
+	}
+	if !toolApprovalArgsMatch(rules[0], map[string]any{"command": "git push --force", "timeout_ms": 600000}) {
+		t.Fatalf("long timeout should match")
+	}
+	if toolApprovalArgsMatch(rules[0], map[string]any{"command": "git push --force", "timeout_ms": 1000}) {
+		t.Fatalf("short timeout must not match")
+	}
+}
+
+func TestToolApprovalBinding_RulesOnlyTightenWithoutAllowRelax(t *testing.T) {
+	t.Parallel()
+
+	newBinding := func(allowRelax bool) *toolApprovalBinding {
+		r := &run{cfg: &config.AIConfig{
+			ExecutionPolicy: &config.AIExecutionPolicy{RequireUserApproval: true},
+			ToolApproval: &config.AIToolApprovalPolicy{AllowRelax: allowRelax, Rules: []config.AIToolApprovalRule{
+				{Tool: "terminal.exec", Mode: config.AIToolApprovalNever},
+			}},
+		}}
+		binding, err := r.newToolApprovalBinding()
+		if err != nil || binding == nil {
+			t.Fatalf("newToolApprovalBinding binding=%v err=%v", binding, err)
+		}
+		return binding
+	}
+	mutating := ToolCall{Name: "terminal.exec", Args: map[string]any{"command": "rm -rf build"}}
+	readonly := ToolCall{Name: "terminal.exec", Args: map[string]any{"command": "ls -la"}}
+
+	strict := newBinding(false)
+	if got := strict.decide(mutating); !got.Require || got.Reason != "required: execution_policy.require_user_approval (tool_approval.allow_relax is off)" {
+		t.Fatalf("never rule without allow_relax decision=%+v, want approval required", got)
+	}
+	if got := strict.decide(readonly); got.Require || got.Reason != "skipped: tool_approval.rules[0] never" {
+		t.Fatalf("never rule on a call execution_policy does not gate decision=%+v", got)
+	}
+	if got := newBinding(true).decide(mutating); got.Require || got.Reason != "skipped: tool_approval.rules[0] never" {
+		t.Fatalf("never rule with allow_relax decision=%+v, want skipped", got)
+	}
+}
+
+func TestNewToolApprovalBinding_RejectsInvalidRules(t *testing.T) {
+	t.Parallel()
+
+	r := &run{cfg: &config.AIConfig{ToolApproval: &config.AIToolApprovalPolicy{Rules: []config.AIToolApprovalRule{
+		{Tool: "terminal.exec", Mode: config.AIToolApprovalMatchArgs, Args: map[string]string{"command": "(rm"}},
+	}}}}
+	if _, err := r.newToolApprovalBinding(); err == nil {
+		t.Fatalf("expected an error for an invalid args expression")
+	}
+}
+
+func TestCoreToolScheduler_ToolApprovalFirstTimePerRun(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	r := newRun(runOptions{
+		Log:          slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
+		AgentHomeDir: workspace,
+		Shell:        "bash",
+		AIConfig: &config.AIConfig{
+			ToolApproval: &config.AIToolApprovalPolicy{Rules: []config.AIToolApprovalRule{
+				{Tool: "terminal.exec", Mode: config.AIToolApprovalFirstTimePerRun},
+			}},
+		},
+		SessionMeta: &session.Meta{
+			CanRead:    true,
+			CanWrite:   true,
+			CanExecute: true,
+			CanAdmin:   true,
+		},
+		MessageID: "msg_tool_approval_first_time",
+	})
+	r.runMode = config.AIModeAct
+
+	reg := NewInMemoryToolRegistry()
+	if err := registerBuiltInTools(reg, r); err != nil {
+		t.Fatalf("registerBuiltInTools: %v", err)
+	}
+	scheduler, err := NewCoreToolScheduler(reg, nil)
+	if err != nil {
+		t.Fatalf("NewCoreToolScheduler: %v", err)
+	}
+	if scheduler.approval, err = r.newToolApprovalBinding(); err != nil {
+		t.Fatalf("newToolApprovalBinding: %v", err)
+	}
+
+	dispatch := func(toolID string, command string, expectApproval bool) ToolResult {
+		t.Helper()
+		done := make(chan ToolResult, 1)
+		go func() {
+			results := scheduler.Dispatch(context.Background(), config.AIModeAct, []ToolCall{{ID: toolID, Name: "terminal.exec", Args: map[string]any{"command": command}}})
+			done <- results[0]
+		}()
+		if expectApproval {
+			waitApprovalRequested(t, r, toolID)
+			if err := r.approveTool(toolID, true); err != nil {
+				t.Fatalf("approveTool: %v", err)
+			}
+		}
+		select {
+		case res := <-done:
+			assertNoApprovalWait(t, r, toolID)
+			return res
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s did not finish", toolID)
+		}
+		return ToolResult{}
+	}
+
+	if first := dispatch("tool_first", "printf first", true); first.Status != toolResultStatusSuccess {
+		t.Fatalf("first call should succeed after approval, result=%+v", first)
+	}
+	if second := dispatch("tool_second", "printf second", false); second.Status != toolResultStatusSuccess {
+		t.Fatalf("second call should run without approval, result=%+v", second)
+	}
+
+	reasons := map[string]string{}
+	r.muAssistant.Lock()
+	for _, raw := range r.assistantBlocks {
+		if block, ok := raw.(ToolCallBlock); ok {
+			reasons[block.ToolID] = block.ApprovalReason
+		}
+	}
+	r.muAssistant.Unlock()
+	if got := reasons["tool_first"]; got != "required: tool_approval.rules[0] first_time_per_run" {
+		t.Fatalf("first approvalReason=%q", got)
+	}
+	if got := reasons["tool_second"]; got != "skipped: tool_approval.rules[0] first_time_per_run (approved earlier in this run)" {
+		t.Fatalf("second approvalReason=%q", got)
+	}
+}
@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// toolArgsRepairStats counts how a turn's tool call arguments were parsed, for the provider diag.
+type toolArgsRepairStats struct {
+	Repaired  int
+	Malformed int
+}
+
+// parse reads one tool call's complete raw arguments: strictly first, then after repairJSONObject, and
+// otherwise as empty args so tool validation reports the problem to the model.
+func (s *toolArgsRepairStats) parse(raw string) map[string]any {
+	raw = strings.TrimSpace(raw)
+	args := map[string]any{}
+	if raw == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(raw), &args); err == nil && args != nil {
+		return args
+	}
+	if repaired, ok := repairJSONObject(raw); ok {
+		args = map[string]any{}
+		if err := json.Unmarshal([]byte(repaired), &args); err == nil && args != nil {
+			if s != nil {
+				s.Repaired++
+			}
+			return args
+		}
+	}
+	if s != nil {
+		s.Malformed++
+	}
+	return map[string]any{}
+}
+
+// applyDiag records the counts on a turn's RawProviderDiag; clean turns add nothing.
+func (s *toolArgsRepairStats) applyDiag(diag map[string]any) {
+	if s == nil || diag == nil {
+		return
+	}
+	if s.Repaired > 0 {
+		diag["repaired_tool_args"] = s.Repaired
+	}
+	if s.Malformed > 0 {
+		diag["malformed_tool_args"] = s.Malformed
+	}
+}
+
+// repairJSONObject rewrites near-JSON that weaker models emit into a JSON object. It handles markdown
+// code fences, text around the object, single-quoted strings, unquoted keys, trailing commas, raw
+// newlines and tabs inside strings, and Python literals (True, False, None).
+//
+// Truncated input (an unterminated string or unclosed brackets) is not repaired: closing it would turn
+// a cut-off write or patch into a complete-looking call.
+func repairJSONObject(raw string) (string, bool) {
+	s := strings.TrimSpace(raw)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```json")
+		s = strings.TrimPrefix(s, "```")
+		s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+	}
+	start := strings.IndexByte(s, '{')
+	if start < 0 {
+		return "", false
+	}
+	s = s[start:]
+
+	out := make([]byte, 0, len(s)+8)
+	var stack []byte
+	inString := false
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case c == '\\' && i+1 < len(s):
+				if quote == '\'' && s[i+1] == '\'' {
+					out = append(out, '\'')
+				} else {
+					out = append(out, c, s[i+1])
+				}
+				i++
+			case c == quote:
+				out = append(out, '"')
+				inString = false
+			case c == '"':
+				out = append(out, '\\', '"')
+			case c == '\n':
+				out = append(out, '\\', 'n')
+			case c == '\r':
+				out = append(out, '\\', 'r')
+			case c == '\t':
+				out = append(out, '\\', 't')
+			default:
+				out = append(out, c)
+			}
+			continue
+		}
+		switch {
+		case c == '"' || c == '\'':
+			inString = true
+			quote = c
+			out = append(out, '"')
+		case c == '{' || c == '[':
+			stack = append(stack, c)
+			out = append(out, c)
+		case c == '}' || c == ']':
+			if len(stack) == 0 || (c == '}') != (stack[len(stack)-1] == '{') {
+				return "", false
+			}
+			stack = stack[:len(stack)-1]
+			out = append(trimTrailingJSONComma(out), c)
+			if len(stack) == 0 {
+				// Anything after the object is prose around it.
+				return string(out), true
+			}
+		case isJSONIdentStart(c):
+			j := i
+			for j < len(s) && isJSONIdentPart(s[j]) {
+				j++
+			}
+			word := s[i:j]
+			k := j
+			for k < len(s) && (s[k] == ' ' || s[k] == '\t' || s[k] == '\n' || s[k] == '\r') {
+				k++
+			}
+			switch {
+			case k < len(s) && s[k] == ':' && len(stack) > 0 && stack[len(stack)-1] == '{':
+				out = append(out, '"')
+				out = append(out, word...)
+				out = append(out, '"')
+			case word == "True":
+				out = append(out, "true"...)
+			case word == "False":
+				out = append(out, "false"...)
+			case word == "None":
+				out = append(out, "null"...)
+			default:
+				out = append(out, word...)
+			}
+			i = j - 1
+		default:
+			out = append(out, c)
+		}
+	}
+	return "", false
+}
+
+// trimTrailingJSONComma drops a comma (and the whitespace after it) that directly precedes a closing bracket.
+func trimTrailingJSONComma(out []byte) []byte {
+	end := len(out)
+	for end > 0 && (out[end-1] == ' ' || out[end-1] == '\t' || out[end-1] == '\n' || out[end-1] == '\r') {
+		end--
+	}
+	if end > 0 && out[end-1] == ',' {
+		return out[:end-1]
+	}
+	return out
+}
+
+func isJSONIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isJSONIdentPart(c byte) bool {
+	return isJSONIdentStart(c) || c == '-' || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToolArgsRepairStats_Parse(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		raw       string
+		want      map[string]any
+		repaired  int
+		malformed int
+	}{
+		{name: "strict", raw: `{"path":"a.go","limit":10}`, want: map[string]any{"path": "a.go", "limit": float64(10)}},
+		{name: "empty", raw: "  ", want: map[string]any{}},
+		{name: "trailing commas", raw: `{"paths":["a","b",],"recursive":true,}`, want: map[string]any{"paths": []any{"a", "b"}, "recursive": true}, repaired: 1},
+		{name: "single quotes", raw: `{'command': 'echo "hi"', 'cwd': 'it\'s'}`, want: map[string]any{"command": `echo "hi"`, "cwd": "it's"}, repaired: 1},
+		{name: "unquoted keys", raw: `{path: "a.go", max_lines: 5}`, want: map[string]any{"path": "a.go", "max_lines": float64(5)}, repaired: 1},
+		{name: "python literals", raw: `{"force": True, "dry_run": False, "owner": None}`, want: map[string]any{"force": true, "dry_run": false, "owner": nil}, repaired: 1},
+		{name: "raw newline in string", raw: "{\"content\": \"line1\nline2\"}", want: map[string]any{"content": "line1\nline2"}, repaired: 1},
+		{name: "code fence and prose", raw: "```json\n{\"path\": \"a.go\",}\n```", want: map[string]any{"path": "a.go"}, repaired: 1},
+		{name: "truncated", raw: `{"content": "partial`, want: map[string]any{}, malformed: 1},
+		{name: "unclosed object", raw: `{"path": "a.go"`, want: map[string]any{}, malformed: 1},
+		{name: "not an object", raw: `["a.go"]`, want: map[string]any{}, malformed: 1},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stats toolArgsRepairStats
+			got := stats.parse(tc.raw)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("args=%#v, want %#v", got, tc.want)
+			}
+			if stats.Repaired != tc.repaired || stats.Malformed != tc.malformed {
+				t.Fatalf("stats=%+v, want repaired=%d malformed=%d", stats, tc.repaired, tc.malformed)
+			}
+		})
+	}
+}
+
+func TestToolArgsRepairStats_ApplyDiag(t *testing.T) {
+	t.Parallel()
+
+	diag := map[string]any{}
+	(&toolArgsRepairStats{}).applyDiag(diag)
+	if len(diag) != 0 {
+		t.Fatalf("clean turn should add nothing: %+v", diag)
+	}
+	(&toolArgsRepairStats{Repaired: 2, Malformed: 1}).applyDiag(diag)
+	if diag["repaired_tool_args"] != 2 || diag["malformed_tool_args"] != 1 {
+		t.Fatalf("unexpected diag: %+v", diag)
+	}
+}
+
+func TestParseReActToolCallBody_RepairsNearJSON(t *testing.T) {
+	t.Parallel()
+
+	call, ok := parseReActToolCallBody(`{'name': 'file.read', 'arguments': {path: 'README.md',},}`)
+	if !ok {
+		t.Fatalf("expected near-JSON tool call to be repaired")
+	}
+	if call.Name != "file.read" || call.Args["path"] != "README.md" {
+		t.Fatalf("unexpected call: %+v", call)
+	}
+}
@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	toolArgsSchemaResource = "tool_input_schema.json"
+	// toolArgsSchemaCacheLimit bounds the compiled schema cache; tool schemas beyond it are compiled per call.
+	toolArgsSchemaCacheLimit = 512
+	// toolArgsMaxViolations caps how many violations one argument error lists.
+	toolArgsMaxViolations = 5
+)
+
+type toolArgsSchemaEntry struct {
+	schema *jsonschema.Schema
+	// err is set when the schema itself does not compile; such tools are dispatched without validation.
+	err error
+}
+
+var toolArgsMessagePrinter = message.NewPrinter(language.English)
+
+// toolArgsSchemas caches compiled tool input schemas by their JSON text.
+var toolArgsSchemas = struct {
+	mu      sync.Mutex
+	entries map[string]toolArgsSchemaEntry
+}{entries: make(map[string]toolArgsSchemaEntry)}
+
+// toolArgsSchemaLoader refuses external $ref targets, so validating arguments never reads files or the network.
+type toolArgsSchemaLoader struct{}
+
+func (toolArgsSchemaLoader) Load(url string) (any, error) {
+	return nil, fmt.Errorf("external schema reference %q is not supported", url)
+}
+
+func compileToolArgsSchema(raw json.RawMessage) (*jsonschema.Schema, error) {
+	key := string(bytes.TrimSpace(raw))
+	toolArgsSchemas.mu.Lock()
+	entry, ok := toolArgsSchemas.entries[key]
+	toolArgsSchemas.mu.Unlock()
+	if ok {
+		return entry.schema, entry.err
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(key))
+	if err == nil {
+		compiler := jsonschema.NewCompiler()
+		compiler.DefaultDraft(jsonschema.Draft2020)
+		compiler.UseLoader(toolArgsSchemaLoader{})
+		if err = compiler.AddResource(toolArgsSchemaResource, doc); err == nil {
+			entry.schema, err = compiler.Compile(toolArgsSchemaResource)
+		}
+	}
+	entry.err = err
+
+	toolArgsSchemas.mu.Lock()
+	if len(toolArgsSchemas.entries) < toolArgsSchemaCacheLimit {
+		toolArgsSchemas.entries[key] = entry
+	}
+	toolArgsSchemas.mu.Unlock()
+	return entry.schema, entry.err
+}
+
+// validateToolArgs checks call arguments against the tool's JSON schema before dispatch.
+//
+// The error lists each violation with its argument path, so the model can repair the call in one shot.
+// Tools without a schema, or with one that does not compile, are not validated.
+func validateToolArgs(def ToolDef, args map[string]any) error {
+	if len(bytes.TrimSpace(def.InputSchema)) == 0 {
+		return nil
+	}
+	schema, err := compileToolArgsSchema(def.InputSchema)
+	if err != nil || schema == nil {
+		return nil
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	// Round-trip through JSON so the validator sees the same value types the provider sent.
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	err = schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return errors.New("invalid arguments: " + formatToolArgsViolations(verr))
+}
+
+// formatToolArgsViolations flattens a validation error tree into its leaf violations, e.g.
+// "missing property 'file_path'; /timeout_ms: got string, want integer".
+func formatToolArgsViolations(verr *jsonschema.ValidationError) string {
+	var leaves []string
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, cause := range e.Causes {
+				walk(cause)
+			}
+			return
+		}
+		msg := e.ErrorKind.LocalizedString(toolArgsMessagePrinter)
+		if len(e.InstanceLocation) > 0 {
+			msg = "/" + strings.Join(e.InstanceLocation, "/") + ": " + msg
+		}
+		leaves = append(leaves, msg)
+	}
+	walk(verr)
+	if len(leaves) == 0 {
+		return verr.Error()
+	}
+	more := len(leaves) - toolArgsMaxViolations
+	if more > 0 {
+		leaves = append(leaves[:toolArgsMaxViolations], fmt.Sprintf("and %d more", more))
+	}
+	return strings.Join(leaves, "; ")
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// toolCallLimitSummary is the tool result summary for calls beyond RunOptions.ToolCallLimits.
+const toolCallLimitSummary = "tool_call_limit"
+
+// normalizeToolCallLimits drops blank tool names and non-positive limits.
+func normalizeToolCallLimits(limits map[string]int) map[string]int {
+	if len(limits) == 0 {
+		return nil
+	}
+	out := make(map[string]int, len(limits))
+	for name, limit := range limits {
+		name = strings.TrimSpace(name)
+		if name == "" || limit <= 0 {
+			continue
+		}
+		out[name] = limit
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// admitToolCall counts one dispatched call of toolName against limits.
+//
+// It returns ok=false, without counting, once the tool already used its limit in this run.
+func (s *runtimeState) admitToolCall(toolName string, limits map[string]int) (limit int, count int, ok bool) {
+	toolName = strings.TrimSpace(toolName)
+	limit = limits[toolName]
+	if s == nil || limit <= 0 {
+		return 0, 0, true
+	}
+	if s.ToolCallCounts == nil {
+		s.ToolCallCounts = make(map[string]int)
+	}
+	count = s.ToolCallCounts[toolName]
+	if count >= limit {
+		return limit, count, false
+	}
+	s.ToolCallCounts[toolName] = count + 1
+	return limit, count + 1, true
+}
+
+func toolCallLimitResult(call ToolCall, limit int) ToolResult {
+	return ToolResult{
+		ToolID:   strings.TrimSpace(call.ID),
+		ToolName: strings.TrimSpace(call.Name),
+		Status:   toolResultStatusAborted,
+		Summary:  toolCallLimitSummary,
+		Details:  fmt.Sprintf("%s already used its limit of %d calls in this run. Further calls are rejected.", strings.TrimSpace(call.Name), limit),
+		Data:     map[string]any{"limit": limit},
+	}
+}
+
+// buildToolCallLimitOverlay tells the model which tools are exhausted for the rest of the run.
+func buildToolCallLimitOverlay(limited map[string]int) string {
+	if len(limited) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(limited))
+	for name := range limited {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s (%d)", name, limited[name]))
+	}
+	return "[TOOL LIMIT] These tools reached their per-run call limit and will reject every further call: " + strings.Join(parts, ", ") + ".\nDo NOT call them again. Finalize with task_complete using the evidence you already have, or switch to a different approach."
+}
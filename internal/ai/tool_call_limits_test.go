@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeToolCallLimits(t *testing.T) {
+	t.Parallel()
+
+	got := normalizeToolCallLimits(map[string]int{" terminal.exec ": 3, "web.search": 0, "": 2, "file.read": -1})
+	if len(got) != 1 || got["terminal.exec"] != 3 {
+		t.Fatalf("limits=%v", got)
+	}
+	if got := normalizeToolCallLimits(map[string]int{"web.search": 0}); got != nil {
+		t.Fatalf("limits=%v, want nil", got)
+	}
+}
+
+func TestRuntimeState_AdmitToolCall(t *testing.T) {
+	t.Parallel()
+
+	state := newRuntimeState("objective")
+	limits := map[string]int{"terminal.exec": 2}
+	for i := 1; i <= 2; i++ {
+		limit, count, ok := state.admitToolCall("terminal.exec", limits)
+		if !ok || limit != 2 || count != i {
+			t.Fatalf("call %d: limit=%d count=%d ok=%v", i, limit, count, ok)
+		}
+	}
+	limit, count, ok := state.admitToolCall("terminal.exec", limits)
+	if ok || limit != 2 || count != 2 {
+		t.Fatalf("call 3: limit=%d count=%d ok=%v", limit, count, ok)
+	}
+	if state.ToolCallCounts["terminal.exec"] != 2 {
+		t.Fatalf("rejected call must not be counted: %v", state.ToolCallCounts)
+	}
+	for i := 0; i < 5; i++ {
+		if _, _, ok := state.admitToolCall("file.read", limits); !ok {
+			t.Fatalf("unlimited tool rejected")
+		}
+	}
+	if _, ok := state.ToolCallCounts["file.read"]; ok {
+		t.Fatalf("unlimited tool must not be counted: %v", state.ToolCallCounts)
+	}
+}
+
+func TestToolCallLimitResultAndOverlay(t *testing.T) {
+	t.Parallel()
+
+	res := toolCallLimitResult(ToolCall{ID: "call_1", Name: "terminal.exec"}, 2)
+	if res.Status != toolResultStatusAborted || res.Summary != toolCallLimitSummary || res.ToolID != "call_1" {
+		t.Fatalf("result=%+v", res)
+	}
+	if buildToolCallLimitOverlay(nil) != "" {
+		t.Fatalf("empty overlay expected")
+	}
+	overlay := buildToolCallLimitOverlay(map[string]int{"web.search": 1, "terminal.exec": 2})
+	if !strings.Contains(overlay, "terminal.exec (2), web.search (1)") || !strings.Contains(overlay, "task_complete") {
+		t.Fatalf("overlay=%q", overlay)
+	}
+}
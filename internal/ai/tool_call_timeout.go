@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// toolCallTimeoutSummary is the tool result summary for calls canceled by their per-call timeout.
+	toolCallTimeoutSummary = "tool_timeout"
+	// toolCallTimeoutGrace is added to a tool's own timeout_ms so the tool reports its timeout first.
+	toolCallTimeoutGrace = 5 * time.Second
+	// toolCallTimeoutWaitAfterCancel bounds how long Dispatch waits for a timed-out handler to unwind,
+	// long enough for terminal.exec to kill its process group.
+	toolCallTimeoutWaitAfterCancel = terminalExecWaitAfterKillTimeout + time.Second
+)
+
+// errToolCallTimeout is the cancel cause of a tool call context whose per-call timeout elapsed.
+var errToolCallTimeout = errors.New("tool call timed out")
+
+// toolCallTimeout describes one tool call canceled by its per-call timeout.
+type toolCallTimeout struct {
+	ToolID   string
+	ToolName string
+	Timeout  time.Duration
+	Elapsed  time.Duration
+}
+
+// toolCallTimeout returns the per-call timeout for call.
+//
+// terminal.exec gets its effective exec timeout plus a grace period; other tools honor a timeout_ms
+// argument the same way and otherwise fall back to the configured default.
+func (r *run) toolCallTimeout(call ToolCall) time.Duration {
+	requestedMS := readInt64Field(call.Args, "timeout_ms", "timeoutMs")
+	if strings.TrimSpace(call.Name) == "terminal.exec" {
+		requestedMS = resolveTerminalExecTimeoutDecision(r.cfg, requestedMS).EffectiveMS
+	}
+	if requestedMS > 0 {
+		return time.Duration(requestedMS)*time.Millisecond + toolCallTimeoutGrace
+	}
+	return time.Duration(r.cfg.EffectiveToolCallTimeoutMS()) * time.Millisecond
+}
+
+type toolCallDeadlineKey struct{}
+
+// toolCallDeadline cancels a tool call context with errToolCallTimeout once its budget is spent.
+//
+// The budget only runs while the deadline is not paused, so time spent waiting for the user
+// (tool approval) does not count against the tool.
+type toolCallDeadline struct {
+	mu        sync.Mutex
+	cancel    context.CancelCauseFunc
+	timer     *time.Timer
+	remaining time.Duration
+	armedAt   time.Time
+	paused    int
+	done      bool
+}
+
+func withToolCallDeadline(parent context.Context, timeout time.Duration) (context.Context, *toolCallDeadline) {
+	ctx, cancel := context.WithCancelCause(parent)
+	d := &toolCallDeadline{cancel: cancel, remaining: timeout}
+	d.mu.Lock()
+	d.armLocked()
+	d.mu.Unlock()
+	return context.WithValue(ctx, toolCallDeadlineKey{}, d), d
+}
+
+func (d *toolCallDeadline) armLocked() {
+	d.armedAt = time.Now()
+	d.timer = time.AfterFunc(d.remaining, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.done || d.paused > 0 {
+			return
+		}
+		d.done = true
+		d.cancel(errToolCallTimeout)
+	})
+}
+
+func (d *toolCallDeadline) pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done {
+		return
+	}
+	d.paused++
+	if d.paused > 1 {
+		return
+	}
+	if d.timer.Stop() {
+		d.remaining -= time.Since(d.armedAt)
+	} else {
+		// The timer fired while we held the lock; let it fire again on resume.
+		d.remaining = 0
+	}
+}
+
+func (d *toolCallDeadline) resume() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done || d.paused == 0 {
+		return
+	}
+	d.paused--
+	if d.paused == 0 {
+		d.armLocked()
+	}
+}
+
+// stop releases the deadline once the call finished.
+func (d *toolCallDeadline) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.done = true
+	d.timer.Stop()
+	d.cancel(nil)
+}
+
+// pauseToolCallTimeout stops the per-call timeout of ctx until the returned func is called.
+// It is a no-op outside a scheduler-dispatched tool call.
+func pauseToolCallTimeout(ctx context.Context) func() {
+	d, _ := ctx.Value(toolCallDeadlineKey{}).(*toolCallDeadline)
+	if d == nil {
+		return func() {}
+	}
+	d.pause()
+	return d.resume
+}
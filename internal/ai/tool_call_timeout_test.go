@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+// funcToolHandler runs execute for every call.
+type funcToolHandler struct {
+	execute func(ctx context.Context, call ToolCall) (ToolResult, error)
+}
+
+func (h funcToolHandler) Validate(context.Context, ToolCall) error { return nil }
+
+func (h funcToolHandler) HandlePartial(context.Context, PartialToolCall) error { return nil }
+
+func (h funcToolHandler) Execute(ctx context.Context, call ToolCall) (ToolResult, error) {
+	return h.execute(ctx, call)
+}
+
+func newTimeoutScheduler(t *testing.T, handler ToolHandler, timeout time.Duration, onTimeout func(toolCallTimeout)) *CoreToolScheduler {
+	t.Helper()
+	reg := NewInMemoryToolRegistry()
+	if err := reg.Register(ToolDef{Name: "terminal.exec"}, handler); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	scheduler, err := NewCoreToolScheduler(reg, nil)
+	if err != nil {
+		t.Fatalf("NewCoreToolScheduler: %v", err)
+	}
+	scheduler.enableToolCallTimeouts(func(ToolCall) time.Duration { return timeout }, onTimeout)
+	return scheduler
+}
+
+func TestCoreToolScheduler_ToolCallTimeoutAbortsHungCall(t *testing.T) {
+	t.Parallel()
+
+	handler := funcToolHandler{execute: func(ctx context.Context, call ToolCall) (ToolResult, error) {
+		if call.ID == "call_fast" {
+			return ToolResult{Summary: "fast"}, nil
+		}
+		<-ctx.Done()
+		return ToolResult{}, ctx.Err()
+	}}
+	var timeouts []toolCallTimeout
+	scheduler := newTimeoutScheduler(t, handler, 50*time.Millisecond, func(timeout toolCallTimeout) {
+		timeouts = append(timeouts, timeout)
+	})
+
+	results := scheduler.Dispatch(context.Background(), "act", []ToolCall{
+		{ID: "call_hung", Name: "terminal.exec", Args: map[string]any{"command": "sleep 600"}},
+		{ID: "call_fast", Name: "terminal.exec", Args: map[string]any{"command": "ls"}},
+	})
+	if got := results[0]; got.Status != toolResultStatusAborted || got.Summary != toolCallTimeoutSummary || got.ToolID != "call_hung" {
+		t.Fatalf("hung result=%+v, want aborted %s", got, toolCallTimeoutSummary)
+	}
+	if got := results[1]; got.Status != toolResultStatusSuccess {
+		t.Fatalf("fast result=%+v, want success", got)
+	}
+	if len(timeouts) != 1 || timeouts[0].ToolName != "terminal.exec" || timeouts[0].Timeout != 50*time.Millisecond || timeouts[0].Elapsed < 50*time.Millisecond {
+		t.Fatalf("timeouts=%+v", timeouts)
+	}
+}
+
+func TestCoreToolScheduler_ToolCallTimeoutPausedWhileWaitingForApproval(t *testing.T) {
+	t.Parallel()
+
+	handler := funcToolHandler{execute: func(ctx context.Context, call ToolCall) (ToolResult, error) {
+		resume := pauseToolCallTimeout(ctx)
+		time.Sleep(150 * time.Millisecond)
+		resume()
+		if err := ctx.Err(); err != nil {
+			return ToolResult{}, err
+		}
+		return ToolResult{Summary: "approved"}, nil
+	}}
+	scheduler := newTimeoutScheduler(t, handler, 50*time.Millisecond, nil)
+
+	results := scheduler.Dispatch(context.Background(), "act", []ToolCall{{ID: "call_1", Name: "terminal.exec", Args: map[string]any{"command": "make"}}})
+	if got := results[0]; got.Status != toolResultStatusSuccess {
+		t.Fatalf("result=%+v, want success after a paused wait", got)
+	}
+}
+
+func TestCoreToolScheduler_ToolCallTimeoutKillsTerminalProcess(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("process-group kill is only asserted on Unix in this test")
+	}
+
+	workspace := t.TempDir()
+	markerPath := filepath.Join(workspace, "survived.txt")
+	handler := funcToolHandler{execute: func(ctx context.Context, call ToolCall) (ToolResult, error) {
+		_, err := defaultTerminalExecRunner(ctx, terminalExecInvocation{
+			Shell:         "/bin/sh",
+			Command:       "(sleep 0.3; printf child > " + shellSingleQuote(markerPath) + ") & wait",
+			WorkingDirAbs: workspace,
+			Env:           os.Environ(),
+		})
+		return ToolResult{}, err
+	}}
+	scheduler := newTimeoutScheduler(t, handler, 50*time.Millisecond, nil)
+
+	results := scheduler.Dispatch(context.Background(), "act", []ToolCall{{ID: "call_1", Name: "terminal.exec", Args: map[string]any{"command": "slow"}}})
+	if got := results[0]; got.Summary != toolCallTimeoutSummary {
+		t.Fatalf("result=%+v, want %s", got, toolCallTimeoutSummary)
+	}
+	time.Sleep(400 * time.Millisecond)
+	if _, err := os.Stat(markerPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the timed-out process tree to be killed, stat err=%v", err)
+	}
+}
+
+func TestRunToolCallTimeout(t *testing.T) {
+	t.Parallel()
+
+	r := &run{cfg: &config.AIConfig{ToolCallTimeoutMS: intPtr(30_000)}}
+	cases := []struct {
+		call ToolCall
+		want time.Duration
+	}{
+		{ToolCall{Name: "web.search"}, 30 * time.Second},
+		{ToolCall{Name: "web.search", Args: map[string]any{"timeout_ms": float64(1_000)}}, time.Second + toolCallTimeoutGrace},
+		{ToolCall{Name: "terminal.exec"}, 2*time.Minute + toolCallTimeoutGrace},
+		{ToolCall{Name: "terminal.exec", Args: map[string]any{"timeout_ms": float64(9_000_000)}}, 10*time.Minute + toolCallTimeoutGrace},
+	}
+	for _, tc := range cases {
+		if got := r.toolCallTimeout(tc.call); got != tc.want {
+			t.Fatalf("toolCallTimeout(%s %v)=%s, want %s", tc.call.Name, tc.call.Args, got, tc.want)
+		}
+	}
+}
@@ -3,21 +3,66 @@ package ai
 import (
 	"strings"
 
+	aitools "github.com/floegence/redeven/internal/ai/tools"
 	"github.com/floegence/redeven/internal/config"
 )
 
+// planModeBlockedSummary is the tool result summary for mutating calls rejected in plan mode.
+const planModeBlockedSummary = "plan_mode_blocked"
+
 func newModeToolFilter(cfg *config.AIConfig, profile RunProtocolProfile, allowUserInteraction bool) ModeToolFilter {
 	_ = cfg
-	return protocolModeToolFilter{
-		base:                 DefaultModeToolFilter{},
-		profile:              normalizeRunProtocolProfile(profile),
-		allowUserInteraction: allowUserInteraction,
+	return planModeToolFilter{
+		base: protocolModeToolFilter{
+			base:                 DefaultModeToolFilter{},
+			profile:              normalizeRunProtocolProfile(profile),
+			allowUserInteraction: allowUserInteraction,
+		},
+	}
+}
+
+// planModeToolFilter hides every mutating tool in plan mode, including built-ins whose
+// registered definition is mutating even when a caller-supplied ToolDef is not.
+// terminal.exec stays visible; its mutating commands are rejected per call by the scheduler.
+type planModeToolFilter struct {
+	base ModeToolFilter
+}
+
+func (f planModeToolFilter) FilterToolsForMode(mode string, all []ToolDef) []ToolDef {
+	base := f.base
+	if base == nil {
+		base = DefaultModeToolFilter{}
+	}
+	filtered := base.FilterToolsForMode(mode, all)
+	if !isPlanMode(mode) {
+		return filtered
+	}
+	out := make([]ToolDef, 0, len(filtered))
+	for _, tool := range filtered {
+		if tool.Mutating || aitools.IsMutating(tool.Name) {
+			continue
+		}
+		out = append(out, tool)
+	}
+	return out
+}
+
+// planModeBlocksInvocation reports whether a call must be rejected because it would mutate
+// the workspace in plan mode: a mutating tool, or a terminal.exec command that is not read-only.
+func planModeBlocksInvocation(mode string, def ToolDef, call ToolCall) bool {
+	if !isPlanMode(mode) {
+		return false
 	}
+	return def.Mutating || isMutatingInvocation(call.Name, call.Args)
 }
 
+// allowlistModeToolFilter keeps only allowlisted tools. With builtinOnly set, tools that are not
+// built-ins (external tools declared by the run) pass unfiltered. A nil allowlist keeps every tool;
+// an empty one keeps none.
 type allowlistModeToolFilter struct {
-	base      ModeToolFilter
-	allowlist map[string]struct{}
+	base        ModeToolFilter
+	allowlist   map[string]struct{}
+	builtinOnly bool
 }
 
 func (f allowlistModeToolFilter) FilterToolsForMode(mode string, all []ToolDef) []ToolDef {
@@ -26,7 +71,7 @@ func (f allowlistModeToolFilter) FilterToolsForMode(mode string, all []ToolDef)
 		base = DefaultModeToolFilter{}
 	}
 	filtered := base.FilterToolsForMode(mode, all)
-	if len(f.allowlist) == 0 {
+	if f.allowlist == nil {
 		return filtered
 	}
 	out := make([]ToolDef, 0, len(filtered))
@@ -35,6 +80,10 @@ func (f allowlistModeToolFilter) FilterToolsForMode(mode string, all []ToolDef)
 		if name == "" {
 			continue
 		}
+		if f.builtinOnly && strings.TrimSpace(tool.Source) != "builtin" {
+			out = append(out, tool)
+			continue
+		}
 		if _, ok := f.allowlist[name]; ok {
 			out = append(out, tool)
 		}
@@ -42,6 +91,57 @@ func (f allowlistModeToolFilter) FilterToolsForMode(mode string, all []ToolDef)
 	return out
 }
 
+// configToolAllowlistAlwaysAllowed lists the signal tools that config tool_allowlists cannot remove.
+var configToolAllowlistAlwaysAllowed = []string{"task_complete", "ask_user", "exit_plan_mode"}
+
+// resolveToolAllowlist merges the config tool_allowlists that apply to mode and the run's session
+// permissions with the run-level allowlist; the most restrictive combination wins.
+//
+// ok is false when neither restricts tools. builtinOnly reports that only the config restricts
+// tools, so external tools are left alone.
+func (r *run) resolveToolAllowlist(mode string) (allowlist map[string]struct{}, builtinOnly bool, ok bool) {
+	if r == nil {
+		return nil, false, false
+	}
+	preset := ""
+	if r.sessionMeta != nil {
+		preset = config.PermissionPresetForSet(config.PermissionSet{
+			Read:    r.sessionMeta.CanRead,
+			Write:   r.sessionMeta.CanWrite,
+			Execute: r.sessionMeta.CanExecute,
+		})
+	}
+	configured, hasConfigured := r.cfg.EffectiveToolAllowlist(mode, preset)
+	if !hasConfigured {
+		if len(r.toolAllowlist) == 0 {
+			return nil, false, false
+		}
+		allowlist = make(map[string]struct{}, len(r.toolAllowlist))
+		for name := range r.toolAllowlist {
+			allowlist[name] = struct{}{}
+		}
+		return allowlist, false, true
+	}
+	configSet := make(map[string]struct{}, len(configured)+len(configToolAllowlistAlwaysAllowed))
+	for _, name := range configured {
+		configSet[name] = struct{}{}
+	}
+	for _, name := range configToolAllowlistAlwaysAllowed {
+		configSet[name] = struct{}{}
+	}
+	if len(r.toolAllowlist) == 0 {
+		return configSet, true, true
+	}
+	allowlist = make(map[string]struct{}, len(r.toolAllowlist))
+	for name := range r.toolAllowlist {
+		_, isBuiltin := aitools.LookupDefinition(name)
+		if _, allowed := configSet[name]; allowed || !isBuiltin {
+			allowlist[name] = struct{}{}
+		}
+	}
+	return allowlist, false, true
+}
+
 type protocolModeToolFilter struct {
 	base                 ModeToolFilter
 	profile              RunProtocolProfile
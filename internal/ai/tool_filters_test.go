@@ -1,9 +1,12 @@
 package ai
 
 import (
+	"strings"
 	"testing"
 
+	aitools "github.com/floegence/redeven/internal/ai/tools"
 	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
 )
 
 func TestNewModeToolFilter_DefaultBlocksPlanMutatingTools(t *testing.T) {
@@ -62,3 +65,93 @@ func TestNewModeToolFilter_ExecutionPolicyDoesNotDisablePlanReadonly(t *testing.
 		t.Fatalf("act filtered len=%d, want 4", len(filteredAct))
 	}
 }
+
+func TestNewModeToolFilter_PlanHidesRegisteredMutatingBuiltins(t *testing.T) {
+	t.Parallel()
+
+	filter := newModeToolFilter(nil, defaultStructuredProtocolProfile(), true)
+	tools := []ToolDef{
+		{Name: "terminal.exec"},
+		{Name: "apply_patch"},
+		{Name: "file.write"},
+	}
+
+	filteredPlan := filter.FilterToolsForMode(config.AIModePlan, tools)
+	if len(filteredPlan) != 1 || filteredPlan[0].Name != "terminal.exec" {
+		t.Fatalf("unexpected plan tools=%+v", filteredPlan)
+	}
+	if filteredAct := filter.FilterToolsForMode(config.AIModeAct, tools); len(filteredAct) != 3 {
+		t.Fatalf("act filtered len=%d, want 3", len(filteredAct))
+	}
+}
+
+func TestBuiltInToolDefinitions_AreInToolRegistry(t *testing.T) {
+	t.Parallel()
+
+	// Config tool_allowlists are validated against the tools registry.
+	for _, def := range builtInToolDefinitions() {
+		if _, ok := aitools.LookupDefinition(def.Name); !ok {
+			t.Fatalf("built-in tool %q missing from the tools registry", def.Name)
+		}
+	}
+}
+
+func TestResolveToolAllowlist_MergesConfigAndRunAllowlists(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.AIConfig{ToolAllowlists: map[string][]string{
+		config.AIModeAct:                {"file.read", "terminal.exec", "web.search"},
+		config.PermissionPresetReadOnly: {"file.read", "web.search"},
+	}}
+	readOnly := &session.Meta{CanRead: true}
+	tools := []ToolDef{
+		{Name: "file.read", Source: "builtin"},
+		{Name: "terminal.exec", Source: "builtin"},
+		{Name: "apply_patch", Source: "builtin", Mutating: true},
+		{Name: "task_complete", Source: "builtin", Namespace: "builtin.signal"},
+		{Name: "ticket.lookup", Source: "external"},
+	}
+	names := func(filter ModeToolFilter) []string {
+		out := []string{}
+		for _, tool := range filter.FilterToolsForMode(config.AIModeAct, tools) {
+			out = append(out, tool.Name)
+		}
+		return out
+	}
+
+	// Config only: built-ins are restricted, signal and external tools stay.
+	r := &run{cfg: cfg, sessionMeta: readOnly}
+	allow, builtinOnly, ok := r.resolveToolAllowlist(config.AIModeAct)
+	if !ok || !builtinOnly {
+		t.Fatalf("ok=%v builtinOnly=%v, want a builtin-only allowlist", ok, builtinOnly)
+	}
+	if got := names(allowlistModeToolFilter{allowlist: allow, builtinOnly: builtinOnly}); strings.Join(got, ",") != "file.read,task_complete,ticket.lookup" {
+		t.Fatalf("config-only tools=%v", got)
+	}
+
+	// Run and config: the intersection wins for built-ins; listed external tools stay.
+	r = &run{cfg: cfg, sessionMeta: readOnly, toolAllowlist: map[string]struct{}{"terminal.exec": {}, "web.search": {}, "ticket.lookup": {}}}
+	allow, builtinOnly, ok = r.resolveToolAllowlist(config.AIModeAct)
+	if !ok || builtinOnly {
+		t.Fatalf("ok=%v builtinOnly=%v, want a merged allowlist", ok, builtinOnly)
+	}
+	if _, has := allow["terminal.exec"]; has || len(allow) != 2 {
+		t.Fatalf("merged allowlist=%v, want web.search and ticket.lookup", allow)
+	}
+	if got := names(allowlistModeToolFilter{allowlist: allow}); strings.Join(got, ",") != "ticket.lookup" {
+		t.Fatalf("merged tools=%v", got)
+	}
+
+	// A disjoint merge allows no tools rather than every tool.
+	r = &run{cfg: cfg, sessionMeta: readOnly, toolAllowlist: map[string]struct{}{"terminal.exec": {}}}
+	allow, _, _ = r.resolveToolAllowlist(config.AIModeAct)
+	if got := names(allowlistModeToolFilter{allowlist: allow}); len(got) != 0 {
+		t.Fatalf("disjoint tools=%v, want none", got)
+	}
+
+	// No config entry applies and no run allowlist.
+	r = &run{cfg: &config.AIConfig{}, sessionMeta: readOnly}
+	if _, _, ok := r.resolveToolAllowlist(config.AIModePlan); ok {
+		t.Fatalf("expected no allowlist")
+	}
+}
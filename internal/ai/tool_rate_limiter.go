@@ -0,0 +1,181 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	aitools "github.com/floegence/redeven/internal/ai/tools"
+)
+
+const toolRateLimitWindow = time.Minute
+
+// toolRateLimiter is a process-wide sliding-window limiter keyed by tool name.
+//
+// It is shared by every run (and subagent) of a Service so per-tool quotas hold across concurrent runs.
+type toolRateLimiter struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	slots map[string][]time.Time
+}
+
+func newToolRateLimiter() *toolRateLimiter {
+	return &toolRateLimiter{
+		now:   time.Now,
+		slots: make(map[string][]time.Time),
+	}
+}
+
+// reserve books a call slot for toolName.
+//
+// It returns the booked slot and the delay the caller must wait before executing. When the delay would
+// exceed maxWait the slot is not booked and ok=false; retryAfter then reports when the next slot frees up.
+func (l *toolRateLimiter) reserve(toolName string, limit int, maxWait time.Duration) (slot time.Time, wait time.Duration, retryAfter time.Duration, ok bool) {
+	if l == nil || limit <= 0 {
+		return time.Time{}, 0, 0, true
+	}
+	toolName = strings.TrimSpace(toolName)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	slots := l.slots[toolName]
+	cutoff := now.Add(-toolRateLimitWindow)
+	keep := 0
+	for keep < len(slots) && !slots[keep].After(cutoff) {
+		keep++
+	}
+	slots = slots[keep:]
+
+	at := now
+	if len(slots) >= limit {
+		at = slots[len(slots)-limit].Add(toolRateLimitWindow)
+	}
+	wait = at.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxWait {
+		l.slots[toolName] = slots
+		return time.Time{}, 0, wait, false
+	}
+	l.slots[toolName] = append(slots, at)
+	return at, wait, 0, true
+}
+
+// release returns a slot booked by reserve that was never used, e.g. because the call was canceled while waiting.
+func (l *toolRateLimiter) release(toolName string, slot time.Time) {
+	if l == nil || slot.IsZero() {
+		return
+	}
+	toolName = strings.TrimSpace(toolName)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots := l.slots[toolName]
+	for i := len(slots) - 1; i >= 0; i-- {
+		if slots[i].Equal(slot) {
+			l.slots[toolName] = append(slots[:i], slots[i+1:]...)
+			return
+		}
+	}
+}
+
+// toolRateLimitBinding connects a scheduler to the shared limiter and the run's config and events.
+type toolRateLimitBinding struct {
+	limiter *toolRateLimiter
+	// limitFor returns the per-minute budget for a tool (0 = unlimited).
+	limitFor func(toolName string) int
+	maxWait  time.Duration
+	// onThrottle is called for delayed (rejected=false) and rejected calls.
+	onThrottle func(call ToolCall, limit int, wait time.Duration, rejected bool)
+}
+
+// throttle applies the per-tool rate limit to call.
+//
+// It blocks for the reserved delay and returns a tool.rate_limited result when the call is rejected.
+func (b *toolRateLimitBinding) throttle(ctx context.Context, call ToolCall) (ToolResult, bool) {
+	if b == nil || b.limiter == nil || b.limitFor == nil {
+		return ToolResult{}, false
+	}
+	limit := b.limitFor(call.Name)
+	if limit <= 0 {
+		return ToolResult{}, false
+	}
+	slot, wait, retryAfter, ok := b.limiter.reserve(call.Name, limit, b.maxWait)
+	if !ok {
+		if b.onThrottle != nil {
+			b.onThrottle(call, limit, retryAfter, true)
+		}
+		return rateLimitedToolResult(call, limit, retryAfter), true
+	}
+	if wait <= 0 {
+		return ToolResult{}, false
+	}
+	if b.onThrottle != nil {
+		b.onThrottle(call, limit, wait, false)
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return ToolResult{}, false
+	case <-ctx.Done():
+		// The call never runs, so hand its slot back instead of shrinking the quota for everyone else.
+		b.limiter.release(call.Name, slot)
+		return ToolResult{ToolID: call.ID, ToolName: call.Name, Status: toolResultStatusAborted, Summary: "tool.aborted", Details: "tool execution canceled"}, true
+	}
+}
+
+func rateLimitedToolResult(call ToolCall, limit int, retryAfter time.Duration) ToolResult {
+	retrySec := int64((retryAfter + time.Second - 1) / time.Second)
+	msg := fmt.Sprintf("%s is rate limited to %d calls per minute; retry after about %ds or continue without it", call.Name, limit, retrySec)
+	return ToolResult{
+		ToolID:   call.ID,
+		ToolName: call.Name,
+		Status:   toolResultStatusError,
+		Summary:  "tool.rate_limited",
+		Details:  msg,
+		Error: &aitools.ToolError{
+			Code:      aitools.ErrorCodeRateLimited,
+			Message:   msg,
+			Retryable: true,
+			SuggestedFixes: []string{
+				"Reuse results you already have instead of repeating the call.",
+				"Batch or narrow the request to reduce call volume.",
+			},
+			Meta: map[string]any{
+				"calls_per_minute": limit,
+				"retry_after_ms":   retryAfter.Milliseconds(),
+			},
+		},
+	}
+}
+
+// newToolRateLimitBinding binds the shared limiter to this run's config and emits tool.rate_limited events.
+func (r *run) newToolRateLimitBinding() *toolRateLimitBinding {
+	if r == nil || r.toolRateLimiter == nil {
+		return nil
+	}
+	cfg := r.cfg
+	return &toolRateLimitBinding{
+		limiter:  r.toolRateLimiter,
+		limitFor: cfg.EffectiveToolCallsPerMinute,
+		maxWait:  time.Duration(cfg.EffectiveToolRateLimitMaxWaitMS()) * time.Millisecond,
+		onThrottle: func(call ToolCall, limit int, wait time.Duration, rejected bool) {
+			action := "delayed"
+			if rejected {
+				action = "rejected"
+			}
+			r.persistRunEvent("tool.rate_limited", RealtimeStreamKindLifecycle, map[string]any{
+				"tool_id":          strings.TrimSpace(call.ID),
+				"tool_name":        strings.TrimSpace(call.Name),
+				"calls_per_minute": limit,
+				"action":           action,
+				"wait_ms":          wait.Milliseconds(),
+			})
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+type countingToolHandler struct {
+	calls atomic.Int32
+}
+
+func (h *countingToolHandler) Validate(context.Context, ToolCall) error { return nil }
+
+func (h *countingToolHandler) Execute(context.Context, ToolCall) (ToolResult, error) {
+	h.calls.Add(1)
+	return ToolResult{Summary: "ok"}, nil
+}
+
+func (h *countingToolHandler) HandlePartial(context.Context, PartialToolCall) error { return nil }
+
+func TestToolRateLimiter_SlidingWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	l := newToolRateLimiter()
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, wait, _, ok := l.reserve("web.search", 2, 0); !ok || wait != 0 {
+			t.Fatalf("reserve #%d = wait %v ok %v, want immediate", i, wait, ok)
+		}
+	}
+	if _, _, retryAfter, ok := l.reserve("web.search", 2, 0); ok || retryAfter != time.Minute {
+		t.Fatalf("expected rejection with retryAfter=1m, got ok=%v retryAfter=%v", ok, retryAfter)
+	}
+	if _, wait, _, ok := l.reserve("web.search", 2, 2*time.Minute); !ok || wait != time.Minute {
+		t.Fatalf("expected delayed reservation of 1m, got ok=%v wait=%v", ok, wait)
+	}
+	if _, wait, _, ok := l.reserve("fetch_url", 2, 0); !ok || wait != 0 {
+		t.Fatalf("limits must be tracked per tool, got ok=%v wait=%v", ok, wait)
+	}
+
+	now = now.Add(61 * time.Second)
+	if _, wait, _, ok := l.reserve("web.search", 2, 0); !ok || wait != 0 {
+		t.Fatalf("expected window to slide, got ok=%v wait=%v", ok, wait)
+	}
+}
+
+func TestToolRateLimitBinding_CanceledWaitReleasesSlot(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	l := newToolRateLimiter()
+	l.now = func() time.Time { return now }
+	binding := &toolRateLimitBinding{
+		limiter:  l,
+		limitFor: func(string) int { return 1 },
+		maxWait:  5 * time.Minute,
+	}
+
+	if _, wait, _, ok := l.reserve("web.search", 1, 0); !ok || wait != 0 {
+		t.Fatalf("first reserve = wait %v ok %v, want immediate", wait, ok)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, handled := binding.throttle(ctx, ToolCall{ID: "call_1", Name: "web.search"})
+	if !handled || res.Status != toolResultStatusAborted {
+		t.Fatalf("throttle canceled = %+v handled=%v, want aborted", res, handled)
+	}
+
+	// The canceled call's slot (now+1m) must be free again, so the next call waits 1m rather than 2m.
+	if _, wait, _, ok := l.reserve("web.search", 1, 5*time.Minute); !ok || wait != time.Minute {
+		t.Fatalf("reserve after cancel = wait %v ok %v, want 1m", wait, ok)
+	}
+}
+
+func TestCoreToolScheduler_RejectsOverLimitCalls(t *testing.T) {
+	t.Parallel()
+
+	reg := NewInMemoryToolRegistry()
+	handler := &countingToolHandler{}
+	if err := reg.Register(ToolDef{Name: "web.search", ParallelSafe: true}, handler); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := reg.Register(ToolDef{Name: "file.read", ParallelSafe: true}, &countingToolHandler{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	scheduler, err := NewCoreToolScheduler(reg, nil)
+	if err != nil {
+		t.Fatalf("NewCoreToolScheduler: %v", err)
+	}
+	zero := 0
+	cfg := &config.AIConfig{ToolRateLimit: &config.AIToolRateLimitPolicy{
+		CallsPerMinute: map[string]int{"web.search": 1},
+		MaxWaitMS:      &zero,
+	}}
+	var rejected atomic.Int32
+	scheduler.rateLimit = &toolRateLimitBinding{
+		limiter:  newToolRateLimiter(),
+		limitFor: cfg.EffectiveToolCallsPerMinute,
+		maxWait:  time.Duration(cfg.EffectiveToolRateLimitMaxWaitMS()) * time.Millisecond,
+		onThrottle: func(_ ToolCall, _ int, _ time.Duration, isRejected bool) {
+			if isRejected {
+				rejected.Add(1)
+			}
+		},
+	}
+
+	results := scheduler.Dispatch(context.Background(), config.AIModeAct, []ToolCall{
+		{ID: "c1", Name: "web.search"},
+		{ID: "c2", Name: "web.search"},
+		{ID: "c3", Name: "file.read"},
+	})
+	if len(results) != 3 {
+		t.Fatalf("results len=%d, want 3", len(results))
+	}
+	limited := 0
+	for _, res := range results {
+		if res.Summary != "tool.rate_limited" {
+			continue
+		}
+		limited++
+		if res.ToolName != "web.search" || res.Error == nil || !res.Error.Retryable {
+			t.Fatalf("unexpected rate-limited result: %+v", res)
+		}
+	}
+	if limited != 1 || rejected.Load() != 1 {
+		t.Fatalf("limited=%d rejected events=%d, want 1/1", limited, rejected.Load())
+	}
+	if got := handler.calls.Load(); got != 1 {
+		t.Fatalf("web.search executions=%d, want 1", got)
+	}
+	if results[2].Status != toolResultStatusSuccess {
+		t.Fatalf("unlimited tool should run normally: %+v", results[2])
+	}
+}
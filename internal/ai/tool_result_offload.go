@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+)
+
+const (
+	// readToolOutputDefaultBytes and readToolOutputMaxBytes bound one read_tool_output page.
+	readToolOutputDefaultBytes = 8_192
+	readToolOutputMaxBytes     = 16_384
+)
+
+func newToolResultContentRef() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "toolout_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// canOffloadToolResults reports whether the run can store large tool results out of band.
+// Summary-only persistence never stores raw tool output.
+func (r *run) canOffloadToolResults() bool {
+	return r != nil && r.threadsDB != nil && !r.summaryOnlyPersist &&
+		strings.TrimSpace(r.endpointID) != "" && strings.TrimSpace(r.threadID) != "" && strings.TrimSpace(r.id) != ""
+}
+
+// inlineToolResultPayload returns the part of a tool payload that goes into the conversation.
+//
+// The preview follows normalizeTruncatedToolPayload. When the full payload is larger than the
+// configured offload threshold, or the preview had to truncate it, the full payload is stored
+// under a content ref that the model can page through with read_tool_output.
+func (r *run) inlineToolResultPayload(toolID string, toolName string, payload any) (any, bool, string) {
+	if toolName == "read_tool_output" || payload == nil || !r.canOffloadToolResults() {
+		data, truncated := normalizeTruncatedToolPayload(toolName, payload)
+		return data, truncated, ""
+	}
+	// Marshal first: the terminal.exec preview trims the payload map in place.
+	full, err := json.Marshal(payload)
+	data, truncated := normalizeTruncatedToolPayload(toolName, payload)
+	if err != nil || (len(full) <= r.cfg.EffectiveToolResultOffloadBytes() && !truncated) {
+		return data, truncated, ""
+	}
+	contentRef, err := newToolResultContentRef()
+	if err != nil {
+		return data, truncated, ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.persistTimeout())
+	defer cancel()
+	if err := r.threadsDB.PutToolResultContent(ctx, threadstore.ToolResultContent{
+		ContentRef: contentRef,
+		EndpointID: r.endpointID,
+		ThreadID:   r.threadID,
+		RunID:      r.id,
+		ToolID:     toolID,
+		ToolName:   toolName,
+		Content:    string(full),
+	}); err != nil {
+		r.debug("ai.run.tool_result.offload_failed", "tool_name", toolName, "error", sanitizeLogText(err.Error(), 200))
+		return data, truncated, ""
+	}
+	inlined, _ := json.Marshal(data)
+	r.persistRunEvent("tool.result.offloaded", RealtimeStreamKindLifecycle, map[string]any{
+		"tool_id":        toolID,
+		"tool_name":      toolName,
+		"content_ref":    contentRef,
+		"original_bytes": len(full),
+		"inlined_bytes":  len(inlined),
+	})
+	return data, true, contentRef
+}
+
+// toolReadToolOutput returns one page of an offloaded tool result of this thread.
+//
+// offset and limit are byte positions in the stored payload, moved to UTF-8 boundaries.
+func (r *run) toolReadToolOutput(ctx context.Context, contentRef string, offset int, limit int) (map[string]any, error) {
+	if r == nil || r.threadsDB == nil {
+		return nil, errors.New("tool output store unavailable")
+	}
+	contentRef = strings.TrimSpace(contentRef)
+	if contentRef == "" {
+		return nil, errors.New("missing content_ref")
+	}
+	if offset < 0 {
+		return nil, errors.New("invalid offset")
+	}
+	if limit <= 0 {
+		limit = readToolOutputDefaultBytes
+	}
+	if limit > readToolOutputMaxBytes {
+		limit = readToolOutputMaxBytes
+	}
+	readCtx, cancel := context.WithTimeout(ctx, r.persistTimeout())
+	defer cancel()
+	rec, err := r.threadsDB.GetToolResultContent(readCtx, r.endpointID, r.threadID, contentRef)
+	if err != nil {
+		return nil, errors.New("unknown content_ref")
+	}
+
+	content := rec.Content
+	total := len(content)
+	start := offset
+	if start > total {
+		start = total
+	}
+	for start < total && !utf8.RuneStart(content[start]) {
+		start++
+	}
+	end := start + limit
+	if end >= total {
+		end = total
+	} else {
+		for end > start && !utf8.RuneStart(content[end]) {
+			end--
+		}
+	}
+	return map[string]any{
+		"content_ref": rec.ContentRef,
+		"tool_id":     rec.ToolID,
+		"tool_name":   rec.ToolName,
+		"total_bytes": total,
+		"offset":      start,
+		"next_offset": end,
+		"has_more":    end < total,
+		"content":     content[start:end],
+	}, nil
+}
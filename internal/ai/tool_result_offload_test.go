@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/floegence/redeven/internal/ai/threadstore"
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestToolResultOffload_LargeResultIsPagedThroughReadToolOutput(t *testing.T) {
+	t.Parallel()
+
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	cfg := &config.AIConfig{ToolResultOffloadBytes: intPtr(2_048)}
+	r := &run{cfg: cfg, threadsDB: db, endpointID: "env_1", threadID: "th_1", id: "run_1"}
+	stdout := strings.Repeat("héllo build output\n", 1_000)
+	payload := map[string]any{"stdout": stdout, "stderr": "", "exit_code": 0}
+	full, _ := json.Marshal(payload)
+
+	data, truncated, contentRef := r.inlineToolResultPayload("call_1", "terminal.exec", payload)
+	if contentRef == "" || !truncated {
+		t.Fatalf("contentRef=%q truncated=%v, want an offloaded result", contentRef, truncated)
+	}
+	if preview, _ := data.(map[string]any)["stdout"].(string); len([]rune(preview)) != 4000 {
+		t.Fatalf("inline stdout runes=%d, want the 4000-rune preview", len([]rune(preview)))
+	}
+
+	var got strings.Builder
+	offset := 0
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatalf("read_tool_output did not finish")
+		}
+		page, err := r.toolReadToolOutput(context.Background(), contentRef, offset, readToolOutputMaxBytes)
+		if err != nil {
+			t.Fatalf("toolReadToolOutput: %v", err)
+		}
+		got.WriteString(page["content"].(string))
+		offset = page["next_offset"].(int)
+		if !page["has_more"].(bool) {
+			break
+		}
+	}
+	if got.String() != string(full) {
+		t.Fatalf("paged content differs from the full result (%d vs %d bytes)", got.Len(), len(full))
+	}
+
+	events, err := db.ListRunEvents(context.Background(), "env_1", "run_1", 10)
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].EventType != "tool.result.offloaded" || !strings.Contains(events[0].PayloadJSON, `"original_bytes":`+strconv.Itoa(len(full))) {
+		t.Fatalf("events=%+v, want one tool.result.offloaded event", events)
+	}
+
+	other := &run{cfg: cfg, threadsDB: db, endpointID: "env_1", threadID: "th_2", id: "run_2"}
+	if _, err := other.toolReadToolOutput(context.Background(), contentRef, 0, 0); err == nil {
+		t.Fatalf("expected a content_ref from another thread to be rejected")
+	}
+}
+
+func TestToolResultOffload_SmallOrSummaryOnlyResultsStayInline(t *testing.T) {
+	t.Parallel()
+
+	db, err := threadstore.Open(filepath.Join(t.TempDir(), "threads.sqlite"))
+	if err != nil {
+		t.Fatalf("threadstore.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	r := &run{cfg: &config.AIConfig{}, threadsDB: db, endpointID: "env_1", threadID: "th_1", id: "run_1"}
+	if _, truncated, contentRef := r.inlineToolResultPayload("call_1", "file.read", map[string]any{"content": "short"}); contentRef != "" || truncated {
+		t.Fatalf("small result contentRef=%q truncated=%v, want inline", contentRef, truncated)
+	}
+
+	r.summaryOnlyPersist = true
+	big := map[string]any{"stdout": strings.Repeat("x", 20_000)}
+	if _, truncated, contentRef := r.inlineToolResultPayload("call_2", "terminal.exec", big); contentRef != "" || !truncated {
+		t.Fatalf("summary-only contentRef=%q truncated=%v, want a truncated inline preview only", contentRef, truncated)
+	}
+}
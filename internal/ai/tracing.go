@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const (
+	aiTracerName = "github.com/floegence/redeven/internal/ai"
+
+	spanNameRun  = "ai.run"
+	spanNameTurn = "ai.turn"
+	spanNameTool = "ai.tool"
+
+	tracingShutdownTimeout = 5 * time.Second
+)
+
+// newTracerProvider builds the OTLP/HTTP exporting tracer provider for cfg.tracing.
+//
+// It returns nil when tracing is not configured. The exporter connects lazily, so an unreachable
+// collector only drops spans.
+func newTracerProvider(ctx context.Context, cfg *config.AIConfig) (*sdktrace.TracerProvider, error) {
+	if cfg == nil || cfg.Tracing == nil {
+		return nil, nil
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSpace(cfg.Tracing.Endpoint)))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.EffectiveTracingServiceName()))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.EffectiveTracingSampleRatio()))),
+	), nil
+}
+
+// startRunSpan starts the root span of a run. Without a tracer it returns ctx and a nil span.
+func (r *run) startRunSpan(ctx context.Context, req RunRequest) (context.Context, trace.Span) {
+	if r == nil || r.tracer == nil {
+		return ctx, nil
+	}
+	providerID, _, _ := strings.Cut(strings.TrimSpace(req.Model), "/")
+	return r.tracer.Start(ctx, spanNameRun, trace.WithAttributes(
+		attribute.String("ai.run_id", strings.TrimSpace(r.id)),
+		attribute.String("ai.thread_id", strings.TrimSpace(r.threadID)),
+		attribute.String("ai.endpoint_id", strings.TrimSpace(r.endpointID)),
+		attribute.String("ai.model", strings.TrimSpace(req.Model)),
+		attribute.String("ai.provider", strings.TrimSpace(providerID)),
+		attribute.String("ai.mode", strings.TrimSpace(req.Options.Mode)),
+	))
+}
+
+func endRunSpan(span trace.Span, state RunState, finalizationReason string, errMsg string) {
+	if span == nil {
+		return
+	}
+	span.SetAttributes(
+		attribute.String("ai.run.state", string(state)),
+		attribute.String("ai.finalization_reason", finalizationReason),
+	)
+	if state == RunStateFailed || state == RunStateTimedOut {
+		span.SetStatus(codes.Error, errMsg)
+	}
+	span.End()
+}
+
+type traceStepIndexKey struct{}
+
+// withTraceStepIndex tags the turn context with the loop step, for the turn span. It is a no-op without a tracer.
+func (r *run) withTraceStepIndex(ctx context.Context, step int) context.Context {
+	if r == nil || r.tracer == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceStepIndexKey{}, step)
+}
+
+// tracedProvider records one span per provider turn, as a child of the span in the turn context.
+type tracedProvider struct {
+	inner        Provider
+	tracer       trace.Tracer
+	providerID   string
+	providerType string
+}
+
+func (p *tracedProvider) StreamTurn(ctx context.Context, req TurnRequest, onEvent func(StreamEvent)) (TurnResult, error) {
+	attrs := []attribute.KeyValue{
+		attribute.String("ai.provider", p.providerID),
+		attribute.String("ai.provider_type", p.providerType),
+		attribute.String("ai.model", strings.TrimSpace(req.Model)),
+	}
+	if step, ok := ctx.Value(traceStepIndexKey{}).(int); ok {
+		attrs = append(attrs, attribute.Int("ai.step_index", step))
+	}
+	ctx, span := p.tracer.Start(ctx, spanNameTurn, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+
+	result, err := p.inner.StreamTurn(ctx, req, onEvent)
+	span.SetAttributes(
+		attribute.String("ai.finish_reason", strings.TrimSpace(result.FinishReason)),
+		attribute.Int64("ai.usage.input_tokens", result.Usage.InputTokens),
+		attribute.Int64("ai.usage.output_tokens", result.Usage.OutputTokens),
+		attribute.Int64("ai.usage.reasoning_tokens", result.Usage.ReasoningTokens),
+		attribute.Int("ai.tool_calls", len(result.ToolCalls)),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, sanitizeLogText(err.Error(), 240))
+	}
+	return result, err
+}
+
+func startToolSpan(ctx context.Context, tracer trace.Tracer, call ToolCall) (context.Context, trace.Span) {
+	return tracer.Start(ctx, spanNameTool, trace.WithAttributes(
+		attribute.String("ai.tool_id", strings.TrimSpace(call.ID)),
+		attribute.String("ai.tool_name", strings.TrimSpace(call.Name)),
+	))
+}
+
+func endToolSpan(span trace.Span, result ToolResult) {
+	span.SetAttributes(
+		attribute.String("ai.tool.status", strings.TrimSpace(result.Status)),
+		attribute.String("ai.tool.summary", strings.TrimSpace(result.Summary)),
+	)
+	if result.Status == toolResultStatusError || result.Status == toolResultStatusTimeout {
+		span.SetStatus(codes.Error, sanitizeLogText(result.Details, 240))
+	}
+	span.End()
+}
+
+// traceContextTransport adds the W3C traceparent header of the request context's span,
+// so gateways in front of the provider can join the turn's trace.
+type traceContextTransport struct {
+	base http.RoundTripper
+}
+
+func (t *traceContextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if trace.SpanContextFromContext(req.Context()).IsValid() {
+		req = req.Clone(req.Context())
+		propagation.TraceContext{}.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (t *traceContextTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func spanAttr(span tracetest.SpanStub, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestIntegration_NativeSDK_OpenAI_Tracing_SpanHierarchy(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	stateDir := t.TempDir()
+	agentHomeDir := t.TempDir()
+
+	finalToken := "OPENAI_TRACING_OK"
+	mock := &openAIDoomLoopGuardMock{finalToken: finalToken, fsPath: agentHomeDir}
+	var headersMu sync.Mutex
+	var traceparents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headersMu.Lock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		headersMu.Unlock()
+		mock.handle(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := &config.AIConfig{
+		Providers: []config.AIProvider{
+			{
+				ID:      "openai",
+				Name:    "OpenAI",
+				Type:    "openai",
+				BaseURL: strings.TrimSuffix(srv.URL, "/") + "/v1",
+				Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	svc, err := NewService(Options{
+		Logger:              logger,
+		StateDir:            stateDir,
+		AgentHomeDir:        agentHomeDir,
+		Shell:               "bash",
+		Config:              cfg,
+		RunMaxWallTime:      30 * time.Second,
+		RunIdleTimeout:      10 * time.Second,
+		ToolApprovalTimeout: 5 * time.Second,
+		ResolveProviderAPIKey: func(providerID string) (string, bool, error) {
+			return "sk-test", strings.TrimSpace(providerID) == "openai", nil
+		},
+		TracerProvider: tracerProvider,
+	})
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+
+	meta := session.Meta{
+		EndpointID:        "env_test",
+		NamespacePublicID: "ns_test",
+		ChannelID:         "ch_test_tracing",
+		UserPublicID:      "u_test",
+		UserEmail:         "u_test@example.com",
+		CanRead:           true,
+		CanWrite:          true,
+		CanExecute:        true,
+		CanAdmin:          true,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	th, err := svc.CreateThread(ctx, &meta, "hello", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	runID := "run_test_native_openai_tracing_1"
+	if err := svc.StartRun(ctx, &meta, runID, RunStartRequest{
+		ThreadID: th.ThreadID,
+		Model:    "openai/gpt-5-mini",
+		Input:    RunInput{Text: "Inspect the workspace"},
+		Options:  RunOptions{MaxSteps: 6, MaxNoToolRounds: 1},
+	}, httptest.NewRecorder()); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+
+	var runSpan *tracetest.SpanStub
+	var turns, tools []tracetest.SpanStub
+	spans := exporter.GetSpans()
+	for i := range spans {
+		switch spans[i].Name {
+		case spanNameRun:
+			runSpan = &spans[i]
+		case spanNameTurn:
+			turns = append(turns, spans[i])
+		case spanNameTool:
+			tools = append(tools, spans[i])
+		}
+	}
+	if runSpan == nil {
+		t.Fatalf("missing %s span; spans=%d", spanNameRun, len(spans))
+	}
+	if runSpan.Parent.IsValid() {
+		t.Fatalf("run span must be a root span")
+	}
+	if v, _ := spanAttr(*runSpan, "ai.run_id"); v.AsString() != runID {
+		t.Fatalf("ai.run_id=%q", v.AsString())
+	}
+	if len(turns) < 2 || len(tools) < 1 {
+		t.Fatalf("turn spans=%d tool spans=%d", len(turns), len(tools))
+	}
+	for _, span := range append(append([]tracetest.SpanStub(nil), turns...), tools...) {
+		if span.Parent.SpanID() != runSpan.SpanContext.SpanID() || span.SpanContext.TraceID() != runSpan.SpanContext.TraceID() {
+			t.Fatalf("%s span is not a child of the run span", span.Name)
+		}
+	}
+	if v, ok := spanAttr(turns[0], "ai.step_index"); !ok || v.AsInt64() != 0 {
+		t.Fatalf("first turn ai.step_index=%v ok=%v", v.AsInt64(), ok)
+	}
+	if v, _ := spanAttr(turns[0], "ai.model"); v.AsString() != "gpt-5-mini" {
+		t.Fatalf("turn ai.model=%q", v.AsString())
+	}
+	if v, _ := spanAttr(turns[0], "ai.usage.output_tokens"); v.AsInt64() != 1 {
+		t.Fatalf("turn ai.usage.output_tokens=%d", v.AsInt64())
+	}
+	if v, _ := spanAttr(tools[0], "ai.tool_name"); v.AsString() != "terminal.exec" {
+		t.Fatalf("tool ai.tool_name=%q", v.AsString())
+	}
+
+	headersMu.Lock()
+	defer headersMu.Unlock()
+	wantTraceID := runSpan.SpanContext.TraceID().String()
+	propagated := false
+	for _, header := range traceparents {
+		if strings.Contains(header, wantTraceID) {
+			propagated = true
+		}
+	}
+	if !propagated {
+		t.Fatalf("no provider request carried trace %s: %q", wantTraceID, traceparents)
+	}
+}
@@ -43,9 +43,27 @@ type RequestUserInputQuestion struct {
 	ChoicesExhaustive *bool                    `json:"choices_exhaustive,omitempty"`
 	WriteLabel        string                   `json:"write_label,omitempty"`
 	WritePlaceholder  string                   `json:"write_placeholder,omitempty"`
+	InputSpec         *RequestUserInputSpec    `json:"input_spec,omitempty"`
 	Choices           []RequestUserInputChoice `json:"choices,omitempty"`
 }
 
+// RequestUserInputSpec types the written answer of a question so the UI can render a matching control.
+//
+// Only the constraints of the selected type are kept. Typed answers are validated before the run resumes.
+type RequestUserInputSpec struct {
+	Type      string   `json:"type"`
+	MinLength int      `json:"min_length,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Integer   bool     `json:"integer,omitempty"`
+	MinDate   string   `json:"min_date,omitempty"`
+	MaxDate   string   `json:"max_date,omitempty"`
+	Absolute  bool     `json:"absolute,omitempty"`
+	Values    []string `json:"values,omitempty"`
+}
+
 type RequestUserInputChoice struct {
 	ChoiceID         string                   `json:"choice_id"`
 	Label            string                   `json:"label"`
@@ -165,8 +183,11 @@ type ThreadView struct {
 	RunStatus           string                  `json:"run_status"`
 	RunUpdatedAtUnixMs  int64                   `json:"run_updated_at_unix_ms"`
 	RunError            string                  `json:"run_error,omitempty"`
+	Busy                bool                    `json:"busy"`
 	WaitingPrompt       *RequestUserInputPrompt `json:"waiting_prompt,omitempty"`
 	LastContextRunID    string                  `json:"last_context_run_id,omitempty"`
+	ForkedFromThreadID  string                  `json:"forked_from_thread_id,omitempty"`
+	ForkedFromMessageID string                  `json:"forked_from_message_id,omitempty"`
 	CreatedAtUnixMs     int64                   `json:"created_at_unix_ms"`
 	UpdatedAtUnixMs     int64                   `json:"updated_at_unix_ms"`
 	LastMessageAtUnixMs int64                   `json:"last_message_at_unix_ms"`
@@ -185,6 +206,27 @@ type CreateThreadRequest struct {
 	WorkingDir    string `json:"working_dir,omitempty"`
 }
 
+type ForkThreadRequest struct {
+	MessageID string `json:"message_id"`
+}
+
+type CompactThreadRequest struct {
+	// TargetTokens is the context size to compact toward; 0 means half of the current estimate.
+	TargetTokens int `json:"target_tokens,omitempty"`
+	// Strategy selects how archived context is folded (truncate|model_summary).
+	Strategy string `json:"strategy,omitempty"`
+}
+
+type CompactThreadResponse struct {
+	ThreadID     string  `json:"thread_id"`
+	Strategy     string  `json:"strategy"`
+	Changed      bool    `json:"changed"`
+	BeforeTokens int     `json:"before_tokens"`
+	AfterTokens  int     `json:"after_tokens"`
+	TargetTokens int     `json:"target_tokens"`
+	SavingRatio  float64 `json:"saving_ratio,omitempty"`
+}
+
 type CreateThreadResponse struct {
 	Thread ThreadView `json:"thread"`
 }
@@ -202,6 +244,32 @@ type ListThreadMessagesResponse struct {
 	TotalReturned int   `json:"total_returned,omitempty"`
 }
 
+// ListThreadMessagesSinceResponse is one page of a thread tail.
+//
+// LatestSeq is the resume token: pass it as the next since_seq. It equals since_seq when nothing is new.
+type ListThreadMessagesSinceResponse struct {
+	Messages  []any `json:"messages"`
+	LatestSeq int64 `json:"latest_seq"`
+	HasMore   bool  `json:"has_more,omitempty"`
+}
+
+// ThreadMessageSearchHit is one message matching a thread message search.
+//
+// Snippet marks matched terms with ** (threadstore.MessageSearchHighlightStart/End).
+type ThreadMessageSearchHit struct {
+	ThreadID        string `json:"thread_id"`
+	ThreadTitle     string `json:"thread_title"`
+	MessageID       string `json:"message_id"`
+	Role            string `json:"role"`
+	CreatedAtUnixMs int64  `json:"created_at_unix_ms"`
+	Snippet         string `json:"snippet"`
+}
+
+type SearchThreadMessagesResponse struct {
+	Query string                   `json:"query"`
+	Hits  []ThreadMessageSearchHit `json:"hits"`
+}
+
 type AppendThreadMessageRequest struct {
 	Role   string `json:"role"`
 	Text   string `json:"text"`
@@ -270,6 +338,7 @@ type RunRequest struct {
 	ContextPack         contextmodel.PromptPack      `json:"-"`
 	ModelCapability     contextmodel.ModelCapability `json:"-"`
 	InteractionContract interactionContract          `json:"-"`
+	AttachmentLimitNote string                       `json:"-"`
 }
 
 type RunHistoryMsg struct {
@@ -300,15 +369,34 @@ type RunOptions struct {
 	MaxSteps int `json:"max_steps"`
 
 	// MaxNoToolRounds controls no-tool backpressure rounds before forcing ask_user.
-	// Default: 3.
+	// Default: the loop profile value (3 for the default profile).
 	MaxNoToolRounds int `json:"max_no_tool_rounds,omitempty"`
 
+	// LoopProfile selects the native loop guard thresholds (default|fast_exit_v1|deep_analysis_v1).
+	// Explicit MaxNoToolRounds and CompactionThreshold override the profile values.
+	LoopProfile string `json:"loop_profile,omitempty"`
+
+	// PromptProfile selects a system-prompt overlay (default|natural_evidence_v2|concise_finish_v1) for A/B evaluation.
+	// Unknown ids fall back to the default prompt.
+	PromptProfile string `json:"prompt_profile,omitempty"`
+
 	// ReasoningOnly relaxes tool-pressure heuristics, but task completion still requires explicit task_complete.
 	ReasoningOnly bool `json:"reasoning_only,omitempty"`
 
 	// RequireUserConfirmOnTaskComplete forces explicit user confirmation when model emits task_complete.
 	RequireUserConfirmOnTaskComplete bool `json:"require_user_confirm_on_task_complete,omitempty"`
 
+	// SelfCheckCompletion runs one tool-free verification turn before accepting task_complete.
+	// A failed check rejects the completion with a recovery overlay; at most two checks run per run.
+	SelfCheckCompletion bool `json:"self_check_completion,omitempty"`
+
+	// MinCompletionChars rejects a task_complete whose result is shorter than this many characters.
+	// Rejections are capped per run; 0 disables the check.
+	MinCompletionChars int `json:"min_completion_chars,omitempty"`
+
+	// RejectPreambleOnlyCompletion rejects a task_complete whose result only announces upcoming work.
+	RejectPreambleOnlyCompletion bool `json:"reject_preamble_only_completion,omitempty"`
+
 	// NoUserInteraction disables ask_user and approval waits for autonomous runs.
 	NoUserInteraction bool `json:"no_user_interaction,omitempty"`
 
@@ -321,6 +409,22 @@ type RunOptions struct {
 	// terminal.exec invocations for the current run.
 	ForceReadonlyExec bool `json:"force_readonly_exec,omitempty"`
 
+	// ToolCallLimits caps how many times each tool (by name) may be called in this run.
+	//
+	// Calls beyond a tool's limit are rejected with summary tool_call_limit. Tools without an entry,
+	// or with a limit <= 0, are only bounded by the step budget.
+	ToolCallLimits map[string]int `json:"tool_call_limits,omitempty"`
+
+	// ExternalTools declares tools this run's caller executes outside the agent.
+	//
+	// A call parks the run until the caller posts the result to
+	// POST /_redeven_proxy/api/ai/runs/{run_id}/tool_result, or the tool's timeout elapses.
+	ExternalTools []ExternalToolDef `json:"external_tools,omitempty"`
+
+	// AllowParallelToolCalls lets the model request several tool calls per turn and runs the
+	// read-only calls of parallel-safe tools concurrently. Every other call is still executed one at a time.
+	AllowParallelToolCalls bool `json:"allow_parallel_tool_calls,omitempty"`
+
 	// Mode overrides runtime mode for this run (act|plan).
 	Mode string `json:"mode,omitempty"`
 
@@ -328,6 +432,13 @@ type RunOptions struct {
 	// Clients should not set this field directly.
 	Intent string `json:"intent,omitempty"`
 
+	// ForceIntent pins the run intent (social|creative|task) and skips the intent classifier.
+	// A structured response to an open goal still continues that goal as a task.
+	ForceIntent string `json:"force_intent,omitempty"`
+
+	// RoutingRule is the ai.routing_rules entry that matched the input, set by the runtime.
+	RoutingRule *routingRuleMatch `json:"-"`
+
 	// ExecutionContract is classified by the runtime (direct_reply|hybrid_first_turn|agentic_loop).
 	// Clients should not set this field directly.
 	ExecutionContract string `json:"execution_contract,omitempty"`
@@ -350,15 +461,49 @@ type RunOptions struct {
 	ResponseFormat       string   `json:"response_format,omitempty"`
 	Temperature          *float64 `json:"temperature,omitempty"`
 	TopP                 *float64 `json:"top_p,omitempty"`
+	// Seed pins the sampling seed for reproducible output where the provider supports it.
+	// Other providers record a seed_unsupported event and run unseeded.
+	Seed *int64 `json:"seed,omitempty"`
+
+	// ResponseJSONSchema is the structured output format used when ResponseFormat is json_schema:
+	// {"name": "...", "description": "...", "strict": true, "schema": {...}}.
+	// It must be a JSON object with a name and an object schema; malformed values reject the run.
+	// Models without strict JSON schema support fall back to json_object and ignore it.
+	ResponseJSONSchema json.RawMessage `json:"response_json_schema,omitempty"`
+
+	// MaxHistoryMessages and MaxHistoryTokens cap the prior conversation a run starts with (0 means unset).
+	// The most recent messages are kept; a history.trimmed event records what was dropped.
+	MaxHistoryMessages int `json:"max_history_messages,omitempty"`
+	MaxHistoryTokens   int `json:"max_history_tokens,omitempty"`
+	// SummarizeTrimmedHistory replaces the dropped history with a short note listing the earlier user requests.
+	SummarizeTrimmedHistory bool `json:"summarize_trimmed_history,omitempty"`
 
 	// Optional hard budgets (0 means unset).
 	MaxInputTokens  int     `json:"max_input_tokens,omitempty"`
 	MaxOutputTokens int     `json:"max_output_tokens,omitempty"`
 	MaxCostUSD      float64 `json:"max_cost_usd,omitempty"`
 
+	// MaxWallTimeMs and MaxIdleTimeMs tighten the service-level run time limits for this run (0 means unset).
+	// Values above the service limits reject the run. Reaching MaxWallTimeMs ends the run with a final summary.
+	MaxWallTimeMs int64 `json:"max_wall_time_ms,omitempty"`
+	MaxIdleTimeMs int64 `json:"max_idle_time_ms,omitempty"`
+
+	// Background detaches the run from the client stream: StartRun returns once the run is started and the run
+	// keeps persisting events and messages, so clients reconnect through the run event stream.
+	// Background runs are canceled when the service closes.
+	Background bool `json:"background,omitempty"`
+
 	// CompactionThreshold controls when runtime compaction is triggered.
 	// Value is a fraction in range [0,1]. 0 means use runtime default.
 	CompactionThreshold float64 `json:"compaction_threshold,omitempty"`
+
+	// CompactionStrategy selects how archived context is folded once compaction triggers (truncate|model_summary).
+	// Empty or unknown values use truncate. model_summary spends one extra model turn per compaction.
+	CompactionStrategy string `json:"compaction_strategy,omitempty"`
+
+	// Compaction tunes how much the truncate strategy keeps. Nil or zero fields use defaults scaled to the
+	// model's context window.
+	Compaction *CompactionConfig `json:"compaction,omitempty"`
 }
 
 type ToolApprovalRequest struct {
@@ -501,7 +646,8 @@ type ToolCallBlock struct {
 	ToolID           string             `json:"toolId"`
 	Args             map[string]any     `json:"args"`
 	RequiresApproval bool               `json:"requiresApproval,omitempty"`
-	ApprovalState    string             `json:"approvalState,omitempty"` // required|approved|rejected
+	ApprovalState    string             `json:"approvalState,omitempty"`  // required|approved|rejected
+	ApprovalReason   string             `json:"approvalReason,omitempty"` // decision + matched ai.tool_approval rule
 	Status           ToolCallStatus     `json:"status"`
 	Result           any                `json:"result,omitempty"`
 	Error            string             `json:"error,omitempty"`
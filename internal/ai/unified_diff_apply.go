@@ -518,29 +518,16 @@ type patchFilePlan struct {
 	delete   bool
 	write    bool
 	perm     fs.FileMode
+	original []byte
 	contents []byte
 }
 
 func applyUnifiedDiff(workingDirAbs string, patchText string) (parsedPatch, error) {
-	workingDirAbs = filepath.Clean(strings.TrimSpace(workingDirAbs))
-	if workingDirAbs == "" || !filepath.IsAbs(workingDirAbs) {
-		return parsedPatch{}, errors.New("invalid working dir")
-	}
-
-	parsed, err := parsePatchText(patchText)
+	parsed, plans, err := planUnifiedDiff(workingDirAbs, patchText)
 	if err != nil {
 		return parsedPatch{}, err
 	}
 
-	plans := make([]patchFilePlan, 0, len(parsed.files))
-	for _, fd := range parsed.files {
-		plan, err := buildPatchFilePlan(workingDirAbs, fd)
-		if err != nil {
-			return parsedPatch{}, err
-		}
-		plans = append(plans, plan)
-	}
-
 	// Apply after full validation to avoid partially-applied patches on parse errors.
 	for _, plan := range plans {
 		if plan.delete {
@@ -564,6 +551,29 @@ func applyUnifiedDiff(workingDirAbs string, patchText string) (parsedPatch, erro
 	return parsed, nil
 }
 
+// planUnifiedDiff parses patchText and computes the resulting file contents without touching disk.
+func planUnifiedDiff(workingDirAbs string, patchText string) (parsedPatch, []patchFilePlan, error) {
+	workingDirAbs = filepath.Clean(strings.TrimSpace(workingDirAbs))
+	if workingDirAbs == "" || !filepath.IsAbs(workingDirAbs) {
+		return parsedPatch{}, nil, errors.New("invalid working dir")
+	}
+
+	parsed, err := parsePatchText(patchText)
+	if err != nil {
+		return parsedPatch{}, nil, err
+	}
+
+	plans := make([]patchFilePlan, 0, len(parsed.files))
+	for _, fd := range parsed.files {
+		plan, err := buildPatchFilePlan(workingDirAbs, fd)
+		if err != nil {
+			return parsedPatch{}, nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return parsed, plans, nil
+}
+
 func buildPatchFilePlan(workingDirAbs string, fd unifiedDiffFile) (patchFilePlan, error) {
 	oldPath := strings.TrimSpace(fd.oldPath)
 	newPath := strings.TrimSpace(fd.newPath)
@@ -626,6 +636,7 @@ func buildPatchFilePlan(workingDirAbs string, fd unifiedDiffFile) (patchFilePlan
 		delete:   false,
 		write:    true,
 		perm:     perm,
+		original: fileBytes,
 		contents: next,
 	}, nil
 }
@@ -49,27 +49,34 @@ func (s *Service) SaveUpload(ctx context.Context, endpointID string, r io.Reader
 		return nil, errors.New("missing endpoint_id")
 	}
 
-	id, err := newUploadID()
-	if err != nil {
-		return nil, err
-	}
-
-	name = strings.TrimSpace(name)
-	if name == "" {
-		name = "upload"
-	}
-
 	s.mu.Lock()
 	dir := strings.TrimSpace(s.uploadsDir)
 	db := s.threadsDB
 	persistTO := s.persistOpTO
 	s.mu.Unlock()
+	return storeUpload(ctx, dir, db, persistTO, endpointID, r, name, mimeType, maxBytes)
+}
+
+// storeUpload writes one upload into dir and registers it in db as staged.
+//
+// Staged uploads expire unless a message or queued turn claims them (see BindUploadsToRef).
+func storeUpload(ctx context.Context, dir string, db *threadstore.Store, persistTO time.Duration, endpointID string, r io.Reader, name string, mimeType string, maxBytes int64) (*UploadResponse, error) {
 	if dir == "" || db == nil {
 		return nil, errors.New("uploads not ready")
 	}
 	if persistTO <= 0 {
 		persistTO = defaultPersistOpTimeout
 	}
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "upload"
+	}
+
 	dataPath := filepath.Join(dir, id+".data")
 	metaPath := filepath.Join(dir, id+".json")
 
@@ -155,7 +162,7 @@ func (s *Service) SaveUpload(ctx context.Context, endpointID string, r io.Reader
 	}
 
 	return &UploadResponse{
-		URL:      "/_redeven_proxy/api/ai/uploads/" + id,
+		URL:      uploadURLPrefix + id,
 		Name:     meta.Name,
 		Size:     meta.Size,
 		MimeType: meta.MimeType,
@@ -267,6 +267,7 @@ func buildCanonicalRequestUserInputQuestion(question RequestUserInputQuestion, l
 		}
 		out.WriteLabel = writeLabel
 		out.WritePlaceholder = writePlaceholder
+		out.InputSpec = normalizeRequestUserInputSpec(question.InputSpec)
 	}
 
 	return out, true
@@ -305,6 +306,7 @@ func requestUserInputQuestionFromRecord(record map[string]any) (RequestUserInput
 		ChoicesExhaustive: choicesExhaustive,
 		WriteLabel:        anyToString(record["write_label"]),
 		WritePlaceholder:  anyToString(record["write_placeholder"]),
+		InputSpec:         parseRequestUserInputSpecAny(record["input_spec"]),
 		Choices:           choices,
 	}, choicesExhaustive)
 }
@@ -551,6 +553,7 @@ func normalizeRequestUserInputQuestions(questions []RequestUserInputQuestion) []
 			ChoicesExhaustive: question.ChoicesExhaustive,
 			WriteLabel:        question.WriteLabel,
 			WritePlaceholder:  question.WritePlaceholder,
+			InputSpec:         question.InputSpec,
 			Choices:           question.Choices,
 		}, nil)
 		if !ok {
@@ -879,6 +882,13 @@ func validateRequestUserInputResponse(prompt *RequestUserInputPrompt, response *
 				return nil, ErrWaitingPromptChanged
 			}
 		}
+		if answer.ChoiceID == "" && question.InputSpec != nil {
+			text, err := validateRequestUserInputSpecAnswer(question.InputSpec, answer.Text)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %s", ErrInvalidPromptAnswer, question.Header, err)
+			}
+			answer.Text = text
+		}
 		normalizedAnswers[question.ID] = answer
 	}
 	return &RequestUserInputResponse{
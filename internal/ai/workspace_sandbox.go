@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	aitools "github.com/floegence/redeven/internal/ai/tools"
+)
+
+// sandboxViolationSummary is the tool result summary for calls rejected by the strict workspace sandbox.
+const sandboxViolationSummary = "sandbox_violation"
+
+// workspaceSandboxViolation returns why a tool invocation escapes the working directory, or "" when
+// it stays inside or the strict workspace sandbox is disabled.
+//
+// Paths are resolved through symlinks, so a link inside the working directory that points outside
+// it is treated as outside. The sandbox fails closed: a working directory or path that cannot be
+// resolved is a violation.
+func (r *run) workspaceSandboxViolation(toolName string, args map[string]any) string {
+	if r == nil || !r.cfg.EffectiveStrictWorkspaceSandbox() {
+		return ""
+	}
+	scope, err := r.pathScope()
+	if err != nil {
+		return fmt.Sprintf("%s cannot be checked against the working directory: %v", toolName, err)
+	}
+	// escapes returns why path is not provably inside the working directory, or "" when it is.
+	escapes := func(path string, baseDir string) string {
+		inside, err := scope.ContainsPath(path, baseDir)
+		if err != nil {
+			return fmt.Sprintf("cannot be resolved inside the working directory: %v", err)
+		}
+		if !inside {
+			return "is outside the working directory"
+		}
+		return ""
+	}
+	absFrom := func(path string, baseDir string) string {
+		switch {
+		case path == "~" || strings.HasPrefix(path, "~/"):
+			return filepath.Join(scope.RuntimeHomeAbs, strings.TrimPrefix(path, "~"))
+		case filepath.IsAbs(path):
+			return filepath.Clean(path)
+		default:
+			return filepath.Join(baseDir, path)
+		}
+	}
+
+	switch toolName {
+	case "file.read", "file.edit", "file.write":
+		path := strings.TrimSpace(readStringField(args, "file_path"))
+		if path == "" {
+			break
+		}
+		if why := escapes(path, ""); why != "" {
+			return fmt.Sprintf("%s path %q %s", toolName, path, why)
+		}
+	case "apply_patch":
+		parsed, err := parsePatchText(readStringField(args, "patch"))
+		if err != nil {
+			return fmt.Sprintf("apply_patch cannot be checked against the working directory: %v", err)
+		}
+		for _, fd := range parsed.files {
+			for _, raw := range []string{fd.oldPath, fd.newPath} {
+				path := strings.TrimSpace(raw)
+				if path == "" || path == "/dev/null" {
+					continue
+				}
+				if why := escapes(path, ""); why != "" {
+					return fmt.Sprintf("apply_patch path %q %s", path, why)
+				}
+			}
+		}
+	case "terminal.exec":
+		cwd := scope.ProjectRootAbs
+		for _, key := range []string{"cwd", "workdir"} {
+			dir := strings.TrimSpace(readStringField(args, key))
+			if dir == "" {
+				continue
+			}
+			if why := escapes(dir, ""); why != "" {
+				return fmt.Sprintf("terminal.exec %s %q %s", key, dir, why)
+			}
+			cwd = absFrom(dir, scope.ProjectRootAbs)
+		}
+		for _, ref := range aitools.TerminalCommandPathRefs(readStringField(args, "command")) {
+			if why := escapes(ref.Path, cwd); why != "" {
+				if ref.ChangesDir {
+					return fmt.Sprintf("terminal.exec changes directory to %q, which %s", ref.Path, why)
+				}
+				return fmt.Sprintf("terminal.exec references %q, which %s", ref.Path, why)
+			}
+			if ref.ChangesDir {
+				cwd = absFrom(ref.Path, cwd)
+			}
+		}
+	}
+	return ""
+}
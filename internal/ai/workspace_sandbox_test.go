@@ -0,0 +1,206 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func newWorkspaceSandboxTestRun(t *testing.T, strict bool) (*run, string, string) {
+	t.Helper()
+	home := t.TempDir()
+	project := filepath.Join(home, "workspace")
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(project, "src"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(project, "escape")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	r := newRun(runOptions{
+		Log:          slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
+		AgentHomeDir: home,
+		WorkingDir:   project,
+		Shell:        "bash",
+		AIConfig:     &config.AIConfig{StrictWorkspaceSandbox: strict},
+		SessionMeta:  &session.Meta{CanRead: true, CanWrite: true, CanExecute: true, CanAdmin: true},
+	})
+	return r, project, outside
+}
+
+func TestRun_WorkspaceSandboxViolation(t *testing.T) {
+	t.Parallel()
+
+	r, project, outside := newWorkspaceSandboxTestRun(t, true)
+	cases := []struct {
+		name     string
+		toolName string
+		args     map[string]any
+		blocked  bool
+	}{
+		{name: "relative file", toolName: "file.read", args: map[string]any{"file_path": "src/main.go"}, blocked: false},
+		{name: "absolute file inside", toolName: "file.write", args: map[string]any{"file_path": filepath.Join(project, "notes.md")}, blocked: false},
+		{name: "parent traversal", toolName: "file.read", args: map[string]any{"file_path": "src/../../secret.txt"}, blocked: true},
+		{name: "symlink escape", toolName: "file.edit", args: map[string]any{"file_path": "escape/config.yaml"}, blocked: true},
+		{name: "absolute outside", toolName: "file.read", args: map[string]any{"file_path": filepath.Join(outside, "a.txt")}, blocked: true},
+		{name: "exec inside", toolName: "terminal.exec", args: map[string]any{"command": "go test ./... 2>&1 | tail -n 5", "cwd": "src"}, blocked: false},
+		{name: "exec cd back inside", toolName: "terminal.exec", args: map[string]any{"command": "cd src && cat ../go.mod", "workdir": project}, blocked: false},
+		{name: "exec workdir outside", toolName: "terminal.exec", args: map[string]any{"command": "ls", "workdir": outside}, blocked: true},
+		{name: "exec cd traversal", toolName: "terminal.exec", args: map[string]any{"command": "cd src && cd ../.. && ls"}, blocked: true},
+		{name: "exec cd symlink", toolName: "terminal.exec", args: map[string]any{"command": "cd escape && ls"}, blocked: true},
+		{name: "exec absolute argument", toolName: "terminal.exec", args: map[string]any{"command": "cat /etc/passwd"}, blocked: true},
+		{name: "exec redirect outside", toolName: "terminal.exec", args: map[string]any{"command": "echo hi >" + filepath.Join(outside, "out.txt")}, blocked: true},
+		{name: "other tool", toolName: "web.search", args: map[string]any{"query": "/etc/passwd"}, blocked: false},
+	}
+	for _, tc := range cases {
+		got := r.workspaceSandboxViolation(tc.toolName, tc.args)
+		if (got != "") != tc.blocked {
+			t.Fatalf("%s: violation=%q, want blocked=%v", tc.name, got, tc.blocked)
+		}
+	}
+
+	relaxed, _, _ := newWorkspaceSandboxTestRun(t, false)
+	if got := relaxed.workspaceSandboxViolation("terminal.exec", map[string]any{"command": "cat /etc/passwd"}); got != "" {
+		t.Fatalf("sandbox disabled: violation=%q", got)
+	}
+}
+
+func TestRun_WorkspaceSandboxViolation_FailsClosed(t *testing.T) {
+	t.Parallel()
+
+	r, project, _ := newWorkspaceSandboxTestRun(t, true)
+	if err := os.Symlink("loop", filepath.Join(project, "loop")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	for name, tc := range map[string]struct {
+		toolName string
+		args     map[string]any
+	}{
+		"file through symlink loop": {toolName: "file.read", args: map[string]any{"file_path": "loop/a.txt"}},
+		"exec cwd through loop":     {toolName: "terminal.exec", args: map[string]any{"command": "ls", "cwd": "loop"}},
+		"exec cd into loop":         {toolName: "terminal.exec", args: map[string]any{"command": "cd loop && ls"}},
+	} {
+		if got := r.workspaceSandboxViolation(tc.toolName, tc.args); !strings.Contains(got, "cannot be resolved") {
+			t.Fatalf("%s: violation=%q, want an unresolvable path violation", name, got)
+		}
+	}
+
+	unscoped := newRun(runOptions{
+		Log:          slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
+		AgentHomeDir: t.TempDir(),
+		WorkingDir:   t.TempDir(),
+		Shell:        "bash",
+		AIConfig:     &config.AIConfig{StrictWorkspaceSandbox: true},
+		SessionMeta:  &session.Meta{CanRead: true, CanWrite: true, CanExecute: true, CanAdmin: true},
+	})
+	if got := unscoped.workspaceSandboxViolation("file.read", map[string]any{"file_path": "a.txt"}); got == "" {
+		t.Fatalf("working directory outside the agent home must be a violation")
+	}
+}
+
+func TestBuiltInToolHandler_SandboxViolation_MapsToAborted(t *testing.T) {
+	t.Parallel()
+
+	r, _, outside := newWorkspaceSandboxTestRun(t, true)
+	target := filepath.Join(outside, "a.txt")
+	h := &builtInToolHandler{r: r, toolName: "terminal.exec"}
+	res, err := h.Execute(context.Background(), ToolCall{
+		ID:   "tool_1",
+		Name: "terminal.exec",
+		Args: map[string]any{"command": "printf 'hi' > " + target},
+	})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if res.Status != toolResultStatusAborted || res.Summary != sandboxViolationSummary {
+		t.Fatalf("status=%q summary=%q details=%q", res.Status, res.Summary, res.Details)
+	}
+	if !strings.Contains(res.Details, "outside the working directory") {
+		t.Fatalf("details=%q", res.Details)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("blocked command must not run, stat err=%v", err)
+	}
+}
+
+func TestBuiltInToolHandler_SandboxViolation_ApplyPatchThroughSymlink(t *testing.T) {
+	t.Parallel()
+
+	r, project, outside := newWorkspaceSandboxTestRun(t, true)
+	target := filepath.Join(outside, "config.yaml")
+	if err := os.WriteFile(target, []byte("mode: safe\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	patch := strings.Join([]string{
+		"*** Begin Patch",
+		"*** Update File: escape/config.yaml",
+		"@@",
+		"-mode: safe",
+		"+mode: pwned",
+		"*** End Patch",
+	}, "\n")
+	if got := r.workspaceSandboxViolation("apply_patch", map[string]any{"patch": patch}); !strings.Contains(got, "outside the working directory") {
+		t.Fatalf("violation=%q, want symlinked patch target rejected", got)
+	}
+	inside := strings.Join([]string{
+		"*** Begin Patch",
+		"*** Add File: src/new.txt",
+		"+hello",
+		"*** End Patch",
+	}, "\n")
+	if got := r.workspaceSandboxViolation("apply_patch", map[string]any{"patch": inside}); got != "" {
+		t.Fatalf("patch inside the workspace: violation=%q", got)
+	}
+
+	h := &builtInToolHandler{r: r, toolName: "apply_patch"}
+	res, err := h.Execute(context.Background(), ToolCall{
+		ID:   "tool_patch",
+		Name: "apply_patch",
+		Args: map[string]any{"patch": patch},
+	})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if res.Status != toolResultStatusAborted || res.Summary != sandboxViolationSummary {
+		t.Fatalf("status=%q summary=%q details=%q", res.Status, res.Summary, res.Details)
+	}
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != "mode: safe\n" {
+		t.Fatalf("file outside the workspace was modified: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(project, "src", "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("unexpected file, stat err=%v", err)
+	}
+}
+
+func TestBuildPromptDocument_StrictWorkspaceSandbox(t *testing.T) {
+	t.Parallel()
+
+	render := func(strict bool) string {
+		snapshot := promptRuntimeSnapshot{ProtocolSurface: RunProtocolSurfaceStructuredFileOps}
+		snapshot.WorkspaceContext.Environment.StrictWorkspaceSandbox = strict
+		doc := buildPromptDocument(snapshot)
+		var sb strings.Builder
+		for _, section := range doc.StaticSections {
+			sb.WriteString(section.render())
+		}
+		return sb.String()
+	}
+	if got := render(false); !strings.Contains(got, "only the outer sandbox") {
+		t.Fatalf("default prompt should keep the soft boundary guidance")
+	}
+	got := render(true)
+	if strings.Contains(got, "only the outer sandbox") || !strings.Contains(got, "hard sandbox") {
+		t.Fatalf("strict prompt should describe the hard sandbox: %s", got)
+	}
+}
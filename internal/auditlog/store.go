@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -75,8 +76,34 @@ type Store struct {
 	maxBackups int
 
 	mu sync.Mutex
+	// lastCreatedAt is the newest created_at written so far; Append keeps created_at strictly increasing
+	// so it can serve as a paging cursor.
+	lastCreatedAt time.Time
 }
 
+// ListQuery selects a page of entries.
+//
+// Before and After are cursors: the created_at of an entry in Unix nanoseconds, as returned in ListPage.NextCursor.
+// With no cursor the newest Limit entries are returned.
+type ListQuery struct {
+	Limit int
+	// Before returns entries strictly older than the cursor (paging backwards).
+	Before int64
+	// After returns the entries strictly newer than the cursor that follow it directly (paging forwards).
+	After int64
+}
+
+type ListPage struct {
+	// Entries are ordered newest first, regardless of the paging direction.
+	Entries []Entry
+	// NextCursor continues in the same direction; empty when no further entries exist.
+	NextCursor string
+	// Total is the number of entries currently retained (active file plus rotated backups).
+	Total int
+}
+
+var ErrConflictingCursors = errors.New("before and after cursors are mutually exclusive")
+
 func New(opts Options) (*Store, error) {
 	stateDir := strings.TrimSpace(opts.StateDir)
 	if stateDir == "" {
@@ -109,13 +136,15 @@ func New(opts Options) (*Store, error) {
 		return nil, err
 	}
 
-	return &Store{
+	s := &Store{
 		log:        logger,
 		dir:        dir,
 		activePath: activePath,
 		maxBytes:   maxBytes,
 		maxBackups: maxBackups,
-	}, nil
+	}
+	s.lastCreatedAt = s.newestCreatedAtLocked()
+	return s, nil
 }
 
 func (s *Store) Append(e Entry) {
@@ -126,9 +155,17 @@ func (s *Store) Append(e Entry) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if strings.TrimSpace(e.CreatedAt) == "" {
-		e.CreatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	createdAt := time.Now().UTC()
+	if raw := strings.TrimSpace(e.CreatedAt); raw != "" {
+		if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			createdAt = t.UTC()
+		}
+	}
+	// Keep created_at strictly increasing, even across clock steps backwards, so cursors never skip entries.
+	if !createdAt.After(s.lastCreatedAt) {
+		createdAt = s.lastCreatedAt.Add(time.Nanosecond)
 	}
+	e.CreatedAt = createdAt.Format(time.RFC3339Nano)
 	if strings.TrimSpace(e.Status) == "" {
 		e.Status = "success"
 	}
@@ -147,13 +184,27 @@ func (s *Store) Append(e Entry) {
 		return
 	}
 
+	s.lastCreatedAt = createdAt
 	s.maybeRotateLocked()
 }
 
 func (s *Store) List(limit int) ([]Entry, error) {
+	page, err := s.ListPage(ListQuery{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return page.Entries, nil
+}
+
+// ListPage returns one page of entries selected by q.
+func (s *Store) ListPage(q ListQuery) (ListPage, error) {
 	if s == nil {
-		return nil, nil
+		return ListPage{}, nil
 	}
+	if q.Before > 0 && q.After > 0 {
+		return ListPage{}, ErrConflictingCursors
+	}
+	limit := q.Limit
 	if limit <= 0 {
 		limit = 200
 	}
@@ -165,20 +216,81 @@ func (s *Store) List(limit int) ([]Entry, error) {
 	files := s.listFilesLocked()
 	s.mu.Unlock()
 
-	out := make([]Entry, 0, limit)
+	// Rotation bounds the retained history, so reading every file stays cheap.
+	var all []Entry
 	for _, path := range files {
-		if len(out) >= limit {
-			break
-		}
-		entries, err := readFileNewestFirst(path, limit-len(out))
+		entries, err := readFileNewestFirst(path, -1)
 		if err != nil {
 			// Best-effort: return what we have.
 			s.log.Warn("auditlog read failed", "path", path, "error", err)
 			continue
 		}
-		out = append(out, entries...)
+		all = append(all, entries...)
 	}
-	return out, nil
+
+	page := ListPage{Total: len(all), Entries: []Entry{}}
+	switch {
+	case q.After > 0:
+		// all is newest first: the entries right after the cursor are the tail of the newer run.
+		end := 0
+		for end < len(all) && entryCursor(all[end]) > q.After {
+			end++
+		}
+		start := 0
+		if end > limit {
+			start = end - limit
+			page.NextCursor = strconv.FormatInt(entryCursor(all[start]), 10)
+		}
+		page.Entries = append(page.Entries, all[start:end]...)
+	default:
+		start := 0
+		if q.Before > 0 {
+			for start < len(all) && entryCursor(all[start]) >= q.Before {
+				start++
+			}
+		}
+		end := min(start+limit, len(all))
+		page.Entries = append(page.Entries, all[start:end]...)
+		if end < len(all) && end > start {
+			page.NextCursor = strconv.FormatInt(entryCursor(all[end-1]), 10)
+		}
+	}
+	return page, nil
+}
+
+// ParseCursor parses a cursor produced by ListPage.NextCursor. An empty string means no cursor.
+func ParseCursor(raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid cursor %q", raw)
+	}
+	return v, nil
+}
+
+// entryCursor is the entry's created_at in Unix nanoseconds, or 0 if it cannot be parsed.
+func entryCursor(e Entry) int64 {
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(e.CreatedAt))
+	if err != nil {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func (s *Store) newestCreatedAtLocked() time.Time {
+	for _, path := range s.listFilesLocked() {
+		entries, err := readFileNewestFirst(path, 1)
+		if err != nil || len(entries) == 0 {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(entries[0].CreatedAt)); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
 }
 
 func (s *Store) listFilesLocked() []string {
@@ -229,6 +341,14 @@ func (s *Store) maybeRotateLocked() {
 
 	ts := time.Now().UnixMilli()
 	dst := filepath.Join(s.dir, fmt.Sprintf("events-%d.jsonl", ts))
+	// Two rotations within one millisecond must not overwrite the previous backup.
+	for {
+		if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		ts++
+		dst = filepath.Join(s.dir, fmt.Sprintf("events-%d.jsonl", ts))
+	}
 	if err := os.Rename(s.activePath, dst); err != nil {
 		s.log.Warn("auditlog rotate failed", "error", err)
 		return
@@ -264,12 +384,13 @@ func (s *Store) maybeRotateLocked() {
 	}
 }
 
+// readFileNewestFirst returns up to limit entries from path, newest first. A negative limit returns all entries.
 func readFileNewestFirst(path string, limit int) ([]Entry, error) {
 	p := strings.TrimSpace(path)
 	if p == "" {
 		return nil, nil
 	}
-	if limit <= 0 {
+	if limit == 0 {
 		return nil, nil
 	}
 
@@ -306,7 +427,7 @@ func readFileNewestFirst(path string, limit int) ([]Entry, error) {
 	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
 		entries[i], entries[j] = entries[j], entries[i]
 	}
-	if len(entries) > limit {
+	if limit > 0 && len(entries) > limit {
 		entries = entries[:limit]
 	}
 	return entries, nil
@@ -0,0 +1,173 @@
+package auditlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, stateDir string, maxBytes int64, maxBackups int) *Store {
+	t.Helper()
+	s, err := New(Options{
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		StateDir:   stateDir,
+		MaxBytes:   maxBytes,
+		MaxBackups: maxBackups,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+func pageActions(page ListPage) []string {
+	out := make([]string, 0, len(page.Entries))
+	for _, e := range page.Entries {
+		out = append(out, e.Action)
+	}
+	return out
+}
+
+func TestListPage_PagesAcrossRotatedFiles(t *testing.T) {
+	t.Parallel()
+
+	// Every few entries rotate into a backup, so pages have to cross file boundaries.
+	s := newTestStore(t, t.TempDir(), 300, 20)
+	for i := 0; i < 12; i++ {
+		s.Append(Entry{Action: fmt.Sprintf("a%02d", i)})
+	}
+	rotated, _ := filepath.Glob(filepath.Join(s.dir, "events-*.jsonl"))
+	if len(rotated) < 2 {
+		t.Fatalf("expected several rotated files, got %v", rotated)
+	}
+
+	var got []string
+	var cursors []string
+	q := ListQuery{Limit: 5}
+	for {
+		page, err := s.ListPage(q)
+		if err != nil {
+			t.Fatalf("ListPage: %v", err)
+		}
+		if page.Total != 12 {
+			t.Fatalf("total=%d, want 12", page.Total)
+		}
+		got = append(got, pageActions(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursors = append(cursors, page.NextCursor)
+		before, err := ParseCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("ParseCursor: %v", err)
+		}
+		q = ListQuery{Limit: 5, Before: before}
+	}
+	want := []string{"a11", "a10", "a09", "a08", "a07", "a06", "a05", "a04", "a03", "a02", "a01", "a00"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("backward pages=%v, want %v", got, want)
+	}
+	if len(cursors) != 2 {
+		t.Fatalf("cursors=%v, want 2 follow-up pages", cursors)
+	}
+
+	// Page forwards from the oldest entry of the first page.
+	after, _ := ParseCursor(cursors[0])
+	page, err := s.ListPage(ListQuery{Limit: 4, After: after})
+	if err != nil {
+		t.Fatalf("ListPage after: %v", err)
+	}
+	if actions := pageActions(page); !slices.Equal(actions, []string{"a11", "a10", "a09", "a08"}) || page.NextCursor != "" {
+		t.Fatalf("after page=%v next=%q", actions, page.NextCursor)
+	}
+	after, _ = ParseCursor(cursors[1])
+	page, err = s.ListPage(ListQuery{Limit: 3, After: after})
+	if err != nil {
+		t.Fatalf("ListPage after: %v", err)
+	}
+	if actions := pageActions(page); !slices.Equal(actions, []string{"a05", "a04", "a03"}) || page.NextCursor == "" {
+		t.Fatalf("after page=%v next=%q", actions, page.NextCursor)
+	}
+
+	// List keeps its latest-N behavior.
+	latest, err := s.List(2)
+	if err != nil || !slices.Equal(pageActions(ListPage{Entries: latest}), []string{"a11", "a10"}) {
+		t.Fatalf("List(2)=%v err=%v", latest, err)
+	}
+}
+
+func TestListPage_WraparoundAndEmptyPages(t *testing.T) {
+	t.Parallel()
+
+	stateDir := t.TempDir()
+	s := newTestStore(t, stateDir, 300, 1)
+
+	empty, err := s.ListPage(ListQuery{})
+	if err != nil || len(empty.Entries) != 0 || empty.NextCursor != "" || empty.Total != 0 {
+		t.Fatalf("empty store page=%+v err=%v", empty, err)
+	}
+
+	s.Append(Entry{Action: "first"})
+	first, err := s.ListPage(ListQuery{Limit: 1})
+	if err != nil || len(first.Entries) != 1 {
+		t.Fatalf("first page=%+v err=%v", first, err)
+	}
+	firstCursor := entryCursor(first.Entries[0])
+
+	// A clock step backwards must not produce an entry that sorts before the previous one.
+	s.Append(Entry{Action: "skewed", CreatedAt: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339Nano)})
+	page, err := s.ListPage(ListQuery{After: firstCursor})
+	if err != nil || !slices.Equal(pageActions(page), []string{"skewed"}) {
+		t.Fatalf("after first=%v err=%v", pageActions(page), err)
+	}
+
+	// Rotation with a single backup drops the oldest entries; their cursors then yield empty pages.
+	for i := 0; i < 10; i++ {
+		s.Append(Entry{Action: fmt.Sprintf("n%02d", i)})
+	}
+	page, err = s.ListPage(ListQuery{Before: firstCursor})
+	if err != nil || len(page.Entries) != 0 || page.NextCursor != "" {
+		t.Fatalf("page before pruned cursor=%+v err=%v", page, err)
+	}
+	latest, err := s.ListPage(ListQuery{Limit: 1000})
+	if err != nil || latest.Total >= 12 || latest.Total != len(latest.Entries) {
+		t.Fatalf("retained page total=%d entries=%d err=%v", latest.Total, len(latest.Entries), err)
+	}
+	newest := entryCursor(latest.Entries[0])
+	page, err = s.ListPage(ListQuery{After: newest})
+	if err != nil || len(page.Entries) != 0 || page.NextCursor != "" {
+		t.Fatalf("page after newest=%+v err=%v", page, err)
+	}
+
+	// A reopened store continues after the newest persisted entry.
+	reopened := newTestStore(t, stateDir, 300, 1)
+	reopened.Append(Entry{Action: "reopened", CreatedAt: time.Unix(0, 1).UTC().Format(time.RFC3339Nano)})
+	page, err = reopened.ListPage(ListQuery{After: newest})
+	if err != nil || !slices.Equal(pageActions(page), []string{"reopened"}) {
+		t.Fatalf("after reopen=%v err=%v", pageActions(page), err)
+	}
+}
+
+func TestListPage_RejectsConflictingCursors(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStore(t, t.TempDir(), 0, 0)
+	if _, err := s.ListPage(ListQuery{Before: 2, After: 1}); !errors.Is(err, ErrConflictingCursors) {
+		t.Fatalf("err=%v, want ErrConflictingCursors", err)
+	}
+	for _, raw := range []string{"abc", "-1", "0"} {
+		if _, err := ParseCursor(raw); err == nil || !strings.Contains(err.Error(), "invalid cursor") {
+			t.Fatalf("ParseCursor(%q) err=%v", raw, err)
+		}
+	}
+	if _, err := os.Stat(s.activePath); err != nil {
+		t.Fatalf("active file: %v", err)
+	}
+}
@@ -52,6 +52,7 @@ type Options struct {
 	// Env/App-level context (used by AI tools).
 	AgentHomeDir string
 	Shell        string
+	AgentVersion string
 
 	AIConfig    *config.AIConfig
 	Audit       *auditlog.Store
@@ -62,6 +63,9 @@ type Options struct {
 	LocalUIEnabled          bool
 	ResolveSessionMeta      func(channelID string) (*session.Meta, bool)
 	ResolveSessionTunnelURL func(channelID string) (string, bool)
+
+	// SecretsBackend selects where provider API keys are read from. Nil keeps secrets.json in StateDir.
+	SecretsBackend *config.SecretsBackendConfig
 }
 
 type Service struct {
@@ -193,20 +197,22 @@ func New(ctx context.Context, opts Options) (*Service, error) {
 		runtime:      runtimeMgr,
 	}
 
-	secrets := settings.NewSecretsStore(filepath.Join(stateAbs, "secrets.json"))
+	secrets, err := settings.NewSecretsBackend(opts.SecretsBackend, filepath.Join(stateAbs, "secrets.json"))
+	if err != nil {
+		_ = reg.Close()
+		_ = pfSvc.Close()
+		return nil, err
+	}
 
 	aiSvc, err := ai.NewService(ai.Options{
 		Logger:       logger,
 		StateDir:     stateAbs,
 		AgentHomeDir: agentHomeDir,
 		Shell:        strings.TrimSpace(opts.Shell),
+		AgentVersion: strings.TrimSpace(opts.AgentVersion),
 		Config:       opts.AIConfig,
-		ResolveProviderAPIKey: func(providerID string) (string, bool, error) {
-			return secrets.GetAIProviderAPIKey(providerID)
-		},
-		ResolveWebSearchProviderAPIKey: func(providerID string) (string, bool, error) {
-			return secrets.GetWebSearchProviderAPIKey(providerID)
-		},
+		Secrets:      secrets,
+		Audit:        opts.Audit,
 	})
 	if err != nil {
 		_ = reg.Close()
@@ -284,7 +290,7 @@ func New(ctx context.Context, opts Options) (*Service, error) {
 		ResolveSessionMeta:      opts.ResolveSessionMeta,
 		ResolveSessionTunnelURL: opts.ResolveSessionTunnelURL,
 		ConfigPath:              strings.TrimSpace(opts.ConfigPath),
-		SecretsStore:            secrets,
+		Secrets:                 secrets,
 		ThreadReadStateStore:    threadReadStateStore,
 		ListenAddr:              "127.0.0.1:0",
 	})
@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/floegence/redeven/internal/ai"
+	"github.com/floegence/redeven/internal/session"
+)
+
+const (
+	aiRunEventStreamRetry     = 3 * time.Second
+	aiRunEventStreamHeartbeat = 15 * time.Second
+	aiRunEventStreamPageLimit = 500
+)
+
+// handleAIRunEventStream pushes persisted run events over SSE until the run ends or the client disconnects.
+//
+// Each event carries its event_id as the SSE id, so a reconnecting client resumes from Last-Event-ID.
+func (g *Gateway) handleAIRunEventStream(w http.ResponseWriter, r *http.Request, meta *session.Meta, runID string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, apiResp{OK: false, Error: "streaming not supported"})
+		return
+	}
+	cursor := int64(0)
+	rawCursor := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if rawCursor == "" {
+		rawCursor = strings.TrimSpace(r.URL.Query().Get("cursor"))
+	}
+	if rawCursor != "" {
+		v, err := strconv.ParseInt(rawCursor, 10, 64)
+		if err != nil || v < 0 {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid cursor"})
+			return
+		}
+		cursor = v
+	}
+	// Subscribe before the first read so events persisted in between still wake the loop.
+	signal, stop, err := g.ai.WatchRunEvents(meta, runID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+		return
+	}
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Connection", "keep-alive")
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", aiRunEventStreamRetry.Milliseconds()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	// drain writes every event after cursor and reports whether the run has ended.
+	drain := func() (bool, error) {
+		for {
+			page, err := g.ai.ListRunEventsWithQuery(r.Context(), meta, runID, ai.ListRunEventsQuery{Cursor: cursor, Limit: aiRunEventStreamPageLimit})
+			if err != nil {
+				return false, err
+			}
+			ended := false
+			for _, ev := range page.Events {
+				if err := writeAIRunSSEEvent(w, ev); err != nil {
+					return false, err
+				}
+				if ev.EventID > cursor {
+					cursor = ev.EventID
+				}
+				if ev.EventType == "run.end" || ev.EventType == "run.error" {
+					ended = true
+				}
+			}
+			if len(page.Events) > 0 {
+				flusher.Flush()
+			}
+			if ended {
+				return true, nil
+			}
+			if !page.HasMore {
+				return false, nil
+			}
+			if page.NextCursor > cursor {
+				cursor = page.NextCursor
+			}
+		}
+	}
+
+	if ended, err := drain(); err != nil || ended {
+		return
+	}
+	heartbeat := time.NewTicker(aiRunEventStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-signal:
+			if ended, err := drain(); err != nil || ended {
+				return
+			}
+		case <-heartbeat.C:
+			if !g.ai.IsRunActive(runID) {
+				// The run is gone without a terminal event in the store (for example a run from a previous process).
+				_, _ = drain()
+				return
+			}
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeAIRunSSEEvent(w io.Writer, ev ai.RunEventView) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	eventType := strings.NewReplacer("\r", "", "\n", "").Replace(ev.EventType)
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.EventID, eventType, b)
+	return err
+}
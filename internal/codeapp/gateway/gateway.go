@@ -21,6 +21,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/floegence/redeven/internal/ai"
@@ -59,9 +60,9 @@ type Options struct {
 	// ConfigPath is the absolute path to the runtime config file.
 	// It is used to read and persist settings updates initiated from the Env App UI.
 	ConfigPath string
-	// SecretsStore holds user-managed secrets (such as AI provider API keys).
-	// If nil, the gateway will derive a default secrets path from ConfigPath.
-	SecretsStore *settings.SecretsStore
+	// Secrets holds user-managed secrets (such as AI provider API keys).
+	// If nil, the gateway will use secrets.json next to ConfigPath.
+	Secrets settings.SecretsBackend
 	// ThreadReadStateStore persists per-user per-surface thread read watermarks.
 	ThreadReadStateStore *threadreadstate.Store
 }
@@ -161,9 +162,12 @@ type Gateway struct {
 	configPath         string
 	stateDir           string
 	localPermissionCap *config.PermissionSet
-	configMu           sync.Mutex
-	secrets            *settings.SecretsStore
-	threadReadState    *threadreadstate.Store
+	// rateLimits is the current gateway_rate_limit policy, refreshed on every config save; nil uses the defaults.
+	rateLimits      atomic.Pointer[config.GatewayRateLimitPolicy]
+	rateLimiter     *rateLimiter
+	configMu        sync.Mutex
+	secrets         settings.SecretsBackend
+	threadReadState *threadreadstate.Store
 
 	distFS fs.FS
 	dist   http.Handler
@@ -245,7 +249,7 @@ func New(opts Options) (*Gateway, error) {
 	// leading "/" (avoids FileServer canonicalization redirects).
 	dist := http.StripPrefix("/_redeven_proxy", http.FileServer(http.FS(opts.DistFS)))
 
-	secrets := opts.SecretsStore
+	secrets := opts.Secrets
 	if secrets == nil {
 		// Keep user-managed secrets in the same state dir as config.json.
 		dir := filepath.Dir(strings.TrimSpace(opts.ConfigPath))
@@ -259,7 +263,7 @@ func New(opts Options) (*Gateway, error) {
 		localFloeAppAgent,
 		config.PermissionSet{Read: true, Write: false, Execute: true},
 	)
-	return &Gateway{
+	g := &Gateway{
 		log:                     logger,
 		backend:                 opts.Backend,
 		pf:                      opts.PortForward,
@@ -275,12 +279,15 @@ func New(opts Options) (*Gateway, error) {
 		configPath:              strings.TrimSpace(opts.ConfigPath),
 		stateDir:                stateDir,
 		localPermissionCap:      &localPermissionCap,
+		rateLimiter:             newRateLimiter(),
 		secrets:                 secrets,
 		threadReadState:         opts.ThreadReadStateStore,
 		distFS:                  opts.DistFS,
 		dist:                    dist,
 		addr:                    addr,
-	}, nil
+	}
+	g.rateLimits.Store(config.ResolveGatewayRateLimitFromConfigPath(g.configPath))
+	return g, nil
 }
 
 func (g *Gateway) Start(ctx context.Context) error {
@@ -511,6 +518,12 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// writeTooManyRuns writes HTTP 429 with Retry-After for a run rejected by ai.run_concurrency.
+func writeTooManyRuns(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(ai.RunLimitRetryAfter/time.Second)))
+	writeJSON(w, http.StatusTooManyRequests, apiResp{OK: false, Error: ai.ErrTooManyRuns.Error(), ErrorCode: "too_many_runs"})
+}
+
 func (g *Gateway) handleAPIWithDiagnostics(w http.ResponseWriter, r *http.Request, localUI bool) {
 	if g != nil && g.diag != nil {
 		w.Header().Set(diagnostics.EnabledHeader, strconv.FormatBool(g.diag.Enabled()))
@@ -583,6 +596,14 @@ func writeCodexError(w http.ResponseWriter, err error) {
 	writeJSON(w, status, apiResp{OK: false, Error: err.Error(), ErrorCode: errorCode})
 }
 
+// aiBackgroundRunView is returned when a run is started with options.background.
+type aiBackgroundRunView struct {
+	RunID    string `json:"run_id"`
+	ThreadID string `json:"thread_id"`
+	// EventsURL is the SSE stream that replays and follows the run's events.
+	EventsURL string `json:"events_url"`
+}
+
 type diagnosticsView struct {
 	Enabled      bool                      `json:"enabled"`
 	StateDir     string                    `json:"state_dir,omitempty"`
@@ -607,6 +628,22 @@ type diagnosticsStreamEvent struct {
 
 var diagnosticsStreamPollInterval = 750 * time.Millisecond
 
+// parseAIUsageSince accepts unix milliseconds or an RFC 3339 timestamp.
+func parseAIUsageSince(raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		if v < 0 {
+			return 0, errors.New("invalid since")
+		}
+		return v, nil
+	}
+	at, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return 0, errors.New("invalid since")
+	}
+	return at.UnixMilli(), nil
+}
+
 func parseDiagnosticsLimit(r *http.Request, key string, defaultValue int, maxValue int) int {
 	if defaultValue <= 0 {
 		defaultValue = 100
@@ -890,7 +927,15 @@ func writeCodexSSEEvent(w io.Writer, ev codexbridge.Event) error {
 	return err
 }
 
-func toSettingsView(cfg *config.Config, configPath string, secrets *settings.SecretsStore) settingsView {
+// secretsPatchErrorStatus maps a failed key patch to 409 for read-only secrets backends and 400 otherwise.
+func secretsPatchErrorStatus(err error) int {
+	if errors.Is(err, settings.ErrSecretsBackendReadOnly) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func toSettingsView(cfg *config.Config, configPath string, secrets settings.SecretsBackend) settingsView {
 	var direct settingsDirectView
 	if cfg != nil && cfg.Direct != nil {
 		direct = settingsDirectView{
@@ -981,6 +1026,11 @@ func (g *Gateway) updateConfigLocked(mut func(cfg *config.Config) error) (*confi
 	if err := config.Save(path, cfg); err != nil {
 		return nil, err
 	}
+	g.rateLimits.Store(cfg.GatewayRateLimit)
+	// History is a recovery aid; a failed snapshot must not fail the update that already landed.
+	if _, err := config.SaveRevision(path, cfg, config.DefaultConfigHistoryMaxRevisions); err != nil && g.log != nil {
+		g.log.Warn("config revision snapshot failed", "error", err)
+	}
 	return cfg, nil
 }
 
@@ -1156,6 +1206,18 @@ func auditURLHost(raw string) (scheme string, host string) {
 	return strings.TrimSpace(u.Scheme), strings.TrimSpace(u.Host)
 }
 
+// auditModelRejected records a model the namespace allowlist rejected (ai.model_allowlist).
+func (g *Gateway) auditModelRejected(meta *session.Meta, operation string, modelID string, err error) {
+	if meta == nil {
+		return
+	}
+	g.appendAudit(meta, "ai_model_rejected", "failure", map[string]any{
+		"operation":           operation,
+		"model_id":            strings.TrimSpace(modelID),
+		"namespace_public_id": strings.TrimSpace(meta.NamespacePublicID),
+	}, err)
+}
+
 func (g *Gateway) appendAudit(meta *session.Meta, action string, status string, detail map[string]any, err error) {
 	if g == nil || g.audit == nil || meta == nil {
 		return
@@ -1201,12 +1263,18 @@ func (g *Gateway) appendAudit(meta *session.Meta, action string, status string,
 }
 
 func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
+	if !g.enforceRateLimit(w, r) {
+		return
+	}
 	if g.handleWorkbenchLayoutAPI(w, r) {
 		return
 	}
 	if g.handleNotesAPI(w, r) {
 		return
 	}
+	if g.handleSettingsHistoryAPI(w, r) {
+		return
+	}
 	switch {
 	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/audit/logs":
 		if _, ok := g.requirePermission(w, r, requiredPermissionAdmin); !ok {
@@ -1222,12 +1290,31 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 				limit = v
 			}
 		}
-		entries, err := g.audit.List(limit)
+		before, err := auditlog.ParseCursor(r.URL.Query().Get("before"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid before cursor"})
+			return
+		}
+		after, err := auditlog.ParseCursor(r.URL.Query().Get("after"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid after cursor"})
+			return
+		}
+		page, err := g.audit.ListPage(auditlog.ListQuery{Limit: limit, Before: before, After: after})
 		if err != nil {
+			if errors.Is(err, auditlog.ErrConflictingCursors) {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+				return
+			}
 			writeJSON(w, http.StatusInternalServerError, apiResp{OK: false, Error: "failed to read audit log"})
 			return
 		}
-		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: map[string]any{"entries": entries}})
+		w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: map[string]any{
+			"entries":     page.Entries,
+			"next_cursor": page.NextCursor,
+			"total":       page.Total,
+		}})
 		return
 
 	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/debug/diagnostics":
@@ -2131,7 +2218,7 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 
 		if err := g.secrets.ApplyAIProviderAPIKeyPatches(converted); err != nil {
 			g.appendAudit(meta, "ai_provider_key_update", "failure", map[string]any{"providers": touched}, err)
-			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+			writeJSON(w, secretsPatchErrorStatus(err), apiResp{OK: false, Error: err.Error()})
 			return
 		}
 
@@ -2146,6 +2233,68 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: map[string]any{"provider_api_key_set": set}})
 		return
 
+	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/ai/providers/status":
+		meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+		if !ok {
+			return
+		}
+		if g.ai == nil || !g.ai.Enabled() {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai not configured"})
+			return
+		}
+		providers, err := g.ai.ProviderCircuitStatus()
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: err.Error()})
+			return
+		}
+		runs, err := g.ai.RunConcurrencyStatus(meta)
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: map[string]any{"providers": providers, "runs": runs}})
+		return
+
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/_redeven_proxy/api/ai/providers/") && strings.HasSuffix(r.URL.Path, "/test"):
+		meta, ok := g.requirePermission(w, r, requiredPermissionAdmin)
+		if !ok {
+			return
+		}
+		if g.ai == nil || !g.ai.Enabled() {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai not configured"})
+			return
+		}
+		providerID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/_redeven_proxy/api/ai/providers/"), "/test")
+		if strings.TrimSpace(providerID) == "" || strings.Contains(providerID, "/") {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid provider id"})
+			return
+		}
+		result, err := g.ai.TestProvider(r.Context(), providerID)
+		if err != nil {
+			if errors.Is(err, ai.ErrProviderNotFound) {
+				writeJSON(w, http.StatusNotFound, apiResp{OK: false, Error: "provider not found"})
+				return
+			}
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: err.Error()})
+			return
+		}
+		auditDetail := map[string]any{
+			"provider_id":        result.ProviderID,
+			"provider_type":      result.ProviderType,
+			"model":              result.Model,
+			"latency_ms":         result.LatencyMS,
+			"strict_tool_schema": result.StrictToolSchema,
+		}
+		if result.OK {
+			g.appendAudit(meta, "ai_provider_test", "success", auditDetail, nil)
+		} else {
+			auditDetail["error_code"] = result.ErrorCode
+			// result.Error is already sanitized; the raw provider error never reaches the audit log.
+			g.appendAudit(meta, "ai_provider_test", "failure", auditDetail, errors.New(result.Error))
+		}
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: map[string]any{"result": result}})
+		return
+
 	case r.Method == http.MethodPost && r.URL.Path == "/_redeven_proxy/api/ai/web_search_provider_keys/status":
 		if _, ok := g.requirePermission(w, r, requiredPermissionRead); !ok {
 			return
@@ -2220,7 +2369,7 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 
 		if err := g.secrets.ApplyWebSearchProviderAPIKeyPatches(converted); err != nil {
 			g.appendAudit(meta, "web_search_provider_key_update", "failure", map[string]any{"providers": touched}, err)
-			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+			writeJSON(w, secretsPatchErrorStatus(err), apiResp{OK: false, Error: err.Error()})
 			return
 		}
 
@@ -2334,14 +2483,48 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
 			return
 		}
-		catalog, err := g.ai.CreateSkill(body.Scope, body.Name, body.Description, body.Body)
+		out, err := g.ai.CreateSkill(body.Scope, body.Name, body.Description, body.Body)
 		if err != nil {
 			g.appendAudit(meta, "ai_skills_create", "failure", map[string]any{"scope": strings.TrimSpace(body.Scope), "name": strings.TrimSpace(body.Name)}, err)
 			writeAISkillError(w, http.StatusBadRequest, err)
 			return
 		}
-		g.appendAudit(meta, "ai_skills_create", "success", map[string]any{"scope": strings.TrimSpace(body.Scope), "name": strings.TrimSpace(body.Name), "catalog_version": catalog.CatalogVersion}, nil)
-		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: catalog})
+		g.appendAudit(meta, "ai_skills_create", "success", map[string]any{"scope": strings.TrimSpace(body.Scope), "name": strings.TrimSpace(body.Name), "catalog_version": out.CatalogVersion, "warnings": len(out.Warnings)}, nil)
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+		return
+
+	case r.Method == http.MethodPost && r.URL.Path == "/_redeven_proxy/api/ai/skills/validate":
+		meta, ok := g.requirePermission(w, r, requiredPermissionAdmin)
+		if !ok {
+			return
+		}
+		if g.ai == nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+			return
+		}
+		var body struct {
+			Scope string `json:"scope"`
+			Name  string `json:"name"`
+			Body  string `json:"body"`
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
+			return
+		}
+		if err := dec.Decode(&struct{}{}); err != io.EOF {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
+			return
+		}
+		out, err := g.ai.ValidateSkill(body.Scope, body.Name, body.Body)
+		if err != nil {
+			g.appendAudit(meta, "ai_skills_validate", "failure", map[string]any{"scope": strings.TrimSpace(body.Scope), "name": strings.TrimSpace(body.Name)}, err)
+			writeAISkillError(w, http.StatusBadRequest, err)
+			return
+		}
+		g.appendAudit(meta, "ai_skills_validate", "success", map[string]any{"scope": strings.TrimSpace(body.Scope), "name": strings.TrimSpace(body.Name), "valid": out.Valid, "errors": len(out.Errors), "warnings": len(out.Warnings)}, nil)
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
 		return
 
 	case r.Method == http.MethodDelete && r.URL.Path == "/_redeven_proxy/api/ai/skills":
@@ -2423,13 +2606,26 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
 			return
 		}
-		out, err := g.ai.ValidateGitHubSkillImport(body)
+		preview := false
+		if raw := strings.TrimSpace(r.URL.Query().Get("preview")); raw != "" {
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid preview"})
+				return
+			}
+			preview = v
+		}
+		validate := g.ai.ValidateGitHubSkillImport
+		if preview {
+			validate = g.ai.PreviewGitHubSkillImport
+		}
+		out, err := validate(body)
 		if err != nil {
-			g.appendAudit(meta, "ai_skills_github_validate", "failure", map[string]any{"scope": strings.TrimSpace(body.Scope), "repo": strings.TrimSpace(body.Repo), "ref": strings.TrimSpace(body.Ref), "paths": len(body.Paths), "url": strings.TrimSpace(body.URL) != ""}, err)
+			g.appendAudit(meta, "ai_skills_github_validate", "failure", map[string]any{"scope": strings.TrimSpace(body.Scope), "repo": strings.TrimSpace(body.Repo), "ref": strings.TrimSpace(body.Ref), "paths": len(body.Paths), "url": strings.TrimSpace(body.URL) != "", "preview": preview}, err)
 			writeAISkillError(w, http.StatusBadRequest, err)
 			return
 		}
-		g.appendAudit(meta, "ai_skills_github_validate", "success", map[string]any{"scope": strings.TrimSpace(body.Scope), "repo": strings.TrimSpace(body.Repo), "ref": strings.TrimSpace(body.Ref), "resolved": len(out.Resolved)}, nil)
+		g.appendAudit(meta, "ai_skills_github_validate", "success", map[string]any{"scope": strings.TrimSpace(body.Scope), "repo": strings.TrimSpace(body.Repo), "ref": strings.TrimSpace(body.Ref), "resolved": len(out.Resolved), "preview": preview}, nil)
 		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
 		return
 
@@ -2463,6 +2659,70 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
 		return
 
+	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/ai/skills/export":
+		meta, ok := g.requirePermission(w, r, requiredPermissionRead)
+		if !ok {
+			return
+		}
+		if g.ai == nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+			return
+		}
+		// Buffer the archive so a failure can still be reported as JSON with a proper status.
+		var buf bytes.Buffer
+		count, err := g.ai.ExportSkillsArchive(&buf)
+		if err != nil {
+			g.appendAudit(meta, "ai_skills_export", "failure", nil, err)
+			writeAISkillError(w, http.StatusInternalServerError, err)
+			return
+		}
+		g.appendAudit(meta, "ai_skills_export", "success", map[string]any{"skills": count, "bytes": buf.Len()}, nil)
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "redeven-skills.tar.gz"))
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(buf.Bytes())
+		return
+
+	case r.Method == http.MethodPost && r.URL.Path == "/_redeven_proxy/api/ai/skills/import/archive":
+		meta, ok := g.requirePermission(w, r, requiredPermissionAdmin)
+		if !ok {
+			return
+		}
+		if g.ai == nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+			return
+		}
+		scope := strings.TrimSpace(r.URL.Query().Get("scope"))
+		overwrite := false
+		switch strings.ToLower(strings.TrimSpace(r.URL.Query().Get("overwrite"))) {
+		case "1", "true", "yes", "y", "on":
+			overwrite = true
+		}
+		archive, err := io.ReadAll(http.MaxBytesReader(w, r.Body, ai.MaxSkillArchiveBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, apiResp{OK: false, Error: fmt.Sprintf("archive exceeds %d bytes", ai.MaxSkillArchiveBytes)})
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "failed to read archive"})
+			return
+		}
+		if len(archive) == 0 {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "missing archive"})
+			return
+		}
+		out, err := g.ai.ImportSkillsArchive(bytes.NewReader(archive), scope, overwrite)
+		if err != nil {
+			g.appendAudit(meta, "ai_skills_archive_import", "failure", map[string]any{"scope": scope, "overwrite": overwrite, "bytes": len(archive)}, err)
+			writeAISkillError(w, http.StatusBadRequest, err)
+			return
+		}
+		g.appendAudit(meta, "ai_skills_archive_import", "success", map[string]any{"scope": scope, "overwrite": overwrite, "skills": len(out.Imports), "catalog_version": out.Catalog.CatalogVersion}, nil)
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+		return
+
 	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/ai/skills/sources":
 		meta, ok := g.requirePermission(w, r, requiredPermissionRead)
 		if !ok {
@@ -2570,14 +2830,15 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 		return
 
 	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/ai/models":
-		if _, ok := g.requirePermission(w, r, requiredPermissionFull); !ok {
+		meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+		if !ok {
 			return
 		}
 		if g.ai == nil || !g.ai.Enabled() {
 			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai not configured"})
 			return
 		}
-		models, err := g.ai.ListModels()
+		models, err := g.ai.ListModels(meta)
 		if err != nil {
 			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: err.Error()})
 			return
@@ -2630,7 +2891,7 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
 			return
 		}
-		models, err := g.ai.ListModels()
+		models, err := g.ai.ListModels(meta)
 		if err != nil {
 			g.appendAudit(meta, "ai_current_model_update", "failure", map[string]any{"model_id": modelID}, err)
 			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: err.Error()})
@@ -2670,6 +2931,68 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: map[string]any{"working_dir": cleaned}})
 		return
 
+	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/ai/usage":
+		meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+		if !ok {
+			return
+		}
+		if g.ai == nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+			return
+		}
+		query := ai.ListUsageQuery{}
+		if raw := strings.TrimSpace(r.URL.Query().Get("since")); raw != "" {
+			since, err := parseAIUsageSince(raw)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			query.SinceUnixMs = since
+		}
+		if raw := strings.TrimSpace(r.URL.Query().Get("cursor")); raw != "" {
+			if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				query.Cursor = v
+			}
+		}
+		if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+			if v, err := strconv.Atoi(raw); err == nil {
+				query.Limit = v
+			}
+		}
+		out, err := g.ai.ListUsagePage(r.Context(), meta, query)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+		return
+
+	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/ai/stats/finalization":
+		meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+		if !ok {
+			return
+		}
+		if g.ai == nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+			return
+		}
+		var since int64
+		if raw := strings.TrimSpace(r.URL.Query().Get("since")); raw != "" {
+			v, err := parseAIUsageSince(raw)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			since = v
+		}
+		out, err := g.ai.FinalizationStats(r.Context(), meta, since)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+		return
+
 	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/ai/threads":
 		meta, ok := g.requirePermission(w, r, requiredPermissionFull)
 		if !ok {
@@ -2701,6 +3024,36 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: view})
 		return
 
+	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/ai/search":
+		meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+		if !ok {
+			return
+		}
+		if g.ai == nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+			return
+		}
+
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		if query == "" {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "missing q"})
+			return
+		}
+		limit := 20
+		if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+			if v, err := strconv.Atoi(raw); err == nil {
+				limit = v
+			}
+		}
+
+		out, err := g.ai.SearchThreadMessages(r.Context(), meta, query, limit)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+		return
+
 	case r.Method == http.MethodPost && r.URL.Path == "/_redeven_proxy/api/ai/threads":
 		meta, ok := g.requirePermission(w, r, requiredPermissionFull)
 		if !ok {
@@ -2724,7 +3077,12 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 
 		th, err := g.ai.CreateThread(r.Context(), meta, body.Title, body.ModelID, body.ExecutionMode, body.WorkingDir)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+			status := http.StatusBadRequest
+			if errors.Is(err, ai.ErrModelNotAllowedForNamespace) {
+				g.auditModelRejected(meta, "create_thread", body.ModelID, err)
+				status = http.StatusForbidden
+			}
+			writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
 			return
 		}
 		view, err := g.buildAIThreadEnvelope(r.Context(), meta, th)
@@ -2823,6 +3181,9 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 						status = http.StatusNotFound
 					} else if errors.Is(err, ai.ErrModelSwitchRequiresExplicitRestart) || errors.Is(err, ai.ErrModelLockViolation) {
 						status = http.StatusConflict
+					} else if errors.Is(err, ai.ErrModelNotAllowedForNamespace) {
+						g.auditModelRejected(meta, "set_thread_model", *body.ModelID, err)
+						status = http.StatusForbidden
 					}
 					writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
 					return
@@ -2906,14 +3267,22 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 				writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
 				return
 			}
-			force := false
-			if raw := strings.TrimSpace(r.URL.Query().Get("force")); raw != "" {
-				if raw == "1" || strings.EqualFold(raw, "true") {
-					force = true
-				}
+			// Deletion archives the thread by default so its history is kept; purge=1 removes it for good.
+			queryFlag := func(name string) bool {
+				raw := strings.TrimSpace(r.URL.Query().Get(name))
+				return raw == "1" || strings.EqualFold(raw, "true")
 			}
+			force := queryFlag("force")
+			purge := queryFlag("purge")
+			auditDetail := map[string]any{"thread_id": threadID, "purge": purge}
+			canceledRuns := 0
 			if err := g.deleteFlowerThreadWithReadStateCleanup(r.Context(), meta, threadID, func() error {
-				return g.ai.DeleteThread(r.Context(), meta, threadID, force)
+				if purge {
+					return g.ai.DeleteThread(r.Context(), meta, threadID, force)
+				}
+				n, err := g.ai.ArchiveThread(r.Context(), meta, threadID)
+				canceledRuns = n
+				return err
 			}); err != nil {
 				status := http.StatusBadRequest
 				var cleanupErr flowerThreadDeleteCleanupError
@@ -2924,12 +3293,14 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 				} else if errors.Is(err, sql.ErrNoRows) {
 					status = http.StatusNotFound
 				}
-				g.appendAudit(meta, "ai_thread_delete", "failure", map[string]any{"thread_id": threadID}, err)
+				auditDetail["canceled_runs"] = canceledRuns
+				g.appendAudit(meta, "ai_thread_delete", "failure", auditDetail, err)
 				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
 				return
 			}
-			g.appendAudit(meta, "ai_thread_delete", "success", map[string]any{"thread_id": threadID}, nil)
-			writeJSON(w, http.StatusOK, apiResp{OK: true})
+			auditDetail["canceled_runs"] = canceledRuns
+			g.appendAudit(meta, "ai_thread_delete", "success", auditDetail, nil)
+			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: map[string]any{"archived": !purge, "canceled_runs": canceledRuns}})
 			return
 
 		case action == "todos" && r.Method == http.MethodGet:
@@ -2953,6 +3324,142 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: map[string]any{"todos": out}})
 			return
 
+		case action == "fork" && r.Method == http.MethodPost:
+			meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+			if !ok {
+				return
+			}
+			if g.ai == nil {
+				writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+				return
+			}
+			dec := json.NewDecoder(r.Body)
+			dec.DisallowUnknownFields()
+			var body ai.ForkThreadRequest
+			if err := dec.Decode(&body); err != nil {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
+				return
+			}
+			if err := dec.Decode(&struct{}{}); err != io.EOF {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
+				return
+			}
+			auditDetail := map[string]any{"thread_id": threadID, "message_id": strings.TrimSpace(body.MessageID)}
+			th, err := g.ai.ForkThread(r.Context(), meta, threadID, body.MessageID)
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, sql.ErrNoRows) {
+					status = http.StatusNotFound
+				}
+				g.appendAudit(meta, "ai_thread_fork", "failure", auditDetail, err)
+				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			auditDetail["forked_thread_id"] = th.ThreadID
+			g.appendAudit(meta, "ai_thread_fork", "success", auditDetail, nil)
+			view, err := g.buildAIThreadEnvelope(r.Context(), meta, th)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: view})
+			return
+
+		case action == "compact" && r.Method == http.MethodPost:
+			meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+			if !ok {
+				return
+			}
+			if g.ai == nil {
+				writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+				return
+			}
+			dec := json.NewDecoder(r.Body)
+			dec.DisallowUnknownFields()
+			var body ai.CompactThreadRequest
+			if err := dec.Decode(&body); err != nil && err != io.EOF {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
+				return
+			}
+			if err := dec.Decode(&struct{}{}); err != io.EOF {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
+				return
+			}
+			auditDetail := map[string]any{"thread_id": threadID, "strategy": strings.TrimSpace(body.Strategy), "target_tokens": body.TargetTokens}
+			out, err := g.ai.CompactThread(r.Context(), meta, threadID, body.TargetTokens, body.Strategy)
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, ai.ErrThreadBusy) {
+					status = http.StatusConflict
+				} else if errors.Is(err, sql.ErrNoRows) {
+					status = http.StatusNotFound
+				}
+				g.appendAudit(meta, "ai_thread_compact", "failure", auditDetail, err)
+				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			auditDetail["before_tokens"] = out.BeforeTokens
+			auditDetail["after_tokens"] = out.AfterTokens
+			g.appendAudit(meta, "ai_thread_compact", "success", auditDetail, nil)
+			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+			return
+
+		case action == "retitle" && r.Method == http.MethodPost:
+			meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+			if !ok {
+				return
+			}
+			if g.ai == nil {
+				writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+				return
+			}
+			auditDetail := map[string]any{"thread_id": threadID}
+			th, err := g.ai.RetitleThread(r.Context(), meta, threadID)
+			if err == nil && th == nil {
+				err = sql.ErrNoRows
+			}
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, ai.ErrThreadTitleLocked) || errors.Is(err, ai.ErrThreadTitleChanged) {
+					status = http.StatusConflict
+				} else if errors.Is(err, sql.ErrNoRows) {
+					status = http.StatusNotFound
+				}
+				g.appendAudit(meta, "ai_thread_retitle", "failure", auditDetail, err)
+				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			auditDetail["title"] = th.Title
+			g.appendAudit(meta, "ai_thread_retitle", "success", auditDetail, nil)
+			view, err := g.buildAIThreadEnvelope(r.Context(), meta, th)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: view})
+			return
+
+		case action == "usage" && r.Method == http.MethodGet:
+			meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+			if !ok {
+				return
+			}
+			if g.ai == nil {
+				writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai service not ready"})
+				return
+			}
+			out, err := g.ai.GetThreadUsage(r.Context(), meta, threadID)
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, sql.ErrNoRows) {
+					status = http.StatusNotFound
+				}
+				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+			return
+
 		case action == "followups" && r.Method == http.MethodGet && len(parts) == 2:
 			meta, ok := g.requirePermission(w, r, requiredPermissionFull)
 			if !ok {
@@ -3086,6 +3593,24 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 					limit = v
 				}
 			}
+			if raw := strings.TrimSpace(r.URL.Query().Get("since_seq")); raw != "" {
+				sinceSeq, err := strconv.ParseInt(raw, 10, 64)
+				if err != nil || sinceSeq < 0 {
+					writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid since_seq"})
+					return
+				}
+				out, err := g.ai.ListThreadMessagesSince(r.Context(), meta, threadID, sinceSeq, limit)
+				if err != nil {
+					status := http.StatusBadRequest
+					if errors.Is(err, sql.ErrNoRows) {
+						status = http.StatusNotFound
+					}
+					writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
+					return
+				}
+				writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+				return
+			}
 			var beforeID int64
 			if raw := strings.TrimSpace(r.URL.Query().Get("before_id")); raw != "" {
 				if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
@@ -3095,7 +3620,11 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 
 			out, err := g.ai.ListThreadMessages(r.Context(), meta, threadID, limit, beforeID)
 			if err != nil {
-				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+				status := http.StatusBadRequest
+				if errors.Is(err, sql.ErrNoRows) {
+					status = http.StatusNotFound
+				}
+				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
 				return
 			}
 			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
@@ -3162,6 +3691,10 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "missing thread_id"})
 			return
 		}
+		if err := ai.ValidateRunOptions(req.Options); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+			return
+		}
 		if g.ai.HasActiveThreadForEndpoint(strings.TrimSpace(meta.EndpointID), strings.TrimSpace(req.ThreadID)) {
 			writeJSON(w, http.StatusConflict, apiResp{OK: false, Error: "thread already active"})
 			return
@@ -3180,7 +3713,7 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 			if m := strings.TrimSpace(th.ModelID); m != "" {
 				req.Model = m
 			} else {
-				models, err := g.ai.ListModels()
+				models, err := g.ai.ListModels(meta)
 				if err != nil {
 					writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: err.Error()})
 					return
@@ -3188,6 +3721,11 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 				req.Model = models.CurrentModel
 			}
 		}
+		if err := g.ai.CheckModelAllowed(meta, req.Model); err != nil {
+			g.auditModelRejected(meta, "start_run", req.Model, err)
+			writeJSON(w, http.StatusForbidden, apiResp{OK: false, Error: err.Error()})
+			return
+		}
 
 		runID, err := ai.NewRunID()
 		if err != nil {
@@ -3195,6 +3733,43 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if req.Options.Background {
+			auditDetail := map[string]any{
+				"run_id":     runID,
+				"thread_id":  strings.TrimSpace(req.ThreadID),
+				"model":      strings.TrimSpace(req.Model),
+				"background": true,
+			}
+			if err := g.ai.StartRun(r.Context(), meta, runID, req, nil); err != nil {
+				g.log.Warn("ai background run failed to start", "channel_id", channelID, "run_id", runID, "error", err)
+				g.appendAudit(meta, "ai_run", "failure", auditDetail, err)
+				if errors.Is(err, ai.ErrTooManyRuns) {
+					writeTooManyRuns(w)
+					return
+				}
+				status := http.StatusBadRequest
+				if errors.Is(err, ai.ErrThreadBusy) || errors.Is(err, ai.ErrThreadArchived) {
+					status = http.StatusConflict
+				}
+				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			g.appendAudit(meta, "ai_run", "success", auditDetail, nil)
+			w.Header().Set("X-Redeven-AI-Run-ID", runID)
+			writeJSON(w, http.StatusAccepted, apiResp{OK: true, Data: aiBackgroundRunView{
+				RunID:     runID,
+				ThreadID:  strings.TrimSpace(req.ThreadID),
+				EventsURL: "/_redeven_proxy/api/ai/runs/" + url.PathEscape(runID) + "/events/stream",
+			}})
+			return
+		}
+
+		// The stream commits to 200 before StartRun, so surface a full run cap first.
+		if err := g.ai.CheckRunCapacity(meta); err != nil {
+			writeTooManyRuns(w)
+			return
+		}
+
 		// Stream response (NDJSON).
 		w.Header().Set("X-Redeven-AI-Run-ID", runID)
 		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
@@ -3218,7 +3793,12 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 		return
 
 	case (r.Method == http.MethodPost || r.Method == http.MethodGet) && strings.HasPrefix(r.URL.Path, "/_redeven_proxy/api/ai/runs/"):
-		meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+		perm := requiredPermissionFull
+		if r.Method == http.MethodPost && strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/cancel") {
+			// Cancelling only stops work that was already started, so execute permission is enough.
+			perm = requiredPermissionExecute
+		}
+		meta, ok := g.requirePermission(w, r, perm)
 		if !ok {
 			return
 		}
@@ -3295,7 +3875,7 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if r.Method == http.MethodPost && action == "cancel" {
-			meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+			meta, ok := g.requirePermission(w, r, requiredPermissionExecute)
 			if !ok {
 				return
 			}
@@ -3309,6 +3889,91 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if r.Method == http.MethodPost && action == "replay" {
+			var body struct {
+				Model string `json:"model"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
+				return
+			}
+			out, err := g.ai.ReplayRunWithModel(r.Context(), meta, runID, body.Model)
+			if err != nil {
+				g.appendAudit(meta, "ai_run_replay", "failure", map[string]any{
+					"run_id": runID,
+					"model":  strings.TrimSpace(body.Model),
+				}, err)
+				status := http.StatusBadRequest
+				switch {
+				case errors.Is(err, ai.ErrRunNotFound):
+					status = http.StatusNotFound
+				case errors.Is(err, ai.ErrThreadBusy):
+					status = http.StatusConflict
+				case errors.Is(err, ai.ErrTooManyRuns):
+					writeTooManyRuns(w)
+					return
+				}
+				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			g.appendAudit(meta, "ai_run_replay", "success", map[string]any{
+				"run_id":        runID,
+				"replay_run_id": out.RunID,
+				"thread_id":     out.ThreadID,
+				"source_model":  out.SourceModel,
+				"model":         out.Model,
+			}, nil)
+			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+			return
+		}
+
+		if r.Method == http.MethodGet && action == "export" {
+			opts := ai.RunExportOptions{}
+			switch strings.ToLower(strings.TrimSpace(r.URL.Query().Get("redact_paths"))) {
+			case "1", "true", "yes", "y", "on":
+				opts.RedactExternalPaths = true
+			}
+			out, err := g.ai.ExportRun(r.Context(), meta, runID, opts)
+			if err != nil {
+				g.appendAudit(meta, "ai_run_export", "failure", map[string]any{"run_id": runID}, err)
+				status := http.StatusBadRequest
+				if errors.Is(err, ai.ErrRunNotFound) {
+					status = http.StatusNotFound
+				}
+				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			g.appendAudit(meta, "ai_run_export", "success", map[string]any{
+				"run_id":         runID,
+				"thread_id":      out.Manifest.ThreadID,
+				"messages":       len(out.Messages),
+				"events":         len(out.Events),
+				"paths_redacted": opts.RedactExternalPaths,
+			}, nil)
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "redeven-run-"+runID+".json"))
+			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+			return
+		}
+
+		if r.Method == http.MethodGet && action == "system_prompt" {
+			out, err := g.ai.GetRunSystemPrompt(r.Context(), meta, runID)
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, ai.ErrRunNotFound) {
+					status = http.StatusNotFound
+				}
+				writeJSON(w, status, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, apiResp{OK: true, Data: out})
+			return
+		}
+
+		if r.Method == http.MethodGet && action == "events" && len(parts) == 3 && strings.TrimSpace(parts[2]) == "stream" {
+			g.handleAIRunEventStream(w, r, meta, runID)
+			return
+		}
+
 		if r.Method == http.MethodGet && action == "events" {
 			limit := 300
 			if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
@@ -3317,19 +3982,30 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 			cursor := int64(0)
-			if raw := strings.TrimSpace(r.URL.Query().Get("cursor")); raw != "" {
+			// since_seq is the event id to resume after, the same value as cursor.
+			for _, param := range []string{"cursor", "since_seq"} {
+				raw := strings.TrimSpace(r.URL.Query().Get(param))
+				if raw == "" {
+					continue
+				}
 				v, err := strconv.ParseInt(raw, 10, 64)
-				if err != nil || v < 0 {
-					writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid cursor"})
+				if err != nil || v < 0 || (cursor > 0 && v != cursor) {
+					writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid " + param})
 					return
 				}
 				cursor = v
 			}
+			var types []string
+			if raw := strings.TrimSpace(r.URL.Query().Get("types")); raw != "" {
+				types = strings.Split(raw, ",")
+			}
 			category := strings.TrimSpace(strings.ToLower(r.URL.Query().Get("category")))
 			out, err := g.ai.ListRunEventsWithQuery(r.Context(), meta, runID, ai.ListRunEventsQuery{
-				Cursor:   cursor,
-				Limit:    limit,
-				Category: category,
+				Cursor:     cursor,
+				Limit:      limit,
+				Category:   category,
+				Types:      types,
+				StreamKind: strings.TrimSpace(r.URL.Query().Get("kind")),
 			})
 			if err != nil {
 				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
@@ -3371,6 +4047,38 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if r.Method == http.MethodPost && action == "tool_result" {
+			meta, ok := g.requirePermission(w, r, requiredPermissionFull)
+			if !ok {
+				return
+			}
+			var body ai.ToolResultRequest
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "invalid json"})
+				return
+			}
+			if strings.TrimSpace(body.ToolID) == "" {
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: "missing tool_id"})
+				return
+			}
+			if err := g.ai.ProvideToolResult(meta, runID, body.ToolID, body.ExternalToolResult); err != nil {
+				g.appendAudit(meta, "ai_tool_result", "failure", map[string]any{
+					"run_id":  runID,
+					"tool_id": strings.TrimSpace(body.ToolID),
+					"status":  strings.TrimSpace(body.Status),
+				}, err)
+				writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+				return
+			}
+			g.appendAudit(meta, "ai_tool_result", "success", map[string]any{
+				"run_id":  runID,
+				"tool_id": strings.TrimSpace(body.ToolID),
+				"status":  strings.TrimSpace(body.Status),
+			}, nil)
+			writeJSON(w, http.StatusOK, apiResp{OK: true})
+			return
+		}
+
 		writeJSON(w, http.StatusNotFound, apiResp{OK: false, Error: "not found"})
 		return
 
@@ -3469,6 +4177,21 @@ func (g *Gateway) handleAPI(w http.ResponseWriter, r *http.Request) {
 		http.ServeContent(w, r, info.Name, st.ModTime(), f)
 		return
 
+	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/metrics":
+		if _, ok := g.requirePermission(w, r, requiredPermissionAdmin); !ok {
+			return
+		}
+		if g.ai == nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: "ai not configured"})
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := g.ai.WriteMetrics(w); err != nil && g.log != nil {
+			g.log.Warn("write metrics failed", "error", err)
+		}
+		return
+
 	case r.Method == http.MethodGet && r.URL.Path == "/_redeven_proxy/api/spaces":
 		if _, ok := g.requirePermission(w, r, requiredPermissionRead); !ok {
 			return
@@ -78,7 +78,7 @@ func TestGateway_AI_Permissions_RequireRWX(t *testing.T) {
 		t.Fatalf("New: %v", err)
 	}
 
-	assertForbidden := func(method string, path string) {
+	assertForbiddenWith := func(method string, path string, wantErr string) {
 		t.Helper()
 		req := httptest.NewRequest(method, path, nil)
 		req.Header.Set("Origin", envOrigin)
@@ -87,26 +87,39 @@ func TestGateway_AI_Permissions_RequireRWX(t *testing.T) {
 		if rr.Code != http.StatusForbidden {
 			t.Fatalf("%s %s status=%d body=%s", method, path, rr.Code, rr.Body.String())
 		}
-		if !strings.Contains(rr.Body.String(), "read/write/execute permission denied") {
+		if !strings.Contains(rr.Body.String(), wantErr) {
 			t.Fatalf("%s %s unexpected body=%s", method, path, rr.Body.String())
 		}
 	}
+	assertForbidden := func(method string, path string) {
+		t.Helper()
+		assertForbiddenWith(method, path, "read/write/execute permission denied")
+	}
 
 	// AI endpoints require RWX for the entire feature surface.
 	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/models")
+	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/providers/status")
 	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/threads")
 	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/threads")
 	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/threads/th_test")
 	assertForbidden(http.MethodPatch, "/_redeven_proxy/api/ai/threads/th_test")
 	assertForbidden(http.MethodDelete, "/_redeven_proxy/api/ai/threads/th_test")
 	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/threads/th_test/todos")
+	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/threads/th_test/usage")
+	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/threads/th_test/fork")
+	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/threads/th_test/compact")
+	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/threads/th_test/retitle")
+	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/usage")
 	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/threads/th_test/messages")
 	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/threads/th_test/messages")
 	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/runs")
 	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/runs/run_test/events")
-	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/runs/run_test/cancel")
 	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/runs/run_test/tool_approvals")
+	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/runs/run_test/replay")
 	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/runs/run_test/tools/tool_test/output")
 	assertForbidden(http.MethodPost, "/_redeven_proxy/api/ai/uploads")
 	assertForbidden(http.MethodGet, "/_redeven_proxy/api/ai/uploads/upload_test")
+
+	// Cancelling a run only needs execute permission.
+	assertForbiddenWith(http.MethodPost, "/_redeven_proxy/api/ai/runs/run_test/cancel", "execute permission denied")
 }
@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/floegence/redeven/internal/ai"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestGateway_AI_ProviderCircuitStatus(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	stateDir := t.TempDir()
+
+	cfg := &config.AIConfig{
+		Providers: []config.AIProvider{
+			{ID: "openai", Name: "OpenAI", Type: "openai", Models: []config.AIProviderModel{{ModelName: "gpt-5-mini"}}},
+			{ID: "local", Type: "ollama", Models: []config.AIProviderModel{{ModelName: "qwen3"}}},
+		},
+	}
+
+	channelID := "ch_test_ai_provider_status_1"
+	envOrigin := envOriginWithChannel(channelID)
+	resolveMeta := resolveMetaForTest(channelID, session.Meta{
+		EndpointID:        "env_123",
+		NamespacePublicID: "ns_test",
+		UserPublicID:      "u_test",
+		UserEmail:         "u_test@example.com",
+		CanRead:           true,
+		CanWrite:          true,
+		CanExecute:        true,
+	})
+
+	aiSvc, err := ai.NewService(ai.Options{
+		Logger:       logger,
+		StateDir:     stateDir,
+		AgentHomeDir: stateDir,
+		Shell:        "bash",
+		Config:       cfg,
+		ResolveProviderAPIKey: func(string) (string, bool, error) {
+			return "sk-test", true, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ai.NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = aiSvc.Close() })
+
+	gw, err := New(Options{
+		Logger:             logger,
+		Backend:            &stubBackend{},
+		DistFS:             fstest.MapFS{"env/index.html": {Data: []byte("<html>env</html>")}, "inject.js": {Data: []byte("console.log('inject');")}},
+		ListenAddr:         "127.0.0.1:0",
+		ConfigPath:         writeTestConfigWithAI(t),
+		ResolveSessionMeta: resolveMeta,
+		AI:                 aiSvc,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/providers/status", nil)
+	req.Header.Set("Origin", envOrigin)
+	rr := httptest.NewRecorder()
+	gw.serveHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		OK   bool `json:"ok"`
+		Data struct {
+			Providers []ai.ProviderCircuitStatus `json:"providers"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !resp.OK || len(resp.Data.Providers) != 2 {
+		t.Fatalf("unexpected response: %s", rr.Body.String())
+	}
+	if got := resp.Data.Providers[0]; got.ProviderID != "openai" || got.State != ai.ProviderCircuitClosed || got.FailureThreshold != 5 {
+		t.Fatalf("providers[0]=%+v", got)
+	}
+	if got := resp.Data.Providers[1]; got.ProviderID != "local" || got.ProviderType != "ollama" || got.State != ai.ProviderCircuitClosed {
+		t.Fatalf("providers[1]=%+v", got)
+	}
+}
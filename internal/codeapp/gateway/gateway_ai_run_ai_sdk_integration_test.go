@@ -278,6 +278,7 @@ func TestGateway_AI_Run_UsesNativeSDKAndPersistsAssistantMessage(t *testing.T) {
 	}
 
 	// Run.
+	var runID string
 	{
 		body := map[string]any{
 			"thread_id": threadID,
@@ -293,7 +294,7 @@ func TestGateway_AI_Run_UsesNativeSDKAndPersistsAssistantMessage(t *testing.T) {
 		if rr.Code != http.StatusOK {
 			t.Fatalf("run status=%d body=%s", rr.Code, rr.Body.String())
 		}
-		runID := strings.TrimSpace(rr.Header().Get("X-Redeven-AI-Run-ID"))
+		runID = strings.TrimSpace(rr.Header().Get("X-Redeven-AI-Run-ID"))
 		if runID == "" {
 			t.Fatalf("missing X-Redeven-AI-Run-ID header")
 		}
@@ -305,6 +306,30 @@ func TestGateway_AI_Run_UsesNativeSDKAndPersistsAssistantMessage(t *testing.T) {
 		}
 	}
 
+	// Persisted run events replay over SSE and the stream closes at the terminal event.
+	{
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/runs/"+runID+"/events/stream", nil)
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("event stream status=%d body=%s", rr.Code, rr.Body.String())
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("event stream content-type=%q", ct)
+		}
+		body := rr.Body.String()
+		if !strings.HasPrefix(body, "retry: 3000\n\n") {
+			t.Fatalf("event stream should start with a retry hint, body=%q", body)
+		}
+		if !strings.Contains(body, "\nid: ") || !strings.Contains(body, "event: run.start\n") {
+			t.Fatalf("event stream missing run.start frame, body=%q", body)
+		}
+		if !strings.HasSuffix(body, "\n\n") || !strings.Contains(body, "event: run.end\n") {
+			t.Fatalf("event stream should end at run.end, body=%q", body)
+		}
+	}
+
 	// Thread metadata should be updated by persisted assistant message.
 	{
 		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/threads/"+threadID, nil)
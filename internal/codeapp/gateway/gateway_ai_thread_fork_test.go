@@ -0,0 +1,237 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/floegence/redeven/internal/ai"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestGateway_AI_ForkThread(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	stateDir := t.TempDir()
+
+	cfg := &config.AIConfig{
+		Providers: []config.AIProvider{
+			{
+				ID:      "openai",
+				Name:    "OpenAI",
+				Type:    "openai",
+				BaseURL: "https://api.openai.com/v1",
+				Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+	}
+
+	channelID := "ch_test_ai_fork_1"
+	envOrigin := envOriginWithChannel(channelID)
+	meta := session.Meta{
+		EndpointID:        "env_123",
+		NamespacePublicID: "ns_test",
+		UserPublicID:      "u_test",
+		UserEmail:         "u_test@example.com",
+		CanRead:           true,
+		CanWrite:          true,
+		CanExecute:        true,
+		CanAdmin:          true,
+	}
+	resolveMeta := resolveMetaForTest(channelID, meta)
+
+	aiSvc, err := ai.NewService(ai.Options{
+		Logger:       logger,
+		StateDir:     stateDir,
+		AgentHomeDir: stateDir,
+		Shell:        "bash",
+		Config:       cfg,
+		ResolveProviderAPIKey: func(string) (string, bool, error) {
+			return "sk-test", true, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ai.NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = aiSvc.Close() })
+
+	dist := fstest.MapFS{
+		"env/index.html": {Data: []byte("<html>env</html>")},
+		"inject.js":      {Data: []byte("console.log('inject');")},
+	}
+	gw, err := New(Options{
+		Logger:             logger,
+		Backend:            &stubBackend{},
+		DistFS:             dist,
+		ListenAddr:         "127.0.0.1:0",
+		ConfigPath:         writeTestConfigWithAI(t),
+		ResolveSessionMeta: resolveMeta,
+		AI:                 aiSvc,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var threadID string
+	{
+		req := httptest.NewRequest(http.MethodPost, "/_redeven_proxy/api/ai/threads", bytes.NewBufferString(`{"title":"fork source"}`))
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("create thread status=%d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			OK   bool `json:"ok"`
+			Data struct {
+				Thread struct {
+					ThreadID string `json:"thread_id"`
+				} `json:"thread"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal create thread: %v", err)
+		}
+		threadID = strings.TrimSpace(resp.Data.Thread.ThreadID)
+		if !resp.OK || threadID == "" {
+			t.Fatalf("unexpected create thread response: %s", rr.Body.String())
+		}
+	}
+
+	ctx := context.Background()
+	for _, text := range []string{"first", "second", "third"} {
+		if err := aiSvc.AppendThreadMessage(ctx, &meta, threadID, "user", text, "text"); err != nil {
+			t.Fatalf("AppendThreadMessage(%s): %v", text, err)
+		}
+	}
+	listMessageIDs := func(threadID string) []string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/threads/"+threadID+"/messages", nil)
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("list messages status=%d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data struct {
+				Messages []struct {
+					ID string `json:"id"`
+				} `json:"messages"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal messages: %v", err)
+		}
+		out := make([]string, 0, len(resp.Data.Messages))
+		for _, m := range resp.Data.Messages {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+	sourceMessages := listMessageIDs(threadID)
+	if len(sourceMessages) != 3 {
+		t.Fatalf("source messages=%v", sourceMessages)
+	}
+
+	{
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/threads/"+threadID+"/messages?since_seq=1&limit=1", nil)
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("list since status=%d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data struct {
+				Messages []struct {
+					ID string `json:"id"`
+				} `json:"messages"`
+				LatestSeq int64 `json:"latest_seq"`
+				HasMore   bool  `json:"has_more"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal messages since: %v", err)
+		}
+		if len(resp.Data.Messages) != 1 || resp.Data.Messages[0].ID != sourceMessages[1] || resp.Data.LatestSeq != 2 || !resp.Data.HasMore {
+			t.Fatalf("messages since=%s", rr.Body.String())
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/threads/"+threadID+"/messages?since_seq=-1", nil)
+		req.Header.Set("Origin", envOrigin)
+		rr = httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("invalid since_seq status=%d body=%s", rr.Code, rr.Body.String())
+		}
+	}
+
+	fork := func(threadID string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/_redeven_proxy/api/ai/threads/"+threadID+"/fork", bytes.NewBufferString(body))
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		return rr
+	}
+
+	rr := fork(threadID, `{"message_id":"`+sourceMessages[1]+`"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("fork status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		OK   bool `json:"ok"`
+		Data struct {
+			Thread struct {
+				ThreadID            string `json:"thread_id"`
+				Title               string `json:"title"`
+				ForkedFromThreadID  string `json:"forked_from_thread_id"`
+				ForkedFromMessageID string `json:"forked_from_message_id"`
+				LastMessagePreview  string `json:"last_message_preview"`
+			} `json:"thread"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal fork: %v", err)
+	}
+	forked := resp.Data.Thread
+	if !resp.OK || forked.ThreadID == "" || forked.ThreadID == threadID {
+		t.Fatalf("unexpected fork response: %s", rr.Body.String())
+	}
+	if forked.Title != "fork source" || forked.ForkedFromThreadID != threadID || forked.ForkedFromMessageID != sourceMessages[1] || forked.LastMessagePreview != "second" {
+		t.Fatalf("forked thread=%+v", forked)
+	}
+	if got := listMessageIDs(forked.ThreadID); len(got) != 2 || got[0] != sourceMessages[0] || got[1] != sourceMessages[1] {
+		t.Fatalf("forked messages=%v, want first two of %v", got, sourceMessages)
+	}
+
+	if err := aiSvc.AppendThreadMessage(ctx, &meta, forked.ThreadID, "user", "branch", "text"); err != nil {
+		t.Fatalf("AppendThreadMessage(fork): %v", err)
+	}
+	if got := listMessageIDs(threadID); len(got) != 3 {
+		t.Fatalf("source messages after fork append=%v", got)
+	}
+
+	if rr := fork(threadID, `{"message_id":"m_missing"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing message status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := fork("th_missing", `{"message_id":"`+sourceMessages[0]+`"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing thread status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := fork(threadID, `{"message_id":"`+sourceMessages[0]+`","extra":1}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("unknown field status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := fork(threadID, `{}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("missing message_id status=%d body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,210 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/floegence/redeven/internal/ai"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestGateway_AI_UsageEndpoints(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	stateDir := t.TempDir()
+
+	cfg := &config.AIConfig{
+		Providers: []config.AIProvider{
+			{
+				ID:      "openai",
+				Name:    "OpenAI",
+				Type:    "openai",
+				BaseURL: "https://api.openai.com/v1",
+				Models:  []config.AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+	}
+
+	channelID := "ch_test_ai_usage_1"
+	envOrigin := envOriginWithChannel(channelID)
+	meta := session.Meta{
+		EndpointID:        "env_123",
+		NamespacePublicID: "ns_test",
+		UserPublicID:      "u_test",
+		UserEmail:         "u_test@example.com",
+		CanRead:           true,
+		CanWrite:          true,
+		CanExecute:        true,
+		CanAdmin:          true,
+	}
+	resolveMeta := resolveMetaForTest(channelID, meta)
+
+	aiSvc, err := ai.NewService(ai.Options{
+		Logger:       logger,
+		StateDir:     stateDir,
+		AgentHomeDir: stateDir,
+		Shell:        "bash",
+		Config:       cfg,
+		ResolveProviderAPIKey: func(string) (string, bool, error) {
+			return "sk-test", true, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ai.NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = aiSvc.Close() })
+
+	dist := fstest.MapFS{
+		"env/index.html": {Data: []byte("<html>env</html>")},
+		"inject.js":      {Data: []byte("console.log('inject');")},
+	}
+	gw, err := New(Options{
+		Logger:             logger,
+		Backend:            &stubBackend{},
+		DistFS:             dist,
+		ListenAddr:         "127.0.0.1:0",
+		ConfigPath:         writeTestConfigWithAI(t),
+		ResolveSessionMeta: resolveMeta,
+		AI:                 aiSvc,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var threadID string
+	{
+		req := httptest.NewRequest(http.MethodPost, "/_redeven_proxy/api/ai/threads", bytes.NewBufferString(`{"title":"usage thread"}`))
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("create thread status=%d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			OK   bool `json:"ok"`
+			Data struct {
+				Thread struct {
+					ThreadID string `json:"thread_id"`
+				} `json:"thread"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal create thread: %v", err)
+		}
+		threadID = strings.TrimSpace(resp.Data.Thread.ThreadID)
+		if !resp.OK || threadID == "" {
+			t.Fatalf("unexpected create thread response: %s", rr.Body.String())
+		}
+	}
+
+	{
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/threads/"+threadID+"/usage", nil)
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("usage status=%d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			OK   bool `json:"ok"`
+			Data struct {
+				ThreadID string `json:"thread_id"`
+				Models   []any  `json:"models"`
+				Totals   struct {
+					Turns int64 `json:"turns"`
+				} `json:"totals"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal usage: %v", err)
+		}
+		if !resp.OK || resp.Data.ThreadID != threadID || len(resp.Data.Models) != 0 || resp.Data.Totals.Turns != 0 {
+			t.Fatalf("unexpected usage response: %s", rr.Body.String())
+		}
+	}
+
+	{
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/threads/not_found/usage", nil)
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("missing thread status=%d body=%s", rr.Code, rr.Body.String())
+		}
+	}
+
+	for _, since := range []string{"", "?since=1700000000000", "?since=2026-01-02T15:04:05Z&limit=10"} {
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/usage"+since, nil)
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("namespace usage %q status=%d body=%s", since, rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			OK   bool `json:"ok"`
+			Data struct {
+				HasMore bool `json:"has_more"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal namespace usage: %v", err)
+		}
+		if !resp.OK || resp.Data.HasMore {
+			t.Fatalf("unexpected namespace usage response: %s", rr.Body.String())
+		}
+	}
+
+	{
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/usage?since=yesterday", nil)
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("invalid since status=%d body=%s", rr.Code, rr.Body.String())
+		}
+	}
+
+	for _, since := range []string{"", "?since=1700000000000", "?since=2026-01-02T15:04:05Z"} {
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/stats/finalization"+since, nil)
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("finalization stats %q status=%d body=%s", since, rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			OK   bool `json:"ok"`
+			Data struct {
+				Runs    int64 `json:"runs"`
+				ByModel []any `json:"by_model"`
+				ByMode  []any `json:"by_mode"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal finalization stats: %v", err)
+		}
+		if !resp.OK || resp.Data.Runs != 0 || resp.Data.ByModel == nil || resp.Data.ByMode == nil {
+			t.Fatalf("unexpected finalization stats response: %s", rr.Body.String())
+		}
+	}
+
+	{
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/ai/stats/finalization?since=yesterday", nil)
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("invalid finalization since status=%d body=%s", rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/floegence/redeven/internal/ai"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestGateway_Metrics(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	stateDir := t.TempDir()
+
+	aiSvc, err := ai.NewService(ai.Options{
+		Logger:       logger,
+		StateDir:     stateDir,
+		AgentHomeDir: stateDir,
+		Shell:        "bash",
+		Config: &config.AIConfig{
+			Providers: []config.AIProvider{
+				{ID: "openai", Name: "OpenAI", Type: "openai", Models: []config.AIProviderModel{{ModelName: "gpt-5-mini"}}},
+			},
+		},
+		ResolveProviderAPIKey: func(string) (string, bool, error) {
+			return "sk-test", true, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ai.NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = aiSvc.Close() })
+
+	newGateway := func(channelID string, canAdmin bool) *Gateway {
+		gw, err := New(Options{
+			Logger:     logger,
+			Backend:    &stubBackend{},
+			DistFS:     fstest.MapFS{"env/index.html": {Data: []byte("<html>env</html>")}, "inject.js": {Data: []byte("console.log('inject');")}},
+			ListenAddr: "127.0.0.1:0",
+			ConfigPath: writeTestConfigWithAI(t),
+			ResolveSessionMeta: resolveMetaForTest(channelID, session.Meta{
+				EndpointID:        "env_123",
+				NamespacePublicID: "ns_test",
+				UserPublicID:      "u_test",
+				UserEmail:         "u_test@example.com",
+				CanRead:           true,
+				CanWrite:          true,
+				CanExecute:        true,
+				CanAdmin:          canAdmin,
+			}),
+			AI: aiSvc,
+		})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		return gw
+	}
+
+	scrape := func(gw *Gateway, channelID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/metrics", nil)
+		req.Header.Set("Origin", envOriginWithChannel(channelID))
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		return rr
+	}
+
+	rr := scrape(newGateway("ch_test_metrics_admin", true), "ch_test_metrics_admin")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content-type=%q", ct)
+	}
+	for _, want := range []string{
+		"# TYPE redeven_ai_runs_active gauge\nredeven_ai_runs_active 0\n",
+		"# TYPE redeven_ai_runs_total counter\n",
+		"# TYPE redeven_ai_tool_calls_total counter\n",
+		"# TYPE redeven_ai_provider_errors_total counter\n",
+		"# TYPE redeven_ai_tokens_total counter\n",
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Fatalf("metrics missing %q:\n%s", want, rr.Body.String())
+		}
+	}
+
+	rr = scrape(newGateway("ch_test_metrics_user", false), "ch_test_metrics_user")
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "admin permission denied") {
+		t.Fatalf("non-admin status=%d body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,261 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/floegence/redeven/internal/auditlog"
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := l.allow("ai|user:u1", 2); !ok {
+			t.Fatalf("request %d rejected within burst", i)
+		}
+	}
+	ok, retryAfter, first := l.allow("ai|user:u1", 2)
+	if ok || !first || retryAfter != 30*time.Second {
+		t.Fatalf("over limit ok=%v first=%v retryAfter=%s", ok, first, retryAfter)
+	}
+	if ok, _, first := l.allow("ai|user:u1", 2); ok || first {
+		t.Fatalf("second rejection ok=%v first=%v, want rejected and not first", ok, first)
+	}
+	if ok, _, _ := l.allow("ai|user:u2", 2); !ok {
+		t.Fatalf("other user shares the bucket")
+	}
+
+	now = now.Add(30 * time.Second)
+	if ok, _, _ := l.allow("ai|user:u1", 2); !ok {
+		t.Fatalf("bucket did not refill")
+	}
+	if ok, _, _ := l.allow("ai|user:u1", 0); !ok {
+		t.Fatalf("zero limit must disable throttling")
+	}
+}
+
+func TestRateLimiter_EvictsIdleBuckets(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 100; i++ {
+		l.allow("ai|channel:ch_"+strings.Repeat("x", i), 10)
+	}
+	if got := len(l.buckets); got != 100 {
+		t.Fatalf("buckets=%d, want 100", got)
+	}
+
+	// A drained bucket that is still refilling must survive the sweep, or its limit would reset early.
+	now = now.Add(30 * time.Second)
+	for i := 0; i < 10; i++ {
+		l.allow("ai|user:busy", 10)
+	}
+	now = now.Add(45 * time.Second)
+	if ok, _, _ := l.allow("ai|user:active", 10); !ok {
+		t.Fatalf("fresh subject rejected")
+	}
+	if _, ok := l.buckets["ai|user:busy"]; !ok {
+		t.Fatalf("bucket used 45s ago was evicted")
+	}
+	if got := len(l.buckets); got != 2 {
+		t.Fatalf("buckets after sweep=%d, want 2 (busy, active)", got)
+	}
+	if ok, _, _ := l.allow("ai|user:busy", 10); !ok {
+		t.Fatalf("busy bucket should have refilled after 45s")
+	}
+}
+
+func TestGateway_RateLimitsAIAndSettingsRequests(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	stateDir := t.TempDir()
+	configPath := filepath.Join(stateDir, "config.json")
+	raw := `{
+  "gateway_rate_limit": {
+    "ai_requests_per_minute": 2,
+    "settings_requests_per_minute": 1,
+    "local_ai_requests_per_minute": 3
+  }
+}
+`
+	if err := os.WriteFile(configPath, []byte(raw), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	audit, err := auditlog.New(auditlog.Options{Logger: logger, StateDir: stateDir})
+	if err != nil {
+		t.Fatalf("auditlog.New: %v", err)
+	}
+
+	channelID := "ch_test_rate_limit_1"
+	envOrigin := envOriginWithChannel(channelID)
+	gw, err := New(Options{
+		Logger:     logger,
+		Backend:    &stubBackend{},
+		DistFS:     fstest.MapFS{"env/index.html": {Data: []byte("<html>env</html>")}},
+		ListenAddr: "127.0.0.1:0",
+		ConfigPath: configPath,
+		Audit:      audit,
+		ResolveSessionMeta: resolveMetaForTest(channelID, session.Meta{
+			EndpointID:   "env_123",
+			UserPublicID: "u_spammer",
+			CanRead:      true,
+			CanWrite:     true,
+			CanExecute:   true,
+		}),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	do := func(method string, path string, local bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{}`))
+		if local {
+			req = WithLocalUIEnvRoute(req)
+		} else {
+			req.Header.Set("Origin", envOrigin)
+		}
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		if rr := do(http.MethodPost, "/_redeven_proxy/api/ai/threads", false); rr.Code == http.StatusTooManyRequests {
+			t.Fatalf("request %d throttled within limit", i)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		rr := do(http.MethodPost, "/_redeven_proxy/api/ai/threads", false)
+		if rr.Code != http.StatusTooManyRequests || !strings.Contains(rr.Body.String(), `"error_code":"rate_limited"`) {
+			t.Fatalf("over limit status=%d body=%s", rr.Code, rr.Body.String())
+		}
+		if got := rr.Header().Get("Retry-After"); got != "30" {
+			t.Fatalf("Retry-After=%q, want 30", got)
+		}
+	}
+	// Reads are not throttled.
+	if rr := do(http.MethodGet, "/_redeven_proxy/api/ai/threads", false); rr.Code == http.StatusTooManyRequests {
+		t.Fatalf("GET throttled")
+	}
+	// Settings have their own bucket.
+	if rr := do(http.MethodPut, "/_redeven_proxy/api/settings", false); rr.Code == http.StatusTooManyRequests {
+		t.Fatalf("first settings update throttled")
+	}
+	if rr := do(http.MethodPut, "/_redeven_proxy/api/settings", false); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("second settings update status=%d, want 429", rr.Code)
+	}
+	// Local UI uses the local limit.
+	for i := 0; i < 3; i++ {
+		if rr := do(http.MethodPost, "/_redeven_proxy/api/ai/threads", true); rr.Code == http.StatusTooManyRequests {
+			t.Fatalf("local request %d throttled within local limit", i)
+		}
+	}
+	if rr := do(http.MethodPost, "/_redeven_proxy/api/ai/threads", true); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("local over limit status=%d, want 429", rr.Code)
+	}
+
+	page, err := audit.ListPage(auditlog.ListQuery{Limit: 50})
+	if err != nil {
+		t.Fatalf("ListPage: %v", err)
+	}
+	var aiEntries, settingsEntries int
+	for _, entry := range page.Entries {
+		if entry.Action != "rate_limited" {
+			continue
+		}
+		switch entry.Detail["category"] {
+		case "ai":
+			aiEntries++
+		case "settings":
+			settingsEntries++
+		}
+	}
+	// One audit entry per burst of rejections: remote ai, local ai, and settings.
+	if aiEntries != 2 || settingsEntries != 1 {
+		t.Fatalf("rate_limited audit entries ai=%d settings=%d", aiEntries, settingsEntries)
+	}
+}
+
+func TestGateway_RateLimitsFollowConfigSaves(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte("{}\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	channelID := "ch_test_rate_limit_reload"
+	envOrigin := envOriginWithChannel(channelID)
+	gw, err := New(Options{
+		Logger:     logger,
+		Backend:    &stubBackend{},
+		DistFS:     fstest.MapFS{"env/index.html": {Data: []byte("<html>env</html>")}},
+		ListenAddr: "127.0.0.1:0",
+		ConfigPath: configPath,
+		ResolveSessionMeta: resolveMetaForTest(channelID, session.Meta{
+			EndpointID:   "env_123",
+			UserPublicID: "u_reload",
+			CanRead:      true,
+			CanWrite:     true,
+			CanExecute:   true,
+		}),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	post := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/_redeven_proxy/api/ai/threads", strings.NewReader(`{}`))
+		req.Header.Set("Origin", envOrigin)
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		return rr.Code
+	}
+	setLimit := func(policy *config.GatewayRateLimitPolicy) {
+		t.Helper()
+		if _, err := gw.updateConfigLocked(func(c *config.Config) error {
+			c.GatewayRateLimit = policy
+			return nil
+		}); err != nil {
+			t.Fatalf("updateConfigLocked: %v", err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := post(); code == http.StatusTooManyRequests {
+			t.Fatalf("request %d throttled without a configured limit", i)
+		}
+	}
+
+	one := 1
+	setLimit(&config.GatewayRateLimitPolicy{AIRequestsPerMinute: &one})
+	if code := post(); code == http.StatusTooManyRequests {
+		t.Fatalf("first request after enabling the limit throttled")
+	}
+	if code := post(); code != http.StatusTooManyRequests {
+		t.Fatalf("second request after enabling the limit status=%d, want 429", code)
+	}
+
+	setLimit(nil)
+	if code := post(); code == http.StatusTooManyRequests {
+		t.Fatalf("request throttled after the limit was removed")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/floegence/redeven/internal/config"
 	"github.com/floegence/redeven/internal/session"
 	"github.com/floegence/redeven/internal/sessionhop"
+	"github.com/floegence/redeven/internal/settings"
 	"github.com/floegence/redeven/internal/threadreadstate"
 )
 
@@ -1040,6 +1041,38 @@ func TestGateway_Settings_RedactsSecrets(t *testing.T) {
 	}
 }
 
+func TestGateway_ProviderKeysUpdate_ReadOnlySecretsBackendConflicts(t *testing.T) {
+	t.Parallel()
+
+	dist := fstest.MapFS{
+		"env/index.html": {Data: []byte("<html>env</html>")},
+		"inject.js":      {Data: []byte("console.log('inject');")},
+	}
+
+	channelID := "ch_test_secrets_ro"
+	envOrigin := envOriginWithChannel(channelID)
+	gw, err := New(Options{
+		Backend:            &stubBackend{},
+		DistFS:             dist,
+		ListenAddr:         "127.0.0.1:0",
+		ConfigPath:         writeTestConfig(t),
+		Secrets:            settings.NewEnvSecretsBackend("REDEVEN_TEST_RO_"),
+		ResolveSessionMeta: resolveMetaForTest(channelID, session.Meta{CanRead: true, CanWrite: true, CanExecute: true, CanAdmin: true}),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/_redeven_proxy/api/ai/provider_keys", strings.NewReader(`{"patches":[{"provider_id":"openai","api_key":"sk-test"}]}`))
+	req.Header.Set("Origin", envOrigin)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	gw.serveHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("status=%d, want %d body=%s", rr.Code, http.StatusConflict, rr.Body.String())
+	}
+}
+
 func TestGateway_SettingsUpdate_ReturnsAIUpdateMeta(t *testing.T) {
 	t.Parallel()
 
@@ -1698,6 +1731,9 @@ func TestGateway_AIThreadDeleteRemovesReadStateForAllUsers(t *testing.T) {
 	if rr.Code != http.StatusOK {
 		t.Fatalf("DELETE /api/ai/threads/:id status=%d body=%s", rr.Code, rr.Body.String())
 	}
+	if !strings.Contains(rr.Body.String(), `"archived":true`) {
+		t.Fatalf("DELETE /api/ai/threads/:id body=%s, want archived", rr.Body.String())
+	}
 
 	remaining, err := store.DeleteThread(context.Background(), creatorMeta.EndpointID, threadreadstate.SurfaceFlower, thread.ThreadID)
 	if err != nil {
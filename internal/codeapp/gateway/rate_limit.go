@@ -0,0 +1,191 @@
+package gateway
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/floegence/redeven/internal/session"
+)
+
+type rateLimitCategory string
+
+const (
+	rateLimitCategoryAI       rateLimitCategory = "ai"
+	rateLimitCategorySettings rateLimitCategory = "settings"
+)
+
+// rateLimitCategoryForRequest returns the throttled category of r.
+//
+// Only mutating requests (run starts, thread updates, settings writes, ...) are limited; reads and
+// event streams are polled by the UI and stay unthrottled.
+func rateLimitCategoryForRequest(r *http.Request) (rateLimitCategory, bool) {
+	if r == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+		return "", false
+	}
+	p := strings.TrimSpace(r.URL.Path)
+	switch {
+	case strings.HasPrefix(p, "/_redeven_proxy/api/ai/"):
+		return rateLimitCategoryAI, true
+	case p == "/_redeven_proxy/api/settings" || strings.HasPrefix(p, "/_redeven_proxy/api/settings/"):
+		return rateLimitCategorySettings, true
+	default:
+		return "", false
+	}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// limited is set while the bucket rejects requests, so one burst of rejections is audited once.
+	limited bool
+}
+
+// rateLimitBucketIdleTTL is how long a bucket must go untouched before it is evicted.
+//
+// Every bucket refills from empty to full within one minute, so an idle bucket is indistinguishable
+// from a fresh one once this long has passed.
+const rateLimitBucketIdleTTL = time.Minute
+
+// rateLimiter is a set of per-subject, per-category token buckets.
+type rateLimiter struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{now: time.Now, buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes one token from the bucket of key, which holds perMinute tokens and refills continuously.
+//
+// When the bucket is empty it returns the wait until the next token, and firstReject reports whether
+// this is the first rejection since the bucket last admitted a request.
+func (l *rateLimiter) allow(key string, perMinute int) (ok bool, retryAfter time.Duration, firstReject bool) {
+	if l == nil || perMinute <= 0 {
+		return true, 0, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweepIdleLocked(now)
+	capacity := float64(perMinute)
+	ratePerSecond := capacity / 60
+	b := l.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*ratePerSecond)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		b.limited = false
+		return true, 0, false
+	}
+	wait := time.Duration((1 - b.tokens) / ratePerSecond * float64(time.Second))
+	firstReject = !b.limited
+	b.limited = true
+	return false, wait, firstReject
+}
+
+// sweepIdleLocked evicts buckets that have been idle long enough to be full again, at most once per TTL.
+//
+// Callers must hold l.mu.
+func (l *rateLimiter) sweepIdleLocked(now time.Time) {
+	if !l.lastSweep.IsZero() && now.Sub(l.lastSweep) < rateLimitBucketIdleTTL {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= rateLimitBucketIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (g *Gateway) rateLimitPerMinute(category rateLimitCategory, local bool) int {
+	switch category {
+	case rateLimitCategoryAI:
+		return g.rateLimits.Load().EffectiveAIRequestsPerMinute(local)
+	case rateLimitCategorySettings:
+		return g.rateLimits.Load().EffectiveSettingsRequestsPerMinute(local)
+	default:
+		return 0
+	}
+}
+
+// rateLimitSubject resolves who r is attributed to, without checking permissions.
+func (g *Gateway) rateLimitSubject(r *http.Request) (*session.Meta, bool) {
+	if _, ok := localUIRouteFromRequest(r); ok {
+		return g.localSessionMeta(), true
+	}
+	if g.resolveSessionMeta == nil {
+		return nil, false
+	}
+	channelID, err := channelIDFromRequest(r)
+	if err != nil {
+		return nil, false
+	}
+	meta, ok := g.resolveSessionMeta(channelID)
+	if !ok || meta == nil {
+		return nil, false
+	}
+	return meta, true
+}
+
+// enforceRateLimit applies the per-user rate limit to AI and settings requests before permission checks.
+//
+// It writes HTTP 429 with Retry-After and returns false when the request must be rejected. Requests that
+// cannot be attributed to a session are left to requirePermission.
+func (g *Gateway) enforceRateLimit(w http.ResponseWriter, r *http.Request) bool {
+	if g == nil || g.rateLimiter == nil {
+		return true
+	}
+	category, ok := rateLimitCategoryForRequest(r)
+	if !ok {
+		return true
+	}
+	_, local := localUIRouteFromRequest(r)
+	perMinute := g.rateLimitPerMinute(category, local)
+	if perMinute <= 0 {
+		return true
+	}
+	meta, ok := g.rateLimitSubject(r)
+	if !ok {
+		return true
+	}
+	subject := "user:" + strings.TrimSpace(meta.UserPublicID)
+	if strings.TrimSpace(meta.UserPublicID) == "" {
+		subject = "channel:" + strings.TrimSpace(meta.ChannelID)
+	}
+	allowed, retryAfter, firstReject := g.rateLimiter.allow(string(category)+"|"+subject, perMinute)
+	if allowed {
+		return true
+	}
+	retryAfterSeconds := int(math.Ceil(retryAfter.Seconds()))
+	if retryAfterSeconds < 1 {
+		retryAfterSeconds = 1
+	}
+	if firstReject {
+		g.appendAudit(meta, "rate_limited", "failure", map[string]any{
+			"category":            string(category),
+			"method":              r.Method,
+			"path":                strings.TrimSpace(r.URL.Path),
+			"limit_per_minute":    perMinute,
+			"retry_after_seconds": retryAfterSeconds,
+			"local_ui":            local,
+		}, nil)
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+	writeJSON(w, http.StatusTooManyRequests, apiResp{OK: false, Error: "rate limit exceeded", ErrorCode: "rate_limited"})
+	return false
+}
@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const settingsHistoryPathPrefix = "/_redeven_proxy/api/settings/history"
+
+type settingsHistoryListView struct {
+	Revisions []config.ConfigRevision `json:"revisions"`
+}
+
+type settingsRevisionView struct {
+	ID string `json:"id"`
+	// Settings is the revision rendered like GET /api/settings, so secrets are never returned.
+	Settings settingsView `json:"settings"`
+	// ChangedSections lists the restorable sections that differ from the current config.
+	ChangedSections []string `json:"changed_sections"`
+}
+
+// settingsChangedSections compares the sections a restore would overwrite.
+func settingsChangedSections(from settingsView, to settingsView) []string {
+	sections := []struct {
+		name     string
+		from, to any
+	}{
+		{"runtime", from.Runtime, to.Runtime},
+		{"logging", from.Logging, to.Logging},
+		{"codespaces", from.Codespaces, to.Codespaces},
+		{"permission_policy", from.PermissionPolicy, to.PermissionPolicy},
+		{"ai", from.AI, to.AI},
+	}
+	out := make([]string, 0, len(sections))
+	for _, s := range sections {
+		a, _ := json.Marshal(s.from)
+		b, _ := json.Marshal(s.to)
+		if string(a) != string(b) {
+			out = append(out, s.name)
+		}
+	}
+	return out
+}
+
+func (g *Gateway) handleSettingsHistoryAPI(w http.ResponseWriter, r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	path := strings.TrimSpace(r.URL.Path)
+	if path != settingsHistoryPathPrefix && !strings.HasPrefix(path, settingsHistoryPathPrefix+"/") {
+		return false
+	}
+
+	switch {
+	case r.Method == http.MethodGet && path == settingsHistoryPathPrefix:
+		if _, ok := g.requirePermission(w, r, requiredPermissionAdmin); !ok {
+			return true
+		}
+		revisions, err := config.ListRevisions(g.configPath)
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: err.Error()})
+			return true
+		}
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: settingsHistoryListView{Revisions: revisions}})
+		return true
+
+	case r.Method == http.MethodPost && strings.HasPrefix(path, settingsHistoryPathPrefix+"/restore/"):
+		meta, ok := g.requirePermission(w, r, requiredPermissionAdmin)
+		if !ok {
+			return true
+		}
+		id := strings.TrimPrefix(path, settingsHistoryPathPrefix+"/restore/")
+		auditDetail := map[string]any{"revision_id": id}
+		revision, err := config.LoadRevision(g.configPath, id)
+		if err != nil {
+			g.appendAudit(meta, "settings_restore", "failure", auditDetail, err)
+			writeSettingsRevisionError(w, err)
+			return true
+		}
+		if err := revision.ValidateLocalMinimal(); err != nil {
+			g.appendAudit(meta, "settings_restore", "failure", auditDetail, err)
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+			return true
+		}
+
+		var updated *config.Config
+		persist := func() error {
+			// Connection fields are control-plane managed and always keep their current values.
+			cfg, err := g.updateConfigLocked(func(c *config.Config) error {
+				c.AgentHomeDir = revision.AgentHomeDir
+				c.Shell = revision.Shell
+				c.LogFormat = revision.LogFormat
+				c.LogLevel = revision.LogLevel
+				c.CodeServerPortMin = revision.CodeServerPortMin
+				c.CodeServerPortMax = revision.CodeServerPortMax
+				c.PermissionPolicy = revision.PermissionPolicy
+				c.AI = revision.AI
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			updated = cfg
+			return nil
+		}
+		var aiUpdate *settingsAIUpdateView
+		if g.ai != nil {
+			activeRunCount := g.ai.ActiveRunCount(strings.TrimSpace(meta.EndpointID))
+			aiUpdate = &settingsAIUpdateView{ApplyScope: "future_runs", ActiveRunCount: activeRunCount}
+			err = g.ai.UpdateConfig(revision.AI, persist)
+		} else {
+			err = persist()
+		}
+		if err != nil {
+			g.appendAudit(meta, "settings_restore", "failure", auditDetail, err)
+			writeJSON(w, http.StatusBadRequest, apiResp{OK: false, Error: err.Error()})
+			return true
+		}
+		g.appendAudit(meta, "settings_restore", "success", auditDetail, nil)
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: settingsUpdateView{
+			Settings: toSettingsView(updated, g.configPath, g.secrets),
+			AIUpdate: aiUpdate,
+		}})
+		return true
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, settingsHistoryPathPrefix+"/"):
+		if _, ok := g.requirePermission(w, r, requiredPermissionAdmin); !ok {
+			return true
+		}
+		id := strings.TrimPrefix(path, settingsHistoryPathPrefix+"/")
+		revision, err := config.LoadRevision(g.configPath, id)
+		if err != nil {
+			writeSettingsRevisionError(w, err)
+			return true
+		}
+		current, err := g.loadConfigLocked()
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: err.Error()})
+			return true
+		}
+		revisionView := toSettingsView(revision, g.configPath, nil)
+		writeJSON(w, http.StatusOK, apiResp{OK: true, Data: settingsRevisionView{
+			ID:              id,
+			Settings:        revisionView,
+			ChangedSections: settingsChangedSections(toSettingsView(current, g.configPath, nil), revisionView),
+		}})
+		return true
+	}
+	return false
+}
+
+func writeSettingsRevisionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, config.ErrConfigRevisionNotFound) {
+		writeJSON(w, http.StatusNotFound, apiResp{OK: false, Error: "config revision not found"})
+		return
+	}
+	writeJSON(w, http.StatusServiceUnavailable, apiResp{OK: false, Error: err.Error()})
+}
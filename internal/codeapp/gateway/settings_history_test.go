@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/floegence/redeven/internal/config"
+	"github.com/floegence/redeven/internal/session"
+)
+
+func TestGateway_SettingsHistoryListGetAndRestore(t *testing.T) {
+	t.Parallel()
+
+	cfgPath := writeTestConfig(t)
+	channelID := "ch_settings_history"
+	gw, err := New(Options{
+		Backend:            &stubBackend{},
+		DistFS:             fstest.MapFS{"env/index.html": {Data: []byte("<html>env</html>")}},
+		ConfigPath:         cfgPath,
+		ResolveSessionMeta: resolveMetaForTest(channelID, session.Meta{CanRead: true, CanAdmin: true}),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	do := func(method string, path string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Origin", envOriginWithChannel(channelID))
+		rr := httptest.NewRecorder()
+		gw.serveHTTP(rr, req)
+		return rr
+	}
+
+	for _, level := range []string{"debug", "warn"} {
+		if rr := do(http.MethodPut, "/_redeven_proxy/api/settings", `{"log_level":"`+level+`"}`); rr.Code != http.StatusOK {
+			t.Fatalf("update status=%d body=%s", rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := do(http.MethodGet, "/_redeven_proxy/api/settings/history", "")
+	var list struct {
+		Data settingsHistoryListView `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("list status=%d err=%v body=%s", rr.Code, err, rr.Body.String())
+	}
+	if len(list.Data.Revisions) != 2 {
+		t.Fatalf("revisions=%+v, want 2", list.Data.Revisions)
+	}
+	oldest := list.Data.Revisions[1].ID
+
+	rr = do(http.MethodGet, "/_redeven_proxy/api/settings/history/"+oldest, "")
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), `"secret"`) {
+		t.Fatalf("get status=%d body=%s, want the revision without secrets", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		Data settingsRevisionView `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal revision: %v", err)
+	}
+	if got.Data.Settings.Logging.LogLevel != "debug" || len(got.Data.ChangedSections) != 1 || got.Data.ChangedSections[0] != "logging" {
+		t.Fatalf("revision=%+v, want the debug revision differing only in logging", got.Data)
+	}
+
+	if rr := do(http.MethodGet, "/_redeven_proxy/api/settings/history/20000101T000000.000000000Z", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown revision status=%d, want 404", rr.Code)
+	}
+
+	if rr := do(http.MethodPost, "/_redeven_proxy/api/settings/history/restore/"+oldest, ""); rr.Code != http.StatusOK {
+		t.Fatalf("restore status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	if cfg.LogLevel != "debug" || cfg.Direct == nil || cfg.Direct.E2eePskB64u != "secret" {
+		t.Fatalf("restored config log_level=%q direct=%+v, want debug with connection fields kept", cfg.LogLevel, cfg.Direct)
+	}
+	if revisions, _ := config.ListRevisions(cfgPath); len(revisions) != 3 {
+		t.Fatalf("revisions after restore=%d, want the restore recorded as a new revision", len(revisions))
+	}
+}
+
+func TestGateway_SettingsHistoryRequiresAdmin(t *testing.T) {
+	t.Parallel()
+
+	channelID := "ch_settings_history_reader"
+	gw, err := New(Options{
+		Backend:            &stubBackend{},
+		DistFS:             fstest.MapFS{"env/index.html": {Data: []byte("<html>env</html>")}},
+		ConfigPath:         writeTestConfig(t),
+		ResolveSessionMeta: resolveMetaForTest(channelID, session.Meta{CanRead: true}),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/_redeven_proxy/api/settings/history", nil)
+	req.Header.Set("Origin", envOriginWithChannel(channelID))
+	rr := httptest.NewRecorder()
+	gw.serveHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("status=%d, want 403", rr.Code)
+	}
+}
@@ -4,7 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+
+	aitools "github.com/floegence/redeven/internal/ai/tools"
 )
 
 // AIConfig configures the optional Flower (AI assistant) feature (Go Native runtime).
@@ -76,6 +81,168 @@ type AIConfig struct {
 	// Notes:
 	// - Secrets (API keys) must never be stored in config.json. Web search keys must live in secrets.json.
 	WebSearchProvider string `json:"web_search_provider,omitempty"`
+
+	// ThreadConcurrency controls what happens when a run starts on a thread that already has an active run.
+	//
+	// Defaults to rejecting the second run with a thread-busy error.
+	ThreadConcurrency *AIThreadConcurrencyPolicy `json:"thread_concurrency,omitempty"`
+
+	// RunConcurrency caps how many runs may execute at once, across the agent and per namespace.
+	//
+	// Defaults to no cap.
+	RunConcurrency *AIRunConcurrencyPolicy `json:"run_concurrency,omitempty"`
+
+	// PersistenceMode controls how much run detail is written to the local thread store.
+	//
+	// Supported values:
+	// - "full": persist transcripts, run events, and tool call bodies (default)
+	// - "summary_only": persist only the final answer, lifecycle state, and coarse metrics
+	//
+	// Runs always stream in full to connected clients; only persistence is affected.
+	PersistenceMode string `json:"persistence_mode,omitempty"`
+
+	// EmptyObjectivePolicy controls how a run proceeds when no clear task objective is available.
+	//
+	// Supported values:
+	// - "off": use the raw objective or input text as-is (default)
+	// - "derive": derive an objective from the first user message with a single-turn model extraction
+	// - "clarify": ask the user to clarify complex tasks; derive for everything else
+	EmptyObjectivePolicy string `json:"empty_objective_policy,omitempty"`
+
+	// ToolRateLimit caps how often individual tools may be called per minute.
+	//
+	// Limits are shared by all runs in the process so third-party quotas (web search, URL fetch)
+	// are protected across concurrent runs. Tools without a limit are not throttled.
+	ToolRateLimit *AIToolRateLimitPolicy `json:"tool_rate_limit,omitempty"`
+
+	// ToolApproval declares per-tool approval rules.
+	//
+	// The first matching rule decides whether a tool call waits for user approval. Rules can only add
+	// approvals on top of execution_policy.require_user_approval unless AllowRelax is set. Calls that no
+	// rule matches keep the execution_policy behavior.
+	ToolApproval *AIToolApprovalPolicy `json:"tool_approval,omitempty"`
+
+	// PersistProviderDiagnostics enables per-turn persistence of provider diagnostics
+	// (response ids, finish reasons, missing-completion flags) and a per-provider quirk report on run end.
+	//
+	// Disabled by default because it adds one run event per model turn.
+	PersistProviderDiagnostics bool `json:"persist_provider_diagnostics,omitempty"`
+
+	// RunAutoRetry restarts a run from scratch when it fails before making any progress
+	// (no assistant text and no tool calls), so retries never repeat side effects.
+	//
+	// Disabled by default.
+	RunAutoRetry *AIRunAutoRetryPolicy `json:"run_auto_retry,omitempty"`
+
+	// AttachmentLimit bounds how many attachments one user message carries into the model context.
+	AttachmentLimit *AIAttachmentLimitPolicy `json:"attachment_limit,omitempty"`
+
+	// ProviderRetry retries a model turn that failed with HTTP 429 or 5xx before any output was streamed.
+	ProviderRetry *AIProviderRetryPolicy `json:"provider_retry,omitempty"`
+
+	// WebSearchCache caches web.search results in memory, keyed by normalized query and result count.
+	//
+	// The cache is shared by all runs in the process. Enabled by default with a 5-minute TTL.
+	WebSearchCache *AIWebSearchCachePolicy `json:"web_search_cache,omitempty"`
+
+	// ProviderCircuitBreaker fails new runs fast after a provider keeps failing, until a cooldown elapses.
+	//
+	// The breaker is kept per provider id and shared by all runs in the process. Enabled by default.
+	ProviderCircuitBreaker *AIProviderCircuitBreakerPolicy `json:"provider_circuit_breaker,omitempty"`
+
+	// ModelAllowlist restricts namespaces to approved models.
+	//
+	// Keys are namespace public ids; values are model wire ids (<provider_id>/<model_name>).
+	// Namespaces without an entry, or with an empty list, may use every configured model.
+	ModelAllowlist map[string][]string `json:"model_allowlist,omitempty"`
+
+	// ToolAllowlists restricts the built-in tools runs may use.
+	//
+	// Keys are a mode ("act", "plan") or the permission preset matching the run's session permissions
+	// ("read_only", "execute_read", "execute_read_write"); values are built-in tool names. A run gets
+	// the intersection of every list that applies to it and of its own tool_allowlist. Keys without a
+	// list, or with an empty list, do not restrict tools. task_complete, ask_user, and exit_plan_mode
+	// stay available, and tools a run declares itself (external tools) are not affected.
+	ToolAllowlists map[string][]string `json:"tool_allowlists,omitempty"`
+
+	// FallbackPhrases extends the built-in fallback phrase catalog, keyed by language code (for example "en", "zh").
+	//
+	// A final assistant message containing one of these phrases is reported as a fallback on the run.end event.
+	FallbackPhrases map[string][]string `json:"fallback_phrases,omitempty"`
+
+	// ApplyPatchPreview makes apply_patch dry-run a patch first and attach the resulting unified diff to the tool call.
+	//
+	// When the call needs approval, the user approves the previewed diff and the patch is applied only after that;
+	// a rejection returns an aborted patch_rejected result so the model replans.
+	ApplyPatchPreview bool `json:"apply_patch_preview,omitempty"`
+
+	// ThreadTitles opts into titles generated from a thread's first exchange. Nil keeps titles from the first
+	// user message only.
+	ThreadTitles *AIThreadTitlePolicy `json:"thread_titles,omitempty"`
+
+	// StrictWorkspaceSandbox turns the run working directory into a hard sandbox.
+	//
+	// When enabled, file tools, apply_patch paths, and terminal.exec (cwd/workdir, `cd` targets, and
+	// absolute path arguments) are rejected before execution if they resolve outside the working directory.
+	// Disabled by default.
+	StrictWorkspaceSandbox bool `json:"strict_workspace_sandbox,omitempty"`
+
+	// ToolCallTimeoutMS bounds one tool call that does not carry its own timeout_ms argument.
+	//
+	// A call that outlives its timeout is canceled (terminal.exec kills its process group) and
+	// reported to the model as tool_timeout. Defaults to 5 minutes.
+	ToolCallTimeoutMS *int `json:"tool_call_timeout_ms,omitempty"`
+
+	// ToolResultOffloadBytes is the serialized size above which a tool result is stored out of band.
+	//
+	// An offloaded result keeps only a truncated preview in the conversation plus a content_ref the
+	// model can page through with read_tool_output. Defaults to 4096 bytes.
+	ToolResultOffloadBytes *int `json:"tool_result_offload_bytes,omitempty"`
+
+	// RunWebhooks notifies integrators when a run of a namespace ends.
+	//
+	// Keys are namespace public ids. Namespaces without an entry are not notified.
+	// The optional HMAC signing secret is kept in secrets.json, not in this file.
+	RunWebhooks map[string]AIRunWebhook `json:"run_webhooks,omitempty"`
+
+	// TerminalCommandPolicy allows or denies terminal.exec commands by regular expression.
+	//
+	// It applies even when the session may execute, and is checked before approval and execution.
+	TerminalCommandPolicy *AITerminalCommandPolicy `json:"terminal_command_policy,omitempty"`
+
+	// Tracing exports OpenTelemetry spans for runs, provider turns, and tool calls.
+	//
+	// Nil disables tracing. Changes apply after the agent restarts.
+	Tracing *AITracingConfig `json:"tracing,omitempty"`
+
+	// RoutingRules pin a run's intent and/or mode when the user input matches a pattern.
+	//
+	// Rules are checked in order before the intent classifier; the first match wins and the matched
+	// trigger text is removed from the input sent to the model.
+	RoutingRules []AIRoutingRule `json:"routing_rules,omitempty"`
+
+	// EnableDegradedSummaryFallback ends a run whose final summary turn failed, and that has no assistant
+	// text yet, with a summary built from recorded progress (done, not done, next actions) instead of an
+	// ask_user prompt. It keeps runs actionable when the provider is down.
+	//
+	// Disabled by default.
+	EnableDegradedSummaryFallback bool `json:"enable_degraded_summary_fallback,omitempty"`
+}
+
+type AIRoutingRule struct {
+	// Pattern is a regular expression matched against the trimmed user input, for example "^/plan\\b".
+	Pattern string `json:"pattern"`
+
+	// Intent pins the run intent: "social", "creative", or "task". Empty leaves the intent to the classifier.
+	Intent string `json:"intent,omitempty"`
+
+	// Mode runs the turn in "act" or "plan" mode instead of the thread's mode. Empty keeps the thread's mode.
+	Mode string `json:"mode,omitempty"`
+}
+
+type AIRunWebhook struct {
+	// URL receives a POST with a compact JSON summary of every ended run (http or https).
+	URL string `json:"url"`
 }
 
 type AIExecutionPolicy struct {
@@ -94,6 +261,306 @@ type AITerminalExecPolicy struct {
 	MaxTimeoutMS *int `json:"max_timeout_ms,omitempty"`
 }
 
+type AIThreadConcurrencyPolicy struct {
+	// OnBusy is one of:
+	// - "reject": fail the second run immediately (default)
+	// - "queue": wait until the active run on the thread finishes, then start
+	OnBusy string `json:"on_busy,omitempty"`
+
+	// QueueTimeoutMS bounds how long a queued run waits for the thread to become idle.
+	//
+	// Defaults to 2 minutes.
+	QueueTimeoutMS *int `json:"queue_timeout_ms,omitempty"`
+}
+
+type AIRunConcurrencyPolicy struct {
+	// MaxRuns caps the runs executing at once across the agent. 0 means no cap.
+	MaxRuns int `json:"max_runs,omitempty"`
+
+	// MaxRunsPerNamespace caps the runs executing at once within one namespace. 0 means no cap.
+	MaxRunsPerNamespace int `json:"max_runs_per_namespace,omitempty"`
+
+	// OnLimit is one of:
+	// - "reject": fail the new run immediately with too_many_runs (default)
+	// - "queue": wait until a running run finishes, then start
+	OnLimit string `json:"on_limit,omitempty"`
+
+	// QueueTimeoutMS bounds how long a queued run waits for a free slot.
+	//
+	// Defaults to 2 minutes.
+	QueueTimeoutMS *int `json:"queue_timeout_ms,omitempty"`
+}
+
+type AIThreadTitlePolicy struct {
+	// FromFirstExchange retitles a thread after its first successful run, from the first user message and the
+	// assistant's answer. Only empty, placeholder, or generated titles are replaced; a title set by the user never is.
+	FromFirstExchange bool `json:"from_first_exchange,omitempty"`
+
+	// Model is the "<provider_id>/<model_name>" used for title generation, typically a cheap one.
+	//
+	// Empty uses the thread's model.
+	Model string `json:"model,omitempty"`
+
+	// Placeholders are extra titles treated as unset, matched case-insensitively, on top of the built-in
+	// ones such as "New chat" and "Untitled".
+	Placeholders []string `json:"placeholders,omitempty"`
+}
+
+type AIToolRateLimitPolicy struct {
+	// CallsPerMinute maps a tool name (for example "web.search") to its per-minute call budget.
+	CallsPerMinute map[string]int `json:"calls_per_minute,omitempty"`
+
+	// MaxWaitMS bounds how long an over-limit call is delayed before it is rejected with tool.rate_limited.
+	//
+	// 0 rejects over-limit calls immediately. Defaults to 5 seconds.
+	MaxWaitMS *int `json:"max_wait_ms,omitempty"`
+}
+
+const (
+	AIToolApprovalAlways          = "always"
+	AIToolApprovalNever           = "never"
+	AIToolApprovalFirstTimePerRun = "first_time_per_run"
+	AIToolApprovalMatchArgs       = "match_args"
+)
+
+type AIToolApprovalPolicy struct {
+	// Rules are evaluated in order; the first rule that matches a tool call decides.
+	Rules []AIToolApprovalRule `json:"rules,omitempty"`
+
+	// AllowRelax lets "never" and "first_time_per_run" rules skip approvals that
+	// execution_policy.require_user_approval would require. Off by default, so rules only make approval stricter.
+	AllowRelax bool `json:"allow_relax,omitempty"`
+
+	// compiled caches the rules compiled by Validate.
+	compiled []CompiledToolApprovalRule
+}
+
+type AIToolApprovalRule struct {
+	// Tool is the tool name (for example "terminal.exec"), or "*" for every tool.
+	Tool string `json:"tool"`
+
+	// Mode is one of:
+	// - "always": every call needs approval
+	// - "never": calls run without approval
+	// - "first_time_per_run": the first call of the tool in a run needs approval; later calls do not
+	// - "match_args": calls whose arguments match Args need approval; other calls fall through to the next rule
+	Mode string `json:"mode"`
+
+	// Args maps argument names to regular expressions (match_args only).
+	//
+	// A call matches when every listed argument is present and matches its expression.
+	Args map[string]string `json:"args,omitempty"`
+}
+
+// CompiledToolApprovalRule is an AIToolApprovalRule with its argument expressions compiled.
+type CompiledToolApprovalRule struct {
+	Tool string
+	Mode string
+	// ArgNames lists the match_args argument names in sorted order; ArgExprs holds the expression for each name.
+	ArgNames []string
+	ArgExprs []*regexp.Regexp
+}
+
+// CompiledRules returns the rules with their argument expressions compiled.
+//
+// Validate compiles the rules once and caches the result; a policy that was never validated is compiled on each call.
+func (p *AIToolApprovalPolicy) CompiledRules() ([]CompiledToolApprovalRule, error) {
+	if p == nil {
+		return nil, nil
+	}
+	if p.compiled != nil {
+		return p.compiled, nil
+	}
+	return compileToolApprovalRules(p.Rules)
+}
+
+func compileToolApprovalRules(rules []AIToolApprovalRule) ([]CompiledToolApprovalRule, error) {
+	out := make([]CompiledToolApprovalRule, 0, len(rules))
+	for i, rule := range rules {
+		compiled := CompiledToolApprovalRule{Tool: strings.TrimSpace(rule.Tool), Mode: strings.TrimSpace(rule.Mode)}
+		if compiled.Tool == "" {
+			return nil, fmt.Errorf("invalid tool_approval.rules[%d]: missing tool", i)
+		}
+		switch compiled.Mode {
+		case AIToolApprovalAlways, AIToolApprovalNever, AIToolApprovalFirstTimePerRun:
+			if len(rule.Args) > 0 {
+				return nil, fmt.Errorf("invalid tool_approval.rules[%d]: args is only supported for mode %q", i, AIToolApprovalMatchArgs)
+			}
+		case AIToolApprovalMatchArgs:
+			if len(rule.Args) == 0 {
+				return nil, fmt.Errorf("invalid tool_approval.rules[%d]: mode %q requires args", i, AIToolApprovalMatchArgs)
+			}
+			names := make([]string, 0, len(rule.Args))
+			for name := range rule.Args {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if strings.TrimSpace(name) == "" {
+					return nil, fmt.Errorf("invalid tool_approval.rules[%d].args: empty argument name", i)
+				}
+				re, err := regexp.Compile(rule.Args[name])
+				if err != nil {
+					return nil, fmt.Errorf("invalid tool_approval.rules[%d].args[%q]: %w", i, name, err)
+				}
+				compiled.ArgNames = append(compiled.ArgNames, strings.TrimSpace(name))
+				compiled.ArgExprs = append(compiled.ArgExprs, re)
+			}
+		default:
+			return nil, fmt.Errorf("invalid tool_approval.rules[%d].mode %q", i, rule.Mode)
+		}
+		out = append(out, compiled)
+	}
+	return out, nil
+}
+
+const (
+	AITerminalCommandPolicyEnforce = "enforce"
+	AITerminalCommandPolicyWarn    = "warn"
+)
+
+// AITerminalCommandPolicy matches its expressions against each simple command of a chain or pipeline,
+// not the full command string.
+type AITerminalCommandPolicy struct {
+	// Allow lists regular expressions every command segment must match; empty allows every command not denied.
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny lists regular expressions that block a command when any segment matches. Deny wins over Allow.
+	Deny []string `json:"deny,omitempty"`
+
+	// Mode is one of:
+	// - "enforce": matching commands are rejected (default)
+	// - "warn": matching commands are logged and audited, then run
+	Mode string `json:"mode,omitempty"`
+
+	// compiled caches the expressions compiled by Validate.
+	compiled *CompiledTerminalCommandPolicy
+}
+
+// CompiledTerminalCommandPolicy holds the compiled Allow and Deny expressions, in config order.
+type CompiledTerminalCommandPolicy struct {
+	Allow []*regexp.Regexp
+	Deny  []*regexp.Regexp
+}
+
+// Compiled returns the policy with its expressions compiled.
+//
+// Validate compiles the expressions once and caches the result; a policy that was never validated is compiled on each call.
+func (p *AITerminalCommandPolicy) Compiled() (*CompiledTerminalCommandPolicy, error) {
+	if p == nil {
+		return nil, nil
+	}
+	if p.compiled != nil {
+		return p.compiled, nil
+	}
+	return compileTerminalCommandPolicy(p)
+}
+
+func compileTerminalCommandPolicy(p *AITerminalCommandPolicy) (*CompiledTerminalCommandPolicy, error) {
+	compile := func(name string, exprs []string) ([]*regexp.Regexp, error) {
+		out := make([]*regexp.Regexp, 0, len(exprs))
+		for i, expr := range exprs {
+			if strings.TrimSpace(expr) == "" {
+				return nil, fmt.Errorf("invalid terminal_command_policy.%s[%d]: empty expression", name, i)
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid terminal_command_policy.%s[%d]: %w", name, i, err)
+			}
+			out = append(out, re)
+		}
+		return out, nil
+	}
+	allow, err := compile("allow", p.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := compile("deny", p.Deny)
+	if err != nil {
+		return nil, err
+	}
+	return &CompiledTerminalCommandPolicy{Allow: allow, Deny: deny}, nil
+}
+
+type AITracingConfig struct {
+	// Endpoint is the OTLP/HTTP traces URL (http or https), for example http://127.0.0.1:4318/v1/traces.
+	Endpoint string `json:"endpoint"`
+
+	// ServiceName is the service.name resource attribute. Defaults to "redeven-agent".
+	ServiceName string `json:"service_name,omitempty"`
+
+	// SampleRatio is the fraction of runs traced, in (0,1]. Defaults to 1.
+	SampleRatio *float64 `json:"sample_ratio,omitempty"`
+}
+
+type AIRunAutoRetryPolicy struct {
+	// MaxRetries is the number of whole-run retries. 0 disables auto-retry.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// InitialBackoffMS is the delay before the first retry; each further retry doubles it.
+	//
+	// Defaults to 1 second.
+	InitialBackoffMS *int `json:"initial_backoff_ms,omitempty"`
+}
+
+type AIAttachmentLimitPolicy struct {
+	// MaxPerMessage caps attachments per user message. Defaults to 20.
+	MaxPerMessage *int `json:"max_per_message,omitempty"`
+
+	// OnExcess is one of:
+	// - "truncate": keep the first max_per_message attachments and note the dropped ones (default)
+	// - "reject": fail the run with a clear error before any model call
+	//
+	// The same policy applies to image attachments that fail validation: "truncate" drops them with a note.
+	OnExcess string `json:"on_excess,omitempty"`
+
+	// MaxImageBytes caps the size of one image attachment. Defaults to 20 MiB.
+	MaxImageBytes *int64 `json:"max_image_bytes,omitempty"`
+}
+
+type AIProviderRetryPolicy struct {
+	// MaxRetries is the number of retries per model turn. 0 disables retries. Defaults to 2.
+	MaxRetries *int `json:"max_retries,omitempty"`
+
+	// InitialBackoffMS is the base delay before the first retry; each further retry doubles it, with jitter.
+	// A Retry-After header from the provider takes precedence.
+	//
+	// Defaults to 500ms.
+	InitialBackoffMS *int `json:"initial_backoff_ms,omitempty"`
+}
+
+type AIWebSearchCachePolicy struct {
+	// TTLSeconds is how long a cached result stays valid. 0 disables the cache. Defaults to 300.
+	TTLSeconds *int `json:"ttl_seconds,omitempty"`
+
+	// MaxEntries caps the number of cached queries; the least recently used entry is evicted first.
+	//
+	// Defaults to 128.
+	MaxEntries *int `json:"max_entries,omitempty"`
+}
+
+type AIProviderCircuitBreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failed model turns that opens the breaker. 0 disables it.
+	//
+	// Defaults to 5.
+	FailureThreshold *int `json:"failure_threshold,omitempty"`
+
+	// WindowSeconds bounds how far apart the counted failures may be; an older failure restarts the count.
+	//
+	// Defaults to 120.
+	WindowSeconds *int `json:"window_seconds,omitempty"`
+
+	// CooldownSeconds is how long an open breaker rejects runs before it lets one trial run through.
+	//
+	// Defaults to 60.
+	CooldownSeconds *int `json:"cooldown_seconds,omitempty"`
+}
+
+const (
+	AIAttachmentExcessTruncate = "truncate"
+	AIAttachmentExcessReject   = "reject"
+)
+
 type AIProvider struct {
 	// ID is a stable internal id (primary key). It must not change once used for secrets/model routing.
 	ID string `json:"id"`
@@ -109,6 +576,11 @@ type AIProvider struct {
 	// - "deepseek"
 	// - "qwen"
 	// - "openai_compatible"
+	// - "ollama" (local inference; no API key required)
+	// - "azure_openai"
+	// - "mistral" (Mistral la Plateforme)
+	// - "bedrock" (Anthropic Claude on AWS Bedrock; SigV4-signed with AWS credentials, no API key required)
+	// - "replay" (offline eval; feeds back turns captured by a provider recording, no API key required)
 	Type string `json:"type"`
 
 	// BaseURL overrides the provider endpoint (example: "https://api.openai.com/v1").
@@ -120,19 +592,81 @@ type AIProvider struct {
 	// - deepseek
 	// - qwen
 	// - openai_compatible
+	// - azure_openai (the resource endpoint, example: "https://my-resource.openai.azure.com")
+	//
+	// ollama defaults to DefaultOllamaBaseURL; mistral defaults to DefaultMistralBaseURL;
+	// bedrock defaults to the bedrock-runtime endpoint of Region.
 	BaseURL string `json:"base_url,omitempty"`
 
+	// Region is the AWS region of the Bedrock runtime (bedrock only).
+	//
+	// When empty, the region of the AWS environment (AWS_REGION or the shared config profile) applies.
+	Region string `json:"region,omitempty"`
+
+	// Deployment is the Azure OpenAI deployment that serves every model of this provider (azure_openai only).
+	//
+	// When empty, each model name is used as its deployment name.
+	Deployment string `json:"deployment,omitempty"`
+
+	// APIVersion is the Azure OpenAI api-version query parameter (azure_openai only).
+	//
+	// When empty, DefaultAzureOpenAIAPIVersion applies.
+	APIVersion string `json:"api_version,omitempty"`
+
+	// RecordingPath is the provider recording (JSONL) replayed by this provider (replay only).
+	RecordingPath string `json:"recording_path,omitempty"`
+
 	// StrictToolSchema overrides provider tool schema strictness.
 	//
 	// When unset, runtime falls back to built-in policy:
 	// - openai official endpoints: strict
 	// - openai custom gateways: non-strict
 	// - openai_compatible: non-strict
-	// - moonshot/chatglm/deepseek/qwen: non-strict
+	// - moonshot/chatglm/deepseek/qwen/ollama/azure_openai/mistral: non-strict
 	StrictToolSchema *bool `json:"strict_tool_schema,omitempty"`
 
+	// ToolCallFormat selects how tool calls are exchanged with the model.
+	//
+	// Supported values:
+	// - "native": provider function/tool calling APIs (default)
+	// - "react_text": tools are described in the prompt and the model writes <tool_call> JSON blocks
+	//
+	// Use "react_text" only for models/gateways without reliable native function calling.
+	ToolCallFormat string `json:"tool_call_format,omitempty"`
+
+	// RequestTimeoutMS caps one provider request, including reading its streamed response.
+	//
+	// When unset, requests are only bounded by the run wall time and idle timeout.
+	RequestTimeoutMS *int `json:"request_timeout_ms,omitempty"`
+
+	// StreamStallTimeoutMS aborts a provider turn whose stream delivers no event for this long,
+	// so the run recovers instead of waiting on a silently stalled gateway.
+	//
+	// When unset, DefaultAIProviderStreamStallTimeoutMS applies. 0 disables the watchdog.
+	StreamStallTimeoutMS *int `json:"stream_stall_timeout_ms,omitempty"`
+
 	// Models is the allowed model list for this provider (shown in the Chat UI).
 	Models []AIProviderModel `json:"models,omitempty"`
+
+	// ModelPricing lists per-model token prices used for run cost accounting (RunOptions.MaxCostUSD).
+	//
+	// Models without an entry are treated as unknown pricing: cost is not accumulated and budgets are not enforced.
+	ModelPricing []AIModelPricing `json:"model_pricing,omitempty"`
+}
+
+type AIModelPricing struct {
+	ModelName string `json:"model_name"`
+
+	// InputUSDPerMTok is the price in USD per one million input tokens.
+	InputUSDPerMTok float64 `json:"input_usd_per_mtok"`
+
+	// OutputUSDPerMTok is the price in USD per one million output tokens.
+	OutputUSDPerMTok float64 `json:"output_usd_per_mtok"`
+
+	// ReasoningUSDPerMTok is the price in USD per one million reasoning tokens.
+	//
+	// Defaults to OutputUSDPerMTok when unset.
+	ReasoningUSDPerMTok *float64 `json:"reasoning_usd_per_mtok,omitempty"`
 }
 
 type AIProviderModel struct {
@@ -140,6 +674,20 @@ type AIProviderModel struct {
 	ContextWindow                 int    `json:"context_window,omitempty"`
 	MaxOutputTokens               int    `json:"max_output_tokens,omitempty"`
 	EffectiveContextWindowPercent int    `json:"effective_context_window_percent,omitempty"`
+
+	// DefaultTemperature and DefaultTopP apply when a run does not set sampling explicitly.
+	DefaultTemperature *float64 `json:"default_temperature,omitempty"`
+	DefaultTopP        *float64 `json:"default_top_p,omitempty"`
+
+	// OmitSamplingParams overrides built-in detection of models that reject temperature/top_p.
+	//
+	// When true, temperature/top_p are never sent to this model, even if a run sets them.
+	OmitSamplingParams *bool `json:"omit_sampling_params,omitempty"`
+
+	// ImageOutput lets the model generate images through the Responses API image_generation tool.
+	//
+	// Only openai/azure_openai providers support it; generated images are stored as uploads.
+	ImageOutput bool `json:"image_output,omitempty"`
 }
 
 const (
@@ -147,6 +695,32 @@ const (
 	AIModePlan = "plan"
 )
 
+const (
+	AIToolCallFormatNative    = "native"
+	AIToolCallFormatReActText = "react_text"
+)
+
+const (
+	AIPersistenceModeFull        = "full"
+	AIPersistenceModeSummaryOnly = "summary_only"
+)
+
+const (
+	AIEmptyObjectiveOff     = "off"
+	AIEmptyObjectiveDerive  = "derive"
+	AIEmptyObjectiveClarify = "clarify"
+)
+
+const (
+	AIThreadBusyReject = "reject"
+	AIThreadBusyQueue  = "queue"
+)
+
+const (
+	AIRunLimitReject = "reject"
+	AIRunLimitQueue  = "queue"
+)
+
 const (
 	defaultAIToolRecoveryEnabled                 = true
 	defaultAIToolRecoveryMaxSteps                = 3
@@ -160,6 +734,59 @@ const (
 	defaultAITerminalExecDefaultTimeoutMS = 120_000
 	defaultAITerminalExecMaxTimeoutMS     = 600_000
 
+	defaultAIToolCallTimeoutMS = 300_000
+	maxAIToolCallTimeoutMS     = 3_600_000
+
+	defaultAITracingServiceName = "redeven-agent"
+
+	defaultAIToolResultOffloadBytes = 4_096
+	minAIToolResultOffloadBytes     = 1_024
+	maxAIToolResultOffloadBytes     = 1_048_576
+
+	defaultAIThreadBusyQueueTimeoutMS = 120_000
+	maxAIThreadBusyQueueTimeoutMS     = 900_000
+
+	defaultAIRunLimitQueueTimeoutMS = 120_000
+	maxAIRunLimitQueueTimeoutMS     = 900_000
+	maxAIConcurrentRuns             = 1_000
+
+	defaultAIToolRateLimitMaxWaitMS = 5_000
+	maxAIToolRateLimitMaxWaitMS     = 60_000
+	maxAIToolCallsPerMinute         = 10_000
+
+	maxAIRunAutoRetries                   = 5
+	defaultAIRunAutoRetryInitialBackoffMS = 1_000
+	maxAIRunAutoRetryInitialBackoffMS     = 30_000
+
+	defaultAIMaxAttachmentsPerMessage = 20
+	maxAIMaxAttachmentsPerMessage     = 200
+	defaultAIMaxImageBytes            = 20 << 20
+	minAIMaxImageBytes                = 1 << 10
+	maxAIMaxImageBytes                = 100 << 20
+
+	defaultAIProviderRetries               = 2
+	maxAIProviderRetries                   = 10
+	defaultAIProviderRetryInitialBackoffMS = 500
+	maxAIProviderRetryInitialBackoffMS     = 30_000
+
+	minAIProviderRequestTimeoutMS = 1_000
+	maxAIProviderRequestTimeoutMS = 3_600_000
+
+	minAIProviderStreamStallTimeoutMS = 5_000
+	maxAIProviderStreamStallTimeoutMS = 3_600_000
+
+	defaultAIProviderCircuitFailureThreshold = 5
+	maxAIProviderCircuitFailureThreshold     = 100
+	defaultAIProviderCircuitWindowSeconds    = 120
+	maxAIProviderCircuitWindowSeconds        = 3_600
+	defaultAIProviderCircuitCooldownSeconds  = 60
+	maxAIProviderCircuitCooldownSeconds      = 3_600
+
+	defaultAIWebSearchCacheTTLSeconds = 300
+	maxAIWebSearchCacheTTLSeconds     = 86_400
+	defaultAIWebSearchCacheMaxEntries = 128
+	maxAIWebSearchCacheMaxEntries     = 10_000
+
 	defaultAIWebSearchProvider                 = "prefer_openai"
 	defaultAIEffectiveContextWindowPercent int = 95
 )
@@ -187,9 +814,105 @@ func (m AIProviderModel) EffectiveInputWindowTokens() int {
 	return effective
 }
 
+// PricingForModel returns the configured token pricing for modelName.
+func (p AIProvider) PricingForModel(modelName string) (AIModelPricing, bool) {
+	modelName = strings.TrimSpace(modelName)
+	if modelName == "" {
+		return AIModelPricing{}, false
+	}
+	for _, pricing := range p.ModelPricing {
+		if strings.TrimSpace(pricing.ModelName) == modelName {
+			return pricing, true
+		}
+	}
+	return AIModelPricing{}, false
+}
+
+// EffectiveReasoningUSDPerMTok returns the reasoning-token price, falling back to the output price.
+func (m AIModelPricing) EffectiveReasoningUSDPerMTok() float64 {
+	if m.ReasoningUSDPerMTok != nil {
+		return *m.ReasoningUSDPerMTok
+	}
+	return m.OutputUSDPerMTok
+}
+
+// EffectiveRequestTimeoutMS returns the per-request timeout for the provider, or 0 when requests are not capped.
+func (p AIProvider) EffectiveRequestTimeoutMS() int {
+	if p.RequestTimeoutMS == nil {
+		return 0
+	}
+	return *p.RequestTimeoutMS
+}
+
+// DefaultAIProviderStreamStallTimeoutMS is the stream stall watchdog interval of providers that leave
+// stream_stall_timeout_ms unset. It is long enough for reasoning models that think silently before the first token.
+const DefaultAIProviderStreamStallTimeoutMS = 180_000
+
+// EffectiveStreamStallTimeoutMS returns the stream stall watchdog interval for the provider, or 0 when it is disabled.
+func (p AIProvider) EffectiveStreamStallTimeoutMS() int {
+	if p.StreamStallTimeoutMS == nil {
+		return DefaultAIProviderStreamStallTimeoutMS
+	}
+	return *p.StreamStallTimeoutMS
+}
+
+// EffectiveToolCallFormat returns the normalized tool-calling format for the provider.
+func (p AIProvider) EffectiveToolCallFormat() string {
+	switch strings.TrimSpace(strings.ToLower(p.ToolCallFormat)) {
+	case AIToolCallFormatReActText:
+		return AIToolCallFormatReActText
+	default:
+		return AIToolCallFormatNative
+	}
+}
+
+// maxAITemperatureForProviderType returns the upper bound of the provider's accepted temperature range.
+func maxAITemperatureForProviderType(providerType string) float64 {
+	switch strings.ToLower(strings.TrimSpace(providerType)) {
+	case "anthropic", "bedrock", "moonshot", "chatglm":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// DefaultOllamaBaseURL is the OpenAI-compatible endpoint of a local Ollama server.
+const DefaultOllamaBaseURL = "http://localhost:11434/v1"
+
+// DefaultMistralBaseURL is the chat-completions endpoint of Mistral la Plateforme.
+const DefaultMistralBaseURL = "https://api.mistral.ai/v1"
+
+// DefaultAzureOpenAIAPIVersion is the Azure OpenAI api-version used when a provider leaves api_version empty.
+const DefaultAzureOpenAIAPIVersion = "2025-04-01-preview"
+
+// AIProviderTypeRequiresAPIKey reports whether runs against the provider type need a stored API key.
+func AIProviderTypeRequiresAPIKey(providerType string) bool {
+	switch strings.ToLower(strings.TrimSpace(providerType)) {
+	case "ollama", "replay", "bedrock":
+		return false
+	default:
+		return true
+	}
+}
+
+var bedrockModelIDRE = regexp.MustCompile(`^(?:[a-z-]+\.)?anthropic\.(claude-[a-z0-9.-]+?)(?:-v\d+(?::\d+)?)?$`)
+
+// BedrockClaudeModelName returns the Anthropic model name of a Bedrock model id.
+//
+// It accepts foundation model ids ("anthropic.claude-sonnet-4-5-20250929-v1:0") and cross-region
+// inference profile ids ("us.anthropic.claude-sonnet-4-5-20250929-v1:0"), which both map to
+// "claude-sonnet-4-5-20250929". ok is false for ids of other model families.
+func BedrockClaudeModelName(modelID string) (name string, ok bool) {
+	m := bedrockModelIDRE.FindStringSubmatch(strings.ToLower(strings.TrimSpace(modelID)))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
 func requiresExplicitAIProviderBaseURL(providerType string) bool {
 	switch strings.ToLower(strings.TrimSpace(providerType)) {
-	case "moonshot", "chatglm", "deepseek", "qwen", "openai_compatible":
+	case "moonshot", "chatglm", "deepseek", "qwen", "openai_compatible", "azure_openai":
 		return true
 	default:
 		return false
@@ -245,6 +968,228 @@ func (c *AIConfig) Validate() error {
 			}
 		}
 	}
+	switch strings.TrimSpace(strings.ToLower(c.PersistenceMode)) {
+	case "", AIPersistenceModeFull, AIPersistenceModeSummaryOnly:
+	default:
+		return fmt.Errorf("invalid persistence_mode %q", c.PersistenceMode)
+	}
+	switch strings.TrimSpace(strings.ToLower(c.EmptyObjectivePolicy)) {
+	case "", AIEmptyObjectiveOff, AIEmptyObjectiveDerive, AIEmptyObjectiveClarify:
+	default:
+		return fmt.Errorf("invalid empty_objective_policy %q", c.EmptyObjectivePolicy)
+	}
+	if c.ThreadConcurrency != nil {
+		switch strings.TrimSpace(strings.ToLower(c.ThreadConcurrency.OnBusy)) {
+		case "", AIThreadBusyReject, AIThreadBusyQueue:
+		default:
+			return fmt.Errorf("invalid thread_concurrency.on_busy %q", c.ThreadConcurrency.OnBusy)
+		}
+		if c.ThreadConcurrency.QueueTimeoutMS != nil {
+			v := *c.ThreadConcurrency.QueueTimeoutMS
+			if v < 1 || v > maxAIThreadBusyQueueTimeoutMS {
+				return fmt.Errorf("invalid thread_concurrency.queue_timeout_ms %d (must be in [1,%d])", v, maxAIThreadBusyQueueTimeoutMS)
+			}
+		}
+	}
+	if c.RunConcurrency != nil {
+		if v := c.RunConcurrency.MaxRuns; v < 0 || v > maxAIConcurrentRuns {
+			return fmt.Errorf("invalid run_concurrency.max_runs %d (must be in [0,%d])", v, maxAIConcurrentRuns)
+		}
+		if v := c.RunConcurrency.MaxRunsPerNamespace; v < 0 || v > maxAIConcurrentRuns {
+			return fmt.Errorf("invalid run_concurrency.max_runs_per_namespace %d (must be in [0,%d])", v, maxAIConcurrentRuns)
+		}
+		switch strings.TrimSpace(strings.ToLower(c.RunConcurrency.OnLimit)) {
+		case "", AIRunLimitReject, AIRunLimitQueue:
+		default:
+			return fmt.Errorf("invalid run_concurrency.on_limit %q", c.RunConcurrency.OnLimit)
+		}
+		if c.RunConcurrency.QueueTimeoutMS != nil {
+			v := *c.RunConcurrency.QueueTimeoutMS
+			if v < 1 || v > maxAIRunLimitQueueTimeoutMS {
+				return fmt.Errorf("invalid run_concurrency.queue_timeout_ms %d (must be in [1,%d])", v, maxAIRunLimitQueueTimeoutMS)
+			}
+		}
+	}
+	if c.ThreadTitles != nil {
+		if model := strings.TrimSpace(c.ThreadTitles.Model); model != "" && !c.IsAllowedModelID(model) {
+			return fmt.Errorf("invalid thread_titles.model %q", c.ThreadTitles.Model)
+		}
+		for _, placeholder := range c.ThreadTitles.Placeholders {
+			if strings.TrimSpace(placeholder) == "" {
+				return errors.New("invalid thread_titles.placeholders: empty title")
+			}
+		}
+	}
+	if c.ToolCallTimeoutMS != nil {
+		v := *c.ToolCallTimeoutMS
+		if v < 1 || v > maxAIToolCallTimeoutMS {
+			return fmt.Errorf("invalid tool_call_timeout_ms %d (must be in [1,%d])", v, maxAIToolCallTimeoutMS)
+		}
+	}
+	if c.ToolResultOffloadBytes != nil {
+		v := *c.ToolResultOffloadBytes
+		if v < minAIToolResultOffloadBytes || v > maxAIToolResultOffloadBytes {
+			return fmt.Errorf("invalid tool_result_offload_bytes %d (must be in [%d,%d])", v, minAIToolResultOffloadBytes, maxAIToolResultOffloadBytes)
+		}
+	}
+	if c.ToolRateLimit != nil {
+		for name, v := range c.ToolRateLimit.CallsPerMinute {
+			if strings.TrimSpace(name) == "" {
+				return errors.New("invalid tool_rate_limit.calls_per_minute: empty tool name")
+			}
+			if v < 1 || v > maxAIToolCallsPerMinute {
+				return fmt.Errorf("invalid tool_rate_limit.calls_per_minute[%q] %d (must be in [1,%d])", name, v, maxAIToolCallsPerMinute)
+			}
+		}
+		if c.ToolRateLimit.MaxWaitMS != nil {
+			v := *c.ToolRateLimit.MaxWaitMS
+			if v < 0 || v > maxAIToolRateLimitMaxWaitMS {
+				return fmt.Errorf("invalid tool_rate_limit.max_wait_ms %d (must be in [0,%d])", v, maxAIToolRateLimitMaxWaitMS)
+			}
+		}
+	}
+	for language, phrases := range c.FallbackPhrases {
+		if strings.TrimSpace(language) == "" {
+			return errors.New("invalid fallback_phrases: empty language code")
+		}
+		for i, phrase := range phrases {
+			if strings.TrimSpace(phrase) == "" {
+				return fmt.Errorf("invalid fallback_phrases[%q][%d]: empty phrase", language, i)
+			}
+		}
+	}
+	for key, names := range c.ToolAllowlists {
+		switch key {
+		case AIModeAct, AIModePlan, PermissionPresetReadOnly, PermissionPresetExecuteRead, PermissionPresetExecuteReadWrite:
+		default:
+			return fmt.Errorf("invalid tool_allowlists key %q (must be a mode or permission preset)", key)
+		}
+		for _, name := range names {
+			if _, ok := aitools.LookupDefinition(name); !ok {
+				return fmt.Errorf("invalid tool_allowlists[%q]: unknown tool %q", key, name)
+			}
+		}
+	}
+	if c.ToolApproval != nil && c.ToolApproval.compiled == nil {
+		rules, err := compileToolApprovalRules(c.ToolApproval.Rules)
+		if err != nil {
+			return err
+		}
+		c.ToolApproval.compiled = rules
+	}
+	if c.TerminalCommandPolicy != nil {
+		switch strings.TrimSpace(c.TerminalCommandPolicy.Mode) {
+		case "", AITerminalCommandPolicyEnforce, AITerminalCommandPolicyWarn:
+		default:
+			return fmt.Errorf("invalid terminal_command_policy.mode %q", c.TerminalCommandPolicy.Mode)
+		}
+		if c.TerminalCommandPolicy.compiled == nil {
+			compiled, err := compileTerminalCommandPolicy(c.TerminalCommandPolicy)
+			if err != nil {
+				return err
+			}
+			c.TerminalCommandPolicy.compiled = compiled
+		}
+	}
+	for i, rule := range c.RoutingRules {
+		if strings.TrimSpace(rule.Pattern) == "" {
+			return fmt.Errorf("invalid routing_rules[%d]: missing pattern", i)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid routing_rules[%d].pattern: %w", i, err)
+		}
+		switch strings.ToLower(strings.TrimSpace(rule.Intent)) {
+		case "", "social", "creative", "task":
+		default:
+			return fmt.Errorf("invalid routing_rules[%d].intent %q", i, rule.Intent)
+		}
+		switch strings.ToLower(strings.TrimSpace(rule.Mode)) {
+		case "", AIModeAct, AIModePlan:
+		default:
+			return fmt.Errorf("invalid routing_rules[%d].mode %q", i, rule.Mode)
+		}
+		if strings.TrimSpace(rule.Intent) == "" && strings.TrimSpace(rule.Mode) == "" {
+			return fmt.Errorf("invalid routing_rules[%d]: intent or mode is required", i)
+		}
+	}
+	if c.RunAutoRetry != nil {
+		if v := c.RunAutoRetry.MaxRetries; v < 0 || v > maxAIRunAutoRetries {
+			return fmt.Errorf("invalid run_auto_retry.max_retries %d (must be in [0,%d])", v, maxAIRunAutoRetries)
+		}
+		if c.RunAutoRetry.InitialBackoffMS != nil {
+			v := *c.RunAutoRetry.InitialBackoffMS
+			if v < 0 || v > maxAIRunAutoRetryInitialBackoffMS {
+				return fmt.Errorf("invalid run_auto_retry.initial_backoff_ms %d (must be in [0,%d])", v, maxAIRunAutoRetryInitialBackoffMS)
+			}
+		}
+	}
+	if c.AttachmentLimit != nil {
+		if c.AttachmentLimit.MaxPerMessage != nil {
+			v := *c.AttachmentLimit.MaxPerMessage
+			if v < 1 || v > maxAIMaxAttachmentsPerMessage {
+				return fmt.Errorf("invalid attachment_limit.max_per_message %d (must be in [1,%d])", v, maxAIMaxAttachmentsPerMessage)
+			}
+		}
+		switch strings.TrimSpace(strings.ToLower(c.AttachmentLimit.OnExcess)) {
+		case "", AIAttachmentExcessTruncate, AIAttachmentExcessReject:
+		default:
+			return fmt.Errorf("invalid attachment_limit.on_excess %q", c.AttachmentLimit.OnExcess)
+		}
+		if c.AttachmentLimit.MaxImageBytes != nil {
+			v := *c.AttachmentLimit.MaxImageBytes
+			if v < minAIMaxImageBytes || v > maxAIMaxImageBytes {
+				return fmt.Errorf("invalid attachment_limit.max_image_bytes %d (must be in [%d,%d])", v, minAIMaxImageBytes, maxAIMaxImageBytes)
+			}
+		}
+	}
+	if c.ProviderRetry != nil {
+		if c.ProviderRetry.MaxRetries != nil {
+			v := *c.ProviderRetry.MaxRetries
+			if v < 0 || v > maxAIProviderRetries {
+				return fmt.Errorf("invalid provider_retry.max_retries %d (must be in [0,%d])", v, maxAIProviderRetries)
+			}
+		}
+		if c.ProviderRetry.InitialBackoffMS != nil {
+			v := *c.ProviderRetry.InitialBackoffMS
+			if v < 0 || v > maxAIProviderRetryInitialBackoffMS {
+				return fmt.Errorf("invalid provider_retry.initial_backoff_ms %d (must be in [0,%d])", v, maxAIProviderRetryInitialBackoffMS)
+			}
+		}
+	}
+	if c.ProviderCircuitBreaker != nil {
+		if c.ProviderCircuitBreaker.FailureThreshold != nil {
+			v := *c.ProviderCircuitBreaker.FailureThreshold
+			if v < 0 || v > maxAIProviderCircuitFailureThreshold {
+				return fmt.Errorf("invalid provider_circuit_breaker.failure_threshold %d (must be in [0,%d])", v, maxAIProviderCircuitFailureThreshold)
+			}
+		}
+		if c.ProviderCircuitBreaker.WindowSeconds != nil {
+			v := *c.ProviderCircuitBreaker.WindowSeconds
+			if v < 1 || v > maxAIProviderCircuitWindowSeconds {
+				return fmt.Errorf("invalid provider_circuit_breaker.window_seconds %d (must be in [1,%d])", v, maxAIProviderCircuitWindowSeconds)
+			}
+		}
+		if c.ProviderCircuitBreaker.CooldownSeconds != nil {
+			v := *c.ProviderCircuitBreaker.CooldownSeconds
+			if v < 1 || v > maxAIProviderCircuitCooldownSeconds {
+				return fmt.Errorf("invalid provider_circuit_breaker.cooldown_seconds %d (must be in [1,%d])", v, maxAIProviderCircuitCooldownSeconds)
+			}
+		}
+	}
+	if c.WebSearchCache != nil {
+		if c.WebSearchCache.TTLSeconds != nil {
+			v := *c.WebSearchCache.TTLSeconds
+			if v < 0 || v > maxAIWebSearchCacheTTLSeconds {
+				return fmt.Errorf("invalid web_search_cache.ttl_seconds %d (must be in [0,%d])", v, maxAIWebSearchCacheTTLSeconds)
+			}
+		}
+		if c.WebSearchCache.MaxEntries != nil {
+			v := *c.WebSearchCache.MaxEntries
+			if v < 1 || v > maxAIWebSearchCacheMaxEntries {
+				return fmt.Errorf("invalid web_search_cache.max_entries %d (must be in [1,%d])", v, maxAIWebSearchCacheMaxEntries)
+			}
+		}
+	}
 	// Validate providers.
 	if len(c.Providers) == 0 {
 		return errors.New("missing providers")
@@ -266,10 +1211,43 @@ func (c *AIConfig) Validate() error {
 
 		t := strings.ToLower(strings.TrimSpace(p.Type))
 		switch t {
-		case "openai", "anthropic", "moonshot", "chatglm", "deepseek", "qwen", "openai_compatible":
+		case "openai", "anthropic", "moonshot", "chatglm", "deepseek", "qwen", "openai_compatible", "ollama", "azure_openai", "mistral", "bedrock", "replay":
 		default:
 			return fmt.Errorf("providers[%d]: invalid type %q", i, t)
 		}
+		if t == "replay" && strings.TrimSpace(p.RecordingPath) == "" {
+			return fmt.Errorf("providers[%d]: recording_path is required for replay", i)
+		}
+		if t != "replay" && strings.TrimSpace(p.RecordingPath) != "" {
+			return fmt.Errorf("providers[%d]: recording_path is only supported for replay", i)
+		}
+		if t != "bedrock" && strings.TrimSpace(p.Region) != "" {
+			return fmt.Errorf("providers[%d]: region is only supported for bedrock", i)
+		}
+		if t != "azure_openai" && (strings.TrimSpace(p.Deployment) != "" || strings.TrimSpace(p.APIVersion) != "") {
+			return fmt.Errorf("providers[%d]: deployment and api_version are only supported for azure_openai", i)
+		}
+		if strings.Contains(p.Deployment, "/") {
+			return fmt.Errorf("providers[%d]: invalid deployment %q (must not contain /)", i, p.Deployment)
+		}
+
+		switch strings.TrimSpace(strings.ToLower(p.ToolCallFormat)) {
+		case "", AIToolCallFormatNative, AIToolCallFormatReActText:
+		default:
+			return fmt.Errorf("providers[%d]: invalid tool_call_format %q", i, p.ToolCallFormat)
+		}
+		if p.RequestTimeoutMS != nil {
+			v := *p.RequestTimeoutMS
+			if v < minAIProviderRequestTimeoutMS || v > maxAIProviderRequestTimeoutMS {
+				return fmt.Errorf("providers[%d]: invalid request_timeout_ms %d (must be in [%d,%d])", i, v, minAIProviderRequestTimeoutMS, maxAIProviderRequestTimeoutMS)
+			}
+		}
+		if p.StreamStallTimeoutMS != nil {
+			v := *p.StreamStallTimeoutMS
+			if v != 0 && (v < minAIProviderStreamStallTimeoutMS || v > maxAIProviderStreamStallTimeoutMS) {
+				return fmt.Errorf("providers[%d]: invalid stream_stall_timeout_ms %d (must be 0 or in [%d,%d])", i, v, minAIProviderStreamStallTimeoutMS, maxAIProviderStreamStallTimeoutMS)
+			}
+		}
 
 		baseURL := strings.TrimSpace(p.BaseURL)
 		if requiresExplicitAIProviderBaseURL(t) && baseURL == "" {
@@ -284,8 +1262,23 @@ func (c *AIConfig) Validate() error {
 			if scheme != "http" && scheme != "https" {
 				return fmt.Errorf("providers[%d]: invalid base_url scheme %q", i, u.Scheme)
 			}
-			if strings.TrimSpace(u.Host) == "" {
-				return fmt.Errorf("providers[%d]: invalid base_url host", i)
+			if strings.TrimSpace(u.Host) == "" {
+				return fmt.Errorf("providers[%d]: invalid base_url host", i)
+			}
+		}
+
+		pricedModels := make(map[string]struct{}, len(p.ModelPricing))
+		for j, pricing := range p.ModelPricing {
+			name := strings.TrimSpace(pricing.ModelName)
+			if name == "" {
+				return fmt.Errorf("providers[%d].model_pricing[%d]: missing model_name", i, j)
+			}
+			if _, ok := pricedModels[name]; ok {
+				return fmt.Errorf("providers[%d].model_pricing[%d]: duplicate model_name %q", i, j, name)
+			}
+			pricedModels[name] = struct{}{}
+			if pricing.InputUSDPerMTok < 0 || pricing.OutputUSDPerMTok < 0 || (pricing.ReasoningUSDPerMTok != nil && *pricing.ReasoningUSDPerMTok < 0) {
+				return fmt.Errorf("providers[%d].model_pricing[%d]: prices must not be negative", i, j)
 			}
 		}
 
@@ -303,6 +1296,9 @@ func (c *AIConfig) Validate() error {
 			if strings.Contains(name, "/") {
 				return fmt.Errorf("providers[%d].models[%d]: invalid model_name %q (must not contain /)", i, j, name)
 			}
+			if _, ok := BedrockClaudeModelName(name); t == "bedrock" && !ok {
+				return fmt.Errorf("providers[%d].models[%d]: bedrock model_name %q is not an Anthropic Claude model id (example: anthropic.claude-sonnet-4-5-20250929-v1:0)", i, j, name)
+			}
 			if _, ok := modelNames[name]; ok {
 				return fmt.Errorf("providers[%d].models[%d]: duplicate model_name %q", i, j, name)
 			}
@@ -325,6 +1321,22 @@ func (c *AIConfig) Validate() error {
 				return fmt.Errorf("providers[%d].models[%d]: max_output_tokens %d exceeds context_window %d", i, j, m.MaxOutputTokens, contextWindow)
 			}
 
+			if m.DefaultTemperature != nil {
+				maxTemp := maxAITemperatureForProviderType(t)
+				if v := *m.DefaultTemperature; v < 0 || v > maxTemp {
+					return fmt.Errorf("providers[%d].models[%d]: invalid default_temperature %g (must be in [0,%g] for %s)", i, j, v, maxTemp, t)
+				}
+			}
+			if m.DefaultTopP != nil {
+				if v := *m.DefaultTopP; v <= 0 || v > 1 {
+					return fmt.Errorf("providers[%d].models[%d]: invalid default_top_p %g (must be in (0,1])", i, j, v)
+				}
+			}
+
+			if m.ImageOutput && t != "openai" && t != "azure_openai" {
+				return fmt.Errorf("providers[%d].models[%d]: image_output is only supported for openai and azure_openai providers", i, j)
+			}
+
 			if m.EffectiveContextWindowPercent != 0 {
 				if m.EffectiveContextWindowPercent < 1 || m.EffectiveContextWindowPercent > 100 {
 					return fmt.Errorf("providers[%d].models[%d]: invalid effective_context_window_percent %d (must be in [1,100])", i, j, m.EffectiveContextWindowPercent)
@@ -344,6 +1356,51 @@ func (c *AIConfig) Validate() error {
 		return fmt.Errorf("current_model_id %q is not in providers[].models[]", c.CurrentModelID)
 	}
 
+	for namespace, modelIDs := range c.ModelAllowlist {
+		if strings.TrimSpace(namespace) == "" {
+			return errors.New("invalid model_allowlist: empty namespace")
+		}
+		for _, modelID := range modelIDs {
+			if !c.IsAllowedModelID(modelID) {
+				return fmt.Errorf("model_allowlist[%q]: model %q is not in providers[].models[]", namespace, modelID)
+			}
+		}
+	}
+
+	if c.Tracing != nil {
+		u, err := url.Parse(strings.TrimSpace(c.Tracing.Endpoint))
+		if err != nil || u == nil {
+			return errors.New("invalid tracing.endpoint")
+		}
+		scheme := strings.ToLower(strings.TrimSpace(u.Scheme))
+		if scheme != "http" && scheme != "https" {
+			return fmt.Errorf("invalid tracing.endpoint scheme %q", u.Scheme)
+		}
+		if strings.TrimSpace(u.Host) == "" {
+			return errors.New("invalid tracing.endpoint host")
+		}
+		if v := c.Tracing.SampleRatio; v != nil && (*v <= 0 || *v > 1) {
+			return fmt.Errorf("invalid tracing.sample_ratio %v (must be in (0,1])", *v)
+		}
+	}
+
+	for namespace, hook := range c.RunWebhooks {
+		if strings.TrimSpace(namespace) == "" {
+			return errors.New("invalid run_webhooks: empty namespace")
+		}
+		u, err := url.Parse(strings.TrimSpace(hook.URL))
+		if err != nil || u == nil {
+			return fmt.Errorf("run_webhooks[%q]: invalid url", namespace)
+		}
+		scheme := strings.ToLower(strings.TrimSpace(u.Scheme))
+		if scheme != "http" && scheme != "https" {
+			return fmt.Errorf("run_webhooks[%q]: invalid url scheme %q", namespace, u.Scheme)
+		}
+		if strings.TrimSpace(u.Host) == "" {
+			return fmt.Errorf("run_webhooks[%q]: invalid url host", namespace)
+		}
+	}
+
 	return nil
 }
 
@@ -421,6 +1478,99 @@ func (c *AIConfig) IsAllowedModelID(modelID string) bool {
 	return false
 }
 
+// EffectiveToolAllowlist returns the built-in tools allowed for a run in mode whose session permissions
+// match permissionPreset: the intersection of the tool_allowlists entries for both keys.
+//
+// ok is false when no non-empty entry applies, so built-in tools are not restricted.
+func (c *AIConfig) EffectiveToolAllowlist(mode string, permissionPreset string) (allowed []string, ok bool) {
+	if c == nil || len(c.ToolAllowlists) == 0 {
+		return nil, false
+	}
+	var set map[string]struct{}
+	for _, key := range []string{strings.ToLower(strings.TrimSpace(mode)), strings.TrimSpace(permissionPreset)} {
+		names := c.ToolAllowlists[key]
+		if key == "" || len(names) == 0 {
+			continue
+		}
+		next := make(map[string]struct{}, len(names))
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if _, keep := set[name]; set == nil || keep {
+				next[name] = struct{}{}
+			}
+		}
+		set = next
+	}
+	if set == nil {
+		return nil, false
+	}
+	allowed = make([]string, 0, len(set))
+	for name := range set {
+		allowed = append(allowed, name)
+	}
+	sort.Strings(allowed)
+	return allowed, true
+}
+
+// IsModelAllowedForNamespace reports whether namespacePublicID may use modelID under model_allowlist.
+//
+// The model must also exist in providers[].models[]; an empty allowlist allows every configured model.
+func (c *AIConfig) IsModelAllowedForNamespace(namespacePublicID string, modelID string) bool {
+	if !c.IsAllowedModelID(modelID) {
+		return false
+	}
+	allowed := c.ModelAllowlist[strings.TrimSpace(namespacePublicID)]
+	if len(allowed) == 0 {
+		return true
+	}
+	modelID = strings.TrimSpace(modelID)
+	for _, id := range allowed {
+		if strings.TrimSpace(id) == modelID {
+			return true
+		}
+	}
+	return false
+}
+
+// RunWebhookForNamespace returns the run webhook configured for namespacePublicID.
+func (c *AIConfig) RunWebhookForNamespace(namespacePublicID string) (AIRunWebhook, bool) {
+	if c == nil {
+		return AIRunWebhook{}, false
+	}
+	hook, ok := c.RunWebhooks[strings.TrimSpace(namespacePublicID)]
+	if !ok || strings.TrimSpace(hook.URL) == "" {
+		return AIRunWebhook{}, false
+	}
+	hook.URL = strings.TrimSpace(hook.URL)
+	return hook, true
+}
+
+// ResolvedCurrentModelIDForNamespace is ResolvedCurrentModelID restricted to the models namespacePublicID may use.
+func (c *AIConfig) ResolvedCurrentModelIDForNamespace(namespacePublicID string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if current, ok := c.ResolvedCurrentModelID(); ok && c.IsModelAllowedForNamespace(namespacePublicID, current) {
+		return current, true
+	}
+	for _, p := range c.Providers {
+		pid := strings.TrimSpace(p.ID)
+		if pid == "" {
+			continue
+		}
+		for _, m := range p.Models {
+			mn := strings.TrimSpace(m.ModelName)
+			if mn == "" {
+				continue
+			}
+			if id := pid + "/" + mn; c.IsModelAllowedForNamespace(namespacePublicID, id) {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
 func (c *AIConfig) EffectiveMode() string {
 	if c == nil {
 		return AIModeAct
@@ -540,3 +1690,386 @@ func (c *AIConfig) EffectiveTerminalExecDefaultTimeoutMS() int64 {
 	}
 	return int64(v)
 }
+
+func (c *AIConfig) EffectiveThreadBusyPolicy() string {
+	if c == nil || c.ThreadConcurrency == nil {
+		return AIThreadBusyReject
+	}
+	switch strings.TrimSpace(strings.ToLower(c.ThreadConcurrency.OnBusy)) {
+	case AIThreadBusyQueue:
+		return AIThreadBusyQueue
+	default:
+		return AIThreadBusyReject
+	}
+}
+
+func (c *AIConfig) EffectiveThreadBusyQueueTimeoutMS() int64 {
+	if c == nil || c.ThreadConcurrency == nil || c.ThreadConcurrency.QueueTimeoutMS == nil {
+		return defaultAIThreadBusyQueueTimeoutMS
+	}
+	v := *c.ThreadConcurrency.QueueTimeoutMS
+	if v < 1 {
+		return defaultAIThreadBusyQueueTimeoutMS
+	}
+	if v > maxAIThreadBusyQueueTimeoutMS {
+		return maxAIThreadBusyQueueTimeoutMS
+	}
+	return int64(v)
+}
+
+// EffectiveRunConcurrencyLimits returns the global and per-namespace caps on running runs; 0 means no cap.
+func (c *AIConfig) EffectiveRunConcurrencyLimits() (int, int) {
+	if c == nil || c.RunConcurrency == nil {
+		return 0, 0
+	}
+	return max(c.RunConcurrency.MaxRuns, 0), max(c.RunConcurrency.MaxRunsPerNamespace, 0)
+}
+
+func (c *AIConfig) EffectiveRunLimitPolicy() string {
+	if c == nil || c.RunConcurrency == nil {
+		return AIRunLimitReject
+	}
+	switch strings.TrimSpace(strings.ToLower(c.RunConcurrency.OnLimit)) {
+	case AIRunLimitQueue:
+		return AIRunLimitQueue
+	default:
+		return AIRunLimitReject
+	}
+}
+
+func (c *AIConfig) EffectiveRunLimitQueueTimeoutMS() int64 {
+	if c == nil || c.RunConcurrency == nil || c.RunConcurrency.QueueTimeoutMS == nil {
+		return defaultAIRunLimitQueueTimeoutMS
+	}
+	v := *c.RunConcurrency.QueueTimeoutMS
+	if v < 1 {
+		return defaultAIRunLimitQueueTimeoutMS
+	}
+	if v > maxAIRunLimitQueueTimeoutMS {
+		return maxAIRunLimitQueueTimeoutMS
+	}
+	return int64(v)
+}
+
+func (c *AIConfig) EffectivePersistenceMode() string {
+	if c == nil {
+		return AIPersistenceModeFull
+	}
+	switch strings.TrimSpace(strings.ToLower(c.PersistenceMode)) {
+	case AIPersistenceModeSummaryOnly:
+		return AIPersistenceModeSummaryOnly
+	default:
+		return AIPersistenceModeFull
+	}
+}
+
+func (c *AIConfig) EffectiveEmptyObjectivePolicy() string {
+	if c == nil {
+		return AIEmptyObjectiveOff
+	}
+	switch strings.TrimSpace(strings.ToLower(c.EmptyObjectivePolicy)) {
+	case AIEmptyObjectiveDerive:
+		return AIEmptyObjectiveDerive
+	case AIEmptyObjectiveClarify:
+		return AIEmptyObjectiveClarify
+	default:
+		return AIEmptyObjectiveOff
+	}
+}
+
+// EffectiveToolCallsPerMinute returns the per-minute call budget for a tool, or 0 when the tool is unlimited.
+func (c *AIConfig) EffectiveToolCallsPerMinute(toolName string) int {
+	if c == nil || c.ToolRateLimit == nil || len(c.ToolRateLimit.CallsPerMinute) == 0 {
+		return 0
+	}
+	toolName = strings.TrimSpace(toolName)
+	for name, v := range c.ToolRateLimit.CallsPerMinute {
+		if strings.TrimSpace(name) != toolName {
+			continue
+		}
+		if v < 1 {
+			return 0
+		}
+		if v > maxAIToolCallsPerMinute {
+			return maxAIToolCallsPerMinute
+		}
+		return v
+	}
+	return 0
+}
+
+func (c *AIConfig) EffectiveToolRateLimitMaxWaitMS() int64 {
+	if c == nil || c.ToolRateLimit == nil || c.ToolRateLimit.MaxWaitMS == nil {
+		return defaultAIToolRateLimitMaxWaitMS
+	}
+	v := *c.ToolRateLimit.MaxWaitMS
+	if v < 0 {
+		return defaultAIToolRateLimitMaxWaitMS
+	}
+	if v > maxAIToolRateLimitMaxWaitMS {
+		return maxAIToolRateLimitMaxWaitMS
+	}
+	return int64(v)
+}
+
+func (c *AIConfig) EffectivePersistProviderDiagnostics() bool {
+	return c != nil && c.PersistProviderDiagnostics
+}
+
+func (c *AIConfig) EffectiveApplyPatchPreview() bool {
+	return c != nil && c.ApplyPatchPreview
+}
+
+func (c *AIConfig) EffectiveThreadTitleFromFirstExchange() bool {
+	return c != nil && c.ThreadTitles != nil && c.ThreadTitles.FromFirstExchange
+}
+
+// EffectiveThreadTitleModel returns thread_titles.model, or "" to use the thread's model.
+func (c *AIConfig) EffectiveThreadTitleModel() string {
+	if c == nil || c.ThreadTitles == nil {
+		return ""
+	}
+	return strings.TrimSpace(c.ThreadTitles.Model)
+}
+
+var defaultThreadTitlePlaceholders = []string{
+	"new chat",
+	"new thread",
+	"new conversation",
+	"new session",
+	"untitled",
+	"untitled thread",
+	"新对话",
+	"新会话",
+	"未命名",
+}
+
+// IsPlaceholderThreadTitle reports whether title is a placeholder a UI passes when it has no real title yet.
+func (c *AIConfig) IsPlaceholderThreadTitle(title string) bool {
+	title = strings.ToLower(strings.TrimSpace(title))
+	if title == "" {
+		return false
+	}
+	if slices.Contains(defaultThreadTitlePlaceholders, title) {
+		return true
+	}
+	if c == nil || c.ThreadTitles == nil {
+		return false
+	}
+	for _, placeholder := range c.ThreadTitles.Placeholders {
+		if strings.ToLower(strings.TrimSpace(placeholder)) == title {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *AIConfig) EffectiveStrictWorkspaceSandbox() bool {
+	return c != nil && c.StrictWorkspaceSandbox
+}
+
+// EffectiveTracingServiceName returns tracing.service_name, or "redeven-agent".
+func (c *AIConfig) EffectiveTracingServiceName() string {
+	if c == nil || c.Tracing == nil || strings.TrimSpace(c.Tracing.ServiceName) == "" {
+		return defaultAITracingServiceName
+	}
+	return strings.TrimSpace(c.Tracing.ServiceName)
+}
+
+// EffectiveTracingSampleRatio returns tracing.sample_ratio, or 1.
+func (c *AIConfig) EffectiveTracingSampleRatio() float64 {
+	if c == nil || c.Tracing == nil || c.Tracing.SampleRatio == nil || *c.Tracing.SampleRatio <= 0 || *c.Tracing.SampleRatio > 1 {
+		return 1
+	}
+	return *c.Tracing.SampleRatio
+}
+
+// EffectiveTerminalCommandPolicyWarnOnly reports whether terminal_command_policy only logs matches.
+func (c *AIConfig) EffectiveTerminalCommandPolicyWarnOnly() bool {
+	return c != nil && c.TerminalCommandPolicy != nil && strings.TrimSpace(c.TerminalCommandPolicy.Mode) == AITerminalCommandPolicyWarn
+}
+
+func (c *AIConfig) EffectiveToolCallTimeoutMS() int64 {
+	if c == nil || c.ToolCallTimeoutMS == nil || *c.ToolCallTimeoutMS < 1 {
+		return defaultAIToolCallTimeoutMS
+	}
+	if *c.ToolCallTimeoutMS > maxAIToolCallTimeoutMS {
+		return maxAIToolCallTimeoutMS
+	}
+	return int64(*c.ToolCallTimeoutMS)
+}
+
+func (c *AIConfig) EffectiveToolResultOffloadBytes() int {
+	if c == nil || c.ToolResultOffloadBytes == nil {
+		return defaultAIToolResultOffloadBytes
+	}
+	v := *c.ToolResultOffloadBytes
+	if v < minAIToolResultOffloadBytes {
+		return minAIToolResultOffloadBytes
+	}
+	if v > maxAIToolResultOffloadBytes {
+		return maxAIToolResultOffloadBytes
+	}
+	return v
+}
+
+func (c *AIConfig) EffectiveRunAutoRetryMaxRetries() int {
+	if c == nil || c.RunAutoRetry == nil {
+		return 0
+	}
+	v := c.RunAutoRetry.MaxRetries
+	if v < 0 {
+		return 0
+	}
+	if v > maxAIRunAutoRetries {
+		return maxAIRunAutoRetries
+	}
+	return v
+}
+
+func (c *AIConfig) EffectiveRunAutoRetryInitialBackoffMS() int64 {
+	if c == nil || c.RunAutoRetry == nil || c.RunAutoRetry.InitialBackoffMS == nil {
+		return defaultAIRunAutoRetryInitialBackoffMS
+	}
+	v := *c.RunAutoRetry.InitialBackoffMS
+	if v < 0 {
+		return defaultAIRunAutoRetryInitialBackoffMS
+	}
+	if v > maxAIRunAutoRetryInitialBackoffMS {
+		return maxAIRunAutoRetryInitialBackoffMS
+	}
+	return int64(v)
+}
+
+func (c *AIConfig) EffectiveMaxAttachmentsPerMessage() int {
+	if c == nil || c.AttachmentLimit == nil || c.AttachmentLimit.MaxPerMessage == nil {
+		return defaultAIMaxAttachmentsPerMessage
+	}
+	v := *c.AttachmentLimit.MaxPerMessage
+	if v < 1 {
+		return defaultAIMaxAttachmentsPerMessage
+	}
+	if v > maxAIMaxAttachmentsPerMessage {
+		return maxAIMaxAttachmentsPerMessage
+	}
+	return v
+}
+
+func (c *AIConfig) EffectiveMaxImageBytes() int64 {
+	if c == nil || c.AttachmentLimit == nil || c.AttachmentLimit.MaxImageBytes == nil {
+		return defaultAIMaxImageBytes
+	}
+	return min(max(*c.AttachmentLimit.MaxImageBytes, minAIMaxImageBytes), maxAIMaxImageBytes)
+}
+
+func (c *AIConfig) EffectiveAttachmentExcessPolicy() string {
+	if c == nil || c.AttachmentLimit == nil {
+		return AIAttachmentExcessTruncate
+	}
+	switch strings.TrimSpace(strings.ToLower(c.AttachmentLimit.OnExcess)) {
+	case AIAttachmentExcessReject:
+		return AIAttachmentExcessReject
+	default:
+		return AIAttachmentExcessTruncate
+	}
+}
+
+func (c *AIConfig) EffectiveProviderRetryMaxRetries() int {
+	if c == nil || c.ProviderRetry == nil || c.ProviderRetry.MaxRetries == nil {
+		return defaultAIProviderRetries
+	}
+	v := *c.ProviderRetry.MaxRetries
+	if v < 0 {
+		return 0
+	}
+	if v > maxAIProviderRetries {
+		return maxAIProviderRetries
+	}
+	return v
+}
+
+func (c *AIConfig) EffectiveProviderRetryInitialBackoffMS() int64 {
+	if c == nil || c.ProviderRetry == nil || c.ProviderRetry.InitialBackoffMS == nil {
+		return defaultAIProviderRetryInitialBackoffMS
+	}
+	v := *c.ProviderRetry.InitialBackoffMS
+	if v < 0 {
+		return defaultAIProviderRetryInitialBackoffMS
+	}
+	if v > maxAIProviderRetryInitialBackoffMS {
+		return maxAIProviderRetryInitialBackoffMS
+	}
+	return int64(v)
+}
+
+// EffectiveProviderCircuitFailureThreshold returns the consecutive failures that open a provider breaker, or 0 when disabled.
+func (c *AIConfig) EffectiveProviderCircuitFailureThreshold() int {
+	if c == nil || c.ProviderCircuitBreaker == nil || c.ProviderCircuitBreaker.FailureThreshold == nil {
+		return defaultAIProviderCircuitFailureThreshold
+	}
+	v := *c.ProviderCircuitBreaker.FailureThreshold
+	if v < 0 {
+		return defaultAIProviderCircuitFailureThreshold
+	}
+	if v > maxAIProviderCircuitFailureThreshold {
+		return maxAIProviderCircuitFailureThreshold
+	}
+	return v
+}
+
+func (c *AIConfig) EffectiveProviderCircuitWindowSeconds() int {
+	if c == nil || c.ProviderCircuitBreaker == nil || c.ProviderCircuitBreaker.WindowSeconds == nil {
+		return defaultAIProviderCircuitWindowSeconds
+	}
+	v := *c.ProviderCircuitBreaker.WindowSeconds
+	if v < 1 {
+		return defaultAIProviderCircuitWindowSeconds
+	}
+	if v > maxAIProviderCircuitWindowSeconds {
+		return maxAIProviderCircuitWindowSeconds
+	}
+	return v
+}
+
+func (c *AIConfig) EffectiveProviderCircuitCooldownSeconds() int {
+	if c == nil || c.ProviderCircuitBreaker == nil || c.ProviderCircuitBreaker.CooldownSeconds == nil {
+		return defaultAIProviderCircuitCooldownSeconds
+	}
+	v := *c.ProviderCircuitBreaker.CooldownSeconds
+	if v < 1 {
+		return defaultAIProviderCircuitCooldownSeconds
+	}
+	if v > maxAIProviderCircuitCooldownSeconds {
+		return maxAIProviderCircuitCooldownSeconds
+	}
+	return v
+}
+
+// EffectiveWebSearchCacheTTLSeconds returns how long web.search results stay cached, or 0 when caching is disabled.
+func (c *AIConfig) EffectiveWebSearchCacheTTLSeconds() int {
+	if c == nil || c.WebSearchCache == nil || c.WebSearchCache.TTLSeconds == nil {
+		return defaultAIWebSearchCacheTTLSeconds
+	}
+	v := *c.WebSearchCache.TTLSeconds
+	if v <= 0 {
+		return 0
+	}
+	if v > maxAIWebSearchCacheTTLSeconds {
+		return maxAIWebSearchCacheTTLSeconds
+	}
+	return v
+}
+
+func (c *AIConfig) EffectiveWebSearchCacheMaxEntries() int {
+	if c == nil || c.WebSearchCache == nil || c.WebSearchCache.MaxEntries == nil {
+		return defaultAIWebSearchCacheMaxEntries
+	}
+	v := *c.WebSearchCache.MaxEntries
+	if v < 1 {
+		return defaultAIWebSearchCacheMaxEntries
+	}
+	if v > maxAIWebSearchCacheMaxEntries {
+		return maxAIWebSearchCacheMaxEntries
+	}
+	return v
+}
@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestAIConfigValidate_RequiresProviderModels(t *testing.T) {
 	t.Parallel()
@@ -97,6 +100,10 @@ func TestAIConfigValidate_ProviderTypeBaseURLRequirements(t *testing.T) {
 		{name: "chatglm_without_base_url", typ: "chatglm", baseURL: "", wantError: true},
 		{name: "deepseek_without_base_url", typ: "deepseek", baseURL: "", wantError: true},
 		{name: "qwen_without_base_url", typ: "qwen", baseURL: "", wantError: true},
+		{name: "ollama_without_base_url", typ: "ollama", baseURL: "", wantError: false},
+		{name: "mistral_without_base_url", typ: "mistral", baseURL: "", wantError: false},
+		{name: "azure_openai_without_base_url", typ: "azure_openai", baseURL: "", wantError: true},
+		{name: "azure_openai_with_base_url", typ: "azure_openai", baseURL: "https://my-resource.openai.azure.com", wantError: false},
 		{name: "chatglm_with_base_url", typ: "chatglm", baseURL: "https://open.bigmodel.cn/api/paas/v4/", wantError: false},
 		{name: "deepseek_with_base_url", typ: "deepseek", baseURL: "https://api.deepseek.com", wantError: false},
 		{name: "qwen_with_base_url", typ: "qwen", baseURL: "https://dashscope-intl.aliyuncs.com/compatible-mode/v1", wantError: false},
@@ -546,3 +553,1065 @@ func TestAIConfigValidate_RejectsInvalidTerminalExecPolicy(t *testing.T) {
 		t.Fatalf("Validate terminal_exec_policy: %v", err)
 	}
 }
+
+func TestAIConfig_EffectiveThreadConcurrencyPolicy(t *testing.T) {
+	t.Parallel()
+
+	nilCfg := (*AIConfig)(nil)
+	if got := nilCfg.EffectiveThreadBusyPolicy(); got != AIThreadBusyReject {
+		t.Fatalf("EffectiveThreadBusyPolicy nil=%q, want %q", got, AIThreadBusyReject)
+	}
+	if got := nilCfg.EffectiveThreadBusyQueueTimeoutMS(); got != 120_000 {
+		t.Fatalf("EffectiveThreadBusyQueueTimeoutMS nil=%d, want 120000", got)
+	}
+
+	cfg := &AIConfig{ThreadConcurrency: &AIThreadConcurrencyPolicy{OnBusy: " Queue ", QueueTimeoutMS: intPtr(5_000)}}
+	if got := cfg.EffectiveThreadBusyPolicy(); got != AIThreadBusyQueue {
+		t.Fatalf("EffectiveThreadBusyPolicy explicit=%q, want %q", got, AIThreadBusyQueue)
+	}
+	if got := cfg.EffectiveThreadBusyQueueTimeoutMS(); got != 5_000 {
+		t.Fatalf("EffectiveThreadBusyQueueTimeoutMS explicit=%d, want 5000", got)
+	}
+
+	base := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{
+				ID:     "openai",
+				Type:   "openai",
+				Models: []AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+	}
+	bad := base
+	bad.ThreadConcurrency = &AIThreadConcurrencyPolicy{OnBusy: "steal"}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for thread_concurrency.on_busy=steal")
+	}
+	bad.ThreadConcurrency = &AIThreadConcurrencyPolicy{QueueTimeoutMS: intPtr(0)}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for thread_concurrency.queue_timeout_ms=0")
+	}
+}
+
+func TestAIConfig_EffectiveRunConcurrencyPolicy(t *testing.T) {
+	t.Parallel()
+
+	nilCfg := (*AIConfig)(nil)
+	if maxRuns, perNamespace := nilCfg.EffectiveRunConcurrencyLimits(); maxRuns != 0 || perNamespace != 0 {
+		t.Fatalf("EffectiveRunConcurrencyLimits nil=(%d,%d), want (0,0)", maxRuns, perNamespace)
+	}
+	if got := nilCfg.EffectiveRunLimitPolicy(); got != AIRunLimitReject {
+		t.Fatalf("EffectiveRunLimitPolicy nil=%q, want %q", got, AIRunLimitReject)
+	}
+	if got := nilCfg.EffectiveRunLimitQueueTimeoutMS(); got != 120_000 {
+		t.Fatalf("EffectiveRunLimitQueueTimeoutMS nil=%d, want 120000", got)
+	}
+
+	cfg := &AIConfig{RunConcurrency: &AIRunConcurrencyPolicy{MaxRuns: 8, MaxRunsPerNamespace: 2, OnLimit: " Queue ", QueueTimeoutMS: intPtr(5_000)}}
+	if maxRuns, perNamespace := cfg.EffectiveRunConcurrencyLimits(); maxRuns != 8 || perNamespace != 2 {
+		t.Fatalf("EffectiveRunConcurrencyLimits explicit=(%d,%d), want (8,2)", maxRuns, perNamespace)
+	}
+	if got := cfg.EffectiveRunLimitPolicy(); got != AIRunLimitQueue {
+		t.Fatalf("EffectiveRunLimitPolicy explicit=%q, want %q", got, AIRunLimitQueue)
+	}
+	if got := cfg.EffectiveRunLimitQueueTimeoutMS(); got != 5_000 {
+		t.Fatalf("EffectiveRunLimitQueueTimeoutMS explicit=%d, want 5000", got)
+	}
+
+	base := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{
+				ID:     "openai",
+				Type:   "openai",
+				Models: []AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+	}
+	bad := base
+	bad.RunConcurrency = &AIRunConcurrencyPolicy{MaxRuns: -1}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for run_concurrency.max_runs=-1")
+	}
+	bad.RunConcurrency = &AIRunConcurrencyPolicy{MaxRunsPerNamespace: -1}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for run_concurrency.max_runs_per_namespace=-1")
+	}
+	bad.RunConcurrency = &AIRunConcurrencyPolicy{OnLimit: "drop"}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for run_concurrency.on_limit=drop")
+	}
+	bad.RunConcurrency = &AIRunConcurrencyPolicy{QueueTimeoutMS: intPtr(0)}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for run_concurrency.queue_timeout_ms=0")
+	}
+}
+
+func TestAIConfig_EffectiveToolCallTimeoutMS(t *testing.T) {
+	t.Parallel()
+
+	if got := (*AIConfig)(nil).EffectiveToolCallTimeoutMS(); got != 300_000 {
+		t.Fatalf("EffectiveToolCallTimeoutMS nil=%d, want 300000", got)
+	}
+	cfg := &AIConfig{ToolCallTimeoutMS: intPtr(45_000)}
+	if got := cfg.EffectiveToolCallTimeoutMS(); got != 45_000 {
+		t.Fatalf("EffectiveToolCallTimeoutMS explicit=%d, want 45000", got)
+	}
+
+	bad := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{
+				ID:     "openai",
+				Type:   "openai",
+				Models: []AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+		ToolCallTimeoutMS: intPtr(0),
+	}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for tool_call_timeout_ms=0")
+	}
+}
+
+func TestAIConfig_EffectiveToolResultOffloadBytes(t *testing.T) {
+	t.Parallel()
+
+	if got := (*AIConfig)(nil).EffectiveToolResultOffloadBytes(); got != 4_096 {
+		t.Fatalf("EffectiveToolResultOffloadBytes nil=%d, want 4096", got)
+	}
+	cfg := &AIConfig{ToolResultOffloadBytes: intPtr(65_536)}
+	if got := cfg.EffectiveToolResultOffloadBytes(); got != 65_536 {
+		t.Fatalf("EffectiveToolResultOffloadBytes explicit=%d, want 65536", got)
+	}
+
+	bad := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{
+				ID:     "openai",
+				Type:   "openai",
+				Models: []AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+		ToolResultOffloadBytes: intPtr(512),
+	}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for tool_result_offload_bytes=512")
+	}
+}
+
+func TestAIConfig_EffectivePersistenceMode(t *testing.T) {
+	t.Parallel()
+
+	if got := (*AIConfig)(nil).EffectivePersistenceMode(); got != AIPersistenceModeFull {
+		t.Fatalf("EffectivePersistenceMode nil=%q, want %q", got, AIPersistenceModeFull)
+	}
+	cfg := &AIConfig{PersistenceMode: "SUMMARY_ONLY"}
+	if got := cfg.EffectivePersistenceMode(); got != AIPersistenceModeSummaryOnly {
+		t.Fatalf("EffectivePersistenceMode explicit=%q, want %q", got, AIPersistenceModeSummaryOnly)
+	}
+
+	bad := AIConfig{
+		CurrentModelID:  "openai/gpt-5-mini",
+		PersistenceMode: "none",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for persistence_mode=none")
+	}
+}
+
+func TestAIConfig_EffectiveEmptyObjectivePolicy(t *testing.T) {
+	t.Parallel()
+
+	if got := (*AIConfig)(nil).EffectiveEmptyObjectivePolicy(); got != AIEmptyObjectiveOff {
+		t.Fatalf("EffectiveEmptyObjectivePolicy nil=%q, want %q", got, AIEmptyObjectiveOff)
+	}
+	cfg := &AIConfig{EmptyObjectivePolicy: " Clarify "}
+	if got := cfg.EffectiveEmptyObjectivePolicy(); got != AIEmptyObjectiveClarify {
+		t.Fatalf("EffectiveEmptyObjectivePolicy explicit=%q, want %q", got, AIEmptyObjectiveClarify)
+	}
+
+	bad := AIConfig{
+		CurrentModelID:       "openai/gpt-5-mini",
+		EmptyObjectivePolicy: "guess",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for empty_objective_policy=guess")
+	}
+}
+
+func TestAIConfig_EffectiveToolRateLimit(t *testing.T) {
+	t.Parallel()
+
+	if got := (*AIConfig)(nil).EffectiveToolCallsPerMinute("web.search"); got != 0 {
+		t.Fatalf("EffectiveToolCallsPerMinute nil=%d, want 0", got)
+	}
+	if got := (*AIConfig)(nil).EffectiveToolRateLimitMaxWaitMS(); got != defaultAIToolRateLimitMaxWaitMS {
+		t.Fatalf("EffectiveToolRateLimitMaxWaitMS nil=%d, want %d", got, defaultAIToolRateLimitMaxWaitMS)
+	}
+	zero := 0
+	cfg := &AIConfig{ToolRateLimit: &AIToolRateLimitPolicy{
+		CallsPerMinute: map[string]int{"web.search": 10},
+		MaxWaitMS:      &zero,
+	}}
+	if got := cfg.EffectiveToolCallsPerMinute("web.search"); got != 10 {
+		t.Fatalf("EffectiveToolCallsPerMinute web.search=%d, want 10", got)
+	}
+	if got := cfg.EffectiveToolCallsPerMinute("terminal.exec"); got != 0 {
+		t.Fatalf("EffectiveToolCallsPerMinute terminal.exec=%d, want 0", got)
+	}
+	if got := cfg.EffectiveToolRateLimitMaxWaitMS(); got != 0 {
+		t.Fatalf("EffectiveToolRateLimitMaxWaitMS explicit=%d, want 0", got)
+	}
+
+	bad := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		ToolRateLimit:  &AIToolRateLimitPolicy{CallsPerMinute: map[string]int{"web.search": 0}},
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for calls_per_minute=0")
+	}
+}
+
+func TestAIConfig_EffectiveRunAutoRetry(t *testing.T) {
+	t.Parallel()
+
+	if got := (*AIConfig)(nil).EffectiveRunAutoRetryMaxRetries(); got != 0 {
+		t.Fatalf("EffectiveRunAutoRetryMaxRetries nil=%d, want 0", got)
+	}
+	if got := (*AIConfig)(nil).EffectiveRunAutoRetryInitialBackoffMS(); got != defaultAIRunAutoRetryInitialBackoffMS {
+		t.Fatalf("EffectiveRunAutoRetryInitialBackoffMS nil=%d, want %d", got, defaultAIRunAutoRetryInitialBackoffMS)
+	}
+	backoff := 250
+	cfg := &AIConfig{RunAutoRetry: &AIRunAutoRetryPolicy{MaxRetries: 2, InitialBackoffMS: &backoff}}
+	if got := cfg.EffectiveRunAutoRetryMaxRetries(); got != 2 {
+		t.Fatalf("EffectiveRunAutoRetryMaxRetries=%d, want 2", got)
+	}
+	if got := cfg.EffectiveRunAutoRetryInitialBackoffMS(); got != 250 {
+		t.Fatalf("EffectiveRunAutoRetryInitialBackoffMS=%d, want 250", got)
+	}
+
+	bad := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		RunAutoRetry:   &AIRunAutoRetryPolicy{MaxRetries: maxAIRunAutoRetries + 1},
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected validation error for run_auto_retry.max_retries above the cap")
+	}
+}
+
+func TestAIConfig_AttachmentLimit(t *testing.T) {
+	t.Parallel()
+
+	if got := (*AIConfig)(nil).EffectiveMaxAttachmentsPerMessage(); got != defaultAIMaxAttachmentsPerMessage {
+		t.Fatalf("EffectiveMaxAttachmentsPerMessage nil=%d, want %d", got, defaultAIMaxAttachmentsPerMessage)
+	}
+	if got := (*AIConfig)(nil).EffectiveAttachmentExcessPolicy(); got != AIAttachmentExcessTruncate {
+		t.Fatalf("EffectiveAttachmentExcessPolicy nil=%q, want truncate", got)
+	}
+	limit := 5
+	cfg := AIConfig{
+		CurrentModelID:  "openai/gpt-5-mini",
+		AttachmentLimit: &AIAttachmentLimitPolicy{MaxPerMessage: &limit, OnExcess: " Reject "},
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if cfg.EffectiveMaxAttachmentsPerMessage() != 5 || cfg.EffectiveAttachmentExcessPolicy() != AIAttachmentExcessReject {
+		t.Fatalf("unexpected effective attachment limit: %d %q", cfg.EffectiveMaxAttachmentsPerMessage(), cfg.EffectiveAttachmentExcessPolicy())
+	}
+
+	cfg.AttachmentLimit.OnExcess = "summarize"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for on_excess=summarize")
+	}
+	zero := 0
+	cfg.AttachmentLimit = &AIAttachmentLimitPolicy{MaxPerMessage: &zero}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for max_per_message=0")
+	}
+
+	if got := (*AIConfig)(nil).EffectiveMaxImageBytes(); got != defaultAIMaxImageBytes {
+		t.Fatalf("EffectiveMaxImageBytes nil=%d, want %d", got, defaultAIMaxImageBytes)
+	}
+	imageBytes := int64(2 << 20)
+	cfg.AttachmentLimit = &AIAttachmentLimitPolicy{MaxImageBytes: &imageBytes}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate max_image_bytes: %v", err)
+	}
+	if got := cfg.EffectiveMaxImageBytes(); got != imageBytes {
+		t.Fatalf("EffectiveMaxImageBytes=%d, want %d", got, imageBytes)
+	}
+	tooSmall := int64(10)
+	cfg.AttachmentLimit.MaxImageBytes = &tooSmall
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for max_image_bytes=10")
+	}
+}
+
+func TestAIConfig_EffectiveProviderRetry(t *testing.T) {
+	t.Parallel()
+
+	if got := (*AIConfig)(nil).EffectiveProviderRetryMaxRetries(); got != defaultAIProviderRetries {
+		t.Fatalf("EffectiveProviderRetryMaxRetries nil=%d, want %d", got, defaultAIProviderRetries)
+	}
+	if got := (*AIConfig)(nil).EffectiveProviderRetryInitialBackoffMS(); got != defaultAIProviderRetryInitialBackoffMS {
+		t.Fatalf("EffectiveProviderRetryInitialBackoffMS nil=%d, want %d", got, defaultAIProviderRetryInitialBackoffMS)
+	}
+	retries := 0
+	backoff := 250
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		ProviderRetry:  &AIProviderRetryPolicy{MaxRetries: &retries, InitialBackoffMS: &backoff},
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if cfg.EffectiveProviderRetryMaxRetries() != 0 || cfg.EffectiveProviderRetryInitialBackoffMS() != 250 {
+		t.Fatalf("unexpected effective provider retry: %d %d", cfg.EffectiveProviderRetryMaxRetries(), cfg.EffectiveProviderRetryInitialBackoffMS())
+	}
+
+	tooMany := maxAIProviderRetries + 1
+	cfg.ProviderRetry = &AIProviderRetryPolicy{MaxRetries: &tooMany}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for max_retries=%d", tooMany)
+	}
+	negative := -1
+	cfg.ProviderRetry = &AIProviderRetryPolicy{InitialBackoffMS: &negative}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for initial_backoff_ms=-1")
+	}
+}
+
+func TestAIConfig_EffectiveWebSearchCache(t *testing.T) {
+	t.Parallel()
+
+	if got := (*AIConfig)(nil).EffectiveWebSearchCacheTTLSeconds(); got != defaultAIWebSearchCacheTTLSeconds {
+		t.Fatalf("EffectiveWebSearchCacheTTLSeconds nil=%d, want %d", got, defaultAIWebSearchCacheTTLSeconds)
+	}
+	if got := (*AIConfig)(nil).EffectiveWebSearchCacheMaxEntries(); got != defaultAIWebSearchCacheMaxEntries {
+		t.Fatalf("EffectiveWebSearchCacheMaxEntries nil=%d, want %d", got, defaultAIWebSearchCacheMaxEntries)
+	}
+	ttl := 0
+	entries := 16
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		WebSearchCache: &AIWebSearchCachePolicy{TTLSeconds: &ttl, MaxEntries: &entries},
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if cfg.EffectiveWebSearchCacheTTLSeconds() != 0 || cfg.EffectiveWebSearchCacheMaxEntries() != 16 {
+		t.Fatalf("unexpected effective web search cache: %d %d", cfg.EffectiveWebSearchCacheTTLSeconds(), cfg.EffectiveWebSearchCacheMaxEntries())
+	}
+
+	tooLong := maxAIWebSearchCacheTTLSeconds + 1
+	cfg.WebSearchCache = &AIWebSearchCachePolicy{TTLSeconds: &tooLong}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for ttl_seconds=%d", tooLong)
+	}
+	zero := 0
+	cfg.WebSearchCache = &AIWebSearchCachePolicy{MaxEntries: &zero}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for max_entries=0")
+	}
+}
+
+func TestAIConfigValidate_ModelPricing(t *testing.T) {
+	t.Parallel()
+
+	reasoning := 1.5
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{
+				ID:     "openai",
+				Type:   "openai",
+				Models: []AIProviderModel{{ModelName: "gpt-5-mini"}},
+				ModelPricing: []AIModelPricing{
+					{ModelName: "gpt-5-mini", InputUSDPerMTok: 0.25, OutputUSDPerMTok: 2},
+					{ModelName: "gpt-5", InputUSDPerMTok: 1.25, OutputUSDPerMTok: 10, ReasoningUSDPerMTok: &reasoning},
+				},
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	pricing, ok := cfg.Providers[0].PricingForModel("gpt-5-mini")
+	if !ok || pricing.EffectiveReasoningUSDPerMTok() != 2 {
+		t.Fatalf("PricingForModel gpt-5-mini=%+v ok=%v", pricing, ok)
+	}
+	pricing, ok = cfg.Providers[0].PricingForModel("gpt-5")
+	if !ok || pricing.EffectiveReasoningUSDPerMTok() != 1.5 {
+		t.Fatalf("PricingForModel gpt-5=%+v ok=%v", pricing, ok)
+	}
+	if _, ok := cfg.Providers[0].PricingForModel("unknown"); ok {
+		t.Fatalf("expected unknown model to have no pricing")
+	}
+
+	cfg.Providers[0].ModelPricing = append(cfg.Providers[0].ModelPricing, AIModelPricing{ModelName: "gpt-5-mini"})
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected duplicate model_pricing error")
+	}
+	cfg.Providers[0].ModelPricing = []AIModelPricing{{ModelName: "gpt-5-mini", InputUSDPerMTok: -1}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected negative price error")
+	}
+}
+
+func TestAIConfigValidate_ModelSamplingDefaults(t *testing.T) {
+	t.Parallel()
+
+	temp := 1.5
+	topP := 0.9
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-4.1",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-4.1", DefaultTemperature: &temp, DefaultTopP: &topP}}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg.Providers = []AIProvider{
+		{ID: "claude", Type: "anthropic", Models: []AIProviderModel{{ModelName: "claude-sonnet", DefaultTemperature: &temp}}},
+	}
+	cfg.CurrentModelID = "claude/claude-sonnet"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected anthropic default_temperature 1.5 to be rejected")
+	}
+
+	badTopP := 1.2
+	cfg.Providers[0].Models[0] = AIProviderModel{ModelName: "claude-sonnet", DefaultTopP: &badTopP}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected default_top_p 1.2 to be rejected")
+	}
+}
+
+func TestAIConfigValidate_ToolCallFormat(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "local/llama",
+		Providers: []AIProvider{
+			{
+				ID:             "local",
+				Type:           "openai_compatible",
+				BaseURL:        "http://127.0.0.1:8080/v1",
+				ToolCallFormat: "react_text",
+				Models:         []AIProviderModel{{ModelName: "llama", ContextWindow: 8192}},
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate react_text: %v", err)
+	}
+	if got := cfg.Providers[0].EffectiveToolCallFormat(); got != AIToolCallFormatReActText {
+		t.Fatalf("EffectiveToolCallFormat=%q, want %q", got, AIToolCallFormatReActText)
+	}
+	if got := (AIProvider{}).EffectiveToolCallFormat(); got != AIToolCallFormatNative {
+		t.Fatalf("EffectiveToolCallFormat default=%q, want %q", got, AIToolCallFormatNative)
+	}
+
+	cfg.Providers[0].ToolCallFormat = "xml"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for tool_call_format=xml")
+	}
+}
+
+func TestAIConfigValidate_AzureOpenAIDeployment(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "azure/gpt-5-mini",
+		Providers: []AIProvider{
+			{
+				ID:         "azure",
+				Type:       "azure_openai",
+				BaseURL:    "https://my-resource.openai.azure.com",
+				Deployment: "prod-gpt5",
+				APIVersion: "2025-04-01-preview",
+				Models:     []AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate azure_openai: %v", err)
+	}
+
+	cfg.Providers[0].Deployment = "prod/gpt5"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for deployment with /")
+	}
+
+	cfg.Providers[0].Type = "openai"
+	cfg.Providers[0].Deployment = "prod-gpt5"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for deployment on openai provider")
+	}
+}
+
+func TestAIConfigValidate_ReplayRecordingPath(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "replay/gpt-5-mini",
+		Providers: []AIProvider{
+			{
+				ID:            "replay",
+				Type:          "replay",
+				RecordingPath: "/tmp/eval/provider.jsonl",
+				Models:        []AIProviderModel{{ModelName: "gpt-5-mini"}},
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate replay: %v", err)
+	}
+	if AIProviderTypeRequiresAPIKey("replay") {
+		t.Fatalf("replay must not require an api key")
+	}
+
+	cfg.Providers[0].RecordingPath = ""
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for replay without recording_path")
+	}
+
+	cfg.Providers[0].Type = "openai"
+	cfg.Providers[0].RecordingPath = "/tmp/eval/provider.jsonl"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for recording_path on openai provider")
+	}
+}
+
+func TestAIConfigValidate_ImageOutputProviderType(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5",
+		Providers: []AIProvider{
+			{
+				ID:      "openai",
+				Type:    "openai",
+				BaseURL: "https://api.openai.com/v1",
+				Models:  []AIProviderModel{{ModelName: "gpt-5", ImageOutput: true}},
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate openai image_output: %v", err)
+	}
+
+	cfg.Providers[0].Type = "anthropic"
+	cfg.Providers[0].BaseURL = "https://api.anthropic.com"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected image_output validation error for anthropic, got %v", err)
+	}
+}
+
+func TestAIConfig_EffectiveProviderCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	var nilCfg *AIConfig
+	if nilCfg.EffectiveProviderCircuitFailureThreshold() != defaultAIProviderCircuitFailureThreshold ||
+		nilCfg.EffectiveProviderCircuitWindowSeconds() != defaultAIProviderCircuitWindowSeconds ||
+		nilCfg.EffectiveProviderCircuitCooldownSeconds() != defaultAIProviderCircuitCooldownSeconds {
+		t.Fatalf("unexpected nil defaults")
+	}
+	disabled := 0
+	window := 30
+	cooldown := 10
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		ProviderCircuitBreaker: &AIProviderCircuitBreakerPolicy{
+			FailureThreshold: &disabled,
+			WindowSeconds:    &window,
+			CooldownSeconds:  &cooldown,
+		},
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if cfg.EffectiveProviderCircuitFailureThreshold() != 0 || cfg.EffectiveProviderCircuitWindowSeconds() != 30 || cfg.EffectiveProviderCircuitCooldownSeconds() != 10 {
+		t.Fatalf("unexpected effective circuit breaker: %d %d %d", cfg.EffectiveProviderCircuitFailureThreshold(), cfg.EffectiveProviderCircuitWindowSeconds(), cfg.EffectiveProviderCircuitCooldownSeconds())
+	}
+
+	tooMany := maxAIProviderCircuitFailureThreshold + 1
+	cfg.ProviderCircuitBreaker = &AIProviderCircuitBreakerPolicy{FailureThreshold: &tooMany}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for failure_threshold=%d", tooMany)
+	}
+	zero := 0
+	cfg.ProviderCircuitBreaker = &AIProviderCircuitBreakerPolicy{CooldownSeconds: &zero}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for cooldown_seconds=0")
+	}
+}
+
+func TestAIConfigValidate_ToolApprovalRules(t *testing.T) {
+	t.Parallel()
+
+	base := func(rules ...AIToolApprovalRule) AIConfig {
+		return AIConfig{
+			CurrentModelID: "openai/gpt-5-mini",
+			Providers: []AIProvider{
+				{ID: "openai", Type: "openai", BaseURL: "https://api.openai.com/v1", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+			},
+			ToolApproval: &AIToolApprovalPolicy{Rules: rules},
+		}
+	}
+
+	valid := base(
+		AIToolApprovalRule{Tool: "terminal.exec", Mode: AIToolApprovalMatchArgs, Args: map[string]string{"command": `\brm\s+-rf\b`}},
+		AIToolApprovalRule{Tool: "terminal.exec", Mode: AIToolApprovalNever},
+		AIToolApprovalRule{Tool: "apply_patch", Mode: AIToolApprovalFirstTimePerRun},
+		AIToolApprovalRule{Tool: "*", Mode: AIToolApprovalAlways},
+	)
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(valid.ToolApproval.compiled) != 4 || len(valid.ToolApproval.compiled[0].ArgExprs) != 1 {
+		t.Fatalf("Validate did not cache the compiled rules: %+v", valid.ToolApproval.compiled)
+	}
+	if rules, err := valid.ToolApproval.CompiledRules(); err != nil || &rules[0] != &valid.ToolApproval.compiled[0] {
+		t.Fatalf("CompiledRules recompiled a validated policy: err=%v", err)
+	}
+
+	for name, rule := range map[string]AIToolApprovalRule{
+		"missing tool":        {Mode: AIToolApprovalAlways},
+		"unknown mode":        {Tool: "terminal.exec", Mode: "sometimes"},
+		"match_args no args":  {Tool: "terminal.exec", Mode: AIToolApprovalMatchArgs},
+		"args on always":      {Tool: "terminal.exec", Mode: AIToolApprovalAlways, Args: map[string]string{"command": "rm"}},
+		"invalid regex":       {Tool: "terminal.exec", Mode: AIToolApprovalMatchArgs, Args: map[string]string{"command": "(rm"}},
+		"empty argument name": {Tool: "terminal.exec", Mode: AIToolApprovalMatchArgs, Args: map[string]string{" ": "rm"}},
+	} {
+		cfg := base(rule)
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestAIConfigValidate_TerminalCommandPolicy(t *testing.T) {
+	t.Parallel()
+
+	base := func(policy AITerminalCommandPolicy) AIConfig {
+		return AIConfig{
+			CurrentModelID: "openai/gpt-5-mini",
+			Providers: []AIProvider{
+				{ID: "openai", Type: "openai", BaseURL: "https://api.openai.com/v1", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+			},
+			TerminalCommandPolicy: &policy,
+		}
+	}
+
+	valid := base(AITerminalCommandPolicy{Allow: []string{`^(go|git)\b`}, Deny: []string{`^sudo\b`}, Mode: AITerminalCommandPolicyWarn})
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if !valid.EffectiveTerminalCommandPolicyWarnOnly() {
+		t.Fatalf("warn mode not reported")
+	}
+	compiled, err := valid.TerminalCommandPolicy.Compiled()
+	if err != nil || compiled != valid.TerminalCommandPolicy.compiled || len(compiled.Allow) != 1 || len(compiled.Deny) != 1 {
+		t.Fatalf("Validate did not cache the compiled policy: compiled=%+v err=%v", compiled, err)
+	}
+
+	for name, policy := range map[string]AITerminalCommandPolicy{
+		"unknown mode":        {Deny: []string{"sudo"}, Mode: "audit"},
+		"invalid deny regex":  {Deny: []string{"(sudo"}},
+		"invalid allow regex": {Allow: []string{"[go"}},
+		"empty expression":    {Deny: []string{" "}},
+	} {
+		cfg := base(policy)
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestAIConfigValidate_RoutingRules(t *testing.T) {
+	t.Parallel()
+
+	base := func(rules ...AIRoutingRule) AIConfig {
+		return AIConfig{
+			CurrentModelID: "openai/gpt-5-mini",
+			Providers: []AIProvider{
+				{ID: "openai", Type: "openai", BaseURL: "https://api.openai.com/v1", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+			},
+			RoutingRules: rules,
+		}
+	}
+
+	valid := base(AIRoutingRule{Pattern: `^/plan\b`, Mode: "plan"}, AIRoutingRule{Pattern: `^/chat\b`, Intent: "Social"})
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	for name, rule := range map[string]AIRoutingRule{
+		"missing pattern":       {Mode: "plan"},
+		"invalid pattern":       {Pattern: "(/plan", Mode: "plan"},
+		"unknown intent":        {Pattern: "^/x", Intent: "chitchat"},
+		"unknown mode":          {Pattern: "^/x", Mode: "review"},
+		"no intent and no mode": {Pattern: "^/x"},
+	} {
+		cfg := base(rule)
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestAIConfigValidate_Tracing(t *testing.T) {
+	t.Parallel()
+
+	base := func(tracing AITracingConfig) AIConfig {
+		return AIConfig{
+			CurrentModelID: "openai/gpt-5-mini",
+			Providers: []AIProvider{
+				{ID: "openai", Type: "openai", BaseURL: "https://api.openai.com/v1", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+			},
+			Tracing: &tracing,
+		}
+	}
+
+	ratio := 0.25
+	valid := base(AITracingConfig{Endpoint: "http://127.0.0.1:4318/v1/traces", SampleRatio: &ratio})
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got := valid.EffectiveTracingSampleRatio(); got != 0.25 {
+		t.Fatalf("sample ratio=%v", got)
+	}
+	if got := valid.EffectiveTracingServiceName(); got != "redeven-agent" {
+		t.Fatalf("service name=%q", got)
+	}
+
+	zero, over := 0.0, 1.5
+	for name, tracing := range map[string]AITracingConfig{
+		"missing endpoint": {},
+		"bad scheme":       {Endpoint: "grpc://127.0.0.1:4317"},
+		"missing host":     {Endpoint: "http:///v1/traces"},
+		"zero ratio":       {Endpoint: "http://127.0.0.1:4318/v1/traces", SampleRatio: &zero},
+		"ratio above one":  {Endpoint: "http://127.0.0.1:4318/v1/traces", SampleRatio: &over},
+	} {
+		cfg := base(tracing)
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestAIConfig_ModelAllowlist(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", BaseURL: "https://api.openai.com/v1", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}, {ModelName: "gpt-4o-mini"}}},
+		},
+		ModelAllowlist: map[string][]string{
+			"ns_restricted": {"openai/gpt-4o-mini"},
+			"ns_open":       {},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if cfg.IsModelAllowedForNamespace("ns_restricted", "openai/gpt-5-mini") || !cfg.IsModelAllowedForNamespace("ns_restricted", "openai/gpt-4o-mini") {
+		t.Fatalf("restricted namespace allowlist not enforced")
+	}
+	if !cfg.IsModelAllowedForNamespace("ns_open", "openai/gpt-5-mini") || !cfg.IsModelAllowedForNamespace("ns_unlisted", "openai/gpt-5-mini") {
+		t.Fatalf("empty or missing allowlist must allow every model")
+	}
+	if cfg.IsModelAllowedForNamespace("ns_unlisted", "openai/unknown") {
+		t.Fatalf("unconfigured model allowed")
+	}
+	if got, ok := cfg.ResolvedCurrentModelIDForNamespace("ns_restricted"); !ok || got != "openai/gpt-4o-mini" {
+		t.Fatalf("restricted current model=%q ok=%v", got, ok)
+	}
+	if got, ok := cfg.ResolvedCurrentModelIDForNamespace("ns_unlisted"); !ok || got != "openai/gpt-5-mini" {
+		t.Fatalf("unlisted current model=%q ok=%v", got, ok)
+	}
+
+	cfg.ModelAllowlist = map[string][]string{"ns_restricted": {"openai/unknown"}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for unknown allowlist model")
+	}
+	cfg.ModelAllowlist = map[string][]string{" ": {"openai/gpt-5-mini"}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for empty namespace")
+	}
+}
+
+func TestAIConfig_RunWebhooks(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", BaseURL: "https://api.openai.com/v1", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+		RunWebhooks: map[string]AIRunWebhook{
+			"ns_hooked": {URL: " https://hooks.example.com/redeven "},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if hook, ok := cfg.RunWebhookForNamespace("ns_hooked"); !ok || hook.URL != "https://hooks.example.com/redeven" {
+		t.Fatalf("hook=%+v ok=%v", hook, ok)
+	}
+	if _, ok := cfg.RunWebhookForNamespace("ns_other"); ok {
+		t.Fatalf("namespace without a webhook must not be notified")
+	}
+
+	for _, hooks := range []map[string]AIRunWebhook{
+		{"ns_hooked": {URL: "ftp://hooks.example.com"}},
+		{"ns_hooked": {URL: "https://"}},
+		{" ": {URL: "https://hooks.example.com"}},
+	} {
+		cfg.RunWebhooks = hooks
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("run_webhooks=%v: expected validation error", hooks)
+		}
+	}
+}
+
+func TestAIConfigValidate_ProviderRequestTimeout(t *testing.T) {
+	t.Parallel()
+
+	timeoutMS := 45_000
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", RequestTimeoutMS: &timeoutMS, Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got := cfg.Providers[0].EffectiveRequestTimeoutMS(); got != timeoutMS {
+		t.Fatalf("EffectiveRequestTimeoutMS=%d, want %d", got, timeoutMS)
+	}
+	if got := (AIProvider{}).EffectiveRequestTimeoutMS(); got != 0 {
+		t.Fatalf("EffectiveRequestTimeoutMS default=%d, want 0", got)
+	}
+
+	for _, v := range []int{0, 999, 3_600_001} {
+		v := v
+		cfg.Providers[0].RequestTimeoutMS = &v
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("request_timeout_ms=%d: expected validation error", v)
+		}
+	}
+}
+
+func TestAIConfigValidate_ProviderStreamStallTimeout(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+	}
+	if got := cfg.Providers[0].EffectiveStreamStallTimeoutMS(); got != DefaultAIProviderStreamStallTimeoutMS {
+		t.Fatalf("EffectiveStreamStallTimeoutMS default=%d, want %d", got, DefaultAIProviderStreamStallTimeoutMS)
+	}
+	for _, v := range []int{0, 5_000, 3_600_000} {
+		v := v
+		cfg.Providers[0].StreamStallTimeoutMS = &v
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("stream_stall_timeout_ms=%d: %v", v, err)
+		}
+		if got := cfg.Providers[0].EffectiveStreamStallTimeoutMS(); got != v {
+			t.Fatalf("EffectiveStreamStallTimeoutMS=%d, want %d", got, v)
+		}
+	}
+	for _, v := range []int{-1, 4_999, 3_600_001} {
+		v := v
+		cfg.Providers[0].StreamStallTimeoutMS = &v
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("stream_stall_timeout_ms=%d: expected validation error", v)
+		}
+	}
+}
+
+func TestAIConfigValidate_BedrockModelIDs(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "bedrock/us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		Providers: []AIProvider{
+			{
+				ID:     "bedrock",
+				Type:   "bedrock",
+				Region: "us-east-1",
+				Models: []AIProviderModel{
+					{ModelName: "us.anthropic.claude-sonnet-4-5-20250929-v1:0"},
+					{ModelName: "anthropic.claude-3-5-haiku-20241022-v1:0"},
+				},
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate bedrock: %v", err)
+	}
+
+	cfg.Providers[0].Models = append(cfg.Providers[0].Models, AIProviderModel{ModelName: "meta.llama3-70b-instruct-v1:0"})
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for a non-Claude bedrock model")
+	}
+
+	cfg.Providers[0].Type = "anthropic"
+	cfg.Providers[0].Models = cfg.Providers[0].Models[:1]
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected validation error for region on anthropic provider")
+	}
+}
+
+func TestBedrockClaudeModelName(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{in: "anthropic.claude-sonnet-4-5-20250929-v1:0", want: "claude-sonnet-4-5-20250929", ok: true},
+		{in: "us.anthropic.claude-3-7-sonnet-20250219-v1:0", want: "claude-3-7-sonnet-20250219", ok: true},
+		{in: "global.anthropic.claude-haiku-4-5-20251001-v1:0", want: "claude-haiku-4-5-20251001", ok: true},
+		{in: "anthropic.claude-opus-4-1-20250805", want: "claude-opus-4-1-20250805", ok: true},
+		{in: "amazon.nova-pro-v1:0", ok: false},
+		{in: "claude-sonnet-4-5", ok: false},
+	}
+	for _, tc := range cases {
+		got, ok := BedrockClaudeModelName(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("BedrockClaudeModelName(%q)=%q,%v want %q,%v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestAIConfig_ToolAllowlists(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", BaseURL: "https://api.openai.com/v1", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+		ToolAllowlists: map[string][]string{
+			AIModePlan:               {"file.read", "terminal.exec", "web.search"},
+			PermissionPresetReadOnly: {"web.search", "file.read"},
+			AIModeAct:                {},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	if got, ok := cfg.EffectiveToolAllowlist(AIModePlan, PermissionPresetReadOnly); !ok || strings.Join(got, ",") != "file.read,web.search" {
+		t.Fatalf("plan+read_only=%v,%v want the intersection", got, ok)
+	}
+	if got, ok := cfg.EffectiveToolAllowlist(AIModeAct, PermissionPresetReadOnly); !ok || strings.Join(got, ",") != "file.read,web.search" {
+		t.Fatalf("act+read_only=%v,%v want the read_only list", got, ok)
+	}
+	if got, ok := cfg.EffectiveToolAllowlist(AIModeAct, PermissionPresetExecuteReadWrite); ok {
+		t.Fatalf("act+execute_read_write=%v, want no restriction", got)
+	}
+
+	for name, lists := range map[string]map[string][]string{
+		"unknown key":  {"yolo": {"file.read"}},
+		"unknown tool": {AIModeAct: {"file.read", "shell.exec"}},
+		"empty tool":   {PermissionPresetExecuteRead: {""}},
+	} {
+		bad := cfg
+		bad.ToolAllowlists = lists
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestAIConfigValidate_FallbackPhrases(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", BaseURL: "https://api.openai.com/v1", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+		FallbackPhrases: map[string][]string{"zh": {"任务已暂停"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	for name, phrases := range map[string]map[string][]string{
+		"empty language": {" ": {"paused"}},
+		"empty phrase":   {"en": {"paused", " "}},
+	} {
+		bad := cfg
+		bad.FallbackPhrases = phrases
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestAIConfigValidate_ThreadTitles(t *testing.T) {
+	t.Parallel()
+
+	cfg := AIConfig{
+		CurrentModelID: "openai/gpt-5-mini",
+		Providers: []AIProvider{
+			{ID: "openai", Type: "openai", BaseURL: "https://api.openai.com/v1", Models: []AIProviderModel{{ModelName: "gpt-5-mini"}}},
+		},
+		ThreadTitles: &AIThreadTitlePolicy{FromFirstExchange: true, Model: "openai/gpt-5-mini", Placeholders: []string{"Draft"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if !cfg.IsPlaceholderThreadTitle(" draft ") || !cfg.IsPlaceholderThreadTitle("New Chat") || cfg.IsPlaceholderThreadTitle("Release notes") {
+		t.Fatalf("IsPlaceholderThreadTitle mismatch")
+	}
+	for name, policy := range map[string]AIThreadTitlePolicy{
+		"unknown model":     {Model: "openai/gpt-unknown"},
+		"empty placeholder": {Placeholders: []string{" "}},
+	} {
+		bad := cfg
+		bad.ThreadTitles = &policy
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
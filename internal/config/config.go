@@ -28,6 +28,13 @@ type Config struct {
 	// It is designed to limit the effective permissions even if the control-plane grants more.
 	PermissionPolicy *PermissionPolicy `json:"permission_policy,omitempty"`
 
+	// GatewayRateLimit throttles the Env App AI and settings APIs per user. Unset leaves them unthrottled.
+	GatewayRateLimit *GatewayRateLimitPolicy `json:"gateway_rate_limit,omitempty"`
+
+	// SecretsBackend selects where provider API keys and other user-managed secrets are read from.
+	// If nil, they live in secrets.json next to this file.
+	SecretsBackend *SecretsBackendConfig `json:"secrets_backend,omitempty"`
+
 	// AgentHomeDir is the configured filesystem scope for user-facing features.
 	// If empty, the runtime picks a safe default (the current user home dir).
 	AgentHomeDir string `json:"agent_home_dir,omitempty"`
@@ -65,6 +72,12 @@ func (c *Config) ValidateLocalMinimal() error {
 			return fmt.Errorf("invalid ai: %w", err)
 		}
 	}
+	if err := c.GatewayRateLimit.Validate(); err != nil {
+		return fmt.Errorf("invalid gateway_rate_limit: %w", err)
+	}
+	if err := c.SecretsBackend.Validate(); err != nil {
+		return fmt.Errorf("invalid secrets_backend: %w", err)
+	}
 	return nil
 }
 
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GatewayRateLimitPolicy caps how often one user may call the gateway's AI and settings APIs.
+//
+// Limits are requests per minute per user (or channel when the user is unknown), enforced as token buckets
+// with a burst of one minute's budget. Rate limiting is opt-in: an unset or zero limit disables a category.
+// Local UI mode is single-user and uses the separate local_* limits.
+type GatewayRateLimitPolicy struct {
+	AIRequestsPerMinute            *int `json:"ai_requests_per_minute,omitempty"`
+	SettingsRequestsPerMinute      *int `json:"settings_requests_per_minute,omitempty"`
+	LocalAIRequestsPerMinute       *int `json:"local_ai_requests_per_minute,omitempty"`
+	LocalSettingsRequestsPerMinute *int `json:"local_settings_requests_per_minute,omitempty"`
+}
+
+const maxGatewayRequestsPerMinute = 100_000
+
+func (p *GatewayRateLimitPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, item := range []struct {
+		name  string
+		value *int
+	}{
+		{"ai_requests_per_minute", p.AIRequestsPerMinute},
+		{"settings_requests_per_minute", p.SettingsRequestsPerMinute},
+		{"local_ai_requests_per_minute", p.LocalAIRequestsPerMinute},
+		{"local_settings_requests_per_minute", p.LocalSettingsRequestsPerMinute},
+	} {
+		if item.value == nil {
+			continue
+		}
+		if v := *item.value; v < 0 || v > maxGatewayRequestsPerMinute {
+			return fmt.Errorf("invalid %s %d (must be in [0,%d])", item.name, v, maxGatewayRequestsPerMinute)
+		}
+	}
+	return nil
+}
+
+func gatewayRequestsPerMinute(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// EffectiveAIRequestsPerMinute returns the AI API limit, 0 when disabled; local selects the Local UI limit.
+func (p *GatewayRateLimitPolicy) EffectiveAIRequestsPerMinute(local bool) int {
+	if p == nil {
+		p = &GatewayRateLimitPolicy{}
+	}
+	if local {
+		return gatewayRequestsPerMinute(p.LocalAIRequestsPerMinute)
+	}
+	return gatewayRequestsPerMinute(p.AIRequestsPerMinute)
+}
+
+// EffectiveSettingsRequestsPerMinute returns the settings API limit, 0 when disabled; local selects the Local UI limit.
+func (p *GatewayRateLimitPolicy) EffectiveSettingsRequestsPerMinute(local bool) int {
+	if p == nil {
+		p = &GatewayRateLimitPolicy{}
+	}
+	if local {
+		return gatewayRequestsPerMinute(p.LocalSettingsRequestsPerMinute)
+	}
+	return gatewayRequestsPerMinute(p.SettingsRequestsPerMinute)
+}
+
+// ResolveGatewayRateLimitFromConfigPath loads configPath and returns its gateway rate limit policy.
+// When the config cannot be loaded, it returns nil, which disables rate limiting.
+func ResolveGatewayRateLimitFromConfigPath(configPath string) *GatewayRateLimitPolicy {
+	path := strings.TrimSpace(configPath)
+	if path == "" {
+		return nil
+	}
+	cfg, err := Load(path)
+	if err != nil || cfg == nil {
+		return nil
+	}
+	return cfg.GatewayRateLimit
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGatewayRateLimitPolicy_EffectiveAndValidate(t *testing.T) {
+	t.Parallel()
+
+	var nilPolicy *GatewayRateLimitPolicy
+	for _, local := range []bool{false, true} {
+		if nilPolicy.EffectiveAIRequestsPerMinute(local) != 0 || nilPolicy.EffectiveSettingsRequestsPerMinute(local) != 0 {
+			t.Fatalf("nil policy must disable rate limiting (local=%v)", local)
+		}
+	}
+
+	ai := 10
+	disabled := 0
+	p := &GatewayRateLimitPolicy{AIRequestsPerMinute: &ai, LocalSettingsRequestsPerMinute: &disabled}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if p.EffectiveAIRequestsPerMinute(false) != 10 || p.EffectiveSettingsRequestsPerMinute(true) != 0 {
+		t.Fatalf("overrides ignored: %+v", p)
+	}
+
+	negative := -1
+	if err := (&GatewayRateLimitPolicy{SettingsRequestsPerMinute: &negative}).Validate(); err == nil {
+		t.Fatalf("expected validation error for negative limit")
+	}
+	cfg := &Config{GatewayRateLimit: &GatewayRateLimitPolicy{LocalAIRequestsPerMinute: &negative}}
+	if err := cfg.ValidateLocalMinimal(); err == nil {
+		t.Fatalf("expected config validation error for invalid gateway_rate_limit")
+	}
+}
+
+func TestResolveGatewayRateLimitFromConfigPath(t *testing.T) {
+	t.Parallel()
+
+	if got := ResolveGatewayRateLimitFromConfigPath(filepath.Join(t.TempDir(), "missing.json")); got != nil {
+		t.Fatalf("missing config=%+v, want nil", got)
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"gateway_rate_limit":{"settings_requests_per_minute":5}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	got := ResolveGatewayRateLimitFromConfigPath(path)
+	if got.EffectiveSettingsRequestsPerMinute(false) != 5 || got.EffectiveAIRequestsPerMinute(false) != 0 || got.EffectiveSettingsRequestsPerMinute(true) != 0 {
+		t.Fatalf("resolved policy=%+v", got)
+	}
+}
+
+func TestResolveGatewayRateLimitFromConfigPath_NoConfigDisablesLimits(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	got := ResolveGatewayRateLimitFromConfigPath(path)
+	if got != nil {
+		t.Fatalf("config without gateway_rate_limit=%+v, want nil", got)
+	}
+	for _, local := range []bool{false, true} {
+		if ai, settings := got.EffectiveAIRequestsPerMinute(local), got.EffectiveSettingsRequestsPerMinute(local); ai != 0 || settings != 0 {
+			t.Fatalf("local=%v limits ai=%d settings=%d, want rate limiting off by default", local, ai, settings)
+		}
+	}
+}
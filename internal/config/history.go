@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ConfigHistoryDirName is the directory next to config.json that keeps config revisions.
+	ConfigHistoryDirName = "config_history"
+	// DefaultConfigHistoryMaxRevisions is the number of revisions kept by SaveRevision.
+	DefaultConfigHistoryMaxRevisions = 20
+
+	// configRevisionIDLayout doubles as the revision file name, so ids sort by time.
+	configRevisionIDLayout = "20060102T150405.000000000Z"
+)
+
+// ErrConfigRevisionNotFound is returned when a revision id does not name a stored revision.
+var ErrConfigRevisionNotFound = errors.New("config revision not found")
+
+// ConfigRevision describes one stored config snapshot.
+type ConfigRevision struct {
+	ID              string `json:"id"`
+	CreatedAtUnixMs int64  `json:"created_at_unix_ms"`
+	SizeBytes       int64  `json:"size_bytes"`
+}
+
+// ConfigHistoryDir returns the revision directory for the config file at configPath.
+func ConfigHistoryDir(configPath string) string {
+	return filepath.Join(filepath.Dir(strings.TrimSpace(configPath)), ConfigHistoryDirName)
+}
+
+func parseConfigRevisionID(id string) (time.Time, bool) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(configRevisionIDLayout, id)
+	if err != nil || t.UTC().Format(configRevisionIDLayout) != id {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// SaveRevision stores cfg as a new revision of the config at configPath and prunes all but the newest keep revisions.
+func SaveRevision(configPath string, cfg *Config, keep int) (ConfigRevision, error) {
+	if cfg == nil {
+		return ConfigRevision{}, errors.New("nil config")
+	}
+	if keep <= 0 {
+		keep = DefaultConfigHistoryMaxRevisions
+	}
+	dir := ConfigHistoryDir(configPath)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return ConfigRevision{}, err
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return ConfigRevision{}, err
+	}
+	b = append(b, '\n')
+
+	now := time.Now().UTC()
+	id := now.Format(configRevisionIDLayout)
+	path := filepath.Join(dir, id+".json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return ConfigRevision{}, err
+	}
+
+	revisions, err := ListRevisions(configPath)
+	if err != nil {
+		return ConfigRevision{}, err
+	}
+	for _, old := range revisions[min(keep, len(revisions)):] {
+		_ = os.Remove(filepath.Join(dir, old.ID+".json"))
+	}
+	return ConfigRevision{ID: id, CreatedAtUnixMs: now.UnixMilli(), SizeBytes: int64(len(b))}, nil
+}
+
+// ListRevisions returns the stored revisions of the config at configPath, newest first.
+func ListRevisions(configPath string) ([]ConfigRevision, error) {
+	entries, err := os.ReadDir(ConfigHistoryDir(configPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return []ConfigRevision{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ConfigRevision, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		createdAt, ok := parseConfigRevisionID(id)
+		if !ok {
+			continue
+		}
+		rev := ConfigRevision{ID: id, CreatedAtUnixMs: createdAt.UnixMilli()}
+		if info, err := entry.Info(); err == nil {
+			rev.SizeBytes = info.Size()
+		}
+		out = append(out, rev)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out, nil
+}
+
+// LoadRevision reads the revision id of the config at configPath.
+func LoadRevision(configPath string, id string) (*Config, error) {
+	id = strings.TrimSpace(id)
+	if _, ok := parseConfigRevisionID(id); !ok {
+		return nil, ErrConfigRevisionNotFound
+	}
+	b, err := os.ReadFile(filepath.Join(ConfigHistoryDir(configPath), id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrConfigRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config revision %s: %w", id, err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigHistory_SaveListLoadAndPrune(t *testing.T) {
+	t.Parallel()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	var ids []string
+	for _, level := range []string{"debug", "info", "warn"} {
+		rev, err := SaveRevision(configPath, &Config{LogLevel: level}, 2)
+		if err != nil {
+			t.Fatalf("SaveRevision: %v", err)
+		}
+		ids = append(ids, rev.ID)
+	}
+
+	revisions, err := ListRevisions(configPath)
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].ID != ids[2] || revisions[1].ID != ids[1] {
+		t.Fatalf("revisions=%+v, want the two newest of %v", revisions, ids)
+	}
+
+	cfg, err := LoadRevision(configPath, ids[1])
+	if err != nil || cfg.LogLevel != "info" {
+		t.Fatalf("LoadRevision cfg=%+v err=%v", cfg, err)
+	}
+	for _, id := range []string{ids[0], "../config", "", "latest"} {
+		if _, err := LoadRevision(configPath, id); !errors.Is(err, ErrConfigRevisionNotFound) {
+			t.Fatalf("LoadRevision(%q) err=%v, want ErrConfigRevisionNotFound", id, err)
+		}
+	}
+}
+
+func TestConfigHistory_ListWithoutHistory(t *testing.T) {
+	t.Parallel()
+
+	revisions, err := ListRevisions(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil || len(revisions) != 0 {
+		t.Fatalf("revisions=%+v err=%v, want none", revisions, err)
+	}
+}
@@ -8,6 +8,13 @@ import (
 
 const permissionPolicySchemaVersionV1 = 1
 
+// Permission policy presets, as accepted by ParsePermissionPolicyPreset.
+const (
+	PermissionPresetReadOnly         = "read_only"
+	PermissionPresetExecuteRead      = "execute_read"
+	PermissionPresetExecuteReadWrite = "execute_read_write"
+)
+
 // PermissionPolicy is the local permission cap configuration stored on the runtime endpoint.
 //
 // It is used to clamp control-plane granted permissions ("session_meta") to a user-approved maximum.
@@ -101,13 +108,13 @@ func ParsePermissionPolicyPreset(preset string) (*PermissionPolicy, error) {
 	switch p {
 	case "":
 		return defaultPermissionPolicy(), nil
-	case "execute_read":
+	case PermissionPresetExecuteRead:
 		s := PermissionSet{Read: true, Write: false, Execute: true}
 		return &PermissionPolicy{SchemaVersion: permissionPolicySchemaVersionV1, LocalMax: &s}, nil
-	case "read_only":
+	case PermissionPresetReadOnly:
 		s := PermissionSet{Read: true, Write: false, Execute: false}
 		return &PermissionPolicy{SchemaVersion: permissionPolicySchemaVersionV1, LocalMax: &s}, nil
-	case "execute_read_write":
+	case PermissionPresetExecuteReadWrite:
 		s := PermissionSet{Read: true, Write: true, Execute: true}
 		return &PermissionPolicy{SchemaVersion: permissionPolicySchemaVersionV1, LocalMax: &s}, nil
 	default:
@@ -115,6 +122,20 @@ func ParsePermissionPolicyPreset(preset string) (*PermissionPolicy, error) {
 	}
 }
 
+// PermissionPresetForSet returns the preset whose permissions equal p, or "" when none does.
+func PermissionPresetForSet(p PermissionSet) string {
+	switch p {
+	case PermissionSet{Read: true}:
+		return PermissionPresetReadOnly
+	case PermissionSet{Read: true, Execute: true}:
+		return PermissionPresetExecuteRead
+	case PermissionSet{Read: true, Write: true, Execute: true}:
+		return PermissionPresetExecuteReadWrite
+	default:
+		return ""
+	}
+}
+
 // ResolvePermissionCapFromConfigPath loads configPath and resolves the effective local cap
 // for the given user/app pair. When the config cannot be loaded, it falls back to fallback.
 func ResolvePermissionCapFromConfigPath(
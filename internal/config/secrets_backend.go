@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	SecretsBackendFile  = "file"
+	SecretsBackendEnv   = "env"
+	SecretsBackendVault = "vault"
+)
+
+const (
+	defaultSecretsEnvPrefix          = "REDEVEN_"
+	defaultVaultSecretsMount         = "secret"
+	defaultVaultSecretsTokenEnv      = "VAULT_TOKEN"
+	defaultVaultSecretsCacheTTLSecs  = 60
+	maxVaultSecretsCacheTTLSecs      = 3600
+	maxSecretsBackendIdentifierRunes = 256
+)
+
+// SecretsBackendConfig selects where user-managed secrets (provider API keys, run webhook secrets) come from.
+//
+// The file backend keeps them in secrets.json next to config.json and is the only one the Env App can write.
+// The env and vault backends are read-only: keys are managed outside the runtime.
+type SecretsBackendConfig struct {
+	// Type is "file" (default), "env", or "vault".
+	Type string `json:"type,omitempty"`
+
+	// EnvPrefix prefixes the variable names the env backend reads. Defaults to "REDEVEN_".
+	EnvPrefix string `json:"env_prefix,omitempty"`
+
+	// Vault configures the vault backend.
+	Vault *VaultSecretsConfig `json:"vault,omitempty"`
+}
+
+// VaultSecretsConfig points at one HashiCorp Vault KV v2 secret that holds every key as a field.
+type VaultSecretsConfig struct {
+	// Address is the Vault base URL, for example "https://vault.example.com:8200".
+	Address string `json:"address"`
+	// Mount is the KV v2 mount. Defaults to "secret".
+	Mount string `json:"mount,omitempty"`
+	// Path is the secret path under the mount, for example "redeven/prod".
+	Path string `json:"path"`
+	// Namespace is sent as X-Vault-Namespace (Vault Enterprise). Optional.
+	Namespace string `json:"namespace,omitempty"`
+	// TokenEnv names the environment variable that holds the Vault token. Defaults to "VAULT_TOKEN".
+	TokenEnv string `json:"token_env,omitempty"`
+	// CacheTTLSeconds is how long a read secret is reused before Vault is asked again. Defaults to 60.
+	CacheTTLSeconds *int `json:"cache_ttl_seconds,omitempty"`
+}
+
+func (c *SecretsBackendConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.EffectiveType() {
+	case SecretsBackendFile:
+	case SecretsBackendEnv:
+		if len([]rune(c.EnvPrefix)) > maxSecretsBackendIdentifierRunes {
+			return errors.New("env_prefix too long")
+		}
+	case SecretsBackendVault:
+		if c.Vault == nil {
+			return errors.New("missing vault")
+		}
+		if err := c.Vault.Validate(); err != nil {
+			return fmt.Errorf("invalid vault: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid type %q", c.Type)
+	}
+	return nil
+}
+
+func (c *SecretsBackendConfig) EffectiveType() string {
+	if c == nil {
+		return SecretsBackendFile
+	}
+	switch t := strings.ToLower(strings.TrimSpace(c.Type)); t {
+	case "":
+		return SecretsBackendFile
+	default:
+		return t
+	}
+}
+
+func (c *SecretsBackendConfig) EffectiveEnvPrefix() string {
+	if c == nil || strings.TrimSpace(c.EnvPrefix) == "" {
+		return defaultSecretsEnvPrefix
+	}
+	return strings.TrimSpace(c.EnvPrefix)
+}
+
+func (c *VaultSecretsConfig) Validate() error {
+	if c == nil {
+		return errors.New("nil vault config")
+	}
+	u, err := url.Parse(strings.TrimSpace(c.Address))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid address %q", c.Address)
+	}
+	if strings.Trim(strings.TrimSpace(c.Path), "/") == "" {
+		return errors.New("missing path")
+	}
+	if c.CacheTTLSeconds != nil {
+		if v := *c.CacheTTLSeconds; v < 0 || v > maxVaultSecretsCacheTTLSecs {
+			return fmt.Errorf("invalid cache_ttl_seconds %d (must be in [0,%d])", v, maxVaultSecretsCacheTTLSecs)
+		}
+	}
+	return nil
+}
+
+func (c *VaultSecretsConfig) EffectiveMount() string {
+	if c == nil || strings.Trim(strings.TrimSpace(c.Mount), "/") == "" {
+		return defaultVaultSecretsMount
+	}
+	return strings.Trim(strings.TrimSpace(c.Mount), "/")
+}
+
+func (c *VaultSecretsConfig) EffectiveTokenEnv() string {
+	if c == nil || strings.TrimSpace(c.TokenEnv) == "" {
+		return defaultVaultSecretsTokenEnv
+	}
+	return strings.TrimSpace(c.TokenEnv)
+}
+
+func (c *VaultSecretsConfig) EffectiveCacheTTLSeconds() int {
+	if c == nil || c.CacheTTLSeconds == nil {
+		return defaultVaultSecretsCacheTTLSecs
+	}
+	return min(max(*c.CacheTTLSeconds, 0), maxVaultSecretsCacheTTLSecs)
+}
@@ -0,0 +1,36 @@
+package config
+
+import "testing"
+
+func TestSecretsBackendConfig_EffectiveAndValidate(t *testing.T) {
+	t.Parallel()
+
+	var nilCfg *SecretsBackendConfig
+	if err := nilCfg.Validate(); err != nil || nilCfg.EffectiveType() != SecretsBackendFile || nilCfg.EffectiveEnvPrefix() != defaultSecretsEnvPrefix {
+		t.Fatalf("unexpected nil defaults: err=%v", err)
+	}
+	if got := (&SecretsBackendConfig{Type: " ENV "}).EffectiveType(); got != SecretsBackendEnv {
+		t.Fatalf("EffectiveType=%q", got)
+	}
+
+	vault := &VaultSecretsConfig{Address: "https://vault.example.com:8200", Path: "/redeven/prod/"}
+	if err := (&SecretsBackendConfig{Type: "vault", Vault: vault}).Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if vault.EffectiveMount() != defaultVaultSecretsMount || vault.EffectiveTokenEnv() != defaultVaultSecretsTokenEnv || vault.EffectiveCacheTTLSeconds() != defaultVaultSecretsCacheTTLSecs {
+		t.Fatalf("unexpected vault defaults: %+v", vault)
+	}
+
+	badTTL := maxVaultSecretsCacheTTLSecs + 1
+	for _, bad := range []*SecretsBackendConfig{
+		{Type: "kms"},
+		{Type: "vault"},
+		{Type: "vault", Vault: &VaultSecretsConfig{Address: "vault.local:8200", Path: "x"}},
+		{Type: "vault", Vault: &VaultSecretsConfig{Address: "https://vault.local", Path: "/"}},
+		{Type: "vault", Vault: &VaultSecretsConfig{Address: "https://vault.local", Path: "x", CacheTTLSeconds: &badTTL}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("Validate(%+v) err=nil", bad)
+		}
+	}
+}
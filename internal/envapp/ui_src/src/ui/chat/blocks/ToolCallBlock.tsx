@@ -3381,7 +3381,7 @@ export const ToolCallBlock: Component<ToolCallBlockProps> = (props) => {
         </button>
 
         <Show when={showApproval()}>
-          <div class="chat-tool-approval-actions">
+          <div class="chat-tool-approval-actions" title={props.block.approvalReason}>
             <button
               type="button"
               class="chat-tool-approval-btn chat-tool-approval-btn-approve"
@@ -97,6 +97,7 @@ export interface ToolCallBlock {
   args: Record<string, unknown>;
   requiresApproval?: boolean;
   approvalState?: 'required' | 'approved' | 'rejected';
+  approvalReason?: string;
   status: 'pending' | 'running' | 'success' | 'error';
   result?: unknown;
   error?: string;
@@ -984,7 +984,11 @@ export function EnvSettingsPage() {
         typ !== 'chatglm' &&
         typ !== 'deepseek' &&
         typ !== 'qwen' &&
-        typ !== 'openai_compatible'
+        typ !== 'openai_compatible' &&
+        typ !== 'ollama' &&
+        typ !== 'azure_openai' &&
+        typ !== 'mistral' &&
+        typ !== 'bedrock'
       ) {
         throw new Error(`Invalid provider type: ${typ || '(empty)'}`);
       }
@@ -85,6 +85,9 @@ export function SkillsCatalogTable(props: {
                     <Show when={item.dependency_state === 'degraded'}>
                       <SettingsPill tone="warning">Dependency degraded</SettingsPill>
                     </Show>
+                    <Show when={item.dependency_state === 'unsatisfied'}>
+                      <SettingsPill tone="danger">Requirements unsatisfied</SettingsPill>
+                    </Show>
                     <Show when={item.shadowed_by}>
                       <SettingsPill tone="danger">Shadowed</SettingsPill>
                     </Show>
@@ -16,6 +16,10 @@ export const AI_PROVIDER_TYPE_OPTIONS: ReadonlyArray<{ value: AIProviderType; la
   { value: 'deepseek', label: 'deepseek' },
   { value: 'qwen', label: 'qwen' },
   { value: 'openai_compatible', label: 'openai_compatible' },
+  { value: 'ollama', label: 'ollama' },
+  { value: 'azure_openai', label: 'azure_openai' },
+  { value: 'mistral', label: 'mistral' },
+  { value: 'bedrock', label: 'bedrock' },
 ];
 
 export const AI_PROVIDER_PRESET_CATALOG: Record<AIProviderType, AIProviderPreset> = {
@@ -89,6 +93,34 @@ export const AI_PROVIDER_PRESET_CATALOG: Record<AIProviderType, AIProviderPreset
     default_base_url: 'https://api.example.com/v1',
     models: [],
   },
+  ollama: {
+    type: 'ollama',
+    name: 'Ollama',
+    default_base_url: 'http://localhost:11434/v1',
+    models: [],
+  },
+  azure_openai: {
+    type: 'azure_openai',
+    name: 'Azure OpenAI',
+    default_base_url: 'https://my-resource.openai.azure.com',
+    models: [],
+  },
+  mistral: {
+    type: 'mistral',
+    name: 'Mistral',
+    default_base_url: 'https://api.mistral.ai/v1',
+    models: [
+      { model_name: 'mistral-large-latest', context_window: 128000, max_output_tokens: 8192, note: 'Flagship general model' },
+    ],
+  },
+  bedrock: {
+    type: 'bedrock',
+    name: 'AWS Bedrock',
+    default_base_url: '',
+    models: [
+      { model_name: 'us.anthropic.claude-sonnet-4-5-20250929-v1:0', context_window: 200000, max_output_tokens: 64000, note: 'Claude Sonnet 4.5 cross-region inference profile' },
+    ],
+  },
 };
 
 export function modelID(providerID: string, modelName: string): string {
@@ -99,7 +131,7 @@ export function modelID(providerID: string, modelName: string): string {
 }
 
 export function providerTypeRequiresBaseURL(providerType: AIProviderType): boolean {
-  return providerType === 'moonshot' || providerType === 'chatglm' || providerType === 'deepseek' || providerType === 'qwen' || providerType === 'openai_compatible';
+  return providerType === 'moonshot' || providerType === 'chatglm' || providerType === 'deepseek' || providerType === 'qwen' || providerType === 'openai_compatible' || providerType === 'azure_openai';
 }
 
 export function providerPresetForType(providerType: AIProviderType): AIProviderPreset {
@@ -7,7 +7,7 @@ export type PermissionPolicy = Readonly<{
   by_app?: Record<string, PermissionSet>;
 }>;
 
-export type AIProviderType = 'openai' | 'anthropic' | 'moonshot' | 'chatglm' | 'deepseek' | 'qwen' | 'openai_compatible';
+export type AIProviderType = 'openai' | 'anthropic' | 'moonshot' | 'chatglm' | 'deepseek' | 'qwen' | 'openai_compatible' | 'ollama' | 'azure_openai' | 'mistral' | 'bedrock';
 
 export type AIProviderModel = Readonly<{
   model_name: string;
@@ -107,6 +107,8 @@ export type SkillCatalogEntry = Readonly<{
   mode_hints?: string[];
   allow_implicit_invocation?: boolean;
   dependencies?: ReadonlyArray<Readonly<{ name?: string; transport?: string; command?: string; url?: string }>>;
+  requires?: string[];
+  required_tools?: string[];
   dependency_state?: string;
   enabled: boolean;
   effective: boolean;
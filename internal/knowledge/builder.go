@@ -11,12 +11,33 @@ import (
 	"strings"
 )
 
+const (
+	DistBundleFile   = "knowledge_bundle.json"
+	DistManifestFile = "knowledge_bundle.manifest.json"
+	DistSHA256File   = "knowledge_bundle.sha256"
+)
+
 type BuildResult struct {
 	Bundle       Bundle
 	BundleJSON   []byte
 	Manifest     BundleManifest
 	ManifestJSON []byte
 	SHA256File   []byte
+	// Hashes maps each dist file name to the sha256 of its built content.
+	Hashes map[string]string
+}
+
+type distFile struct {
+	Name    string
+	Content []byte
+}
+
+func (r BuildResult) distFiles() []distFile {
+	return []distFile{
+		{Name: DistBundleFile, Content: r.BundleJSON},
+		{Name: DistManifestFile, Content: r.ManifestJSON},
+		{Name: DistSHA256File, Content: r.SHA256File},
+	}
 }
 
 func BuildFromSource(sourceRoot string) (BuildResult, error) {
@@ -97,35 +118,49 @@ func BuildFromSource(sourceRoot string) (BuildResult, error) {
 	if err != nil {
 		return BuildResult{}, err
 	}
-	shaLine := fmt.Sprintf("%s  knowledge_bundle.json\n", bundleHash)
+	shaLine := fmt.Sprintf("%s  %s\n", bundleHash, DistBundleFile)
 
-	return BuildResult{
+	result := BuildResult{
 		Bundle:       bundle,
 		BundleJSON:   bundleJSON,
 		Manifest:     manifest,
 		ManifestJSON: manifestJSON,
 		SHA256File:   []byte(shaLine),
-	}, nil
+	}
+	result.Hashes = make(map[string]string, 3)
+	for _, file := range result.distFiles() {
+		result.Hashes[file.Name] = sha256Hex(file.Content)
+	}
+	return result, nil
 }
 
-func WriteDistFiles(distRoot string, result BuildResult) error {
+// WriteDistFiles writes the built dist files and returns the names it wrote.
+//
+// Files whose on-disk content already hashes to the built content are left untouched.
+func WriteDistFiles(distRoot string, result BuildResult) ([]string, error) {
 	root := strings.TrimSpace(distRoot)
 	if root == "" {
-		return fmt.Errorf("missing dist root")
+		return nil, fmt.Errorf("missing dist root")
 	}
 	if err := os.MkdirAll(root, 0o755); err != nil {
-		return err
-	}
-	if err := os.WriteFile(filepath.Join(root, "knowledge_bundle.json"), result.BundleJSON, 0o644); err != nil {
-		return err
-	}
-	if err := os.WriteFile(filepath.Join(root, "knowledge_bundle.manifest.json"), result.ManifestJSON, 0o644); err != nil {
-		return err
+		return nil, err
 	}
-	if err := os.WriteFile(filepath.Join(root, "knowledge_bundle.sha256"), result.SHA256File, 0o644); err != nil {
-		return err
+	written := make([]string, 0, 3)
+	for _, file := range result.distFiles() {
+		path := filepath.Join(root, file.Name)
+		want := result.Hashes[file.Name]
+		if want == "" {
+			want = sha256Hex(file.Content)
+		}
+		if existing, err := os.ReadFile(path); err == nil && sha256Hex(existing) == want {
+			continue
+		}
+		if err := os.WriteFile(path, file.Content, 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, file.Name)
 	}
-	return nil
+	return written, nil
 }
 
 func VerifyDistFiles(distRoot string, result BuildResult) error {
@@ -133,20 +168,12 @@ func VerifyDistFiles(distRoot string, result BuildResult) error {
 	if root == "" {
 		return fmt.Errorf("missing dist root")
 	}
-	checks := []struct {
-		Name string
-		Want []byte
-	}{
-		{Name: "knowledge_bundle.json", Want: result.BundleJSON},
-		{Name: "knowledge_bundle.manifest.json", Want: result.ManifestJSON},
-		{Name: "knowledge_bundle.sha256", Want: result.SHA256File},
-	}
-	for _, item := range checks {
+	for _, item := range result.distFiles() {
 		got, err := os.ReadFile(filepath.Join(root, item.Name))
 		if err != nil {
 			return fmt.Errorf("read %s failed: %w", item.Name, err)
 		}
-		if strings.TrimSpace(string(got)) != strings.TrimSpace(string(item.Want)) {
+		if strings.TrimSpace(string(got)) != strings.TrimSpace(string(item.Content)) {
 			return fmt.Errorf("%s is stale; run scripts/build_knowledge_bundle.sh", item.Name)
 		}
 	}
@@ -0,0 +1,40 @@
+package knowledge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteDistFiles_SkipsUnchangedFiles(t *testing.T) {
+	t.Parallel()
+
+	result, err := BuildFromSource(filepath.Join("source"))
+	if err != nil {
+		t.Fatalf("BuildFromSource: %v", err)
+	}
+	if len(result.Hashes) != 3 || result.Hashes[DistBundleFile] != result.Manifest.BundleSHA256 {
+		t.Fatalf("unexpected dist hashes: %v", result.Hashes)
+	}
+
+	dist := t.TempDir()
+	written, err := WriteDistFiles(dist, result)
+	if err != nil || len(written) != 3 {
+		t.Fatalf("first write=%v err=%v, want all files", written, err)
+	}
+	written, err = WriteDistFiles(dist, result)
+	if err != nil || len(written) != 0 {
+		t.Fatalf("second write=%v err=%v, want no files", written, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dist, DistSHA256File), []byte("stale\n"), 0o644); err != nil {
+		t.Fatalf("corrupt dist file: %v", err)
+	}
+	written, err = WriteDistFiles(dist, result)
+	if err != nil || len(written) != 1 || written[0] != DistSHA256File {
+		t.Fatalf("rewrite=%v err=%v, want only %s", written, err, DistSHA256File)
+	}
+	if err := VerifyDistFiles(dist, result); err != nil {
+		t.Fatalf("VerifyDistFiles: %v", err)
+	}
+}
@@ -140,6 +140,27 @@ func (s PathScope) ResolveTargetPath(path string) (string, error) {
 	return s.validateResolved(resolved)
 }
 
+// ContainsPath reports whether path stays inside the project root after symlinks are resolved.
+//
+// Relative paths resolve from baseDir, or from the project root when baseDir is empty.
+// The path does not need to exist; its nearest existing ancestor is canonicalized instead.
+func (s PathScope) ContainsPath(path string, baseDir string) (bool, error) {
+	path = strings.TrimSpace(path)
+	baseDir = strings.TrimSpace(baseDir)
+	if path != "" && !filepath.IsAbs(path) && path != "~" && !strings.HasPrefix(path, "~/") && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+	normalized, err := s.normalizeInput(path)
+	if err != nil {
+		return false, err
+	}
+	resolved, err := resolvePathViaExistingAncestor(normalized)
+	if err != nil {
+		return false, err
+	}
+	return IsWithinScope(resolved, s.ProjectRootAbs)
+}
+
 // ResolveExistingScopedPath validates an existing path under agentHomeAbs.
 func ResolveExistingScopedPath(path string, agentHomeAbs string) (string, error) {
 	normalized, err := NormalizeUserPathInput(path, agentHomeAbs)
@@ -185,3 +185,46 @@ func TestPathScope_ResolveTargetPath_AllowsRelativeProjectPath(t *testing.T) {
 		t.Fatalf("got=%q, want %q", got, want)
 	}
 }
+
+func TestPathScope_ContainsPath(t *testing.T) {
+	t.Parallel()
+
+	home := t.TempDir()
+	project := filepath.Join(home, "workspace")
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(project, "src"), 0o755); err != nil {
+		t.Fatalf("MkdirAll src: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(project, "escape")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	scope, err := NewPathScope(home, project)
+	if err != nil {
+		t.Fatalf("NewPathScope: %v", err)
+	}
+
+	cases := []struct {
+		path    string
+		baseDir string
+		want    bool
+	}{
+		{path: "src/main.go", want: true},
+		{path: "new/dir/file.txt", want: true},
+		{path: "..", baseDir: filepath.Join(project, "src"), want: true},
+		{path: "../..", baseDir: filepath.Join(project, "src"), want: false},
+		{path: "../other/file.txt", want: false},
+		{path: "escape/secret.txt", want: false},
+		{path: "/etc/passwd", want: false},
+		{path: "~/notes.txt", want: false},
+		{path: filepath.Join(project, "src"), want: true},
+	}
+	for _, tc := range cases {
+		got, err := scope.ContainsPath(tc.path, tc.baseDir)
+		if err != nil {
+			t.Fatalf("ContainsPath(%q, %q): %v", tc.path, tc.baseDir, err)
+		}
+		if got != tc.want {
+			t.Fatalf("ContainsPath(%q, %q)=%v, want %v", tc.path, tc.baseDir, got, tc.want)
+		}
+	}
+}
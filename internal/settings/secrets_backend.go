@@ -0,0 +1,71 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+// ErrSecretsBackendReadOnly is returned by the patch methods of backends whose keys are managed outside the runtime.
+var ErrSecretsBackendReadOnly = errors.New("secrets backend is read-only")
+
+// SecretsBackend is the source of user-managed secrets that the gateway and the AI service depend on.
+//
+// Implementations must never hand secrets back to the UI; callers only expose the *Set views.
+type SecretsBackend interface {
+	GetAIProviderAPIKey(providerID string) (string, bool, error)
+	GetAIProviderAPIKeySet(providerIDs []string) (map[string]bool, error)
+	ApplyAIProviderAPIKeyPatches(patches []AIProviderAPIKeyPatch) error
+
+	GetWebSearchProviderAPIKey(providerID string) (string, bool, error)
+	GetWebSearchProviderAPIKeySet(providerIDs []string) (map[string]bool, error)
+	ApplyWebSearchProviderAPIKeyPatches(patches []WebSearchProviderAPIKeyPatch) error
+
+	GetAIRunWebhookSecret(namespacePublicID string) (string, bool, error)
+}
+
+var (
+	_ SecretsBackend = (*SecretsStore)(nil)
+	_ SecretsBackend = (*EnvSecretsBackend)(nil)
+	_ SecretsBackend = (*VaultSecretsBackend)(nil)
+)
+
+// NewSecretsBackend builds the backend selected by cfg. A nil cfg selects the file backend at filePath.
+func NewSecretsBackend(cfg *config.SecretsBackendConfig, filePath string) (SecretsBackend, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid secrets_backend: %w", err)
+	}
+	switch cfg.EffectiveType() {
+	case config.SecretsBackendEnv:
+		return NewEnvSecretsBackend(cfg.EffectiveEnvPrefix()), nil
+	case config.SecretsBackendVault:
+		return NewVaultSecretsBackend(cfg.Vault, &http.Client{Timeout: 10 * time.Second})
+	default:
+		return NewSecretsStore(filePath), nil
+	}
+}
+
+func readOnlyBackendError(name string) error {
+	return fmt.Errorf("%w: keys come from the %s backend and must be changed there", ErrSecretsBackendReadOnly, name)
+}
+
+// lookupKeySet reports, for every non-empty id, whether get returns a key.
+func lookupKeySet(providerIDs []string, get func(id string) (string, bool, error)) (map[string]bool, error) {
+	out := make(map[string]bool, len(providerIDs))
+	for _, id := range providerIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		_, ok, err := get(id)
+		if err != nil {
+			return nil, err
+		}
+		out[id] = ok
+	}
+	return out, nil
+}
@@ -0,0 +1,184 @@
+package settings
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+func TestNewSecretsBackend_SelectsByType(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	for _, tc := range []struct {
+		cfg  *config.SecretsBackendConfig
+		want string
+	}{
+		{nil, "*settings.SecretsStore"},
+		{&config.SecretsBackendConfig{Type: "FILE"}, "*settings.SecretsStore"},
+		{&config.SecretsBackendConfig{Type: "env"}, "*settings.EnvSecretsBackend"},
+		{&config.SecretsBackendConfig{Type: "vault", Vault: &config.VaultSecretsConfig{Address: "https://vault.example.com", Path: "redeven"}}, "*settings.VaultSecretsBackend"},
+	} {
+		b, err := NewSecretsBackend(tc.cfg, path)
+		if err != nil {
+			t.Fatalf("NewSecretsBackend(%+v): %v", tc.cfg, err)
+		}
+		if got := typeName(b); got != tc.want {
+			t.Fatalf("NewSecretsBackend(%+v)=%s, want %s", tc.cfg, got, tc.want)
+		}
+	}
+	if _, err := NewSecretsBackend(&config.SecretsBackendConfig{Type: "vault"}, path); err == nil {
+		t.Fatalf("vault backend without vault config err=nil")
+	}
+	if _, err := NewSecretsBackend(&config.SecretsBackendConfig{Type: "kms"}, path); err == nil {
+		t.Fatalf("unknown backend err=nil")
+	}
+}
+
+func typeName(b SecretsBackend) string {
+	switch b.(type) {
+	case *SecretsStore:
+		return "*settings.SecretsStore"
+	case *EnvSecretsBackend:
+		return "*settings.EnvSecretsBackend"
+	case *VaultSecretsBackend:
+		return "*settings.VaultSecretsBackend"
+	default:
+		return "unknown"
+	}
+}
+
+func TestSecretsStore_FileBackendRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var b SecretsBackend = NewSecretsStore(filepath.Join(t.TempDir(), "secrets.json"))
+	key := "sk-file"
+	if err := b.ApplyAIProviderAPIKeyPatches([]AIProviderAPIKeyPatch{{ProviderID: "openai", APIKey: &key}}); err != nil {
+		t.Fatalf("ApplyAIProviderAPIKeyPatches: %v", err)
+	}
+	if v, ok, err := b.GetAIProviderAPIKey("openai"); err != nil || !ok || v != key {
+		t.Fatalf("GetAIProviderAPIKey=%q,%v,%v", v, ok, err)
+	}
+	set, err := b.GetAIProviderAPIKeySet([]string{"openai", "anthropic"})
+	if err != nil || !set["openai"] || set["anthropic"] {
+		t.Fatalf("GetAIProviderAPIKeySet=%v err=%v", set, err)
+	}
+	if err := b.ApplyAIProviderAPIKeyPatches([]AIProviderAPIKeyPatch{{ProviderID: "openai"}}); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if _, ok, _ := b.GetAIProviderAPIKey("openai"); ok {
+		t.Fatalf("key still set after clear")
+	}
+}
+
+func TestEnvSecretsBackend_ReadsVariablesAndIsReadOnly(t *testing.T) {
+	t.Setenv("ACME_AI_PROVIDER_API_KEY_OPENAI_PROD", " sk-env ")
+	t.Setenv("ACME_WEB_SEARCH_API_KEY_BRAVE", "brave-env")
+	t.Setenv("ACME_AI_RUN_WEBHOOK_SECRET_NS_1", "hook-env")
+
+	b := NewEnvSecretsBackend("ACME_")
+	if name := b.EnvVarName("AI_PROVIDER_API_KEY", "openai-prod"); name != "ACME_AI_PROVIDER_API_KEY_OPENAI_PROD" {
+		t.Fatalf("EnvVarName=%q", name)
+	}
+	if v, ok, err := b.GetAIProviderAPIKey("openai-prod"); err != nil || !ok || v != "sk-env" {
+		t.Fatalf("GetAIProviderAPIKey=%q,%v,%v", v, ok, err)
+	}
+	if v, ok, err := b.GetWebSearchProviderAPIKey("brave"); err != nil || !ok || v != "brave-env" {
+		t.Fatalf("GetWebSearchProviderAPIKey=%q,%v,%v", v, ok, err)
+	}
+	if v, ok, err := b.GetAIRunWebhookSecret("ns_1"); err != nil || !ok || v != "hook-env" {
+		t.Fatalf("GetAIRunWebhookSecret=%q,%v,%v", v, ok, err)
+	}
+	set, err := b.GetAIProviderAPIKeySet([]string{"openai-prod", "anthropic", " "})
+	if err != nil || len(set) != 2 || !set["openai-prod"] || set["anthropic"] {
+		t.Fatalf("GetAIProviderAPIKeySet=%v err=%v", set, err)
+	}
+
+	key := "sk-new"
+	if err := b.ApplyAIProviderAPIKeyPatches([]AIProviderAPIKeyPatch{{ProviderID: "openai", APIKey: &key}}); !errors.Is(err, ErrSecretsBackendReadOnly) {
+		t.Fatalf("ApplyAIProviderAPIKeyPatches err=%v, want ErrSecretsBackendReadOnly", err)
+	}
+	if err := b.ApplyWebSearchProviderAPIKeyPatches([]WebSearchProviderAPIKeyPatch{{ProviderID: "brave"}}); !errors.Is(err, ErrSecretsBackendReadOnly) {
+		t.Fatalf("ApplyWebSearchProviderAPIKeyPatches err=%v, want ErrSecretsBackendReadOnly", err)
+	}
+}
+
+func TestVaultSecretsBackend_ReadsKVv2SecretWithCache(t *testing.T) {
+	t.Setenv("TEST_VAULT_TOKEN", "s.token")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/v1/kv/data/redeven/prod" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "s.token" || r.Header.Get("X-Vault-Namespace") != "team-a" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"ai_provider_api_key_openai":"sk-vault","web_search_api_key_brave":"brave-vault","ai_run_webhook_secret_ns_1":"hook-vault","ignored":42}}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	b, err := NewVaultSecretsBackend(&config.VaultSecretsConfig{
+		Address:   srv.URL,
+		Mount:     "/kv/",
+		Path:      "/redeven/prod",
+		Namespace: "team-a",
+		TokenEnv:  "TEST_VAULT_TOKEN",
+	}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewVaultSecretsBackend: %v", err)
+	}
+	if v, ok, err := b.GetAIProviderAPIKey("openai"); err != nil || !ok || v != "sk-vault" {
+		t.Fatalf("GetAIProviderAPIKey=%q,%v,%v", v, ok, err)
+	}
+	if v, ok, err := b.GetWebSearchProviderAPIKey("brave"); err != nil || !ok || v != "brave-vault" {
+		t.Fatalf("GetWebSearchProviderAPIKey=%q,%v,%v", v, ok, err)
+	}
+	if v, ok, err := b.GetAIRunWebhookSecret("ns_1"); err != nil || !ok || v != "hook-vault" {
+		t.Fatalf("GetAIRunWebhookSecret=%q,%v,%v", v, ok, err)
+	}
+	set, err := b.GetAIProviderAPIKeySet([]string{"openai", "anthropic"})
+	if err != nil || !set["openai"] || set["anthropic"] {
+		t.Fatalf("GetAIProviderAPIKeySet=%v err=%v", set, err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("vault calls=%d, want 1 within the cache ttl", got)
+	}
+
+	key := "sk-new"
+	if err := b.ApplyAIProviderAPIKeyPatches([]AIProviderAPIKeyPatch{{ProviderID: "openai", APIKey: &key}}); !errors.Is(err, ErrSecretsBackendReadOnly) {
+		t.Fatalf("ApplyAIProviderAPIKeyPatches err=%v, want ErrSecretsBackendReadOnly", err)
+	}
+}
+
+func TestVaultSecretsBackend_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := &config.VaultSecretsConfig{Address: srv.URL, Path: "redeven", TokenEnv: "TEST_VAULT_TOKEN_ERRORS"}
+	b, err := NewVaultSecretsBackend(cfg, srv.Client())
+	if err != nil {
+		t.Fatalf("NewVaultSecretsBackend: %v", err)
+	}
+	t.Setenv("TEST_VAULT_TOKEN_ERRORS", "")
+	if _, _, err := b.GetAIProviderAPIKey("openai"); err == nil {
+		t.Fatalf("missing token err=nil")
+	}
+	t.Setenv("TEST_VAULT_TOKEN_ERRORS", "s.bad")
+	if _, _, err := b.GetAIProviderAPIKey("openai"); err == nil {
+		t.Fatalf("forbidden read err=nil")
+	}
+	if _, err := NewVaultSecretsBackend(&config.VaultSecretsConfig{Address: "vault.local", Path: "x"}, nil); err == nil {
+		t.Fatalf("address without scheme err=nil")
+	}
+}
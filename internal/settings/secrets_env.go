@@ -0,0 +1,87 @@
+package settings
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// EnvSecretsBackend reads secrets from environment variables. It is read-only.
+//
+// Variable names are the prefix plus a fixed kind and the id in upper case, with every character
+// outside [A-Z0-9] replaced by "_":
+//
+//	<prefix>AI_PROVIDER_API_KEY_<PROVIDER_ID>
+//	<prefix>WEB_SEARCH_API_KEY_<PROVIDER_ID>
+//	<prefix>AI_RUN_WEBHOOK_SECRET_<NAMESPACE_ID>
+type EnvSecretsBackend struct {
+	prefix string
+}
+
+func NewEnvSecretsBackend(prefix string) *EnvSecretsBackend {
+	return &EnvSecretsBackend{prefix: strings.TrimSpace(prefix)}
+}
+
+// EnvVarName returns the variable that holds the secret of kind for id.
+func (b *EnvSecretsBackend) EnvVarName(kind string, id string) string {
+	var sb strings.Builder
+	sb.WriteString(b.prefix)
+	sb.WriteString(kind)
+	sb.WriteByte('_')
+	for _, r := range strings.ToUpper(strings.TrimSpace(id)) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			continue
+		}
+		sb.WriteByte('_')
+	}
+	return sb.String()
+}
+
+func (b *EnvSecretsBackend) lookup(kind string, id string, missing string) (string, bool, error) {
+	if b == nil {
+		return "", false, errors.New("nil secrets backend")
+	}
+	if strings.TrimSpace(id) == "" {
+		return "", false, errors.New(missing)
+	}
+	v := strings.TrimSpace(os.Getenv(b.EnvVarName(kind, id)))
+	if v == "" {
+		return "", false, nil
+	}
+	return v, true, nil
+}
+
+func (b *EnvSecretsBackend) GetAIProviderAPIKey(providerID string) (string, bool, error) {
+	return b.lookup("AI_PROVIDER_API_KEY", providerID, "missing provider id")
+}
+
+func (b *EnvSecretsBackend) GetAIProviderAPIKeySet(providerIDs []string) (map[string]bool, error) {
+	return lookupKeySet(providerIDs, b.GetAIProviderAPIKey)
+}
+
+func (b *EnvSecretsBackend) ApplyAIProviderAPIKeyPatches(patches []AIProviderAPIKeyPatch) error {
+	if len(patches) == 0 {
+		return nil
+	}
+	return readOnlyBackendError("env")
+}
+
+func (b *EnvSecretsBackend) GetWebSearchProviderAPIKey(providerID string) (string, bool, error) {
+	return b.lookup("WEB_SEARCH_API_KEY", providerID, "missing provider id")
+}
+
+func (b *EnvSecretsBackend) GetWebSearchProviderAPIKeySet(providerIDs []string) (map[string]bool, error) {
+	return lookupKeySet(providerIDs, b.GetWebSearchProviderAPIKey)
+}
+
+func (b *EnvSecretsBackend) ApplyWebSearchProviderAPIKeyPatches(patches []WebSearchProviderAPIKeyPatch) error {
+	if len(patches) == 0 {
+		return nil
+	}
+	return readOnlyBackendError("env")
+}
+
+func (b *EnvSecretsBackend) GetAIRunWebhookSecret(namespacePublicID string) (string, bool, error) {
+	return b.lookup("AI_RUN_WEBHOOK_SECRET", namespacePublicID, "missing namespace id")
+}
@@ -41,6 +41,8 @@ type secretsFile struct {
 
 type aiSecrets struct {
 	ProviderAPIKeys map[string]string `json:"provider_api_keys,omitempty"`
+	// RunWebhookSecrets maps a namespace public id to the HMAC secret that signs its run webhooks.
+	RunWebhookSecrets map[string]string `json:"run_webhook_secrets,omitempty"`
 }
 
 type webSearchSecrets struct {
@@ -127,6 +129,32 @@ func (s *SecretsStore) GetWebSearchProviderAPIKey(providerID string) (string, bo
 	return s.getWebSearchProviderKey(providerID)
 }
 
+// GetAIRunWebhookSecret returns the HMAC secret that signs run webhooks of namespacePublicID.
+func (s *SecretsStore) GetAIRunWebhookSecret(namespacePublicID string) (string, bool, error) {
+	if s == nil {
+		return "", false, errors.New("nil secrets store")
+	}
+	namespacePublicID = strings.TrimSpace(namespacePublicID)
+	if namespacePublicID == "" {
+		return "", false, errors.New("missing namespace id")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sf, err := s.loadLocked()
+	if err != nil {
+		return "", false, err
+	}
+	if sf == nil || sf.AI == nil || len(sf.AI.RunWebhookSecrets) == 0 {
+		return "", false, nil
+	}
+	v := strings.TrimSpace(sf.AI.RunWebhookSecrets[namespacePublicID])
+	if v == "" {
+		return "", false, nil
+	}
+	return v, true, nil
+}
+
 func (s *SecretsStore) SetAIProviderAPIKey(providerID string, apiKey string) error {
 	if s == nil {
 		return errors.New("nil secrets store")
@@ -0,0 +1,163 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/floegence/redeven/internal/config"
+)
+
+const vaultSecretsMaxResponseBytes = 1 << 20
+
+// VaultSecretsBackend reads secrets from one HashiCorp Vault KV v2 secret. It is read-only.
+//
+// Every key is a field of that secret:
+//
+//	ai_provider_api_key_<provider_id>
+//	web_search_api_key_<provider_id>
+//	ai_run_webhook_secret_<namespace_id>
+//
+// The secret is cached for the configured TTL so a run does not call Vault per model turn.
+type VaultSecretsBackend struct {
+	address   string
+	mount     string
+	path      string
+	namespace string
+	tokenEnv  string
+	ttl       time.Duration
+	client    *http.Client
+
+	mu       sync.Mutex
+	fields   map[string]string
+	loadedAt time.Time
+}
+
+func NewVaultSecretsBackend(cfg *config.VaultSecretsConfig, client *http.Client) (*VaultSecretsBackend, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &VaultSecretsBackend{
+		address:   strings.TrimRight(strings.TrimSpace(cfg.Address), "/"),
+		mount:     cfg.EffectiveMount(),
+		path:      strings.Trim(strings.TrimSpace(cfg.Path), "/"),
+		namespace: strings.TrimSpace(cfg.Namespace),
+		tokenEnv:  cfg.EffectiveTokenEnv(),
+		ttl:       time.Duration(cfg.EffectiveCacheTTLSeconds()) * time.Second,
+		client:    client,
+	}, nil
+}
+
+func (b *VaultSecretsBackend) lookup(field string, id string, missing string) (string, bool, error) {
+	if b == nil {
+		return "", false, errors.New("nil secrets backend")
+	}
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", false, errors.New(missing)
+	}
+	fields, err := b.load()
+	if err != nil {
+		return "", false, err
+	}
+	v := strings.TrimSpace(fields[field+"_"+id])
+	if v == "" {
+		return "", false, nil
+	}
+	return v, true, nil
+}
+
+// load returns the secret's fields, reading Vault again once the cache is older than the TTL.
+func (b *VaultSecretsBackend) load() (map[string]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fields != nil && time.Since(b.loadedAt) < b.ttl {
+		return b.fields, nil
+	}
+
+	token := strings.TrimSpace(os.Getenv(b.tokenEnv))
+	if token == "" {
+		return nil, fmt.Errorf("vault token env %s is empty", b.tokenEnv)
+	}
+	req, err := http.NewRequest(http.MethodGet, b.address+"/v1/"+b.mount+"/data/"+b.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if b.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", b.namespace)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault read failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	fields := map[string]string{}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		// A secret that was never written holds no keys.
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("vault read failed: status %d", resp.StatusCode)
+	default:
+		var body struct {
+			Data struct {
+				Data map[string]any `json:"data"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, vaultSecretsMaxResponseBytes)).Decode(&body); err != nil {
+			return nil, fmt.Errorf("vault read failed: %w", err)
+		}
+		for k, v := range body.Data.Data {
+			if s, ok := v.(string); ok {
+				fields[k] = s
+			}
+		}
+	}
+	b.fields = fields
+	b.loadedAt = time.Now()
+	return fields, nil
+}
+
+func (b *VaultSecretsBackend) GetAIProviderAPIKey(providerID string) (string, bool, error) {
+	return b.lookup("ai_provider_api_key", providerID, "missing provider id")
+}
+
+func (b *VaultSecretsBackend) GetAIProviderAPIKeySet(providerIDs []string) (map[string]bool, error) {
+	return lookupKeySet(providerIDs, b.GetAIProviderAPIKey)
+}
+
+func (b *VaultSecretsBackend) ApplyAIProviderAPIKeyPatches(patches []AIProviderAPIKeyPatch) error {
+	if len(patches) == 0 {
+		return nil
+	}
+	return readOnlyBackendError("vault")
+}
+
+func (b *VaultSecretsBackend) GetWebSearchProviderAPIKey(providerID string) (string, bool, error) {
+	return b.lookup("web_search_api_key", providerID, "missing provider id")
+}
+
+func (b *VaultSecretsBackend) GetWebSearchProviderAPIKeySet(providerIDs []string) (map[string]bool, error) {
+	return lookupKeySet(providerIDs, b.GetWebSearchProviderAPIKey)
+}
+
+func (b *VaultSecretsBackend) ApplyWebSearchProviderAPIKeyPatches(patches []WebSearchProviderAPIKeyPatch) error {
+	if len(patches) == 0 {
+		return nil
+	}
+	return readOnlyBackendError("vault")
+}
+
+func (b *VaultSecretsBackend) GetAIRunWebhookSecret(namespacePublicID string) (string, bool, error) {
+	return b.lookup("ai_run_webhook_secret", namespacePublicID, "missing namespace id")
+}
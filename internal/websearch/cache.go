@@ -0,0 +1,107 @@
+package websearch
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheHit marks a SearchResult served from Cache.
+const CacheHit = "hit"
+
+// Cache is an in-memory LRU of search results keyed by provider, normalized
+// query, and result count. It is safe for concurrent use.
+type Cache struct {
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+type cacheEntry struct {
+	key       string
+	result    SearchResult
+	expiresAt time.Time
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		order: list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// CacheKey normalizes the query (case and whitespace) so trivially different
+// spellings of the same search share one entry.
+func CacheKey(provider string, req SearchRequest) string {
+	provider = strings.TrimSpace(strings.ToLower(provider))
+	if provider == "" {
+		provider = ProviderBrave
+	}
+	req = req.Normalize()
+	query := strings.Join(strings.Fields(strings.ToLower(req.Query)), " ")
+	return provider + "\x00" + strconv.Itoa(req.Count) + "\x00" + query
+}
+
+// Get returns a cached result marked with CacheHit, or false when the entry is missing or expired.
+func (c *Cache) Get(provider string, req SearchRequest) (SearchResult, bool) {
+	if c == nil {
+		return SearchResult{}, false
+	}
+	key := CacheKey(provider, req)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return SearchResult{}, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return SearchResult{}, false
+	}
+	c.order.MoveToFront(el)
+	out := entry.result.clone()
+	out.Cache = CacheHit
+	return out, true
+}
+
+// Put stores a result for ttl and evicts least recently used entries beyond maxEntries.
+// A non-positive ttl or maxEntries leaves the cache unchanged.
+func (c *Cache) Put(provider string, req SearchRequest, result SearchResult, ttl time.Duration, maxEntries int) {
+	if c == nil || ttl <= 0 || maxEntries <= 0 {
+		return
+	}
+	key := CacheKey(provider, req)
+	result = result.clone()
+	result.Cache = ""
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt := c.now().Add(ttl)
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.result = result
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+	} else {
+		c.items[key] = c.order.PushFront(&cacheEntry{key: key, result: result, expiresAt: expiresAt})
+	}
+	for c.order.Len() > maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (r SearchResult) clone() SearchResult {
+	out := r
+	out.Results = append([]ResultItem(nil), r.Results...)
+	if r.Sources != nil {
+		out.Sources = append([]ResultItem(nil), r.Sources...)
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package websearch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_HitNormalizesQueryAndExpires(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	c := NewCache()
+	c.now = func() time.Time { return now }
+
+	result := SearchResult{Provider: ProviderBrave, Query: "go generics", Results: []ResultItem{{Title: "Go", URL: "https://go.dev"}}}
+	c.Put("", SearchRequest{Query: "go generics"}, result, time.Minute, 8)
+
+	got, ok := c.Get("Brave", SearchRequest{Query: "  Go   GENERICS ", Count: 5})
+	if !ok || got.Cache != CacheHit || len(got.Results) != 1 {
+		t.Fatalf("got=%+v ok=%v, want cache hit", got, ok)
+	}
+	got.Results[0].Title = "mutated"
+	if again, _ := c.Get(ProviderBrave, SearchRequest{Query: "go generics"}); again.Results[0].Title != "Go" {
+		t.Fatalf("cached result must not share slices with callers")
+	}
+	if _, ok := c.Get(ProviderBrave, SearchRequest{Query: "go generics", Count: 10}); ok {
+		t.Fatalf("different result count must miss")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get(ProviderBrave, SearchRequest{Query: "go generics"}); ok {
+		t.Fatalf("expired entry must miss")
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	c := NewCache()
+	for _, q := range []string{"a", "b"} {
+		c.Put(ProviderBrave, SearchRequest{Query: q}, SearchResult{Query: q}, time.Minute, 2)
+	}
+	if _, ok := c.Get(ProviderBrave, SearchRequest{Query: "a"}); !ok {
+		t.Fatalf("a should be cached")
+	}
+	c.Put(ProviderBrave, SearchRequest{Query: "c"}, SearchResult{Query: "c"}, time.Minute, 2)
+	if _, ok := c.Get(ProviderBrave, SearchRequest{Query: "b"}); ok {
+		t.Fatalf("b should have been evicted as least recently used")
+	}
+	for _, q := range []string{"a", "c"} {
+		if _, ok := c.Get(ProviderBrave, SearchRequest{Query: q}); !ok {
+			t.Fatalf("%s should still be cached", q)
+		}
+	}
+
+	c.Put(ProviderBrave, SearchRequest{Query: "d"}, SearchResult{Query: "d"}, 0, 2)
+	if _, ok := c.Get(ProviderBrave, SearchRequest{Query: "d"}); ok {
+		t.Fatalf("zero ttl must not cache")
+	}
+	var nilCache *Cache
+	nilCache.Put(ProviderBrave, SearchRequest{Query: "a"}, SearchResult{}, time.Minute, 2)
+	if _, ok := nilCache.Get(ProviderBrave, SearchRequest{Query: "a"}); ok {
+		t.Fatalf("nil cache must always miss")
+	}
+}
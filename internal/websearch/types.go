@@ -34,4 +34,6 @@ type SearchResult struct {
 	Query    string       `json:"query"`
 	Results  []ResultItem `json:"results"`
 	Sources  []ResultItem `json:"sources,omitempty"`
+	// Cache is CacheHit when the result was served from Cache.
+	Cache string `json:"cache,omitempty"`
 }